@@ -0,0 +1,88 @@
+package args
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var actionsOutput string
+
+// actionMenus короткие имена подменю для вывода в JSON
+var actionMenus = map[string]string{
+	tui.CategorySecurity: "security",
+	tui.CategoryNetwork:  "network",
+	tui.CategoryOther:    "others",
+	tui.ModeSettings:     "settings",
+}
+
+// actionInfo пункт меню и подкоманда, которая выполняет его без терминала
+type actionInfo struct {
+	Menu    string `json:"menu"`
+	Key     string `json:"key"`
+	Title   string `json:"title"`
+	Command string `json:"command,omitempty"`
+}
+
+// actionCommand возвращает полную подкоманду действия; пусто — действие есть только в меню
+func actionCommand(a tui.Action) string {
+	if a.Command == "" {
+		return ""
+	}
+	path := strings.Fields(a.Command)
+	cmd, _, err := rootCmd.Find(path)
+	if err != nil || cmd.Name() != path[len(path)-1] {
+		return ""
+	}
+	return cmd.CommandPath()
+}
+
+// actionsCmd команда для вывода пунктов меню и подкоманд, выполняющих их без терминала
+var actionsCmd = &cobra.Command{
+	Use:   "actions",
+	Short: i18n.T("cli.actions.short"),
+	Long:  i18n.T("cli.actions.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(actionsOutput); err != nil {
+			return err
+		}
+		list := []actionInfo{}
+		menu := ""
+		for _, a := range tui.Actions() {
+			info := actionInfo{Menu: actionMenus[a.Menu], Key: a.Key, Title: a.Title(), Command: actionCommand(a)}
+			list = append(list, info)
+			if actionsOutput == outputJSON {
+				continue
+			}
+			if a.Menu != menu {
+				menu = a.Menu
+				fmt.Println(i18n.T(menu) + ":")
+			}
+			command := info.Command
+			if command == "" {
+				command = i18n.T("cli.actions.menu_only")
+			}
+			fmt.Printf("  %s — %s\n", info.Title, command)
+		}
+		if actionsOutput == outputJSON {
+			return printJSON(list)
+		}
+		return nil
+	},
+}
+
+func localizeActionsCommand() {
+	actionsCmd.Short = i18n.T("cli.actions.short")
+	actionsCmd.Long = i18n.T("cli.actions.long")
+}
+
+func init() {
+	localizeActionsCommand()
+	addOutputFlag(actionsCmd, &actionsOutput)
+
+	rootCmd.AddCommand(actionsCmd)
+}
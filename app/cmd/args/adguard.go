@@ -0,0 +1,396 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/adguard"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adguardOutput        string
+	adguardFiltersOutput string
+	adguardRulesOutput   string
+	adguardAllow         bool
+	adguardWebPort       int
+	adguardDNSPort       int
+	adguardURL           string
+	adguardUser          string
+	adguardPasswordStdin bool
+)
+
+// adguardReport состояние и статистика AdGuard Home для вывода в JSON
+type adguardReport struct {
+	Status adguard.Status `json:"status"`
+	Stats  adguard.Stats  `json:"stats"`
+}
+
+// adguardReady возвращает клиент API, если AdGuard Home установлен и настроен;
+// иначе подсказывает команду установки
+func adguardReady() (adguard.Client, error) {
+	c := AppConfig.AdGuardClient()
+	if !adguard.Service.Installed() {
+		return c, errors.New(i18n.T("cli.adguard.error.not_installed"))
+	}
+	need, err := c.NeedsSetup()
+	if err == nil && need {
+		err = errors.New(i18n.T("cli.adguard.error.needs_setup"))
+	}
+	return c, err
+}
+
+// adguardFilter возвращает список блокировки по адресу
+func adguardFilter(c adguard.Client, url string) (adguard.Filter, error) {
+	f, err := c.Filtering()
+	if err != nil {
+		return adguard.Filter{}, err
+	}
+	for _, filter := range f.Filters {
+		if filter.URL == url {
+			return filter, nil
+		}
+	}
+	return adguard.Filter{}, fmt.Errorf(i18n.T("cli.adguard.error.filter"), url)
+}
+
+// adguardCmd команда для вывода состояния и статистики AdGuard Home
+var adguardCmd = &cobra.Command{
+	Use:   "adguard",
+	Short: i18n.T("cli.adguard.short"),
+	Long:  i18n.T("cli.adguard.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(adguardOutput); err != nil {
+			return err
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		st, err := c.Status()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+		if adguardOutput == outputJSON {
+			return printJSON(adguardReport{Status: st, Stats: stats})
+		}
+		for _, line := range tui.AdGuardSummary(st, stats) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// adguardProtectionCmd команда для включения и выключения защиты
+var adguardProtectionCmd = &cobra.Command{
+	Use:       "protection <on|off>",
+	Short:     i18n.T("cli.adguard.protection.short"),
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enabled, err := parseOnOff(args[0])
+		if err != nil {
+			return err
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		if err := c.SetProtection(enabled); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("adguard.log.protection"), tui.ProtectionState(enabled))
+		return nil
+	},
+}
+
+// adguardFiltersCmd команда для вывода списков блокировки
+var adguardFiltersCmd = &cobra.Command{
+	Use:   "filters",
+	Short: i18n.T("cli.adguard.filters.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(adguardFiltersOutput); err != nil {
+			return err
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		f, err := c.Filtering()
+		if err != nil {
+			return err
+		}
+		if adguardFiltersOutput == outputJSON {
+			return printJSON(f.Filters)
+		}
+		for _, filter := range f.Filters {
+			fmt.Println(tui.FilterLine(filter))
+			fmt.Println("    " + filter.URL)
+		}
+		fmt.Println(i18n.T("adguard.filters.total", len(f.Filters), len(f.UserRules)))
+		return nil
+	},
+}
+
+// adguardFilterAddCmd команда для подключения списка блокировки
+var adguardFilterAddCmd = &cobra.Command{
+	Use:   "add <name> <url>",
+	Short: i18n.T("cli.adguard.filters.add.short"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := tui.CheckFilterURL(args[1]); err != nil {
+			return usageError(err)
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		if err := c.AddFilter(args[0], args[1]); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("adguard.log.filter_added"), args[1])
+		return nil
+	},
+}
+
+// adguardFilterSwitchCmd возвращает команду для включения или выключения списка блокировки
+func adguardFilterSwitchCmd(use string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:  use + " <url>",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := adguardReady()
+			if err != nil {
+				return err
+			}
+			filter, err := adguardFilter(c, args[0])
+			if err != nil {
+				return err
+			}
+			return c.SetFilterEnabled(filter, enabled)
+		},
+	}
+}
+
+var (
+	adguardFilterEnableCmd  = adguardFilterSwitchCmd("enable", true)
+	adguardFilterDisableCmd = adguardFilterSwitchCmd("disable", false)
+)
+
+// adguardFilterRemoveCmd команда для удаления списка блокировки
+var adguardFilterRemoveCmd = &cobra.Command{
+	Use:   "remove <url>",
+	Short: i18n.T("cli.adguard.filters.remove.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		filter, err := adguardFilter(c, args[0])
+		if err != nil {
+			return err
+		}
+		return c.RemoveFilter(filter.URL)
+	},
+}
+
+// adguardFilterRefreshCmd команда для обновления списков блокировки
+var adguardFilterRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: i18n.T("cli.adguard.filters.refresh.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		return c.RefreshFilters()
+	},
+}
+
+// adguardRulesCmd команда для вывода правил для клиентов
+var adguardRulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: i18n.T("cli.adguard.rules.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(adguardRulesOutput); err != nil {
+			return err
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		f, err := c.Filtering()
+		if err != nil {
+			return err
+		}
+		rules := adguard.ClientRules(f.UserRules)
+		if adguardRulesOutput == outputJSON {
+			return printJSON(rules)
+		}
+		for _, r := range rules {
+			fmt.Println(tui.RuleLine(r))
+		}
+		return nil
+	},
+}
+
+// adguardRuleArgs собирает правило для клиента из аргументов и флага --allow
+func adguardRuleArgs(args []string) (adguard.ClientRule, error) {
+	rule := adguard.ClientRule{Client: args[0], Domain: args[1], Allow: adguardAllow}
+	if err := rule.Validate(); err != nil {
+		return rule, usageError(err)
+	}
+	return rule, nil
+}
+
+// adguardRuleAddCmd команда для добавления правила для клиента
+var adguardRuleAddCmd = &cobra.Command{
+	Use:   "add <client> <domain>",
+	Short: i18n.T("cli.adguard.rules.add.short"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rule, err := adguardRuleArgs(args)
+		if err != nil {
+			return err
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		if err := c.AddUserRule(rule.String()); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("adguard.log.rule_added"), rule.String())
+		return nil
+	},
+}
+
+// adguardRuleRemoveCmd команда для удаления правила для клиента
+var adguardRuleRemoveCmd = &cobra.Command{
+	Use:   "remove <client> <domain>",
+	Short: i18n.T("cli.adguard.rules.remove.short"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rule, err := adguardRuleArgs(args)
+		if err != nil {
+			return err
+		}
+		c, err := adguardReady()
+		if err != nil {
+			return err
+		}
+		return c.RemoveUserRule(rule.String())
+	},
+}
+
+// adguardSetupCmd команда для установки AdGuard Home и первоначальной настройки
+// или подключения терема к уже настроенному экземпляру
+var adguardSetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: i18n.T("cli.adguard.setup.short"),
+	Long:  i18n.T("cli.adguard.setup.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var password string
+		if adguardPasswordStdin {
+			passwords, err := readPasswords(1)
+			if err != nil {
+				return err
+			}
+			password = passwords[0]
+		}
+
+		if !adguard.Service.Installed() {
+			if err := tui.CheckPorts(adguard.Service, adguard.WizardPorts()); err != nil {
+				return err
+			}
+		}
+		c, needsSetup, err := AppConfig.InstallAdGuard()
+		if err != nil {
+			return err
+		}
+
+		var saved conf.AdGuardConfig
+		if needsSetup {
+			if password == "" {
+				return errors.New(i18n.T("cli.adguard.error.password"))
+			}
+			if err := tui.CheckPorts(adguard.Service, adguard.Ports(adguardWebPort, adguardDNSPort)); err != nil {
+				return err
+			}
+			saved, err = AppConfig.CompleteAdGuardSetup(c, adguard.SetupConfig{
+				Web:      adguard.SetupAddress{IP: "0.0.0.0", Port: adguardWebPort},
+				DNS:      adguard.SetupAddress{IP: "0.0.0.0", Port: adguardDNSPort},
+				Username: adguardUser,
+				Password: password,
+			})
+		} else {
+			saved = AppConfig.Conf.AdGuard
+			saved.URL = c.BaseURL
+			if adguardURL != "" {
+				saved.URL = adguardURL
+			}
+			if cmd.Flags().Changed("user") {
+				saved.User = adguardUser
+			}
+			if password != "" {
+				saved.Password = password
+			}
+			_, err = AppConfig.ConnectAdGuard(saved)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("adguard.setup.saved", saved.URL, AppConfig.ConfFile))
+		return nil
+	},
+}
+
+func localizeAdGuardCommand() {
+	adguardCmd.Short = i18n.T("cli.adguard.short")
+	adguardCmd.Long = i18n.T("cli.adguard.long")
+	adguardProtectionCmd.Short = i18n.T("cli.adguard.protection.short")
+	adguardFiltersCmd.Short = i18n.T("cli.adguard.filters.short")
+	adguardFilterAddCmd.Short = i18n.T("cli.adguard.filters.add.short")
+	adguardFilterEnableCmd.Short = i18n.T("cli.adguard.filters.enable.short")
+	adguardFilterDisableCmd.Short = i18n.T("cli.adguard.filters.disable.short")
+	adguardFilterRemoveCmd.Short = i18n.T("cli.adguard.filters.remove.short")
+	adguardFilterRefreshCmd.Short = i18n.T("cli.adguard.filters.refresh.short")
+	adguardRulesCmd.Short = i18n.T("cli.adguard.rules.short")
+	adguardRuleAddCmd.Short = i18n.T("cli.adguard.rules.add.short")
+	adguardRuleRemoveCmd.Short = i18n.T("cli.adguard.rules.remove.short")
+	adguardSetupCmd.Short = i18n.T("cli.adguard.setup.short")
+	adguardSetupCmd.Long = i18n.T("cli.adguard.setup.long")
+}
+
+func init() {
+	localizeAdGuardCommand()
+	addOutputFlag(adguardCmd, &adguardOutput)
+	addOutputFlag(adguardFiltersCmd, &adguardFiltersOutput)
+	addOutputFlag(adguardRulesCmd, &adguardRulesOutput)
+	for _, c := range []*cobra.Command{adguardRuleAddCmd, adguardRuleRemoveCmd} {
+		c.Flags().BoolVar(&adguardAllow, "allow", false, "allow the domain instead of blocking it")
+	}
+	adguardSetupCmd.Flags().IntVar(&adguardWebPort, "web-port", adguard.WizardPort, "web interface port for the initial setup")
+	adguardSetupCmd.Flags().IntVar(&adguardDNSPort, "dns-port", 53, "DNS port for the initial setup")
+	adguardSetupCmd.Flags().StringVar(&adguardUser, "user", "admin", "administrator login")
+	adguardSetupCmd.Flags().StringVar(&adguardURL, "url", "", "web interface address of an already configured instance")
+	adguardSetupCmd.Flags().BoolVar(&adguardPasswordStdin, "password-stdin", false, "read the administrator password from stdin")
+
+	adguardFiltersCmd.AddCommand(adguardFilterAddCmd, adguardFilterEnableCmd, adguardFilterDisableCmd, adguardFilterRemoveCmd, adguardFilterRefreshCmd)
+	adguardRulesCmd.AddCommand(adguardRuleAddCmd, adguardRuleRemoveCmd)
+	adguardCmd.AddCommand(adguardProtectionCmd, adguardFiltersCmd, adguardRulesCmd, adguardSetupCmd)
+	rootCmd.AddCommand(adguardCmd)
+}
@@ -0,0 +1,78 @@
+package args
+
+import (
+	"fmt"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/clients"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	clientsOutput  string
+	clientsSearch  string
+	clientsAddMAC  string
+	clientsAddIP   string
+	clientsAddName string
+)
+
+// clientsCmd команда для вывода устройств локальной сети
+var clientsCmd = &cobra.Command{
+	Use:   "clients",
+	Short: i18n.T("cli.clients.short"),
+	Long:  i18n.T("cli.clients.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(clientsOutput); err != nil {
+			return err
+		}
+
+		list := clients.Filter(clients.Collector{}.Collect(), clientsSearch)
+		if clientsOutput == outputJSON {
+			return printJSON(list)
+		}
+		for _, client := range list {
+			fmt.Println(tui.ClientLine(client))
+		}
+		fmt.Println(i18n.T("clients.list.total", len(list)))
+		return nil
+	},
+}
+
+// clientsAddCmd команда для добавления статической привязки
+var clientsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: i18n.T("cli.clients.add.short"),
+	Long:  i18n.T("cli.clients.add.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := clients.StaticHost{MAC: clientsAddMAC, IP: clientsAddIP, Hostname: clientsAddName}
+		if err := (clients.Collector{}).AddStatic(host); err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("clients.add.done", clients.NormalizeMAC(host.MAC), host.IP))
+		return nil
+	},
+}
+
+func localizeClientsCommand() {
+	clientsCmd.Short = i18n.T("cli.clients.short")
+	clientsCmd.Long = i18n.T("cli.clients.long")
+	clientsAddCmd.Short = i18n.T("cli.clients.add.short")
+	clientsAddCmd.Long = i18n.T("cli.clients.add.long")
+}
+
+func init() {
+	localizeClientsCommand()
+	addOutputFlag(clientsCmd, &clientsOutput)
+	clientsCmd.Flags().StringVarP(&clientsSearch, "search", "s", "", "show only clients whose name, address, MAC or vendor contains the text")
+
+	clientsAddCmd.Flags().StringVar(&clientsAddMAC, "mac", "", "MAC address of the device")
+	clientsAddCmd.Flags().StringVar(&clientsAddIP, "ip", "", "IPv4 address to reserve")
+	clientsAddCmd.Flags().StringVar(&clientsAddName, "name", "", "optional hostname")
+	_ = clientsAddCmd.MarkFlagRequired("mac")
+	_ = clientsAddCmd.MarkFlagRequired("ip")
+
+	// Добавляем команду clients
+	clientsCmd.AddCommand(clientsAddCmd)
+	rootCmd.AddCommand(clientsCmd)
+}
@@ -0,0 +1,230 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/cron"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cronOutput   string
+	cronFile     string
+	cronRuns     int
+	cronSchedule string
+	cronCommand  string
+	cronName     string
+)
+
+// cronJob задание crontab с ближайшим запуском для вывода в JSON
+type cronJob struct {
+	cron.Entry
+	Line int        `json:"line"` // Номер строки с единицы, как в cron remove
+	Next *time.Time `json:"next,omitempty"`
+}
+
+// cronManager возвращает менеджер crontab с учётом флага --file
+func cronManager() cron.Manager {
+	return cron.Manager{Path: cronFile}
+}
+
+// cronCmd команда для вывода заданий cron
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: i18n.T("cli.cron.short"),
+	Long:  i18n.T("cli.cron.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(cronOutput); err != nil {
+			return err
+		}
+		t, err := cronManager().Load()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if cronOutput == outputJSON {
+			jobs := []cronJob{}
+			for _, e := range t.Entries() {
+				job := cronJob{Entry: e, Line: e.Line + 1}
+				if s, err := cron.Parse(e.Schedule); err == nil {
+					if next := s.Next(now); !next.IsZero() {
+						job.Next = &next
+					}
+				}
+				jobs = append(jobs, job)
+			}
+			return printJSON(jobs)
+		}
+		for _, line := range tui.CronLines(t.Entries(), now) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// cronAddCmd команда для добавления задания терема
+var cronAddCmd = &cobra.Command{
+	Use:   "add <name> <schedule> <command...>",
+	Short: i18n.T("cli.cron.add.short"),
+	Long:  i18n.T("cli.cron.add.long"),
+	Args:  cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		e := cron.Entry{Name: args[0], Schedule: args[1], Command: strings.Join(args[2:], " ")}
+		if err := e.Validate(); err != nil {
+			return usageError(err)
+		}
+		if err := cronManager().Add(e); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("cron.log.saved"), e.Name, e.Schedule, e.Command)
+		return nil
+	},
+}
+
+// cronEntry находит задание по имени терема или номеру строки
+func cronEntry(t cron.Table, value string) (cron.Entry, error) {
+	if e, ok := t.Find(value); ok {
+		return e, nil
+	}
+	// Номер строки указывается с единицы, как в редакторе
+	if line, err := strconv.Atoi(value); err == nil {
+		for _, e := range t.Entries() {
+			if e.Line == line-1 {
+				return e, nil
+			}
+		}
+	}
+	return cron.Entry{}, fmt.Errorf(i18n.T("cli.cron.error.missing"), value)
+}
+
+// cronEditCmd команда для изменения расписания, команды или имени задания
+var cronEditCmd = &cobra.Command{
+	Use:   "edit <name|line>",
+	Short: i18n.T("cli.cron.edit.short"),
+	Long:  i18n.T("cli.cron.edit.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := cronManager()
+		t, err := m.Load()
+		if err != nil {
+			return err
+		}
+		e, err := cronEntry(t, args[0])
+		if err != nil {
+			return err
+		}
+		entry := cron.Entry{Schedule: e.Schedule, Command: e.Command, Name: e.Name}
+		if cronSchedule != "" {
+			entry.Schedule = cronSchedule
+		}
+		if cronCommand != "" {
+			entry.Command = cronCommand
+		}
+		if cronName != "" {
+			entry.Name = cronName
+		}
+		if err := entry.Validate(); err != nil {
+			return usageError(err)
+		}
+		if !e.Owned() {
+			if err := confirm(i18n.T("cron.confirm.save", entry.Schedule) + " " + i18n.T("cron.confirm.foreign")); err != nil {
+				return err
+			}
+		}
+		if err := m.Replace(e.Line, entry); err != nil {
+			return err
+		}
+		name := entry.Name
+		if name == "" {
+			name = "-"
+		}
+		AppConfig.Log.Info(i18n.T("cron.log.saved"), name, entry.Schedule, entry.Command)
+		return nil
+	},
+}
+
+// cronRemoveCmd команда для удаления задания по имени терема или номеру строки
+var cronRemoveCmd = &cobra.Command{
+	Use:   "remove <name|line>",
+	Short: i18n.T("cli.cron.remove.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := cronManager()
+		t, err := m.Load()
+		if err != nil {
+			return err
+		}
+		e, err := cronEntry(t, args[0])
+		if err != nil {
+			return err
+		}
+		question := i18n.T("cron.confirm.remove", e.Schedule, e.Command)
+		if !e.Owned() {
+			question += " " + i18n.T("cron.confirm.foreign")
+		}
+		if err := confirm(question); err != nil {
+			return err
+		}
+		if err := m.Remove(e.Line); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("cron.log.removed"), e.Schedule, e.Command)
+		return nil
+	},
+}
+
+// cronNextCmd команда для проверки расписания и вывода ближайших запусков
+var cronNextCmd = &cobra.Command{
+	Use:   "next <schedule>",
+	Short: i18n.T("cli.cron.next.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(cronOutput); err != nil {
+			return err
+		}
+		s, err := cron.Parse(args[0])
+		if err != nil {
+			return usageError(err)
+		}
+		runs := s.NextRuns(time.Now(), cronRuns)
+		if cronOutput == outputJSON {
+			return printJSON(map[string]any{"expr": s.Expr, "runs": runs})
+		}
+		for _, t := range runs {
+			fmt.Println(t.Format("2006-01-02 15:04 Mon"))
+		}
+		return nil
+	},
+}
+
+func localizeCronCommand() {
+	cronCmd.Short = i18n.T("cli.cron.short")
+	cronCmd.Long = i18n.T("cli.cron.long")
+	cronAddCmd.Short = i18n.T("cli.cron.add.short")
+	cronAddCmd.Long = i18n.T("cli.cron.add.long")
+	cronEditCmd.Short = i18n.T("cli.cron.edit.short")
+	cronEditCmd.Long = i18n.T("cli.cron.edit.long")
+	cronRemoveCmd.Short = i18n.T("cli.cron.remove.short")
+	cronNextCmd.Short = i18n.T("cli.cron.next.short")
+}
+
+func init() {
+	localizeCronCommand()
+	addOutputFlag(cronCmd, &cronOutput)
+	addOutputFlag(cronNextCmd, &cronOutput)
+	cronCmd.PersistentFlags().StringVarP(&cronFile, "file", "f", "", "crontab path (default: Entware crontab if cron is installed, otherwise OpenWrt)")
+	cronNextCmd.Flags().IntVarP(&cronRuns, "count", "n", 5, "number of runs to show")
+	cronEditCmd.Flags().StringVar(&cronSchedule, "schedule", "", "new schedule: five fields or a shortcut like @daily")
+	cronEditCmd.Flags().StringVar(&cronCommand, "command", "", "new command")
+	cronEditCmd.Flags().StringVar(&cronName, "name", "", "new terem job name")
+	cronEditCmd.MarkFlagsOneRequired("schedule", "command", "name")
+
+	cronCmd.AddCommand(cronAddCmd, cronEditCmd, cronRemoveCmd, cronNextCmd)
+	rootCmd.AddCommand(cronCmd)
+}
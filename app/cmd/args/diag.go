@@ -0,0 +1,55 @@
+package args
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var bundleOutput string
+
+// diagCmd группа команд диагностики
+var diagCmd = &cobra.Command{
+	Use:   "diag",
+	Short: i18n.T("cli.diag.short"),
+	Long:  i18n.T("cli.diag.long"),
+}
+
+// diagBundleCmd команда для сборки диагностического архива
+var diagBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: i18n.T("cli.diag.bundle.short"),
+	Long:  i18n.T("cli.diag.bundle.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := bundleOutput
+		if path == "" {
+			name := fmt.Sprintf("%s-diag-%s.tar.gz", AppConfig.AppName, time.Now().Format("20060102-150405"))
+			path = filepath.Join(os.TempDir(), name)
+		}
+
+		if err := AppConfig.CreateDiagBundle(path); err != nil {
+			return err
+		}
+		fmt.Printf(i18n.T("cli.diag.bundle.done")+"\n", path)
+		return nil
+	},
+}
+
+func localizeDiagCommand() {
+	diagCmd.Short = i18n.T("cli.diag.short")
+	diagCmd.Long = i18n.T("cli.diag.long")
+	diagBundleCmd.Short = i18n.T("cli.diag.bundle.short")
+	diagBundleCmd.Long = i18n.T("cli.diag.bundle.long")
+}
+
+func init() {
+	localizeDiagCommand()
+	diagBundleCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "path to the resulting tar.gz archive")
+	diagCmd.AddCommand(diagBundleCmd)
+	// Добавляем команду diag
+	rootCmd.AddCommand(diagCmd)
+}
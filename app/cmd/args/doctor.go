@@ -0,0 +1,90 @@
+package args
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/doctor"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorOutput string
+	doctorHost   string
+	doctorPort   string
+)
+
+// doctorReport результат проверки окружения в формате JSON
+type doctorReport struct {
+	Host    string          `json:"host"`
+	Results []doctor.Result `json:"results"`
+	Pass    int             `json:"pass"`
+	Warn    int             `json:"warn"`
+	Fail    int             `json:"fail"`
+}
+
+// doctorCmd команда для проверки окружения
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: i18n.T("cli.doctor.short"),
+	Long:  i18n.T("cli.doctor.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(doctorOutput); err != nil {
+			return err
+		}
+
+		var runner utils.Runner = utils.Local{}
+		host := "localhost"
+		if doctorHost != "" {
+			runner = utils.Router{Address: doctorHost, SSHPort: doctorPort}
+			host = doctorHost
+		}
+
+		results := doctor.Run(AppConfig.RootCtx, AppConfig.DoctorOptions(runner))
+		pass, warn, fail := doctor.Count(results)
+
+		if doctorOutput == outputJSON {
+			if err := printJSON(doctorReport{Host: host, Results: results, Pass: pass, Warn: warn, Fail: fail}); err != nil {
+				return err
+			}
+		} else {
+			printDoctorResults(results)
+			fmt.Printf(i18n.T("doctor.summary")+"\n", pass, warn, fail)
+		}
+
+		if fail > 0 {
+			return fmt.Errorf(i18n.T("doctor.error.failed"), fail)
+		}
+		return nil
+	},
+}
+
+// printDoctorResults выводит результаты проверок в текстовом виде
+func printDoctorResults(results []doctor.Result) {
+	for _, r := range results {
+		line := fmt.Sprintf("[%s] %s", strings.ToUpper(string(r.Status)), r.Title)
+		if r.Detail != "" {
+			line += ": " + r.Detail
+		}
+		fmt.Println(line)
+		if r.Fix != "" {
+			fmt.Printf("       %s: %s\n", i18n.T("doctor.label.fix"), r.Fix)
+		}
+	}
+}
+
+func localizeDoctorCommand() {
+	doctorCmd.Short = i18n.T("cli.doctor.short")
+	doctorCmd.Long = i18n.T("cli.doctor.long")
+}
+
+func init() {
+	localizeDoctorCommand()
+	addOutputFlag(doctorCmd, &doctorOutput)
+	doctorCmd.Flags().StringVar(&doctorHost, "host", "", "check a remote router over SSH instead of the local system")
+	doctorCmd.Flags().StringVar(&doctorPort, "port", "22", "SSH port of the remote router")
+	// Добавляем команду doctor
+	rootCmd.AddCommand(doctorCmd)
+}
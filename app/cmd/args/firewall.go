@@ -0,0 +1,214 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/firewall"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	firewallOutput    string
+	firewallOwnedOnly bool
+	firewallProto     string
+	firewallToPort    int
+	firewallIface     string
+	firewallChain     string
+	firewallSource    string
+	firewallNote      string
+)
+
+// firewallCmd команда для вывода правил межсетевого экрана
+var firewallCmd = &cobra.Command{
+	Use:     "firewall",
+	Aliases: []string{"fw"},
+	Short:   i18n.T("cli.firewall.short"),
+	Long:    i18n.T("cli.firewall.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(firewallOutput); err != nil {
+			return err
+		}
+
+		sets, err := firewall.Manager{}.Load()
+		if err != nil {
+			return err
+		}
+		if firewallOwnedOnly {
+			owned := firewall.Owned(sets)
+			if firewallOutput == outputJSON {
+				return printJSON(owned)
+			}
+			// Номера нужны для firewall remove
+			for i, r := range owned {
+				fmt.Printf("%d. %s %s/%s: %s\n", i+1, r.Family, r.Table, r.Chain, tui.FirewallRuleLine(r))
+			}
+			return nil
+		}
+		if firewallOutput == outputJSON {
+			return printJSON(sets)
+		}
+		for _, line := range tui.FirewallTree(sets) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// firewallCleanupCmd команда для удаления правил терема (например, при удалении пакета)
+var firewallCleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: i18n.T("cli.firewall.cleanup.short"),
+	Long:  i18n.T("cli.firewall.cleanup.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := confirm(i18n.T("firewall.cleanup.question")); err != nil {
+			return err
+		}
+		removed, err := firewall.Manager{}.RemoveOwned()
+		fmt.Println(i18n.T("firewall.cleanup.done", removed))
+		return err
+	},
+}
+
+// firewallPort разбирает номер порта из аргумента
+func firewallPort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, usageError(fmt.Errorf(i18n.T("proxy.error.port_value"), value))
+	}
+	return port, nil
+}
+
+// addFirewallRules добавляет правила терема и выводит их
+func addFirewallRules(rules []firewall.Rule) error {
+	if err := AppConfig.AddFirewallRules(firewall.Manager{}, rules); err != nil {
+		return err
+	}
+	for _, r := range rules {
+		fmt.Printf("%s %s/%s: %s\n", r.Family, r.Table, r.Chain, r.Text)
+	}
+	fmt.Println(i18n.T("firewall.add.volatile"))
+	return nil
+}
+
+// firewallForwardCmd команда для проброса порта на устройство в локальной сети
+var firewallForwardCmd = &cobra.Command{
+	Use:   "forward <port> <address>",
+	Short: i18n.T("cli.firewall.forward.short"),
+	Long:  i18n.T("cli.firewall.forward.long"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, err := firewallPort(args[0])
+		if err != nil {
+			return err
+		}
+		f := firewall.Forward{
+			Proto:  firewallProto,
+			Port:   port,
+			To:     args[1],
+			ToPort: firewallToPort,
+			Iface:  firewallIface,
+			Note:   firewallNote,
+		}
+		if err := f.Validate(); err != nil {
+			return usageError(err)
+		}
+		return addFirewallRules(f.Rules())
+	},
+}
+
+// firewallFilterCmd возвращает команду для добавления правила разрешения или запрета
+func firewallFilterCmd(use string, allow bool) *cobra.Command {
+	return &cobra.Command{
+		Use:  use + " <port>",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			port, err := firewallPort(args[0])
+			if err != nil {
+				return err
+			}
+			f := firewall.Filter{
+				Allow:  allow,
+				Chain:  strings.ToUpper(firewallChain),
+				Proto:  firewallProto,
+				Port:   port,
+				Source: firewallSource,
+				Note:   firewallNote,
+			}
+			if err := f.Validate(); err != nil {
+				return usageError(err)
+			}
+			return addFirewallRules(f.Rules())
+		},
+	}
+}
+
+// Команды для добавления правил разрешения и запрета
+var (
+	firewallAllowCmd = firewallFilterCmd("allow", true)
+	firewallDenyCmd  = firewallFilterCmd("deny", false)
+)
+
+// firewallRemoveCmd команда для удаления одного правила терема по номеру из firewall --owned
+var firewallRemoveCmd = &cobra.Command{
+	Use:   "remove <number>",
+	Short: i18n.T("cli.firewall.remove.short"),
+	Long:  i18n.T("cli.firewall.remove.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := firewall.Manager{}
+		sets, err := m.Load()
+		if err != nil {
+			return err
+		}
+		owned := firewall.Owned(sets)
+		number, err := strconv.Atoi(args[0])
+		if err != nil || number < 1 || number > len(owned) {
+			return usageError(fmt.Errorf(i18n.T("cli.firewall.error.rule"), args[0]))
+		}
+		rule := owned[number-1]
+		if err := confirm(i18n.T("firewall.remove.question", rule.Text)); err != nil {
+			return err
+		}
+		return AppConfig.RemoveFirewallRule(m, rule)
+	},
+}
+
+func localizeFirewallCommand() {
+	firewallCmd.Short = i18n.T("cli.firewall.short")
+	firewallCmd.Long = i18n.T("cli.firewall.long")
+	firewallCleanupCmd.Short = i18n.T("cli.firewall.cleanup.short")
+	firewallCleanupCmd.Long = i18n.T("cli.firewall.cleanup.long")
+	firewallForwardCmd.Short = i18n.T("cli.firewall.forward.short")
+	firewallForwardCmd.Long = i18n.T("cli.firewall.forward.long")
+	firewallAllowCmd.Short = i18n.T("cli.firewall.allow.short")
+	firewallAllowCmd.Long = i18n.T("cli.firewall.filter.long")
+	firewallDenyCmd.Short = i18n.T("cli.firewall.deny.short")
+	firewallDenyCmd.Long = i18n.T("cli.firewall.filter.long")
+	firewallRemoveCmd.Short = i18n.T("cli.firewall.remove.short")
+	firewallRemoveCmd.Long = i18n.T("cli.firewall.remove.long")
+}
+
+func init() {
+	localizeFirewallCommand()
+	addOutputFlag(firewallCmd, &firewallOutput)
+	firewallCmd.Flags().BoolVar(&firewallOwnedOnly, "owned", false, "show only rules added by terem")
+
+	for _, c := range []*cobra.Command{firewallForwardCmd, firewallAllowCmd, firewallDenyCmd} {
+		c.Flags().StringVar(&firewallProto, "proto", firewall.ProtoTCP, "protocol: tcp, udp or tcp+udp")
+		c.Flags().StringVar(&firewallNote, "note", "", "note in the rule comment (generated if empty)")
+	}
+	firewallForwardCmd.Flags().IntVar(&firewallToPort, "to-port", 0, "port on the device (default: the external port)")
+	firewallForwardCmd.Flags().StringVar(&firewallIface, "iface", "", "incoming interface (default: any)")
+	for _, c := range []*cobra.Command{firewallAllowCmd, firewallDenyCmd} {
+		c.Flags().StringVar(&firewallChain, "chain", "input", "input (access to the router) or forward (forwarded traffic)")
+		c.Flags().StringVar(&firewallSource, "source", "", "source address or subnet (default: any)")
+	}
+
+	// Добавляем команду firewall
+	firewallCmd.AddCommand(firewallForwardCmd, firewallAllowCmd, firewallDenyCmd, firewallRemoveCmd, firewallCleanupCmd)
+	rootCmd.AddCommand(firewallCmd)
+}
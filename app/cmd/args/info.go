@@ -3,20 +3,59 @@ package args
 import (
 	"fmt"
 
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/buildinfo"
 	"github.com/qzeleza/terem/internal/i18n"
 	"github.com/spf13/cobra"
 )
 
+var infoOutput string
+
+// infoReport сведения о приложении и системе в формате JSON
+type infoReport struct {
+	Build      buildinfo.Info     `json:"build"`
+	ConfigFile string             `json:"configFile"`
+	LogFile    string             `json:"logFile"`
+	Warnings   []string           `json:"warnings,omitempty"`
+	System     *tui.SysInfoResult `json:"system"`
+}
+
 // infoCmd команда для отображения информации о системе
 var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: i18n.T("cli.info.short"),
 	Long:  i18n.T("cli.info.long"),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(infoOutput); err != nil {
+			return err
+		}
+		if infoOutput == outputJSON {
+			return printJSON(infoReport{
+				Build:      buildinfo.Get(),
+				ConfigFile: AppConfig.ConfFile,
+				LogFile:    AppConfig.LogFile,
+				Warnings:   AppConfig.PathWarnings(),
+				System:     AppConfig.GetSysInfo(),
+			})
+		}
+
 		fmt.Println(i18n.T("cli.info.header"))
-		fmt.Println(i18n.T("cli.info.version"))
-		fmt.Println(fmt.Sprintf(i18n.T("cli.info.go_version"), "1.25.0+"))
-		fmt.Println(fmt.Sprintf(i18n.T("cli.info.arch"), i18n.T("cli.info.arch.value")))
+		build := buildinfo.Get()
+		fmt.Println(fmt.Sprintf(i18n.T("cli.info.version"), build.AppName, build))
+		fmt.Println(fmt.Sprintf(i18n.T("cli.info.go_version"), build.GoVersion))
+		fmt.Println(fmt.Sprintf(i18n.T("cli.info.arch"), build.Arch))
+		fmt.Println(fmt.Sprintf(i18n.T("cli.info.config"), AppConfig.ConfFile))
+		fmt.Println(fmt.Sprintf(i18n.T("cli.info.log"), AppConfig.LogFile))
+
+		// Предупреждения о вынужденной замене путей
+		for _, warning := range AppConfig.PathWarnings() {
+			fmt.Println("! " + warning)
+		}
+
+		for _, line := range tui.SysInfoSummary(AppConfig.GetSysInfo()) {
+			fmt.Println(line)
+		}
+		return nil
 	},
 }
 
@@ -27,6 +66,7 @@ func localizeInfoCommand() {
 
 func init() {
 	localizeInfoCommand()
+	addOutputFlag(infoCmd, &infoOutput)
 	// Добавляем команду info
 	rootCmd.AddCommand(infoCmd)
 }
@@ -0,0 +1,282 @@
+package args
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ipset"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ipsetOutput        string
+	ipsetEntriesOutput string
+	ipsetFamily        string
+	ipsetSources       []string
+	ipsetRefresh       string
+	ipsetReplace       bool
+	ipsetRemember      bool
+)
+
+// ipsetFamilies семейства адресов по значению флага --family
+var ipsetFamilies = map[string]string{"ipv4": ipset.FamilyIPv4, "ipv6": ipset.FamilyIPv6}
+
+// ipsetPeriod возвращает период обновления по аргументу never, hourly, daily или weekly
+func ipsetPeriod(value string) (string, error) {
+	if value == "never" {
+		return ipset.RefreshNever, nil
+	}
+	if value != ipset.RefreshNever && slices.Contains(ipset.RefreshPeriods, value) {
+		return value, nil
+	}
+	return "", usageError(fmt.Errorf(i18n.T("cli.ipset.error.period"), value))
+}
+
+// ipsetInfo возвращает сведения о списке на роутере; семейство по умолчанию — IPv4
+func ipsetInfo(name string) (ipset.Info, error) {
+	sets, err := ipset.Manager{}.List()
+	if err != nil {
+		return ipset.Info{}, err
+	}
+	for _, s := range sets {
+		if s.Name == name {
+			if s.Family == "" {
+				s.Family = ipset.FamilyIPv4
+			}
+			return s, nil
+		}
+	}
+	return ipset.Info{}, fmt.Errorf(i18n.T("cli.ipset.error.not_found"), name)
+}
+
+// ipsetCmd команда для вывода списков ipset
+var ipsetCmd = &cobra.Command{
+	Use:   "ipset",
+	Short: i18n.T("cli.ipset.short"),
+	Long:  i18n.T("cli.ipset.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(ipsetOutput); err != nil {
+			return err
+		}
+
+		sets, err := ipset.Manager{}.List()
+		if err != nil {
+			return err
+		}
+		if ipsetOutput == outputJSON {
+			return printJSON(sets)
+		}
+		for _, s := range sets {
+			fmt.Println(i18n.T("ipset.line", s.Name, s.Type, s.Entries))
+		}
+		return nil
+	},
+}
+
+// ipsetRefreshCmd команда для обновления списков терема из их источников (вызывается из cron)
+var ipsetRefreshCmd = &cobra.Command{
+	Use:   "refresh [name...]",
+	Short: i18n.T("cli.ipset.refresh.short"),
+	Long:  i18n.T("cli.ipset.refresh.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := ipset.Manager{}
+		var errs []error
+		for _, set := range AppConfig.Conf.IPSets {
+			if len(args) > 0 && !slices.Contains(args, set.Name) {
+				continue
+			}
+			res, err := m.Refresh(context.Background(), ipset.Importer{}, set.Name, set.Family, set.Sources)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			AppConfig.Log.Info(i18n.T("ipset.log.refreshed"), set.Name, res.Count)
+			fmt.Println(tui.IPSetResultLine(set.Name, res))
+		}
+		for _, name := range args {
+			if _, ok := AppConfig.Conf.IPSet(name); !ok {
+				errs = append(errs, fmt.Errorf(i18n.T("ipset.error.unknown"), name))
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// ipsetRestoreCmd команда для восстановления сохранённых списков
+var ipsetRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: i18n.T("cli.ipset.restore.short"),
+	Long:  i18n.T("cli.ipset.restore.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		restored, err := ipset.Manager{}.Restore()
+		fmt.Println(i18n.T("ipset.restore.done", restored))
+		return err
+	},
+}
+
+// ipsetEntriesCmd команда для вывода записей списка
+var ipsetEntriesCmd = &cobra.Command{
+	Use:   "entries <name>",
+	Short: i18n.T("cli.ipset.entries.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(ipsetEntriesOutput); err != nil {
+			return err
+		}
+		entries, err := ipset.Manager{}.Entries(args[0])
+		if err != nil {
+			return err
+		}
+		if ipsetEntriesOutput == outputJSON {
+			return printJSON(entries)
+		}
+		for _, entry := range entries {
+			fmt.Println(entry)
+		}
+		return nil
+	},
+}
+
+// ipsetCreateCmd команда для создания списка терема
+var ipsetCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: i18n.T("cli.ipset.create.short"),
+	Long:  i18n.T("cli.ipset.create.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		family, ok := ipsetFamilies[ipsetFamily]
+		if !ok {
+			return usageError(fmt.Errorf(i18n.T("cli.ipset.error.family"), ipsetFamily))
+		}
+		period, err := ipsetPeriod(ipsetRefresh)
+		if err != nil {
+			return err
+		}
+		if err := ipset.ValidateName(args[0]); err != nil {
+			return usageError(err)
+		}
+		set := conf.IPSetConfig{Name: args[0], Family: family, Sources: ipsetSources, Refresh: period}
+		res, err := AppConfig.CreateIPSet(ipset.Manager{}, set)
+		if err != nil {
+			return err
+		}
+		fmt.Println(tui.IPSetResultLine(set.Name, res))
+		return nil
+	},
+}
+
+// ipsetEntryCmd возвращает команду для добавления или удаления записи списка
+func ipsetEntryCmd(use string, add bool) *cobra.Command {
+	return &cobra.Command{
+		Use:  use + " <name> <entry>",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := ipsetInfo(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = AppConfig.EditIPSetEntry(ipset.Manager{}, info.Name, info.Family, args[1], add)
+			return err
+		},
+	}
+}
+
+var (
+	ipsetAddCmd    = ipsetEntryCmd("add", true)
+	ipsetRemoveCmd = ipsetEntryCmd("remove", false)
+)
+
+// ipsetImportCmd команда для импорта записей из файла или по адресу HTTP
+var ipsetImportCmd = &cobra.Command{
+	Use:   "import <name> <source>",
+	Short: i18n.T("cli.ipset.import.short"),
+	Long:  i18n.T("cli.ipset.import.long"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := ipsetInfo(args[0])
+		if err != nil {
+			return err
+		}
+		res, err := AppConfig.ImportIPSet(ipset.Manager{}, info.Name, info.Family, args[1], ipsetReplace, ipsetRemember)
+		if err != nil {
+			return err
+		}
+		fmt.Println(tui.IPSetResultLine(info.Name, res))
+		return nil
+	},
+}
+
+// ipsetScheduleCmd команда для выбора периода автоматического обновления списка
+var ipsetScheduleCmd = &cobra.Command{
+	Use:       "schedule <name> <never|hourly|daily|weekly>",
+	Short:     i18n.T("cli.ipset.schedule.short"),
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"never", ipset.RefreshHourly, ipset.RefreshDaily, ipset.RefreshWeekly},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		period, err := ipsetPeriod(args[1])
+		if err != nil {
+			return err
+		}
+		info, err := ipsetInfo(args[0])
+		if err != nil {
+			return err
+		}
+		return AppConfig.ScheduleIPSet(ipset.Manager{}, info.Name, info.Family, period)
+	},
+}
+
+// ipsetDestroyCmd команда для удаления списка вместе с расписанием и сохранённой копией
+var ipsetDestroyCmd = &cobra.Command{
+	Use:   "destroy <name>",
+	Short: i18n.T("cli.ipset.destroy.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ipset.ValidateName(args[0]); err != nil {
+			return usageError(err)
+		}
+		if err := confirm(i18n.T("ipset.destroy.question", args[0])); err != nil {
+			return err
+		}
+		_, err := AppConfig.DestroyIPSet(ipset.Manager{}, args[0])
+		return err
+	},
+}
+
+func localizeIPSetCommand() {
+	ipsetCmd.Short = i18n.T("cli.ipset.short")
+	ipsetCmd.Long = i18n.T("cli.ipset.long")
+	ipsetRefreshCmd.Short = i18n.T("cli.ipset.refresh.short")
+	ipsetRefreshCmd.Long = i18n.T("cli.ipset.refresh.long")
+	ipsetRestoreCmd.Short = i18n.T("cli.ipset.restore.short")
+	ipsetRestoreCmd.Long = i18n.T("cli.ipset.restore.long")
+	ipsetEntriesCmd.Short = i18n.T("cli.ipset.entries.short")
+	ipsetCreateCmd.Short = i18n.T("cli.ipset.create.short")
+	ipsetCreateCmd.Long = i18n.T("cli.ipset.create.long")
+	ipsetAddCmd.Short = i18n.T("cli.ipset.add.short")
+	ipsetRemoveCmd.Short = i18n.T("cli.ipset.remove.short")
+	ipsetImportCmd.Short = i18n.T("cli.ipset.import.short")
+	ipsetImportCmd.Long = i18n.T("cli.ipset.import.long")
+	ipsetScheduleCmd.Short = i18n.T("cli.ipset.schedule.short")
+	ipsetDestroyCmd.Short = i18n.T("cli.ipset.destroy.short")
+}
+
+func init() {
+	localizeIPSetCommand()
+	addOutputFlag(ipsetCmd, &ipsetOutput)
+	addOutputFlag(ipsetEntriesCmd, &ipsetEntriesOutput)
+	ipsetCreateCmd.Flags().StringVar(&ipsetFamily, "family", "ipv4", "address family (ipv4, ipv6)")
+	ipsetCreateCmd.Flags().StringSliceVar(&ipsetSources, "source", nil, "URL or file on the router to fill the list from; repeat for several sources")
+	ipsetCreateCmd.Flags().StringVar(&ipsetRefresh, "refresh", "never", "refresh period (never, hourly, daily, weekly)")
+	ipsetImportCmd.Flags().BoolVar(&ipsetReplace, "replace", false, "replace the list contents instead of appending")
+	ipsetImportCmd.Flags().BoolVar(&ipsetRemember, "remember", false, "keep the source for scheduled refresh")
+
+	// Добавляем команду ipset
+	ipsetCmd.AddCommand(ipsetEntriesCmd, ipsetCreateCmd, ipsetAddCmd, ipsetRemoveCmd, ipsetImportCmd,
+		ipsetRefreshCmd, ipsetScheduleCmd, ipsetDestroyCmd, ipsetRestoreCmd)
+	rootCmd.AddCommand(ipsetCmd)
+}
@@ -0,0 +1,28 @@
+package args
+
+import (
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// netCmd группа неинтерактивных сетевых команд
+var netCmd = &cobra.Command{
+	Use:   "net",
+	Short: i18n.T("cli.net.short"),
+	Long:  i18n.T("cli.net.long"),
+}
+
+func localizeNetCommand() {
+	netCmd.Short = i18n.T("cli.net.short")
+	netCmd.Long = i18n.T("cli.net.long")
+	localizeNetIfacesCommand()
+	localizeNetDNSCommand()
+	localizeNetDiagCommand()
+	localizeNetPortsCommand()
+}
+
+func init() {
+	localizeNetCommand()
+	// Добавляем группу команд net
+	rootCmd.AddCommand(netCmd)
+}
@@ -0,0 +1,297 @@
+package args
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/netdiag"
+	"github.com/spf13/cobra"
+)
+
+var (
+	netDiagOutput string
+
+	netPingCount    int
+	netPingInterval time.Duration
+	netPingTimeout  time.Duration
+	netPingSize     int
+
+	netTraceMaxHops int
+	netTraceProbes  int
+	netTraceTimeout time.Duration
+	netTraceNoNames bool
+
+	netMTRRounds   int
+	netMTRInterval time.Duration
+	netMTRMaxHops  int
+	netMTRTimeout  time.Duration
+
+	netLookupServer  string
+	netLookupTypes   []string
+	netLookupTimeout time.Duration
+
+	netServeListen string
+
+	netSpeedDuration  time.Duration
+	netSpeedDirection string
+)
+
+// interruptContext возвращает контекст, отменяемый по Ctrl+C или SIGTERM,
+// чтобы длительная проверка успела вывести итог
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// netPingCmd команда для отправки эхо-запросов ICMP
+var netPingCmd = &cobra.Command{
+	Use:   "ping <host>",
+	Short: i18n.T("cli.net.ping.short"),
+	Long:  i18n.T("cli.net.ping.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDiagOutput); err != nil {
+			return err
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+
+		p := netdiag.Pinger{Count: netPingCount, Interval: netPingInterval, Timeout: netPingTimeout, Size: netPingSize}
+		if netDiagOutput == outputText {
+			p.OnReply = func(r netdiag.Reply) { fmt.Println(tui.PingReplyLine(r)) }
+		}
+		stats, err := p.Run(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if netDiagOutput == outputJSON {
+			return printJSON(stats)
+		}
+		for _, line := range tui.PingSummary(stats) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// netTraceCmd команда для трассировки маршрута
+var netTraceCmd = &cobra.Command{
+	Use:     "trace <host>",
+	Aliases: []string{"traceroute"},
+	Short:   i18n.T("cli.net.trace.short"),
+	Long:    i18n.T("cli.net.trace.long"),
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDiagOutput); err != nil {
+			return err
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+
+		t := netdiag.Tracer{MaxHops: netTraceMaxHops, Probes: netTraceProbes, Timeout: netTraceTimeout, NoNames: netTraceNoNames}
+		if netDiagOutput == outputText {
+			fmt.Println(i18n.T("netdiag.trace.start", args[0], netTraceMaxHops))
+			t.OnHop = func(h netdiag.Hop) { fmt.Println(tui.HopLine(h)) }
+		}
+		trace, err := t.Run(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if netDiagOutput == outputJSON {
+			return printJSON(trace)
+		}
+		if !trace.Reached && ctx.Err() == nil {
+			fmt.Println(i18n.T("netdiag.trace.not_reached"))
+		}
+		return nil
+	},
+}
+
+// netMTRCmd команда для непрерывной трассировки
+var netMTRCmd = &cobra.Command{
+	Use:   "mtr <host>",
+	Short: i18n.T("cli.net.mtr.short"),
+	Long:  i18n.T("cli.net.mtr.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDiagOutput); err != nil {
+			return err
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+
+		m := netdiag.MTR{MaxHops: netMTRMaxHops, Rounds: netMTRRounds, Interval: netMTRInterval, Timeout: netMTRTimeout}
+		// На терминале таблица перерисовывается после каждого раунда
+		shown := 0
+		if netDiagOutput == outputText && isTerminal(os.Stdout) {
+			m.OnRound = func(round int, hops []netdiag.HopStats) {
+				if shown > 0 {
+					fmt.Printf("\033[%dA\033[J", shown)
+				}
+				lines := append([]string{i18n.T("netdiag.mtr.round", round)}, tui.MTRLines(hops)...)
+				fmt.Println(strings.Join(lines, "\n"))
+				shown = len(lines)
+			}
+		}
+		hops, err := m.Run(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if netDiagOutput == outputJSON {
+			return printJSON(hops)
+		}
+		if shown == 0 {
+			for _, line := range tui.MTRLines(hops) {
+				fmt.Println(line)
+			}
+		}
+		return nil
+	},
+}
+
+// netLookupCmd команда для запросов к DNS
+var netLookupCmd = &cobra.Command{
+	Use:   "lookup <name>",
+	Short: i18n.T("cli.net.lookup.short"),
+	Long:  i18n.T("cli.net.lookup.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDiagOutput); err != nil {
+			return err
+		}
+		result, err := netdiag.Resolver{Server: netLookupServer, Timeout: netLookupTimeout}.Lookup(context.Background(), args[0], netLookupTypes)
+		if err != nil {
+			return err
+		}
+		if netDiagOutput == outputJSON {
+			return printJSON(result)
+		}
+		for _, line := range tui.LookupLines(result) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// netServeCmd команда для запуска сервера замера скорости
+var netServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: i18n.T("cli.net.serve.short"),
+	Long:  i18n.T("cli.net.serve.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := interruptContext()
+		defer stop()
+
+		ln, err := net.Listen("tcp", netServeListen)
+		if err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("netdiag.serve.listening", ln.Addr()))
+		return netdiag.SpeedServer{OnSession: func(s netdiag.SpeedSession) {
+			fmt.Println(tui.SpeedSessionLine(s))
+		}}.Serve(ctx, ln)
+	},
+}
+
+// netSpeedCmd команда для замера скорости до сервера «terem net serve»
+var netSpeedCmd = &cobra.Command{
+	Use:   "speed <host[:port]>",
+	Short: i18n.T("cli.net.speed.short"),
+	Long:  i18n.T("cli.net.speed.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDiagOutput); err != nil {
+			return err
+		}
+		var modes []string
+		switch netSpeedDirection {
+		case "both":
+			modes = []string{netdiag.SpeedDownload, netdiag.SpeedUpload}
+		case netdiag.SpeedDownload, netdiag.SpeedUpload:
+			modes = []string{netSpeedDirection}
+		default:
+			return usageError(fmt.Errorf(i18n.T("netdiag.error.direction"), netSpeedDirection))
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+
+		test := netdiag.SpeedTest{Duration: netSpeedDuration}
+		if netDiagOutput == outputText {
+			test.OnProgress = func(mode string, elapsed time.Duration, bits float64) {
+				fmt.Println(tui.SpeedProgressLine(mode, elapsed, bits))
+			}
+		}
+		var results []netdiag.SpeedResult
+		for _, mode := range modes {
+			r, err := test.Run(ctx, args[0], mode)
+			if err != nil {
+				return err
+			}
+			results = append(results, r)
+			if netDiagOutput == outputText {
+				fmt.Println(tui.SpeedLine(r))
+			}
+		}
+		if netDiagOutput == outputJSON {
+			return printJSON(results)
+		}
+		return nil
+	},
+}
+
+func localizeNetDiagCommand() {
+	netPingCmd.Short = i18n.T("cli.net.ping.short")
+	netPingCmd.Long = i18n.T("cli.net.ping.long")
+	netTraceCmd.Short = i18n.T("cli.net.trace.short")
+	netTraceCmd.Long = i18n.T("cli.net.trace.long")
+	netMTRCmd.Short = i18n.T("cli.net.mtr.short")
+	netMTRCmd.Long = i18n.T("cli.net.mtr.long")
+	netLookupCmd.Short = i18n.T("cli.net.lookup.short")
+	netLookupCmd.Long = i18n.T("cli.net.lookup.long")
+	netServeCmd.Short = i18n.T("cli.net.serve.short")
+	netServeCmd.Long = i18n.T("cli.net.serve.long")
+	netSpeedCmd.Short = i18n.T("cli.net.speed.short")
+	netSpeedCmd.Long = i18n.T("cli.net.speed.long")
+}
+
+func init() {
+	localizeNetDiagCommand()
+	for _, cmd := range []*cobra.Command{netPingCmd, netTraceCmd, netMTRCmd, netLookupCmd, netSpeedCmd} {
+		addOutputFlag(cmd, &netDiagOutput)
+	}
+
+	netPingCmd.Flags().IntVarP(&netPingCount, "count", "c", 4, "number of echo requests")
+	netPingCmd.Flags().DurationVarP(&netPingInterval, "interval", "i", time.Second, "interval between requests")
+	netPingCmd.Flags().DurationVarP(&netPingTimeout, "timeout", "W", 2*time.Second, "time to wait for each reply")
+	netPingCmd.Flags().IntVarP(&netPingSize, "size", "s", 56, "payload size in bytes")
+
+	netTraceCmd.Flags().IntVarP(&netTraceMaxHops, "max-hops", "m", 30, "maximum number of hops")
+	netTraceCmd.Flags().IntVarP(&netTraceProbes, "probes", "q", 3, "probes per hop")
+	netTraceCmd.Flags().DurationVarP(&netTraceTimeout, "timeout", "W", 2*time.Second, "time to wait for each probe")
+	netTraceCmd.Flags().BoolVarP(&netTraceNoNames, "numeric", "n", false, "do not resolve hop names")
+
+	netMTRCmd.Flags().IntVarP(&netMTRRounds, "count", "c", 10, "number of rounds")
+	netMTRCmd.Flags().DurationVarP(&netMTRInterval, "interval", "i", time.Second, "interval between rounds")
+	netMTRCmd.Flags().IntVarP(&netMTRMaxHops, "max-hops", "m", 30, "maximum number of hops")
+	netMTRCmd.Flags().DurationVarP(&netMTRTimeout, "timeout", "W", time.Second, "time to wait for each probe")
+
+	netLookupCmd.Flags().StringVar(&netLookupServer, "server", "", "DNS server to query instead of the system resolver")
+	netLookupCmd.Flags().StringSliceVarP(&netLookupTypes, "type", "t", nil, "record types: "+strings.Join(netdiag.LookupTypes, ", "))
+	netLookupCmd.Flags().DurationVarP(&netLookupTimeout, "timeout", "W", 5*time.Second, "timeout for each query")
+
+	netServeCmd.Flags().StringVar(&netServeListen, "listen", ":"+strconv.Itoa(netdiag.SpeedPort), "address to listen on")
+
+	netSpeedCmd.Flags().DurationVarP(&netSpeedDuration, "duration", "d", 5*time.Second, "duration of each direction")
+	netSpeedCmd.Flags().StringVar(&netSpeedDirection, "direction", "both", "direction: download, upload or both")
+
+	netCmd.AddCommand(netPingCmd, netTraceCmd, netMTRCmd, netLookupCmd, netServeCmd, netSpeedCmd)
+}
@@ -0,0 +1,167 @@
+package args
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/dns"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	netDNSOutput  string
+	netDNSTimeout time.Duration
+	netDNSClear   bool
+)
+
+// netDNSCmd команда для вывода состояния DNS
+var netDNSCmd = &cobra.Command{
+	Use:   "dns",
+	Short: i18n.T("cli.net.dns.short"),
+	Long:  i18n.T("cli.net.dns.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDNSOutput); err != nil {
+			return err
+		}
+
+		st := dns.Manager{}.Detect()
+		if netDNSOutput == outputJSON {
+			return printJSON(st)
+		}
+		for _, line := range tui.DNSStateSummary(st) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// netDNSTestCmd команда для проверки вышестоящих DNS-серверов
+var netDNSTestCmd = &cobra.Command{
+	Use:   "test [name]",
+	Short: i18n.T("cli.net.dns.test.short"),
+	Long:  i18n.T("cli.net.dns.test.long"),
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netDNSOutput); err != nil {
+			return err
+		}
+		name := tui.DefaultTestName
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		upstreams := tui.TestUpstreams(dns.Manager{}.Detect())
+		if len(upstreams) == 0 {
+			return errors.New(i18n.T("dns.error.no_upstreams"))
+		}
+		results := dns.Tester{Timeout: netDNSTimeout}.TestAll(context.Background(), upstreams, name)
+
+		if netDNSOutput == outputJSON {
+			return printJSON(results)
+		}
+		for _, res := range results {
+			fmt.Println(tui.DNSResultLine(res))
+		}
+		return nil
+	},
+}
+
+// applyDNS применяет параметры DNS, изменённые функцией change, и сообщает о результате.
+// Резолвер перезапускается, поэтому применение подтверждается, как и другие опасные действия
+func applyDNS(change func(*dns.Settings) error) error {
+	m := dns.Manager{}
+	s := tui.CurrentDNSSettings(m)
+	if err := change(&s); err != nil {
+		return usageError(err)
+	}
+	if err := confirm(i18n.T("dns.apply.question")); err != nil {
+		return err
+	}
+	if err := AppConfig.ApplyDNS(m, s); err != nil {
+		return err
+	}
+	fmt.Println(i18n.T("dns.status.managed", dns.DropInFile))
+	return nil
+}
+
+// dnsListArgs проверяет, что указаны либо записи списка, либо флаг --clear
+func dnsListArgs(cmd *cobra.Command, args []string) error {
+	if (len(args) == 0) == !netDNSClear {
+		return errors.New(i18n.T("cli.net.dns.error.clear"))
+	}
+	return nil
+}
+
+// netDNSUpstreamsCmd команда для замены вышестоящих серверов
+var netDNSUpstreamsCmd = &cobra.Command{
+	Use:   "upstreams <server...>",
+	Short: i18n.T("cli.net.dns.upstreams.short"),
+	Long:  i18n.T("cli.net.dns.upstreams.long"),
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return applyDNS(func(s *dns.Settings) error {
+			upstreams, err := dns.ParseUpstreams(args)
+			s.Upstreams = upstreams
+			return err
+		})
+	},
+}
+
+// netDNSHostsCmd команда для замены локальных подмен имён
+var netDNSHostsCmd = &cobra.Command{
+	Use:   "hosts [name=ip...]",
+	Short: i18n.T("cli.net.dns.hosts.short"),
+	Long:  i18n.T("cli.net.dns.hosts.long"),
+	Args:  dnsListArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return applyDNS(func(s *dns.Settings) error {
+			hosts, err := dns.ParseHosts(args)
+			s.Hosts = hosts
+			return err
+		})
+	},
+}
+
+// netDNSForwardsCmd команда для замены правил пересылки запросов по доменам
+var netDNSForwardsCmd = &cobra.Command{
+	Use:   "forwards [domain=server...]",
+	Short: i18n.T("cli.net.dns.forwards.short"),
+	Long:  i18n.T("cli.net.dns.forwards.long"),
+	Args:  dnsListArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return applyDNS(func(s *dns.Settings) error {
+			forwards, err := dns.ParseForwards(args)
+			s.Forwards = forwards
+			return err
+		})
+	},
+}
+
+func localizeNetDNSCommand() {
+	netDNSCmd.Short = i18n.T("cli.net.dns.short")
+	netDNSCmd.Long = i18n.T("cli.net.dns.long")
+	netDNSTestCmd.Short = i18n.T("cli.net.dns.test.short")
+	netDNSTestCmd.Long = i18n.T("cli.net.dns.test.long")
+	netDNSUpstreamsCmd.Short = i18n.T("cli.net.dns.upstreams.short")
+	netDNSUpstreamsCmd.Long = i18n.T("cli.net.dns.upstreams.long")
+	netDNSHostsCmd.Short = i18n.T("cli.net.dns.hosts.short")
+	netDNSHostsCmd.Long = i18n.T("cli.net.dns.hosts.long")
+	netDNSForwardsCmd.Short = i18n.T("cli.net.dns.forwards.short")
+	netDNSForwardsCmd.Long = i18n.T("cli.net.dns.forwards.long")
+}
+
+func init() {
+	localizeNetDNSCommand()
+	addOutputFlag(netDNSCmd, &netDNSOutput)
+	addOutputFlag(netDNSTestCmd, &netDNSOutput)
+	netDNSTestCmd.Flags().DurationVar(&netDNSTimeout, "timeout", 5*time.Second, "timeout for each test query")
+	for _, cmd := range []*cobra.Command{netDNSHostsCmd, netDNSForwardsCmd} {
+		cmd.Flags().BoolVar(&netDNSClear, "clear", false, "remove all entries")
+	}
+	netDNSCmd.AddCommand(netDNSTestCmd, netDNSUpstreamsCmd, netDNSHostsCmd, netDNSForwardsCmd)
+	netCmd.AddCommand(netDNSCmd)
+}
@@ -0,0 +1,56 @@
+package args
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/netif"
+	"github.com/spf13/cobra"
+)
+
+var (
+	netIfacesOutput   string
+	netIfacesInterval time.Duration
+)
+
+// netIfacesCmd команда для вывода сетевых интерфейсов
+var netIfacesCmd = &cobra.Command{
+	Use:   "ifaces",
+	Short: i18n.T("cli.net.ifaces.short"),
+	Long:  i18n.T("cli.net.ifaces.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netIfacesOutput); err != nil {
+			return err
+		}
+
+		ifaces, err := netif.NewReader().Collect(netIfacesInterval)
+		if err != nil {
+			return err
+		}
+
+		if netIfacesOutput == outputJSON {
+			return printJSON(ifaces)
+		}
+		for _, iface := range ifaces {
+			fmt.Println(tui.InterfaceTitle(iface))
+			for _, line := range tui.InterfaceSummary(iface) {
+				fmt.Println("    " + line)
+			}
+		}
+		return nil
+	},
+}
+
+func localizeNetIfacesCommand() {
+	netIfacesCmd.Short = i18n.T("cli.net.ifaces.short")
+	netIfacesCmd.Long = i18n.T("cli.net.ifaces.long")
+}
+
+func init() {
+	localizeNetIfacesCommand()
+	addOutputFlag(netIfacesCmd, &netIfacesOutput)
+	netIfacesCmd.Flags().DurationVar(&netIfacesInterval, "interval", time.Second, "sampling interval for rx/tx rates")
+	netCmd.AddCommand(netIfacesCmd)
+}
@@ -0,0 +1,77 @@
+package args
+
+import (
+	"fmt"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+var (
+	netPortsOutput    string
+	netPortsListening bool
+	netPortsConntrack bool
+)
+
+// netPortsCmd команда для вывода портов и соединений
+var netPortsCmd = &cobra.Command{
+	Use:   "ports [filter...]",
+	Short: i18n.T("cli.net.ports.short"),
+	Long:  i18n.T("cli.net.ports.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(netPortsOutput); err != nil {
+			return err
+		}
+		filter, err := ports.ParseFilter(args)
+		if err != nil {
+			return usageError(err)
+		}
+		r := ports.Reader{}
+
+		if netPortsConntrack {
+			conns, err := r.Conntrack()
+			if err != nil {
+				return err
+			}
+			conns = filter.Conns(conns)
+			if netPortsOutput == outputJSON {
+				return printJSON(conns)
+			}
+			for _, c := range conns {
+				fmt.Println(tui.ConnLine(c))
+			}
+			return nil
+		}
+
+		sockets, err := r.Sockets()
+		if err != nil {
+			return err
+		}
+		sockets = filter.Sockets(sockets)
+		if netPortsListening {
+			sockets = ports.Listeners(sockets)
+		}
+		if netPortsOutput == outputJSON {
+			return printJSON(sockets)
+		}
+		for _, s := range sockets {
+			fmt.Println(tui.SocketLine(s))
+		}
+		return nil
+	},
+}
+
+func localizeNetPortsCommand() {
+	netPortsCmd.Short = i18n.T("cli.net.ports.short")
+	netPortsCmd.Long = i18n.T("cli.net.ports.long")
+}
+
+func init() {
+	localizeNetPortsCommand()
+	addOutputFlag(netPortsCmd, &netPortsOutput)
+	netPortsCmd.Flags().BoolVar(&netPortsListening, "listening", false, "show listening sockets only")
+	netPortsCmd.Flags().BoolVarP(&netPortsConntrack, "conntrack", "c", false, "show the conntrack table instead of sockets")
+	netCmd.AddCommand(netPortsCmd)
+}
@@ -10,8 +10,12 @@ var networkCmd = &cobra.Command{
 	Use:   "network",
 	Short: i18n.T("cli.network.short"),
 	Long:  i18n.T("cli.network.long"),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireTerminal(); err != nil {
+			return err
+		}
 		AppConfig.NetworkCategoryLoop()
+		return nil
 	},
 }
 
@@ -0,0 +1,47 @@
+package args
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// Форматы вывода команд
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// addOutputFlag добавляет команде флаг --output (text|json)
+func addOutputFlag(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVarP(target, "output", "o", outputText, "output format (text, json)")
+}
+
+// checkOutputFormat проверяет значение флага --output
+func checkOutputFormat(format string) error {
+	if format != outputText && format != outputJSON {
+		return usageError(fmt.Errorf(i18n.T("cli.error.output_format"), format))
+	}
+	return nil
+}
+
+// printJSON выводит значение в stdout в формате JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// parseOnOff разбирает аргумент on или off
+func parseOnOff(value string) (bool, error) {
+	switch value {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, usageError(fmt.Errorf(i18n.T("cli.error.on_off"), value))
+}
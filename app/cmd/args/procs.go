@@ -0,0 +1,154 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/procs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	procsOutput string
+	procsSort   string
+	procsLimit  int
+	procsApps   bool
+	procsSignal string
+)
+
+// procsInterval интервал замера загрузки процессора
+const procsInterval = 500 * time.Millisecond
+
+// procsCmd команда для вывода процессов
+var procsCmd = &cobra.Command{
+	Use:   "procs [name]",
+	Short: i18n.T("cli.procs.short"),
+	Long:  i18n.T("cli.procs.long"),
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(procsOutput); err != nil {
+			return err
+		}
+		s, err := procs.Reader{}.Sample(procsInterval)
+		if err != nil {
+			return err
+		}
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		list := procs.Filter(s.Procs, name)
+
+		if procsApps {
+			groups := procs.GroupByName(list)
+			if procsLimit > 0 && len(groups) > procsLimit {
+				groups = groups[:procsLimit]
+			}
+			if procsOutput == outputJSON {
+				return printJSON(groups)
+			}
+			fmt.Println(tui.MemoryLine(s))
+			for _, g := range groups {
+				fmt.Println(tui.GroupLine(g, s.Memory))
+			}
+			return nil
+		}
+
+		if err := procs.Sort(list, procsSort); err != nil {
+			return err
+		}
+		if procsLimit > 0 && len(list) > procsLimit {
+			list = list[:procsLimit]
+		}
+		if procsOutput == outputJSON {
+			s.Procs = list
+			return printJSON(s)
+		}
+		for _, line := range tui.ProcessLines(s, list, 0) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// procsKillCmd команда для отправки сигнала процессу
+var procsKillCmd = &cobra.Command{
+	Use:   "kill <pid>",
+	Short: i18n.T("cli.procs.kill.short"),
+	Long:  i18n.T("cli.procs.kill.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pid, err := strconv.Atoi(args[0])
+		if err != nil {
+			return usageError(fmt.Errorf(i18n.T("procs.error.pid"), args[0]))
+		}
+		name := procName(pid)
+		if err := confirm(i18n.T("procs.confirm.kill", name, pid)); err != nil {
+			return err
+		}
+		if err := procs.Kill(pid, procsSignal); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("procs.log.killed"), procsSignal, name, pid)
+		return nil
+	},
+}
+
+// procsReniceCmd команда для смены приоритета процесса
+var procsReniceCmd = &cobra.Command{
+	Use:   "renice <pid> <nice>",
+	Short: i18n.T("cli.procs.renice.short"),
+	Long:  i18n.T("cli.procs.renice.long"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pid, err := strconv.Atoi(args[0])
+		if err != nil {
+			return usageError(fmt.Errorf(i18n.T("procs.error.pid"), args[0]))
+		}
+		nice, err := strconv.Atoi(args[1])
+		if err != nil {
+			return usageError(fmt.Errorf(i18n.T("procs.error.nice_value"), args[1]))
+		}
+		if err := procs.Renice(pid, nice); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("procs.log.reniced"), procName(pid), pid, nice)
+		return nil
+	},
+}
+
+// procName возвращает имя процесса для журнала или «?», если процесс не найден
+func procName(pid int) string {
+	s, err := procs.Reader{}.Snapshot()
+	if err != nil {
+		return "?"
+	}
+	if p, ok := procs.Find(s.Procs, pid); ok {
+		return p.Name
+	}
+	return "?"
+}
+
+func localizeProcsCommand() {
+	procsCmd.Short = i18n.T("cli.procs.short")
+	procsCmd.Long = i18n.T("cli.procs.long")
+	procsKillCmd.Short = i18n.T("cli.procs.kill.short")
+	procsKillCmd.Long = i18n.T("cli.procs.kill.long")
+	procsReniceCmd.Short = i18n.T("cli.procs.renice.short")
+	procsReniceCmd.Long = i18n.T("cli.procs.renice.long")
+}
+
+func init() {
+	localizeProcsCommand()
+	addOutputFlag(procsCmd, &procsOutput)
+	procsCmd.Flags().StringVarP(&procsSort, "sort", "s", procs.SortCPU, "sort key (cpu, mem, pid, name)")
+	procsCmd.Flags().IntVarP(&procsLimit, "number", "n", 0, "show at most this many processes (0 - all)")
+	procsCmd.Flags().BoolVarP(&procsApps, "apps", "a", false, "group processes by application")
+	procsKillCmd.Flags().StringVarP(&procsSignal, "signal", "s", "TERM", "signal to send (TERM, HUP, INT, KILL)")
+
+	procsCmd.AddCommand(procsKillCmd, procsReniceCmd)
+	rootCmd.AddCommand(procsCmd)
+}
@@ -0,0 +1,186 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	proxyOutput  string
+	proxyListen  string
+	proxyPort    int
+	proxySubnets []string
+	proxyUsers   []string
+	proxyNoAuth  bool
+	proxyStdin   bool
+)
+
+// proxyStatus состояние прокси-сервера для вывода в JSON
+type proxyStatus struct {
+	ID          string   `json:"id"`
+	Installed   bool     `json:"installed"`
+	Running     bool     `json:"running"`
+	Listen      string   `json:"listen,omitempty"`
+	Port        int      `json:"port,omitempty"`
+	Subnets     []string `json:"subnets,omitempty"`
+	Users       []string `json:"users,omitempty"`
+	Connections int      `json:"connections"`
+	Config      string   `json:"config"`
+}
+
+// proxyKind возвращает прокси-сервер по идентификатору из аргумента
+func proxyKind(id string) (proxy.Kind, error) {
+	if kind, ok := proxy.Find(id); ok {
+		return kind, nil
+	}
+	ids := make([]string, 0, len(proxy.Kinds))
+	for _, k := range proxy.Kinds {
+		ids = append(ids, k.ID)
+	}
+	return proxy.Kind{}, usageError(fmt.Errorf(i18n.T("cli.proxy.error.kind"), id, strings.Join(ids, ", ")))
+}
+
+// proxyCmd команда для вывода состояния прокси-серверов
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: i18n.T("cli.proxy.short"),
+	Long:  i18n.T("cli.proxy.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(proxyOutput); err != nil {
+			return err
+		}
+		statuses := make([]proxyStatus, 0, len(proxy.Kinds))
+		for _, kind := range proxy.Kinds {
+			m := proxy.Manager{Kind: kind}
+			s := AppConfig.CurrentProxySettings(m)
+			st := m.Status(s.Port)
+			status := proxyStatus{ID: kind.ID, Installed: st.Installed, Running: st.Running, Connections: st.Connections, Config: kind.ConfigPath}
+			if st.Installed {
+				status.Listen, status.Port, status.Subnets, status.Users = s.ListenAddr, s.Port, s.AllowedSubnets, s.UserNames()
+			}
+			statuses = append(statuses, status)
+
+			if proxyOutput == outputText {
+				fmt.Printf("%s (%s):\n", kind.Title, kind.ID)
+				if !st.Installed {
+					fmt.Println("  " + i18n.T("proxy.status.state", i18n.T("service.state.not_installed")))
+					continue
+				}
+				for _, line := range tui.ProxyStatusLines(m, s, st) {
+					fmt.Println("  " + line)
+				}
+			}
+		}
+		if proxyOutput == outputJSON {
+			return printJSON(statuses)
+		}
+		return nil
+	},
+}
+
+// proxySetCmd команда для установки и настройки прокси-сервера
+var proxySetCmd = &cobra.Command{
+	Use:   "set <kind>",
+	Short: i18n.T("cli.proxy.set.short"),
+	Long:  i18n.T("cli.proxy.set.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind, err := proxyKind(args[0])
+		if err != nil {
+			return err
+		}
+		m := proxy.Manager{Kind: kind}
+		s := AppConfig.CurrentProxySettings(m)
+		if cmd.Flags().Changed("listen") {
+			s.ListenAddr = proxyListen
+		}
+		if cmd.Flags().Changed("port") {
+			s.Port = proxyPort
+		}
+		if cmd.Flags().Changed("subnets") {
+			s.AllowedSubnets = proxySubnets
+		}
+		switch {
+		case proxyNoAuth:
+			s.Users = nil
+		case cmd.Flags().Changed("user"):
+			if !proxyStdin {
+				return usageError(errors.New(i18n.T("cli.proxy.error.password_stdin")))
+			}
+			passwords, err := readPasswords(len(proxyUsers))
+			if err != nil {
+				return err
+			}
+			s.Users = make([]proxy.User, len(proxyUsers))
+			for i, name := range proxyUsers {
+				s.Users[i] = proxy.User{Name: name, Password: passwords[i]}
+			}
+		}
+		if err := s.Validate(kind); err != nil {
+			return usageError(err)
+		}
+
+		if err := tui.CheckPorts(m.Service(), s.Ports()); err != nil {
+			return err
+		}
+		if svc := m.Service(); !svc.Installed() {
+			AppConfig.Log.Info(i18n.T("proxy.log.install"), kind.ID)
+			if err := svc.Install(); err != nil {
+				return err
+			}
+		}
+		if err := m.Apply(s); err != nil {
+			AppConfig.Log.Error(i18n.T("proxy.log.apply_failed"), err)
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("proxy.log.applied"), kind.ID, s.ListenAddr, s.Port)
+		for _, line := range tui.ProxyStatusLines(m, s, m.Status(s.Port)) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// proxyRestartCmd команда для перезапуска прокси-сервера
+var proxyRestartCmd = &cobra.Command{
+	Use:   "restart <kind>",
+	Short: i18n.T("cli.proxy.restart.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind, err := proxyKind(args[0])
+		if err != nil {
+			return err
+		}
+		return proxy.Manager{Kind: kind}.Service().Restart()
+	},
+}
+
+func localizeProxyCommand() {
+	proxyCmd.Short = i18n.T("cli.proxy.short")
+	proxyCmd.Long = i18n.T("cli.proxy.long")
+	proxySetCmd.Short = i18n.T("cli.proxy.set.short")
+	proxySetCmd.Long = i18n.T("cli.proxy.set.long")
+	proxyRestartCmd.Short = i18n.T("cli.proxy.restart.short")
+}
+
+func init() {
+	localizeProxyCommand()
+	addOutputFlag(proxyCmd, &proxyOutput)
+	proxySetCmd.Flags().StringVar(&proxyListen, "listen", "", "address to accept connections on")
+	proxySetCmd.Flags().IntVarP(&proxyPort, "port", "p", 0, "proxy port")
+	proxySetCmd.Flags().StringSliceVar(&proxySubnets, "subnets", nil, "subnets allowed to connect, comma-separated CIDRs")
+	proxySetCmd.Flags().StringSliceVar(&proxyUsers, "user", nil, "user allowed to connect; repeat for several users")
+	proxySetCmd.Flags().BoolVar(&proxyStdin, "password-stdin", false, "read the passwords of --user from stdin, one per line in the same order")
+	proxySetCmd.Flags().BoolVar(&proxyNoAuth, "no-auth", false, "disable authentication")
+	proxySetCmd.MarkFlagsMutuallyExclusive("user", "no-auth")
+
+	proxyCmd.AddCommand(proxySetCmd, proxyRestartCmd)
+	rootCmd.AddCommand(proxyCmd)
+}
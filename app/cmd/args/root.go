@@ -24,9 +24,26 @@ var rootCmd = &cobra.Command{
 	Use:   "terem",
 	Short: i18n.T("cli.root.short"),
 	Long:  i18n.T("cli.root.long"),
-	Run: func(cmd *cobra.Command, args []string) {
+	// Флаги и аргументы уже разобраны: дальнейшие ошибки относятся к работе команды,
+	// и справка по ней не выводится. Обязательные флаги cobra проверяет после этого
+	// вызова, поэтому они проверяются здесь, чтобы остаться ошибками вызова
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return err
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Запускаем интерактивный режим, в случае если запущена без аргументов
 		if len(args) == 0 {
+			// Без терминала меню не показать: подсказываем подкоманды вместо зависания
+			if err := requireTerminal(); err != nil {
+				return err
+			}
 
 			// Запускаем главный цикл с автоматической проверкой контекста
 			AppConfig.ContextualLoop(func() bool {
@@ -50,6 +67,7 @@ var rootCmd = &cobra.Command{
 				return true // продолжить главный цикл
 			}, i18n.T("loop.main"))
 		}
+		return nil
 	},
 }
 
@@ -60,6 +78,25 @@ func localizeRoot() {
 	localizeNetworkCommand()
 	localizeDebugCommand()
 	localizeInfoCommand()
+	localizeDiagCommand()
+	localizeDoctorCommand()
+	localizeNetCommand()
+	localizeClientsCommand()
+	localizeFirewallCommand()
+	localizeIPSetCommand()
+	localizeVPNCommand()
+	localizeRouteCommand()
+	localizeProcsCommand()
+	localizeStorageCommand()
+	localizeSwapCommand()
+	localizeCronCommand()
+	localizeUpdateCommand()
+	localizeVersionCommand()
+	localizeSSHCommand()
+	localizeProxyCommand()
+	localizeAdGuardCommand()
+	localizeSettingsCommand()
+	localizeActionsCommand()
 }
 
 func applyLanguageOverride() {
@@ -75,24 +112,29 @@ func applyLanguageOverride() {
 	if AppConfig != nil {
 		AppConfig.Language = i18n.Language()
 		AppConfig.Conf.SetLanguage(AppConfig.Language)
-		AppConfig.AppTitle = i18n.T("app.title")
+		AppConfig.SetTitle()
 	}
 
 	localizeRoot()
 }
 
-// Execute запускает командную строку
-func Execute(ac *tui.AppConfig) {
+// Execute запускает командную строку и возвращает код завершения; os.Exit вызывает main,
+// чтобы перед выходом успеть закрыть логгер
+func Execute(ac *tui.AppConfig) int {
 	ac.Log.Info(i18n.T("cli.root.log.start"))
 	AppConfig = ac // передаем конфигурацию
 	localizeRoot() // локализация команды root
-	if err := rootCmd.Execute(); err != nil {
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return exitCode(cmd, err)
 	}
+	return 0
 }
 
 func init() {
 	cobra.OnInitialize(applyLanguageOverride)
+	// Ошибки выводит Execute, чтобы они не дублировались
+	rootCmd.SilenceErrors = true
 	rootCmd.PersistentFlags().StringVarP(&languageFlag, "lang", "l", "", "interface language (ru, en, tt)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "confirm dangerous actions without asking (required without a terminal)")
 }
@@ -0,0 +1,52 @@
+package args
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qzeleza/terem/cmd/tui"
+)
+
+// newTestConfig создаёт конфигурацию приложения с логом и настройками во временном каталоге
+func newTestConfig(t *testing.T) *tui.AppConfig {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	ac, err := tui.NewSetup("en", "terem", "dev", false, filepath.Join(dir, "terem.log"), filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	t.Cleanup(func() { _ = ac.Log.Close() })
+	return ac
+}
+
+func TestExecuteExitCodes(t *testing.T) {
+	ac := newTestConfig(t)
+	// Архив нельзя создать: родитель пути — обычный файл
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Команды cobra сохраняют SilenceUsage между вызовами, поэтому ошибки вызова
+	// проверяются раньше ошибок выполнения той же команды
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"unknown flag", []string{"diag", "bundle", "--bogus"}, exitUsage},
+		{"bad output format", []string{"version", "-o", "yaml"}, exitUsage},
+		{"missing required flag", []string{"route", "rule", "add", "--source", "192.168.1.10"}, exitUsage},
+		{"bundle write fails", []string{"diag", "bundle", "-o", filepath.Join(file, "x.tar.gz")}, exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd.SetArgs(tt.args)
+			if got := Execute(ac); got != tt.want {
+				t.Errorf("Execute(%v) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
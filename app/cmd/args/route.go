@@ -0,0 +1,276 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/clients"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/route"
+	"github.com/spf13/cobra"
+)
+
+var (
+	routeOutput string
+	routeTable  conf.RouteTableConfig
+	routeRule   conf.RouteRuleConfig
+	routeTarget string
+)
+
+// routeCmd команда для вывода политики маршрутизации терема и действующих правил ip rule
+var routeCmd = &cobra.Command{
+	Use:     "route",
+	Aliases: []string{"routing"},
+	Short:   i18n.T("cli.route.short"),
+	Long:    i18n.T("cli.route.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(routeOutput); err != nil {
+			return err
+		}
+		rules, err := route.Manager{}.Rules()
+		if err != nil {
+			return err
+		}
+		policy := tui.PolicyFor(AppConfig.Conf.Routing)
+		if routeOutput == outputJSON {
+			return printJSON(map[string]any{"policy": policy, "rules": rules})
+		}
+		for _, t := range policy.Tables {
+			fmt.Println(tui.RouteTableLabel(t))
+		}
+		for _, line := range AppConfig.RouteRuleLines() {
+			fmt.Println(line)
+		}
+		for _, line := range tui.IPRuleLines(rules) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// routeApplyCmd команда для применения политики из конфигурации (вызывается init-скриптом)
+var routeApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: i18n.T("cli.route.apply.short"),
+	Long:  i18n.T("cli.route.apply.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		routing := AppConfig.Conf.Routing
+		if err := (route.Manager{}).Apply(tui.PolicyFor(routing)); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("routing.log.applied"), len(routing.Tables), len(routing.Rules))
+		fmt.Println(i18n.T("routing.apply.done", len(routing.Tables), len(routing.Rules)))
+		return nil
+	},
+}
+
+// routeClearCmd команда для снятия политики: правил, маркировки и таблиц терема
+var routeClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: i18n.T("cli.route.clear.short"),
+	Long:  i18n.T("cli.route.clear.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := route.Manager{}
+		errs := []error{m.Clear()}
+		for _, t := range AppConfig.Conf.Routing.Tables {
+			errs = append(errs, m.Flush(t.ID))
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("routing.log.cleared"))
+		fmt.Println(i18n.T("routing.clear.done"))
+		return nil
+	},
+}
+
+// routePolicyCmd команда для вывода итоговой политики клиентов; аргумент отбирает клиентов
+// по части имени, адреса или MAC-адреса
+var routePolicyCmd = &cobra.Command{
+	Use:   "policy [client]",
+	Short: i18n.T("cli.route.policy.short"),
+	Long:  i18n.T("cli.route.policy.long"),
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(routeOutput); err != nil {
+			return err
+		}
+		list, effective, err := tui.ClientPolicy(route.Manager{})
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			query := strings.ToLower(args[0])
+			var filtered []clients.Client
+			var result []route.Effective
+			for i, c := range list {
+				if strings.Contains(strings.ToLower(c.Hostname+" "+c.IP+" "+c.MAC), query) {
+					filtered = append(filtered, c)
+					result = append(result, effective[i])
+				}
+			}
+			list, effective = filtered, result
+		}
+		if routeOutput == outputJSON {
+			if effective == nil {
+				effective = []route.Effective{}
+			}
+			return printJSON(effective)
+		}
+		for _, line := range tui.PolicyLines(&AppConfig.Conf, list, effective) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// routeTableCmd группа команд для таблиц политики
+var routeTableCmd = &cobra.Command{
+	Use:   "table",
+	Short: i18n.T("cli.route.table.short"),
+}
+
+// routeTableAddCmd команда для добавления таблицы политики
+var routeTableAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: i18n.T("cli.route.table.add.short"),
+	Long:  i18n.T("cli.route.table.add.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		table, err := AppConfig.AddRouteTable(route.Manager{}, routeTable)
+		if err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("routing.table.added", table.ID))
+		return nil
+	},
+}
+
+// routeTableDeleteCmd команда для удаления таблицы политики вместе с её правилами
+var routeTableDeleteCmd = &cobra.Command{
+	Use:   "delete <table>",
+	Short: i18n.T("cli.route.table.delete.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return usageError(fmt.Errorf(i18n.T("route.error.table_number"), args[0]))
+		}
+		if _, ok := AppConfig.Conf.RouteTable(id); !ok {
+			return fmt.Errorf(i18n.T("cli.route.error.table"), id)
+		}
+		if err := confirm(i18n.T("routing.delete.table", id)); err != nil {
+			return err
+		}
+		return AppConfig.RemoveRouteTable(route.Manager{}, id)
+	},
+}
+
+// routeRuleCmd группа команд для правил политики
+var routeRuleCmd = &cobra.Command{
+	Use:   "rule",
+	Short: i18n.T("cli.route.rule.short"),
+}
+
+// routeRuleAddCmd команда для добавления правила в конец политики
+var routeRuleAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: i18n.T("cli.route.rule.add.short"),
+	Long:  i18n.T("cli.route.rule.add.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rule := routeRule
+		if routeTarget == "main" {
+			rule.Table = route.TableMain
+		} else if id, err := strconv.Atoi(routeTarget); err == nil {
+			rule.Table = id
+		} else {
+			return usageError(fmt.Errorf(i18n.T("route.error.table_number"), routeTarget))
+		}
+		if rule.MAC != "" {
+			macs, err := clientMACs([]string{rule.MAC})
+			if err != nil {
+				return err
+			}
+			rule.MAC = macs[0]
+		}
+		if err := AppConfig.AddRouteRule(route.Manager{}, rule); err != nil {
+			return err
+		}
+		r := tui.PolicyFor(conf.RoutingConfig{Rules: []conf.RouteRuleConfig{rule}}).Rules[0]
+		fmt.Println(i18n.T("routing.rule.added", r.Match(), tui.TableTitle(&AppConfig.Conf, strconv.Itoa(r.Table))))
+		return nil
+	},
+}
+
+// routeRuleDeleteCmd команда для удаления правила политики по приоритету
+var routeRuleDeleteCmd = &cobra.Command{
+	Use:   "delete <priority>",
+	Short: i18n.T("cli.route.rule.delete.short"),
+	Long:  i18n.T("cli.route.rule.delete.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules := tui.PolicyFor(AppConfig.Conf.Routing).Rules
+		priority, err := strconv.Atoi(args[0])
+		index := priority - route.PriorityBase
+		if err != nil || index < 0 || index >= len(rules) {
+			return usageError(fmt.Errorf(i18n.T("cli.route.error.rule"), args[0]))
+		}
+		if err := confirm(i18n.T("routing.delete.rule", rules[index].Match())); err != nil {
+			return err
+		}
+		return AppConfig.RemoveRouteRule(route.Manager{}, index)
+	},
+}
+
+func localizeRouteCommand() {
+	routeCmd.Short = i18n.T("cli.route.short")
+	routeCmd.Long = i18n.T("cli.route.long")
+	routeApplyCmd.Short = i18n.T("cli.route.apply.short")
+	routeApplyCmd.Long = i18n.T("cli.route.apply.long")
+	routeClearCmd.Short = i18n.T("cli.route.clear.short")
+	routeClearCmd.Long = i18n.T("cli.route.clear.long")
+	routePolicyCmd.Short = i18n.T("cli.route.policy.short")
+	routePolicyCmd.Long = i18n.T("cli.route.policy.long")
+	routeTableCmd.Short = i18n.T("cli.route.table.short")
+	routeTableAddCmd.Short = i18n.T("cli.route.table.add.short")
+	routeTableAddCmd.Long = i18n.T("cli.route.table.add.long")
+	routeTableDeleteCmd.Short = i18n.T("cli.route.table.delete.short")
+	routeRuleCmd.Short = i18n.T("cli.route.rule.short")
+	routeRuleAddCmd.Short = i18n.T("cli.route.rule.add.short")
+	routeRuleAddCmd.Long = i18n.T("cli.route.rule.add.long")
+	routeRuleDeleteCmd.Short = i18n.T("cli.route.rule.delete.short")
+	routeRuleDeleteCmd.Long = i18n.T("cli.route.rule.delete.long")
+}
+
+func init() {
+	localizeRouteCommand()
+	addOutputFlag(routeCmd, &routeOutput)
+	addOutputFlag(routePolicyCmd, &routeOutput)
+
+	flags := routeTableAddCmd.Flags()
+	flags.IntVar(&routeTable.ID, "id", 0, "table number (0 - first free from 100)")
+	flags.StringVar(&routeTable.Name, "name", "", "table name")
+	flags.StringVar(&routeTable.Dev, "dev", "", "outgoing interface")
+	flags.StringVar(&routeTable.Via, "via", "", "gateway IPv4 address")
+
+	flags = routeRuleAddCmd.Flags()
+	flags.StringVar(&routeRule.Source, "source", "", "source address or subnet")
+	flags.StringVar(&routeRule.MAC, "mac", "", "client MAC address, IP address or name")
+	flags.StringVar(&routeRule.IPSet, "ipset", "", "ipset list of destination addresses")
+	flags.StringVar(&routeTarget, "table", "", "table number or main")
+	flags.StringVar(&routeRule.Note, "note", "", "rule note")
+	routeRuleAddCmd.MarkFlagsOneRequired("source", "mac", "ipset")
+	routeRuleAddCmd.MarkFlagsMutuallyExclusive("source", "mac", "ipset")
+	_ = routeRuleAddCmd.MarkFlagRequired("table")
+
+	// Добавляем команду route
+	routeTableCmd.AddCommand(routeTableAddCmd, routeTableDeleteCmd)
+	routeRuleCmd.AddCommand(routeRuleAddCmd, routeRuleDeleteCmd)
+	routeCmd.AddCommand(routeApplyCmd, routeClearCmd, routePolicyCmd, routeTableCmd, routeRuleCmd)
+	rootCmd.AddCommand(routeCmd)
+}
@@ -0,0 +1,150 @@
+package args
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	settingsOutput string
+	settingsLines  int
+)
+
+// logModes допустимые режимы записи лога
+var logModes = []string{conf.LogModeFile, conf.LogModeBuffered, conf.LogModeMemory}
+
+// settingsReport настройки терема для вывода в JSON
+type settingsReport struct {
+	Debug    bool   `json:"debug"`
+	LogMode  string `json:"logMode"`
+	LogFile  string `json:"logFile"`
+	Language string `json:"language"`
+	Config   string `json:"config"`
+}
+
+// saveSettings сохраняет настройки в файл конфигурации и перенастраивает логгер
+func saveSettings() error {
+	if err := AppConfig.Conf.Save(AppConfig.ConfFile); err != nil {
+		return fmt.Errorf(i18n.T("settings.error.save"), AppConfig.ConfFile, err)
+	}
+	return AppConfig.SetupLogger()
+}
+
+// settingsCmd команда для вывода настроек терема
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: i18n.T("cli.settings.short"),
+	Long:  i18n.T("cli.settings.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(settingsOutput); err != nil {
+			return err
+		}
+		report := settingsReport{
+			Debug:    AppConfig.Conf.DebugMode,
+			LogMode:  AppConfig.Conf.LogMode,
+			LogFile:  AppConfig.LogFile,
+			Language: AppConfig.Language,
+			Config:   AppConfig.ConfFile,
+		}
+		if settingsOutput == outputJSON {
+			return printJSON(report)
+		}
+		fmt.Println(i18n.T("cli.settings.show.debug", report.Debug))
+		fmt.Println(i18n.T("cli.settings.show.log_mode", i18n.T("settings.log_mode."+report.LogMode)))
+		fmt.Println(i18n.T("cli.settings.show.log_file", report.LogFile))
+		fmt.Println(i18n.T("cli.settings.show.language", report.Language))
+		fmt.Println(i18n.T("cli.settings.show.config", report.Config))
+		return nil
+	},
+}
+
+// settingsDebugCmd команда для включения и выключения отладочного лога
+var settingsDebugCmd = &cobra.Command{
+	Use:       "debug <on|off>",
+	Short:     i18n.T("cli.settings.debug.short"),
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		on, err := parseOnOff(args[0])
+		if err != nil {
+			return err
+		}
+		AppConfig.Debug = on
+		AppConfig.Conf.SetDebugMode(on)
+		if err := saveSettings(); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("settings.log.toggle"), on)
+		return nil
+	},
+}
+
+// settingsLogModeCmd команда для выбора режима записи лога
+var settingsLogModeCmd = &cobra.Command{
+	Use:       "log-mode <file|buffered|memory>",
+	Short:     i18n.T("cli.settings.log_mode.short"),
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: logModes,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !slices.Contains(logModes, args[0]) {
+			return usageError(fmt.Errorf(i18n.T("cli.settings.error.log_mode"), args[0], strings.Join(logModes, ", ")))
+		}
+		AppConfig.Conf.SetLogMode(args[0])
+		if err := saveSettings(); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("settings.log.log_mode"), i18n.T("settings.log_mode."+args[0]))
+		return nil
+	},
+}
+
+// settingsLogCmd команда для вывода последних записей из файла лога
+var settingsLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: i18n.T("cli.settings.log.short"),
+	Long:  i18n.T("cli.settings.log.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(AppConfig.LogFile)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(data) == 0 {
+			fmt.Println(i18n.T("settings.log_view.empty"))
+			return nil
+		}
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		if settingsLines > 0 && len(lines) > settingsLines {
+			lines = lines[len(lines)-settingsLines:]
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+func localizeSettingsCommand() {
+	settingsCmd.Short = i18n.T("cli.settings.short")
+	settingsCmd.Long = i18n.T("cli.settings.long")
+	settingsDebugCmd.Short = i18n.T("cli.settings.debug.short")
+	settingsLogModeCmd.Short = i18n.T("cli.settings.log_mode.short")
+	settingsLogCmd.Short = i18n.T("cli.settings.log.short")
+	settingsLogCmd.Long = i18n.T("cli.settings.log.long")
+}
+
+func init() {
+	localizeSettingsCommand()
+	addOutputFlag(settingsCmd, &settingsOutput)
+	settingsLogCmd.Flags().IntVarP(&settingsLines, "lines", "n", 20, "number of recent entries to show (0 - all)")
+
+	settingsCmd.AddCommand(settingsDebugCmd, settingsLogModeCmd, settingsLogCmd)
+	rootCmd.AddCommand(settingsCmd)
+}
@@ -0,0 +1,252 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/sshd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sshOutput     string
+	sshKeysOutput string
+	sshPort       int
+	sshAuth       string
+	sshRootLogin  string
+)
+
+// sshStatus состояние сервера OpenSSH для вывода в JSON
+type sshStatus struct {
+	sshd.Settings
+	Running bool `json:"running"`
+	Keys    int  `json:"keys"`
+}
+
+// sshInstalled проверяет, что openssh-server установлен, и подсказывает команду установки
+func sshInstalled(m sshd.Manager) error {
+	if !m.Service().Installed() {
+		return errors.New(i18n.T("cli.ssh.error.not_installed"))
+	}
+	return nil
+}
+
+// sshCmd команда для вывода состояния сервера OpenSSH
+var sshCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: i18n.T("cli.ssh.short"),
+	Long:  i18n.T("cli.ssh.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(sshOutput); err != nil {
+			return err
+		}
+		m := sshd.Manager{}
+		if err := sshInstalled(m); err != nil {
+			return err
+		}
+		s, _, err := m.Load()
+		if err != nil {
+			return err
+		}
+		keys, err := m.Keys()
+		if err != nil {
+			return err
+		}
+		running := m.Service().Running()
+
+		if sshOutput == outputJSON {
+			return printJSON(sshStatus{Settings: s, Running: running, Keys: len(keys)})
+		}
+		for _, line := range tui.SSHDSummary(s, running, len(keys)) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// sshSetCmd команда для смены порта, способа входа и политики для root
+var sshSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: i18n.T("cli.ssh.set.short"),
+	Long:  i18n.T("cli.ssh.set.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := sshd.Manager{}
+		if err := sshInstalled(m); err != nil {
+			return err
+		}
+		cur, _, err := m.Load()
+		if err != nil {
+			return err
+		}
+
+		next := cur
+		if cmd.Flags().Changed("port") {
+			next.Port = sshPort
+		}
+		if cmd.Flags().Changed("auth") {
+			next.Auth = sshAuth
+		}
+		if cmd.Flags().Changed("root-login") {
+			next.RootLogin = sshRootLogin
+		}
+		if err := next.Validate(); err != nil {
+			return err
+		}
+
+		keys, err := m.Keys()
+		if err != nil {
+			return err
+		}
+		if next.Port != cur.Port {
+			if err := tui.CheckPorts(m.Service(), next.Ports()); err != nil {
+				return err
+			}
+		}
+		if risks := sshd.LockoutRisks(cur, next, len(keys), sshd.CurrentSession()); len(risks) > 0 {
+			for _, risk := range risks {
+				fmt.Fprintln(os.Stderr, "! "+risk)
+			}
+			if err := confirm(i18n.T("sshd.confirm.question")); err != nil {
+				return err
+			}
+		}
+		if err := m.Apply(next); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("sshd.log.applied"), next.Port, next.Auth, next.RootLogin)
+		for _, line := range tui.SSHDSummary(next, m.Service().Running(), len(keys)) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// sshKeysCmd команда для вывода ключей из authorized_keys
+var sshKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: i18n.T("cli.ssh.keys.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(sshKeysOutput); err != nil {
+			return err
+		}
+		m := sshd.Manager{}
+		keys, err := m.Keys()
+		if err != nil {
+			return err
+		}
+		if sshKeysOutput == outputJSON {
+			return printJSON(keys)
+		}
+		fmt.Println(i18n.T("sshd.keys.file", m.KeysPath()))
+		for _, k := range keys {
+			fmt.Println(tui.KeyLine(k))
+		}
+		return nil
+	},
+}
+
+// sshAddKeyCmd команда для добавления открытого ключа в authorized_keys
+var sshAddKeyCmd = &cobra.Command{
+	Use:   "add-key <key>",
+	Short: i18n.T("cli.ssh.add_key.short"),
+	Long:  i18n.T("cli.ssh.add_key.long"),
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Ключ можно передать без кавычек: тип, ключ и комментарий приходят отдельными аргументами
+		key, err := sshd.Manager{}.AddKey(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("sshd.log.key_added"), key.Fingerprint)
+		fmt.Println(tui.KeyLine(key))
+		return nil
+	},
+}
+
+// sshRemoveKeyCmd команда для удаления ключа по отпечатку
+var sshRemoveKeyCmd = &cobra.Command{
+	Use:   "remove-key <fingerprint>",
+	Short: i18n.T("cli.ssh.remove_key.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := sshd.Manager{}
+		keys, err := m.Keys()
+		if err != nil {
+			return err
+		}
+		// Удаление последнего ключа при запрещённом входе по паролю закроет доступ
+		if len(keys) == 1 && keys[0].Fingerprint == args[0] {
+			if cur, _, err := m.Load(); err == nil && !cur.PasswordAllowed() {
+				fmt.Fprintln(os.Stderr, "! "+i18n.T("sshd.risk.last_key"))
+				if err := confirm(i18n.T("sshd.confirm.question")); err != nil {
+					return err
+				}
+			}
+		}
+		if err := m.RemoveKey(args[0]); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("sshd.log.key_removed"), args[0])
+		return nil
+	},
+}
+
+// sshInstallCmd команда для установки openssh-server
+var sshInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: i18n.T("cli.ssh.install.short"),
+	Long:  i18n.T("cli.ssh.install.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := sshd.Manager{}
+		if err := tui.CheckPorts(m.Service(), sshd.Settings{Port: sshPort}.Ports()); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("sshd.log.install"))
+		s, err := tui.InstallSSHD(m, sshPort)
+		if err != nil {
+			return err
+		}
+		keys, err := m.Keys()
+		if err != nil {
+			return err
+		}
+		for _, line := range tui.SSHDSummary(s, m.Service().Running(), len(keys)) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+func localizeSSHCommand() {
+	sshCmd.Short = i18n.T("cli.ssh.short")
+	sshCmd.Long = i18n.T("cli.ssh.long")
+	sshSetCmd.Short = i18n.T("cli.ssh.set.short")
+	sshSetCmd.Long = i18n.T("cli.ssh.set.long")
+	sshKeysCmd.Short = i18n.T("cli.ssh.keys.short")
+	sshAddKeyCmd.Short = i18n.T("cli.ssh.add_key.short")
+	sshAddKeyCmd.Long = i18n.T("cli.ssh.add_key.long")
+	sshRemoveKeyCmd.Short = i18n.T("cli.ssh.remove_key.short")
+	sshInstallCmd.Short = i18n.T("cli.ssh.install.short")
+	sshInstallCmd.Long = i18n.T("cli.ssh.install.long")
+}
+
+func init() {
+	localizeSSHCommand()
+	addOutputFlag(sshCmd, &sshOutput)
+	addOutputFlag(sshKeysCmd, &sshKeysOutput)
+	sshSetCmd.Flags().IntVarP(&sshPort, "port", "p", sshd.DefaultPort, "SSH port")
+	sshSetCmd.Flags().StringVar(&sshAuth, "auth", "", "login method: "+strings.Join(sshd.AuthModes, ", "))
+	sshSetCmd.Flags().StringVar(&sshRootLogin, "root-login", "", "root login policy: "+strings.Join(sshd.RootLogins, ", "))
+	sshInstallCmd.Flags().IntVarP(&sshPort, "port", "p", sshd.DefaultPort, "SSH port to use instead of the default one")
+
+	sshCmd.AddCommand(sshSetCmd, sshKeysCmd, sshAddKeyCmd, sshRemoveKeyCmd, sshInstallCmd)
+	rootCmd.AddCommand(sshCmd)
+}
@@ -0,0 +1,181 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storageOutput    string
+	storageThreshold int
+)
+
+// storageCmd команда для вывода накопителей
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: i18n.T("cli.storage.short"),
+	Long:  i18n.T("cli.storage.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(storageOutput); err != nil {
+			return err
+		}
+		devices, err := storage.Reader{}.Devices()
+		if err != nil {
+			return err
+		}
+		if storageOutput == outputJSON {
+			return printJSON(devices)
+		}
+		for _, line := range tui.StorageLines(devices) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// storageCheckCmd команда для проверки свободного места
+var storageCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: i18n.T("cli.storage.check.short"),
+	Long:  i18n.T("cli.storage.check.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(storageOutput); err != nil {
+			return err
+		}
+		threshold := storageThreshold
+		if threshold <= 0 {
+			threshold = AppConfig.FreeThreshold()
+		}
+		alerts, err := storage.Reader{}.Alerts(threshold)
+		if err != nil {
+			return err
+		}
+		if storageOutput == outputJSON {
+			if err := printJSON(alerts); err != nil {
+				return err
+			}
+		} else {
+			for _, a := range alerts {
+				fmt.Println(tui.AlertLine(a))
+			}
+		}
+		if len(alerts) > 0 {
+			return errors.New(i18n.T("storage.error.alerts", len(alerts), threshold))
+		}
+		return nil
+	},
+}
+
+// storageVolume возвращает раздел по имени (sda1 или /dev/sda1) или точке монтирования
+func storageVolume(name string) (storage.Volume, error) {
+	devices, err := storage.Reader{}.Devices()
+	if err != nil {
+		return storage.Volume{}, err
+	}
+	for _, d := range devices {
+		for _, v := range d.Volumes() {
+			if storage.DevicePath(v.Name) == storage.DevicePath(name) || (v.Mounted() && v.MountPoint == path.Clean(name)) {
+				return v, nil
+			}
+		}
+	}
+	return storage.Volume{}, fmt.Errorf(i18n.T("cli.storage.error.volume"), name)
+}
+
+// storageMountCmd команда для монтирования раздела
+var storageMountCmd = &cobra.Command{
+	Use:   "mount <volume> [point]",
+	Short: i18n.T("cli.storage.mount.short"),
+	Long:  i18n.T("cli.storage.mount.long"),
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := storageVolume(args[0])
+		if err != nil {
+			return err
+		}
+		if v.Mounted() {
+			return fmt.Errorf(i18n.T("cli.storage.error.mounted"), v.Name, v.MountPoint)
+		}
+		point := ""
+		if len(args) > 1 {
+			point = args[1]
+		}
+		if point, err = AppConfig.MountVolume(storage.Manager{}, v.Name, point); err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("storage.log.mounted", v.Name, point))
+		return nil
+	},
+}
+
+// storageUnmountCmd команда для отмонтирования раздела
+var storageUnmountCmd = &cobra.Command{
+	Use:   "unmount <volume|point>",
+	Short: i18n.T("cli.storage.unmount.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := storageVolume(args[0])
+		if err != nil {
+			return err
+		}
+		if !v.Mounted() {
+			return fmt.Errorf(i18n.T("cli.storage.error.unmounted"), v.Name)
+		}
+		if storage.Protected(v.MountPoint) {
+			return fmt.Errorf(i18n.T("storage.error.protected"), v.MountPoint)
+		}
+		if err := confirm(i18n.T("storage.confirm.unmount", v.Name, v.MountPoint)); err != nil {
+			return err
+		}
+		if err := AppConfig.UnmountVolume(storage.Manager{}, v); err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("storage.log.unmounted", v.Name, v.MountPoint))
+		return nil
+	},
+}
+
+// storageThresholdCmd команда для изменения порога свободного места
+var storageThresholdCmd = &cobra.Command{
+	Use:   "threshold <percent>",
+	Short: i18n.T("cli.storage.threshold.short"),
+	Long:  i18n.T("cli.storage.threshold.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		percent, err := AppConfig.SetFreeThreshold(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("storage.log.threshold", percent))
+		return nil
+	},
+}
+
+func localizeStorageCommand() {
+	storageCmd.Short = i18n.T("cli.storage.short")
+	storageCmd.Long = i18n.T("cli.storage.long")
+	storageCheckCmd.Short = i18n.T("cli.storage.check.short")
+	storageCheckCmd.Long = i18n.T("cli.storage.check.long")
+	storageMountCmd.Short = i18n.T("cli.storage.mount.short")
+	storageMountCmd.Long = i18n.T("cli.storage.mount.long")
+	storageUnmountCmd.Short = i18n.T("cli.storage.unmount.short")
+	storageThresholdCmd.Short = i18n.T("cli.storage.threshold.short")
+	storageThresholdCmd.Long = i18n.T("cli.storage.threshold.long")
+}
+
+func init() {
+	localizeStorageCommand()
+	addOutputFlag(storageCmd, &storageOutput)
+	addOutputFlag(storageCheckCmd, &storageOutput)
+	storageCheckCmd.Flags().IntVarP(&storageThreshold, "threshold", "t", 0, "free space threshold, % (0 - from config)")
+
+	storageCmd.AddCommand(storageCheckCmd, storageMountCmd, storageUnmountCmd, storageThresholdCmd)
+	rootCmd.AddCommand(storageCmd)
+}
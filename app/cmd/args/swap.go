@@ -0,0 +1,172 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/procs"
+	"github.com/qzeleza/terem/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	swapOutput  string
+	swapFile    string
+	swapSize    int
+	swapPersist bool
+)
+
+// swapCmd команда для вывода состояния подкачки
+var swapCmd = &cobra.Command{
+	Use:   "swap",
+	Short: i18n.T("cli.swap.short"),
+	Long:  i18n.T("cli.swap.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(swapOutput); err != nil {
+			return err
+		}
+		s, err := storage.Manager{}.SwapStatus(swapFile)
+		if err != nil {
+			return err
+		}
+		if swapOutput == outputJSON {
+			return printJSON(s)
+		}
+		for _, line := range tui.SwapLines(s) {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// swapCreateCmd команда для создания файла подкачки
+var swapCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: i18n.T("cli.swap.create.short"),
+	Long:  i18n.T("cli.swap.create.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size := uint64(swapSize) << 20
+		if swapSize <= 0 {
+			s, _ := procs.Reader{}.Snapshot()
+			size = storage.SuggestedSwapSize(s.Memory.Total)
+		}
+		m := storage.Manager{}
+		if err := m.CreateSwap(swapFile, size); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("storage.log.swap"), swapFile, size>>20)
+		if swapPersist {
+			return m.PersistSwap(swapFile, true)
+		}
+		return nil
+	},
+}
+
+// swapOnCmd команда для включения файла подкачки
+var swapOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: i18n.T("cli.swap.on.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := (storage.Manager{}).EnableSwap(swapFile); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("swap.log.enabled"), swapFile)
+		return nil
+	},
+}
+
+// swapOffCmd команда для выключения файла подкачки
+var swapOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: i18n.T("cli.swap.off.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := (storage.Manager{}).DisableSwap(swapFile); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("swap.log.disabled"), swapFile)
+		return nil
+	},
+}
+
+// swapResizeCmd команда для изменения размера файла подкачки
+var swapResizeCmd = &cobra.Command{
+	Use:   "resize <MB>",
+	Short: i18n.T("cli.swap.resize.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mb, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return usageError(fmt.Errorf(i18n.T("storage.error.swap_value"), args[0]))
+		}
+		if err := (storage.Manager{}).ResizeSwap(swapFile, mb<<20); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("swap.log.resized"), swapFile, mb)
+		return nil
+	},
+}
+
+// swapPersistCmd команда для включения или отключения автозапуска подкачки
+var swapPersistCmd = &cobra.Command{
+	Use:       "persist <on|off>",
+	Short:     i18n.T("cli.swap.persist.short"),
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[0] != "on" && args[0] != "off" {
+			return usageError(fmt.Errorf(i18n.T("cli.swap.error.persist"), args[0]))
+		}
+		on := args[0] == "on"
+		if err := (storage.Manager{}).PersistSwap(swapFile, on); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("swap.log.persist"), swapFile, args[0])
+		return nil
+	},
+}
+
+// swapRemoveCmd команда для удаления файла подкачки
+var swapRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: i18n.T("cli.swap.remove.short"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := confirm(i18n.T("swap.confirm.remove", swapFile)); err != nil {
+			return err
+		}
+		if err := (storage.Manager{}).RemoveSwap(swapFile); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("swap.log.removed"), swapFile)
+		return nil
+	},
+}
+
+func localizeSwapCommand() {
+	swapCmd.Short = i18n.T("cli.swap.short")
+	swapCmd.Long = i18n.T("cli.swap.long")
+	swapCreateCmd.Short = i18n.T("cli.swap.create.short")
+	swapCreateCmd.Long = i18n.T("cli.swap.create.long")
+	swapOnCmd.Short = i18n.T("cli.swap.on.short")
+	swapOffCmd.Short = i18n.T("cli.swap.off.short")
+	swapResizeCmd.Short = i18n.T("cli.swap.resize.short")
+	swapPersistCmd.Short = i18n.T("cli.swap.persist.short")
+	swapRemoveCmd.Short = i18n.T("cli.swap.remove.short")
+}
+
+func init() {
+	localizeSwapCommand()
+	addOutputFlag(swapCmd, &swapOutput)
+	swapCmd.PersistentFlags().StringVarP(&swapFile, "file", "f", storage.DefaultSwapPath, "swap file path")
+	swapCreateCmd.Flags().IntVarP(&swapSize, "size", "s", 0, "swap size, MB (0 - twice the RAM, up to 512)")
+	swapCreateCmd.Flags().BoolVarP(&swapPersist, "persist", "p", false, "enable the swap file at boot")
+
+	swapCmd.AddCommand(swapCreateCmd, swapOnCmd, swapOffCmd, swapResizeCmd, swapPersistCmd, swapRemoveCmd)
+	rootCmd.AddCommand(swapCmd)
+}
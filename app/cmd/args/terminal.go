@@ -0,0 +1,99 @@
+package args
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// Коды завершения терема
+const (
+	exitFailure    = 1 // Действие завершилось ошибкой
+	exitUsage      = 2 // Неверные аргументы или флаги
+	exitNoTerminal = 3 // Нужен терминал: меню или подтверждение без --yes
+)
+
+// assumeYes флаг --yes: подтверждать опасные действия без вопроса
+var assumeYes bool
+
+// exitError ошибка с заданным кодом завершения
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// usageError помечает ошибку в аргументах, найденную уже в самой команде, как ошибку вызова
+func usageError(err error) error {
+	return &exitError{code: exitUsage, err: err}
+}
+
+// exitCode возвращает код завершения для ошибки команды cmd. Ошибки разбора флагов
+// и аргументов (до rootCmd.PersistentPreRunE, который устанавливает cmd.SilenceUsage)
+// и ошибки, помеченные usageError, считаются ошибками вызова
+func exitCode(cmd *cobra.Command, err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	if cmd != nil && !cmd.SilenceUsage {
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// isTerminal сообщает, подключён ли файл к терминалу. Проверка режима файла не подходит:
+// /dev/null тоже символьное устройство
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd())
+}
+
+// requireTerminal возвращает ошибку, если stdin не терминал и интерактивное меню не запустить
+func requireTerminal() error {
+	if isTerminal(os.Stdin) {
+		return nil
+	}
+	return &exitError{code: exitNoTerminal, err: errors.New(i18n.T("cli.error.no_terminal"))}
+}
+
+// confirm запрашивает подтверждение опасного действия. С флагом --yes вопрос не задаётся,
+// а без терминала и без --yes действие не выполняется
+func confirm(question string) error {
+	if assumeYes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return &exitError{code: exitNoTerminal, err: fmt.Errorf(i18n.T("cli.error.confirm"), question)}
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "" && slices.Contains(strings.Split(i18n.T("cli.confirm.yes"), ","), answer) {
+		return nil
+	}
+	return errors.New(i18n.T("cli.error.cancelled"))
+}
+
+// readPasswords читает из stdin n паролей, по одному в строке. Пароли передаются через stdin,
+// чтобы они не попали в список процессов и историю команд
+func readPasswords(n int) ([]string, error) {
+	reader := bufio.NewReader(os.Stdin)
+	passwords := make([]string, 0, n)
+	for len(passwords) < n {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf(i18n.T("cli.error.passwords"), n, len(passwords))
+		}
+		passwords = append(passwords, strings.TrimRight(line, "\r\n"))
+	}
+	return passwords, nil
+}
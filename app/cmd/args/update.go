@@ -0,0 +1,120 @@
+package args
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/update"
+	"github.com/qzeleza/terem/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var (
+	updateOutput   string
+	updateFeed     string
+	updateCheck    bool
+	updateForce    bool
+	updateRollback bool
+)
+
+// updateStatus результат проверки обновления для вывода в JSON
+type updateStatus struct {
+	Current   string        `json:"current"`
+	Latest    string        `json:"latest"`
+	Date      string        `json:"date,omitempty"`
+	Arch      string        `json:"arch"`
+	Available bool          `json:"available"`
+	Notes     []update.Note `json:"notes,omitempty"`
+}
+
+// selfUpdateCmd команда для обновления терема из ленты выпусков
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: i18n.T("cli.update.short"),
+	Long:  i18n.T("cli.update.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(updateOutput); err != nil {
+			return err
+		}
+		u := AppConfig.Updater(updateFeed)
+		current := AppConfig.Version
+
+		if updateRollback {
+			if err := u.Rollback(); err != nil {
+				return err
+			}
+			AppConfig.Log.Info(i18n.T("update.log.rollback"), current)
+			fmt.Println(i18n.T("update.rolled_back"))
+			return nil
+		}
+
+		ctx := AppConfig.RootCtx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		release, err := u.Latest(ctx)
+		if err != nil {
+			return err
+		}
+		notes, err := u.Changes(ctx, release, current)
+		if err != nil {
+			// Журнал версий необязателен: без него обновление всё равно возможно
+			fmt.Fprintln(os.Stderr, i18n.T("update.warn.changelog", err))
+		}
+		available := version.Compare(release.Version, current) > 0
+
+		if updateOutput == outputJSON {
+			if err := printJSON(updateStatus{
+				Current:   current,
+				Latest:    release.Version,
+				Date:      release.Date,
+				Arch:      update.Arch(),
+				Available: available,
+				Notes:     notes,
+			}); err != nil {
+				return err
+			}
+		} else {
+			for _, line := range tui.UpdateLines(current, release, notes) {
+				fmt.Println(line)
+			}
+		}
+		if updateCheck || (!available && !updateForce) {
+			return nil
+		}
+
+		if err := confirm(i18n.T("update.confirm.install", release.Version)); err != nil {
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("update.log.check"), current)
+		if err := u.Install(ctx, release); err != nil {
+			AppConfig.Log.Error(i18n.T("update.log.failed"), err)
+			return err
+		}
+		AppConfig.Log.Info(i18n.T("update.log.installed"), current, release.Version)
+		if updateOutput != outputJSON {
+			fmt.Println(i18n.T("update.installed", release.Version))
+		}
+		return nil
+	},
+}
+
+func localizeUpdateCommand() {
+	selfUpdateCmd.Short = i18n.T("cli.update.short")
+	selfUpdateCmd.Long = i18n.T("cli.update.long")
+}
+
+func init() {
+	localizeUpdateCommand()
+	addOutputFlag(selfUpdateCmd, &updateOutput)
+	selfUpdateCmd.Flags().StringVar(&updateFeed, "feed", "", "release feed URL: http(s)://, file:// or a path (default: from config or the project releases)")
+	selfUpdateCmd.Flags().BoolVarP(&updateCheck, "check", "c", false, "only check for an update and show the changelog")
+	selfUpdateCmd.Flags().BoolVar(&updateForce, "force", false, "install the latest release even if it is not newer")
+	selfUpdateCmd.Flags().BoolVar(&updateRollback, "rollback", false, "restore the version replaced by the last update")
+
+	rootCmd.AddCommand(selfUpdateCmd)
+}
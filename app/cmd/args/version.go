@@ -0,0 +1,70 @@
+package args
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/qzeleza/terem/internal/buildinfo"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var versionOutput string
+
+// versionReport сведения о сборке и пакете opkg для вывода в JSON
+type versionReport struct {
+	buildinfo.Info
+	Package  string `json:"package,omitempty"`  // Версия установленного пакета opkg
+	Mismatch bool   `json:"mismatch,omitempty"` // Версия пакета не совпадает с запущенной
+}
+
+// versionCmd команда для вывода версии и сведений о сборке
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: i18n.T("cli.version.short"),
+	Long:  i18n.T("cli.version.long"),
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(versionOutput); err != nil {
+			return err
+		}
+		info := buildinfo.Get()
+		pkg, installed := buildinfo.PackageVersion(nil, info.AppName)
+		mismatch := installed && !buildinfo.Matches(pkg, info.Version)
+
+		if versionOutput == outputJSON {
+			return printJSON(versionReport{Info: info, Package: pkg, Mismatch: mismatch})
+		}
+
+		fmt.Printf("%s %s\n", info.AppName, info.Version)
+		if info.Commit != "" {
+			fmt.Println(i18n.T("cli.version.commit", info.Commit))
+		}
+		if info.Date != "" {
+			fmt.Println(i18n.T("cli.version.date", info.Date))
+		}
+		fmt.Println(i18n.T("cli.version.arch", info.Arch))
+		fmt.Println(i18n.T("cli.version.go", info.GoVersion))
+		if installed {
+			fmt.Println(i18n.T("cli.version.package", pkg))
+		} else {
+			fmt.Println(i18n.T("cli.version.package", i18n.T("cli.version.no_package")))
+		}
+		if mismatch {
+			fmt.Fprintln(os.Stderr, "! "+i18n.T("cli.version.mismatch", pkg, info.Version))
+		}
+		return nil
+	},
+}
+
+func localizeVersionCommand() {
+	versionCmd.Short = i18n.T("cli.version.short")
+	versionCmd.Long = i18n.T("cli.version.long")
+}
+
+func init() {
+	localizeVersionCommand()
+	addOutputFlag(versionCmd, &versionOutput)
+
+	rootCmd.AddCommand(versionCmd)
+}
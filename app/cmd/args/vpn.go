@@ -0,0 +1,341 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/clients"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/vpn"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vpnOutput    string
+	vpnAutostart bool
+	vpnAll       bool
+	vpnUser      string
+	vpnStdin     bool
+	vpnClients   []string
+	vpnIPSets    []string
+	vpnClear     bool
+)
+
+// vpnCmd команда для вывода состояния туннелей VPN
+var vpnCmd = &cobra.Command{
+	Use:   "vpn",
+	Short: i18n.T("cli.vpn.short"),
+	Long:  i18n.T("cli.vpn.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(vpnOutput); err != nil {
+			return err
+		}
+
+		m := vpn.Manager{}
+		statuses := make([]vpn.Status, 0, len(AppConfig.Conf.VPN))
+		for _, t := range AppConfig.Conf.VPN {
+			st, err := m.Status(t.Name, t.Kind)
+			if err != nil {
+				return err
+			}
+			statuses = append(statuses, st)
+		}
+		if vpnOutput == outputJSON {
+			return printJSON(statuses)
+		}
+		for i, t := range AppConfig.Conf.VPN {
+			for _, line := range tui.VPNStatusLines(t, statuses[i]) {
+				fmt.Println(line)
+			}
+		}
+		return nil
+	},
+}
+
+// selectTunnels возвращает туннели по именам; без имён — отобранные filter
+func selectTunnels(names []string, filter func(conf.VPNConfig) bool) ([]conf.VPNConfig, error) {
+	var selected []conf.VPNConfig
+	var errs []error
+	for _, name := range names {
+		t, ok := AppConfig.Conf.VPNTunnel(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf(i18n.T("vpn.error.unknown"), name))
+			continue
+		}
+		selected = append(selected, t)
+	}
+	if len(names) == 0 {
+		selected = slices.DeleteFunc(slices.Clone(AppConfig.Conf.VPN), func(t conf.VPNConfig) bool { return !filter(t) })
+	}
+	return selected, errors.Join(errs...)
+}
+
+// vpnTunnel возвращает туннель терема по имени
+func vpnTunnel(name string) (conf.VPNConfig, error) {
+	if t, ok := AppConfig.Conf.VPNTunnel(name); ok {
+		return t, nil
+	}
+	return conf.VPNConfig{}, fmt.Errorf(i18n.T("vpn.error.unknown"), name)
+}
+
+// clientMACs возвращает MAC-адреса клиентов, заданных MAC-адресом, IP-адресом или именем
+func clientMACs(values []string) ([]string, error) {
+	var known []clients.Client
+	macs := make([]string, 0, len(values))
+	for _, value := range values {
+		if hw, err := net.ParseMAC(value); err == nil {
+			macs = append(macs, hw.String())
+			continue
+		}
+		if known == nil {
+			known = clients.Collector{}.Collect()
+		}
+		i := slices.IndexFunc(known, func(c clients.Client) bool {
+			return c.IP == value || strings.EqualFold(c.Hostname, value)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf(i18n.T("cli.vpn.error.client"), value)
+		}
+		macs = append(macs, known[i].MAC)
+	}
+	return macs, nil
+}
+
+// vpnUpCmd команда для подъёма туннелей (вызывается init-скриптом с --autostart)
+var vpnUpCmd = &cobra.Command{
+	Use:   "up [name...]",
+	Short: i18n.T("cli.vpn.up.short"),
+	Long:  i18n.T("cli.vpn.up.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !vpnAutostart {
+			return usageError(errors.New(i18n.T("cli.vpn.error.no_names")))
+		}
+		tunnels, err := selectTunnels(args, func(t conf.VPNConfig) bool { return t.Autostart })
+		errs := []error{err}
+		m := vpn.Manager{}
+		for _, t := range tunnels {
+			if err := m.Up(tui.TunnelFor(t)); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			AppConfig.Log.Info(i18n.T("vpn.log.up"), t.Name)
+			fmt.Println(i18n.T("vpn.up.done", t.Name))
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// vpnDownCmd команда для остановки туннелей
+var vpnDownCmd = &cobra.Command{
+	Use:   "down [name...]",
+	Short: i18n.T("cli.vpn.down.short"),
+	Long:  i18n.T("cli.vpn.down.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !vpnAll {
+			return usageError(errors.New(i18n.T("cli.vpn.error.no_names")))
+		}
+		tunnels, err := selectTunnels(args, func(conf.VPNConfig) bool { return true })
+		errs := []error{err}
+		m := vpn.Manager{}
+		for _, t := range tunnels {
+			if err := m.Down(tui.TunnelFor(t)); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			AppConfig.Log.Info(i18n.T("vpn.log.down"), t.Name)
+			fmt.Println(i18n.T("vpn.down.done", t.Name))
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// vpnKeygenCmd команда для создания пары ключей WireGuard
+var vpnKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: i18n.T("cli.vpn.keygen.short"),
+	Long:  i18n.T("cli.vpn.keygen.long"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(vpnOutput); err != nil {
+			return err
+		}
+		private, public, err := vpn.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if vpnOutput == outputJSON {
+			return printJSON(map[string]string{"privateKey": private, "publicKey": public})
+		}
+		fmt.Println(i18n.T("vpn.keys.private", private))
+		fmt.Println(i18n.T("vpn.keys.public", public))
+		return nil
+	},
+}
+
+// vpnImportCmd команда для создания туннеля из профиля WireGuard или OpenVPN
+var vpnImportCmd = &cobra.Command{
+	Use:   "import <name> <file>",
+	Short: i18n.T("cli.vpn.import.short"),
+	Long:  i18n.T("cli.vpn.import.long"),
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		auth := vpn.Credentials{User: vpnUser}
+		if vpnStdin {
+			passwords, err := readPasswords(1)
+			if err != nil {
+				return err
+			}
+			auth.Password = passwords[0]
+		}
+		t, err := AppConfig.ImportVPN(vpn.Manager{}, args[0], args[1], auth)
+		if err != nil {
+			return err
+		}
+		kind := t.Kind
+		if k, ok := vpn.FindKind(t.Kind); ok {
+			kind = k.Title
+		}
+		fmt.Println(i18n.T("vpn.import.done", t.Name, kind))
+		return nil
+	},
+}
+
+// vpnRoutingCmd команда для выбора клиентов и списков ipset, выходящих в интернет через туннель
+var vpnRoutingCmd = &cobra.Command{
+	Use:   "routing <name>",
+	Short: i18n.T("cli.vpn.routing.short"),
+	Long:  i18n.T("cli.vpn.routing.long"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := vpnTunnel(args[0])
+		if err != nil {
+			return err
+		}
+		macs, sets := t.Clients, t.IPSets
+		if vpnClear {
+			macs, sets = nil, nil
+		}
+		if cmd.Flags().Changed("client") {
+			if macs, err = clientMACs(vpnClients); err != nil {
+				return err
+			}
+		}
+		if cmd.Flags().Changed("ipset") {
+			sets = vpnIPSets
+		}
+		updated, err := AppConfig.SetVPNRouting(vpn.Manager{}, t, macs, sets)
+		if err != nil {
+			return err
+		}
+		for _, line := range tui.VPNStatusLines(updated, vpn.Status{Name: updated.Name, Kind: updated.Kind})[1:] {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// vpnAutostartCmd команда для включения и выключения подъёма туннеля при загрузке
+var vpnAutostartCmd = &cobra.Command{
+	Use:       "autostart <name> <on|off>",
+	Short:     i18n.T("cli.vpn.autostart.short"),
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enabled, err := parseOnOff(args[1])
+		if err != nil {
+			return err
+		}
+		if err := AppConfig.SetVPNAutostart(vpn.Manager{}, args[0], enabled); err != nil {
+			return err
+		}
+		if enabled {
+			fmt.Println(i18n.T("vpn.autostart.on", args[0]))
+		} else {
+			fmt.Println(i18n.T("vpn.autostart.off", args[0]))
+		}
+		return nil
+	},
+}
+
+// vpnRekeyCmd команда для замены закрытого ключа туннеля WireGuard
+var vpnRekeyCmd = &cobra.Command{
+	Use:   "rekey <name>",
+	Short: i18n.T("cli.vpn.rekey.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := vpnTunnel(args[0])
+		if err != nil {
+			return err
+		}
+		if t.Kind != vpn.KindWireGuard {
+			return fmt.Errorf(i18n.T("cli.vpn.error.not_wireguard"), t.Name)
+		}
+		if err := confirm(i18n.T("vpn.keys.question", t.Name)); err != nil {
+			return err
+		}
+		public, err := AppConfig.RegenerateVPNKey(vpn.Manager{}, t.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Println(i18n.T("vpn.keys.public", public))
+		fmt.Println(i18n.T("vpn.keys.hint"))
+		return nil
+	},
+}
+
+// vpnDeleteCmd команда для остановки туннеля и удаления его профиля
+var vpnDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: i18n.T("cli.vpn.delete.short"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := vpnTunnel(args[0])
+		if err != nil {
+			return err
+		}
+		if err := confirm(i18n.T("vpn.delete.question", t.Name)); err != nil {
+			return err
+		}
+		_, err = AppConfig.DeleteVPN(vpn.Manager{}, t)
+		return err
+	},
+}
+
+func localizeVPNCommand() {
+	vpnCmd.Short = i18n.T("cli.vpn.short")
+	vpnCmd.Long = i18n.T("cli.vpn.long")
+	vpnUpCmd.Short = i18n.T("cli.vpn.up.short")
+	vpnUpCmd.Long = i18n.T("cli.vpn.up.long")
+	vpnDownCmd.Short = i18n.T("cli.vpn.down.short")
+	vpnDownCmd.Long = i18n.T("cli.vpn.down.long")
+	vpnKeygenCmd.Short = i18n.T("cli.vpn.keygen.short")
+	vpnKeygenCmd.Long = i18n.T("cli.vpn.keygen.long")
+	vpnImportCmd.Short = i18n.T("cli.vpn.import.short")
+	vpnImportCmd.Long = i18n.T("cli.vpn.import.long")
+	vpnRoutingCmd.Short = i18n.T("cli.vpn.routing.short")
+	vpnRoutingCmd.Long = i18n.T("cli.vpn.routing.long")
+	vpnAutostartCmd.Short = i18n.T("cli.vpn.autostart.short")
+	vpnRekeyCmd.Short = i18n.T("cli.vpn.rekey.short")
+	vpnDeleteCmd.Short = i18n.T("cli.vpn.delete.short")
+}
+
+func init() {
+	localizeVPNCommand()
+	addOutputFlag(vpnCmd, &vpnOutput)
+	addOutputFlag(vpnKeygenCmd, &vpnOutput)
+	vpnUpCmd.Flags().BoolVar(&vpnAutostart, "autostart", false, "bring up all tunnels marked for autostart")
+	vpnDownCmd.Flags().BoolVar(&vpnAll, "all", false, "stop all terem tunnels")
+	vpnImportCmd.Flags().StringVar(&vpnUser, "user", "", "OpenVPN user name for profiles with auth-user-pass")
+	vpnImportCmd.Flags().BoolVar(&vpnStdin, "password-stdin", false, "read the OpenVPN password from stdin")
+	vpnRoutingCmd.Flags().StringSliceVar(&vpnClients, "client", nil, "client MAC address, IP address or name; replaces the current clients")
+	vpnRoutingCmd.Flags().StringSliceVar(&vpnIPSets, "ipset", nil, "ipset list routed through the tunnel; replaces the current lists")
+	vpnRoutingCmd.Flags().BoolVar(&vpnClear, "clear", false, "stop routing clients and lists through the tunnel")
+
+	// Добавляем команду vpn
+	vpnCmd.AddCommand(vpnUpCmd, vpnDownCmd, vpnImportCmd, vpnRoutingCmd, vpnAutostartCmd, vpnRekeyCmd, vpnDeleteCmd, vpnKeygenCmd)
+	rootCmd.AddCommand(vpnCmd)
+}
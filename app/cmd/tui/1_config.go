@@ -3,6 +3,8 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
+	runtimedebug "runtime/debug"
 	"sync"
 
 	"github.com/charmbracelet/lipgloss"
@@ -26,6 +28,7 @@ type AppConfig struct {
 	ConfFile      string
 	Conf          conf.Config
 	Log           log.Logger
+	logger        *log.Logger // Зарегистрированный логгер, копия которого лежит в Log
 	RootCtx       context.Context
 	CancelFunc    context.CancelFunc // Функция для отмены контекста при shutdown
 	Version       string
@@ -74,7 +77,6 @@ func NewSetup(language string, appName string, version string, debug bool, logFi
 	ac := &AppConfig{
 		AppName:       appName,
 		AppTitleColor: termos.GreenBright,
-		AppTitle:      i18n.T("app.title"),
 		LogFile:       logFile,
 		ConfFile:      resolvedPath,
 		Conf:          *confData,
@@ -87,26 +89,72 @@ func NewSetup(language string, appName string, version string, debug bool, logFi
 		},
 	}
 
+	ac.SetTitle()
+
 	// Инициализируем логгер
 	if err := ac.SetupLogger(); err != nil {
 		return nil, err
 	}
 
+	// Сообщаем о вынужденной замене путей конфигурации и лога
+	for _, w := range ac.Conf.Warnings {
+		ac.Log.Warn(w.String())
+	}
+
+	// Сохраняем отчёт о сбое перед завершением по Fatal
+	log.SetFatalHook(func(msg string) {
+		if path, err := ac.WriteCrashReport(msg, runtimedebug.Stack()); err == nil {
+			fmt.Fprintf(os.Stderr, i18n.T("diag.crash.saved")+"\n", path)
+		}
+	})
+
 	return ac, nil
 }
+
+// SetTitle задаёт заголовок экранов с версией на текущем языке
+func (ac *AppConfig) SetTitle() {
+	ac.AppTitle = i18n.T("app.title.version", i18n.T("app.title"), ac.Version)
+}
+
+// SetupLogger (пере)создаёт логгер с учётом режима отладки и режима записи лога
 func (ac *AppConfig) SetupLogger() error {
-	logger := log.New(ac.LogFile)
+	// Закрываем предыдущий логгер: он сбрасывает буфер, останавливает таймер отложенной
+	// записи, закрывает файл лога и исключается из реестра логгеров
+	prev := ac.logger
+	if prev != nil {
+		_ = prev.Close()
+	}
+
+	logFile := ac.LogFile
+	if ac.Conf.LogMode == conf.LogModeMemory {
+		logFile = ""
+	}
+	logger := log.New(logFile)
+	logger.AdoptRecent(prev)
+	if ac.Conf.LogMode == conf.LogModeBuffered {
+		logger.EnableBuffering(log.BufferOptions{})
+	}
 
 	if ac.Conf.DebugMode || ac.Debug {
 		logger.SetLevel(log.DebugLevel)
 	} else {
 		logger.SetLevel(log.InfoLevel)
 	}
+	ac.logger = logger
 	ac.Log = *logger
 
 	return nil
 }
 
+// PathWarnings возвращает локализованные предупреждения о замене путей конфигурации и лога
+func (ac *AppConfig) PathWarnings() []string {
+	warnings := make([]string, 0, len(ac.Conf.Warnings))
+	for _, w := range ac.Conf.Warnings {
+		warnings = append(warnings, w.String())
+	}
+	return warnings
+}
+
 // IsContextCancelled проверяет, был ли отменен контекст (например, по Ctrl+C)
 func (ac *AppConfig) IsContextCancelled() bool {
 	if ac.RootCtx == nil {
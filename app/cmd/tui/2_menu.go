@@ -18,7 +18,7 @@ func (ac *AppConfig) SelectMainMenu() {
 
 	// Устанавливаем язык для Термоса (TUI)
 	termos.SetDefaultLanguage(i18n.Language())
-	ac.AppTitle = i18n.T("app.title")
+	ac.SetTitle()
 
 	// Создаем основную очередь для выбора приложения
 	setupQueue := termos.NewQueue(i18n.T("menu.main.queue.title")).
@@ -27,7 +27,9 @@ func (ac *AppConfig) SelectMainMenu() {
 		WithTitleColor(ac.AppTitleColor, true).
 		WithClearScreen(true)
 
-	// Выводим информацию о системе
+	// Выводим предупреждения о путях и месте на накопителях и информацию о системе
+	ac.PathWarningsTask(setupQueue)
+	ac.StorageAlertsTask(setupQueue)
 	ac.SysInfo(setupQueue)
 
 	// Создаем список для выбора
@@ -1,7 +1,9 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/qzeleza/terem/internal/i18n"
@@ -11,14 +13,15 @@ import (
 
 // SysInfoResult результат запуска DNS сервера
 type SysInfoResult struct {
-	Model       string        // Модель роутера
-	Arch        string        // Архитектура
-	MemoryUsage utils.RAMInfo // Использование памяти
-	Uptime      time.Time     // Время работы
-	Hostname    string        // Доменное имя
-	IP          string        // IP-адрес
-	Gateway     string        // Шлюз
-	MAC         string        // MAC-адрес
+	Model       string         `json:"model"`    // Модель роутера
+	Arch        string         `json:"arch"`     // Архитектура
+	MemoryUsage utils.RAMInfo  `json:"memory"`   // Использование памяти
+	Uptime      time.Time      `json:"bootTime"` // Время работы
+	Hostname    string         `json:"hostname"` // Доменное имя
+	IP          string         `json:"ip"`       // IP-адрес
+	Gateway     string         `json:"gateway"`  // Шлюз
+	MAC         string         `json:"mac"`      // MAC-адрес
+	IPv6        utils.IPv6Info `json:"ipv6"`     // Сведения об IPv6
 }
 
 // SysInfo выводит информацию о системе
@@ -34,24 +37,7 @@ func (ac *AppConfig) SysInfo(queue *termos.Queue) {
 		},
 		termos.WithSummaryFunction(func() []string {
 			// Получаем кешированные данные для отображения
-			info := ac.GetSysInfo()
-			divider := "────────────────────────────"
-			maxLength := 15
-			return []string{
-				divider,
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.model"), maxLength), info.Model),
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.arch"), maxLength), info.Arch),
-				fmt.Sprintf("%s: %d/%d/%d Mb", utils.PadRight(i18n.T("sysinfo.summary.memory"), maxLength),
-					info.MemoryUsage.Total-info.MemoryUsage.Free,
-					info.MemoryUsage.Total,
-					info.MemoryUsage.Free),
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.uptime"), maxLength), utils.FormatUptime(info.Uptime)),
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.hostname"), maxLength), info.Hostname),
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ip"), maxLength), info.IP),
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.gateway"), maxLength), info.Gateway),
-				fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.mac"), maxLength), info.MAC),
-				// divider,
-			}
+			return SysInfoSummary(ac.GetSysInfo())
 		}),
 		termos.WithStopOnError(true),
 	)
@@ -59,6 +45,98 @@ func (ac *AppConfig) SysInfo(queue *termos.Queue) {
 	queue.AddTasks(task)
 }
 
+// SysInfoSummary возвращает строки сводки о системе для панели и текстового вывода
+func SysInfoSummary(info *SysInfoResult) []string {
+	divider := "────────────────────────────"
+	maxLength := 15
+	lines := []string{
+		divider,
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.model"), maxLength), info.Model),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.arch"), maxLength), info.Arch),
+		fmt.Sprintf("%s: %d/%d/%d Mb", utils.PadRight(i18n.T("sysinfo.summary.memory"), maxLength),
+			info.MemoryUsage.Total-info.MemoryUsage.Free,
+			info.MemoryUsage.Total,
+			info.MemoryUsage.Free),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.swap"), maxLength), swapSummary(info.MemoryUsage)),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.uptime"), maxLength), utils.FormatUptime(info.Uptime)),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.hostname"), maxLength), info.Hostname),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ip"), maxLength), info.IP),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.gateway"), maxLength), info.Gateway),
+		fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.mac"), maxLength), info.MAC),
+		// divider,
+	}
+	return append(lines, ipv6Summary(info.IPv6, maxLength)...)
+}
+
+// swapSummary возвращает занятую и полную подкачку или отметку, что она выключена
+func swapSummary(mem utils.RAMInfo) string {
+	if mem.SwapTotal == 0 {
+		return i18n.T("sysinfo.swap.off")
+	}
+	return fmt.Sprintf("%d/%d Mb", mem.SwapTotal-mem.SwapFree, mem.SwapTotal)
+}
+
+// ipv6Summary возвращает строки сводки об IPv6; link-local показывается, только если других адресов нет
+func ipv6Summary(v6 utils.IPv6Info, width int) []string {
+	if !v6.Enabled() {
+		return []string{fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ipv6"), width), i18n.T("sysinfo.ipv6.disabled"))}
+	}
+
+	addrs := append(append([]string{}, v6.Global...), v6.ULA...)
+	if len(addrs) == 0 {
+		addrs = v6.LinkLocal
+	}
+	lines := []string{fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ipv6"), width), strings.Join(addrs, ", "))}
+
+	gateway := i18n.T("sysinfo.default")
+	if v6.Gateway != "" {
+		gateway = v6.Gateway
+		if v6.GatewayDev != "" {
+			gateway += " (" + v6.GatewayDev + ")"
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ipv6_gateway"), width), gateway))
+
+	if len(v6.DelegatedPrefix) > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ipv6_prefix"), width), strings.Join(v6.DelegatedPrefix, ", ")))
+	}
+	if v6.AcceptRA != utils.RAUnknown || v6.RAServer != "" {
+		lines = append(lines, fmt.Sprintf("%s: %s", utils.PadRight(i18n.T("sysinfo.summary.ipv6_ra"), width), raStatus(v6)))
+	}
+	return lines
+}
+
+// raStatus описывает приём и раздачу объявлений маршрутизатора
+func raStatus(v6 utils.IPv6Info) string {
+	var parts []string
+	switch v6.AcceptRA {
+	case utils.RADisabled:
+		parts = append(parts, i18n.T("sysinfo.ipv6.ra_accept_off"))
+	case utils.RAEnabled, utils.RAForwarding:
+		parts = append(parts, i18n.T("sysinfo.ipv6.ra_accept_on"))
+	}
+	if v6.RAServer != "" {
+		parts = append(parts, i18n.T("sysinfo.ipv6.ra_server", v6.RAServer))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PathWarningsTask добавляет в очередь задачу с предупреждениями о замене путей, если они есть
+func (ac *AppConfig) PathWarningsTask(queue *termos.Queue) {
+	warnings := ac.PathWarnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	task := termos.NewFuncTask(i18n.T("config.warn.title"),
+		func() error {
+			return errors.New(strings.Join(warnings, "\n"))
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+}
+
 // getSysInfo получает информацию о системе роутера
 func (ac *AppConfig) getSysInfo(result *SysInfoResult) {
 	// Инициализируем структуру с значениями по умолчанию
@@ -71,6 +149,7 @@ func (ac *AppConfig) getSysInfo(result *SysInfoResult) {
 		IP:          i18n.T("sysinfo.default"),
 		Gateway:     i18n.T("sysinfo.default"),
 		MAC:         i18n.T("sysinfo.default"),
+		IPv6:        utils.IPv6Info{AcceptRA: utils.RAUnknown},
 	}
 
 	// Получаем модель роутера
@@ -103,5 +182,6 @@ func (ac *AppConfig) getSysInfo(result *SysInfoResult) {
 		result.IP = netInfo.IP
 		result.Gateway = netInfo.Gateway
 		result.MAC = netInfo.MAC
+		result.IPv6 = netInfo.IPv6
 	}
 }
@@ -1,14 +1,21 @@
 package tui
 
 import (
+	"fmt"
+
+	conf "github.com/qzeleza/terem/internal/config"
 	"github.com/qzeleza/terem/internal/i18n"
 	"github.com/qzeleza/termos"
 )
 
-// settingsList содержит список настроек приложения
-var settingsList = []string{
-	SettingsOptionLogging,
-	SettingsOptionBack,
+// recentLogLines количество последних записей лога, выводимых на экране просмотра
+const recentLogLines = 20
+
+// logModes порядок переключения режимов записи лога
+var logModes = []string{
+	conf.LogModeFile,
+	conf.LogModeBuffered,
+	conf.LogModeMemory,
 }
 
 // SelectSettingsLoop отображает меню настроек приложения
@@ -21,16 +28,14 @@ func (ac *AppConfig) SelectSettingsLoop() {
 			return false
 		}
 
-		switch ac.Category {
-		case SettingsOptionLogging:
-			ac.SetDebugMode()
-			return true
-		case SettingsOptionBack:
-			return false
-		default:
-			ac.Log.Warn(i18n.T("settings.warn.invalid"))
+		if ac.Category == SettingsOptionBack {
 			return false
 		}
+		if ac.runAction(ModeSettings, ac.Category) {
+			return true
+		}
+		ac.Log.Warn(i18n.T("settings.warn.invalid"))
+		return false
 	}, i18n.T("loop.settings"))
 }
 
@@ -42,7 +47,8 @@ func (ac *AppConfig) SelectSettings() {
 		WithTitleColor(ac.AppTitleColor, true).
 		WithClearScreen(true)
 
-	labels := labelsFor(settingsList)
+	list := menuList(ModeSettings, SettingsOptionBack)
+	labels := labelsFor(list)
 
 	// Создаем задачу для выбора пункта меню с запоминанием последней позиции
 	menuTask := termos.NewSingleSelectTask(i18n.T("settings.task.title"), labels).WithDefaultItem(ac.LastSettingsIndex)
@@ -62,7 +68,7 @@ func (ac *AppConfig) SelectSettings() {
 	// Сохраняем выбранный индекс и устанавливаем категорию
 	selected := menuTask.GetSelectedIndex()
 	ac.LastSettingsIndex = selected
-	ac.Category = settingsList[selected]
+	ac.Category = list[selected]
 }
 
 func (ac *AppConfig) SetDebugMode() {
@@ -73,3 +79,39 @@ func (ac *AppConfig) SetDebugMode() {
 		ac.Log.Fatal(i18n.T("cli.debug.error"), err)
 	}
 }
+
+// SwitchLogMode переключает режим записи лога по кругу: файл → буфер → память
+func (ac *AppConfig) SwitchLogMode() {
+	next := logModes[0]
+	for i, mode := range logModes {
+		if mode == ac.Conf.LogMode {
+			next = logModes[(i+1)%len(logModes)]
+			break
+		}
+	}
+	ac.Conf.SetLogMode(next)
+	if err := ac.SetupLogger(); err != nil {
+		ac.Log.Fatal(i18n.T("cli.debug.error"), err)
+	}
+	ac.Log.Info(i18n.T("settings.log.log_mode"), i18n.T("settings.log_mode."+next))
+}
+
+// ShowRecentLog выводит последние записи лога, хранимые в памяти
+func (ac *AppConfig) ShowRecentLog() {
+	queue := ac.newScreenQueue(i18n.T("settings.log_view.queue.title"))
+
+	task := termos.NewFuncTask(
+		fmt.Sprintf(i18n.T("settings.log_view.task.title"), i18n.T("settings.log_mode."+ac.Conf.LogMode)),
+		func() error { return nil },
+		termos.WithSummaryFunction(func() []string {
+			lines := ac.Log.Recent(recentLogLines)
+			if len(lines) == 0 {
+				return []string{i18n.T("settings.log_view.empty")}
+			}
+			return lines
+		}),
+	)
+	queue.AddTasks(task)
+
+	ac.runScreen(queue)
+}
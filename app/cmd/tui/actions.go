@@ -0,0 +1,76 @@
+package tui
+
+import "github.com/qzeleza/terem/internal/i18n"
+
+// Action действие подменю. Каждое действие описывается один раз: по списку actions
+// строятся меню, а подкоманда Command выполняет то же действие без терминала.
+type Action struct {
+	Menu    string           // Меню: CategorySecurity, CategoryNetwork, CategoryOther или ModeSettings
+	Key     string           // Короткое имя действия
+	Label   string           // Ключ локализации пункта меню
+	Command string           // Подкоманда для запуска без терминала; пусто — действие есть только в меню
+	Run     func(*AppConfig) // Экран действия
+}
+
+// Title возвращает локализованное название пункта меню
+func (a Action) Title() string {
+	return i18n.T(a.Label)
+}
+
+// actions действия всех подменю в порядке показа
+var actions = []Action{
+	{Menu: CategorySecurity, Key: "parental", Label: SecurityOptionParental, Run: (*AppConfig).SelectParentalControl},
+	{Menu: CategorySecurity, Key: "antiscan", Label: SecurityOptionAntiscan, Run: (*AppConfig).SelectAntiscan},
+	{Menu: CategorySecurity, Key: "backup", Label: SecurityOptionBackup, Run: (*AppConfig).SelectBackup},
+	{Menu: CategorySecurity, Key: "firewall", Label: SecurityOptionFirewall, Command: "firewall", Run: (*AppConfig).SelectFirewall},
+
+	{Menu: CategoryNetwork, Key: "interfaces", Label: NetworkOptionInterfaces, Command: "net ifaces", Run: (*AppConfig).SelectInterfacesApp},
+	{Menu: CategoryNetwork, Key: "clients", Label: NetworkOptionClients, Command: "clients", Run: (*AppConfig).SelectClientsApp},
+	{Menu: CategoryNetwork, Key: "openssh", Label: NetworkOptionOpenSSH, Command: "ssh", Run: (*AppConfig).SelectOpenSSHApp},
+	{Menu: CategoryNetwork, Key: "proxy", Label: NetworkOptionProxy, Command: "proxy", Run: (*AppConfig).SelectProxyApp},
+	{Menu: CategoryNetwork, Key: "dns", Label: NetworkOptionDNS, Command: "net dns", Run: (*AppConfig).SelectDNSApp},
+	{Menu: CategoryNetwork, Key: "adguard", Label: NetworkOptionAdGuard, Command: "adguard", Run: (*AppConfig).SelectAdGuardApp},
+	{Menu: CategoryNetwork, Key: "ipset", Label: NetworkOptionIPSet, Command: "ipset", Run: (*AppConfig).SelectIPSetApp},
+	{Menu: CategoryNetwork, Key: "vpn", Label: NetworkOptionVPN, Command: "vpn", Run: (*AppConfig).SelectVPNApp},
+	{Menu: CategoryNetwork, Key: "routing", Label: NetworkOptionRouting, Command: "route", Run: (*AppConfig).SelectRoutingApp},
+	{Menu: CategoryNetwork, Key: "netdiag", Label: NetworkOptionNetDiag, Command: "net", Run: (*AppConfig).SelectNetDiagApp},
+	{Menu: CategoryNetwork, Key: "ports", Label: NetworkOptionPorts, Command: "net ports", Run: (*AppConfig).SelectPortsApp},
+
+	{Menu: CategoryOther, Key: "info", Label: OtherOptionInfo, Command: "info", Run: (*AppConfig).SelectInfoApp},
+	{Menu: CategoryOther, Key: "doctor", Label: OtherOptionDoctor, Command: "doctor", Run: (*AppConfig).SelectDoctorApp},
+	{Menu: CategoryOther, Key: "procs", Label: OtherOptionProcesses, Command: "procs", Run: (*AppConfig).SelectProcessesApp},
+	{Menu: CategoryOther, Key: "storage", Label: OtherOptionStorage, Command: "storage", Run: (*AppConfig).SelectStorageApp},
+	{Menu: CategoryOther, Key: "cron", Label: OtherOptionCron, Command: "cron", Run: (*AppConfig).SelectCronApp},
+
+	{Menu: ModeSettings, Key: "logging", Label: SettingsOptionLogging, Command: "settings debug", Run: (*AppConfig).SetDebugMode},
+	{Menu: ModeSettings, Key: "log_mode", Label: SettingsOptionLogMode, Command: "settings log-mode", Run: (*AppConfig).SwitchLogMode},
+	{Menu: ModeSettings, Key: "log_view", Label: SettingsOptionLogView, Command: "settings log", Run: (*AppConfig).ShowRecentLog},
+	{Menu: ModeSettings, Key: "update", Label: SettingsOptionUpdate, Command: "self-update", Run: (*AppConfig).SelfUpdate},
+}
+
+// Actions возвращает действия всех подменю в порядке показа
+func Actions() []Action {
+	return append([]Action(nil), actions...)
+}
+
+// menuList возвращает ключи пунктов меню menu с пунктом back в конце
+func menuList(menu, back string) []string {
+	list := []string{}
+	for _, a := range actions {
+		if a.Menu == menu {
+			list = append(list, a.Label)
+		}
+	}
+	return append(list, back)
+}
+
+// runAction запускает действие меню menu по ключу пункта; false — такого действия в меню нет
+func (ac *AppConfig) runAction(menu, label string) bool {
+	for _, a := range actions {
+		if a.Menu == menu && a.Label == label {
+			a.Run(ac)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,370 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/cron"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/termos"
+)
+
+// Действия в разделе заданий cron
+var cronActions = []string{
+	"cron.action.list",
+	"cron.action.add",
+	"cron.action.edit",
+	"cron.action.remove",
+	"cron.action.check",
+}
+
+// Параметры предпросмотра расписания
+const (
+	cronPreviewRuns = 5                  // Сколько ближайших запусков показывать
+	cronTimeLayout  = "2006-01-02 15:04" // Формат времени запуска
+)
+
+// Особые пункты выбора расписания
+const (
+	cronKeep   = "keep"   // Оставить текущее расписание
+	cronCustom = "custom" // Ввести выражение вручную
+)
+
+// SelectCronApp отображает задания cron и действия с ними
+func (ac *AppConfig) SelectCronApp() {
+	ac.Log.Info(i18n.T("others.log.cron"))
+	m := cron.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		index, ok := ac.cronPick(i18n.T("cron.task.action", m.File()), labelsFor(cronActions))
+		if !ok {
+			return false
+		}
+		switch cronActions[index] {
+		case "cron.action.list":
+			ac.showCron(m)
+		case "cron.action.add":
+			ac.editCronEntry(m, cron.Entry{}, false)
+		case "cron.action.edit":
+			if e, ok := ac.pickCronEntry(m); ok {
+				ac.editCronEntry(m, e, true)
+			}
+		case "cron.action.remove":
+			if e, ok := ac.pickCronEntry(m); ok {
+				ac.removeCronEntry(m, e)
+			}
+		case "cron.action.check":
+			if expr, ok := ac.cronSchedule(""); ok {
+				ac.runCronTask(i18n.T("cron.task.preview"), func() error { return cron.Valid(expr) },
+					func() []string { return CronPreview(expr, time.Now()) })
+			}
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.cron"))
+}
+
+// cronPick показывает список с пунктом «Назад»; false — выбран возврат
+func (ac *AppConfig) cronPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("cron.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("cron.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// runCronTask показывает экран с одной задачей
+func (ac *AppConfig) runCronTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("cron.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// CronEntryLine возвращает строку задания с именем терема и ближайшим запуском
+func CronEntryLine(e cron.Entry, now time.Time) string {
+	owner := i18n.T("cron.entry.foreign")
+	if e.Owned() {
+		owner = e.Name
+	}
+	next := i18n.T("cron.entry.invalid")
+	if s, err := cron.Parse(e.Schedule); err == nil {
+		if t := s.Next(now); !t.IsZero() {
+			next = t.Format(cronTimeLayout)
+		} else {
+			next = i18n.T("cron.entry.never")
+		}
+	}
+	return i18n.T("cron.entry.line", owner, e.Schedule, e.Command, next)
+}
+
+// CronLines возвращает строки со всеми заданиями crontab
+func CronLines(entries []cron.Entry, now time.Time) []string {
+	if len(entries) == 0 {
+		return []string{i18n.T("cron.empty")}
+	}
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, CronEntryLine(e, now))
+	}
+	return lines
+}
+
+// CronPreview возвращает расписание и его ближайшие запуски
+func CronPreview(expr string, now time.Time) []string {
+	s, err := cron.Parse(expr)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	runs := s.NextRuns(now, cronPreviewRuns)
+	if len(runs) == 0 {
+		return []string{i18n.T("cron.preview.schedule", s.Expr), i18n.T("cron.entry.never")}
+	}
+	lines := []string{i18n.T("cron.preview.schedule", s.Expr)}
+	for _, t := range runs {
+		lines = append(lines, i18n.T("cron.preview.run", t.Format(cronTimeLayout), i18n.T(fmt.Sprintf("cron.weekday.%d", t.Weekday()))))
+	}
+	return lines
+}
+
+// showCron показывает задания crontab
+func (ac *AppConfig) showCron(m cron.Manager) {
+	var entries []cron.Entry
+	ac.runCronTask(i18n.T("cron.task.list"),
+		func() error {
+			t, err := m.Load()
+			entries = t.Entries()
+			return err
+		},
+		func() []string { return CronLines(entries, time.Now()) })
+}
+
+// pickCronEntry показывает задания и возвращает выбранное
+func (ac *AppConfig) pickCronEntry(m cron.Manager) (cron.Entry, bool) {
+	t, err := m.Load()
+	if err != nil {
+		ac.Log.Error(i18n.T("cron.log.read_failed"), err)
+		return cron.Entry{}, false
+	}
+	entries := t.Entries()
+	if len(entries) == 0 {
+		ac.runCronTask(i18n.T("cron.task.list"), func() error { return errors.New(i18n.T("cron.empty")) }, nil)
+		return cron.Entry{}, false
+	}
+	now := time.Now()
+	labels := make([]string, 0, len(entries))
+	for _, e := range entries {
+		labels = append(labels, CronEntryLine(e, now))
+	}
+	index, ok := ac.cronPick(i18n.T("cron.task.pick"), labels)
+	if !ok {
+		return cron.Entry{}, false
+	}
+	return entries[index], true
+}
+
+// cronSchedule предлагает собрать расписание конструктором или ввести выражение;
+// current — текущее расписание, которое можно оставить
+func (ac *AppConfig) cronSchedule(current string) (string, bool) {
+	var keys, labels []string
+	if current != "" {
+		keys = append(keys, cronKeep)
+		labels = append(labels, i18n.T("cron.kind.keep", current))
+	}
+	for _, kind := range append(append([]string(nil), cron.Kinds...), cronCustom) {
+		keys = append(keys, kind)
+		labels = append(labels, i18n.T("cron.kind."+kind))
+	}
+	index, ok := ac.cronPick(i18n.T("cron.task.kind"), labels)
+	if !ok {
+		return "", false
+	}
+	kind := keys[index]
+	if kind == cronKeep {
+		return current, true
+	}
+
+	queue := ac.newScreenQueue(i18n.T("cron.queue.title"))
+	var tasks []termos.Task
+	input := func(key, placeholder string) *termos.InputTask {
+		task := termos.NewInputTask(i18n.T("cron.input."+key), i18n.T("cron.input."+key+"_hint"))
+		task.WithPlaceholder(placeholder).WithAllowEmpty(true)
+		tasks = append(tasks, task)
+		return task
+	}
+	var interval, minute, clock, day, expr *termos.InputTask
+	var weekday *termos.SingleSelectTask
+	switch kind {
+	case cron.EveryMinutes:
+		interval = input("interval", "30")
+	case cron.Hourly:
+		minute = input("minute", "0")
+	case cron.Weekly:
+		weekdays := make([]string, 7)
+		for i := range weekdays {
+			weekdays[i] = i18n.T(fmt.Sprintf("cron.weekday.%d", (i+1)%7))
+		}
+		weekday = termos.NewSingleSelectTask(i18n.T("cron.input.weekday"), weekdays)
+		tasks = append(tasks, weekday)
+		clock = input("time", "04:00")
+	case cron.Monthly:
+		day = input("day", "1")
+		clock = input("time", "04:00")
+	case cron.Daily:
+		clock = input("time", "04:00")
+	case cronCustom:
+		expr = input("expr", valueOr(current, "0 4 * * *"))
+	}
+
+	var result string
+	build := termos.NewFuncTask(i18n.T("cron.task.preview"),
+		func() error {
+			if expr != nil {
+				result = valueOr(strings.TrimSpace(expr.GetValue()), valueOr(current, "0 4 * * *"))
+				return cron.Valid(result)
+			}
+			b := cron.Builder{Kind: kind}
+			var err error
+			if interval != nil {
+				if b.Interval, err = inputNumber(interval, 30); err != nil {
+					return err
+				}
+			}
+			if minute != nil {
+				if b.Minute, err = inputNumber(minute, 0); err != nil {
+					return err
+				}
+			}
+			if day != nil {
+				if b.Day, err = inputNumber(day, 1); err != nil {
+					return err
+				}
+			}
+			if weekday != nil {
+				b.Weekday = (weekday.GetSelectedIndex() + 1) % 7
+			}
+			if clock != nil {
+				if b.Hour, b.Minute, err = parseClock(valueOr(strings.TrimSpace(clock.GetValue()), "04:00")); err != nil {
+					return err
+				}
+			}
+			result, err = b.Expr()
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return CronPreview(result, time.Now()) }),
+		termos.WithStopOnError(true),
+	)
+	queue.AddTasks(append(tasks, build)...)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return "", false
+	}
+	if build.HasError() || ac.IsContextCancelled() {
+		return "", false
+	}
+	return result, true
+}
+
+// inputNumber возвращает число из поля ввода или def для пустого поля
+func inputNumber(input *termos.InputTask, def int) (int, error) {
+	value := strings.TrimSpace(input.GetValue())
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf(i18n.T("cron.error.number"), value)
+	}
+	return n, nil
+}
+
+// parseClock разбирает время вида 4:30 или 04:30
+func parseClock(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf(i18n.T("cron.error.time"), value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// editCronEntry собирает расписание, запрашивает имя и команду, показывает ближайшие
+// запуски и после подтверждения сохраняет задание
+func (ac *AppConfig) editCronEntry(m cron.Manager, e cron.Entry, existing bool) {
+	schedule, ok := ac.cronSchedule(e.Schedule)
+	if !ok {
+		return
+	}
+
+	queue := ac.newScreenQueue(i18n.T("cron.queue.title"))
+	name := termos.NewInputTask(i18n.T("cron.input.name"), i18n.T("cron.input.name_hint"))
+	name.WithPlaceholder(e.Name).WithAllowEmpty(existing && !e.Owned())
+	command := termos.NewInputTask(i18n.T("cron.input.command"), i18n.T("cron.input.command_hint"))
+	command.WithPlaceholder(e.Command).WithAllowEmpty(existing)
+	confirm := termos.NewYesNoTask(i18n.T("cron.confirm.title"), i18n.T("cron.confirm.save", schedule))
+
+	task := termos.NewFuncTask(i18n.T("cron.task.save"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("cron.cancelled"))
+			}
+			entry := cron.Entry{
+				Schedule: schedule,
+				Command:  valueOr(strings.TrimSpace(command.GetValue()), e.Command),
+				Name:     valueOr(strings.TrimSpace(name.GetValue()), e.Name),
+			}
+			var err error
+			if existing {
+				err = m.Replace(e.Line, entry)
+			} else {
+				err = m.Add(entry)
+			}
+			if err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("cron.log.saved"), valueOr(entry.Name, "-"), entry.Schedule, entry.Command)
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string { return CronPreview(schedule, time.Now()) }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(name, command, confirm, task)
+	ac.runScreen(queue)
+}
+
+// removeCronEntry запрашивает подтверждение и удаляет задание
+func (ac *AppConfig) removeCronEntry(m cron.Manager, e cron.Entry) {
+	queue := ac.newScreenQueue(i18n.T("cron.queue.title"))
+	text := i18n.T("cron.confirm.remove", e.Schedule, e.Command)
+	if !e.Owned() {
+		text += " " + i18n.T("cron.confirm.foreign")
+	}
+	confirm := termos.NewYesNoTask(i18n.T("cron.confirm.title"), text)
+	confirm.WithDefaultItem(termos.NoOption)
+
+	task := termos.NewFuncTask(i18n.T("cron.task.remove"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("cron.cancelled"))
+			}
+			if err := m.Remove(e.Line); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("cron.log.removed"), e.Schedule, e.Command)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/buildinfo"
+	"github.com/qzeleza/terem/internal/diag"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// crashLogLines количество последних записей лога, попадающих в отчёт о сбое
+const crashLogLines = 100
+
+// CrashDir возвращает каталог для отчётов о сбоях (рядом с файлом лога)
+func (ac *AppConfig) CrashDir() string {
+	if ac.LogFile == "" {
+		return ""
+	}
+	return filepath.Dir(ac.LogFile)
+}
+
+// WriteCrashReport сохраняет отчёт о панике или фатальной ошибке и возвращает путь до него
+func (ac *AppConfig) WriteCrashReport(reason any, stack []byte) (string, error) {
+	_ = ac.Log.Flush()
+
+	report := diag.CrashReport{
+		Time:       time.Now(),
+		Version:    ac.Version,
+		Build:      buildinfo.Get().String(),
+		Reason:     fmt.Sprint(reason),
+		Stack:      stack,
+		Config:     ac.redactedConfig(),
+		SysInfo:    ac.sysInfoJSON(),
+		LogLines:   ac.Log.Recent(crashLogLines),
+		Goroutines: diag.Goroutines(),
+	}
+
+	return diag.SaveCrashReport(ac.CrashDir(), report)
+}
+
+// CreateDiagBundle собирает конфигурацию, логи, системную информацию, список пакетов
+// и отчёты о сбоях в архив tar.gz по пути path
+func (ac *AppConfig) CreateDiagBundle(path string) error {
+	ac.Log.Info(i18n.T("diag.log.bundle"), path)
+
+	entries := []diag.Entry{
+		{Name: "version.txt", Data: []byte(fmt.Sprintf("%s %s\n", ac.AppName, buildinfo.Get()))},
+		{Name: "config.yaml", Data: ac.redactedConfig()},
+		{Name: "sysinfo.json", Data: ac.sysInfoJSON()},
+		{Name: "opkg.txt", Data: commandOutput("opkg list-installed")},
+		{Name: "logs/recent.log", Data: []byte(strings.Join(ac.Log.Recent(0), "\n") + "\n")},
+	}
+
+	for _, file := range diag.LogFiles(ac.LogFile) {
+		if entry, err := diag.FileEntry("logs/"+filepath.Base(file), file); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, file := range diag.CrashReports(ac.CrashDir()) {
+		if entry, err := diag.FileEntry("crash/"+filepath.Base(file), file); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return diag.SaveBundle(path, entries)
+}
+
+// redactedConfig возвращает конфигурацию в YAML со скрытыми секретами
+func (ac *AppConfig) redactedConfig() []byte {
+	data, err := yaml.Marshal(ac.Conf)
+	if err != nil {
+		return []byte(err.Error())
+	}
+	redacted, err := diag.RedactYAML(data)
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return redacted
+}
+
+// sysInfoJSON возвращает снимок системной информации в JSON
+func (ac *AppConfig) sysInfoJSON() []byte {
+	data, err := json.MarshalIndent(ac.GetSysInfo(), "", "  ")
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return data
+}
+
+// commandOutput возвращает вывод команды либо текст ошибки
+func commandOutput(cmd string) []byte {
+	output, err := utils.ExecuteCommand(cmd)
+	if err != nil {
+		return []byte(err.Error() + "\n")
+	}
+	return []byte(output + "\n")
+}
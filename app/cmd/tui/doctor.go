@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qzeleza/terem/internal/doctor"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/termos"
+)
+
+// DoctorOptions возвращает параметры проверки окружения для текущей конфигурации
+func (ac *AppConfig) DoctorOptions(runner utils.Runner) doctor.Options {
+	return doctor.Options{
+		Runner:     runner,
+		ConfigPath: ac.ConfFile,
+		LogPath:    ac.LogFile,
+		Version:    ac.Version,
+	}.WithDefaults()
+}
+
+// SelectDoctorApp выполняет проверку окружения и выводит результат каждой проверки
+func (ac *AppConfig) SelectDoctorApp() {
+	ac.Log.Info(i18n.T("others.log.doctor"))
+
+	queue := ac.newScreenQueue(i18n.T("doctor.queue.title"))
+	opts := ac.DoctorOptions(utils.Local{})
+
+	for _, check := range doctor.Checks() {
+		var res doctor.Result
+		task := termos.NewFuncTask(i18n.T("doctor.check."+check.ID),
+			func() error {
+				res = check.Run(ac.RootCtx, opts)
+				ac.Log.Debug(i18n.T("doctor.log.result"), res.ID, res.Status, res.Detail)
+				if res.Status == doctor.StatusFail {
+					return errors.New(res.Detail + ". " + res.Fix)
+				}
+				return nil
+			},
+			termos.WithSummaryFunction(func() []string {
+				if res.Status == doctor.StatusWarn {
+					return []string{
+						fmt.Sprintf("%s: %s", i18n.T("doctor.label.warn"), res.Detail),
+						fmt.Sprintf("%s: %s", i18n.T("doctor.label.fix"), res.Fix),
+					}
+				}
+				return []string{res.Detail}
+			}),
+			termos.WithStopOnError(false),
+		)
+		queue.AddTasks(task)
+	}
+
+	ac.runScreen(queue)
+}
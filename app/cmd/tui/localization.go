@@ -12,21 +12,36 @@ const (
 	CategoryOther    = "category.other"
 	CategoryBack     = "category.back"
 
-	NetworkOptionOpenSSH = "network.option.openssh"
-	NetworkOptionProxy   = "network.option.proxy"
-	NetworkOptionDNS     = "network.option.dns"
-	NetworkOptionAdGuard = "network.option.adguard"
-	NetworkOptionBack    = "network.option.back"
-
-	OtherOptionInfo = "others.option.info"
-	OtherOptionBack = "others.option.back"
+	NetworkOptionInterfaces = "network.option.interfaces"
+	NetworkOptionClients    = "network.option.clients"
+	NetworkOptionOpenSSH    = "network.option.openssh"
+	NetworkOptionProxy      = "network.option.proxy"
+	NetworkOptionDNS        = "network.option.dns"
+	NetworkOptionAdGuard    = "network.option.adguard"
+	NetworkOptionIPSet      = "network.option.ipset"
+	NetworkOptionVPN        = "network.option.vpn"
+	NetworkOptionRouting    = "network.option.routing"
+	NetworkOptionNetDiag    = "network.option.netdiag"
+	NetworkOptionPorts      = "network.option.ports"
+	NetworkOptionBack       = "network.option.back"
+
+	OtherOptionInfo      = "others.option.info"
+	OtherOptionDoctor    = "others.option.doctor"
+	OtherOptionProcesses = "others.option.procs"
+	OtherOptionStorage   = "others.option.storage"
+	OtherOptionCron      = "others.option.cron"
+	OtherOptionBack      = "others.option.back"
 
 	SecurityOptionParental = "security.option.parental"
 	SecurityOptionAntiscan = "security.option.antiscan"
 	SecurityOptionBackup   = "security.option.backup"
+	SecurityOptionFirewall = "security.option.firewall"
 	SecurityOptionBack     = "security.option.back"
 
 	SettingsOptionLogging = "settings.option.logging"
+	SettingsOptionLogMode = "settings.option.log_mode"
+	SettingsOptionLogView = "settings.option.log_view"
+	SettingsOptionUpdate  = "settings.option.update"
 	SettingsOptionBack    = "settings.option.back"
 )
 
@@ -5,24 +5,6 @@ import (
 	"github.com/qzeleza/termos"
 )
 
-// networkList содержит список сетевых приложений в фиксированном порядке
-var networkList = []string{
-	NetworkOptionOpenSSH,
-	NetworkOptionProxy,
-	NetworkOptionDNS,
-	NetworkOptionAdGuard,
-	NetworkOptionBack,
-}
-
-// networkKeys соответствующие ключи для networkList
-var networkKeys = []string{
-	"openssh",
-	"proxy",
-	"dns",
-	"adguard",
-	"quit",
-}
-
 // NetworkCategoryLoop запускает цикл для выбора сетевых приложений
 func (ac *AppConfig) NetworkCategoryLoop() {
 	ac.ContextualLoop(func() bool {
@@ -33,25 +15,14 @@ func (ac *AppConfig) NetworkCategoryLoop() {
 			return false
 		}
 
-		switch ac.Category {
-		case NetworkOptionOpenSSH:
-			ac.SelectOpenSSHApp()
-			return true
-		case NetworkOptionProxy:
-			ac.SelectProxyApp()
-			return true
-		case NetworkOptionDNS:
-			ac.SelectDNSApp()
-			return true
-		case NetworkOptionAdGuard:
-			ac.SelectAdGuardApp()
-			return true
-		case NetworkOptionBack:
-			return false
-		default:
-			ac.Log.Warn(i18n.T("network.warn.invalid"))
+		if ac.Category == NetworkOptionBack {
 			return false
 		}
+		if ac.runAction(CategoryNetwork, ac.Category) {
+			return true
+		}
+		ac.Log.Warn(i18n.T("network.warn.invalid"))
+		return false
 	}, i18n.T("loop.network"))
 }
 
@@ -64,7 +35,8 @@ func (ac *AppConfig) SelectNetworkCategory() {
 		WithTitleColor(ac.AppTitleColor, true).
 		WithClearScreen(true)
 
-	labels := labelsFor(networkList)
+	list := menuList(CategoryNetwork, NetworkOptionBack)
+	labels := labelsFor(list)
 
 	// Создаем задачу для выбора пункта меню с запоминанием последней позиции
 	menuTask := termos.NewSingleSelectTask(i18n.T("network.task.title"), labels).WithDefaultItem(ac.LastNetworkIndex)
@@ -84,25 +56,5 @@ func (ac *AppConfig) SelectNetworkCategory() {
 	// Сохраняем выбранный индекс и устанавливаем категорию
 	selected := menuTask.GetSelectedIndex()
 	ac.LastNetworkIndex = selected
-	ac.Category = networkList[selected]
-}
-
-// SelectOpenSSHApp отображает меню для выбора OpenSSH-сервера
-func (ac *AppConfig) SelectOpenSSHApp() {
-	ac.Log.Info(i18n.T("network.log.openssh"))
-}
-
-// SelectProxyApp отображает меню для выбора прокси сервера
-func (ac *AppConfig) SelectProxyApp() {
-	ac.Log.Info(i18n.T("network.log.proxy"))
-}
-
-// SelectDNSApp отображает меню для выбора DNS-сервера
-func (ac *AppConfig) SelectDNSApp() {
-	ac.Log.Info(i18n.T("network.log.dns"))
-}
-
-// SelectAdGuardApp отображает меню для выбора AdGuard Home сервера
-func (ac *AppConfig) SelectAdGuardApp() {
-	ac.Log.Info(i18n.T("network.log.adguard"))
+	ac.Category = list[selected]
 }
@@ -0,0 +1,660 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/adguard"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/termos"
+)
+
+// Действия с AdGuard Home
+var adguardActions = []string{
+	"adguard.action.status",
+	"adguard.action.protection",
+	"adguard.action.filters",
+	"adguard.action.rules",
+	"adguard.action.setup",
+	"adguard.action.back",
+}
+
+// Действия со списками фильтров
+var adguardFilterActions = []string{
+	"adguard.filters.list",
+	"adguard.filters.add",
+	"adguard.filters.toggle",
+	"adguard.filters.remove",
+	"adguard.filters.refresh",
+	"adguard.action.back",
+}
+
+// Действия с правилами для клиентов
+var adguardRuleActions = []string{
+	"adguard.rules.list",
+	"adguard.rules.add",
+	"adguard.rules.remove",
+	"adguard.action.back",
+}
+
+// adguardTopLimit число строк рейтинга заблокированных доменов
+const adguardTopLimit = 10
+
+// SelectAdGuardApp отображает раздел управления AdGuard Home до выбора «Назад»
+func (ac *AppConfig) SelectAdGuardApp() {
+	ac.Log.Info(i18n.T("network.log.adguard"))
+
+	ac.ContextualLoop(func() bool {
+		action, ok := ac.adguardAction(i18n.T("adguard.queue.title"), adguardActions)
+		if !ok {
+			return false
+		}
+
+		switch action {
+		case "adguard.action.status":
+			ac.showAdGuardStatus()
+		case "adguard.action.protection":
+			ac.toggleAdGuardProtection()
+		case "adguard.action.filters":
+			ac.adguardFiltersLoop()
+		case "adguard.action.rules":
+			ac.adguardRulesLoop()
+		case "adguard.action.setup":
+			ac.setupAdGuard()
+		default:
+			return false
+		}
+		return true
+	}, i18n.T("loop.adguard"))
+}
+
+// adguardAction показывает меню действий и возвращает ключ выбранного пункта
+func (ac *AppConfig) adguardAction(title string, actions []string) (string, bool) {
+	queue := ac.newScreenQueue(title)
+	menu := termos.NewSingleSelectTask(i18n.T("adguard.task.action"), labelsFor(actions))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return "", false
+	}
+	if menu.HasError() || ac.IsContextCancelled() {
+		return "", false
+	}
+	return actions[menu.GetSelectedIndex()], true
+}
+
+// AdGuardClient возвращает клиент API с параметрами из конфигурации терема
+func (ac *AppConfig) AdGuardClient() adguard.Client {
+	return adguard.Client{
+		BaseURL:  valueOr(ac.Conf.AdGuard.URL, adguard.DefaultURL),
+		User:     ac.Conf.AdGuard.User,
+		Password: ac.Conf.AdGuard.Password,
+	}
+}
+
+// CheckAdGuard проверяет, что AdGuard Home установлен и настроен
+func (ac *AppConfig) CheckAdGuard(c adguard.Client) error {
+	if !adguard.Service.Installed() {
+		return errors.New(i18n.T("adguard.error.not_installed"))
+	}
+	if need, err := c.NeedsSetup(); err != nil {
+		return err
+	} else if need {
+		return errors.New(i18n.T("adguard.error.needs_setup"))
+	}
+	return nil
+}
+
+// ProtectionState возвращает локализованное состояние защиты
+func ProtectionState(enabled bool) string {
+	if enabled {
+		return i18n.T("adguard.state.enabled")
+	}
+	return i18n.T("adguard.state.disabled")
+}
+
+// AdGuardSummary возвращает строки с состоянием и статистикой AdGuard Home
+func AdGuardSummary(st adguard.Status, stats adguard.Stats) []string {
+	lines := []string{
+		i18n.T("adguard.status.version", st.Version),
+		i18n.T("adguard.status.protection", ProtectionState(st.ProtectionEnabled)),
+		i18n.T("adguard.status.dns", strings.Join(st.DNSAddresses, ", "), st.DNSPort),
+		i18n.T("adguard.status.queries", stats.Queries),
+		i18n.T("adguard.status.blocked", stats.Blocked, stats.BlockedPercent()),
+		i18n.T("adguard.status.avg", stats.AvgProcessingSec*1000),
+	}
+	if len(stats.TopBlocked) > 0 {
+		lines = append(lines, i18n.T("adguard.status.top_blocked"))
+		for i, entry := range stats.TopBlocked {
+			if i == adguardTopLimit {
+				break
+			}
+			lines = append(lines, fmt.Sprintf("  %2d. %s — %d", i+1, entry.Name, entry.Count))
+		}
+	}
+	return lines
+}
+
+// showAdGuardStatus показывает состояние защиты, статистику запросов и самые блокируемые домены
+func (ac *AppConfig) showAdGuardStatus() {
+	queue := ac.newScreenQueue(i18n.T("adguard.queue.title"))
+	c := ac.AdGuardClient()
+
+	var st adguard.Status
+	var stats adguard.Stats
+	var processes []string
+	task := termos.NewFuncTask(i18n.T("adguard.status.title"),
+		func() error {
+			if err := ac.CheckAdGuard(c); err != nil {
+				return err
+			}
+			var err error
+			if st, err = c.Status(); err != nil {
+				return err
+			}
+			processes = appProcesses(adguard.Service.Runner, adguard.Service.ProcessName())
+			stats, err = c.Stats()
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return append(AdGuardSummary(st, stats), processes...) }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// toggleAdGuardProtection включает защиту, если она выключена, и выключает, если включена
+func (ac *AppConfig) toggleAdGuardProtection() {
+	queue := ac.newScreenQueue(i18n.T("adguard.queue.title"))
+	c := ac.AdGuardClient()
+
+	var enabled bool
+	task := termos.NewFuncTask(i18n.T("adguard.task.protection"),
+		func() error {
+			if err := ac.CheckAdGuard(c); err != nil {
+				return err
+			}
+			st, err := c.Status()
+			if err != nil {
+				return err
+			}
+			enabled = !st.ProtectionEnabled
+			if err := c.SetProtection(enabled); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("adguard.log.protection"), ProtectionState(enabled))
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("adguard.status.protection", ProtectionState(enabled))}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// runAdGuardTask показывает экран с одной задачей, выполняющей действие через API
+func (ac *AppConfig) runAdGuardTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("adguard.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// loadAdGuardFiltering получает фильтры и правила; ошибка показывается отдельным экраном
+func (ac *AppConfig) loadAdGuardFiltering(c adguard.Client) (adguard.Filtering, bool) {
+	var f adguard.Filtering
+	err := ac.CheckAdGuard(c)
+	if err == nil {
+		f, err = c.Filtering()
+	}
+	if err != nil {
+		ac.runAdGuardTask(i18n.T("adguard.task.load"), func() error { return err }, nil)
+		return f, false
+	}
+	return f, true
+}
+
+// adguardPick показывает список и возвращает индекс выбранного элемента; последний пункт — «Назад»
+func (ac *AppConfig) adguardPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("adguard.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("adguard.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// FilterLine возвращает строку с описанием списка фильтров
+func FilterLine(f adguard.Filter) string {
+	mark := "✗"
+	if f.Enabled {
+		mark = "✓"
+	}
+	return i18n.T("adguard.filters.line", mark, f.Name, f.RulesCount)
+}
+
+// adguardFiltersLoop управляет списками блокировки
+func (ac *AppConfig) adguardFiltersLoop() {
+	c := ac.AdGuardClient()
+
+	ac.ContextualLoop(func() bool {
+		action, ok := ac.adguardAction(i18n.T("adguard.filters.title"), adguardFilterActions)
+		if !ok {
+			return false
+		}
+
+		switch action {
+		case "adguard.filters.list":
+			var f adguard.Filtering
+			ac.runAdGuardTask(i18n.T("adguard.task.load"),
+				func() error {
+					if err := ac.CheckAdGuard(c); err != nil {
+						return err
+					}
+					var err error
+					f, err = c.Filtering()
+					return err
+				},
+				func() []string {
+					lines := make([]string, 0, len(f.Filters)+1)
+					for _, filter := range f.Filters {
+						lines = append(lines, FilterLine(filter), "    "+filter.URL)
+					}
+					return append(lines, i18n.T("adguard.filters.total", len(f.Filters), len(f.UserRules)))
+				})
+		case "adguard.filters.add":
+			ac.addAdGuardFilter(c)
+		case "adguard.filters.toggle", "adguard.filters.remove":
+			f, ok := ac.loadAdGuardFiltering(c)
+			if !ok {
+				return true
+			}
+			labels := make([]string, 0, len(f.Filters))
+			for _, filter := range f.Filters {
+				labels = append(labels, FilterLine(filter))
+			}
+			index, ok := ac.adguardPick(i18n.T("adguard.filters.pick"), labels)
+			if !ok {
+				return true
+			}
+			filter := f.Filters[index]
+			if action == "adguard.filters.toggle" {
+				ac.runAdGuardTask(i18n.T("adguard.task.filter_toggle", filter.Name),
+					func() error { return c.SetFilterEnabled(filter, !filter.Enabled) }, nil)
+			} else {
+				ac.runAdGuardTask(i18n.T("adguard.task.filter_remove", filter.Name),
+					func() error { return c.RemoveFilter(filter.URL) }, nil)
+			}
+		case "adguard.filters.refresh":
+			ac.runAdGuardTask(i18n.T("adguard.task.refresh"),
+				func() error {
+					if err := ac.CheckAdGuard(c); err != nil {
+						return err
+					}
+					return c.RefreshFilters()
+				}, nil)
+		default:
+			return false
+		}
+		return true
+	}, i18n.T("loop.adguard"))
+}
+
+// addAdGuardFilter запрашивает название и адрес списка блокировки и подключает его
+func (ac *AppConfig) addAdGuardFilter(c adguard.Client) {
+	queue := ac.newScreenQueue(i18n.T("adguard.filters.title"))
+	name := termos.NewInputTask(i18n.T("adguard.input.filter_name"), i18n.T("adguard.input.filter_name_hint"))
+	url := termos.NewInputTask(i18n.T("adguard.input.filter_url"), i18n.T("adguard.input.filter_url_hint"))
+
+	add := termos.NewFuncTask(i18n.T("adguard.task.filter_add"),
+		func() error {
+			value := strings.TrimSpace(url.GetValue())
+			if err := CheckFilterURL(value); err != nil {
+				return err
+			}
+			if err := c.AddFilter(strings.TrimSpace(name.GetValue()), value); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("adguard.log.filter_added"), value)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(name, url, add)
+	ac.runScreen(queue)
+}
+
+// CheckFilterURL проверяет адрес списка блокировки: HTTP(S) или путь к файлу на роутере
+func CheckFilterURL(value string) error {
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") && !strings.HasPrefix(value, "/") {
+		return fmt.Errorf(i18n.T("adguard.error.filter_url"), value)
+	}
+	return nil
+}
+
+// RuleLine возвращает строку с описанием правила для клиента
+func RuleLine(r adguard.ClientRule) string {
+	if r.Allow {
+		return i18n.T("adguard.rules.line_allow", r.Domain, r.Client)
+	}
+	return i18n.T("adguard.rules.line_block", r.Domain, r.Client)
+}
+
+// adguardRulesLoop управляет правилами блокировки и разрешения доменов для отдельных клиентов
+func (ac *AppConfig) adguardRulesLoop() {
+	c := ac.AdGuardClient()
+
+	ac.ContextualLoop(func() bool {
+		action, ok := ac.adguardAction(i18n.T("adguard.rules.title"), adguardRuleActions)
+		if !ok {
+			return false
+		}
+
+		switch action {
+		case "adguard.rules.list":
+			var rules []adguard.ClientRule
+			ac.runAdGuardTask(i18n.T("adguard.task.load"),
+				func() error {
+					if err := ac.CheckAdGuard(c); err != nil {
+						return err
+					}
+					f, err := c.Filtering()
+					rules = adguard.ClientRules(f.UserRules)
+					if err == nil && len(rules) == 0 {
+						return errors.New(i18n.T("adguard.rules.empty"))
+					}
+					return err
+				},
+				func() []string {
+					lines := make([]string, 0, len(rules))
+					for _, r := range rules {
+						lines = append(lines, RuleLine(r))
+					}
+					return lines
+				})
+		case "adguard.rules.add":
+			ac.addAdGuardRule(c)
+		case "adguard.rules.remove":
+			f, ok := ac.loadAdGuardFiltering(c)
+			if !ok {
+				return true
+			}
+			rules := adguard.ClientRules(f.UserRules)
+			labels := make([]string, 0, len(rules))
+			for _, r := range rules {
+				labels = append(labels, RuleLine(r))
+			}
+			index, ok := ac.adguardPick(i18n.T("adguard.rules.pick"), labels)
+			if !ok {
+				return true
+			}
+			rule := rules[index]
+			ac.runAdGuardTask(i18n.T("adguard.task.rule_remove"),
+				func() error { return c.RemoveUserRule(rule.String()) }, nil)
+		default:
+			return false
+		}
+		return true
+	}, i18n.T("loop.adguard"))
+}
+
+// addAdGuardRule запрашивает клиента, домен и вид правила и добавляет его в пользовательские правила
+func (ac *AppConfig) addAdGuardRule(c adguard.Client) {
+	queue := ac.newScreenQueue(i18n.T("adguard.rules.title"))
+
+	hint := i18n.T("adguard.input.client_hint")
+	if known, err := c.Clients(); err == nil && len(known) > 0 {
+		names := make([]string, 0, len(known))
+		for _, k := range known {
+			names = append(names, k.Name)
+		}
+		hint = i18n.T("adguard.input.client_known", strings.Join(names, ", "))
+	}
+	client := termos.NewInputTask(i18n.T("adguard.input.client"), hint)
+	domain := termos.NewInputTask(i18n.T("adguard.input.domain"), i18n.T("adguard.input.domain_hint"))
+	kind := termos.NewSingleSelectTask(i18n.T("adguard.input.rule_kind"),
+		[]string{i18n.T("adguard.rules.block"), i18n.T("adguard.rules.allow")})
+
+	add := termos.NewFuncTask(i18n.T("adguard.task.rule_add"),
+		func() error {
+			rule := adguard.ClientRule{
+				Domain: strings.TrimSpace(domain.GetValue()),
+				Client: strings.TrimSpace(client.GetValue()),
+				Allow:  kind.GetSelectedIndex() == 1,
+			}
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+			if err := c.AddUserRule(rule.String()); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("adguard.log.rule_added"), rule.String())
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(client, domain, kind, add)
+	ac.runScreen(queue)
+}
+
+// setupAdGuard устанавливает AdGuard Home и проводит первоначальную настройку
+// или сохраняет параметры подключения к уже настроенному экземпляру
+func (ac *AppConfig) setupAdGuard() {
+	if !adguard.Service.Installed() {
+		ac.adviseSwap(i18n.T("network.option.adguard"), adguard.Service.Runner)
+		if _, ok := ac.resolvePorts(i18n.T("adguard.queue.title"), adguard.Service, adguard.WizardPorts()); !ok {
+			return
+		}
+	}
+	queue := ac.newScreenQueue(i18n.T("adguard.setup.title"))
+	c := adguard.Client{BaseURL: adguard.DefaultURL}
+
+	var needsSetup bool
+	install := termos.NewFuncTask(i18n.T("adguard.task.install"),
+		func() error {
+			var err error
+			c, needsSetup, err = ac.InstallAdGuard()
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			if needsSetup {
+				return []string{i18n.T("adguard.setup.wizard")}
+			}
+			return []string{i18n.T("adguard.setup.configured")}
+		}),
+		termos.WithStopOnError(true),
+	)
+	queue.AddTasks(install)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if install.HasError() || ac.IsContextCancelled() {
+		return
+	}
+
+	if needsSetup {
+		ac.runAdGuardWizard(c)
+	} else {
+		ac.connectAdGuard(c)
+	}
+}
+
+// InstallAdGuard устанавливает и запускает AdGuard Home, если нужно, и дожидается ответа API.
+// Возвращает клиент по адресу из конфигурации; true — ожидается первоначальная настройка
+func (ac *AppConfig) InstallAdGuard() (adguard.Client, bool, error) {
+	c := adguard.Client{BaseURL: valueOr(ac.Conf.AdGuard.URL, adguard.DefaultURL)}
+	if !adguard.Service.Installed() {
+		ac.Log.Info(i18n.T("adguard.log.install"))
+		if err := adguard.Service.Install(); err != nil {
+			return c, false, err
+		}
+	}
+	if !adguard.Service.Running() {
+		if err := adguard.Service.Start(); err != nil {
+			return c, false, err
+		}
+	}
+	if err := c.WaitReady(30 * time.Second); err != nil {
+		return c, false, err
+	}
+	needsSetup, err := c.NeedsSetup()
+	return c, needsSetup, err
+}
+
+// runAdGuardWizard запрашивает порты и учётную запись и завершает первоначальную настройку
+func (ac *AppConfig) runAdGuardWizard(c adguard.Client) {
+	queue := ac.newScreenQueue(i18n.T("adguard.setup.title"))
+
+	webPort := termos.NewInputTask(i18n.T("adguard.input.web_port"), i18n.T("proxy.input.keep_hint"))
+	webPort.WithPlaceholder("3000").WithAllowEmpty(true)
+	dnsPort := termos.NewInputTask(i18n.T("adguard.input.dns_port"), i18n.T("adguard.input.dns_port_hint"))
+	dnsPort.WithPlaceholder("53").WithAllowEmpty(true)
+	user := termos.NewInputTask(i18n.T("adguard.input.user"), i18n.T("adguard.input.user_hint"))
+	user.WithPlaceholder("admin").WithAllowEmpty(true)
+	password := termos.NewInputTask(i18n.T("adguard.input.password"), i18n.T("adguard.input.password_hint"))
+	password.WithInputType(termos.InputTypePassword)
+
+	queue.AddTasks(webPort, dnsPort, user, password)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if password.HasError() || ac.IsContextCancelled() {
+		return
+	}
+
+	web, err := portValue(webPort.GetValue(), adguard.WizardPort)
+	var dns int
+	if err == nil {
+		dns, err = portValue(dnsPort.GetValue(), 53)
+	}
+	if err == nil {
+		needs, ok := ac.resolvePorts(i18n.T("adguard.queue.title"), adguard.Service, adguard.Ports(web, dns))
+		if !ok {
+			return
+		}
+		web, dns = needs[0].Port, needs[1].Port
+	}
+
+	queue = ac.newScreenQueue(i18n.T("adguard.setup.title"))
+	var saved conf.AdGuardConfig
+	apply := termos.NewFuncTask(i18n.T("adguard.task.setup"),
+		func() error {
+			if err != nil {
+				return err
+			}
+			saved, err = ac.CompleteAdGuardSetup(c, adguard.SetupConfig{
+				Web:      adguard.SetupAddress{IP: "0.0.0.0", Port: web},
+				DNS:      adguard.SetupAddress{IP: "0.0.0.0", Port: dns},
+				Username: valueOr(user.GetValue(), "admin"),
+				Password: password.GetValue(),
+			})
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("adguard.setup.saved", saved.URL, ac.ConfFile)}
+		}),
+		termos.WithStopOnError(false),
+	)
+
+	queue.AddTasks(apply)
+	ac.runScreen(queue)
+}
+
+// CompleteAdGuardSetup завершает первоначальную настройку AdGuard Home и сохраняет
+// параметры подключения в конфигурацию терема
+func (ac *AppConfig) CompleteAdGuardSetup(c adguard.Client, cfg adguard.SetupConfig) (conf.AdGuardConfig, error) {
+	if err := c.Setup(cfg); err != nil {
+		return conf.AdGuardConfig{}, err
+	}
+	saved := conf.AdGuardConfig{URL: fmt.Sprintf("http://127.0.0.1:%d", cfg.Web.Port), User: cfg.Username, Password: cfg.Password}
+	ac.Log.Info(i18n.T("adguard.log.setup"), cfg.Web.Port, cfg.DNS.Port)
+	return saved, ac.saveAdGuardConfig(saved)
+}
+
+// connectAdGuard запрашивает адрес и учётную запись уже настроенного AdGuard Home и проверяет вход
+func (ac *AppConfig) connectAdGuard(c adguard.Client) {
+	queue := ac.newScreenQueue(i18n.T("adguard.setup.title"))
+
+	url := termos.NewInputTask(i18n.T("adguard.input.url"), i18n.T("proxy.input.keep_hint"))
+	url.WithPlaceholder(c.BaseURL).WithAllowEmpty(true)
+	user := termos.NewInputTask(i18n.T("adguard.input.user"), i18n.T("proxy.input.keep_hint"))
+	user.WithPlaceholder(ac.Conf.AdGuard.User).WithAllowEmpty(true)
+	password := termos.NewInputTask(i18n.T("adguard.input.password"), i18n.T("proxy.input.keep_hint"))
+	password.WithInputType(termos.InputTypePassword).WithAllowEmpty(true)
+
+	var saved conf.AdGuardConfig
+	var st adguard.Status
+	check := termos.NewFuncTask(i18n.T("adguard.task.connect"),
+		func() error {
+			saved = conf.AdGuardConfig{
+				URL:      valueOr(url.GetValue(), c.BaseURL),
+				User:     valueOr(user.GetValue(), ac.Conf.AdGuard.User),
+				Password: ac.Conf.AdGuard.Password,
+			}
+			if password.GetValue() != "" {
+				saved.Password = password.GetValue()
+			}
+			var err error
+			st, err = ac.ConnectAdGuard(saved)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{
+				i18n.T("adguard.status.version", st.Version),
+				i18n.T("adguard.setup.saved", saved.URL, ac.ConfFile),
+			}
+		}),
+		termos.WithStopOnError(false),
+	)
+
+	queue.AddTasks(url, user, password, check)
+	ac.runScreen(queue)
+}
+
+// ConnectAdGuard проверяет вход в уже настроенный AdGuard Home и сохраняет
+// параметры подключения в конфигурацию терема
+func (ac *AppConfig) ConnectAdGuard(cfg conf.AdGuardConfig) (adguard.Status, error) {
+	st, err := adguard.Client{BaseURL: cfg.URL, User: cfg.User, Password: cfg.Password}.Status()
+	if err != nil {
+		return st, err
+	}
+	return st, ac.saveAdGuardConfig(cfg)
+}
+
+// saveAdGuardConfig сохраняет параметры подключения в конфигурацию терема
+func (ac *AppConfig) saveAdGuardConfig(cfg conf.AdGuardConfig) error {
+	ac.Conf.AdGuard = cfg
+	if err := ac.Conf.Save(ac.ConfFile); err != nil {
+		return fmt.Errorf(i18n.T("adguard.error.save"), ac.ConfFile, err)
+	}
+	return nil
+}
+
+// portValue разбирает номер порта; пустой ввод возвращает def
+func portValue(value string, def int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf(i18n.T("proxy.error.port_value"), value)
+	}
+	return port, nil
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/clients"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/termos"
+)
+
+// Пункты меню устройств сети
+var clientsActions = []string{
+	"clients.action.list",
+	"clients.action.add",
+	"clients.action.back",
+}
+
+// SelectClientsApp показывает устройства локальной сети и позволяет добавить статическую привязку
+func (ac *AppConfig) SelectClientsApp() {
+	ac.Log.Info(i18n.T("network.log.clients"))
+
+	queue := ac.newScreenQueue(i18n.T("clients.queue.title"))
+	menu := termos.NewSingleSelectTask(i18n.T("clients.task.title"), labelsFor(clientsActions))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if menu.HasError() {
+		return
+	}
+
+	switch clientsActions[menu.GetSelectedIndex()] {
+	case "clients.action.list":
+		ac.showClients()
+	case "clients.action.add":
+		ac.addStaticLease()
+	}
+}
+
+// showClients выводит список устройств с поиском по имени, адресу, MAC или производителю
+func (ac *AppConfig) showClients() {
+	queue := ac.newScreenQueue(i18n.T("clients.queue.title"))
+
+	search := termos.NewInputTask(i18n.T("clients.search.title"), i18n.T("clients.search.prompt"))
+	search.WithAllowEmpty(true)
+
+	var found []clients.Client
+	list := termos.NewFuncTask(i18n.T("clients.list.title"),
+		func() error {
+			found = clients.Filter(clients.Collector{}.Collect(), search.GetValue())
+			if len(found) == 0 {
+				return errors.New(i18n.T("clients.list.empty"))
+			}
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			lines := make([]string, 0, len(found)+1)
+			for _, client := range found {
+				lines = append(lines, ClientLine(client))
+			}
+			return append(lines, i18n.T("clients.list.total", len(found)))
+		}),
+		termos.WithStopOnError(false),
+	)
+
+	queue.AddTasks(search, list)
+	ac.runScreen(queue)
+}
+
+// addStaticLease запрашивает MAC, IP и имя устройства и добавляет статическую привязку
+func (ac *AppConfig) addStaticLease() {
+	queue := ac.newScreenQueue(i18n.T("clients.add.title"))
+
+	mac := termos.NewInputTask(i18n.T("clients.add.mac"), i18n.T("clients.add.mac_prompt"))
+	ip := termos.NewInputTask(i18n.T("clients.add.ip"), i18n.T("clients.add.ip_prompt")).
+		WithValidator(termos.DefaultValidators.IPv4())
+	name := termos.NewInputTask(i18n.T("clients.add.hostname"), i18n.T("clients.add.hostname_prompt"))
+	name.WithAllowEmpty(true)
+
+	save := termos.NewFuncTask(i18n.T("clients.add.save"),
+		func() error {
+			host := clients.StaticHost{MAC: mac.GetValue(), IP: ip.GetValue(), Hostname: name.GetValue()}
+			if err := (clients.Collector{}).AddStatic(host); err != nil {
+				ac.Log.Error(i18n.T("clients.log.add_failed"), err)
+				return err
+			}
+			ac.Log.Info(i18n.T("clients.log.added"), host.MAC, host.IP)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+
+	queue.AddTasks(mac, ip, name, save)
+	ac.runScreen(queue)
+}
+
+// ClientLine возвращает строку с описанием устройства для списка
+func ClientLine(c clients.Client) string {
+	status := i18n.T("clients.status.offline")
+	if c.Online {
+		status = i18n.T("clients.status.online")
+	}
+
+	name := c.Hostname
+	if name == "" {
+		name = "-"
+	}
+	vendor := c.Vendor
+	switch vendor {
+	case "":
+		vendor = "-"
+	case clients.VendorRandomized:
+		vendor = i18n.T("clients.vendor.randomized")
+	}
+
+	parts := []string{fmt.Sprintf("[%s] %-15s %s %s (%s)", status, c.IP, c.MAC, name, vendor)}
+	if len(c.IPv6) > 0 {
+		parts = append(parts, strings.Join(c.IPv6, ", "))
+	}
+	switch {
+	case c.Static:
+		parts = append(parts, i18n.T("clients.lease.static"))
+	case !c.Expiry.IsZero():
+		parts = append(parts, i18n.T("clients.lease.expires", c.Expiry.Local().Format(time.DateTime)))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,290 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/dns"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/termos"
+)
+
+// Действия в разделе DNS
+var dnsActions = []string{
+	"dns.action.status",
+	"dns.action.upstreams",
+	"dns.action.hosts",
+	"dns.action.forwards",
+	"dns.action.test",
+	"dns.action.back",
+}
+
+// DefaultTestName имя, запрашиваемое при проверке серверов по умолчанию
+const DefaultTestName = "example.com"
+
+// SelectDNSApp отображает раздел управления DNS до выбора «Назад»
+func (ac *AppConfig) SelectDNSApp() {
+	ac.Log.Info(i18n.T("network.log.dns"))
+	m := dns.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		queue := ac.newScreenQueue(i18n.T("dns.queue.title"))
+		menu := termos.NewSingleSelectTask(i18n.T("dns.task.action"), labelsFor(dnsActions))
+		queue.AddTasks(menu)
+		if err := queue.Run(); err != nil {
+			ac.Log.Error(i18n.T("screen.error"), err)
+			return false
+		}
+		if menu.HasError() || ac.IsContextCancelled() {
+			return false
+		}
+
+		switch dnsActions[menu.GetSelectedIndex()] {
+		case "dns.action.status":
+			ac.showDNSStatus(m)
+		case "dns.action.upstreams":
+			ac.editDNSUpstreams(m)
+		case "dns.action.hosts":
+			ac.editDNSHosts(m)
+		case "dns.action.forwards":
+			ac.editDNSForwards(m)
+		case "dns.action.test":
+			ac.testDNS(m)
+		default:
+			return false
+		}
+		return true
+	}, i18n.T("loop.dns"))
+}
+
+// CurrentDNSSettings возвращает параметры терема или серверы, найденные в конфигурации dnsmasq
+func CurrentDNSSettings(m dns.Manager) dns.Settings {
+	st := m.Detect()
+	s := st.Settings
+	if !st.Managed {
+		s.Upstreams = st.Upstreams
+	}
+	return s
+}
+
+// DNSStateSummary возвращает строки с описанием состояния DNS
+func DNSStateSummary(st dns.State) []string {
+	resolver := st.Resolver
+	if resolver == "" {
+		resolver = i18n.T("dns.status.no_resolver")
+	}
+	lines := []string{i18n.T("dns.status.resolver", resolver)}
+	if len(st.Backends) > 0 {
+		lines = append(lines, i18n.T("dns.status.backends", strings.Join(st.Backends, ", ")))
+	}
+	if st.Managed {
+		lines = append(lines, i18n.T("dns.status.managed", dns.DropInFile))
+	} else {
+		lines = append(lines, i18n.T("dns.status.unmanaged"))
+	}
+	for _, u := range st.Upstreams {
+		lines = append(lines, i18n.T("dns.status.upstream", u.String(), u.Proto))
+	}
+	for _, h := range st.Settings.Hosts {
+		lines = append(lines, i18n.T("dns.status.host", h.Name, h.IP))
+	}
+	for _, f := range st.Settings.Forwards {
+		lines = append(lines, i18n.T("dns.status.forward", f.Domain, f.Server))
+	}
+	return lines
+}
+
+// DNSResultLine возвращает строку с результатом проверки сервера
+func DNSResultLine(res dns.Result) string {
+	rtt := res.RTT.Round(time.Millisecond)
+	if res.Error != "" {
+		return i18n.T("dns.test.failed", res.Upstream, rtt, res.Error)
+	}
+	return i18n.T("dns.test.ok", res.Upstream, rtt, strings.Join(res.Addrs, ", "))
+}
+
+// showDNSStatus показывает активный резолвер, вышестоящие серверы и правила
+func (ac *AppConfig) showDNSStatus(m dns.Manager) {
+	queue := ac.newScreenQueue(i18n.T("dns.queue.title"))
+
+	var st dns.State
+	var processes []string
+	task := termos.NewFuncTask(i18n.T("dns.status.title"),
+		func() error {
+			st = m.Detect()
+			processes = appProcesses(m.Runner, dns.Dnsmasq.ProcessName(), dns.Stubby.ProcessName(), dns.DNSCrypt.ProcessName())
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string { return append(DNSStateSummary(st), processes...) }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// applyDNSTask возвращает задачу, применяющую параметры, собранные функцией build
+func (ac *AppConfig) applyDNSTask(m dns.Manager, build func() (dns.Settings, error)) *termos.FuncTask {
+	return termos.NewFuncTask(i18n.T("dns.task.apply"),
+		func() error {
+			s, err := build()
+			if err != nil {
+				return err
+			}
+			return ac.ApplyDNS(m, s)
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("dns.status.managed", dns.DropInFile)}
+		}),
+		termos.WithStopOnError(false),
+	)
+}
+
+// ApplyDNS записывает параметры DNS и перезапускает резолвер
+func (ac *AppConfig) ApplyDNS(m dns.Manager, s dns.Settings) error {
+	if err := m.Apply(s); err != nil {
+		ac.Log.Error(i18n.T("dns.log.apply_failed"), err)
+		return err
+	}
+	ac.Log.Info(i18n.T("dns.log.applied"), len(s.Upstreams), len(s.Hosts), len(s.Forwards))
+	return nil
+}
+
+// editDNSUpstreams запрашивает список вышестоящих серверов
+func (ac *AppConfig) editDNSUpstreams(m dns.Manager) {
+	queue := ac.newScreenQueue(i18n.T("dns.queue.title"))
+	current := CurrentDNSSettings(m)
+
+	names := make([]string, 0, len(current.Upstreams))
+	for _, u := range current.Upstreams {
+		names = append(names, u.String())
+	}
+	input := termos.NewInputTask(i18n.T("dns.input.upstreams"), i18n.T("dns.input.upstreams_hint"))
+	input.WithPlaceholder(strings.Join(names, ", ")).WithAllowEmpty(true)
+
+	apply := ac.applyDNSTask(m, func() (dns.Settings, error) {
+		s := current
+		if value := strings.TrimSpace(input.GetValue()); value != "" {
+			upstreams, err := dns.ParseUpstreams(splitList(value))
+			if err != nil {
+				return s, err
+			}
+			s.Upstreams = upstreams
+		}
+		return s, nil
+	})
+
+	queue.AddTasks(input, apply)
+	ac.runScreen(queue)
+}
+
+// editDNSHosts запрашивает локальные подмены имён
+func (ac *AppConfig) editDNSHosts(m dns.Manager) {
+	queue := ac.newScreenQueue(i18n.T("dns.queue.title"))
+	current := CurrentDNSSettings(m)
+
+	items := make([]string, 0, len(current.Hosts))
+	for _, h := range current.Hosts {
+		items = append(items, h.Name+"="+h.IP)
+	}
+	input := termos.NewInputTask(i18n.T("dns.input.hosts"), i18n.T("dns.input.hosts_hint"))
+	input.WithPlaceholder(strings.Join(items, ", ")).WithAllowEmpty(true)
+
+	apply := ac.applyDNSTask(m, func() (dns.Settings, error) {
+		s := current
+		switch value := strings.TrimSpace(input.GetValue()); value {
+		case "":
+		case "-":
+			s.Hosts = nil
+		default:
+			hosts, err := dns.ParseHosts(splitList(value))
+			if err != nil {
+				return s, err
+			}
+			s.Hosts = hosts
+		}
+		return s, nil
+	})
+
+	queue.AddTasks(input, apply)
+	ac.runScreen(queue)
+}
+
+// editDNSForwards запрашивает правила пересылки запросов по доменам
+func (ac *AppConfig) editDNSForwards(m dns.Manager) {
+	queue := ac.newScreenQueue(i18n.T("dns.queue.title"))
+	current := CurrentDNSSettings(m)
+
+	items := make([]string, 0, len(current.Forwards))
+	for _, f := range current.Forwards {
+		items = append(items, f.Domain+"="+f.Server)
+	}
+	input := termos.NewInputTask(i18n.T("dns.input.forwards"), i18n.T("dns.input.forwards_hint"))
+	input.WithPlaceholder(strings.Join(items, ", ")).WithAllowEmpty(true)
+
+	apply := ac.applyDNSTask(m, func() (dns.Settings, error) {
+		s := current
+		switch value := strings.TrimSpace(input.GetValue()); value {
+		case "":
+		case "-":
+			s.Forwards = nil
+		default:
+			forwards, err := dns.ParseForwards(splitList(value))
+			if err != nil {
+				return s, err
+			}
+			s.Forwards = forwards
+		}
+		return s, nil
+	})
+
+	queue.AddTasks(input, apply)
+	ac.runScreen(queue)
+}
+
+// TestUpstreams возвращает серверы для проверки: локальный резолвер и все вышестоящие
+func TestUpstreams(st dns.State) []dns.Upstream {
+	var upstreams []dns.Upstream
+	if st.Resolver != "" {
+		upstreams = append(upstreams, dns.Upstream{Proto: dns.ProtoPlain, Address: "127.0.0.1", Port: 53})
+	}
+	return append(upstreams, st.Upstreams...)
+}
+
+// testDNS запрашивает имя через каждый сервер и показывает время ответа
+func (ac *AppConfig) testDNS(m dns.Manager) {
+	queue := ac.newScreenQueue(i18n.T("dns.queue.title"))
+
+	name := termos.NewInputTask(i18n.T("dns.input.test_name"), i18n.T("proxy.input.keep_hint"))
+	name.WithPlaceholder(DefaultTestName).WithAllowEmpty(true)
+
+	var results []dns.Result
+	task := termos.NewFuncTask(i18n.T("dns.test.title"),
+		func() error {
+			upstreams := TestUpstreams(m.Detect())
+			if len(upstreams) == 0 {
+				return errors.New(i18n.T("dns.error.no_upstreams"))
+			}
+			query := valueOr(name.GetValue(), DefaultTestName)
+			results = dns.Tester{}.TestAll(context.Background(), upstreams, query)
+			for _, res := range results {
+				if res.Error != "" {
+					ac.Log.Warn(i18n.T("dns.log.test_failed"), res.Upstream, res.Error)
+				}
+			}
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			lines := make([]string, 0, len(results))
+			for _, res := range results {
+				lines = append(lines, DNSResultLine(res))
+			}
+			return lines
+		}),
+		termos.WithStopOnError(false),
+	)
+
+	queue.AddTasks(name, task)
+	ac.runScreen(queue)
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/netif"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/termos"
+)
+
+// ifacesSampleInterval интервал между замерами счётчиков для расчёта скорости
+const ifacesSampleInterval = time.Second
+
+// SelectInterfacesApp показывает обзор сетевых интерфейсов
+func (ac *AppConfig) SelectInterfacesApp() {
+	ac.Log.Info(i18n.T("network.log.interfaces"))
+
+	queue := ac.newScreenQueue(i18n.T("ifaces.queue.title"))
+
+	ifaces, err := netif.NewReader().Collect(ifacesSampleInterval)
+	if err != nil {
+		ac.Log.Error(i18n.T("ifaces.error.collect"), err)
+		queue.AddTasks(termos.NewFuncTask(i18n.T("ifaces.queue.title"),
+			func() error { return err },
+			termos.WithStopOnError(false)))
+		ac.runScreen(queue)
+		return
+	}
+
+	for _, iface := range ifaces {
+		task := termos.NewFuncTask(InterfaceTitle(iface),
+			func() error { return nil },
+			termos.WithSummaryFunction(func() []string { return InterfaceSummary(iface) }),
+			termos.WithStopOnError(false),
+		)
+		queue.AddTasks(task)
+	}
+
+	ac.runScreen(queue)
+}
+
+// InterfaceTitle возвращает заголовок интерфейса: имя, вид и состояние
+func InterfaceTitle(iface netif.Interface) string {
+	return fmt.Sprintf("%s (%s, %s)", iface.Name, i18n.T("ifaces.kind."+iface.Kind), iface.State)
+}
+
+// InterfaceSummary возвращает строки с подробностями об интерфейсе
+func InterfaceSummary(iface netif.Interface) []string {
+	lines := []string{fmt.Sprintf("MTU %d", iface.MTU)}
+	if iface.MAC != "" {
+		lines[0] += ", MAC " + iface.MAC
+	}
+	if len(iface.IPv4) > 0 {
+		lines = append(lines, "IPv4: "+strings.Join(iface.IPv4, ", "))
+	}
+	if len(iface.IPv6) > 0 {
+		lines = append(lines, "IPv6: "+strings.Join(iface.IPv6, ", "))
+	}
+	if iface.Master != "" {
+		lines = append(lines, i18n.T("ifaces.label.master", iface.Master))
+	}
+	if len(iface.Members) > 0 {
+		lines = append(lines, i18n.T("ifaces.label.members", strings.Join(iface.Members, ", ")))
+	}
+	lines = append(lines,
+		i18n.T("ifaces.label.traffic",
+			utils.FormatBytes(iface.Stats.RxBytes), utils.FormatBytes(uint64(iface.RxRate)),
+			utils.FormatBytes(iface.Stats.TxBytes), utils.FormatBytes(uint64(iface.TxRate))))
+	if errs := iface.Stats.RxErrors + iface.Stats.TxErrors; errs > 0 || iface.Stats.RxDropped+iface.Stats.TxDropped > 0 {
+		lines = append(lines, i18n.T("ifaces.label.errors", errs, iface.Stats.RxDropped+iface.Stats.TxDropped))
+	}
+	return lines
+}
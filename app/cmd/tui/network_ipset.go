@@ -0,0 +1,437 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ipset"
+	"github.com/qzeleza/termos"
+)
+
+// Действия со списком ipset
+var ipsetActions = []string{
+	"ipset.action.entries",
+	"ipset.action.add",
+	"ipset.action.remove",
+	"ipset.action.import",
+	"ipset.action.refresh",
+	"ipset.action.schedule",
+	"ipset.action.destroy",
+	"ipset.action.back",
+}
+
+// ipsetShowLimit число записей, показываемых на экране
+const ipsetShowLimit = 100
+
+// SelectIPSetApp отображает списки ipset и действия с ними до выбора «Назад»
+func (ac *AppConfig) SelectIPSetApp() {
+	ac.Log.Info(i18n.T("network.log.ipset"))
+	m := ipset.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		sets, err := m.List()
+		if err != nil {
+			ac.runIPSetTask(i18n.T("ipset.task.list"), func() error { return err }, nil)
+			return false
+		}
+
+		labels := make([]string, 0, len(sets)+3)
+		for _, s := range sets {
+			labels = append(labels, ac.ipsetLabel(s))
+		}
+		labels = append(labels, i18n.T("ipset.action.create"), i18n.T("ipset.action.restore"))
+		index, ok := ac.ipsetPick(i18n.T("ipset.task.pick"), labels)
+		if !ok {
+			return false
+		}
+
+		switch index - len(sets) {
+		case 0:
+			ac.createIPSet(m)
+		case 1:
+			restored := 0
+			ac.runIPSetTask(i18n.T("ipset.task.restore"),
+				func() error {
+					var err error
+					restored, err = m.Restore()
+					return err
+				},
+				func() []string { return []string{i18n.T("ipset.restore.done", restored)} })
+		default:
+			ac.ipsetLoop(m, sets[index])
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.ipset"))
+}
+
+// ipsetLabel возвращает строку списка; списки терема отмечаются звёздочкой и периодом обновления
+func (ac *AppConfig) ipsetLabel(s ipset.Info) string {
+	if cfg, ok := ac.Conf.IPSet(s.Name); ok {
+		return i18n.T("ipset.line.owned", s.Name, s.Entries, len(cfg.Sources), refreshLabel(cfg.Refresh))
+	}
+	return i18n.T("ipset.line", s.Name, s.Type, s.Entries)
+}
+
+// refreshLabel возвращает локализованный период обновления
+func refreshLabel(period string) string {
+	return i18n.T("ipset.refresh." + valueOr(period, "never"))
+}
+
+// ipsetPick показывает список и возвращает индекс выбранного элемента; последний пункт — «Назад»
+func (ac *AppConfig) ipsetPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("ipset.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("ipset.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// runIPSetTask показывает экран с одной задачей
+func (ac *AppConfig) runIPSetTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("ipset.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// saveIPSetConfig сохраняет описание списка в конфигурацию терема
+func (ac *AppConfig) saveIPSetConfig(set conf.IPSetConfig) error {
+	ac.Conf.SetIPSet(set)
+	if err := ac.Conf.Save(ac.ConfFile); err != nil {
+		return fmt.Errorf(i18n.T("ipset.error.save"), ac.ConfFile, err)
+	}
+	return nil
+}
+
+// IPSetResultLine возвращает строку с итогом импорта
+func IPSetResultLine(name string, res ipset.Result) string {
+	return i18n.T("ipset.import.result", name, res.Count, res.Domains, res.Unresolved, res.Skipped)
+}
+
+// refreshPeriodLabels возвращает названия периодов обновления для меню
+func refreshPeriodLabels() []string {
+	labels := make([]string, 0, len(ipset.RefreshPeriods))
+	for _, p := range ipset.RefreshPeriods {
+		labels = append(labels, refreshLabel(p))
+	}
+	return labels
+}
+
+// createIPSet запрашивает имя, семейство, источники и период обновления и создаёт список
+func (ac *AppConfig) createIPSet(m ipset.Manager) {
+	queue := ac.newScreenQueue(i18n.T("ipset.create.title"))
+	name := termos.NewInputTask(i18n.T("ipset.input.name"), i18n.T("ipset.input.name_hint"))
+	family := termos.NewSingleSelectTask(i18n.T("ipset.input.family"), []string{"IPv4", "IPv6"})
+	sources := termos.NewInputTask(i18n.T("ipset.input.sources"), i18n.T("ipset.input.sources_hint"))
+	sources.WithAllowEmpty(true)
+	period := termos.NewSingleSelectTask(i18n.T("ipset.input.refresh"), refreshPeriodLabels())
+
+	var res ipset.Result
+	var set conf.IPSetConfig
+	create := termos.NewFuncTask(i18n.T("ipset.task.create"),
+		func() error {
+			set = conf.IPSetConfig{
+				Name:    strings.TrimSpace(name.GetValue()),
+				Family:  []string{ipset.FamilyIPv4, ipset.FamilyIPv6}[family.GetSelectedIndex()],
+				Sources: splitList(sources.GetValue()),
+				Refresh: ipset.RefreshPeriods[period.GetSelectedIndex()],
+			}
+			var err error
+			res, err = ac.CreateIPSet(m, set)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{IPSetResultLine(set.Name, res), i18n.T("ipset.status.refresh", refreshLabel(set.Refresh))}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(name, family, sources, period, create)
+	ac.runScreen(queue)
+}
+
+// CreateIPSet создаёт список, заполняет его из источников, задаёт период обновления
+// и сохраняет описание списка в конфигурацию
+func (ac *AppConfig) CreateIPSet(m ipset.Manager, set conf.IPSetConfig) (ipset.Result, error) {
+	var res ipset.Result
+	if set.Refresh != ipset.RefreshNever && len(set.Sources) == 0 {
+		return res, fmt.Errorf(i18n.T("ipset.error.no_sources"), set.Name)
+	}
+	if err := m.Create(set.Name, set.Family); err != nil {
+		return res, err
+	}
+	if len(set.Sources) > 0 {
+		var err error
+		if res, err = m.Refresh(context.Background(), ipset.Importer{}, set.Name, set.Family, set.Sources); err != nil {
+			return res, err
+		}
+	} else if err := m.Save(set.Name); err != nil {
+		return res, err
+	}
+	if err := m.Schedule(set.Name, set.Refresh); err != nil {
+		return res, err
+	}
+	ac.Log.Info(i18n.T("ipset.log.created"), set.Name, set.Family, res.Count)
+	return res, ac.saveIPSetConfig(set)
+}
+
+// ipsetLoop показывает действия с выбранным списком
+func (ac *AppConfig) ipsetLoop(m ipset.Manager, info ipset.Info) {
+	family := valueOr(info.Family, ipset.FamilyIPv4)
+
+	ac.ContextualLoop(func() bool {
+		index, ok := ac.ipsetPick(i18n.T("ipset.task.action", info.Name), labelsFor(ipsetActions[:len(ipsetActions)-1]))
+		if !ok {
+			return false
+		}
+
+		switch ipsetActions[index] {
+		case "ipset.action.entries":
+			ac.showIPSetEntries(m, info.Name)
+		case "ipset.action.add", "ipset.action.remove":
+			ac.editIPSetEntry(m, info.Name, family, ipsetActions[index] == "ipset.action.add")
+		case "ipset.action.import":
+			ac.importIPSet(m, info.Name, family)
+		case "ipset.action.refresh":
+			ac.refreshIPSet(m, info.Name, family)
+		case "ipset.action.schedule":
+			ac.scheduleIPSet(m, info.Name, family)
+		case "ipset.action.destroy":
+			return !ac.destroyIPSet(m, info.Name)
+		default:
+			return false
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.ipset"))
+}
+
+// showIPSetEntries показывает записи списка (не более ipsetShowLimit)
+func (ac *AppConfig) showIPSetEntries(m ipset.Manager, name string) {
+	var entries []string
+	ac.runIPSetTask(i18n.T("ipset.task.entries", name),
+		func() error {
+			var err error
+			entries, err = m.Entries(name)
+			if err == nil && len(entries) == 0 {
+				return errors.New(i18n.T("ipset.entries.empty"))
+			}
+			return err
+		},
+		func() []string {
+			lines := slices.Clone(entries[:min(len(entries), ipsetShowLimit)])
+			if len(entries) > ipsetShowLimit {
+				lines = append(lines, i18n.T("ipset.entries.more", len(entries)-ipsetShowLimit))
+			}
+			return lines
+		})
+}
+
+// editIPSetEntry запрашивает адрес или подсеть и добавляет или удаляет запись
+func (ac *AppConfig) editIPSetEntry(m ipset.Manager, name, family string, add bool) {
+	queue := ac.newScreenQueue(i18n.T("ipset.queue.title"))
+	entry := termos.NewInputTask(i18n.T("ipset.input.entry"), i18n.T("ipset.input.entry_hint"))
+
+	title := i18n.T("ipset.task.remove", name)
+	if add {
+		title = i18n.T("ipset.task.add", name)
+	}
+	task := termos.NewFuncTask(title,
+		func() error {
+			_, err := ac.EditIPSetEntry(m, name, family, entry.GetValue(), add)
+			return err
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(entry, task)
+	ac.runScreen(queue)
+}
+
+// EditIPSetEntry добавляет запись в список или удаляет её и сохраняет список;
+// возвращает запись в том виде, в каком она попала в список
+func (ac *AppConfig) EditIPSetEntry(m ipset.Manager, name, family, entry string, add bool) (string, error) {
+	if add {
+		var err error
+		if entry, err = m.Add(name, family, entry); err != nil {
+			return entry, err
+		}
+		ac.Log.Info(i18n.T("ipset.log.added"), entry, name)
+	} else {
+		if err := m.Remove(name, family, entry); err != nil {
+			return entry, err
+		}
+		ac.Log.Info(i18n.T("ipset.log.removed"), entry, name)
+	}
+	return entry, m.Save(name)
+}
+
+// importIPSet загружает записи из файла или по адресу HTTP и заменяет или дополняет ими список
+func (ac *AppConfig) importIPSet(m ipset.Manager, name, family string) {
+	queue := ac.newScreenQueue(i18n.T("ipset.import.title"))
+	source := termos.NewInputTask(i18n.T("ipset.input.source"), i18n.T("ipset.input.source_hint"))
+	mode := termos.NewSingleSelectTask(i18n.T("ipset.input.mode"),
+		[]string{i18n.T("ipset.import.append"), i18n.T("ipset.import.replace")})
+	remember := termos.NewYesNoTask(i18n.T("ipset.input.remember"), i18n.T("ipset.input.remember_question"))
+
+	var res ipset.Result
+	task := termos.NewFuncTask(i18n.T("ipset.task.import", name),
+		func() error {
+			var err error
+			res, err = ac.ImportIPSet(m, name, family, source.GetValue(), mode.GetSelectedIndex() == 1, remember.IsYes())
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return []string{IPSetResultLine(name, res)} }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(source, mode, remember, task)
+	ac.runScreen(queue)
+}
+
+// ImportIPSet загружает записи из источника source и дополняет ими список, а при replace
+// заменяет его содержимое. remember добавляет источник к сохранённым для обновления
+func (ac *AppConfig) ImportIPSet(m ipset.Manager, name, family, source string, replace, remember bool) (ipset.Result, error) {
+	src := strings.TrimSpace(source)
+	res, err := (ipset.Importer{}).Collect(context.Background(), []string{src}, family)
+	if err != nil {
+		return res, err
+	}
+	entries := res.Entries
+	if !replace {
+		current, err := m.Entries(name)
+		if err != nil {
+			return res, err
+		}
+		entries = append(current, entries...)
+		slices.Sort(entries)
+		entries = slices.Compact(entries)
+	}
+	if err := m.Replace(name, family, entries); err != nil {
+		return res, err
+	}
+	if err := m.Save(name); err != nil {
+		return res, err
+	}
+	ac.Log.Info(i18n.T("ipset.log.imported"), name, src, res.Count)
+
+	if !remember {
+		return res, nil
+	}
+	set, ok := ac.Conf.IPSet(name)
+	if !ok {
+		set = conf.IPSetConfig{Name: name, Family: family}
+	}
+	if !slices.Contains(set.Sources, src) {
+		set.Sources = append(set.Sources, src)
+	}
+	return res, ac.saveIPSetConfig(set)
+}
+
+// refreshIPSet заново собирает список из сохранённых источников
+func (ac *AppConfig) refreshIPSet(m ipset.Manager, name, family string) {
+	var res ipset.Result
+	ac.runIPSetTask(i18n.T("ipset.task.refresh", name),
+		func() error {
+			set, _ := ac.Conf.IPSet(name)
+			var err error
+			res, err = m.Refresh(context.Background(), ipset.Importer{}, name, family, set.Sources)
+			if err == nil {
+				ac.Log.Info(i18n.T("ipset.log.refreshed"), name, res.Count)
+			}
+			return err
+		},
+		func() []string { return []string{IPSetResultLine(name, res)} })
+}
+
+// scheduleIPSet задаёт период автоматического обновления списка
+func (ac *AppConfig) scheduleIPSet(m ipset.Manager, name, family string) {
+	set, ok := ac.Conf.IPSet(name)
+	if !ok {
+		set = conf.IPSetConfig{Name: name, Family: family}
+	}
+	queue := ac.newScreenQueue(i18n.T("ipset.queue.title"))
+	period := termos.NewSingleSelectTask(i18n.T("ipset.input.refresh"), refreshPeriodLabels()).
+		WithDefaultItem(max(slices.Index(ipset.RefreshPeriods, set.Refresh), 0))
+
+	task := termos.NewFuncTask(i18n.T("ipset.task.schedule", name),
+		func() error {
+			set.Refresh = ipset.RefreshPeriods[period.GetSelectedIndex()]
+			return ac.ScheduleIPSet(m, name, family, set.Refresh)
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("ipset.status.refresh", refreshLabel(set.Refresh))}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(period, task)
+	ac.runScreen(queue)
+}
+
+// ScheduleIPSet задаёт период автоматического обновления списка и сохраняет его в конфигурацию
+func (ac *AppConfig) ScheduleIPSet(m ipset.Manager, name, family, period string) error {
+	set, ok := ac.Conf.IPSet(name)
+	if !ok {
+		set = conf.IPSetConfig{Name: name, Family: family}
+	}
+	set.Refresh = period
+	if set.Refresh != ipset.RefreshNever && len(set.Sources) == 0 {
+		return fmt.Errorf(i18n.T("ipset.error.no_sources"), name)
+	}
+	if err := m.Schedule(name, set.Refresh); err != nil {
+		return err
+	}
+	return ac.saveIPSetConfig(set)
+}
+
+// destroyIPSet после подтверждения удаляет список, его расписание и описание; возвращает true при успехе
+func (ac *AppConfig) destroyIPSet(m ipset.Manager, name string) bool {
+	queue := ac.newScreenQueue(i18n.T("ipset.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("ipset.destroy.title"), i18n.T("ipset.destroy.question", name))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	destroyed := false
+	task := termos.NewFuncTask(i18n.T("ipset.task.destroy", name),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("ipset.cancelled"))
+			}
+			var err error
+			destroyed, err = ac.DestroyIPSet(m, name)
+			return err
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+	return destroyed
+}
+
+// DestroyIPSet удаляет список, его расписание и описание в конфигурации;
+// true — сам список удалён, даже если описание сохранить не удалось
+func (ac *AppConfig) DestroyIPSet(m ipset.Manager, name string) (bool, error) {
+	if err := m.Schedule(name, ipset.RefreshNever); err != nil {
+		return false, err
+	}
+	if err := m.Destroy(name); err != nil {
+		return false, err
+	}
+	ac.Log.Info(i18n.T("ipset.log.destroyed"), name)
+	if _, ok := ac.Conf.IPSet(name); ok {
+		ac.Conf.RemoveIPSet(name)
+		if err := ac.Conf.Save(ac.ConfFile); err != nil {
+			return true, fmt.Errorf(i18n.T("ipset.error.save"), ac.ConfFile, err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,324 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/netdiag"
+	"github.com/qzeleza/termos"
+)
+
+// Действия в разделе диагностики
+var netdiagActions = []string{
+	"netdiag.action.ping",
+	"netdiag.action.trace",
+	"netdiag.action.mtr",
+	"netdiag.action.lookup",
+	"netdiag.action.speed",
+	"netdiag.action.serve",
+}
+
+// DefaultDiagHost узел, проверяемый по умолчанию
+const DefaultDiagHost = "1.1.1.1"
+
+// liveLines сколько последних строк показывает задача во время выполнения
+const liveLines = 12
+
+// SelectNetDiagApp отображает раздел сетевой диагностики до выбора «Назад»
+func (ac *AppConfig) SelectNetDiagApp() {
+	ac.Log.Info(i18n.T("network.log.netdiag"))
+
+	ac.ContextualLoop(func() bool {
+		index, ok := ac.netdiagPick(i18n.T("netdiag.task.action"), labelsFor(netdiagActions))
+		if !ok {
+			return false
+		}
+		switch netdiagActions[index] {
+		case "netdiag.action.ping":
+			ac.runPing()
+		case "netdiag.action.trace":
+			ac.runTrace()
+		case "netdiag.action.mtr":
+			ac.runMTR()
+		case "netdiag.action.lookup":
+			ac.runLookup()
+		case "netdiag.action.speed":
+			ac.runSpeedTest()
+		case "netdiag.action.serve":
+			ac.runSpeedServer()
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.netdiag"))
+}
+
+// netdiagPick показывает список с пунктом «Назад»; false — выбран возврат
+func (ac *AppConfig) netdiagPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("netdiag.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// hostInput запрашивает узел; пустой ввод означает значение по умолчанию
+func hostInput(title, placeholder string) *termos.InputTask {
+	input := termos.NewInputTask(title, i18n.T("proxy.input.keep_hint"))
+	input.WithPlaceholder(placeholder).WithAllowEmpty(true)
+	return input
+}
+
+// inputOr возвращает введённое значение или значение по умолчанию
+func inputOr(input *termos.InputTask, fallback string) string {
+	return valueOr(strings.TrimSpace(input.GetValue()), fallback)
+}
+
+// ms форматирует время ответа в миллисекундах
+func ms(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 2, 64)
+}
+
+// PingReplyLine возвращает строку с ответом на эхо-запрос
+func PingReplyLine(r netdiag.Reply) string {
+	if r.Lost {
+		return i18n.T("netdiag.ping.lost", r.Seq)
+	}
+	return i18n.T("netdiag.ping.reply", r.Size, r.From, r.Seq, ms(r.RTT))
+}
+
+// PingSummary возвращает итог серии эхо-запросов
+func PingSummary(s netdiag.PingStats) []string {
+	lines := []string{
+		i18n.T("netdiag.ping.target", s.Target, s.Addr, s.Mode),
+		i18n.T("netdiag.ping.stats", s.Sent, s.Received, s.Loss),
+	}
+	if s.Received > 0 {
+		lines = append(lines, i18n.T("netdiag.ping.rtt", ms(s.Min), ms(s.Avg), ms(s.Max), ms(s.Jitter)))
+	}
+	return lines
+}
+
+// HopLine возвращает строку с узлом маршрута
+func HopLine(h netdiag.Hop) string {
+	if h.Addr == "" {
+		return fmt.Sprintf("%2d  %s", h.TTL, strings.TrimSpace(strings.Repeat("* ", h.Lost)))
+	}
+	host := h.Addr
+	if h.Name != "" {
+		host = fmt.Sprintf("%s (%s)", h.Name, h.Addr)
+	}
+	times := make([]string, 0, len(h.RTTs)+h.Lost)
+	for _, rtt := range h.RTTs {
+		times = append(times, ms(rtt))
+	}
+	for range h.Lost {
+		times = append(times, "*")
+	}
+	return strings.TrimRight(fmt.Sprintf("%2d  %s  %s %s", h.TTL, host, i18n.T("netdiag.trace.times", strings.Join(times, " ")), h.Note), " ")
+}
+
+// TraceSummary возвращает все узлы маршрута и итог трассировки
+func TraceSummary(t netdiag.Trace) []string {
+	lines := []string{i18n.T("netdiag.trace.target", t.Target, t.Addr)}
+	for _, h := range t.Hops {
+		lines = append(lines, HopLine(h))
+	}
+	if !t.Reached {
+		lines = append(lines, i18n.T("netdiag.trace.not_reached"))
+	}
+	return lines
+}
+
+// MTRLines возвращает таблицу непрерывной трассировки
+func MTRLines(hops []netdiag.HopStats) []string {
+	lines := []string{i18n.T("netdiag.mtr.header")}
+	for _, h := range hops {
+		addr := valueOr(h.Addr, "???")
+		if h.Sent == h.Lost {
+			lines = append(lines, fmt.Sprintf("%2d. %-15s %5.1f%% %4d", h.TTL, addr, h.Loss(), h.Sent))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%2d. %-15s %5.1f%% %4d %7s %7s %7s %7s",
+			h.TTL, addr, h.Loss(), h.Sent, ms(h.Last), ms(h.Avg), ms(h.Best), ms(h.Worst)))
+	}
+	return lines
+}
+
+// LookupLines возвращает записи DNS
+func LookupLines(l netdiag.Lookup) []string {
+	lines := []string{i18n.T("netdiag.lookup.server", l.Name, l.Server, ms(l.RTT))}
+	if len(l.Records) == 0 {
+		return append(lines, i18n.T("netdiag.lookup.empty"))
+	}
+	for _, r := range l.Records {
+		lines = append(lines, fmt.Sprintf("%-5s %s", r.Type, r.Value))
+	}
+	return lines
+}
+
+// speedModeLabel название направления замера
+func speedModeLabel(mode string) string {
+	return i18n.T("netdiag.speed." + mode)
+}
+
+// SpeedLine возвращает итог замера в одном направлении
+func SpeedLine(r netdiag.SpeedResult) string {
+	return i18n.T("netdiag.speed.result", speedModeLabel(r.Mode), netdiag.FormatRate(r.Bits),
+		float64(r.Bytes)/(1<<20), r.Duration.Round(10*time.Millisecond))
+}
+
+// SpeedProgressLine возвращает строку с текущей скоростью
+func SpeedProgressLine(mode string, elapsed time.Duration, bits float64) string {
+	return i18n.T("netdiag.speed.progress", speedModeLabel(mode), elapsed, netdiag.FormatRate(bits))
+}
+
+// SpeedSessionLine возвращает строку о замере, проведённом сервером
+func SpeedSessionLine(s netdiag.SpeedSession) string {
+	if s.Err != nil {
+		return i18n.T("netdiag.serve.failed", s.Remote, s.Err)
+	}
+	return i18n.T("netdiag.serve.session", s.Remote, SpeedLine(s.SpeedResult))
+}
+
+// runPing отправляет эхо-запросы и показывает ответы по мере поступления
+func (ac *AppConfig) runPing() {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	host := hostInput(i18n.T("netdiag.input.host"), DefaultDiagHost)
+
+	var stats netdiag.PingStats
+	task := ac.newLiveTask(i18n.T("netdiag.task.ping"), liveLines,
+		func(ctx context.Context, t *liveTask) error {
+			var err error
+			p := netdiag.Pinger{Count: 10, OnReply: func(r netdiag.Reply) { t.Add(PingReplyLine(r)) }}
+			stats, err = p.Run(ctx, inputOr(host, DefaultDiagHost))
+			return err
+		},
+		func() []string { return PingSummary(stats) })
+	queue.AddTasks(host, task)
+	ac.runScreen(queue)
+}
+
+// runTrace трассирует маршрут и показывает узлы по мере их обнаружения
+func (ac *AppConfig) runTrace() {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	host := hostInput(i18n.T("netdiag.input.host"), DefaultDiagHost)
+
+	var trace netdiag.Trace
+	task := ac.newLiveTask(i18n.T("netdiag.task.trace"), liveLines,
+		func(ctx context.Context, t *liveTask) error {
+			var err error
+			tr := netdiag.Tracer{OnHop: func(h netdiag.Hop) { t.Add(HopLine(h)) }}
+			trace, err = tr.Run(ctx, inputOr(host, DefaultDiagHost))
+			return err
+		},
+		func() []string { return TraceSummary(trace) })
+	queue.AddTasks(host, task)
+	ac.runScreen(queue)
+}
+
+// runMTR выполняет непрерывную трассировку и обновляет таблицу после каждого раунда
+func (ac *AppConfig) runMTR() {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	host := hostInput(i18n.T("netdiag.input.host"), DefaultDiagHost)
+
+	var hops []netdiag.HopStats
+	task := ac.newLiveTask(i18n.T("netdiag.task.mtr"), 0,
+		func(ctx context.Context, t *liveTask) error {
+			var err error
+			m := netdiag.MTR{OnRound: func(round int, hops []netdiag.HopStats) {
+				t.Set(append([]string{i18n.T("netdiag.mtr.round", round)}, MTRLines(hops)...))
+			}}
+			hops, err = m.Run(ctx, inputOr(host, DefaultDiagHost))
+			return err
+		},
+		func() []string { return MTRLines(hops) })
+	queue.AddTasks(host, task)
+	ac.runScreen(queue)
+}
+
+// runLookup запрашивает записи DNS через системный или указанный сервер
+func (ac *AppConfig) runLookup() {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	name := hostInput(i18n.T("netdiag.input.name"), DefaultTestName)
+	types := termos.NewInputTask(i18n.T("netdiag.input.types"), i18n.T("netdiag.input.types_hint", strings.Join(netdiag.LookupTypes, ", ")))
+	types.WithAllowEmpty(true)
+	server := termos.NewInputTask(i18n.T("netdiag.input.server"), i18n.T("netdiag.input.server_hint"))
+	server.WithAllowEmpty(true)
+
+	var result netdiag.Lookup
+	task := termos.NewFuncTask(i18n.T("netdiag.task.lookup"),
+		func() error {
+			var err error
+			r := netdiag.Resolver{Server: strings.TrimSpace(server.GetValue())}
+			result, err = r.Lookup(context.Background(), inputOr(name, DefaultTestName), splitList(types.GetValue()))
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return LookupLines(result) }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(name, types, server, task)
+	ac.runScreen(queue)
+}
+
+// runSpeedTest замеряет скорость приёма и передачи до сервера «terem net serve»
+func (ac *AppConfig) runSpeedTest() {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	host := termos.NewInputTask(i18n.T("netdiag.input.peer"), i18n.T("netdiag.input.peer_hint", netdiag.SpeedPort))
+
+	var results []netdiag.SpeedResult
+	task := ac.newLiveTask(i18n.T("netdiag.task.speed"), liveLines,
+		func(ctx context.Context, t *liveTask) error {
+			test := netdiag.SpeedTest{OnProgress: func(mode string, elapsed time.Duration, bits float64) {
+				t.Add(SpeedProgressLine(mode, elapsed, bits))
+			}}
+			for _, mode := range []string{netdiag.SpeedDownload, netdiag.SpeedUpload} {
+				r, err := test.Run(ctx, strings.TrimSpace(host.GetValue()), mode)
+				if err != nil {
+					return err
+				}
+				results = append(results, r)
+				t.Add(SpeedLine(r))
+			}
+			return nil
+		},
+		func() []string {
+			lines := make([]string, 0, len(results))
+			for _, r := range results {
+				lines = append(lines, SpeedLine(r))
+			}
+			return lines
+		})
+	queue.AddTasks(host, task)
+	ac.runScreen(queue)
+}
+
+// runSpeedServer принимает замеры скорости, пока пользователь не закроет задачу
+func (ac *AppConfig) runSpeedServer() {
+	queue := ac.newScreenQueue(i18n.T("netdiag.queue.title"))
+	addr := net.JoinHostPort("", strconv.Itoa(netdiag.SpeedPort))
+
+	task := ac.newLiveTask(i18n.T("netdiag.task.serve", netdiag.SpeedPort), liveLines,
+		func(ctx context.Context, t *liveTask) error {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("netdiag.log.serve"), addr)
+			t.Add(i18n.T("netdiag.serve.listening", addr))
+			return netdiag.SpeedServer{OnSession: func(s netdiag.SpeedSession) {
+				t.Add(SpeedSessionLine(s))
+			}}.Serve(ctx, ln)
+		}, nil)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
@@ -0,0 +1,392 @@
+package tui
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/sshd"
+	"github.com/qzeleza/termos"
+)
+
+// Действия с сервером OpenSSH
+var sshdActions = []string{
+	"sshd.action.status",
+	"sshd.action.configure",
+	"sshd.action.keys",
+	"sshd.action.add_key",
+	"sshd.action.remove_key",
+	"sshd.action.install",
+	"sshd.action.back",
+}
+
+// SelectOpenSSHApp отображает раздел управления сервером OpenSSH до выбора «Назад»
+func (ac *AppConfig) SelectOpenSSHApp() {
+	ac.Log.Info(i18n.T("network.log.openssh"))
+	m := sshd.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		queue := ac.newScreenQueue(i18n.T("sshd.queue.title"))
+		menu := termos.NewSingleSelectTask(i18n.T("sshd.task.action"), labelsFor(sshdActions))
+		queue.AddTasks(menu)
+		if err := queue.Run(); err != nil {
+			ac.Log.Error(i18n.T("screen.error"), err)
+			return false
+		}
+		if menu.HasError() || ac.IsContextCancelled() {
+			return false
+		}
+
+		switch sshdActions[menu.GetSelectedIndex()] {
+		case "sshd.action.status":
+			ac.showSSHDStatus(m)
+		case "sshd.action.configure":
+			ac.configureSSHD(m)
+		case "sshd.action.keys":
+			ac.showSSHDKeys(m)
+		case "sshd.action.add_key":
+			ac.addSSHDKey(m)
+		case "sshd.action.remove_key":
+			ac.removeSSHDKey(m)
+		case "sshd.action.install":
+			ac.installSSHD(m)
+		default:
+			return false
+		}
+		return true
+	}, i18n.T("loop.sshd"))
+}
+
+// checkSSHD проверяет, что openssh-server установлен
+func checkSSHD(m sshd.Manager) error {
+	if !m.Service().Installed() {
+		return errors.New(i18n.T("sshd.error.not_installed"))
+	}
+	return nil
+}
+
+// authLabel возвращает локализованное название способа входа
+func authLabel(mode string) string {
+	return i18n.T("sshd.auth." + mode)
+}
+
+// rootLoginLabel возвращает локализованное название политики входа под root
+func rootLoginLabel(policy string) string {
+	return i18n.T("sshd.root." + strings.ReplaceAll(policy, "-", "_"))
+}
+
+// KeyLine возвращает строку с описанием ключа
+func KeyLine(k sshd.Key) string {
+	return i18n.T("sshd.keys.line", k.Type, k.Fingerprint, valueOr(k.Comment, "—"))
+}
+
+// SSHDSummary возвращает строки с состоянием и параметрами сервера OpenSSH
+func SSHDSummary(s sshd.Settings, running bool, keys int) []string {
+	return []string{
+		i18n.T("sshd.status.service", serviceStateLabel(running)),
+		i18n.T("sshd.status.port", s.Port),
+		i18n.T("sshd.status.auth", authLabel(s.Auth)),
+		i18n.T("sshd.status.root", rootLoginLabel(s.RootLogin)),
+		i18n.T("sshd.status.keys", keys),
+	}
+}
+
+// serviceStateLabel возвращает локализованное состояние службы
+func serviceStateLabel(running bool) string {
+	if running {
+		return i18n.T("sshd.state.running")
+	}
+	return i18n.T("sshd.state.stopped")
+}
+
+// runSSHDTask показывает экран с одной задачей
+func (ac *AppConfig) runSSHDTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("sshd.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// showSSHDStatus показывает состояние службы и текущие параметры
+func (ac *AppConfig) showSSHDStatus(m sshd.Manager) {
+	var s sshd.Settings
+	var running bool
+	var keys []sshd.Key
+	var processes []string
+	ac.runSSHDTask(i18n.T("sshd.task.status"),
+		func() error {
+			if err := checkSSHD(m); err != nil {
+				return err
+			}
+			var err error
+			if s, _, err = m.Load(); err != nil {
+				return err
+			}
+			running = m.Service().Running()
+			processes = appProcesses(m.Runner, m.Service().ProcessName())
+			keys, err = m.Keys()
+			return err
+		},
+		func() []string { return append(SSHDSummary(s, running, len(keys)), processes...) })
+}
+
+// configureSSHD запрашивает порт, способ входа и политику для root, предупреждает
+// о риске потерять доступ и применяет параметры после проверки sshd -t
+func (ac *AppConfig) configureSSHD(m sshd.Manager) {
+	var cur sshd.Settings
+	var keys []sshd.Key
+	err := checkSSHD(m)
+	if err == nil {
+		cur, _, err = m.Load()
+	}
+	if err == nil {
+		keys, err = m.Keys()
+	}
+	if err != nil {
+		ac.runSSHDTask(i18n.T("sshd.task.load"), func() error { return err }, nil)
+		return
+	}
+
+	queue := ac.newScreenQueue(i18n.T("sshd.configure.title"))
+	port := termos.NewInputTask(i18n.T("sshd.input.port"), i18n.T("proxy.input.keep_hint"))
+	port.WithPlaceholder(strconv.Itoa(cur.Port)).WithAllowEmpty(true)
+
+	authLabels := make([]string, 0, len(sshd.AuthModes))
+	for _, mode := range sshd.AuthModes {
+		authLabels = append(authLabels, authLabel(mode))
+	}
+	auth := termos.NewSingleSelectTask(i18n.T("sshd.input.auth"), authLabels).
+		WithDefaultItem(max(slices.Index(sshd.AuthModes, cur.Auth), 0))
+
+	rootLabels := make([]string, 0, len(sshd.RootLogins))
+	for _, policy := range sshd.RootLogins {
+		rootLabels = append(rootLabels, rootLoginLabel(policy))
+	}
+	root := termos.NewSingleSelectTask(i18n.T("sshd.input.root"), rootLabels).
+		WithDefaultItem(max(slices.Index(sshd.RootLogins, cur.RootLogin), 0))
+
+	queue.AddTasks(port, auth, root)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if auth.HasError() || root.HasError() || ac.IsContextCancelled() {
+		return
+	}
+
+	next := sshd.Settings{
+		Auth:      sshd.AuthModes[auth.GetSelectedIndex()],
+		RootLogin: sshd.RootLogins[root.GetSelectedIndex()],
+	}
+	next.Port, err = portValue(port.GetValue(), cur.Port)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		ac.runSSHDTask(i18n.T("sshd.task.apply"), func() error { return err }, nil)
+		return
+	}
+	if next.Port != cur.Port {
+		needs, ok := ac.resolvePorts(i18n.T("sshd.queue.title"), m.Service(), next.Ports())
+		if !ok {
+			return
+		}
+		next.Port = needs[0].Port
+	}
+	ac.applySSHD(m, cur, next, len(keys))
+}
+
+// applySSHD применяет параметры; при риске потерять доступ сначала показывает
+// предупреждения и запрашивает подтверждение
+func (ac *AppConfig) applySSHD(m sshd.Manager, cur, next sshd.Settings, keys int) {
+	queue := ac.newScreenQueue(i18n.T("sshd.configure.title"))
+
+	risks := sshd.LockoutRisks(cur, next, keys, sshd.CurrentSession())
+	var confirm *termos.YesNoTask
+	if len(risks) > 0 {
+		warn := termos.NewFuncTask(i18n.T("sshd.task.risks"),
+			func() error { return nil },
+			termos.WithSummaryFunction(func() []string { return risks }),
+		)
+		confirm = termos.NewYesNoTask(i18n.T("sshd.confirm.title"), i18n.T("sshd.confirm.question"))
+		confirm.WithDefaultItem(termos.NoOption)
+		queue.AddTasks(warn, confirm)
+	}
+
+	apply := termos.NewFuncTask(i18n.T("sshd.task.apply"),
+		func() error {
+			if confirm != nil && !confirm.IsYes() {
+				return errors.New(i18n.T("sshd.cancelled"))
+			}
+			if err := m.Apply(next); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("sshd.log.applied"), next.Port, next.Auth, next.RootLogin)
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{
+				i18n.T("sshd.status.port", next.Port),
+				i18n.T("sshd.status.auth", authLabel(next.Auth)),
+				i18n.T("sshd.status.root", rootLoginLabel(next.RootLogin)),
+				i18n.T("sshd.configure.keep_session"),
+			}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(apply)
+	ac.runScreen(queue)
+}
+
+// showSSHDKeys показывает ключи из authorized_keys с отпечатками
+func (ac *AppConfig) showSSHDKeys(m sshd.Manager) {
+	var keys []sshd.Key
+	ac.runSSHDTask(i18n.T("sshd.task.keys"),
+		func() error {
+			var err error
+			keys, err = m.Keys()
+			if err == nil && len(keys) == 0 {
+				return errors.New(i18n.T("sshd.keys.empty"))
+			}
+			return err
+		},
+		func() []string {
+			lines := []string{i18n.T("sshd.keys.file", m.KeysPath())}
+			for _, k := range keys {
+				lines = append(lines, KeyLine(k))
+			}
+			return lines
+		})
+}
+
+// addSSHDKey запрашивает строку открытого ключа и добавляет её в authorized_keys
+func (ac *AppConfig) addSSHDKey(m sshd.Manager) {
+	queue := ac.newScreenQueue(i18n.T("sshd.queue.title"))
+	line := termos.NewInputTask(i18n.T("sshd.input.key"), i18n.T("sshd.input.key_hint"))
+
+	var key sshd.Key
+	add := termos.NewFuncTask(i18n.T("sshd.task.add_key"),
+		func() error {
+			var err error
+			if key, err = m.AddKey(line.GetValue()); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("sshd.log.key_added"), key.Fingerprint)
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string { return []string{KeyLine(key)} }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(line, add)
+	ac.runScreen(queue)
+}
+
+// removeSSHDKey предлагает выбрать ключ и удаляет его из authorized_keys
+func (ac *AppConfig) removeSSHDKey(m sshd.Manager) {
+	keys, err := m.Keys()
+	if err == nil && len(keys) == 0 {
+		err = errors.New(i18n.T("sshd.keys.empty"))
+	}
+	if err != nil {
+		ac.runSSHDTask(i18n.T("sshd.task.keys"), func() error { return err }, nil)
+		return
+	}
+
+	labels := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		labels = append(labels, KeyLine(k))
+	}
+	queue := ac.newScreenQueue(i18n.T("sshd.queue.title"))
+	menu := termos.NewSingleSelectTask(i18n.T("sshd.keys.pick"), append(labels, i18n.T("sshd.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(keys) || ac.IsContextCancelled() {
+		return
+	}
+
+	key := keys[menu.GetSelectedIndex()]
+	var risks []string
+	if len(keys) == 1 {
+		if cur, _, err := m.Load(); err == nil && !cur.PasswordAllowed() {
+			risks = append(risks, i18n.T("sshd.risk.last_key"))
+		}
+	}
+
+	queue = ac.newScreenQueue(i18n.T("sshd.queue.title"))
+	var confirm *termos.YesNoTask
+	if len(risks) > 0 {
+		warn := termos.NewFuncTask(i18n.T("sshd.task.risks"),
+			func() error { return nil },
+			termos.WithSummaryFunction(func() []string { return risks }),
+		)
+		confirm = termos.NewYesNoTask(i18n.T("sshd.confirm.title"), i18n.T("sshd.confirm.question"))
+		confirm.WithDefaultItem(termos.NoOption)
+		queue.AddTasks(warn, confirm)
+	}
+	remove := termos.NewFuncTask(i18n.T("sshd.task.remove_key", key.Fingerprint),
+		func() error {
+			if confirm != nil && !confirm.IsYes() {
+				return errors.New(i18n.T("sshd.cancelled"))
+			}
+			if err := m.RemoveKey(key.Fingerprint); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("sshd.log.key_removed"), key.Fingerprint)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(remove)
+	ac.runScreen(queue)
+}
+
+// installSSHD проверяет, свободен ли порт SSH, устанавливает openssh-server, создаёт ключи хоста
+// и перезапускает службу после проверки конфигурации; выбранный вместо занятого порт записывается в sshd_config
+func (ac *AppConfig) installSSHD(m sshd.Manager) {
+	needs, ok := ac.resolvePorts(i18n.T("sshd.queue.title"), m.Service(), sshd.Settings{Port: sshd.DefaultPort}.Ports())
+	if !ok {
+		return
+	}
+
+	var s sshd.Settings
+	var keys []sshd.Key
+	ac.runSSHDTask(i18n.T("sshd.task.install"),
+		func() error {
+			ac.Log.Info(i18n.T("sshd.log.install"))
+			var err error
+			if s, err = InstallSSHD(m, needs[0].Port); err != nil {
+				return err
+			}
+			keys, err = m.Keys()
+			return err
+		},
+		func() []string { return SSHDSummary(s, m.Service().Running(), len(keys)) })
+}
+
+// InstallSSHD устанавливает openssh-server, проверяет конфигурацию и перезапускает службу;
+// порт port вместо стандартного записывается в sshd_config
+func InstallSSHD(m sshd.Manager, port int) (sshd.Settings, error) {
+	if err := m.Install(); err != nil {
+		return sshd.Settings{}, err
+	}
+	if err := m.Check(); err != nil {
+		return sshd.Settings{}, err
+	}
+	s, _, err := m.Load()
+	if err != nil {
+		return s, err
+	}
+	if port != sshd.DefaultPort && s.Port == sshd.DefaultPort {
+		s.Port = port
+		return s, m.Apply(s)
+	}
+	return s, m.Service().Restart()
+}
@@ -0,0 +1,382 @@
+package tui
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/adguard"
+	"github.com/qzeleza/terem/internal/dns"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ports"
+	"github.com/qzeleza/terem/internal/proxy"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/sshd"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/termos"
+)
+
+// Действия в разделе портов и соединений
+var portsActions = []string{
+	"ports.action.listening",
+	"ports.action.connections",
+	"ports.action.conntrack",
+	"ports.action.filter",
+}
+
+// portsLimit сколько строк показывает экран; остальные сводятся к счётчику
+const portsLimit = 100
+
+// SelectPortsApp отображает прослушиваемые порты и соединения с фильтром
+func (ac *AppConfig) SelectPortsApp() {
+	ac.Log.Info(i18n.T("network.log.ports"))
+	r := ports.Reader{}
+	var filter ports.Filter
+
+	ac.ContextualLoop(func() bool {
+		labels := labelsFor(portsActions)
+		labels[len(labels)-1] = i18n.T("ports.action.filter", valueOr(filter.String(), i18n.T("ports.filter.none")))
+		index, ok := ac.portsPick(i18n.T("ports.task.action"), labels)
+		if !ok {
+			return false
+		}
+		switch portsActions[index] {
+		case "ports.action.listening":
+			ac.showSockets(r, filter, true)
+		case "ports.action.connections":
+			ac.showSockets(r, filter, false)
+		case "ports.action.conntrack":
+			ac.showConntrack(r, filter)
+		case "ports.action.filter":
+			filter = ac.editPortsFilter(filter)
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.ports"))
+}
+
+// portsPick показывает список с пунктом «Назад»; false — выбран возврат
+func (ac *AppConfig) portsPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("ports.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("ports.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// endpoint форматирует адрес с портом; порт 0 (ICMP, сокет без соединения) опускается
+func endpoint(ap netip.AddrPort) string {
+	switch {
+	case !ap.Addr().IsValid():
+		return "-"
+	case ap.Port() == 0 && ap.Addr().IsUnspecified():
+		return "*"
+	case ap.Port() == 0:
+		return ap.Addr().String()
+	}
+	return ap.String()
+}
+
+// SocketLine возвращает строку с сокетом и его владельцем
+func SocketLine(s ports.Socket) string {
+	remote := endpoint(s.Remote)
+	if s.Listening() {
+		remote = "*"
+	}
+	return strings.TrimRight(fmt.Sprintf("%-5s %-11s %-23s %-23s %s", s.Proto, s.State, endpoint(s.Local), remote, s.Owner()), " ")
+}
+
+// ConnLine возвращает строку с записью conntrack
+func ConnLine(c ports.Conn) string {
+	line := fmt.Sprintf("%-4s %-11s %s → %s", c.Proto, valueOr(c.State, "-"), endpoint(c.Src), endpoint(c.Dst))
+	if c.NAT() {
+		line += " " + i18n.T("ports.conn.nat", endpoint(c.ReplyDst))
+	}
+	if c.Unreplied {
+		line += " " + i18n.T("ports.conn.unreplied")
+	}
+	return line + " " + i18n.T("ports.conn.timeout", c.Timeout)
+}
+
+// limitLines обрезает список строк и добавляет счётчик пропущенных
+func limitLines(lines []string, limit int) []string {
+	if limit <= 0 || len(lines) <= limit {
+		return lines
+	}
+	return append(lines[:limit:limit], i18n.T("ports.more", len(lines)-limit))
+}
+
+// SocketLines возвращает строки сокетов; listening отбирает прослушиваемые, иначе — остальные
+func SocketLines(sockets []ports.Socket, listening bool, limit int) []string {
+	var lines []string
+	for _, s := range sockets {
+		if s.Listening() == listening {
+			lines = append(lines, SocketLine(s))
+		}
+	}
+	if len(lines) == 0 {
+		return []string{i18n.T("ports.empty")}
+	}
+	return limitLines(lines, limit)
+}
+
+// showSockets показывает прослушиваемые сокеты или соединения, подходящие под фильтр
+func (ac *AppConfig) showSockets(r ports.Reader, filter ports.Filter, listening bool) {
+	queue := ac.newScreenQueue(i18n.T("ports.queue.title"))
+	title := i18n.T("ports.task.connections")
+	if listening {
+		title = i18n.T("ports.task.listening")
+	}
+
+	var sockets []ports.Socket
+	task := termos.NewFuncTask(title,
+		func() error {
+			all, err := r.Sockets()
+			sockets = filter.Sockets(all)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return SocketLines(sockets, listening, portsLimit) }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// showConntrack показывает записи conntrack, подходящие под фильтр
+func (ac *AppConfig) showConntrack(r ports.Reader, filter ports.Filter) {
+	queue := ac.newScreenQueue(i18n.T("ports.queue.title"))
+
+	var conns []ports.Conn
+	task := termos.NewFuncTask(i18n.T("ports.task.conntrack"),
+		func() error {
+			all, err := r.Conntrack()
+			conns = filter.Conns(all)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			if len(conns) == 0 {
+				return []string{i18n.T("ports.empty")}
+			}
+			lines := make([]string, 0, len(conns))
+			for _, c := range conns {
+				lines = append(lines, ConnLine(c))
+			}
+			return limitLines(lines, portsLimit)
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// editPortsFilter запрашивает новый фильтр; «-» сбрасывает его, ошибка оставляет прежний
+func (ac *AppConfig) editPortsFilter(current ports.Filter) ports.Filter {
+	queue := ac.newScreenQueue(i18n.T("ports.queue.title"))
+	input := termos.NewInputTask(i18n.T("ports.input.filter"), i18n.T("ports.input.filter_hint"))
+	input.WithPlaceholder(current.String()).WithAllowEmpty(true)
+
+	result := current
+	task := termos.NewFuncTask(i18n.T("ports.task.filter"),
+		func() error {
+			switch value := strings.TrimSpace(input.GetValue()); value {
+			case "":
+			case "-":
+				result = ports.Filter{}
+			default:
+				f, err := ports.ParseFilter(strings.Fields(value))
+				if err != nil {
+					return err
+				}
+				result = f
+			}
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("ports.filter.current", valueOr(result.String(), i18n.T("ports.filter.none")))}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(input, task)
+	ac.runScreen(queue)
+	return result
+}
+
+// Способы разрешить конфликт портов
+var conflictActions = []string{
+	"ports.conflict.change",
+	"ports.conflict.stop",
+	"ports.conflict.ignore",
+	"ports.conflict.cancel",
+}
+
+// conflictService ищет службу Entware, которой принадлежит процесс, занявший порт
+func conflictService(process string) (service.Service, bool) {
+	known := []service.Service{dns.Dnsmasq, dns.Stubby, dns.DNSCrypt, adguard.Service, sshd.Service}
+	for _, k := range proxy.Kinds {
+		known = append(known, proxy.Manager{Kind: k}.Service())
+	}
+	for _, svc := range known {
+		if process != "" && ports.SameProcess(process, svc.ProcessName()) {
+			return svc, true
+		}
+	}
+	return service.Service{}, false
+}
+
+// ConflictLine возвращает строку с занятым портом и процессом, который его держит
+func ConflictLine(c ports.Conflict) string {
+	return i18n.T("ports.conflict.line", c.Need, i18n.T("ports.role."+c.Need.Role),
+		valueOr(c.Socket.Owner(), i18n.T("ports.conflict.unknown")))
+}
+
+// resolvePorts проверяет, свободны ли порты службы svc, и при конфликте предлагает
+// сменить порт, остановить мешающую службу или продолжить как есть. Сокеты читаются
+// из локального /proc, поэтому для службы на удалённом роутере проверка пропускается.
+// Возвращает итоговые порты; false — действие отменено
+func (ac *AppConfig) resolvePorts(app string, svc service.Service, needs []ports.Need) ([]ports.Need, bool) {
+	if !utils.IsLocal(svc.Runner) {
+		return needs, true
+	}
+	r := ports.Reader{}
+	for !ac.IsContextCancelled() {
+		conflicts, err := r.Conflicts(needs, svc.ProcessName())
+		if err != nil {
+			ac.Log.Warn(i18n.T("ports.log.check_failed"), err)
+			return needs, true
+		}
+		if len(conflicts) == 0 {
+			return needs, true
+		}
+		for _, c := range conflicts {
+			ac.Log.Warn(i18n.T("ports.log.conflict"), app, c.Need, valueOr(c.Socket.Owner(), "?"))
+		}
+
+		c := conflicts[0]
+		owner, stoppable := conflictService(c.Socket.Process)
+		var actions, labels []string
+		for _, action := range conflictActions {
+			switch {
+			case action == "ports.conflict.change" && c.Need.Fixed:
+				continue
+			case action == "ports.conflict.stop" && !stoppable:
+				continue
+			case action == "ports.conflict.change":
+				labels = append(labels, i18n.T(action, c.Need))
+			case action == "ports.conflict.stop":
+				labels = append(labels, i18n.T(action, owner.Name))
+			default:
+				labels = append(labels, i18n.T(action))
+			}
+			actions = append(actions, action)
+		}
+
+		queue := ac.newScreenQueue(i18n.T("ports.conflict.title", app))
+		info := termos.NewFuncTask(i18n.T("ports.conflict.task"),
+			func() error { return nil },
+			termos.WithSummaryFunction(func() []string {
+				lines := make([]string, 0, len(conflicts))
+				for _, c := range conflicts {
+					lines = append(lines, ConflictLine(c))
+				}
+				return lines
+			}),
+		)
+		menu := termos.NewSingleSelectTask(i18n.T("ports.conflict.action", c.Need), labels)
+		queue.AddTasks(info, menu)
+		if err := queue.Run(); err != nil {
+			ac.Log.Error(i18n.T("screen.error"), err)
+			return nil, false
+		}
+		if menu.HasError() || ac.IsContextCancelled() {
+			return nil, false
+		}
+
+		switch actions[menu.GetSelectedIndex()] {
+		case "ports.conflict.change":
+			needs = ac.changePort(needs, c.Need)
+		case "ports.conflict.stop":
+			ac.stopConflicting(owner)
+		case "ports.conflict.ignore":
+			ac.Log.Warn(i18n.T("ports.log.ignored"), app, c.Need)
+			return needs, true
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// CheckPorts проверяет без вопросов, свободны ли порты службы svc: занятый порт
+// возвращается ошибкой. Используется при запуске действий без терминала; как и в
+// resolvePorts, если сокеты прочитать не удалось, проверка пропускается
+func CheckPorts(svc service.Service, needs []ports.Need) error {
+	if !utils.IsLocal(svc.Runner) {
+		return nil
+	}
+	conflicts, err := ports.Reader{}.Conflicts(needs, svc.ProcessName())
+	if err != nil || len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf(i18n.T("ports.error.busy"), ConflictLine(conflicts[0]))
+}
+
+// changePort запрашивает новый номер порта и заменяет его во всех портах того же назначения
+func (ac *AppConfig) changePort(needs []ports.Need, busy ports.Need) []ports.Need {
+	queue := ac.newScreenQueue(i18n.T("ports.queue.title"))
+	input := termos.NewInputTask(i18n.T("ports.conflict.input", busy), i18n.T("ports.conflict.input_hint"))
+
+	result := needs
+	task := termos.NewFuncTask(i18n.T("ports.conflict.task_port"),
+		func() error {
+			port, err := portValue(input.GetValue(), busy.Port)
+			if err != nil {
+				return err
+			}
+			result = slices.Clone(needs)
+			for i, n := range result {
+				if n.Port == busy.Port && n.Role == busy.Role {
+					result[i].Port = port
+				}
+			}
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(input, task)
+	ac.runScreen(queue)
+	return result
+}
+
+// stopConflicting останавливает службу, занявшую порт, и отключает её автозапуск,
+// чтобы после перезагрузки порт снова не оказался занят
+func (ac *AppConfig) stopConflicting(svc service.Service) {
+	queue := ac.newScreenQueue(i18n.T("ports.queue.title"))
+	summary := i18n.T("ports.conflict.stopped", svc.Name)
+	task := termos.NewFuncTask(i18n.T("ports.conflict.task_stop", svc.Name),
+		func() error {
+			if err := svc.Stop(); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("ports.log.stopped"), svc.Name)
+			disabled, err := svc.DisableAutostart()
+			if err != nil {
+				ac.Log.Warn(i18n.T("ports.log.autostart_failed"), svc.Name, err)
+			}
+			if disabled {
+				ac.Log.Info(i18n.T("ports.log.autostart_disabled"), svc.Name)
+				summary = i18n.T("ports.conflict.disabled", svc.Name)
+			}
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string { return []string{summary} }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
@@ -0,0 +1,271 @@
+package tui
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/proxy"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/termos"
+)
+
+// Действия с прокси-сервером
+var proxyActions = []string{
+	"proxy.action.status",
+	"proxy.action.configure",
+	"proxy.action.restart",
+	"proxy.action.back",
+}
+
+// SelectProxyApp отображает меню для выбора и настройки прокси-сервера
+func (ac *AppConfig) SelectProxyApp() {
+	ac.Log.Info(i18n.T("network.log.proxy"))
+
+	labels := make([]string, 0, len(proxy.Kinds)+1)
+	for _, kind := range proxy.Kinds {
+		svc := proxy.Manager{Kind: kind}.Service()
+		labels = append(labels, fmt.Sprintf("%s (%s)", kind.Title, serviceState(svc)))
+	}
+	labels = append(labels, i18n.T("proxy.action.back"))
+
+	queue := ac.newScreenQueue(i18n.T("proxy.queue.title"))
+	menu := termos.NewSingleSelectTask(i18n.T("proxy.task.kind"), labels)
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(proxy.Kinds) {
+		return
+	}
+
+	ac.proxyActionsLoop(proxy.Manager{Kind: proxy.Kinds[menu.GetSelectedIndex()]})
+}
+
+// proxyActionsLoop показывает действия с выбранным прокси-сервером до выбора «Назад»
+func (ac *AppConfig) proxyActionsLoop(m proxy.Manager) {
+	ac.ContextualLoop(func() bool {
+		queue := ac.newScreenQueue(m.Kind.Title)
+		menu := termos.NewSingleSelectTask(i18n.T("proxy.task.action"), labelsFor(proxyActions))
+		queue.AddTasks(menu)
+		if err := queue.Run(); err != nil {
+			ac.Log.Error(i18n.T("screen.error"), err)
+			return false
+		}
+		if menu.HasError() || ac.IsContextCancelled() {
+			return false
+		}
+
+		switch proxyActions[menu.GetSelectedIndex()] {
+		case "proxy.action.status":
+			ac.showProxyStatus(m)
+		case "proxy.action.configure":
+			ac.configureProxy(m)
+		case "proxy.action.restart":
+			ac.restartProxy(m)
+		default:
+			return false
+		}
+		return true
+	}, i18n.T("loop.proxy"))
+}
+
+// CurrentProxySettings возвращает параметры из текущей конфигурации или значения по умолчанию
+func (ac *AppConfig) CurrentProxySettings(m proxy.Manager) proxy.Settings {
+	if content, err := service.ReadFile(m.Runner, m.Kind.ConfigPath); err == nil {
+		if s, ok := m.Kind.ParseSettings(content); ok {
+			return s
+		}
+	}
+
+	s := proxy.Settings{ListenAddr: "0.0.0.0", Port: m.Kind.DefaultPort, AllowedSubnets: []string{"192.168.1.0/24"}}
+	if ip := net.ParseIP(ac.GetSysInfo().IP).To4(); ip != nil {
+		s.ListenAddr = ip.String()
+		s.AllowedSubnets = []string{(&net.IPNet{IP: ip.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()}
+	}
+	return s
+}
+
+// showProxyStatus показывает состояние прокси-сервера и число активных подключений
+func (ac *AppConfig) showProxyStatus(m proxy.Manager) {
+	queue := ac.newScreenQueue(m.Kind.Title)
+	current := ac.CurrentProxySettings(m)
+
+	var st proxy.Status
+	var processes []string
+	task := termos.NewFuncTask(i18n.T("proxy.status.title"),
+		func() error {
+			st = m.Status(current.Port)
+			processes = appProcesses(m.Runner, m.Service().ProcessName())
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			return append(ProxyStatusLines(m, current, st), processes...)
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// ProxyStatusLines возвращает строки с состоянием прокси-сервера и его параметрами
+func ProxyStatusLines(m proxy.Manager, s proxy.Settings, st proxy.Status) []string {
+	return []string{
+		i18n.T("proxy.status.state", serviceState(m.Service())),
+		i18n.T("proxy.status.listen", s.ListenAddr, s.Port),
+		i18n.T("proxy.status.subnets", strings.Join(s.AllowedSubnets, ", ")),
+		i18n.T("proxy.status.users", len(s.Users)),
+		i18n.T("proxy.status.connections", st.Connections),
+		i18n.T("proxy.status.config", m.Kind.ConfigPath),
+	}
+}
+
+// configureProxy запрашивает параметры, проверяет, свободен ли порт, устанавливает пакет
+// при необходимости и применяет конфигурацию
+func (ac *AppConfig) configureProxy(m proxy.Manager) {
+	queue := ac.newScreenQueue(m.Kind.Title)
+	current := ac.CurrentProxySettings(m)
+
+	listen := termos.NewInputTask(i18n.T("proxy.input.listen"), i18n.T("proxy.input.keep_hint"))
+	listen.WithPlaceholder(current.ListenAddr).WithAllowEmpty(true)
+	port := termos.NewInputTask(i18n.T("proxy.input.port"), i18n.T("proxy.input.keep_hint"))
+	port.WithPlaceholder(strconv.Itoa(current.Port)).WithAllowEmpty(true)
+	subnets := termos.NewInputTask(i18n.T("proxy.input.subnets"), i18n.T("proxy.input.list_hint"))
+	subnets.WithPlaceholder(strings.Join(current.AllowedSubnets, ",")).WithAllowEmpty(true)
+	queue.AddTasks(listen, port, subnets)
+
+	var users *termos.InputTask
+	if m.Kind.Auth {
+		users = termos.NewInputTask(i18n.T("proxy.input.users"), i18n.T("proxy.input.users_hint"))
+		users.WithAllowEmpty(true)
+		queue.AddTasks(users)
+	}
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if ac.IsContextCancelled() {
+		return
+	}
+
+	s, err := proxySettings(current, listen, port, subnets, users)
+	if err == nil {
+		needs, ok := ac.resolvePorts(m.Kind.Title, m.Service(), s.Ports())
+		if !ok {
+			return
+		}
+		s.Port = needs[0].Port
+	}
+
+	queue = ac.newScreenQueue(m.Kind.Title)
+	svc := m.Service()
+	install := termos.NewFuncTask(i18n.T("proxy.task.install", m.Kind.ID),
+		func() error {
+			if err != nil || svc.Installed() {
+				return nil
+			}
+			ac.Log.Info(i18n.T("proxy.log.install"), m.Kind.ID)
+			return svc.Install()
+		},
+		termos.WithStopOnError(true),
+	)
+
+	apply := termos.NewFuncTask(i18n.T("proxy.task.apply"),
+		func() error {
+			if err != nil {
+				return err
+			}
+			if err := m.Apply(s); err != nil {
+				ac.Log.Error(i18n.T("proxy.log.apply_failed"), err)
+				return err
+			}
+			ac.Log.Info(i18n.T("proxy.log.applied"), m.Kind.ID, s.ListenAddr, s.Port)
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("proxy.status.config", m.Kind.ConfigPath)}
+		}),
+		termos.WithStopOnError(false),
+	)
+
+	queue.AddTasks(install, apply)
+	ac.runScreen(queue)
+}
+
+// proxySettings собирает параметры прокси из введённых значений; пустой ввод сохраняет текущее значение
+func proxySettings(current proxy.Settings, listen, port, subnets, users *termos.InputTask) (proxy.Settings, error) {
+	s := current
+	s.ListenAddr = valueOr(listen.GetValue(), current.ListenAddr)
+	if value := strings.TrimSpace(port.GetValue()); value != "" {
+		p, err := strconv.Atoi(value)
+		if err != nil {
+			return s, fmt.Errorf(i18n.T("proxy.error.port_value"), value)
+		}
+		s.Port = p
+	}
+	if value := strings.TrimSpace(subnets.GetValue()); value != "" {
+		s.AllowedSubnets = splitList(value)
+	}
+	if users != nil && strings.TrimSpace(users.GetValue()) == "-" {
+		s.Users = nil
+	} else if users != nil && strings.TrimSpace(users.GetValue()) != "" {
+		parsed, err := parseProxyUsers(users.GetValue())
+		if err != nil {
+			return s, err
+		}
+		s.Users = parsed
+	}
+	return s, nil
+}
+
+// restartProxy перезапускает прокси-сервер
+func (ac *AppConfig) restartProxy(m proxy.Manager) {
+	queue := ac.newScreenQueue(m.Kind.Title)
+	queue.AddTasks(termos.NewFuncTask(i18n.T("proxy.task.restart"),
+		func() error { return m.Service().Restart() },
+		termos.WithStopOnError(false),
+	))
+	ac.runScreen(queue)
+}
+
+// parseProxyUsers разбирает список пользователей вида "имя:пароль, имя2:пароль2"
+func parseProxyUsers(value string) ([]proxy.User, error) {
+	var users []proxy.User
+	for _, entry := range splitList(value) {
+		name, password, ok := strings.Cut(entry, ":")
+		if !ok || name == "" || password == "" {
+			return nil, fmt.Errorf(i18n.T("proxy.error.user"), entry)
+		}
+		users = append(users, proxy.User{Name: name, Password: password})
+	}
+	return users, nil
+}
+
+// serviceState возвращает локализованное состояние службы
+func serviceState(svc service.Service) string {
+	switch {
+	case !svc.Installed():
+		return i18n.T("service.state.not_installed")
+	case svc.Running():
+		return i18n.T("service.state.running")
+	}
+	return i18n.T("service.state.stopped")
+}
+
+// valueOr возвращает введённое значение или значение по умолчанию, если ввод пуст
+func valueOr(value, def string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return value
+	}
+	return def
+}
+
+// splitList разбивает список, разделённый запятыми или пробелами
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	})
+}
@@ -0,0 +1,499 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/clients"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ipset"
+	"github.com/qzeleza/terem/internal/route"
+	"github.com/qzeleza/termos"
+)
+
+// Действия с политикой маршрутизации
+var routingActions = []string{
+	"routing.action.clients",
+	"routing.action.rules",
+	"routing.action.add_table",
+	"routing.action.add_rule",
+	"routing.action.apply",
+}
+
+// Признаки правил политики
+var routingMatches = []string{
+	"routing.match.source",
+	"routing.match.mac",
+	"routing.match.ipset",
+}
+
+// firstRouteTable номер, с которого предлагаются таблицы политики
+const firstRouteTable = 100
+
+// SelectRoutingApp отображает таблицы и правила политики маршрутизации и действия с ними
+func (ac *AppConfig) SelectRoutingApp() {
+	ac.Log.Info(i18n.T("network.log.routing"))
+	m := route.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		tables, rules := ac.Conf.Routing.Tables, ac.Conf.Routing.Rules
+		policy := PolicyFor(ac.Conf.Routing)
+		labels := make([]string, 0, len(tables)+len(rules)+len(routingActions))
+		for _, t := range policy.Tables {
+			labels = append(labels, RouteTableLabel(t))
+		}
+		labels = append(labels, ac.RouteRuleLines()...)
+		labels = append(labels, labelsFor(routingActions)...)
+		index, ok := ac.routingPick(i18n.T("routing.task.pick"), labels)
+		if !ok {
+			return false
+		}
+
+		switch {
+		case index < len(tables):
+			ac.removeRouteTable(m, tables[index])
+		case index < len(tables)+len(rules):
+			ac.removeRouteRule(m, index-len(tables))
+		default:
+			switch routingActions[index-len(tables)-len(rules)] {
+			case "routing.action.clients":
+				ac.showClientPolicy(m)
+			case "routing.action.rules":
+				ac.showIPRules(m)
+			case "routing.action.add_table":
+				ac.addRouteTable(m)
+			case "routing.action.add_rule":
+				ac.addRouteRule(m)
+			case "routing.action.apply":
+				ac.runRoutingTask(i18n.T("routing.task.apply"),
+					func() error { return ac.applyRouting(m, ac.Conf.Routing) },
+					func() []string {
+						return []string{i18n.T("routing.apply.done", len(ac.Conf.Routing.Tables), len(ac.Conf.Routing.Rules))}
+					})
+			}
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.routing"))
+}
+
+// PolicyFor возвращает политику маршрутизации по её описанию в конфигурации
+func PolicyFor(cfg conf.RoutingConfig) route.Policy {
+	var p route.Policy
+	for _, t := range cfg.Tables {
+		p.Tables = append(p.Tables, route.Table{ID: t.ID, Name: t.Name, Dev: t.Dev, Via: t.Via})
+	}
+	for _, r := range cfg.Rules {
+		p.Rules = append(p.Rules, route.Rule{Table: r.Table, Source: r.Source, MAC: r.MAC, IPSet: r.IPSet, Note: r.Note})
+	}
+	return p
+}
+
+// RouteTableLabel возвращает строку таблицы для меню
+func RouteTableLabel(t route.Table) string {
+	return i18n.T("routing.table.line", t.Title(), strings.TrimSpace(t.Dev+" "+t.Via))
+}
+
+// RouteRuleLines возвращает строки правил политики в порядке приоритета
+func (ac *AppConfig) RouteRuleLines() []string {
+	rules := PolicyFor(ac.Conf.Routing).Rules
+	lines := make([]string, 0, len(rules))
+	for i, r := range rules {
+		lines = append(lines, ac.routeRuleLabel(i, r))
+	}
+	return lines
+}
+
+// routeRuleLabel возвращает строку правила для меню
+func (ac *AppConfig) routeRuleLabel(index int, r route.Rule) string {
+	line := i18n.T("routing.rule.line", route.PriorityBase+index, r.Match(), TableTitle(&ac.Conf, strconv.Itoa(r.Table)))
+	if r.Note != "" {
+		line += " — " + r.Note
+	}
+	return line
+}
+
+// TableTitle возвращает название таблицы: из политики терема, туннеля VPN или как есть
+func TableTitle(cfg *conf.Config, table string) string {
+	id, err := strconv.Atoi(table)
+	if err != nil {
+		return table
+	}
+	if t, ok := cfg.RouteTable(id); ok && t.Name != "" {
+		return fmt.Sprintf("%d (%s)", id, t.Name)
+	}
+	for _, t := range cfg.VPN {
+		if t.Table == id {
+			return i18n.T("routing.table.vpn", id, t.Name)
+		}
+	}
+	if id == route.TableMain {
+		return "main"
+	}
+	return table
+}
+
+// PolicyLines возвращает строки итоговой политики для клиентов сети
+func PolicyLines(cfg *conf.Config, list []clients.Client, effective []route.Effective) []string {
+	var lines []string
+	for i, e := range effective {
+		host := valueOr(list[i].Hostname, e.MAC)
+		lines = append(lines, i18n.T("routing.client.line", host, e.IP, TableTitle(cfg, e.Table)))
+		if e.Priority > 0 {
+			lines = append(lines, "  "+i18n.T("routing.client.rule", e.Priority, e.Rule))
+		}
+		for _, s := range e.Sets {
+			lines = append(lines, "  "+i18n.T("routing.client.set", s.Set, TableTitle(cfg, s.Table)))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, i18n.T("routing.client.none"))
+	}
+	return lines
+}
+
+// ClientPolicy собирает клиентов с адресом IPv4 и вычисляет для них итоговую политику
+func ClientPolicy(m route.Manager) ([]clients.Client, []route.Effective, error) {
+	rules, err := m.Rules()
+	if err != nil {
+		return nil, nil, err
+	}
+	marks, err := m.Marks()
+	if err != nil {
+		return nil, nil, err
+	}
+	var list []clients.Client
+	var effective []route.Effective
+	for _, c := range (clients.Collector{}).Collect() {
+		if c.IP == "" {
+			continue
+		}
+		list = append(list, c)
+		effective = append(effective, route.Resolve(c.IP, c.MAC, rules, marks))
+	}
+	return list, effective, nil
+}
+
+// routingPick показывает список и возвращает индекс выбранного элемента; последний пункт — «Назад»
+func (ac *AppConfig) routingPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("routing.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("routing.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// runRoutingTask показывает экран с одной задачей
+func (ac *AppConfig) runRoutingTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("routing.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// applyRouting применяет политику и сохраняет её в конфигурацию;
+// при ошибке применения конфигурация не меняется
+func (ac *AppConfig) applyRouting(m route.Manager, cfg conf.RoutingConfig) error {
+	if err := m.Apply(PolicyFor(cfg)); err != nil {
+		return err
+	}
+	ac.Conf.Routing = cfg
+	if err := ac.Conf.Save(ac.ConfFile); err != nil {
+		return fmt.Errorf(i18n.T("routing.error.save"), ac.ConfFile, err)
+	}
+	ac.Log.Info(i18n.T("routing.log.applied"), len(cfg.Tables), len(cfg.Rules))
+	return nil
+}
+
+// cloneRouting возвращает копию политики, которую можно менять, не затрагивая текущую
+func cloneRouting(cfg conf.RoutingConfig) conf.RoutingConfig {
+	return conf.RoutingConfig{
+		Tables: append([]conf.RouteTableConfig(nil), cfg.Tables...),
+		Rules:  append([]conf.RouteRuleConfig(nil), cfg.Rules...),
+	}
+}
+
+// showClientPolicy показывает, по какой таблице уходит трафик каждого клиента
+func (ac *AppConfig) showClientPolicy(m route.Manager) {
+	var list []clients.Client
+	var effective []route.Effective
+	ac.runRoutingTask(i18n.T("routing.task.clients"),
+		func() error {
+			var err error
+			list, effective, err = ClientPolicy(m)
+			return err
+		},
+		func() []string { return PolicyLines(&ac.Conf, list, effective) })
+}
+
+// showIPRules показывает действующие правила ip rule; правила терема отмечены звёздочкой
+func (ac *AppConfig) showIPRules(m route.Manager) {
+	var rules []route.IPRule
+	ac.runRoutingTask(i18n.T("routing.task.rules"),
+		func() error {
+			var err error
+			rules, err = m.Rules()
+			return err
+		},
+		func() []string { return IPRuleLines(rules) })
+}
+
+// IPRuleLines возвращает строки правил ip rule; правила терема отмечены звёздочкой
+func IPRuleLines(rules []route.IPRule) []string {
+	lines := make([]string, 0, len(rules))
+	for _, r := range rules {
+		mark := " "
+		if r.Owned() {
+			mark = "★"
+		}
+		lines = append(lines, fmt.Sprintf("%s %5d: %s", mark, r.Priority, r.Text))
+	}
+	return lines
+}
+
+// nextRouteTable возвращает свободный номер таблицы, не занятый политикой и туннелями VPN
+func (ac *AppConfig) nextRouteTable() int {
+	used := map[int]bool{}
+	for _, t := range ac.Conf.Routing.Tables {
+		used[t.ID] = true
+	}
+	for _, t := range ac.Conf.VPN {
+		used[t.Table] = true
+	}
+	id := firstRouteTable
+	for used[id] {
+		id++
+	}
+	return id
+}
+
+// addRouteTable запрашивает номер, интерфейс и шлюз и добавляет таблицу
+func (ac *AppConfig) addRouteTable(m route.Manager) {
+	queue := ac.newScreenQueue(i18n.T("routing.table.title"))
+	id := termos.NewInputTask(i18n.T("routing.input.table"), i18n.T("routing.input.table_hint"))
+	id.WithAllowEmpty(true)
+	name := termos.NewInputTask(i18n.T("routing.input.name"), i18n.T("routing.input.name_hint"))
+	name.WithAllowEmpty(true)
+	dev := termos.NewInputTask(i18n.T("routing.input.dev"), i18n.T("routing.input.dev_hint"))
+	dev.WithAllowEmpty(true)
+	via := termos.NewInputTask(i18n.T("routing.input.via"), i18n.T("routing.input.via_hint"))
+	via.WithAllowEmpty(true)
+
+	var table conf.RouteTableConfig
+	task := termos.NewFuncTask(i18n.T("routing.task.add_table"),
+		func() error {
+			table = conf.RouteTableConfig{
+				Name: name.GetValue(),
+				Dev:  dev.GetValue(),
+				Via:  via.GetValue(),
+			}
+			if value := strings.TrimSpace(id.GetValue()); value != "" {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf(i18n.T("route.error.table_number"), value)
+				}
+				table.ID = n
+			}
+			var err error
+			table, err = ac.AddRouteTable(m, table)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("routing.table.added", table.ID)}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(id, name, dev, via, task)
+	ac.runScreen(queue)
+}
+
+// AddRouteTable добавляет таблицу в политику, заполняет её и возвращает добавленную таблицу;
+// без номера таблице назначается первый свободный
+func (ac *AppConfig) AddRouteTable(m route.Manager, table conf.RouteTableConfig) (conf.RouteTableConfig, error) {
+	table.Name, table.Dev, table.Via = strings.TrimSpace(table.Name), strings.TrimSpace(table.Dev), strings.TrimSpace(table.Via)
+	if table.ID == 0 {
+		table.ID = ac.nextRouteTable()
+	}
+	if _, ok := ac.Conf.RouteTable(table.ID); ok {
+		return table, fmt.Errorf(i18n.T("route.error.duplicate"), table.ID)
+	}
+	for _, t := range ac.Conf.VPN {
+		if t.Table == table.ID {
+			return table, fmt.Errorf(i18n.T("routing.error.vpn_table"), table.ID, t.Name)
+		}
+	}
+	updated := cloneRouting(ac.Conf.Routing)
+	updated.Tables = append(updated.Tables, table)
+	return table, ac.applyRouting(m, updated)
+}
+
+// routeTableChoices возвращает таблицы, в которые можно направить трафик: таблицы политики,
+// туннелей VPN и основную таблицу для исключений
+func (ac *AppConfig) routeTableChoices() ([]string, []int) {
+	var labels []string
+	var ids []int
+	for _, t := range ac.Conf.Routing.Tables {
+		labels = append(labels, TableTitle(&ac.Conf, strconv.Itoa(t.ID)))
+		ids = append(ids, t.ID)
+	}
+	for _, t := range ac.Conf.VPN {
+		if t.Table > 0 {
+			labels = append(labels, TableTitle(&ac.Conf, strconv.Itoa(t.Table)))
+			ids = append(ids, t.Table)
+		}
+	}
+	labels = append(labels, i18n.T("routing.table.main"))
+	ids = append(ids, route.TableMain)
+	return labels, ids
+}
+
+// addRouteRule выбирает признак, значение и таблицу и добавляет правило в конец политики
+func (ac *AppConfig) addRouteRule(m route.Manager) {
+	kind, ok := ac.routingPick(i18n.T("routing.input.match"), labelsFor(routingMatches))
+	if !ok {
+		return
+	}
+
+	queue := ac.newScreenQueue(i18n.T("routing.rule.title"))
+	var value func() string
+	switch routingMatches[kind] {
+	case "routing.match.source":
+		input := termos.NewInputTask(i18n.T("routing.input.source"), i18n.T("routing.input.source_hint"))
+		queue.AddTasks(input)
+		value = func() string { return strings.TrimSpace(input.GetValue()) }
+	case "routing.match.mac":
+		var labels, macs []string
+		for _, c := range (clients.Collector{}).Collect() {
+			labels = append(labels, vpnClientLabel(c))
+			macs = append(macs, c.MAC)
+		}
+		if len(labels) == 0 {
+			ac.runRoutingTask(i18n.T("routing.task.add_rule"),
+				func() error { return errors.New(i18n.T("routing.error.no_clients")) }, nil)
+			return
+		}
+		pick := termos.NewSingleSelectTask(i18n.T("routing.input.client"), labels)
+		queue.AddTasks(pick)
+		value = func() string { return macs[pick.GetSelectedIndex()] }
+	default:
+		var names []string
+		if sets, err := (ipset.Manager{}).List(); err == nil {
+			for _, s := range sets {
+				names = append(names, s.Name)
+			}
+		}
+		if len(names) == 0 {
+			ac.runRoutingTask(i18n.T("routing.task.add_rule"),
+				func() error { return errors.New(i18n.T("routing.error.no_ipsets")) }, nil)
+			return
+		}
+		pick := termos.NewSingleSelectTask(i18n.T("routing.input.ipset"), names)
+		queue.AddTasks(pick)
+		value = func() string { return names[pick.GetSelectedIndex()] }
+	}
+
+	tableLabels, tableIDs := ac.routeTableChoices()
+	table := termos.NewSingleSelectTask(i18n.T("routing.input.rule_table"), tableLabels)
+	note := termos.NewInputTask(i18n.T("routing.input.note"), i18n.T("routing.input.note_hint"))
+	note.WithAllowEmpty(true)
+
+	var rule conf.RouteRuleConfig
+	task := termos.NewFuncTask(i18n.T("routing.task.add_rule"),
+		func() error {
+			rule = conf.RouteRuleConfig{Table: tableIDs[table.GetSelectedIndex()], Note: strings.TrimSpace(note.GetValue())}
+			switch routingMatches[kind] {
+			case "routing.match.source":
+				rule.Source = value()
+			case "routing.match.mac":
+				rule.MAC = value()
+			default:
+				rule.IPSet = value()
+			}
+			return ac.AddRouteRule(m, rule)
+		},
+		termos.WithSummaryFunction(func() []string {
+			r := PolicyFor(conf.RoutingConfig{Rules: []conf.RouteRuleConfig{rule}}).Rules[0]
+			return []string{i18n.T("routing.rule.added", r.Match(), TableTitle(&ac.Conf, strconv.Itoa(r.Table)))}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(table, note, task)
+	ac.runScreen(queue)
+}
+
+// AddRouteRule добавляет правило в конец политики; трафик можно направить в таблицу политики,
+// туннеля VPN или в основную таблицу
+func (ac *AppConfig) AddRouteRule(m route.Manager, rule conf.RouteRuleConfig) error {
+	if _, ids := ac.routeTableChoices(); !slices.Contains(ids, rule.Table) {
+		return fmt.Errorf(i18n.T("route.error.table"), rule.Table)
+	}
+	updated := cloneRouting(ac.Conf.Routing)
+	updated.Rules = append(updated.Rules, rule)
+	return ac.applyRouting(m, updated)
+}
+
+// removeRouteTable после подтверждения удаляет таблицу вместе с правилами, которые на неё ссылаются
+func (ac *AppConfig) removeRouteTable(m route.Manager, t conf.RouteTableConfig) {
+	queue := ac.newScreenQueue(i18n.T("routing.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("routing.delete.title"), i18n.T("routing.delete.table", t.ID))
+	confirm.WithDefaultItem(termos.NoOption)
+	task := termos.NewFuncTask(i18n.T("routing.task.delete"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("routing.cancelled"))
+			}
+			return ac.RemoveRouteTable(m, t.ID)
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
+
+// RemoveRouteTable удаляет таблицу вместе с правилами, которые на неё ссылаются, и очищает её
+func (ac *AppConfig) RemoveRouteTable(m route.Manager, id int) error {
+	cfg := conf.Config{Routing: cloneRouting(ac.Conf.Routing)}
+	cfg.RemoveRouteTable(id)
+	if err := ac.applyRouting(m, cfg.Routing); err != nil {
+		return err
+	}
+	return m.Flush(id)
+}
+
+// removeRouteRule после подтверждения удаляет правило политики
+func (ac *AppConfig) removeRouteRule(m route.Manager, index int) {
+	r := PolicyFor(ac.Conf.Routing).Rules[index]
+	queue := ac.newScreenQueue(i18n.T("routing.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("routing.delete.title"), i18n.T("routing.delete.rule", r.Match()))
+	confirm.WithDefaultItem(termos.NoOption)
+	task := termos.NewFuncTask(i18n.T("routing.task.delete"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("routing.cancelled"))
+			}
+			return ac.RemoveRouteRule(m, index)
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
+
+// RemoveRouteRule удаляет правило политики с номером index
+func (ac *AppConfig) RemoveRouteRule(m route.Manager, index int) error {
+	updated := cloneRouting(ac.Conf.Routing)
+	updated.Rules = append(updated.Rules[:index], updated.Rules[index+1:]...)
+	return ac.applyRouting(m, updated)
+}
@@ -0,0 +1,485 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/clients"
+	conf "github.com/qzeleza/terem/internal/config"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ipset"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/terem/internal/vpn"
+	"github.com/qzeleza/termos"
+)
+
+// Действия с туннелем VPN
+var vpnActions = []string{
+	"vpn.action.status",
+	"vpn.action.up",
+	"vpn.action.down",
+	"vpn.action.routing",
+	"vpn.action.autostart",
+	"vpn.action.keys",
+	"vpn.action.delete",
+	"vpn.action.back",
+}
+
+// SelectVPNApp отображает туннели VPN и действия с ними до выбора «Назад»
+func (ac *AppConfig) SelectVPNApp() {
+	ac.Log.Info(i18n.T("network.log.vpn"))
+	m := vpn.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		labels := make([]string, 0, len(ac.Conf.VPN)+2)
+		for _, t := range ac.Conf.VPN {
+			st, _ := m.Status(t.Name, t.Kind)
+			labels = append(labels, vpnLabel(t, st))
+		}
+		labels = append(labels, i18n.T("vpn.action.import"), i18n.T("vpn.action.keygen"))
+		index, ok := ac.vpnPick(i18n.T("vpn.task.pick"), labels)
+		if !ok {
+			return false
+		}
+
+		switch index - len(ac.Conf.VPN) {
+		case 0:
+			ac.importVPN(m)
+		case 1:
+			ac.generateVPNKeys()
+		default:
+			ac.vpnLoop(m, ac.Conf.VPN[index].Name)
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.vpn"))
+}
+
+// TunnelFor возвращает туннель для менеджера VPN по его описанию в конфигурации
+func TunnelFor(cfg conf.VPNConfig) vpn.Tunnel {
+	return vpn.Tunnel{Name: cfg.Name, Kind: cfg.Kind, Table: cfg.Table, Clients: cfg.Clients, IPSets: cfg.IPSets}
+}
+
+// kindTitle возвращает название вида туннеля
+func kindTitle(kind string) string {
+	if k, ok := vpn.FindKind(kind); ok {
+		return k.Title
+	}
+	return kind
+}
+
+// vpnLabel возвращает строку туннеля для меню
+func vpnLabel(t conf.VPNConfig, st vpn.Status) string {
+	state := i18n.T("vpn.state.down")
+	if st.Up {
+		state = i18n.T("vpn.state.up")
+	}
+	auto := ""
+	if t.Autostart {
+		auto = " ⟳"
+	}
+	return i18n.T("vpn.line", t.Name, kindTitle(t.Kind), state, auto)
+}
+
+// VPNStatusLines возвращает подробное состояние туннеля
+func VPNStatusLines(t conf.VPNConfig, st vpn.Status) []string {
+	lines := []string{vpnLabel(t, st)}
+	if st.Endpoint != "" {
+		lines = append(lines, i18n.T("vpn.status.endpoint", st.Endpoint))
+	}
+	if st.Up {
+		handshake := i18n.T("vpn.status.never")
+		if !st.Handshake.IsZero() {
+			handshake = i18n.T("vpn.status.ago", time.Since(st.Handshake).Round(time.Second))
+		}
+		lines = append(lines,
+			i18n.T("vpn.status.handshake", handshake),
+			i18n.T("vpn.status.transfer", utils.FormatBytes(st.RX), utils.FormatBytes(st.TX)))
+	}
+	if st.PublicKey != "" {
+		lines = append(lines, i18n.T("vpn.status.public_key", st.PublicKey))
+	}
+	if t.Table > 0 && len(t.Clients)+len(t.IPSets) > 0 {
+		lines = append(lines, i18n.T("vpn.status.routing", t.Table, len(t.Clients), strings.Join(t.IPSets, ", ")))
+	} else {
+		lines = append(lines, i18n.T("vpn.status.no_routing"))
+	}
+	return lines
+}
+
+// vpnPick показывает список и возвращает индекс выбранного элемента; последний пункт — «Назад»
+func (ac *AppConfig) vpnPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("vpn.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("vpn.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// runVPNTask показывает экран с одной задачей
+func (ac *AppConfig) runVPNTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("vpn.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// saveVPNConfig сохраняет описание туннеля в конфигурацию терема
+func (ac *AppConfig) saveVPNConfig(t conf.VPNConfig) error {
+	ac.Conf.SetVPNTunnel(t)
+	if err := ac.Conf.Save(ac.ConfFile); err != nil {
+		return fmt.Errorf(i18n.T("vpn.error.save"), ac.ConfFile, err)
+	}
+	return nil
+}
+
+// importVPN запрашивает имя и путь к профилю и сохраняет туннель
+func (ac *AppConfig) importVPN(m vpn.Manager) {
+	queue := ac.newScreenQueue(i18n.T("vpn.import.title"))
+	name := termos.NewInputTask(i18n.T("vpn.input.name"), i18n.T("vpn.input.name_hint"))
+	file := termos.NewInputTask(i18n.T("vpn.input.file"), i18n.T("vpn.input.file_hint"))
+	user := termos.NewInputTask(i18n.T("vpn.input.user"), i18n.T("vpn.input.user_hint"))
+	user.WithAllowEmpty(true)
+	password := termos.NewInputTask(i18n.T("vpn.input.password"), i18n.T("vpn.input.password_hint"))
+	password.WithAllowEmpty(true)
+	password.WithInputType(termos.InputTypePassword)
+
+	var tunnel conf.VPNConfig
+	task := termos.NewFuncTask(i18n.T("vpn.task.import"),
+		func() error {
+			auth := vpn.Credentials{User: strings.TrimSpace(user.GetValue()), Password: password.GetValue()}
+			var err error
+			tunnel, err = ac.ImportVPN(m, name.GetValue(), file.GetValue(), auth)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("vpn.import.done", tunnel.Name, kindTitle(tunnel.Kind))}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(name, file, user, password, task)
+	ac.runScreen(queue)
+}
+
+// ImportVPN создаёт туннель name из профиля WireGuard или OpenVPN в файле file на роутере
+// и сохраняет его описание в конфигурацию
+func (ac *AppConfig) ImportVPN(m vpn.Manager, name, file string, auth vpn.Credentials) (conf.VPNConfig, error) {
+	tunnel := conf.VPNConfig{Name: strings.TrimSpace(name)}
+	if _, ok := ac.Conf.VPNTunnel(tunnel.Name); ok {
+		return tunnel, fmt.Errorf(i18n.T("vpn.error.exists"), tunnel.Name)
+	}
+	content, err := service.ReadFile(nil, strings.TrimSpace(file))
+	if err != nil {
+		return tunnel, err
+	}
+	if tunnel.Kind, err = m.Import(tunnel.Name, content, auth); err != nil {
+		return tunnel, err
+	}
+	ac.Log.Info(i18n.T("vpn.log.imported"), tunnel.Name, tunnel.Kind)
+	return tunnel, ac.saveVPNConfig(tunnel)
+}
+
+// generateVPNKeys создаёт пару ключей WireGuard и показывает её
+func (ac *AppConfig) generateVPNKeys() {
+	var private, public string
+	ac.runVPNTask(i18n.T("vpn.task.keygen"),
+		func() error {
+			var err error
+			private, public, err = vpn.GenerateKey()
+			return err
+		},
+		func() []string {
+			return []string{i18n.T("vpn.keys.private", private), i18n.T("vpn.keys.public", public)}
+		})
+}
+
+// vpnLoop показывает действия с выбранным туннелем
+func (ac *AppConfig) vpnLoop(m vpn.Manager, name string) {
+	ac.ContextualLoop(func() bool {
+		t, ok := ac.Conf.VPNTunnel(name)
+		if !ok {
+			return false
+		}
+		actions := vpnActions[:len(vpnActions)-1]
+		if t.Kind != vpn.KindWireGuard {
+			actions = slices.DeleteFunc(slices.Clone(actions), func(a string) bool { return a == "vpn.action.keys" })
+		}
+		index, ok := ac.vpnPick(i18n.T("vpn.task.action", name), labelsFor(actions))
+		if !ok {
+			return false
+		}
+
+		switch actions[index] {
+		case "vpn.action.status":
+			var st vpn.Status
+			ac.runVPNTask(i18n.T("vpn.task.status", name),
+				func() error {
+					var err error
+					st, err = m.Status(t.Name, t.Kind)
+					return err
+				},
+				func() []string { return VPNStatusLines(t, st) })
+		case "vpn.action.up":
+			ac.vpnUp(m, t)
+		case "vpn.action.down":
+			ac.runVPNTask(i18n.T("vpn.task.down", name),
+				func() error {
+					if err := m.Down(TunnelFor(t)); err != nil {
+						return err
+					}
+					ac.Log.Info(i18n.T("vpn.log.down"), name)
+					return nil
+				}, nil)
+		case "vpn.action.routing":
+			ac.editVPNRouting(m, t)
+		case "vpn.action.autostart":
+			ac.toggleVPNAutostart(m, t)
+		case "vpn.action.keys":
+			ac.regenerateVPNKey(m, t)
+		case "vpn.action.delete":
+			return !ac.deleteVPN(m, t)
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.vpn"))
+}
+
+// vpnUp поднимает туннель, при необходимости установив пакет с утилитами
+func (ac *AppConfig) vpnUp(m vpn.Manager, t conf.VPNConfig) {
+	var st vpn.Status
+	ac.runVPNTask(i18n.T("vpn.task.up", t.Name),
+		func() error {
+			if !m.Installed(t.Kind) {
+				k, _ := vpn.FindKind(t.Kind)
+				ac.Log.Info(i18n.T("vpn.log.install"), k.Package)
+				if err := k.Service(nil).Install(); err != nil {
+					return err
+				}
+			}
+			if err := m.Up(TunnelFor(t)); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("vpn.log.up"), t.Name)
+			st, _ = m.Status(t.Name, t.Kind)
+			return nil
+		},
+		func() []string { return VPNStatusLines(t, st) })
+}
+
+// vpnClientLabel возвращает строку клиента для выбора; запятые недопустимы в пунктах выбора
+func vpnClientLabel(c clients.Client) string {
+	return strings.TrimSpace(strings.ReplaceAll(i18n.T("vpn.client.line", valueOr(c.Hostname, "—"), valueOr(c.IP, "—"), c.MAC), ",", " "))
+}
+
+// editVPNRouting выбирает клиентов и списки ipset, которые выходят в интернет через туннель
+func (ac *AppConfig) editVPNRouting(m vpn.Manager, t conf.VPNConfig) {
+	none := i18n.T("vpn.route.none")
+
+	// Клиенты: известные устройства и уже выбранные MAC-адреса, которых сейчас нет в сети
+	clientLabels, macs := []string{none}, map[string]string{}
+	var selectedClients []string
+	known := map[string]bool{}
+	for _, c := range (clients.Collector{}).Collect() {
+		label := vpnClientLabel(c)
+		clientLabels = append(clientLabels, label)
+		macs[label], known[c.MAC] = c.MAC, true
+		if slices.Contains(t.Clients, c.MAC) {
+			selectedClients = append(selectedClients, label)
+		}
+	}
+	for _, mac := range t.Clients {
+		if !known[mac] {
+			label := vpnClientLabel(clients.Client{MAC: mac})
+			clientLabels = append(clientLabels, label)
+			macs[label] = mac
+			selectedClients = append(selectedClients, label)
+		}
+	}
+
+	setLabels := []string{none}
+	if sets, err := (ipset.Manager{}).List(); err == nil {
+		for _, s := range sets {
+			setLabels = append(setLabels, s.Name)
+		}
+	}
+
+	queue := ac.newScreenQueue(i18n.T("vpn.routing.title"))
+	clientTask := termos.NewMultiSelectTask(i18n.T("vpn.input.clients"), clientLabels).
+		WithDefaultItems(valueOrList(selectedClients, none))
+	setTask := termos.NewMultiSelectTask(i18n.T("vpn.input.ipsets"), setLabels).
+		WithDefaultItems(valueOrList(t.IPSets, none))
+
+	updated := t
+	task := termos.NewFuncTask(i18n.T("vpn.task.routing", t.Name),
+		func() error {
+			var macList, sets []string
+			for _, label := range clientTask.GetSelected() {
+				if mac, ok := macs[label]; ok {
+					macList = append(macList, mac)
+				}
+			}
+			for _, set := range setTask.GetSelected() {
+				if set != none {
+					sets = append(sets, set)
+				}
+			}
+			var err error
+			updated, err = ac.SetVPNRouting(m, t, macList, sets)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return VPNStatusLines(updated, vpn.Status{Name: updated.Name, Kind: updated.Kind})[1:]
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(clientTask, setTask, task)
+	ac.runScreen(queue)
+}
+
+// SetVPNRouting направляет через туннель трафик клиентов с MAC-адресами macs и адресов из списков
+// ipset sets, переводит работающий туннель на новые правила и сохраняет описание туннеля
+func (ac *AppConfig) SetVPNRouting(m vpn.Manager, t conf.VPNConfig, macs, sets []string) (conf.VPNConfig, error) {
+	updated := t
+	updated.Clients, updated.IPSets = macs, sets
+	if updated.Table == 0 && len(updated.Clients)+len(updated.IPSets) > 0 {
+		var used []int
+		for _, other := range ac.Conf.VPN {
+			used = append(used, other.Table)
+		}
+		updated.Table = vpn.NextTable(used)
+	}
+	if _, err := TunnelFor(updated).Rules(); err != nil {
+		return t, err
+	}
+
+	// Работающий туннель сразу переводится на новые правила
+	if st, _ := m.Status(t.Name, t.Kind); st.Up {
+		if err := m.Unroute(TunnelFor(t)); err != nil {
+			return t, err
+		}
+		if err := m.Route(TunnelFor(updated)); err != nil {
+			return t, err
+		}
+	}
+	ac.Log.Info(i18n.T("vpn.log.routing"), t.Name, len(updated.Clients), len(updated.IPSets))
+	return updated, ac.saveVPNConfig(updated)
+}
+
+// valueOrList возвращает список или список из одного значения по умолчанию, если он пуст
+func valueOrList(list []string, def string) []string {
+	if len(list) == 0 {
+		return []string{def}
+	}
+	return list
+}
+
+// toggleVPNAutostart включает или выключает подъём туннеля при загрузке роутера
+func (ac *AppConfig) toggleVPNAutostart(m vpn.Manager, t conf.VPNConfig) {
+	t.Autostart = !t.Autostart
+	ac.runVPNTask(i18n.T("vpn.task.autostart", t.Name),
+		func() error { return ac.SetVPNAutostart(m, t.Name, t.Autostart) },
+		func() []string {
+			if t.Autostart {
+				return []string{i18n.T("vpn.autostart.on", t.Name)}
+			}
+			return []string{i18n.T("vpn.autostart.off", t.Name)}
+		})
+}
+
+// SetVPNAutostart включает или выключает подъём туннеля name при загрузке роутера
+func (ac *AppConfig) SetVPNAutostart(m vpn.Manager, name string, enabled bool) error {
+	t, ok := ac.Conf.VPNTunnel(name)
+	if !ok {
+		return fmt.Errorf(i18n.T("vpn.error.unknown"), name)
+	}
+	t.Autostart = enabled
+	if enabled {
+		if err := m.EnsureInitScript(); err != nil {
+			return err
+		}
+	}
+	return ac.saveVPNConfig(t)
+}
+
+// regenerateVPNKey после подтверждения заменяет закрытый ключ туннеля WireGuard
+func (ac *AppConfig) regenerateVPNKey(m vpn.Manager, t conf.VPNConfig) {
+	queue := ac.newScreenQueue(i18n.T("vpn.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("vpn.keys.title"), i18n.T("vpn.keys.question", t.Name))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	var public string
+	task := termos.NewFuncTask(i18n.T("vpn.task.keys", t.Name),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("vpn.cancelled"))
+			}
+			var err error
+			public, err = ac.RegenerateVPNKey(m, t.Name)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			return []string{i18n.T("vpn.keys.public", public), i18n.T("vpn.keys.hint")}
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
+
+// RegenerateVPNKey заменяет закрытый ключ туннеля WireGuard и возвращает новый открытый ключ
+func (ac *AppConfig) RegenerateVPNKey(m vpn.Manager, name string) (string, error) {
+	public, err := m.RegenerateKey(name)
+	if err != nil {
+		return "", err
+	}
+	ac.Log.Info(i18n.T("vpn.log.keys"), name)
+	return public, nil
+}
+
+// deleteVPN после подтверждения останавливает туннель и удаляет профиль; возвращает true при успехе
+func (ac *AppConfig) deleteVPN(m vpn.Manager, t conf.VPNConfig) bool {
+	queue := ac.newScreenQueue(i18n.T("vpn.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("vpn.delete.title"), i18n.T("vpn.delete.question", t.Name))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	deleted := false
+	task := termos.NewFuncTask(i18n.T("vpn.task.delete", t.Name),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("vpn.cancelled"))
+			}
+			var err error
+			deleted, err = ac.DeleteVPN(m, t)
+			return err
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+	return deleted
+}
+
+// DeleteVPN останавливает туннель, удаляет его профиль и описание в конфигурации;
+// true — туннель удалён, даже если описание сохранить не удалось
+func (ac *AppConfig) DeleteVPN(m vpn.Manager, t conf.VPNConfig) (bool, error) {
+	if err := m.Delete(TunnelFor(t)); err != nil {
+		return false, err
+	}
+	ac.Log.Info(i18n.T("vpn.log.deleted"), t.Name)
+	ac.Conf.RemoveVPNTunnel(t.Name)
+	if err := ac.Conf.Save(ac.ConfFile); err != nil {
+		return true, fmt.Errorf(i18n.T("vpn.error.save"), ac.ConfFile, err)
+	}
+	return true, nil
+}
@@ -7,18 +7,6 @@ import (
 	"github.com/qzeleza/termos"
 )
 
-// otherList содержит список прочих приложений в фиксированном порядке
-var otherList = []string{
-	OtherOptionInfo,
-	OtherOptionBack,
-}
-
-// otherKeys соответствующие ключи для otherList
-var otherKeys = []string{
-	"info",
-	"quit",
-}
-
 // OtherCategoryLoop запускает цикл для выбора прочих приложений
 func (ac *AppConfig) OtherCategoryLoop() {
 	ac.ContextualLoop(func() bool {
@@ -29,16 +17,14 @@ func (ac *AppConfig) OtherCategoryLoop() {
 			return false
 		}
 
-		switch ac.Category {
-		case OtherOptionInfo:
-			ac.SelectInfoApp()
-			return true
-		case OtherOptionBack:
-			return false
-		default:
-			ac.Log.Warn(i18n.T("others.warn.invalid"))
+		if ac.Category == OtherOptionBack {
 			return false
 		}
+		if ac.runAction(CategoryOther, ac.Category) {
+			return true
+		}
+		ac.Log.Warn(i18n.T("others.warn.invalid"))
+		return false
 	}, i18n.T("loop.others"))
 }
 
@@ -51,7 +37,8 @@ func (ac *AppConfig) SelectOtherCategory() {
 		WithTitleColor(ac.AppTitleColor, true).
 		WithClearScreen(true)
 
-	labels := labelsFor(otherList)
+	list := menuList(CategoryOther, OtherOptionBack)
+	labels := labelsFor(list)
 
 	// Создаем задачу для выбора пункта меню с запоминанием последней позиции
 	menuTask := termos.NewSingleSelectTask(i18n.T("others.task.title"), labels).WithDefaultItem(ac.LastOthersIndex)
@@ -71,7 +58,7 @@ func (ac *AppConfig) SelectOtherCategory() {
 	// Сохраняем выбранный индекс и устанавливаем категорию
 	selected := menuTask.GetSelectedIndex()
 	ac.LastOthersIndex = selected
-	ac.Category = otherList[selected]
+	ac.Category = list[selected]
 }
 
 // SelectInfoApp отображает меню для выбора утилит для работы с файловой системой
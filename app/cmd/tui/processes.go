@@ -0,0 +1,362 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/procs"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/terem/internal/zlog"
+	"github.com/qzeleza/termos"
+)
+
+// Действия в разделе процессов
+var procsActions = []string{
+	"procs.action.top",
+	"procs.action.apps",
+	"procs.action.kill",
+	"procs.action.renice",
+	"procs.action.sort",
+	"procs.action.filter",
+}
+
+// Параметры списка процессов
+const (
+	procsInterval = 2 * time.Second        // Период обновления списка
+	procsSample   = 500 * time.Millisecond // Интервал замера загрузки перед выбором процесса
+	procsLimit    = 30                     // Сколько строк показывает список
+	procsLowFree  = 15                     // Доля доступной памяти, %, ниже которой выводится предупреждение
+)
+
+// procsView сортировка и фильтр списка процессов
+type procsView struct {
+	sort   string
+	filter string
+}
+
+// defaultProcsView на роутерах с малой памятью сортирует по памяти, иначе — по загрузке процессора
+func defaultProcsView(mem procs.Memory) procsView {
+	if mem.Total > 0 && mem.Total < zlog.SmallMemory {
+		return procsView{sort: procs.SortMem}
+	}
+	return procsView{sort: procs.SortCPU}
+}
+
+// apply отбирает и упорядочивает процессы снимка; потоки ядра скрываются, если не заданы фильтром явно
+func (v procsView) apply(s procs.Snapshot) []procs.Process {
+	var list []procs.Process
+	for _, p := range procs.Filter(s.Procs, v.filter) {
+		if !p.Kernel() || v.filter != "" {
+			list = append(list, p)
+		}
+	}
+	_ = procs.Sort(list, v.sort)
+	return list
+}
+
+// SelectProcessesApp отображает процессы роутера с сортировкой, фильтром и управлением
+func (ac *AppConfig) SelectProcessesApp() {
+	ac.Log.Info(i18n.T("others.log.procs"))
+	r := procs.Reader{}
+	first, _ := r.Snapshot()
+	view := defaultProcsView(first.Memory)
+
+	ac.ContextualLoop(func() bool {
+		labels := labelsFor(procsActions)
+		labels[len(labels)-2] = i18n.T("procs.action.sort", i18n.T("procs.sort."+view.sort))
+		labels[len(labels)-1] = i18n.T("procs.action.filter", valueOr(view.filter, i18n.T("procs.filter.none")))
+		index, ok := ac.procsPick(i18n.T("procs.task.action"), labels)
+		if !ok {
+			return false
+		}
+		switch procsActions[index] {
+		case "procs.action.top":
+			ac.showProcesses(r, view)
+		case "procs.action.apps":
+			ac.showProcessGroups(r)
+		case "procs.action.kill":
+			if p, ok := ac.pickProcess(r, view); ok {
+				ac.killProcess(p)
+			}
+		case "procs.action.renice":
+			if p, ok := ac.pickProcess(r, view); ok {
+				ac.reniceProcess(p)
+			}
+		case "procs.action.sort":
+			view.sort = ac.pickProcsSort(view.sort)
+		case "procs.action.filter":
+			view.filter = ac.editProcsFilter(view.filter)
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.procs"))
+}
+
+// procsPick показывает список с пунктом «Назад»; false — выбран возврат
+func (ac *AppConfig) procsPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("procs.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("procs.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// MemoryLine возвращает строку с загрузкой процессора, памятью и подкачкой
+func MemoryLine(s procs.Snapshot) string {
+	m := s.Memory
+	line := i18n.T("procs.summary", s.Load, utils.FormatBytes(m.Used()), utils.FormatBytes(m.Total),
+		utils.FormatBytes(m.Available), m.Percent(m.Available))
+	if m.SwapTotal > 0 {
+		line += " " + i18n.T("procs.summary.swap", utils.FormatBytes(m.SwapUsed()), utils.FormatBytes(m.SwapTotal))
+	}
+	return line
+}
+
+// MemoryWarning предупреждает о нехватке памяти на роутерах до 128 МБ
+func MemoryWarning(m procs.Memory) (string, bool) {
+	if m.Total == 0 || m.Total >= zlog.SmallMemory || m.Percent(m.Available) >= procsLowFree {
+		return "", false
+	}
+	return i18n.T("procs.warn.memory", utils.FormatBytes(m.Available)), true
+}
+
+// processColumns формат строки процесса; заголовок ProcessHeader использует те же ширины
+const processColumns = "%5v %5v %-1v %3v %5v %9v %5v %v"
+
+// ProcessHeader возвращает заголовок колонок списка процессов
+func ProcessHeader() string {
+	return fmt.Sprintf(processColumns, "PID", "PPID", "S", "NI", "CPU%", "RSS", "MEM%", i18n.T("procs.column.name"))
+}
+
+// ProcessLine возвращает строку процесса в колонках ProcessHeader
+func ProcessLine(p procs.Process, mem procs.Memory) string {
+	return fmt.Sprintf(processColumns, p.PID, p.PPID, p.State, p.Nice, fmt.Sprintf("%.1f", p.CPU),
+		utils.FormatBytes(p.RSS), fmt.Sprintf("%.1f", mem.Percent(p.RSS)), p.Name)
+}
+
+// ProcessLines возвращает сводку, заголовок и строки процессов, не больше limit
+func ProcessLines(s procs.Snapshot, list []procs.Process, limit int) []string {
+	lines := []string{MemoryLine(s)}
+	if warning, ok := MemoryWarning(s.Memory); ok {
+		lines = append(lines, warning)
+	}
+	if len(list) == 0 {
+		return append(lines, i18n.T("procs.empty"))
+	}
+	rows := make([]string, 0, len(list))
+	for _, p := range list {
+		rows = append(rows, ProcessLine(p, s.Memory))
+	}
+	return append(append(lines, ProcessHeader()), limitLines(rows, limit)...)
+}
+
+// GroupLine возвращает строку с процессами одного приложения
+func GroupLine(g procs.Group, mem procs.Memory) string {
+	return i18n.T("procs.group.line", g.Name, len(g.Procs), utils.FormatBytes(g.RSS), mem.Percent(g.RSS), g.CPU)
+}
+
+// AppFootprint возвращает строки с процессами приложения и занятой ими памятью
+func AppFootprint(g procs.Group, mem procs.Memory) []string {
+	if len(g.Procs) == 0 {
+		return []string{i18n.T("procs.app.none")}
+	}
+	owners := make([]string, 0, len(g.Procs))
+	for _, p := range g.Procs {
+		owners = append(owners, fmt.Sprintf("%s[%d]", p.Name, p.PID))
+	}
+	return []string{
+		i18n.T("procs.app.memory", utils.FormatBytes(g.RSS), mem.Percent(g.RSS)),
+		i18n.T("procs.app.list", strings.Join(owners, ", ")),
+	}
+}
+
+// appProcesses возвращает строки с процессами и памятью приложения для экранов состояния;
+// процессы читаются из локального /proc, поэтому для удалённого runner строк нет
+func appProcesses(runner utils.Runner, names ...string) []string {
+	if !utils.IsLocal(runner) {
+		return nil
+	}
+	s, err := procs.Reader{}.Snapshot()
+	if err != nil {
+		return nil
+	}
+	return AppFootprint(procs.App(s.Procs, names...), s.Memory)
+}
+
+// showProcesses показывает список процессов и обновляет его, пока экран не закроют
+func (ac *AppConfig) showProcesses(r procs.Reader, view procsView) {
+	queue := ac.newScreenQueue(i18n.T("procs.queue.title"))
+
+	var last procs.Snapshot
+	task := ac.newLiveTask(i18n.T("procs.task.top"), 0,
+		func(ctx context.Context, t *liveTask) error {
+			prev, err := r.Snapshot()
+			if err != nil {
+				return err
+			}
+			last = prev
+			t.Set(ProcessLines(prev, view.apply(prev), procsLimit))
+			ticker := time.NewTicker(procsInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+				cur, err := r.Snapshot()
+				if err != nil {
+					return err
+				}
+				procs.Usage(prev, &cur)
+				prev, last = cur, cur
+				t.Set(ProcessLines(cur, view.apply(cur), procsLimit))
+			}
+		},
+		func() []string { return ProcessLines(last, view.apply(last), procsLimit) })
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// showProcessGroups показывает память и загрузку процессора по приложениям
+func (ac *AppConfig) showProcessGroups(r procs.Reader) {
+	queue := ac.newScreenQueue(i18n.T("procs.queue.title"))
+
+	var s procs.Snapshot
+	task := termos.NewFuncTask(i18n.T("procs.task.apps"),
+		func() error {
+			var err error
+			s, err = r.Sample(procsSample)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string {
+			lines := []string{MemoryLine(s)}
+			var rows []string
+			for _, g := range procs.GroupByName(s.Procs) {
+				rows = append(rows, GroupLine(g, s.Memory))
+			}
+			return append(lines, limitLines(rows, procsLimit)...)
+		}),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// pickProcess показывает процессы с текущей сортировкой и фильтром и возвращает выбранный
+func (ac *AppConfig) pickProcess(r procs.Reader, view procsView) (procs.Process, bool) {
+	s, err := r.Sample(procsSample)
+	if err != nil {
+		ac.Log.Error(i18n.T("procs.log.read_failed"), err)
+		return procs.Process{}, false
+	}
+	list := view.apply(s)
+	if len(list) > procsLimit {
+		list = list[:procsLimit]
+	}
+	labels := make([]string, 0, len(list))
+	for _, p := range list {
+		labels = append(labels, ProcessLine(p, s.Memory))
+	}
+	index, ok := ac.procsPick(i18n.T("procs.task.pick"), labels)
+	if !ok {
+		return procs.Process{}, false
+	}
+	return list[index], true
+}
+
+// killProcess запрашивает сигнал и подтверждение и отправляет сигнал процессу
+func (ac *AppConfig) killProcess(p procs.Process) {
+	queue := ac.newScreenQueue(i18n.T("procs.queue.title"))
+	signals := make([]string, 0, len(procs.Signals))
+	for _, name := range procs.Signals {
+		signals = append(signals, i18n.T("procs.signal."+strings.ToLower(name)))
+	}
+	signal := termos.NewSingleSelectTask(i18n.T("procs.input.signal", p.Name, p.PID), signals)
+	confirm := termos.NewYesNoTask(i18n.T("procs.confirm.title"), i18n.T("procs.confirm.kill", p.Name, p.PID))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	task := termos.NewFuncTask(i18n.T("procs.task.kill"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("procs.cancelled"))
+			}
+			name := procs.Signals[signal.GetSelectedIndex()]
+			if err := procs.Kill(p.PID, name); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("procs.log.killed"), name, p.Name, p.PID)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(signal, confirm, task)
+	ac.runScreen(queue)
+}
+
+// reniceProcess запрашивает новый приоритет и применяет его
+func (ac *AppConfig) reniceProcess(p procs.Process) {
+	queue := ac.newScreenQueue(i18n.T("procs.queue.title"))
+	input := termos.NewInputTask(i18n.T("procs.input.nice", p.Name, p.PID),
+		i18n.T("procs.input.nice_hint", procs.MinNice, procs.MaxNice))
+	input.WithPlaceholder(strconv.Itoa(p.Nice))
+
+	task := termos.NewFuncTask(i18n.T("procs.task.renice"),
+		func() error {
+			value := strings.TrimSpace(input.GetValue())
+			nice, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf(i18n.T("procs.error.nice_value"), value)
+			}
+			if err := procs.Renice(p.PID, nice); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("procs.log.reniced"), p.Name, p.PID, nice)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(input, task)
+	ac.runScreen(queue)
+}
+
+// pickProcsSort предлагает ключ сортировки; отмена сохраняет прежний
+func (ac *AppConfig) pickProcsSort(current string) string {
+	labels := make([]string, 0, len(procs.SortKeys))
+	for _, key := range procs.SortKeys {
+		labels = append(labels, i18n.T("procs.sort."+key))
+	}
+	index, ok := ac.procsPick(i18n.T("procs.input.sort"), labels)
+	if !ok {
+		return current
+	}
+	return procs.SortKeys[index]
+}
+
+// editProcsFilter запрашивает часть имени процесса; «-» сбрасывает фильтр
+func (ac *AppConfig) editProcsFilter(current string) string {
+	queue := ac.newScreenQueue(i18n.T("procs.queue.title"))
+	input := termos.NewInputTask(i18n.T("procs.input.filter"), i18n.T("procs.input.filter_hint"))
+	input.WithPlaceholder(current).WithAllowEmpty(true)
+	queue.AddTasks(input)
+	ac.runScreen(queue)
+
+	switch value := strings.TrimSpace(input.GetValue()); value {
+	case "":
+		return current
+	case "-":
+		return ""
+	default:
+		return value
+	}
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/termos"
+)
+
+// newScreenQueue создаёт очередь для информационного экрана в оформлении приложения
+func (ac *AppConfig) newScreenQueue(title string) *termos.Queue {
+	return termos.NewQueue(title).
+		WithAppName(ac.AppTitle).
+		WithSummary(false).
+		WithTitleColor(ac.AppTitleColor, true).
+		WithClearScreen(true)
+}
+
+// runScreen запускает очередь экрана и ожидает подтверждения возврата,
+// чтобы результат не был стёрт следующим меню
+func (ac *AppConfig) runScreen(queue *termos.Queue) {
+	back := termos.NewSingleSelectTask(i18n.T("screen.back.title"), []string{i18n.T("screen.back.option")})
+	queue.AddTasks(back)
+
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+	}
+}
+
+// liveIndent отступ строк, которые задача выводит во время выполнения
+const liveIndent = "     "
+
+// liveTask задача, которая во время выполнения показывает под заголовком строки,
+// поступающие от функции, например ответы ping. Выход из задачи клавишей q или Esc
+// отменяет контекст функции
+type liveTask struct {
+	*termos.FuncTask
+	mu     sync.Mutex
+	lines  []string
+	limit  int
+	cancel context.CancelFunc
+}
+
+// newLiveTask создаёт задачу, функция которой получает контекст и саму задачу для
+// вывода строк; limit ограничивает число показанных последних строк
+func (ac *AppConfig) newLiveTask(title string, limit int, fn func(ctx context.Context, t *liveTask) error, summary func() []string) *liveTask {
+	parent := ac.RootCtx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	t := &liveTask{limit: limit, cancel: cancel}
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	t.FuncTask = termos.NewFuncTask(title, func() error {
+		defer cancel()
+		return fn(ctx, t)
+	}, opts...)
+	return t
+}
+
+// Add добавляет строку к выводу
+func (t *liveTask) Add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if t.limit > 0 && len(t.lines) > t.limit {
+		t.lines = t.lines[len(t.lines)-t.limit:]
+	}
+}
+
+// Set заменяет весь вывод
+func (t *liveTask) Set(lines []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append([]string(nil), lines...)
+}
+
+// Update передаёт сообщение задаче и отменяет функцию, если задачу закрыли
+func (t *liveTask) Update(msg tea.Msg) (termos.Task, tea.Cmd) {
+	_, cmd := t.FuncTask.Update(msg)
+	if t.IsDone() {
+		t.cancel()
+	}
+	return t, cmd
+}
+
+// View вставляет текущие строки между заголовком и подсказкой
+func (t *liveTask) View(width int) string {
+	view := t.FuncTask.View(width)
+	if t.IsDone() {
+		return view
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.lines) == 0 {
+		return view
+	}
+	head, rest, _ := strings.Cut(view, "\n")
+	var b strings.Builder
+	b.WriteString(head + "\n")
+	for _, line := range t.lines {
+		b.WriteString(liveIndent + line + "\n")
+	}
+	b.WriteString(rest)
+	return b.String()
+}
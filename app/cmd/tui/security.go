@@ -5,22 +5,6 @@ import (
 	"github.com/qzeleza/termos"
 )
 
-// Список меню для выбора категорий безопасности в фиксированном порядке
-var securityAppList = []string{
-	SecurityOptionParental,
-	SecurityOptionAntiscan,
-	SecurityOptionBackup,
-	SecurityOptionBack,
-}
-
-// securityKeys соответствующие ключи для securityAppList
-var securityKeys = []string{
-	"parental",
-	"antiscan",
-	"backup",
-	"quit",
-}
-
 // SecurityCategoryLoop запускает цикл для выбора категорий безопасности
 func (ac *AppConfig) SecurityCategoryLoop() {
 	ac.ContextualLoop(func() bool {
@@ -31,22 +15,14 @@ func (ac *AppConfig) SecurityCategoryLoop() {
 			return false
 		}
 
-		switch ac.Mode {
-		case SecurityOptionParental:
-			ac.SelectParentalControl()
-			return true
-		case SecurityOptionAntiscan:
-			ac.SelectAntiscan()
-			return true
-		case SecurityOptionBackup:
-			ac.SelectBackup()
-			return true
-		case SecurityOptionBack:
-			return false
-		default:
-			ac.Log.Warn(i18n.T("security.warn.invalid"))
+		if ac.Mode == SecurityOptionBack {
 			return false
 		}
+		if ac.runAction(CategorySecurity, ac.Mode) {
+			return true
+		}
+		ac.Log.Warn(i18n.T("security.warn.invalid"))
+		return false
 	}, i18n.T("loop.security"))
 }
 
@@ -59,7 +35,8 @@ func (ac *AppConfig) SelectSecurityApp() {
 		WithTitleColor(ac.AppTitleColor, true).
 		WithClearScreen(true)
 
-	labels := labelsFor(securityAppList)
+	list := menuList(CategorySecurity, SecurityOptionBack)
+	labels := labelsFor(list)
 
 	// Создаем задачу для выбора пункта меню с запоминанием последней позиции
 	menuTask := termos.NewSingleSelectTask(i18n.T("security.task.title"), labels).WithDefaultItem(ac.LastSecurityIndex)
@@ -79,7 +56,7 @@ func (ac *AppConfig) SelectSecurityApp() {
 	// Сохраняем выбранный индекс и устанавливаем режим
 	selected := menuTask.GetSelectedIndex()
 	ac.LastSecurityIndex = selected
-	ac.Mode = securityAppList[selected]
+	ac.Mode = list[selected]
 }
 
 // SelectParentalControl отображает меню для выбора утилит для работы с файловой системой
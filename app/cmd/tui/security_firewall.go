@@ -0,0 +1,369 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/firewall"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/termos"
+)
+
+// Действия с межсетевым экраном
+var firewallActions = []string{
+	"firewall.action.tree",
+	"firewall.action.forward",
+	"firewall.action.filter",
+	"firewall.action.owned",
+	"firewall.action.cleanup",
+	"firewall.action.back",
+}
+
+// Протоколы в порядке показа в меню
+var firewallProtos = []string{firewall.ProtoTCP, firewall.ProtoUDP, firewall.ProtoBoth}
+
+// SelectFirewall отображает раздел межсетевого экрана до выбора «Назад»
+func (ac *AppConfig) SelectFirewall() {
+	ac.Log.Info(i18n.T("security.log.firewall"))
+	m := firewall.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		index, ok := ac.firewallPick(i18n.T("firewall.task.action"), labelsFor(firewallActions[:len(firewallActions)-1]))
+		if !ok {
+			return false
+		}
+
+		switch firewallActions[index] {
+		case "firewall.action.tree":
+			ac.browseFirewall(m)
+		case "firewall.action.forward":
+			ac.addFirewallForward(m)
+		case "firewall.action.filter":
+			ac.addFirewallFilter(m)
+		case "firewall.action.owned":
+			ac.removeFirewallRule(m)
+		case "firewall.action.cleanup":
+			ac.cleanupFirewall(m)
+		default:
+			return false
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.firewall"))
+}
+
+// firewallPick показывает список и возвращает индекс выбранного элемента; последний пункт — «Назад»
+func (ac *AppConfig) firewallPick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("firewall.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("firewall.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// runFirewallTask показывает экран с одной задачей
+func (ac *AppConfig) runFirewallTask(title string, fn func() error, summary func() []string) {
+	queue := ac.newScreenQueue(i18n.T("firewall.queue.title"))
+	opts := []termos.FuncTaskOption{termos.WithStopOnError(false)}
+	if summary != nil {
+		opts = append(opts, termos.WithSummaryFunction(summary))
+	}
+	queue.AddTasks(termos.NewFuncTask(title, fn, opts...))
+	ac.runScreen(queue)
+}
+
+// rulesetLabel возвращает название набора правил
+func rulesetLabel(rs firewall.Ruleset) string {
+	return i18n.T("firewall.family."+rs.Family, len(rs.Tables), len(rs.Rules()))
+}
+
+// tableLabel возвращает название таблицы с числом цепочек
+func tableLabel(t firewall.Table) string {
+	name := t.Name
+	if t.Family != "" {
+		name = t.Family + " " + t.Name
+	}
+	return i18n.T("firewall.table.line", name, len(t.Chains))
+}
+
+// chainLabel возвращает строку цепочки с политикой и счётчиками
+func chainLabel(c firewall.Chain) string {
+	return i18n.T("firewall.chain.line", c.Name, valueOr(c.Policy, "—"), len(c.Rules), c.Packets, utils.FormatBytes(c.Bytes))
+}
+
+// FirewallRuleLine возвращает строку правила со счётчиками; правила терема отмечаются звёздочкой
+func FirewallRuleLine(r firewall.Rule) string {
+	mark := " "
+	if r.Owned() {
+		mark = "★"
+	}
+	return i18n.T("firewall.rule.line", mark, r.Packets, utils.FormatBytes(r.Bytes), r.Text)
+}
+
+// FirewallTree возвращает дерево «набор → таблица → цепочка → правило» в виде строк
+func FirewallTree(sets []firewall.Ruleset) []string {
+	var lines []string
+	for _, rs := range sets {
+		lines = append(lines, rulesetLabel(rs))
+		for _, t := range rs.Tables {
+			lines = append(lines, "  "+tableLabel(t))
+			for _, c := range t.Chains {
+				lines = append(lines, "    "+chainLabel(c))
+				for _, r := range c.Rules {
+					lines = append(lines, "      "+FirewallRuleLine(r))
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// loadFirewall читает правила; ошибка показывается отдельным экраном
+func (ac *AppConfig) loadFirewall(m firewall.Manager) ([]firewall.Ruleset, bool) {
+	sets, err := m.Load()
+	if err != nil {
+		ac.runFirewallTask(i18n.T("firewall.task.load"), func() error { return err }, nil)
+		return nil, false
+	}
+	return sets, true
+}
+
+// browseFirewall показывает правила по уровням: набор, таблица, цепочка
+func (ac *AppConfig) browseFirewall(m firewall.Manager) {
+	sets, ok := ac.loadFirewall(m)
+	if !ok {
+		return
+	}
+	labels := make([]string, 0, len(sets))
+	for _, rs := range sets {
+		labels = append(labels, rulesetLabel(rs))
+	}
+
+	ac.ContextualLoop(func() bool {
+		setIndex, ok := ac.firewallPick(i18n.T("firewall.tree.family"), labels)
+		if !ok {
+			return false
+		}
+		rs := sets[setIndex]
+
+		ac.ContextualLoop(func() bool {
+			tables := make([]string, 0, len(rs.Tables))
+			for _, t := range rs.Tables {
+				tables = append(tables, tableLabel(t))
+			}
+			tableIndex, ok := ac.firewallPick(i18n.T("firewall.tree.table"), tables)
+			if !ok {
+				return false
+			}
+			table := rs.Tables[tableIndex]
+
+			ac.ContextualLoop(func() bool {
+				chains := make([]string, 0, len(table.Chains))
+				for _, c := range table.Chains {
+					chains = append(chains, chainLabel(c))
+				}
+				chainIndex, ok := ac.firewallPick(i18n.T("firewall.tree.chain"), chains)
+				if !ok {
+					return false
+				}
+				chain := table.Chains[chainIndex]
+				ac.runFirewallTask(chainLabel(chain),
+					func() error {
+						if len(chain.Rules) == 0 {
+							return errors.New(i18n.T("firewall.tree.empty"))
+						}
+						return nil
+					},
+					func() []string {
+						lines := make([]string, 0, len(chain.Rules))
+						for _, r := range chain.Rules {
+							lines = append(lines, FirewallRuleLine(r))
+						}
+						return lines
+					})
+				return true
+			}, i18n.T("loop.firewall"))
+			return !ac.IsContextCancelled()
+		}, i18n.T("loop.firewall"))
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.firewall"))
+}
+
+// protoLabels возвращает названия протоколов для меню
+func protoLabels() []string {
+	labels := make([]string, 0, len(firewallProtos))
+	for _, p := range firewallProtos {
+		labels = append(labels, strings.ToUpper(p))
+	}
+	return labels
+}
+
+// addFirewallRules показывает задачу добавления правил и их список после успеха
+func (ac *AppConfig) addFirewallRules(m firewall.Manager, build func() ([]firewall.Rule, error)) *termos.FuncTask {
+	var rules []firewall.Rule
+	return termos.NewFuncTask(i18n.T("firewall.task.add"),
+		func() error {
+			var err error
+			if rules, err = build(); err != nil {
+				return err
+			}
+			return ac.AddFirewallRules(m, rules)
+		},
+		termos.WithSummaryFunction(func() []string {
+			lines := make([]string, 0, len(rules)+1)
+			for _, r := range rules {
+				lines = append(lines, fmt.Sprintf("%s %s/%s: %s", r.Family, r.Table, r.Chain, r.Text))
+			}
+			return append(lines, i18n.T("firewall.add.volatile"))
+		}),
+		termos.WithStopOnError(false),
+	)
+}
+
+// AddFirewallRules добавляет правила терема и записывает их в журнал
+func (ac *AppConfig) AddFirewallRules(m firewall.Manager, rules []firewall.Rule) error {
+	if err := m.Add(rules); err != nil {
+		return err
+	}
+	for _, r := range rules {
+		ac.Log.Info(i18n.T("firewall.log.added"), r.Family, r.Table, r.Chain, r.Text)
+	}
+	return nil
+}
+
+// RemoveFirewallRule удаляет одно правило терема и записывает это в журнал
+func (ac *AppConfig) RemoveFirewallRule(m firewall.Manager, rule firewall.Rule) error {
+	if err := m.Remove(rule); err != nil {
+		return err
+	}
+	ac.Log.Info(i18n.T("firewall.log.removed"), rule.Family, rule.Table, rule.Chain, rule.Text)
+	return nil
+}
+
+// addFirewallForward запрашивает параметры и добавляет проброс порта
+func (ac *AppConfig) addFirewallForward(m firewall.Manager) {
+	queue := ac.newScreenQueue(i18n.T("firewall.forward.title"))
+	proto := termos.NewSingleSelectTask(i18n.T("firewall.input.proto"), protoLabels())
+	port := termos.NewInputTask(i18n.T("firewall.input.port"), i18n.T("firewall.input.port_hint"))
+	to := termos.NewInputTask(i18n.T("firewall.input.to"), i18n.T("firewall.input.to_hint"))
+	toPort := termos.NewInputTask(i18n.T("firewall.input.to_port"), i18n.T("firewall.input.to_port_hint"))
+	toPort.WithAllowEmpty(true)
+	iface := termos.NewInputTask(i18n.T("firewall.input.iface"), i18n.T("firewall.input.iface_hint"))
+	iface.WithAllowEmpty(true)
+	note := termos.NewInputTask(i18n.T("firewall.input.note"), i18n.T("firewall.input.note_hint"))
+	note.WithAllowEmpty(true)
+
+	add := ac.addFirewallRules(m, func() ([]firewall.Rule, error) {
+		f := firewall.Forward{
+			Proto: firewallProtos[proto.GetSelectedIndex()],
+			To:    strings.TrimSpace(to.GetValue()),
+			Iface: strings.TrimSpace(iface.GetValue()),
+			Note:  strings.TrimSpace(note.GetValue()),
+		}
+		var err error
+		if f.Port, err = strconv.Atoi(strings.TrimSpace(port.GetValue())); err != nil {
+			return nil, fmt.Errorf(i18n.T("proxy.error.port_value"), port.GetValue())
+		}
+		if f.ToPort, err = portValue(toPort.GetValue(), f.Port); err != nil {
+			return nil, err
+		}
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+		return f.Rules(), nil
+	})
+	queue.AddTasks(proto, port, to, toPort, iface, note, add)
+	ac.runScreen(queue)
+}
+
+// addFirewallFilter запрашивает параметры и добавляет правило разрешения или запрета
+func (ac *AppConfig) addFirewallFilter(m firewall.Manager) {
+	queue := ac.newScreenQueue(i18n.T("firewall.filter.title"))
+	action := termos.NewSingleSelectTask(i18n.T("firewall.input.action"),
+		[]string{i18n.T("firewall.filter.allow"), i18n.T("firewall.filter.deny")})
+	chain := termos.NewSingleSelectTask(i18n.T("firewall.input.chain"),
+		[]string{i18n.T("firewall.filter.input"), i18n.T("firewall.filter.forward")})
+	proto := termos.NewSingleSelectTask(i18n.T("firewall.input.proto"), protoLabels())
+	port := termos.NewInputTask(i18n.T("firewall.input.port"), i18n.T("firewall.input.port_hint"))
+	source := termos.NewInputTask(i18n.T("firewall.input.source"), i18n.T("firewall.input.source_hint"))
+	source.WithAllowEmpty(true)
+	note := termos.NewInputTask(i18n.T("firewall.input.note"), i18n.T("firewall.input.note_hint"))
+	note.WithAllowEmpty(true)
+
+	add := ac.addFirewallRules(m, func() ([]firewall.Rule, error) {
+		f := firewall.Filter{
+			Allow:  action.GetSelectedIndex() == 0,
+			Chain:  []string{firewall.ChainInput, firewall.ChainForward}[chain.GetSelectedIndex()],
+			Proto:  firewallProtos[proto.GetSelectedIndex()],
+			Source: strings.TrimSpace(source.GetValue()),
+			Note:   strings.TrimSpace(note.GetValue()),
+		}
+		var err error
+		if f.Port, err = strconv.Atoi(strings.TrimSpace(port.GetValue())); err != nil {
+			return nil, fmt.Errorf(i18n.T("proxy.error.port_value"), port.GetValue())
+		}
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+		return f.Rules(), nil
+	})
+	queue.AddTasks(action, chain, proto, port, source, note, add)
+	ac.runScreen(queue)
+}
+
+// removeFirewallRule предлагает выбрать правило терема и удаляет его
+func (ac *AppConfig) removeFirewallRule(m firewall.Manager) {
+	sets, ok := ac.loadFirewall(m)
+	if !ok {
+		return
+	}
+	owned := firewall.Owned(sets)
+	if len(owned) == 0 {
+		ac.runFirewallTask(i18n.T("firewall.task.load"), func() error { return errors.New(i18n.T("firewall.owned.empty")) }, nil)
+		return
+	}
+
+	labels := make([]string, 0, len(owned))
+	for _, r := range owned {
+		labels = append(labels, fmt.Sprintf("%s %s/%s: %s", r.Family, r.Table, r.Chain, r.Comment))
+	}
+	index, ok := ac.firewallPick(i18n.T("firewall.owned.pick"), labels)
+	if !ok {
+		return
+	}
+	rule := owned[index]
+	ac.runFirewallTask(i18n.T("firewall.task.remove"),
+		func() error { return ac.RemoveFirewallRule(m, rule) }, nil)
+}
+
+// cleanupFirewall после подтверждения удаляет все правила терема, не трогая остальные
+func (ac *AppConfig) cleanupFirewall(m firewall.Manager) {
+	queue := ac.newScreenQueue(i18n.T("firewall.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("firewall.cleanup.title"), i18n.T("firewall.cleanup.question"))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	removed := 0
+	cleanup := termos.NewFuncTask(i18n.T("firewall.task.cleanup"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("firewall.cancelled"))
+			}
+			var err error
+			removed, err = m.RemoveOwned()
+			ac.Log.Info(i18n.T("firewall.log.cleanup"), removed)
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return []string{i18n.T("firewall.cleanup.done", removed)} }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, cleanup)
+	ac.runScreen(queue)
+}
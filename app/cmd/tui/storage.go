@@ -0,0 +1,307 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/storage"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/termos"
+)
+
+// Действия в разделе накопителей
+var storageActions = []string{
+	"storage.action.devices",
+	"storage.action.mount",
+	"storage.action.unmount",
+	"storage.action.swap",
+	"storage.action.threshold",
+}
+
+// SelectStorageApp отображает накопители роутера и действия с ними
+func (ac *AppConfig) SelectStorageApp() {
+	ac.Log.Info(i18n.T("others.log.storage"))
+	r := storage.Reader{}
+	m := storage.Manager{}
+
+	ac.ContextualLoop(func() bool {
+		labels := labelsFor(storageActions)
+		labels[len(labels)-1] = i18n.T("storage.action.threshold", ac.FreeThreshold())
+		index, ok := ac.storagePick(i18n.T("storage.task.action"), labels)
+		if !ok {
+			return false
+		}
+		switch storageActions[index] {
+		case "storage.action.devices":
+			ac.showStorage(r)
+		case "storage.action.mount":
+			if v, ok := ac.pickVolume(r, false); ok {
+				ac.mountVolume(m, v)
+			}
+		case "storage.action.unmount":
+			if v, ok := ac.pickVolume(r, true); ok {
+				ac.unmountVolume(m, v)
+			}
+		case "storage.action.swap":
+			ac.swapLoop(m)
+		case "storage.action.threshold":
+			ac.editFreeThreshold()
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.storage"))
+}
+
+// storagePick показывает список с пунктом «Назад»; false — выбран возврат
+func (ac *AppConfig) storagePick(title string, labels []string) (int, bool) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	menu := termos.NewSingleSelectTask(title, append(labels, i18n.T("storage.action.back")))
+	queue.AddTasks(menu)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return 0, false
+	}
+	if menu.HasError() || menu.GetSelectedIndex() >= len(labels) || ac.IsContextCancelled() {
+		return 0, false
+	}
+	return menu.GetSelectedIndex(), true
+}
+
+// FreeThreshold возвращает порог свободного места из конфигурации или значение по умолчанию
+func (ac *AppConfig) FreeThreshold() int {
+	if ac.Conf.Storage.FreeThreshold > 0 {
+		return ac.Conf.Storage.FreeThreshold
+	}
+	return storage.DefaultFreeThreshold
+}
+
+// DeviceLine возвращает строку с описанием накопителя
+func DeviceLine(d storage.Device) string {
+	parts := []string{d.Name}
+	if model := strings.TrimSpace(d.Vendor + " " + d.Model); model != "" {
+		parts = append(parts, model)
+	}
+	if d.Transport != "" {
+		parts = append(parts, strings.ToUpper(d.Transport))
+	}
+	parts = append(parts, utils.FormatBytes(d.Size))
+	if d.Removable {
+		parts = append(parts, i18n.T("storage.device.removable"))
+	}
+	if d.Rotational {
+		parts = append(parts, i18n.T("storage.device.rotational"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// HealthLine возвращает строку с признаками состояния накопителя
+func HealthLine(h storage.Health) string {
+	status := i18n.T("storage.health.ok")
+	if !h.OK() {
+		status = i18n.T("storage.health.bad", valueOr(h.State, "running"), h.IOErrors)
+	}
+	return i18n.T("storage.health.line", status, utils.FormatBytes(h.Read), utils.FormatBytes(h.Written))
+}
+
+// VolumeLine возвращает строку раздела: файловая система, точка монтирования и свободное место
+func VolumeLine(v storage.Volume) string {
+	if !v.Mounted() {
+		return i18n.T("storage.volume.unmounted", v.Name, utils.FormatBytes(v.Size))
+	}
+	line := i18n.T("storage.volume.mounted", v.Name, v.FSType, v.MountPoint,
+		utils.FormatBytes(v.Usage.Avail), utils.FormatBytes(v.Usage.Total), v.Usage.FreePercent())
+	if v.ReadOnly {
+		line += " " + i18n.T("storage.volume.readonly")
+	}
+	return line
+}
+
+// StorageLines возвращает строки с накопителями, их состоянием и разделами
+func StorageLines(devices []storage.Device) []string {
+	if len(devices) == 0 {
+		return []string{i18n.T("storage.empty")}
+	}
+	var lines []string
+	for _, d := range devices {
+		lines = append(lines, DeviceLine(d), "  "+HealthLine(d.Health))
+		for _, v := range d.Volumes() {
+			lines = append(lines, "  "+VolumeLine(v))
+		}
+	}
+	return lines
+}
+
+// AlertLine возвращает предупреждение о нехватке места
+func AlertLine(a storage.Alert) string {
+	return i18n.T("storage.alert", a.Point, a.Device, utils.FormatBytes(a.Usage.Avail), a.Usage.FreePercent())
+}
+
+// StorageAlertsTask добавляет в очередь задачу с предупреждениями о нехватке места, если они есть
+func (ac *AppConfig) StorageAlertsTask(queue *termos.Queue) {
+	alerts, err := storage.Reader{}.Alerts(ac.FreeThreshold())
+	if err != nil || len(alerts) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		lines = append(lines, AlertLine(a))
+		ac.Log.Warn(i18n.T("storage.log.alert"), a.Point, a.Usage.FreePercent())
+	}
+
+	task := termos.NewFuncTask(i18n.T("storage.alert.title", ac.FreeThreshold()),
+		func() error {
+			return errors.New(strings.Join(lines, "\n"))
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+}
+
+// showStorage показывает накопители, их разделы и свободное место
+func (ac *AppConfig) showStorage(r storage.Reader) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+
+	var devices []storage.Device
+	task := termos.NewFuncTask(i18n.T("storage.task.devices"),
+		func() error {
+			var err error
+			devices, err = r.Devices()
+			return err
+		},
+		termos.WithSummaryFunction(func() []string { return StorageLines(devices) }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(task)
+	ac.runScreen(queue)
+}
+
+// pickVolume показывает смонтированные или свободные разделы и возвращает выбранный.
+// Разделы, которые нельзя отмонтировать, в список не попадают
+func (ac *AppConfig) pickVolume(r storage.Reader, mounted bool) (storage.Volume, bool) {
+	devices, err := r.Devices()
+	if err != nil {
+		ac.Log.Error(i18n.T("storage.log.read_failed"), err)
+		return storage.Volume{}, false
+	}
+	var volumes []storage.Volume
+	var labels []string
+	for _, d := range devices {
+		for _, v := range d.Volumes() {
+			if v.Mounted() != mounted || (mounted && storage.Protected(v.MountPoint)) {
+				continue
+			}
+			volumes = append(volumes, v)
+			labels = append(labels, VolumeLine(v))
+		}
+	}
+	if len(volumes) == 0 {
+		ac.runStorageTask(i18n.T("storage.task.volumes"), func() error {
+			return errors.New(i18n.T("storage.error.no_volumes"))
+		})
+		return storage.Volume{}, false
+	}
+	index, ok := ac.storagePick(i18n.T("storage.task.pick"), labels)
+	if !ok {
+		return storage.Volume{}, false
+	}
+	return volumes[index], true
+}
+
+// runStorageTask показывает экран с одной задачей
+func (ac *AppConfig) runStorageTask(title string, fn func() error) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	queue.AddTasks(termos.NewFuncTask(title, fn, termos.WithStopOnError(false)))
+	ac.runScreen(queue)
+}
+
+// mountVolume запрашивает точку монтирования и монтирует раздел
+func (ac *AppConfig) mountVolume(m storage.Manager, v storage.Volume) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	input := termos.NewInputTask(i18n.T("storage.input.point", v.Name), i18n.T("storage.input.point_hint"))
+	input.WithPlaceholder(storage.MountPoint(v.Name)).WithAllowEmpty(true)
+
+	task := termos.NewFuncTask(i18n.T("storage.task.mount"),
+		func() error {
+			_, err := ac.MountVolume(m, v.Name, input.GetValue())
+			return err
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(input, task)
+	ac.runScreen(queue)
+}
+
+// MountVolume монтирует раздел name в point (пустая — точка по умолчанию) и возвращает точку монтирования
+func (ac *AppConfig) MountVolume(m storage.Manager, name, point string) (string, error) {
+	point = valueOr(strings.TrimSpace(point), storage.MountPoint(name))
+	if err := m.Mount(name, point); err != nil {
+		return point, err
+	}
+	ac.Log.Info(i18n.T("storage.log.mounted"), name, point)
+	return point, nil
+}
+
+// unmountVolume запрашивает подтверждение и отмонтирует раздел
+func (ac *AppConfig) unmountVolume(m storage.Manager, v storage.Volume) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("storage.confirm.title"), i18n.T("storage.confirm.unmount", v.Name, v.MountPoint))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	task := termos.NewFuncTask(i18n.T("storage.task.unmount"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("storage.cancelled"))
+			}
+			return ac.UnmountVolume(m, v)
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
+
+// UnmountVolume отмонтирует раздел
+func (ac *AppConfig) UnmountVolume(m storage.Manager, v storage.Volume) error {
+	if err := m.Unmount(v.MountPoint); err != nil {
+		return err
+	}
+	ac.Log.Info(i18n.T("storage.log.unmounted"), v.Name, v.MountPoint)
+	return nil
+}
+
+// editFreeThreshold запрашивает порог свободного места и сохраняет его в конфигурацию
+func (ac *AppConfig) editFreeThreshold() {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	input := termos.NewInputTask(i18n.T("storage.input.threshold"), i18n.T("storage.input.threshold_hint"))
+	input.WithPlaceholder(strconv.Itoa(ac.FreeThreshold())).WithAllowEmpty(true)
+
+	task := termos.NewFuncTask(i18n.T("storage.task.threshold"),
+		func() error {
+			value := strings.TrimSpace(input.GetValue())
+			if value == "" {
+				return nil
+			}
+			_, err := ac.SetFreeThreshold(value)
+			return err
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(input, task)
+	ac.runScreen(queue)
+}
+
+// SetFreeThreshold проверяет порог свободного места в процентах и сохраняет его в конфигурацию
+func (ac *AppConfig) SetFreeThreshold(value string) (int, error) {
+	percent, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || percent < 1 || percent > 90 {
+		return 0, fmt.Errorf(i18n.T("storage.error.threshold"), value)
+	}
+	ac.Conf.Storage.FreeThreshold = percent
+	if err := ac.Conf.Save(ac.ConfFile); err != nil {
+		return 0, fmt.Errorf(i18n.T("storage.error.save"), ac.ConfFile, err)
+	}
+	ac.Log.Info(i18n.T("storage.log.threshold"), percent)
+	return percent, nil
+}
@@ -0,0 +1,262 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/procs"
+	"github.com/qzeleza/terem/internal/storage"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/termos"
+)
+
+// SwapLines возвращает строки с состоянием файла подкачки и других областей подкачки
+func SwapLines(s storage.SwapFile) []string {
+	var lines []string
+	if !s.Exists {
+		lines = append(lines, i18n.T("swap.status.missing", s.Path))
+	} else {
+		state := i18n.T("swap.status.off")
+		if s.Active {
+			state = i18n.T("swap.status.on", utils.FormatBytes(s.Used))
+		}
+		boot := i18n.T("swap.status.manual")
+		if s.Persistent {
+			boot = i18n.T("swap.status.persistent")
+		}
+		lines = append(lines, i18n.T("swap.status.file", s.Path, utils.FormatBytes(s.Size), state, boot))
+	}
+	for _, sw := range s.Other {
+		lines = append(lines, i18n.T("swap.status.other", sw.Path, sw.Type, utils.FormatBytes(sw.Used), utils.FormatBytes(sw.Size)))
+	}
+	return lines
+}
+
+// swapActions возвращает действия, доступные для файла подкачки в его текущем состоянии
+func swapActions(s storage.SwapFile) []string {
+	if !s.Exists {
+		return []string{"swap.action.create"}
+	}
+	actions := []string{"swap.action.enable"}
+	if s.Active {
+		actions[0] = "swap.action.disable"
+	}
+	return append(actions, "swap.action.resize", "swap.action.persist", "swap.action.remove")
+}
+
+// swapLoop показывает состояние файла подкачки в /opt и действия с ним
+func (ac *AppConfig) swapLoop(m storage.Manager) {
+	file := storage.DefaultSwapPath
+	ac.ContextualLoop(func() bool {
+		s, err := m.SwapStatus(file)
+		if err != nil {
+			ac.runStorageTask(i18n.T("swap.task.status"), func() error { return err })
+			return false
+		}
+		actions := swapActions(s)
+		labels := labelsFor(actions)
+		for i, key := range actions {
+			if key == "swap.action.persist" {
+				labels[i] = i18n.T(key, onOff(s.Persistent))
+			}
+		}
+		index, ok := ac.storagePick(strings.Join(SwapLines(s), "; "), labels)
+		if !ok {
+			return false
+		}
+		switch actions[index] {
+		case "swap.action.create":
+			ac.createSwap(m, file)
+		case "swap.action.enable":
+			ac.runStorageTask(i18n.T("swap.task.enable"), func() error {
+				if err := m.EnableSwap(file); err != nil {
+					return err
+				}
+				ac.Log.Info(i18n.T("swap.log.enabled"), file)
+				return nil
+			})
+		case "swap.action.disable":
+			ac.runStorageTask(i18n.T("swap.task.disable"), func() error {
+				if err := m.DisableSwap(file); err != nil {
+					return err
+				}
+				ac.Log.Info(i18n.T("swap.log.disabled"), file)
+				return nil
+			})
+		case "swap.action.resize":
+			ac.resizeSwap(m, s)
+		case "swap.action.persist":
+			ac.runStorageTask(i18n.T("swap.task.persist"), func() error {
+				if err := m.PersistSwap(file, !s.Persistent); err != nil {
+					return err
+				}
+				ac.Log.Info(i18n.T("swap.log.persist"), file, onOff(!s.Persistent))
+				return nil
+			})
+		case "swap.action.remove":
+			ac.removeSwap(m, file)
+		}
+		return !ac.IsContextCancelled()
+	}, i18n.T("loop.swap"))
+}
+
+// onOff возвращает локализованное «вкл» или «выкл»
+func onOff(on bool) string {
+	if on {
+		return i18n.T("swap.on")
+	}
+	return i18n.T("swap.off")
+}
+
+// swapSizeInput возвращает поле ввода размера подкачки в МБ с предложенным значением
+func swapSizeInput(title string, suggested uint64) *termos.InputTask {
+	input := termos.NewInputTask(title, i18n.T("storage.input.swap_size_hint", storage.MinSwapSize>>20, storage.MaxSwapSize>>20))
+	input.WithPlaceholder(strconv.FormatUint(suggested>>20, 10)).WithAllowEmpty(true)
+	return input
+}
+
+// parseSwapSize разбирает размер подкачки в МБ; пустое значение заменяется предложенным
+func parseSwapSize(value string, suggested uint64) (uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return suggested, nil
+	}
+	mb, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf(i18n.T("storage.error.swap_value"), value)
+	}
+	return mb << 20, nil
+}
+
+// suggestedSwap возвращает размер подкачки, предлагаемый для памяти роутера
+func suggestedSwap() uint64 {
+	s, _ := procs.Reader{}.Snapshot()
+	return storage.SuggestedSwapSize(s.Memory.Total)
+}
+
+// createSwap запрашивает размер файла подкачки и автозапуск, создаёт и включает его
+func (ac *AppConfig) createSwap(m storage.Manager, file string) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	suggested := suggestedSwap()
+	size := swapSizeInput(i18n.T("storage.input.swap_size"), suggested)
+	persist := termos.NewYesNoTask(i18n.T("swap.input.persist"), i18n.T("swap.input.persist_hint"))
+
+	task := termos.NewFuncTask(i18n.T("storage.task.swap"),
+		func() error {
+			bytes, err := parseSwapSize(size.GetValue(), suggested)
+			if err != nil {
+				return err
+			}
+			if err := m.CreateSwap(file, bytes); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("storage.log.swap"), file, bytes>>20)
+			if persist.IsYes() {
+				return m.PersistSwap(file, true)
+			}
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(size, persist, task)
+	ac.runScreen(queue)
+}
+
+// resizeSwap запрашивает новый размер и пересоздаёт файл подкачки
+func (ac *AppConfig) resizeSwap(m storage.Manager, s storage.SwapFile) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	size := swapSizeInput(i18n.T("swap.input.resize", utils.FormatBytes(s.Size)), s.Size)
+
+	task := termos.NewFuncTask(i18n.T("swap.task.resize"),
+		func() error {
+			bytes, err := parseSwapSize(size.GetValue(), s.Size)
+			if err != nil {
+				return err
+			}
+			if bytes == s.Size {
+				return nil
+			}
+			if err := m.ResizeSwap(s.Path, bytes); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("swap.log.resized"), s.Path, bytes>>20)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(size, task)
+	ac.runScreen(queue)
+}
+
+// removeSwap запрашивает подтверждение, выключает и удаляет файл подкачки
+func (ac *AppConfig) removeSwap(m storage.Manager, file string) {
+	queue := ac.newScreenQueue(i18n.T("storage.queue.title"))
+	confirm := termos.NewYesNoTask(i18n.T("storage.confirm.title"), i18n.T("swap.confirm.remove", file))
+	confirm.WithDefaultItem(termos.NoOption)
+
+	task := termos.NewFuncTask(i18n.T("swap.task.remove"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("storage.cancelled"))
+			}
+			if err := m.RemoveSwap(file); err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("swap.log.removed"), file)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
+
+// adviseSwap перед установкой тяжёлого приложения предлагает включить подкачку,
+// если её нет, а доступной памяти мало. Установка продолжается при любом ответе
+func (ac *AppConfig) adviseSwap(app string, runner utils.Runner) {
+	if !utils.IsLocal(runner) {
+		return
+	}
+	snapshot, err := procs.Reader{}.Snapshot()
+	if err != nil || !storage.SwapAdvised(snapshot.Memory) {
+		return
+	}
+	m := storage.Manager{Runner: runner}
+	file := storage.DefaultSwapPath
+	s, err := m.SwapStatus(file)
+	if err != nil {
+		return
+	}
+	size := storage.SuggestedSwapSize(snapshot.Memory.Total)
+	if s.Exists {
+		size = s.Size
+	}
+	ac.Log.Warn(i18n.T("swap.log.advised"), app, utils.FormatBytes(snapshot.Memory.Available))
+
+	queue := ac.newScreenQueue(i18n.T("swap.advice.title"))
+	confirm := termos.NewYesNoTask(i18n.T("swap.advice.title"),
+		i18n.T("swap.advice.text", utils.FormatBytes(snapshot.Memory.Available), app, utils.FormatBytes(size), file))
+	task := termos.NewFuncTask(i18n.T("storage.task.swap"),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("swap.advice.skipped"))
+			}
+			if s.Exists {
+				err = m.EnableSwap(file)
+			} else {
+				err = m.CreateSwap(file, size)
+			}
+			if err != nil {
+				return err
+			}
+			ac.Log.Info(i18n.T("swap.log.enabled"), file)
+			return m.PersistSwap(file, true)
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
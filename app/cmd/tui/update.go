@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"errors"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/update"
+	"github.com/qzeleza/terem/internal/version"
+	"github.com/qzeleza/termos"
+)
+
+// Updater возвращает средство обновления с лентой из конфигурации; feed, если задан,
+// заменяет ленту из конфигурации. Подписи проверяются только ключом из сборки
+func (ac *AppConfig) Updater(feed string) update.Updater {
+	return update.Updater{
+		Feed: valueOr(feed, ac.Conf.Update.Feed),
+		Lang: i18n.Language(),
+	}
+}
+
+// UpdateLines возвращает строки о доступном выпуске и изменениях в нём
+func UpdateLines(current string, r update.Release, notes []update.Note) []string {
+	if version.Compare(r.Version, current) <= 0 {
+		return []string{i18n.T("update.latest", current)}
+	}
+	lines := []string{i18n.T("update.available", current, r.Version, valueOr(r.Date, "-"))}
+	for _, n := range notes {
+		lines = append(lines, i18n.T("update.note", n.Version, valueOr(n.Date, "-")))
+		for _, line := range n.Lines {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return lines
+}
+
+// SelfUpdate проверяет ленту выпусков, показывает изменения и после подтверждения
+// обновляет терем
+func (ac *AppConfig) SelfUpdate() {
+	ac.Log.Info(i18n.T("update.log.check"), ac.Version)
+	u := ac.Updater("")
+	var release update.Release
+	var notes []update.Note
+	var warning error
+
+	queue := ac.newScreenQueue(i18n.T("update.queue.title"))
+	check := termos.NewFuncTask(i18n.T("update.task.check"),
+		func() error {
+			var err error
+			if release, err = u.Latest(ac.RootCtx); err != nil {
+				return err
+			}
+			// Журнал версий необязателен: без него обновление всё равно возможно
+			notes, warning = u.Changes(ac.RootCtx, release, ac.Version)
+			return nil
+		},
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(check)
+	if err := queue.Run(); err != nil {
+		ac.Log.Error(i18n.T("screen.error"), err)
+		return
+	}
+	if ac.IsContextCancelled() {
+		return
+	}
+
+	lines := UpdateLines(ac.Version, release, notes)
+	if warning != nil {
+		lines = append(lines, i18n.T("update.warn.changelog", warning))
+	}
+	queue = ac.newScreenQueue(i18n.T("update.queue.title"))
+	summary := termos.NewFuncTask(i18n.T("update.task.check"),
+		func() error {
+			return check.Error()
+		},
+		termos.WithSummaryFunction(func() []string { return lines }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(summary)
+	if check.HasError() || version.Compare(release.Version, ac.Version) <= 0 {
+		ac.runScreen(queue)
+		return
+	}
+
+	confirm := termos.NewYesNoTask(i18n.T("update.confirm.title"), i18n.T("update.confirm.install", release.Version))
+	task := termos.NewFuncTask(i18n.T("update.task.install", release.Version),
+		func() error {
+			if !confirm.IsYes() {
+				return errors.New(i18n.T("update.cancelled"))
+			}
+			if err := u.Install(ac.RootCtx, release); err != nil {
+				ac.Log.Error(i18n.T("update.log.failed"), err)
+				return err
+			}
+			ac.Log.Info(i18n.T("update.log.installed"), ac.Version, release.Version)
+			return nil
+		},
+		termos.WithSummaryFunction(func() []string { return []string{i18n.T("update.restart")} }),
+		termos.WithStopOnError(false),
+	)
+	queue.AddTasks(confirm, task)
+	ac.runScreen(queue)
+}
@@ -0,0 +1,267 @@
+package adguard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// fakeAdGuard заглушка API AdGuard Home с состоянием в памяти
+type fakeAdGuard struct {
+	mu         sync.Mutex
+	configured bool
+	protection bool
+	filters    []Filter
+	rules      []string
+	setup      SetupConfig
+}
+
+func (f *fakeAdGuard) handler() http.Handler {
+	mux := http.NewServeMux()
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !f.configured {
+				http.Redirect(w, r, "/install.html", http.StatusFound)
+				return
+			}
+			user, password, ok := r.BasicAuth()
+			if !ok || user != f.setup.Username || password != f.setup.Password {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			h(w, r)
+		}
+	}
+	decode := func(r *http.Request, v any) {
+		_ = json.NewDecoder(r.Body).Decode(v)
+	}
+
+	mux.HandleFunc("GET /control/install/get_addresses", func(w http.ResponseWriter, r *http.Request) {
+		if f.configured {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"web_port":3000,"dns_port":53,"interfaces":{}}`))
+	})
+	mux.HandleFunc("POST /control/install/check_config", func(w http.ResponseWriter, r *http.Request) {
+		var req SetupConfig
+		decode(r, &req)
+		if req.DNS.Port == 53 {
+			// Порт 53 занят dnsmasq
+			_, _ = w.Write([]byte(`{"dns":{"status":"address already in use"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"dns":{"status":""},"web":{"status":""}}`))
+	})
+	mux.HandleFunc("POST /control/install/configure", func(w http.ResponseWriter, r *http.Request) {
+		decode(r, &f.setup)
+		f.configured = true
+	})
+	mux.HandleFunc("GET /control/status", auth(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Status{Version: "v0.107.52", Running: true, ProtectionEnabled: f.protection,
+			DNSPort: f.setup.DNS.Port, HTTPPort: f.setup.Web.Port})
+	}))
+	mux.HandleFunc("POST /control/protection", auth(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Enabled bool }
+		decode(r, &req)
+		f.protection = req.Enabled
+	}))
+	mux.HandleFunc("GET /control/stats", auth(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"num_dns_queries":200,"num_blocked_filtering":50,"avg_processing_time":0.012,
+			"top_blocked_domains":[{"ads.example.com":30},{"tracker.example.net":20}],
+			"top_queried_domains":[{"example.com":100}],"top_clients":[{"192.168.1.10":150}]}`))
+	}))
+	mux.HandleFunc("GET /control/filtering/status", auth(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Filtering{Enabled: true, Interval: 24, Filters: f.filters, UserRules: f.rules})
+	}))
+	mux.HandleFunc("POST /control/filtering/add_url", auth(func(w http.ResponseWriter, r *http.Request) {
+		var req Filter
+		decode(r, &req)
+		for _, existing := range f.filters {
+			if existing.URL == req.URL {
+				http.Error(w, "Filter URL already added", http.StatusBadRequest)
+				return
+			}
+		}
+		f.filters = append(f.filters, Filter{ID: int64(len(f.filters) + 1), Name: req.Name, URL: req.URL, Enabled: true, RulesCount: 10})
+	}))
+	mux.HandleFunc("POST /control/filtering/remove_url", auth(func(w http.ResponseWriter, r *http.Request) {
+		var req Filter
+		decode(r, &req)
+		f.filters = slices.DeleteFunc(f.filters, func(x Filter) bool { return x.URL == req.URL })
+	}))
+	mux.HandleFunc("POST /control/filtering/set_url", auth(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL  string
+			Data Filter
+		}
+		decode(r, &req)
+		for i := range f.filters {
+			if f.filters[i].URL == req.URL {
+				f.filters[i].Enabled = req.Data.Enabled
+			}
+		}
+	}))
+	mux.HandleFunc("POST /control/filtering/set_rules", auth(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Rules []string }
+		decode(r, &req)
+		f.rules = req.Rules
+	}))
+	mux.HandleFunc("GET /control/clients", auth(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"clients":[{"name":"Kids tablet","ids":["192.168.1.20"],"use_global_settings":true,"filtering_enabled":true}],"auto_clients":[]}`))
+	}))
+	return mux
+}
+
+func newFake(t *testing.T, configured bool) (*fakeAdGuard, Client) {
+	t.Helper()
+	fake := &fakeAdGuard{configured: configured, protection: true}
+	if configured {
+		fake.setup = SetupConfig{Username: "admin", Password: "password1"}
+	}
+	srv := httptest.NewServer(fake.handler())
+	t.Cleanup(srv.Close)
+	return fake, Client{BaseURL: srv.URL, User: "admin", Password: "password1", HTTPClient: srv.Client()}
+}
+
+func TestStatusStatsAndProtection(t *testing.T) {
+	_, c := newFake(t, true)
+
+	st, err := c.Status()
+	if err != nil || !st.ProtectionEnabled || st.Version != "v0.107.52" {
+		t.Fatalf("status = %+v, %v", st, err)
+	}
+	if err := c.SetProtection(false); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := c.Status(); st.ProtectionEnabled {
+		t.Error("protection still enabled")
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TopList{{Name: "ads.example.com", Count: 30}, {Name: "tracker.example.net", Count: 20}}
+	if !reflect.DeepEqual(stats.TopBlocked, want) || stats.BlockedPercent() != 25 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestAuthError(t *testing.T) {
+	_, c := newFake(t, true)
+	c.Password = "wrong"
+	if _, err := c.Status(); err == nil || err.Error() != i18n.T("adguard.error.auth") {
+		t.Errorf("expected auth error, got %v", err)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	fake, c := newFake(t, true)
+	const url = "https://adguardteam.github.io/HostlistsRegistry/assets/filter_1.txt"
+
+	if err := c.AddFilter("AdGuard DNS filter", url); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddFilter("duplicate", url); err == nil || !strings.Contains(err.Error(), "already added") {
+		t.Errorf("expected API error text, got %v", err)
+	}
+	f, err := c.Filtering()
+	if err != nil || len(f.Filters) != 1 {
+		t.Fatalf("filtering = %+v, %v", f, err)
+	}
+	if err := c.SetFilterEnabled(f.Filters[0], false); err != nil || fake.filters[0].Enabled {
+		t.Errorf("filter not disabled: %v", err)
+	}
+	if err := c.RemoveFilter(url); err != nil || len(fake.filters) != 0 {
+		t.Errorf("filter not removed: %v", err)
+	}
+}
+
+func TestClientRules(t *testing.T) {
+	fake, c := newFake(t, true)
+	fake.rules = []string{"||example.org^"}
+
+	block := ClientRule{Domain: "youtube.com", Client: "Kids tablet"}
+	allow := ClientRule{Domain: "ads.example.com", Client: "192.168.1.10", Allow: true}
+	for _, r := range []ClientRule{block, allow, block} {
+		if err := c.AddUserRule(r.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if block.String() != "||youtube.com^$client='Kids tablet'" || allow.String() != "@@||ads.example.com^$client=192.168.1.10" {
+		t.Errorf("unexpected rule syntax: %s, %s", block, allow)
+	}
+	if got := ClientRules(fake.rules); !reflect.DeepEqual(got, []ClientRule{block, allow}) {
+		t.Errorf("ClientRules = %+v", got)
+	}
+
+	if err := c.RemoveUserRule(block.String()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fake.rules, []string{"||example.org^", allow.String()}) {
+		t.Errorf("rules = %v", fake.rules)
+	}
+
+	quoted := ClientRule{Domain: "example.com", Client: "Bob's phone, old"}
+	if parsed, ok := ParseClientRule(quoted.String()); !ok || parsed != quoted {
+		t.Errorf("round trip %q -> %+v", quoted.String(), parsed)
+	}
+	if err := (ClientRule{Domain: "bad domain", Client: "x"}).Validate(); err == nil {
+		t.Error("expected validation error")
+	}
+
+	clients, err := c.Clients()
+	if err != nil || len(clients) != 1 || clients[0].Name != "Kids tablet" {
+		t.Errorf("clients = %+v, %v", clients, err)
+	}
+}
+
+func TestSetupWizard(t *testing.T) {
+	fake, c := newFake(t, false)
+
+	need, err := c.NeedsSetup()
+	if err != nil || !need {
+		t.Fatalf("NeedsSetup = %v, %v", need, err)
+	}
+	cfg := SetupConfig{
+		Web:      SetupAddress{IP: "0.0.0.0", Port: 3000},
+		DNS:      SetupAddress{IP: "0.0.0.0", Port: 5353},
+		Username: "admin",
+		Password: "short",
+	}
+	if err := c.Setup(cfg); err == nil {
+		t.Error("expected error for short password")
+	}
+	cfg.Password = "password1"
+	cfg.DNS.Port = 53
+	if err := c.Setup(cfg); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("expected busy port error, got %v", err)
+	}
+	cfg.DNS.Port = 5353
+	if err := c.Setup(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.configured || fake.setup != cfg {
+		t.Errorf("setup = %+v", fake.setup)
+	}
+	if need, _ := c.NeedsSetup(); need {
+		t.Error("setup still required")
+	}
+	if err := c.WaitReady(time.Second); err != nil {
+		t.Error(err)
+	}
+	if st, err := c.Status(); err != nil || st.DNSPort != 5353 {
+		t.Errorf("status after setup = %+v, %v", st, err)
+	}
+}
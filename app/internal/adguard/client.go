@@ -0,0 +1,180 @@
+// Package adguard работает с AdGuard Home через его локальный HTTP API: состояние и статистика,
+// включение защиты, списки фильтров, правила для клиентов и первоначальная настройка.
+package adguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ports"
+	"github.com/qzeleza/terem/internal/service"
+)
+
+// DefaultURL адрес веб-интерфейса AdGuard Home по умолчанию (мастер первого запуска)
+const DefaultURL = "http://127.0.0.1:3000"
+
+// WizardPort порт мастера первого запуска; до настройки его сменить нельзя
+const WizardPort = 3000
+
+// Service служба AdGuard Home в Entware
+var Service = service.Service{Name: "adguardhome", Package: "adguardhome-go", Process: "AdGuardHome"}
+
+// WizardPorts порты, которые занимает только что установленный AdGuard Home
+func WizardPorts() []ports.Need {
+	return []ports.Need{{Proto: "tcp", Port: WizardPort, Role: ports.RoleWeb, Fixed: true}}
+}
+
+// Ports порты настроенного AdGuard Home: веб-интерфейс и DNS по TCP и UDP
+func Ports(web, dns int) []ports.Need {
+	return []ports.Need{
+		{Proto: "tcp", Port: web, Role: ports.RoleWeb},
+		{Proto: "udp", Port: dns, Role: ports.RoleDNS},
+		{Proto: "tcp", Port: dns, Role: ports.RoleDNS},
+	}
+}
+
+// Client клиент API AdGuard Home
+type Client struct {
+	BaseURL    string // Адрес веб-интерфейса, по умолчанию DefaultURL
+	User       string
+	Password   string
+	HTTPClient *http.Client
+}
+
+func (c Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (c Client) url(path string) string {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultURL
+	}
+	return strings.TrimSuffix(base, "/") + "/control/" + path
+}
+
+// do выполняет запрос к API; in кодируется в JSON, ответ декодируется в out (если не nil)
+func (c Client) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.url(path), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Password)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf(i18n.T("adguard.error.connect"), c.url(""), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errors.New(i18n.T("adguard.error.auth"))
+	case resp.StatusCode/100 != 2:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf(i18n.T("adguard.error.http"), method, path, resp.Status, strings.TrimSpace(string(msg)))
+	case out == nil:
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf(i18n.T("adguard.error.decode"), path, err)
+	}
+	return nil
+}
+
+// Status состояние AdGuard Home
+type Status struct {
+	Version           string   `json:"version"`
+	Running           bool     `json:"running"`
+	ProtectionEnabled bool     `json:"protection_enabled"`
+	DNSPort           int      `json:"dns_port"`
+	HTTPPort          int      `json:"http_port"`
+	DNSAddresses      []string `json:"dns_addresses"`
+}
+
+// Status возвращает состояние AdGuard Home
+func (c Client) Status() (Status, error) {
+	var st Status
+	err := c.do(http.MethodGet, "status", nil, &st)
+	return st, err
+}
+
+// SetProtection включает или выключает защиту (фильтрацию DNS)
+func (c Client) SetProtection(enabled bool) error {
+	return c.do(http.MethodPost, "protection", map[string]bool{"enabled": enabled}, nil)
+}
+
+// TopEntry строка рейтинга статистики
+type TopEntry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TopList рейтинг; API возвращает его как список объектов вида {"имя": число}
+type TopList []TopEntry
+
+// UnmarshalJSON разбирает рейтинг из формата API
+func (t *TopList) UnmarshalJSON(data []byte) error {
+	var raw []map[string]int
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	list := make(TopList, 0, len(raw))
+	for _, item := range raw {
+		for name, count := range item {
+			list = append(list, TopEntry{Name: name, Count: count})
+		}
+	}
+	*t = list
+	return nil
+}
+
+// Stats статистика запросов DNS
+type Stats struct {
+	Queries          int     `json:"num_dns_queries"`
+	Blocked          int     `json:"num_blocked_filtering"`
+	SafeBrowsing     int     `json:"num_replaced_safebrowsing"`
+	Parental         int     `json:"num_replaced_parental"`
+	AvgProcessingSec float64 `json:"avg_processing_time"`
+	TopQueried       TopList `json:"top_queried_domains"`
+	TopBlocked       TopList `json:"top_blocked_domains"`
+	TopClients       TopList `json:"top_clients"`
+}
+
+// BlockedPercent возвращает долю заблокированных запросов в процентах
+func (s Stats) BlockedPercent() float64 {
+	if s.Queries == 0 {
+		return 0
+	}
+	return float64(s.Blocked) * 100 / float64(s.Queries)
+}
+
+// Stats возвращает статистику запросов
+func (c Client) Stats() (Stats, error) {
+	var st Stats
+	err := c.do(http.MethodGet, "stats", nil, &st)
+	return st, err
+}
@@ -0,0 +1,181 @@
+package adguard
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Filter список фильтров
+type Filter struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Enabled     bool   `json:"enabled"`
+	RulesCount  int    `json:"rules_count"`
+	LastUpdated string `json:"last_updated,omitempty"`
+}
+
+// Filtering состояние фильтрации
+type Filtering struct {
+	Enabled          bool     `json:"enabled"`
+	Interval         int      `json:"interval"` // Период обновления списков, часов
+	Filters          []Filter `json:"filters"`
+	WhitelistFilters []Filter `json:"whitelist_filters"`
+	UserRules        []string `json:"user_rules"`
+}
+
+// Filtering возвращает списки фильтров и пользовательские правила
+func (c Client) Filtering() (Filtering, error) {
+	var f Filtering
+	err := c.do(http.MethodGet, "filtering/status", nil, &f)
+	return f, err
+}
+
+// AddFilter подключает список блокировки по адресу
+func (c Client) AddFilter(name, url string) error {
+	return c.do(http.MethodPost, "filtering/add_url",
+		map[string]any{"name": name, "url": url, "whitelist": false}, nil)
+}
+
+// RemoveFilter отключает список блокировки
+func (c Client) RemoveFilter(url string) error {
+	return c.do(http.MethodPost, "filtering/remove_url", map[string]any{"url": url, "whitelist": false}, nil)
+}
+
+// SetFilterEnabled включает или выключает список блокировки, не удаляя его
+func (c Client) SetFilterEnabled(f Filter, enabled bool) error {
+	return c.do(http.MethodPost, "filtering/set_url", map[string]any{
+		"url":       f.URL,
+		"whitelist": false,
+		"data":      map[string]any{"name": f.Name, "url": f.URL, "enabled": enabled},
+	}, nil)
+}
+
+// RefreshFilters загружает обновления списков блокировки
+func (c Client) RefreshFilters() error {
+	return c.do(http.MethodPost, "filtering/refresh", map[string]bool{"whitelist": false}, nil)
+}
+
+// SetUserRules заменяет пользовательские правила
+func (c Client) SetUserRules(rules []string) error {
+	return c.do(http.MethodPost, "filtering/set_rules", map[string][]string{"rules": rules}, nil)
+}
+
+// AddUserRule добавляет пользовательское правило, если его ещё нет
+func (c Client) AddUserRule(rule string) error {
+	f, err := c.Filtering()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(f.UserRules, rule) {
+		return nil
+	}
+	return c.SetUserRules(append(f.UserRules, rule))
+}
+
+// RemoveUserRule удаляет пользовательское правило
+func (c Client) RemoveUserRule(rule string) error {
+	f, err := c.Filtering()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(f.UserRules, func(r string) bool { return r == rule })
+	return c.SetUserRules(kept)
+}
+
+// KnownClient клиент, заданный в настройках AdGuard Home
+type KnownClient struct {
+	Name              string   `json:"name"`
+	IDs               []string `json:"ids"` // IP, CIDR, MAC или ClientID
+	UseGlobalSettings bool     `json:"use_global_settings"`
+	FilteringEnabled  bool     `json:"filtering_enabled"`
+}
+
+// Clients возвращает клиентов, заданных в настройках AdGuard Home
+func (c Client) Clients() ([]KnownClient, error) {
+	var resp struct {
+		Clients []KnownClient `json:"clients"`
+	}
+	err := c.do(http.MethodGet, "clients", nil, &resp)
+	return resp.Clients, err
+}
+
+// ClientRule правило блокировки или разрешения домена для отдельного клиента
+type ClientRule struct {
+	Domain string `json:"domain"`
+	Client string `json:"client"` // Имя клиента, IP или CIDR
+	Allow  bool   `json:"allow"`
+}
+
+var (
+	ruleDomainPattern = regexp.MustCompile(`^[A-Za-z0-9*]([A-Za-z0-9*._-]*[A-Za-z0-9*])?$`)
+	clientRulePattern = regexp.MustCompile(`^(@@)?\|\|([^^$]+)\^\$client=(.+)$`)
+)
+
+// Validate проверяет правило
+func (r ClientRule) Validate() error {
+	if !ruleDomainPattern.MatchString(r.Domain) || strings.TrimSpace(r.Client) == "" ||
+		strings.ContainsAny(r.Client, "|\n") {
+		return fmt.Errorf(i18n.T("adguard.error.rule"), r.Domain, r.Client)
+	}
+	return nil
+}
+
+// String возвращает правило в синтаксисе AdGuard: ||домен^$client='имя'
+func (r ClientRule) String() string {
+	rule := "||" + r.Domain + "^$client=" + quoteClient(r.Client)
+	if r.Allow {
+		rule = "@@" + rule
+	}
+	return rule
+}
+
+// ParseClientRule разбирает пользовательское правило с модификатором $client
+func ParseClientRule(rule string) (ClientRule, bool) {
+	m := clientRulePattern.FindStringSubmatch(strings.TrimSpace(rule))
+	if m == nil {
+		return ClientRule{}, false
+	}
+	return ClientRule{Domain: m[2], Client: unquoteClient(m[3]), Allow: m[1] != ""}, true
+}
+
+// ClientRules возвращает правила для клиентов из списка пользовательских правил
+func ClientRules(userRules []string) []ClientRule {
+	var rules []ClientRule
+	for _, line := range userRules {
+		if r, ok := ParseClientRule(line); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// quoteClient заключает имя клиента в кавычки, если в нём есть пробелы или спецсимволы
+func quoteClient(client string) string {
+	if !strings.ContainsAny(client, " ',|$\"") {
+		return client
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, ",", `\,`, "|", `\|`).Replace(client) + "'"
+}
+
+func unquoteClient(client string) string {
+	if len(client) < 2 || client[0] != '\'' || client[len(client)-1] != '\'' {
+		return client
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range client[1 : len(client)-1] {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
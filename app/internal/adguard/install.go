@@ -0,0 +1,101 @@
+package adguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// SetupAddress адрес и порт, на которых слушает AdGuard Home
+type SetupAddress struct {
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+}
+
+// SetupConfig параметры мастера первоначальной настройки
+type SetupConfig struct {
+	Web      SetupAddress `json:"web"`
+	DNS      SetupAddress `json:"dns"`
+	Username string       `json:"username"`
+	Password string       `json:"password"`
+}
+
+// Validate проверяет параметры первоначальной настройки
+func (s SetupConfig) Validate() error {
+	for _, addr := range []SetupAddress{s.Web, s.DNS} {
+		if net.ParseIP(addr.IP) == nil || addr.Port < 1 || addr.Port > 65535 {
+			return fmt.Errorf(i18n.T("adguard.error.address"), addr.IP, addr.Port)
+		}
+	}
+	if s.Web == s.DNS {
+		return fmt.Errorf(i18n.T("adguard.error.address"), s.Web.IP, s.Web.Port)
+	}
+	if s.Username == "" || len(s.Password) < 8 {
+		return errors.New(i18n.T("adguard.error.credentials"))
+	}
+	return nil
+}
+
+// NeedsSetup сообщает, ожидает ли AdGuard Home первоначальной настройки.
+// Обработчики мастера доступны только до её завершения.
+func (c Client) NeedsSetup() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("install/get_addresses"), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return false, fmt.Errorf(i18n.T("adguard.error.connect"), c.url(""), err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// Setup выполняет первоначальную настройку: адреса веб-интерфейса и DNS, учётная запись администратора
+func (c Client) Setup(cfg SetupConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	var check struct {
+		Web struct {
+			Status string `json:"status"`
+		} `json:"web"`
+		DNS struct {
+			Status string `json:"status"`
+		} `json:"dns"`
+	}
+	if err := c.do(http.MethodPost, "install/check_config", map[string]any{"web": cfg.Web, "dns": cfg.DNS}, &check); err != nil {
+		return err
+	}
+	if check.Web.Status != "" {
+		return fmt.Errorf(i18n.T("adguard.error.check"), cfg.Web.IP, cfg.Web.Port, check.Web.Status)
+	}
+	if check.DNS.Status != "" {
+		return fmt.Errorf(i18n.T("adguard.error.check"), cfg.DNS.IP, cfg.DNS.Port, check.DNS.Status)
+	}
+	return c.do(http.MethodPost, "install/configure", cfg, nil)
+}
+
+// WaitReady ждёт, пока API начнёт отвечать, не дольше timeout
+func (c Client) WaitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		req, err := http.NewRequest(http.MethodGet, c.url("status"), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.httpClient().Do(req)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf(i18n.T("adguard.error.connect"), c.url(""), err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,127 @@
+// Package buildinfo хранит сведения о сборке терема: версию, коммит, дату и архитектуру.
+// Значения задаются при сборке через -ldflags "-X github.com/qzeleza/terem/internal/buildinfo.Version=...",
+// а если не заданы — берутся из сведений о модуле, которые записывает go build.
+package buildinfo
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/terem/internal/version"
+)
+
+// Сведения, задаваемые при сборке
+var (
+	AppName = "terem" // Имя приложения и пакета opkg
+	Version = ""      // Версия без префикса v, например 1.2.0
+	Commit  = ""      // Короткий хеш коммита
+	Date    = ""      // Дата сборки в RFC 3339
+	Arch    = ""      // Целевая архитектура, например linux-mipsle
+)
+
+// devVersion версия сборки без заданной версии
+const devVersion = "dev"
+
+// Info сведения о сборке
+type Info struct {
+	AppName   string `json:"app"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Arch      string `json:"arch"`
+	GoVersion string `json:"go"`
+	Modified  bool   `json:"modified,omitempty"` // Собрано из рабочего каталога с незафиксированными изменениями
+}
+
+// Get возвращает сведения о текущей сборке
+func Get() Info {
+	info := Info{
+		AppName:   AppName,
+		Version:   strings.TrimPrefix(Version, "v"),
+		Commit:    Commit,
+		Date:      Date,
+		Arch:      Arch,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = strings.TrimPrefix(bi.Main.Version, "v")
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	if info.Version == "" {
+		info.Version = devVersion
+	}
+	if info.Arch == "" {
+		info.Arch = runtime.GOOS + "-" + runtime.GOARCH
+	}
+	return info
+}
+
+// String возвращает сведения одной строкой: 1.2.0 (abc1234, 2026-10-01T10:00:00Z, linux-mipsle, go1.25.0)
+func (i Info) String() string {
+	parts := []string{}
+	if i.Commit != "" {
+		commit := i.Commit
+		if i.Modified {
+			commit += "+"
+		}
+		parts = append(parts, commit)
+	}
+	if i.Date != "" {
+		parts = append(parts, i.Date)
+	}
+	parts = append(parts, i.Arch, i.GoVersion)
+	return fmt.Sprintf("%s (%s)", i.Version, strings.Join(parts, ", "))
+}
+
+// packageLine строка opkg list-installed: terem - 1.2.0-1
+var packageLine = regexp.MustCompile(`^(\S+) - (\S+)`)
+
+// PackageVersion возвращает версию установленного пакета opkg с именем name;
+// false — пакет не установлен или opkg недоступен
+func PackageVersion(runner utils.Runner, name string) (string, bool) {
+	output, err := utils.OrLocal(runner).RunCommand("opkg list-installed " + utils.ShellQuote(name) + " 2>/dev/null")
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if m := packageLine.FindStringSubmatch(strings.TrimSpace(line)); m != nil && m[1] == name {
+			return m[2], true
+		}
+	}
+	return "", false
+}
+
+// opkgRevision номер сборки пакета opkg в конце версии: 1.2.0-1
+var opkgRevision = regexp.MustCompile(`-\d+$`)
+
+// Matches сообщает, соответствует ли версия пакета opkg версии сборки;
+// номер сборки пакета не учитывается, а сборка dev или с версией, которая
+// не является номером версии (например, хешем коммита), совпадает с любой версией
+func Matches(packageVersion, build string) bool {
+	if build == devVersion || !version.Valid(build) {
+		return true
+	}
+	return version.Compare(opkgRevision.ReplaceAllString(packageVersion, ""), build) == 0
+}
@@ -0,0 +1,51 @@
+package buildinfo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+func TestGet(t *testing.T) {
+	saved := []string{Version, Commit, Date, Arch}
+	t.Cleanup(func() { Version, Commit, Date, Arch = saved[0], saved[1], saved[2], saved[3] })
+
+	Version, Commit, Date, Arch = "v1.3.0", "0123456789abcdef", "2026-10-01T10:00:00Z", "linux-mipsle"
+	info := Get()
+	if info.Version != "1.3.0" || info.Commit != "0123456789ab" || info.Arch != "linux-mipsle" || info.GoVersion == "" {
+		t.Errorf("info = %+v", info)
+	}
+	if s := info.String(); !strings.HasPrefix(s, "1.3.0 (0123456789ab") || !strings.Contains(s, "2026-10-01T10:00:00Z, linux-mipsle, go") {
+		t.Errorf("string = %q", s)
+	}
+
+	Version, Arch = "", ""
+	if info := Get(); info.Version != devVersion || !strings.Contains(info.Arch, "-") {
+		t.Errorf("default info = %+v", info)
+	}
+}
+
+func TestPackage(t *testing.T) {
+	if v, ok := PackageVersion(&testutil.Runner{Outputs: map[string]string{"opkg": "terem-extra - 2.0.0-1\nterem - 1.2.0-3\n"}}, "terem"); !ok || v != "1.2.0-3" {
+		t.Errorf("package version = %q, %v", v, ok)
+	}
+	if _, ok := PackageVersion(&testutil.Runner{Fail: "opkg"}, "terem"); ok {
+		t.Error("missing opkg reported as installed")
+	}
+	cases := []struct {
+		pkg, version string
+		want         bool
+	}{
+		{"1.2.0-3", "1.2.0", true},
+		{"1.2.0", "v1.2.0", true},
+		{"1.1.0-1", "1.2.0", false},
+		{"1.1.0-1", devVersion, true},
+		{"1.1.0-1", "3293afe", true},
+	}
+	for _, c := range cases {
+		if got := Matches(c.pkg, c.version); got != c.want {
+			t.Errorf("Matches(%q, %q) = %v", c.pkg, c.version, got)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package clients собирает список устройств локальной сети: аренды DHCP (dnsmasq, odhcpd),
+// таблицу соседей ARP/NDP и статические привязки, определяя производителя по MAC-адресу.
+package clients
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Пути по умолчанию
+var (
+	// DefaultDnsmasqLeases файлы аренд dnsmasq (OpenWrt, Entware, стандартный Linux)
+	DefaultDnsmasqLeases = []string{
+		"/tmp/dhcp.leases",
+		"/opt/var/lib/misc/dnsmasq.leases",
+		"/var/lib/misc/dnsmasq.leases",
+	}
+	// DefaultOdhcpdLeases файл состояния odhcpd (OpenWrt)
+	DefaultOdhcpdLeases = []string{"/tmp/hosts/odhcpd"}
+)
+
+const (
+	// DefaultStaticFile файл статических привязок, которым управляет терем
+	DefaultStaticFile = "/opt/etc/dnsmasq.d/terem-hosts.conf"
+	// DefaultDnsmasqConf основная конфигурация dnsmasq Entware
+	DefaultDnsmasqConf = "/opt/etc/dnsmasq.conf"
+	// DefaultReloadCommand перезапускает dnsmasq Entware или OpenWrt
+	DefaultReloadCommand = "/opt/etc/init.d/S56dnsmasq restart 2>/dev/null || /etc/init.d/dnsmasq restart"
+)
+
+// Client устройство локальной сети
+type Client struct {
+	Hostname  string    `json:"hostname,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	IPv6      []string  `json:"ipv6,omitempty"`
+	MAC       string    `json:"mac"`
+	Vendor    string    `json:"vendor,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	Expiry    time.Time `json:"leaseExpiry,omitzero"` // Окончание аренды; нулевое значение — нет аренды или бессрочная
+	Static    bool      `json:"static"`
+	Online    bool      `json:"online"`
+}
+
+// Collector собирает сведения об устройствах
+type Collector struct {
+	Runner        utils.Runner // Выполнение команд на роутере (по умолчанию — локально)
+	DnsmasqLeases []string     // Файлы аренд dnsmasq
+	OdhcpdLeases  []string     // Файлы состояния odhcpd
+	StaticFiles   []string     // Дополнительные файлы с dhcp-host=
+	StaticFile    string       // Файл статических привязок терема
+	DnsmasqConf   string       // Конфигурация dnsmasq, в которой подключается каталог StaticFile
+	ReloadCommand string       // Команда перезапуска dnsmasq после изменения привязок
+}
+
+// WithDefaults заполняет незаданные параметры значениями по умолчанию
+func (c Collector) WithDefaults() Collector {
+	if c.Runner == nil {
+		c.Runner = utils.Local{}
+	}
+	if c.DnsmasqLeases == nil {
+		c.DnsmasqLeases = DefaultDnsmasqLeases
+	}
+	if c.OdhcpdLeases == nil {
+		c.OdhcpdLeases = DefaultOdhcpdLeases
+	}
+	if c.StaticFile == "" {
+		c.StaticFile = DefaultStaticFile
+	}
+	if c.DnsmasqConf == "" {
+		c.DnsmasqConf = DefaultDnsmasqConf
+	}
+	if c.ReloadCommand == "" {
+		c.ReloadCommand = DefaultReloadCommand
+	}
+	return c
+}
+
+// Collect объединяет аренды, таблицу соседей и статические привязки в один список.
+// Отсутствующие источники пропускаются.
+func (c Collector) Collect() []Client {
+	c = c.WithDefaults()
+
+	var leases []Lease
+	for _, path := range c.DnsmasqLeases {
+		leases = append(leases, ParseDnsmasqLeases(c.read(path))...)
+	}
+	for _, path := range c.OdhcpdLeases {
+		leases = append(leases, ParseOdhcpdLeases(c.read(path))...)
+	}
+
+	var static []StaticHost
+	for _, path := range append([]string{c.StaticFile}, c.StaticFiles...) {
+		static = append(static, ParseStaticHosts(c.read(path))...)
+	}
+
+	neighbors := ParseNeighbors(c.run("ip neigh show 2>/dev/null"))
+	if len(neighbors) == 0 {
+		neighbors = ParseProcARP(c.read("/proc/net/arp"))
+	}
+
+	return Merge(leases, neighbors, static)
+}
+
+// Merge объединяет записи из всех источников по MAC-адресу
+func Merge(leases []Lease, neighbors []Neighbor, static []StaticHost) []Client {
+	byMAC := make(map[string]*Client)
+	get := func(mac string) *Client {
+		mac = NormalizeMAC(mac)
+		if client, ok := byMAC[mac]; ok {
+			return client
+		}
+		client := &Client{MAC: mac, Vendor: Vendor(mac)}
+		byMAC[mac] = client
+		return client
+	}
+
+	for _, h := range static {
+		client := get(h.MAC)
+		client.Static = true
+		client.IP = h.IP
+		if h.Hostname != "" {
+			client.Hostname = h.Hostname
+		}
+	}
+
+	for _, lease := range leases {
+		client := get(lease.MAC)
+		if isIPv6(lease.IP) {
+			client.IPv6 = appendUnique(client.IPv6, lease.IP)
+		} else if client.IP == "" || !client.Static {
+			client.IP = lease.IP
+		}
+		if client.Hostname == "" {
+			client.Hostname = lease.Hostname
+		}
+		if lease.Expiry.After(client.Expiry) {
+			client.Expiry = lease.Expiry
+		}
+	}
+
+	for _, n := range neighbors {
+		client := get(n.MAC)
+		if isIPv6(n.IP) {
+			// Адреса link-local есть у каждого устройства и не несут полезной информации
+			if ip := net.ParseIP(n.IP); ip != nil && !ip.IsLinkLocalUnicast() {
+				client.IPv6 = appendUnique(client.IPv6, n.IP)
+			}
+		} else if client.IP == "" {
+			client.IP = n.IP
+		}
+		if client.Interface == "" {
+			client.Interface = n.Interface
+		}
+		if n.Reachable() {
+			client.Online = true
+		}
+	}
+
+	result := make([]Client, 0, len(byMAC))
+	for _, client := range byMAC {
+		result = append(result, *client)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareIP(result[i].IP, result[j].IP, result[i].MAC, result[j].MAC)
+	})
+	return result
+}
+
+// Filter возвращает устройства, у которых имя, адрес, MAC или производитель содержат query
+func Filter(clients []Client, query string) []Client {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return clients
+	}
+
+	var result []Client
+	for _, client := range clients {
+		fields := append([]string{client.Hostname, client.IP, client.MAC, client.Vendor}, client.IPv6...)
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), query) {
+				result = append(result, client)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// read возвращает содержимое файла на роутере или пустую строку
+func (c Collector) read(path string) string {
+	return c.run("cat " + utils.ShellQuote(path) + " 2>/dev/null")
+}
+
+// run выполняет команду и возвращает вывод или пустую строку при ошибке
+func (c Collector) run(command string) string {
+	output, err := c.Runner.RunCommand(command)
+	if err != nil {
+		return ""
+	}
+	return output
+}
+
+// compareIP упорядочивает устройства по IPv4-адресу, устройства без адреса — в конце по MAC
+func compareIP(a, b, macA, macB string) bool {
+	ipA, ipB := net.ParseIP(a).To4(), net.ParseIP(b).To4()
+	switch {
+	case ipA == nil && ipB == nil:
+		return macA < macB
+	case ipA == nil:
+		return false
+	case ipB == nil:
+		return true
+	}
+	return string(ipA) < string(ipB)
+}
+
+func isIPv6(ip string) bool {
+	return strings.Contains(ip, ":")
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
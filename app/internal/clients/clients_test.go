@@ -0,0 +1,105 @@
+package clients
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+func TestCollectMergesSources(t *testing.T) {
+	router := &testutil.Runner{
+		Files: map[string]string{
+			"/tmp/dhcp.leases": "1760000000 b8:27:eb:11:22:33 192.168.1.20 pi *\n" +
+				"0 3A:11:22:33:44:55 192.168.1.30 phone 01:3a:11:22:33:44:55\n" +
+				"duid 00:01:00:01:2a:2b:2c:2d:aa:bb:cc:dd:ee:ff\n",
+			"/tmp/hosts/odhcpd": "# br-lan 00010001aabbccddb827eb112233 1 pi 1760003600 2 128 2001:db8::20/128\n",
+			"/static.conf":      "dhcp-host=00:11:32:aa:bb:cc,set:nas,192.168.1.5,nas,infinite\n",
+		},
+		Outputs: map[string]string{
+			"ip neigh": "192.168.1.20 dev br-lan lladdr b8:27:eb:11:22:33 REACHABLE\n" +
+				"192.168.1.5 dev br-lan lladdr 00:11:32:aa:bb:cc FAILED\n" +
+				"192.168.1.40 dev br-lan lladdr 50:ff:20:00:00:01 STALE\n" +
+				"fe80::1 dev br-lan lladdr b8:27:eb:11:22:33 router REACHABLE\n" +
+				"192.168.1.99 dev br-lan  FAILED\n",
+		},
+	}
+
+	list := Collector{Runner: router, StaticFile: "/static.conf"}.Collect()
+	if len(list) != 4 {
+		t.Fatalf("expected 4 clients, got %d: %+v", len(list), list)
+	}
+
+	nas, pi, phone, keenetic := list[0], list[1], list[2], list[3]
+	if nas.IP != "192.168.1.5" || !nas.Static || nas.Online || nas.Hostname != "nas" || nas.Vendor != "Synology" {
+		t.Errorf("unexpected static client: %+v", nas)
+	}
+	if pi.Hostname != "pi" || !pi.Online || pi.Vendor != "Raspberry Pi" || pi.Interface != "br-lan" {
+		t.Errorf("unexpected lease client: %+v", pi)
+	}
+	if len(pi.IPv6) != 1 || pi.IPv6[0] != "2001:db8::20" {
+		t.Errorf("expected DHCPv6 address from odhcpd, got %v", pi.IPv6)
+	}
+	if !pi.Expiry.Equal(time.Unix(1760003600, 0)) {
+		t.Errorf("expected latest lease expiry, got %v", pi.Expiry)
+	}
+	if phone.MAC != "3a:11:22:33:44:55" || phone.Vendor != VendorRandomized || !phone.Expiry.IsZero() || phone.Online {
+		t.Errorf("unexpected randomized client: %+v", phone)
+	}
+	if keenetic.IP != "192.168.1.40" || !keenetic.Online || keenetic.Vendor != "Keenetic" {
+		t.Errorf("unexpected neighbor-only client: %+v", keenetic)
+	}
+
+	if got := Filter(list, "RASPBERRY"); len(got) != 1 || got[0].MAC != pi.MAC {
+		t.Errorf("unexpected search result: %+v", got)
+	}
+	if got := Filter(list, "2001:db8"); len(got) != 1 {
+		t.Errorf("expected search by IPv6, got %+v", got)
+	}
+}
+
+func TestParseProcARP(t *testing.T) {
+	content := "IP address       HW type     Flags       HW address            Mask     Device\n" +
+		"192.168.1.20     0x1         0x2         b8:27:eb:11:22:33     *        br-lan\n" +
+		"192.168.1.21     0x1         0x0         00:00:00:00:00:00     *        br-lan\n"
+	neighbors := ParseProcARP(content)
+	if len(neighbors) != 1 || !neighbors[0].Reachable() || neighbors[0].Interface != "br-lan" {
+		t.Fatalf("unexpected neighbors: %+v", neighbors)
+	}
+}
+
+func TestAddStatic(t *testing.T) {
+	router := &testutil.Runner{Files: map[string]string{
+		"/dnsmasq.d/static.conf": "dhcp-host=00:11:32:aa:bb:cc,192.168.1.5,nas\n",
+	}}
+	c := Collector{Runner: router, StaticFile: "/dnsmasq.d/static.conf", DnsmasqConf: "/dnsmasq.conf", ReloadCommand: "reload-dnsmasq"}
+
+	if err := c.AddStatic(StaticHost{MAC: "00:11:32:AA:BB:CC", IP: "192.168.1.6"}); err == nil {
+		t.Fatal("expected duplicate MAC to be rejected")
+	}
+	if err := c.AddStatic(StaticHost{MAC: "b8:27:eb:11:22:33", IP: "192.168.1.5"}); err == nil {
+		t.Fatal("expected duplicate IP to be rejected")
+	}
+	if err := c.AddStatic(StaticHost{MAC: "b8:27:eb:11:22:33", IP: "192.168.1.300"}); err == nil {
+		t.Fatal("expected invalid IP to be rejected")
+	}
+	if err := c.AddStatic(StaticHost{MAC: "b8:27:eb:11:22:33", IP: "192.168.1.20", Hostname: "bad name"}); err == nil {
+		t.Fatal("expected invalid hostname to be rejected")
+	}
+
+	router.Commands = nil
+	if err := c.AddStatic(StaticHost{MAC: "B8-27-EB-11-22-33", IP: "192.168.1.20", Hostname: "pi"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	joined := strings.Join(router.Commands, "\n")
+	if !strings.Contains(joined, "'dhcp-host=b8:27:eb:11:22:33,192.168.1.20,pi' >> '/dnsmasq.d/static.conf'") {
+		t.Errorf("expected dhcp-host line to be appended, got:\n%s", joined)
+	}
+	if !strings.Contains(joined, "'conf-dir=/dnsmasq.d,*.conf' >> '/dnsmasq.conf'") {
+		t.Errorf("expected static hosts directory to be included in dnsmasq.conf, got:\n%s", joined)
+	}
+	if router.Commands[len(router.Commands)-1] != "reload-dnsmasq" {
+		t.Errorf("expected dnsmasq reload, got:\n%s", joined)
+	}
+}
@@ -0,0 +1,53 @@
+package clients
+
+import (
+	_ "embed"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed oui.txt
+var ouiData string
+
+var (
+	ouiOnce  sync.Once
+	ouiTable map[string]string
+)
+
+// VendorRandomized условное имя производителя для случайных (локально администрируемых) MAC-адресов
+const VendorRandomized = "randomized"
+
+// Vendor возвращает производителя устройства по MAC-адресу из встроенной таблицы OUI.
+// Для случайных MAC-адресов, которые используют современные телефоны, возвращает VendorRandomized.
+func Vendor(mac string) string {
+	ouiOnce.Do(loadOUI)
+
+	mac = NormalizeMAC(mac)
+	if len(mac) < 8 {
+		return ""
+	}
+	if vendor, ok := ouiTable[mac[:8]]; ok {
+		return vendor
+	}
+	// Бит 0x02 первого октета — локально администрируемый адрес
+	if first, err := strconv.ParseUint(mac[:2], 16, 8); err == nil && first&0x02 != 0 {
+		return VendorRandomized
+	}
+	return ""
+}
+
+// loadOUI разбирает встроенную таблицу OUI
+func loadOUI() {
+	ouiTable = make(map[string]string)
+	for _, line := range strings.Split(ouiData, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, vendor, ok := strings.Cut(line, " ")
+		if ok {
+			ouiTable[strings.ToLower(prefix)] = strings.TrimSpace(vendor)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package clients
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lease запись об аренде адреса DHCP
+type Lease struct {
+	MAC      string
+	IP       string
+	Hostname string
+	Expiry   time.Time // Нулевое значение — бессрочная аренда
+}
+
+// Neighbor запись таблицы соседей (ARP/NDP)
+type Neighbor struct {
+	IP        string
+	MAC       string
+	Interface string
+	State     string
+}
+
+// Reachable сообщает, что сосед присутствует в сети
+func (n Neighbor) Reachable() bool {
+	switch n.State {
+	case "FAILED", "INCOMPLETE", "NONE", "":
+		return false
+	}
+	return true
+}
+
+// ParseDnsmasqLeases разбирает файл аренд dnsmasq:
+// "<expiry> <mac> <ip> <hostname> <client-id>"
+func ParseDnsmasqLeases(content string) []Lease {
+	var leases []Lease
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		// Записи DHCPv6 начинаются со строки "duid ..." и не содержат MAC-адреса
+		if fields[0] == "duid" || !isMAC(fields[1]) {
+			continue
+		}
+		lease := Lease{MAC: NormalizeMAC(fields[1]), IP: fields[2]}
+		if fields[3] != "*" {
+			lease.Hostname = fields[3]
+		}
+		if expiry, err := strconv.ParseInt(fields[0], 10, 64); err == nil && expiry > 0 {
+			lease.Expiry = time.Unix(expiry, 0)
+		}
+		leases = append(leases, lease)
+	}
+	return leases
+}
+
+// ParseOdhcpdLeases разбирает файл состояния odhcpd (OpenWrt):
+// "# <iface> <duid|mac> <iaid> <hostname> <valid-until> <assigned> <length> <addr>/<len> ..."
+func ParseOdhcpdLeases(content string) []Lease {
+	var leases []Lease
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || fields[0] != "#" {
+			continue
+		}
+		id := fields[2]
+		if !isMAC(id) {
+			// Для DHCPv6 DUID типа LL/LLT содержит MAC-адрес в последних 6 байтах
+			id = macFromDUID(id)
+		}
+
+		lease := Lease{MAC: NormalizeMAC(id)}
+		if fields[4] != "-" {
+			lease.Hostname = fields[4]
+		}
+		if until, err := strconv.ParseInt(fields[5], 10, 64); err == nil && until > 0 {
+			lease.Expiry = time.Unix(until, 0)
+		}
+		for _, addr := range fields[8:] {
+			lease.IP = strings.SplitN(addr, "/", 2)[0]
+			if lease.IP != "" {
+				leases = append(leases, lease)
+			}
+		}
+	}
+	return leases
+}
+
+// ParseNeighbors разбирает вывод ip neigh show:
+// "192.168.1.10 dev br-lan lladdr aa:bb:cc:dd:ee:ff REACHABLE"
+func ParseNeighbors(output string) []Neighbor {
+	var neighbors []Neighbor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		n := Neighbor{IP: fields[0], State: fields[len(fields)-1]}
+		for i := 1; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "dev":
+				n.Interface = fields[i+1]
+			case "lladdr":
+				n.MAC = NormalizeMAC(fields[i+1])
+			}
+		}
+		if n.MAC != "" {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// ParseProcARP разбирает /proc/net/arp, если утилита ip недоступна
+func ParseProcARP(content string) []Neighbor {
+	var neighbors []Neighbor
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || !isMAC(fields[3]) {
+			continue
+		}
+		n := Neighbor{IP: fields[0], MAC: NormalizeMAC(fields[3]), Interface: fields[5], State: "FAILED"}
+		// Флаг 0x2 (ATF_COM) — запись разрешена
+		if flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32); err == nil && flags&0x2 != 0 {
+			n.State = "REACHABLE"
+		}
+		if n.MAC != "00:00:00:00:00:00" {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// NormalizeMAC приводит MAC-адрес к виду aa:bb:cc:dd:ee:ff
+func NormalizeMAC(mac string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(mac), "-", ":"))
+}
+
+// isMAC проверяет, что строка является MAC-адресом вида aa:bb:cc:dd:ee:ff или aa-bb-...
+func isMAC(s string) bool {
+	s = NormalizeMAC(s)
+	if len(s) != 17 {
+		return false
+	}
+	for i, c := range s {
+		if i%3 == 2 {
+			if c != ':' {
+				return false
+			}
+			continue
+		}
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// macFromDUID извлекает MAC-адрес из DUID-LLT (0001) или DUID-LL (0003) с типом оборудования Ethernet
+func macFromDUID(duid string) string {
+	duid = strings.ToLower(strings.ReplaceAll(duid, ":", ""))
+	var hex string
+	switch {
+	case strings.HasPrefix(duid, "00010001") && len(duid) == 28:
+		hex = duid[16:]
+	case strings.HasPrefix(duid, "00030001") && len(duid) == 20:
+		hex = duid[8:]
+	default:
+		return ""
+	}
+	parts := make([]string, 0, 6)
+	for i := 0; i < len(hex); i += 2 {
+		parts = append(parts, hex[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
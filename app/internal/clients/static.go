@@ -0,0 +1,117 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/dns"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// StaticHost статическая привязка адреса (dhcp-host в dnsmasq)
+type StaticHost struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+var (
+	leaseTimePattern = regexp.MustCompile(`^(\d+[smhdw]?|infinite)$`)
+	hostnamePattern  = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+)
+
+// ParseStaticHosts разбирает строки dhcp-host=... из конфигурации dnsmasq
+func ParseStaticHosts(content string) []StaticHost {
+	var hosts []StaticHost
+	for _, line := range strings.Split(content, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "dhcp-host=")
+		if !ok {
+			continue
+		}
+
+		var host StaticHost
+		for _, token := range strings.Split(value, ",") {
+			token = strings.TrimSpace(token)
+			switch {
+			case token == "" || strings.Contains(token, ":") && !isMAC(token) && net.ParseIP(token) == nil:
+				// Теги set:/tag:/id: и прочие параметры не относятся к привязке
+			case isMAC(token):
+				host.MAC = NormalizeMAC(token)
+			case net.ParseIP(strings.Trim(token, "[]")) != nil:
+				host.IP = strings.Trim(token, "[]")
+			case leaseTimePattern.MatchString(token), token == "ignore":
+			default:
+				host.Hostname = token
+			}
+		}
+		if host.MAC != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// Validate проверяет MAC-адрес, IPv4-адрес и имя устройства статической привязки
+func (h StaticHost) Validate() error {
+	if !isMAC(h.MAC) {
+		return fmt.Errorf(i18n.T("clients.error.mac"), h.MAC)
+	}
+	if ip := net.ParseIP(h.IP); ip == nil || ip.To4() == nil {
+		return fmt.Errorf(i18n.T("clients.error.ip"), h.IP)
+	}
+	if h.Hostname != "" && !hostnamePattern.MatchString(h.Hostname) {
+		return fmt.Errorf(i18n.T("clients.error.hostname"), h.Hostname)
+	}
+	return nil
+}
+
+// line возвращает строку конфигурации dnsmasq для привязки
+func (h StaticHost) line() string {
+	parts := []string{NormalizeMAC(h.MAC), h.IP}
+	if h.Hostname != "" {
+		parts = append(parts, h.Hostname)
+	}
+	return "dhcp-host=" + strings.Join(parts, ",")
+}
+
+// AddStatic добавляет статическую привязку в файл терема, подключает его каталог
+// в конфигурации dnsmasq и перезапускает dnsmasq.
+// Привязка отклоняется, если MAC-адрес или IP уже заняты другой статической записью.
+func (c Collector) AddStatic(host StaticHost) error {
+	c = c.WithDefaults()
+	host.MAC = NormalizeMAC(host.MAC)
+	if err := host.Validate(); err != nil {
+		return err
+	}
+
+	existing, _ := c.Runner.RunCommand("cat " + utils.ShellQuote(c.StaticFile) + " 2>/dev/null")
+	for _, h := range ParseStaticHosts(existing) {
+		if h.MAC == host.MAC {
+			return fmt.Errorf(i18n.T("clients.error.mac_exists"), host.MAC, h.IP)
+		}
+		if h.IP == host.IP {
+			return fmt.Errorf(i18n.T("clients.error.ip_exists"), host.IP, h.MAC)
+		}
+	}
+
+	command := fmt.Sprintf("mkdir -p %s && printf '%%s\\n' %s >> %s",
+		utils.ShellQuote(path.Dir(c.StaticFile)), utils.ShellQuote(host.line()), utils.ShellQuote(c.StaticFile))
+	if _, err := c.Runner.RunCommand(command); err != nil {
+		return fmt.Errorf(i18n.T("clients.error.write"), c.StaticFile, err)
+	}
+	if err := dns.EnsureConfDir(c.Runner, c.DnsmasqConf, path.Dir(c.StaticFile)); err != nil {
+		return err
+	}
+
+	if c.ReloadCommand != "" {
+		if _, err := c.Runner.RunCommand(c.ReloadCommand); err != nil {
+			return errors.Join(errors.New(i18n.T("clients.error.reload")), err)
+		}
+	}
+	return nil
+}
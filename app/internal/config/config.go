@@ -17,46 +17,281 @@ const (
 	defaultConfigDirectory = "terem"
 	defaultConfigFile      = "config.yaml"
 	configEnvVariable      = "TEREM_CONFIG"
+	strictEnvVariable      = "TEREM_STRICT_PATHS"
 	defaultLogFilePath     = "/tmp/terem.log"
 )
 
+// Виды предупреждений о путях.
+const (
+	WarningConfigPath     = "config_path"     // конфигурация перенесена во временный каталог
+	WarningConfigReadOnly = "config_readonly" // файл конфигурации недоступен для записи
+	WarningLogPath        = "log_path"        // лог перенесён во временный каталог
+)
+
+// Warning описывает вынужденную замену пути конфигурации или лога.
+type Warning struct {
+	Kind      string `json:"kind"`      // Вид предупреждения (WarningConfigPath, WarningLogPath, ...)
+	Requested string `json:"requested"` // Путь, который не удалось использовать
+	Actual    string `json:"actual"`    // Путь, который используется вместо него
+	Reason    string `json:"reason"`    // Причина замены
+}
+
+// String возвращает локализованное описание предупреждения.
+func (w Warning) String() string {
+	return i18n.T("config.warn."+w.Kind, w.Requested, w.Actual, w.Reason)
+}
+
+// Режимы записи лога.
+const (
+	LogModeFile     = "file"     // синхронная запись каждой строки в файл
+	LogModeBuffered = "buffered" // пакетная запись в файл из буфера в памяти
+	LogModeMemory   = "memory"   // запись в файл отключена, лог хранится только в памяти
+)
+
 // Config описывает настройки приложения. Та же структура сохраняется в YAML.
 type Config struct {
 	DebugMode bool   `yaml:"debugMode" json:"debugMode"` // Режим отладки
 	LogFile   string `yaml:"logFile" json:"logFile"`     // Путь до файла логов
 	Language  string `yaml:"language" json:"language"`   // Код языка интерфейса
+	LogMode   string `yaml:"logMode" json:"logMode"`     // Режим записи лога: file, buffered, memory
+
+	// AdGuard параметры подключения к API AdGuard Home
+	AdGuard AdGuardConfig `yaml:"adguard,omitempty" json:"adguard,omitzero"`
+	// IPSets списки ipset под управлением терема
+	IPSets []IPSetConfig `yaml:"ipsets,omitempty" json:"ipsets,omitempty"`
+	// VPN туннели VPN под управлением терема
+	VPN []VPNConfig `yaml:"vpn,omitempty" json:"vpn,omitempty"`
+	// Routing политика маршрутизации под управлением терема
+	Routing RoutingConfig `yaml:"routing,omitempty" json:"routing,omitzero"`
+	// Storage параметры контроля накопителей
+	Storage StorageConfig `yaml:"storage,omitempty" json:"storage,omitzero"`
+	// Update параметры самообновления
+	Update UpdateConfig `yaml:"update,omitempty" json:"update,omitzero"`
+
+	// Warnings содержит предупреждения о вынужденной замене путей (в файл не сохраняются)
+	Warnings []Warning `yaml:"-" json:"-"`
+	// strictPaths запрещает замену недоступных путей временным каталогом
+	strictPaths bool
+}
+
+// AdGuardConfig описывает подключение к API AdGuard Home.
+type AdGuardConfig struct {
+	URL      string `yaml:"url,omitempty" json:"url,omitempty"`   // Адрес веб-интерфейса (например, http://127.0.0.1:3000)
+	User     string `yaml:"user,omitempty" json:"user,omitempty"` // Имя пользователя
+	Password string `yaml:"password,omitempty" json:"-"`          // Пароль (в JSON не выводится)
+}
+
+// StorageConfig описывает контроль свободного места на накопителях.
+type StorageConfig struct {
+	FreeThreshold int `yaml:"freeThreshold,omitempty" json:"freeThreshold,omitempty"` // Порог свободного места, %; 0 — значение по умолчанию
+}
+
+// UpdateConfig описывает источник обновлений терема. Ключ проверки подписей в конфигурации
+// не задаётся: доверенным считается только ключ, встроенный при сборке.
+type UpdateConfig struct {
+	Feed string `yaml:"feed,omitempty" json:"feed,omitempty"` // Адрес ленты выпусков; пусто — лента по умолчанию
+}
+
+// IPSetConfig описывает список ipset под управлением терема.
+type IPSetConfig struct {
+	Name    string   `yaml:"name" json:"name"`                           // Имя списка в ipset
+	Family  string   `yaml:"family" json:"family"`                       // Семейство адресов: inet или inet6
+	Sources []string `yaml:"sources,omitempty" json:"sources,omitempty"` // Файлы и адреса HTTP(S) для обновления
+	Refresh string   `yaml:"refresh,omitempty" json:"refresh,omitempty"` // Период обновления: hourly, daily, weekly
+}
+
+// IPSet возвращает описание списка по имени.
+func (c *Config) IPSet(name string) (IPSetConfig, bool) {
+	for _, s := range c.IPSets {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return IPSetConfig{}, false
+}
+
+// SetIPSet добавляет описание списка или заменяет существующее с тем же именем.
+func (c *Config) SetIPSet(set IPSetConfig) {
+	for i := range c.IPSets {
+		if c.IPSets[i].Name == set.Name {
+			c.IPSets[i] = set
+			return
+		}
+	}
+	c.IPSets = append(c.IPSets, set)
+}
+
+// RemoveIPSet удаляет описание списка.
+func (c *Config) RemoveIPSet(name string) {
+	for i := range c.IPSets {
+		if c.IPSets[i].Name == name {
+			c.IPSets = append(c.IPSets[:i], c.IPSets[i+1:]...)
+			return
+		}
+	}
+}
+
+// VPNConfig описывает туннель VPN под управлением терема.
+type VPNConfig struct {
+	Name      string   `yaml:"name" json:"name"`                               // Имя туннеля и его сетевого интерфейса
+	Kind      string   `yaml:"kind" json:"kind"`                               // Вид туннеля: wireguard или openvpn
+	Table     int      `yaml:"table,omitempty" json:"table,omitempty"`         // Таблица маршрутизации и метка пакетов
+	Autostart bool     `yaml:"autostart,omitempty" json:"autostart,omitempty"` // Поднимать туннель при загрузке роутера
+	Clients   []string `yaml:"clients,omitempty" json:"clients,omitempty"`     // MAC-адреса клиентов, выходящих через туннель
+	IPSets    []string `yaml:"ipsets,omitempty" json:"ipsets,omitempty"`       // Списки ipset адресов, доступных через туннель
+}
+
+// VPNTunnel возвращает описание туннеля по имени.
+func (c *Config) VPNTunnel(name string) (VPNConfig, bool) {
+	for _, t := range c.VPN {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return VPNConfig{}, false
+}
+
+// SetVPNTunnel добавляет описание туннеля или заменяет существующее с тем же именем.
+func (c *Config) SetVPNTunnel(tunnel VPNConfig) {
+	for i := range c.VPN {
+		if c.VPN[i].Name == tunnel.Name {
+			c.VPN[i] = tunnel
+			return
+		}
+	}
+	c.VPN = append(c.VPN, tunnel)
+}
+
+// RemoveVPNTunnel удаляет описание туннеля.
+func (c *Config) RemoveVPNTunnel(name string) {
+	for i := range c.VPN {
+		if c.VPN[i].Name == name {
+			c.VPN = append(c.VPN[:i], c.VPN[i+1:]...)
+			return
+		}
+	}
+}
+
+// RoutingConfig описывает политику маршрутизации: таблицы и правила выбора таблицы.
+// Правила проверяются в порядке списка.
+type RoutingConfig struct {
+	Tables []RouteTableConfig `yaml:"tables,omitempty" json:"tables,omitempty"`
+	Rules  []RouteRuleConfig  `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// RouteTableConfig описывает таблицу маршрутизации с маршрутом по умолчанию.
+type RouteTableConfig struct {
+	ID   int    `yaml:"id" json:"id"`                         // Номер таблицы
+	Name string `yaml:"name,omitempty" json:"name,omitempty"` // Название для интерфейса
+	Dev  string `yaml:"dev,omitempty" json:"dev,omitempty"`   // Интерфейс выхода
+	Via  string `yaml:"via,omitempty" json:"via,omitempty"`   // Шлюз
+}
+
+// RouteRuleConfig описывает правило выбора таблицы: ровно один признак — адрес источника,
+// MAC-адрес клиента или список ipset адресов назначения.
+type RouteRuleConfig struct {
+	Table  int    `yaml:"table" json:"table"`
+	Source string `yaml:"source,omitempty" json:"source,omitempty"` // Адрес или подсеть источника
+	MAC    string `yaml:"mac,omitempty" json:"mac,omitempty"`
+	IPSet  string `yaml:"ipset,omitempty" json:"ipset,omitempty"`
+	Note   string `yaml:"note,omitempty" json:"note,omitempty"`
+}
+
+// RouteTable возвращает описание таблицы маршрутизации по номеру.
+func (c *Config) RouteTable(id int) (RouteTableConfig, bool) {
+	for _, t := range c.Routing.Tables {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return RouteTableConfig{}, false
+}
+
+// SetRouteTable добавляет описание таблицы или заменяет существующее с тем же номером.
+func (c *Config) SetRouteTable(table RouteTableConfig) {
+	for i := range c.Routing.Tables {
+		if c.Routing.Tables[i].ID == table.ID {
+			c.Routing.Tables[i] = table
+			return
+		}
+	}
+	c.Routing.Tables = append(c.Routing.Tables, table)
+}
+
+// RemoveRouteTable удаляет описание таблицы вместе с правилами, которые на неё ссылаются.
+func (c *Config) RemoveRouteTable(id int) {
+	for i := range c.Routing.Tables {
+		if c.Routing.Tables[i].ID == id {
+			c.Routing.Tables = append(c.Routing.Tables[:i], c.Routing.Tables[i+1:]...)
+			break
+		}
+	}
+	rules := c.Routing.Rules[:0]
+	for _, r := range c.Routing.Rules {
+		if r.Table != id {
+			rules = append(rules, r)
+		}
+	}
+	c.Routing.Rules = rules
 }
 
 // Load загружает конфигурацию и гарантирует наличие файлов/директорий.
-// Если путь недоступен, используетсяFallback в /tmp.
+// Если путь недоступен, используется fallback в /tmp, а замена фиксируется в Config.Warnings.
+// При TEREM_STRICT_PATHS=1 вместо замены возвращается ошибка (см. LoadStrict).
 // explicitPath — явный путь до файла конфигурации.
 func Load(explicitPath string) (*Config, string, error) {
+	return load(explicitPath, utils.GetEnvBool(strictEnvVariable, false))
+}
+
+// LoadStrict загружает конфигурацию и возвращает ошибку, если путь конфигурации
+// или лога недоступен, вместо переноса во временный каталог.
+// explicitPath — явный путь до файла конфигурации.
+func LoadStrict(explicitPath string) (*Config, string, error) {
+	return load(explicitPath, true)
+}
+
+// load загружает конфигурацию.
+// explicitPath — явный путь до файла конфигурации.
+// strict — запрет замены недоступных путей.
+func load(explicitPath string, strict bool) (*Config, string, error) {
 	cfg := defaultConfig()
+	cfg.strictPaths = strict
 
 	path, err := resolveConfigPath(explicitPath)
 	if err != nil {
 		return nil, "", fmt.Errorf("%s: %w", i18n.T("config.error.resolve_path"), err)
 	}
 
-	path, err = ensureConfigFile(path, cfg)
+	path, warning, err := ensureConfigFile(path, cfg, strict)
 	if err != nil {
 		return nil, "", fmt.Errorf("%s: %w", i18n.T("config.error.create_file"), err)
 	}
+	cfg.addWarning(warning)
 
 	changedByMerge, err := mergeConfigFromFile(path, cfg)
 	if err != nil {
 		return nil, "", fmt.Errorf("%s: %w", i18n.T("config.error.read_file"), err)
 	}
 
-	previousLogPath := cfg.LogFile
-	cfg.LogFile = ensureLogFilePath(cfg.LogFile)
+	requestedLogPath := cfg.LogFile
+	cfg.LogFile, warning, err = ensureLogFilePath(cfg.LogFile, strict)
+	if err != nil {
+		return nil, "", err
+	}
+	cfg.addWarning(warning)
 	if cfg.Language == "" {
 		cfg.Language = "ru"
 	}
-	logPathAdjusted := cfg.LogFile != previousLogPath
-
-	if changedByMerge || logPathAdjusted {
-		_ = writeConfigFile(path, cfg)
+	previousLogMode := cfg.LogMode
+	cfg.LogMode = normalizeLogMode(cfg.LogMode)
+
+	if changedByMerge || cfg.LogMode != previousLogMode {
+		// Временный путь лога не сохраняем, чтобы после восстановления каталога вернуться к исходному
+		persisted := *cfg
+		if warning != nil {
+			persisted.LogFile = requestedLogPath
+		}
+		_ = writeConfigFile(path, &persisted)
 	}
 
 	return cfg, path, nil
@@ -82,12 +317,42 @@ func (c *Config) SetDebugMode(v bool) {
 }
 
 // SetLogFile обновляет путь до файла логов.
+// Если путь недоступен, используется временный каталог, а замена фиксируется в Warnings.
 // path — путь до файла логов.
 func (c *Config) SetLogFile(path string) {
 	if c == nil {
 		return
 	}
-	c.LogFile = ensureLogFilePath(path)
+	logFile, warning, err := ensureLogFilePath(path, c.strictPaths)
+	if err != nil {
+		c.addWarning(&Warning{Kind: WarningLogPath, Requested: path, Actual: c.LogFile, Reason: err.Error()})
+		return
+	}
+	c.LogFile = logFile
+	c.clearWarning(WarningLogPath)
+	c.addWarning(warning)
+}
+
+// addWarning добавляет предупреждение, заменяя предыдущее того же вида.
+// w — предупреждение (nil игнорируется).
+func (c *Config) addWarning(w *Warning) {
+	if c == nil || w == nil {
+		return
+	}
+	c.clearWarning(w.Kind)
+	c.Warnings = append(c.Warnings, *w)
+}
+
+// clearWarning удаляет предупреждения указанного вида.
+// kind — вид предупреждения.
+func (c *Config) clearWarning(kind string) {
+	kept := c.Warnings[:0]
+	for _, w := range c.Warnings {
+		if w.Kind != kind {
+			kept = append(kept, w)
+		}
+	}
+	c.Warnings = kept
 }
 
 // SetLanguage обновляет язык интерфейса.
@@ -99,12 +364,33 @@ func (c *Config) SetLanguage(lang string) {
 	c.Language = lang
 }
 
+// SetLogMode обновляет режим записи лога.
+// mode — один из режимов LogModeFile, LogModeBuffered, LogModeMemory.
+func (c *Config) SetLogMode(mode string) {
+	if c == nil {
+		return
+	}
+	c.LogMode = normalizeLogMode(mode)
+}
+
+// normalizeLogMode возвращает корректный режим записи лога, по умолчанию — LogModeFile.
+// mode — режим записи лога.
+func normalizeLogMode(mode string) string {
+	switch mode {
+	case LogModeFile, LogModeBuffered, LogModeMemory:
+		return mode
+	default:
+		return LogModeFile
+	}
+}
+
 // defaultConfig возвращает конфигурацию по умолчанию.
 func defaultConfig() *Config {
 	return &Config{
 		DebugMode: utils.GetEnvBool("DEBUG", true),
 		LogFile:   utils.GetEnv("TEREM_LOG_FILE", defaultLogFilePath),
 		Language:  utils.GetEnv("TEREM_LANG", "ru"),
+		LogMode:   normalizeLogMode(utils.GetEnv("TEREM_LOG_MODE", LogModeFile)),
 	}
 }
 
@@ -126,34 +412,61 @@ func resolveConfigPath(explicit string) (string, error) {
 }
 
 // ensureConfigFile создает конфигурационный файл, если он отсутствует.
+// Если каталог или файл недоступны, использует файл во временном каталоге и возвращает предупреждение,
+// а в строгом режиме — ошибку.
 // path — путь до файла конфигурации.
 // cfg — конфигурация.
-func ensureConfigFile(path string, cfg *Config) (string, error) {
-	candidate := path
-
-	if err := ensureDirectory(filepath.Dir(candidate)); err != nil {
-		candidate = fallbackConfigPath(candidate)
+// strict — запрет замены пути.
+func ensureConfigFile(path string, cfg *Config, strict bool) (string, *Warning, error) {
+	fallback := func(reason error) (string, *Warning, error) {
+		if strict {
+			return "", nil, fmt.Errorf(i18n.T("config.error.strict_fallback"), path, reason)
+		}
+		candidate := fallbackConfigPath(path)
 		if err := ensureDirectory(filepath.Dir(candidate)); err != nil {
-			return "", err
+			return "", nil, err
 		}
+		if _, err := os.Stat(candidate); err != nil {
+			if err := writeConfigFile(candidate, cfg); err != nil {
+				return "", nil, err
+			}
+		}
+		return candidate, &Warning{Kind: WarningConfigPath, Requested: path, Actual: candidate, Reason: reason.Error()}, nil
+	}
+
+	if err := ensureDirectory(filepath.Dir(path)); err != nil {
+		return fallback(err)
 	}
 
-	if _, err := os.Stat(candidate); err != nil {
+	if _, err := os.Stat(path); err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
-			candidate = fallbackConfigPath(candidate)
-			if err := ensureDirectory(filepath.Dir(candidate)); err != nil {
-				return "", err
-			}
+			return fallback(err)
 		}
-		if err := writeConfigFile(candidate, cfg); err != nil {
-			candidate = fallbackConfigPath(candidate)
-			if err := writeConfigFile(candidate, cfg); err != nil {
-				return "", err
-			}
+		if err := writeConfigFile(path, cfg); err != nil {
+			return fallback(err)
+		}
+		return path, nil, nil
+	}
+
+	// Файл существует, но может находиться на разделе только для чтения
+	if err := checkWritable(path); err != nil {
+		if strict {
+			return "", nil, fmt.Errorf(i18n.T("config.error.strict_fallback"), path, err)
 		}
+		return path, &Warning{Kind: WarningConfigReadOnly, Requested: path, Actual: path, Reason: err.Error()}, nil
 	}
 
-	return candidate, nil
+	return path, nil, nil
+}
+
+// checkWritable проверяет, что существующий файл можно открыть на запись.
+// path — путь до файла.
+func checkWritable(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 // ensureDirectory создает директорию, если она отсутствует.
@@ -189,6 +502,7 @@ func mergeConfigFromFile(path string, cfg *Config) (bool, error) {
 	originalDebug := cfg.DebugMode
 	originalLog := cfg.LogFile
 	originalLang := cfg.Language
+	originalMode := cfg.LogMode
 
 	cfg.DebugMode = fileCfg.DebugMode
 	if fileCfg.LogFile != "" {
@@ -197,46 +511,77 @@ func mergeConfigFromFile(path string, cfg *Config) (bool, error) {
 	if fileCfg.Language != "" {
 		cfg.Language = fileCfg.Language
 	}
-
-	changed := originalDebug != cfg.DebugMode || originalLog != cfg.LogFile || originalLang != cfg.Language
+	if fileCfg.LogMode != "" {
+		cfg.LogMode = fileCfg.LogMode
+	}
+	cfg.AdGuard = fileCfg.AdGuard
+	cfg.IPSets = fileCfg.IPSets
+	cfg.VPN = fileCfg.VPN
+	cfg.Routing = fileCfg.Routing
+	cfg.Storage = fileCfg.Storage
+	cfg.Update = fileCfg.Update
+
+	changed := originalDebug != cfg.DebugMode || originalLog != cfg.LogFile || originalLang != cfg.Language ||
+		originalMode != cfg.LogMode
 	return changed, nil
 }
 
-// ensureLogFilePath создает директорию для файла логов, если она отсутствует.
+// ensureLogFilePath создает директорию и файл логов, если они отсутствуют.
+// Если путь недоступен, использует файл во временном каталоге и возвращает предупреждение,
+// а в строгом режиме — ошибку.
 // path — путь до файла логов.
-func ensureLogFilePath(path string) string {
+// strict — запрет замены пути.
+func ensureLogFilePath(path string, strict bool) (string, *Warning, error) {
 	candidate := path
 	if candidate == "" {
 		candidate = defaultLogFilePath
 	}
 
+	fallback := func(reason error) (string, *Warning, error) {
+		if strict {
+			return "", nil, fmt.Errorf(i18n.T("config.error.strict_fallback"), candidate, reason)
+		}
+		fallback := filepath.Join(os.TempDir(), filepath.Base(candidate))
+		_ = ensureDirectory(filepath.Dir(fallback))
+		if err := touchFile(fallback); err != nil {
+			// Ни исходный, ни временный путь недоступны: оставляем исходный, но сообщаем об этом
+			return candidate, &Warning{Kind: WarningLogPath, Requested: candidate, Actual: candidate,
+				Reason: errors.Join(reason, err).Error()}, nil
+		}
+		return fallback, &Warning{Kind: WarningLogPath, Requested: candidate, Actual: fallback, Reason: reason.Error()}, nil
+	}
+
 	dir := filepath.Dir(candidate)
 	if dir == "" || dir == "." {
-		candidate = filepath.Join(os.TempDir(), filepath.Base(candidate))
-		dir = filepath.Dir(candidate)
+		return fallback(errors.New(i18n.T("config.error.relative_path")))
 	}
 
 	if err := ensureDirectory(dir); err != nil {
-		candidate = filepath.Join(os.TempDir(), filepath.Base(candidate))
-		_ = ensureDirectory(filepath.Dir(candidate))
+		return fallback(err)
 	}
 
-	if f, err := os.OpenFile(candidate, os.O_CREATE|os.O_APPEND, 0o644); err == nil {
-		_ = f.Close()
-		return candidate
+	if err := touchFile(candidate); err != nil {
+		return fallback(err)
 	}
 
-	fallback := filepath.Join(os.TempDir(), filepath.Base(candidate))
-	_ = ensureDirectory(filepath.Dir(fallback))
-	if f, err := os.OpenFile(fallback, os.O_CREATE|os.O_APPEND, 0o644); err == nil {
-		_ = f.Close()
-		return fallback
-	}
+	return candidate, nil, nil
+}
 
-	return candidate
+// touchFile создает файл при необходимости и проверяет, что он доступен для записи.
+// path — путь до файла.
+func touchFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
 
-// writeConfigFile записывает конфигурацию в файл.
+// configFileMode права на файл конфигурации: в нём хранится пароль администратора AdGuard Home
+const configFileMode = 0o600
+
+// writeConfigFile записывает конфигурацию в файл, доступный только владельцу.
+// Права существующего файла меняются до записи, чтобы пароль не оказался в файле, доступном всем.
 // path — путь до файла конфигурации.
 // cfg — конфигурация.
 func writeConfigFile(path string, cfg *Config) error {
@@ -247,7 +592,10 @@ func writeConfigFile(path string, cfg *Config) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0o644)
+	if err := os.Chmod(path, configFileMode); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(path, data, configFileMode)
 }
 
 // MarshalJSON сериализует конфигурацию в JSON.
@@ -266,11 +614,12 @@ func (c *Config) Save(path string) error {
 		return errors.New(i18n.T("config.error.path_missing"))
 	}
 
-	path, err := ensureConfigFile(path, c)
+	path, warning, err := ensureConfigFile(path, c, c.strictPaths)
 	if err != nil {
 		return err
 	}
+	c.addWarning(warning)
 
-	c.LogFile = ensureLogFilePath(c.LogFile)
+	c.SetLogFile(c.LogFile)
 	return writeConfigFile(path, c)
 }
@@ -0,0 +1,371 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// blockedDir возвращает путь каталога, который невозможно создать (родитель — обычный файл)
+func blockedDir(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(file, "terem")
+}
+
+// isolateTemp перенаправляет os.TempDir во временный каталог теста
+func isolateTemp(t *testing.T) string {
+	t.Helper()
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv(strictEnvVariable, "")
+	return tmp
+}
+
+func TestLoadWithoutFallback(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEREM_LOG_FILE", filepath.Join(dir, "terem.log"))
+
+	cfg, path, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if path != confPath {
+		t.Fatalf("expected %s, got %s", confPath, path)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v", cfg.Warnings)
+	}
+}
+
+func TestConfigFallbackWhenDirectoryUnavailable(t *testing.T) {
+	tmp := isolateTemp(t)
+	requested := filepath.Join(blockedDir(t), "config.yaml")
+
+	path, warning, err := ensureConfigFile(requested, defaultConfig(), false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if path != filepath.Join(tmp, "config.yaml") {
+		t.Fatalf("expected fallback into temp dir, got %s", path)
+	}
+	if warning == nil || warning.Kind != WarningConfigPath || warning.Requested != requested || warning.Actual != path {
+		t.Fatalf("unexpected warning: %+v", warning)
+	}
+}
+
+func TestConfigFallbackWhenStatFails(t *testing.T) {
+	tmp := isolateTemp(t)
+	requested := filepath.Join(t.TempDir(), "config.yaml")
+	// Ссылка на саму себя: Stat возвращает ELOOP, а не ErrNotExist
+	if err := os.Symlink(requested, requested); err != nil {
+		t.Fatal(err)
+	}
+
+	path, warning, err := ensureConfigFile(requested, defaultConfig(), false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if filepath.Dir(path) != tmp || warning == nil || warning.Kind != WarningConfigPath {
+		t.Fatalf("expected fallback with warning, got %s %+v", path, warning)
+	}
+}
+
+func TestConfigFallbackWhenWriteFails(t *testing.T) {
+	tmp := isolateTemp(t)
+	requested := filepath.Join(t.TempDir(), "config.yaml")
+	// Висячая ссылка: Stat сообщает об отсутствии файла, но создать его по ссылке нельзя
+	if err := os.Symlink(filepath.Join(blockedDir(t), "config.yaml"), requested); err != nil {
+		t.Fatal(err)
+	}
+
+	path, warning, err := ensureConfigFile(requested, defaultConfig(), false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if path != filepath.Join(tmp, "config.yaml") || warning == nil || warning.Kind != WarningConfigPath {
+		t.Fatalf("expected fallback with warning, got %s %+v", path, warning)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected fallback config to be written: %v", err)
+	}
+}
+
+func TestConfigStrictModeFails(t *testing.T) {
+	isolateTemp(t)
+	requested := filepath.Join(blockedDir(t), "config.yaml")
+
+	if _, _, err := ensureConfigFile(requested, defaultConfig(), true); err == nil {
+		t.Fatal("expected error in strict mode")
+	}
+	if _, _, err := LoadStrict(requested); err == nil {
+		t.Fatal("expected LoadStrict to fail")
+	}
+}
+
+func TestLogFallbackRelativePath(t *testing.T) {
+	tmp := isolateTemp(t)
+
+	path, warning, err := ensureLogFilePath("terem.log", false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if path != filepath.Join(tmp, "terem.log") || warning == nil || warning.Kind != WarningLogPath {
+		t.Fatalf("expected fallback with warning, got %s %+v", path, warning)
+	}
+}
+
+func TestLogFallbackWhenDirectoryUnavailable(t *testing.T) {
+	tmp := isolateTemp(t)
+	requested := filepath.Join(blockedDir(t), "terem.log")
+
+	path, warning, err := ensureLogFilePath(requested, false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if path != filepath.Join(tmp, "terem.log") || warning == nil || warning.Requested != requested {
+		t.Fatalf("expected fallback with warning, got %s %+v", path, warning)
+	}
+}
+
+func TestLogFallbackWhenFileNotWritable(t *testing.T) {
+	tmp := isolateTemp(t)
+	// Каталог вместо файла: открыть его на запись нельзя
+	requested := filepath.Join(t.TempDir(), "terem.log")
+	if err := os.Mkdir(requested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	path, warning, err := ensureLogFilePath(requested, false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if path != filepath.Join(tmp, "terem.log") || warning == nil {
+		t.Fatalf("expected fallback with warning, got %s %+v", path, warning)
+	}
+}
+
+func TestLogFallbackUnavailableKeepsRequestedPath(t *testing.T) {
+	tmp := isolateTemp(t)
+	requested := filepath.Join(t.TempDir(), "terem.log")
+	for _, dir := range []string{requested, filepath.Join(tmp, "terem.log")} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, warning, err := ensureLogFilePath(requested, false)
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if path != requested || warning == nil || warning.Actual != requested {
+		t.Fatalf("expected requested path with warning, got %s %+v", path, warning)
+	}
+}
+
+func TestLogStrictModeFails(t *testing.T) {
+	isolateTemp(t)
+	if _, _, err := ensureLogFilePath(filepath.Join(blockedDir(t), "terem.log"), true); err == nil {
+		t.Fatal("expected error in strict mode")
+	}
+}
+
+func TestLoadDoesNotPersistLogFallback(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	requestedLog := filepath.Join(blockedDir(t), "terem.log")
+	if err := os.WriteFile(confPath, []byte("logFile: "+requestedLog+"\nlanguage: en\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.Warnings) != 1 || cfg.Warnings[0].Kind != WarningLogPath {
+		t.Fatalf("expected log path warning, got %+v", cfg.Warnings)
+	}
+
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), requestedLog) {
+		t.Fatalf("expected requested log path to stay in config, got:\n%s", data)
+	}
+}
+
+func TestAdGuardSectionRoundTrip(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEREM_LOG_FILE", filepath.Join(dir, "terem.log"))
+	// Файл, созданный раньше с правами 0644, после сохранения пароля доступен только владельцу
+	if err := os.WriteFile(confPath, []byte("language: en\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.AdGuard = AdGuardConfig{URL: "http://127.0.0.1:3000", User: "admin", Password: "password1"}
+	if err := cfg.Save(confPath); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	info, err := os.Stat(confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected config mode 0600, got %v", info.Mode().Perm())
+	}
+
+	loaded, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if loaded.AdGuard != cfg.AdGuard {
+		t.Fatalf("expected %+v, got %+v", cfg.AdGuard, loaded.AdGuard)
+	}
+	data, err := loaded.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "password1") {
+		t.Fatalf("password leaked to JSON: %s", data)
+	}
+}
+
+func TestIPSetSectionRoundTrip(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEREM_LOG_FILE", filepath.Join(dir, "terem.log"))
+
+	cfg, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.SetIPSet(IPSetConfig{Name: "vpn", Family: "inet", Sources: []string{"https://example.com/list.txt"}, Refresh: "daily"})
+	cfg.SetIPSet(IPSetConfig{Name: "block", Family: "inet"})
+	cfg.SetIPSet(IPSetConfig{Name: "vpn", Family: "inet", Refresh: "hourly"})
+	if err := cfg.Save(confPath); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.IPSets, cfg.IPSets) || len(loaded.IPSets) != 2 {
+		t.Fatalf("expected %+v, got %+v", cfg.IPSets, loaded.IPSets)
+	}
+	if set, ok := loaded.IPSet("vpn"); !ok || set.Refresh != "hourly" {
+		t.Errorf("IPSet(vpn) = %+v, %v", set, ok)
+	}
+	loaded.RemoveIPSet("vpn")
+	if _, ok := loaded.IPSet("vpn"); ok || len(loaded.IPSets) != 1 {
+		t.Errorf("RemoveIPSet left %+v", loaded.IPSets)
+	}
+}
+
+func TestVPNSectionRoundTrip(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEREM_LOG_FILE", filepath.Join(dir, "terem.log"))
+
+	cfg, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.SetVPNTunnel(VPNConfig{Name: "wg0", Kind: "wireguard", Table: 1001, Autostart: true,
+		Clients: []string{"aa:bb:cc:dd:ee:ff"}, IPSets: []string{"vpn"}})
+	cfg.SetVPNTunnel(VPNConfig{Name: "office", Kind: "openvpn"})
+	if err := cfg.Save(confPath); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.VPN, cfg.VPN) {
+		t.Fatalf("expected %+v, got %+v", cfg.VPN, loaded.VPN)
+	}
+	loaded.RemoveVPNTunnel("wg0")
+	if _, ok := loaded.VPNTunnel("wg0"); ok || len(loaded.VPN) != 1 {
+		t.Errorf("RemoveVPNTunnel left %+v", loaded.VPN)
+	}
+}
+
+func TestRoutingSectionRoundTrip(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEREM_LOG_FILE", filepath.Join(dir, "terem.log"))
+
+	cfg, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.SetRouteTable(RouteTableConfig{ID: 100, Name: "isp2", Dev: "eth3", Via: "10.0.0.1"})
+	cfg.SetRouteTable(RouteTableConfig{ID: 101, Dev: "ppp0"})
+	cfg.Routing.Rules = []RouteRuleConfig{
+		{Table: 100, Source: "192.168.1.50"},
+		{Table: 101, MAC: "aa:bb:cc:dd:ee:ff", Note: "tv"},
+		{Table: 100, IPSet: "isp2"},
+	}
+	if err := cfg.Save(confPath); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Routing, cfg.Routing) {
+		t.Fatalf("expected %+v, got %+v", cfg.Routing, loaded.Routing)
+	}
+	loaded.RemoveRouteTable(100)
+	if _, ok := loaded.RouteTable(100); ok || len(loaded.Routing.Rules) != 1 || loaded.Routing.Rules[0].Table != 101 {
+		t.Errorf("RemoveRouteTable left %+v", loaded.Routing)
+	}
+}
+
+func TestSettingsSectionsRoundTrip(t *testing.T) {
+	isolateTemp(t)
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	t.Setenv("TEREM_LOG_FILE", filepath.Join(dir, "terem.log"))
+
+	cfg, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.Storage.FreeThreshold = 15
+	cfg.Update.Feed = "file:///opt/tmp/feed.json"
+	if err := cfg.Save(confPath); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, _, err := Load(confPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if loaded.Storage != cfg.Storage {
+		t.Errorf("expected storage %+v, got %+v", cfg.Storage, loaded.Storage)
+	}
+	if loaded.Update != cfg.Update {
+		t.Errorf("expected update %+v, got %+v", cfg.Update, loaded.Update)
+	}
+}
@@ -0,0 +1,151 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+func TestParse(t *testing.T) {
+	// Понедельник, 2026-03-02 10:07
+	now := time.Date(2026, 3, 2, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		expr string
+		want []string
+	}{
+		{"*/15 * * * *", []string{"2026-03-02 10:15", "2026-03-02 10:30", "2026-03-02 10:45"}},
+		{"30 4 * * *", []string{"2026-03-03 04:30", "2026-03-04 04:30", "2026-03-05 04:30"}},
+		{"0 3 * * sun", []string{"2026-03-08 03:00", "2026-03-15 03:00", "2026-03-22 03:00"}},
+		{"0 3 * * 7", []string{"2026-03-08 03:00", "2026-03-15 03:00", "2026-03-22 03:00"}},
+		{"0 0 1 jan-mar/2 *", []string{"2027-01-01 00:00", "2027-03-01 00:00", "2028-01-01 00:00"}},
+		{"0 12 13 * fri", []string{"2026-03-06 12:00", "2026-03-13 12:00", "2026-03-20 12:00"}},
+		{"5,10 9-10 * * 1-5", []string{"2026-03-02 10:10", "2026-03-03 09:05", "2026-03-03 09:10"}},
+		{"@daily", []string{"2026-03-03 00:00", "2026-03-04 00:00", "2026-03-05 00:00"}},
+		{"0 0 31 * *", []string{"2026-03-31 00:00", "2026-05-31 00:00", "2026-07-31 00:00"}},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.expr, err)
+			continue
+		}
+		var got []string
+		for _, run := range s.NextRuns(now, 3) {
+			got = append(got, run.Format("2006-01-02 15:04"))
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%s: runs = %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	if s, _ := Parse("0 0 30 feb *"); len(s.NextRuns(now, 1)) != 0 {
+		t.Error("February 30 runs")
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * foo", "@reboot"} {
+		if err := Valid(expr); err == nil {
+			t.Errorf("%q accepted", expr)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	cases := map[string]Builder{
+		"*/20 * * * *": {Kind: EveryMinutes, Interval: 20},
+		"7 * * * *":    {Kind: Hourly, Minute: 7},
+		"30 4 * * *":   {Kind: Daily, Hour: 4, Minute: 30},
+		"0 3 * * 0":    {Kind: Weekly, Hour: 3, Weekday: 0},
+		"15 2 1 * *":   {Kind: Monthly, Hour: 2, Minute: 15, Day: 1},
+	}
+	for want, b := range cases {
+		if got, err := b.Expr(); err != nil || got != want {
+			t.Errorf("%+v: %q, %v; want %q", b, got, err, want)
+		}
+	}
+	for _, b := range []Builder{{Kind: EveryMinutes}, {Kind: Daily, Hour: 25}, {Kind: Monthly, Day: 32}, {Kind: "yearly"}} {
+		if _, err := b.Expr(); err == nil {
+			t.Errorf("%+v accepted", b)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	content := "# правка вручную\nSHELL=/bin/sh\n\n0 4 * * *  /opt/bin/backup.sh  --all\n*/30 * * * * /opt/bin/terem lists update # terem:lists\n@weekly reboot\n"
+	table := ParseTable(content)
+	if table.String() != content {
+		t.Errorf("round trip = %q", table.String())
+	}
+	entries := table.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if e := entries[0]; e.Line != 3 || e.Schedule != "0 4 * * *" || e.Command != "/opt/bin/backup.sh  --all" || e.Owned() {
+		t.Errorf("manual entry = %+v", e)
+	}
+	if e := entries[1]; e.Name != "lists" || e.Command != "/opt/bin/terem lists update" {
+		t.Errorf("terem entry = %+v", e)
+	}
+	if e := entries[2]; e.Schedule != "@weekly" || e.Command != "reboot" {
+		t.Errorf("macro entry = %+v", e)
+	}
+
+	if err := table.Add(Entry{Name: "lists", Schedule: "0 */6 * * *", Command: "/opt/bin/terem lists update"}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := table.Find("lists"); e.Line != 4 || e.Schedule != "0 */6 * * *" {
+		t.Errorf("upserted entry = %+v", e)
+	}
+	if err := table.Add(Entry{Name: "bad name", Schedule: "* * * * *", Command: "true"}); err == nil {
+		t.Error("invalid name accepted")
+	}
+	if err := table.Add(Entry{Name: "x", Schedule: "* * * *", Command: "true"}); err == nil {
+		t.Error("invalid schedule accepted")
+	}
+	if err := table.Replace(3, Entry{Name: "lists", Schedule: "* * * * *", Command: "true"}); err == nil {
+		t.Error("duplicate name accepted")
+	}
+	if err := table.Replace(0, Entry{Schedule: "* * * * *", Command: "true"}); err == nil {
+		t.Error("comment line replaced")
+	}
+	if err := table.Replace(3, Entry{Name: "backup", Schedule: "0 5 * * *", Command: "/opt/bin/backup.sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Remove(5); err != nil {
+		t.Fatal(err)
+	}
+	want := "# правка вручную\nSHELL=/bin/sh\n\n0 5 * * * /opt/bin/backup.sh # terem:backup\n0 */6 * * * /opt/bin/terem lists update # terem:lists\n"
+	if table.String() != want {
+		t.Errorf("table = %q", table.String())
+	}
+	if err := table.Remove(1); err == nil {
+		t.Error("environment line removed")
+	}
+}
+
+func TestManager(t *testing.T) {
+	f := &testutil.Runner{Fail: "[ -d"}
+	if file := (Manager{Runner: f}).File(); file != OpenWrtCrontab {
+		t.Errorf("file without Entware cron = %s", file)
+	}
+
+	f = &testutil.Runner{Outputs: map[string]string{"cat '/opt/crontab'": "0 4 * * * backup\n"}}
+	m := Manager{Runner: f, Path: "/opt/crontab"}
+	if err := m.Add(Entry{Name: "reboot", Schedule: "0 5 * * 1", Command: "reboot"}); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Ran("0 5 * * 1 reboot # terem:reboot") || !f.Ran("echo root >> '/opt'/cron.update") {
+		t.Errorf("save commands = %q", f.Commands)
+	}
+
+	f = &testutil.Runner{Fail: "[ -f"}
+	m.Runner = f
+	table, err := m.Load()
+	if err != nil || len(table.Entries()) != 0 {
+		t.Errorf("missing crontab = %+v, %v", table, err)
+	}
+	f.Fail = "cron.update"
+	if err := m.Save(table); err == nil {
+		t.Error("cron.update failure ignored")
+	}
+}
@@ -0,0 +1,253 @@
+package cron
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Пути к crontab пользователя root
+const (
+	EntwareCrontab = "/opt/var/spool/cron/crontabs/root" // cron из Entware
+	OpenWrtCrontab = "/etc/crontabs/root"                // crond прошивки OpenWrt
+)
+
+// tagPrefix метка строк терема в конце команды: sh считает её комментарием
+const tagPrefix = "# terem:"
+
+var (
+	tagPattern  = regexp.MustCompile(`\s+# terem:([A-Za-z0-9_.-]+)\s*$`)
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+	envPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+)
+
+// Entry задание crontab
+type Entry struct {
+	Line     int    `json:"line"`           // Номер строки в файле, с нуля
+	Schedule string `json:"schedule"`       // Расписание как в файле
+	Command  string `json:"command"`        // Команда без метки терема
+	Name     string `json:"name,omitempty"` // Имя задания терема; пусто — строка добавлена не теремом
+}
+
+// Owned сообщает, что задание добавлено теремом
+func (e Entry) Owned() bool {
+	return e.Name != ""
+}
+
+// String возвращает строку crontab с меткой терема
+func (e Entry) String() string {
+	line := e.Schedule + " " + e.Command
+	if e.Owned() {
+		line += " " + tagPrefix + e.Name
+	}
+	return line
+}
+
+// Validate проверяет имя, расписание и команду задания
+func (e Entry) Validate() error {
+	if e.Name != "" && !namePattern.MatchString(e.Name) {
+		return fmt.Errorf(i18n.T("cron.error.name"), e.Name)
+	}
+	if strings.TrimSpace(e.Command) == "" || strings.ContainsAny(e.Command, "\n\r") {
+		return fmt.Errorf(i18n.T("cron.error.command"), e.Command)
+	}
+	return Valid(e.Schedule)
+}
+
+// ParseLine разбирает строку crontab; комментарии, пустые строки и переменные окружения
+// заданиями не считаются
+func ParseLine(line string) (Entry, bool) {
+	text := strings.TrimSpace(line)
+	if text == "" || strings.HasPrefix(text, "#") || envPattern.MatchString(text) {
+		return Entry{}, false
+	}
+	parts := strings.Fields(text)
+	count := len(fields)
+	if strings.HasPrefix(parts[0], "@") {
+		count = 1
+	}
+	if len(parts) <= count {
+		return Entry{}, false
+	}
+	e := Entry{Schedule: strings.Join(parts[:count], " ")}
+	// Команда берётся из исходной строки, чтобы сохранить пробелы в ней
+	command := text
+	for range count {
+		command = strings.TrimLeft(command, " \t")
+		command = command[strings.IndexAny(command+" ", " \t"):]
+	}
+	command = strings.TrimSpace(command)
+	if m := tagPattern.FindStringSubmatchIndex(command); m != nil {
+		e.Name = command[m[2]:m[3]]
+		command = strings.TrimSpace(command[:m[0]])
+	}
+	e.Command = command
+	return e, true
+}
+
+// Table содержимое crontab; строки, не являющиеся заданиями, сохраняются как есть
+type Table struct {
+	lines []string
+}
+
+// ParseTable разбирает содержимое crontab
+func ParseTable(content string) Table {
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return Table{}
+	}
+	return Table{lines: strings.Split(content, "\n")}
+}
+
+// String возвращает содержимое crontab
+func (t Table) String() string {
+	if len(t.lines) == 0 {
+		return ""
+	}
+	return strings.Join(t.lines, "\n") + "\n"
+}
+
+// Entries возвращает задания в порядке файла
+func (t Table) Entries() []Entry {
+	var entries []Entry
+	for i, line := range t.lines {
+		if e, ok := ParseLine(line); ok {
+			e.Line = i
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Find возвращает задание терема по имени
+func (t Table) Find(name string) (Entry, bool) {
+	for _, e := range t.Entries() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Add добавляет задание в конец; задание терема с уже существующим именем заменяется
+func (t *Table) Add(e Entry) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	if current, ok := t.Find(e.Name); ok && e.Owned() {
+		t.lines[current.Line] = e.String()
+		return nil
+	}
+	t.lines = append(t.lines, e.String())
+	return nil
+}
+
+// Replace заменяет задание в строке line
+func (t *Table) Replace(line int, e Entry) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	if _, ok := t.entryAt(line); !ok {
+		return fmt.Errorf(i18n.T("cron.error.line"), line+1)
+	}
+	if current, ok := t.Find(e.Name); ok && e.Owned() && current.Line != line {
+		return fmt.Errorf(i18n.T("cron.error.duplicate"), e.Name)
+	}
+	t.lines[line] = e.String()
+	return nil
+}
+
+// Remove удаляет строку задания
+func (t *Table) Remove(line int) error {
+	if _, ok := t.entryAt(line); !ok {
+		return fmt.Errorf(i18n.T("cron.error.line"), line+1)
+	}
+	t.lines = append(t.lines[:line], t.lines[line+1:]...)
+	return nil
+}
+
+// entryAt возвращает задание в строке line
+func (t Table) entryAt(line int) (Entry, bool) {
+	if line < 0 || line >= len(t.lines) {
+		return Entry{}, false
+	}
+	return ParseLine(t.lines[line])
+}
+
+// Manager читает и записывает crontab root
+type Manager struct {
+	Runner utils.Runner
+	Path   string // По умолчанию определяется методом File
+}
+
+// File возвращает путь к crontab: заданный явно, при установленном cron из Entware — его файл,
+// иначе файл OpenWrt
+func (m Manager) File() string {
+	if m.Path != "" {
+		return m.Path
+	}
+	if _, err := utils.OrLocal(m.Runner).RunCommand("[ -d " + utils.ShellQuote(path.Dir(EntwareCrontab)) + " ]"); err == nil {
+		return EntwareCrontab
+	}
+	return OpenWrtCrontab
+}
+
+// Load читает crontab; отсутствующий файл даёт пустую таблицу
+func (m Manager) Load() (Table, error) {
+	file := m.File()
+	if _, err := utils.OrLocal(m.Runner).RunCommand("[ -f " + utils.ShellQuote(file) + " ]"); err != nil {
+		return Table{}, nil
+	}
+	content, err := service.ReadFile(m.Runner, file)
+	if err != nil {
+		return Table{}, err
+	}
+	return ParseTable(content), nil
+}
+
+// Save записывает crontab и сообщает crond об изменении через cron.update,
+// как это делает crontab -e в busybox
+func (m Manager) Save(t Table) error {
+	file := m.File()
+	if err := service.WriteFile(m.Runner, file, t.String()); err != nil {
+		return err
+	}
+	dir := utils.ShellQuote(path.Dir(file))
+	command := fmt.Sprintf("rm -f %s && echo root >> %s/cron.update", utils.ShellQuote(file+".bak"), dir)
+	if output, err := utils.OrLocal(m.Runner).RunCommand(command + " 2>&1"); err != nil {
+		return fmt.Errorf(i18n.T("cron.error.save"), file, strings.TrimSpace(output+" "+err.Error()))
+	}
+	return nil
+}
+
+// Add добавляет задание и сохраняет crontab
+func (m Manager) Add(e Entry) error {
+	return m.update(func(t *Table) error { return t.Add(e) })
+}
+
+// Replace заменяет задание в строке line и сохраняет crontab
+func (m Manager) Replace(line int, e Entry) error {
+	return m.update(func(t *Table) error { return t.Replace(line, e) })
+}
+
+// Remove удаляет задание в строке line и сохраняет crontab
+func (m Manager) Remove(line int) error {
+	return m.update(func(t *Table) error { return t.Remove(line) })
+}
+
+// update читает crontab, изменяет его и сохраняет
+func (m Manager) update(change func(*Table) error) error {
+	t, err := m.Load()
+	if err != nil {
+		return err
+	}
+	if err := change(&t); err != nil {
+		return err
+	}
+	return m.Save(t)
+}
@@ -0,0 +1,246 @@
+// Package cron управляет заданиями в crontab Entware и OpenWrt: разбирает и проверяет
+// расписания, вычисляет ближайшие запуски, собирает расписание из простых параметров
+// и помечает строки, добавленные теремом.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// field поле расписания: диапазон значений и имена (jan, mon)
+type field struct {
+	key      string // Суффикс ключа локализации с названием поля
+	min, max int
+	names    []string // Имена значений, начиная с min
+}
+
+// fields поля расписания в порядке записи
+var fields = []field{
+	{key: "minute", min: 0, max: 59},
+	{key: "hour", min: 0, max: 23},
+	{key: "dom", min: 1, max: 31},
+	{key: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{key: "dow", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// macros сокращения расписаний, которые раскрываются в пять полей
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// searchLimit насколько далеко вперёд ищется следующий запуск
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// Schedule разобранное расписание cron
+type Schedule struct {
+	Expr   string    `json:"expr"` // Расписание из пяти полей; сокращения раскрыты
+	sets   [5]uint64 // Допустимые значения каждого поля битами
+	anyDom bool      // День месяца не ограничен
+	anyDow bool      // День недели не ограничен
+}
+
+// Parse разбирает расписание из пяти полей или сокращение вида @daily.
+// @reboot не поддерживается: busybox crond его не понимает
+func Parse(expr string) (Schedule, error) {
+	expr = strings.Join(strings.Fields(expr), " ")
+	if strings.HasPrefix(expr, "@") {
+		full, ok := macros[strings.ToLower(expr)]
+		if !ok {
+			return Schedule{}, fmt.Errorf(i18n.T("cron.error.macro"), expr)
+		}
+		expr = full
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf(i18n.T("cron.error.fields"), expr)
+	}
+	s := Schedule{Expr: expr}
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, err
+		}
+		s.sets[i] = set
+	}
+	// Воскресенье можно записать как 0 и как 7
+	if s.sets[4]&(1<<7) != 0 {
+		s.sets[4] |= 1
+	}
+	s.anyDom = strings.HasPrefix(parts[2], "*")
+	s.anyDow = strings.HasPrefix(parts[4], "*")
+	return s, nil
+}
+
+// Valid проверяет расписание
+func Valid(expr string) error {
+	_, err := Parse(expr)
+	return err
+}
+
+// parseField разбирает поле: *, значение, диапазон a-b, шаг */n или a-b/n и списки через запятую
+func parseField(text string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(text, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 || n > f.max {
+				return 0, fieldError(text, f)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, fieldError(text, f)
+			}
+			if hi, err = f.value(b); err != nil || hi < lo {
+				return 0, fieldError(text, f)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, fieldError(text, f)
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value возвращает числовое значение поля по числу или имени
+func (f field) value(text string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(text, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < f.min || v > f.max {
+		return 0, strconv.ErrRange
+	}
+	return v, nil
+}
+
+// fieldError возвращает ошибку в поле расписания
+func fieldError(text string, f field) error {
+	return fmt.Errorf(i18n.T("cron.error.field"), text, i18n.T("cron.field."+f.key), f.min, f.max)
+}
+
+// has сообщает, что значение v поля i входит в расписание
+func (s Schedule) has(i, v int) bool {
+	return s.sets[i]&(1<<v) != 0
+}
+
+// dayMatches проверяет день: если ограничены и день месяца, и день недели,
+// достаточно совпадения любого из них, как в классическом cron
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom, dow := s.has(2, t.Day()), s.has(4, int(t.Weekday()))
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next возвращает первый запуск строго после after или нулевое время, если расписание
+// не срабатывает в ближайшие годы (например, 30 февраля)
+func (s Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(searchLimit)
+	for t.Before(limit) {
+		switch {
+		case !s.has(3, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.has(1, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// NextRuns возвращает до n ближайших запусков после after
+func (s Schedule) NextRuns(after time.Time, n int) []time.Time {
+	var runs []time.Time
+	for len(runs) < n {
+		after = s.Next(after)
+		if after.IsZero() {
+			break
+		}
+		runs = append(runs, after)
+	}
+	return runs
+}
+
+// Виды расписаний конструктора
+const (
+	EveryMinutes = "minutes" // Каждые N минут
+	Hourly       = "hourly"  // Каждый час в заданную минуту
+	Daily        = "daily"   // Каждый день в заданное время
+	Weekly       = "weekly"  // Раз в неделю в заданный день и время
+	Monthly      = "monthly" // Раз в месяц в заданное число и время
+)
+
+// Kinds виды расписаний конструктора в порядке показа в меню
+var Kinds = []string{EveryMinutes, Hourly, Daily, Weekly, Monthly}
+
+// Builder параметры конструктора расписания; используются только поля, нужные виду
+type Builder struct {
+	Kind     string
+	Interval int // Период в минутах для EveryMinutes
+	Minute   int
+	Hour     int
+	Weekday  int // 0 — воскресенье
+	Day      int // Число месяца
+}
+
+// Expr собирает расписание из параметров конструктора и проверяет его
+func (b Builder) Expr() (string, error) {
+	var expr string
+	switch b.Kind {
+	case EveryMinutes:
+		if b.Interval < 1 || b.Interval > 59 {
+			return "", fmt.Errorf(i18n.T("cron.error.interval"), b.Interval)
+		}
+		expr = fmt.Sprintf("*/%d * * * *", b.Interval)
+	case Hourly:
+		expr = fmt.Sprintf("%d * * * *", b.Minute)
+	case Daily:
+		expr = fmt.Sprintf("%d %d * * *", b.Minute, b.Hour)
+	case Weekly:
+		expr = fmt.Sprintf("%d %d * * %d", b.Minute, b.Hour, b.Weekday)
+	case Monthly:
+		expr = fmt.Sprintf("%d %d %d * *", b.Minute, b.Hour, b.Day)
+	default:
+		return "", fmt.Errorf(i18n.T("cron.error.kind"), b.Kind)
+	}
+	return expr, Valid(expr)
+}
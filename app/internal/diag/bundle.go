@@ -0,0 +1,160 @@
+package diag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue значение, которым заменяются секреты
+const redactedValue = "***"
+
+// secretMarkers фрагменты имён ключей, значения которых считаются секретными
+var secretMarkers = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"api_key",
+	"privatekey",
+	"private_key",
+	"presharedkey",
+	"credential",
+}
+
+// Entry описывает файл, добавляемый в диагностический архив.
+type Entry struct {
+	Name    string    // Имя файла внутри архива
+	Data    []byte    // Содержимое
+	ModTime time.Time // Время изменения (по умолчанию — текущее)
+}
+
+// FileEntry читает файл path и возвращает запись архива с именем name.
+func FileEntry(name, path string) (Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Name: name, Data: data, ModTime: info.ModTime()}, nil
+}
+
+// LogFiles возвращает текущий файл лога и его ротированные копии (lumberjack: name-<время>.ext[.gz]).
+func LogFiles(logFile string) []string {
+	if logFile == "" {
+		return nil
+	}
+	ext := filepath.Ext(logFile)
+	base := strings.TrimSuffix(logFile, ext)
+
+	files := []string{}
+	if _, err := os.Stat(logFile); err == nil {
+		files = append(files, logFile)
+	}
+	backups, _ := filepath.Glob(base + "-*" + ext + "*")
+	sort.Strings(backups)
+	return append(files, backups...)
+}
+
+// WriteBundle упаковывает записи в tar.gz и пишет архив в w.
+func WriteBundle(w io.Writer, entries []Entry) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, entry := range entries {
+		modTime := entry.ModTime
+		if modTime.IsZero() {
+			modTime = time.Now()
+		}
+		header := &tar.Header{
+			Name:    entry.Name,
+			Mode:    0o600,
+			Size:    int64(len(entry.Data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf(i18n.T("diag.error.bundle_entry"), entry.Name, err)
+		}
+		if _, err := tw.Write(entry.Data); err != nil {
+			return fmt.Errorf(i18n.T("diag.error.bundle_entry"), entry.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// SaveBundle создаёт архив по пути path.
+func SaveBundle(path string, entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf(i18n.T("diag.error.bundle_create"), path, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf(i18n.T("diag.error.bundle_create"), path, err)
+	}
+	if err := WriteBundle(f, entries); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RedactYAML маскирует значения секретных ключей в YAML-документе.
+func RedactYAML(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(Redact(doc))
+}
+
+// Redact рекурсивно маскирует значения ключей, похожих на пароли, токены и ключи.
+func Redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			if isSecretKey(key) && item != nil && item != "" {
+				out[key] = redactedValue
+				continue
+			}
+			out[key] = Redact(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = Redact(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// isSecretKey сообщает, содержит ли имя ключа признак секрета.
+func isSecretKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, marker := range secretMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
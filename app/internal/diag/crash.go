@@ -0,0 +1,130 @@
+// Package diag формирует отчёты о сбоях и диагностические архивы для баг-репортов.
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+const (
+	crashFilePrefix = "terem-crash-"
+	crashFileSuffix = ".txt"
+	crashTimeFormat = "20060102-150405"
+)
+
+// CrashReport содержит сведения, сохраняемые при панике или фатальной ошибке.
+type CrashReport struct {
+	Time       time.Time // Время сбоя
+	Version    string    // Версия приложения
+	Build      string    // Коммит, дата и архитектура сборки
+	Reason     string    // Текст паники или фатальной ошибки
+	Stack      []byte    // Стек горутины, в которой произошёл сбой
+	Config     []byte    // Конфигурация (YAML) с замаскированными секретами
+	SysInfo    []byte    // Снимок системной информации (JSON)
+	LogLines   []string  // Последние записи лога
+	Goroutines []byte    // Дамп всех горутин
+}
+
+// WriteTo записывает отчёт в текстовом виде.
+func (r CrashReport) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "terem crash report\n")
+	fmt.Fprintf(&buf, "time:    %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&buf, "version: %s\n", r.Version)
+	if r.Build != "" {
+		fmt.Fprintf(&buf, "build:   %s\n", r.Build)
+	}
+	fmt.Fprintf(&buf, "runtime: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&buf, "reason:  %s\n", r.Reason)
+
+	section(&buf, "stack", r.Stack)
+	section(&buf, "config", r.Config)
+	section(&buf, "sysinfo", r.SysInfo)
+	section(&buf, "log", []byte(strings.Join(r.LogLines, "\n")))
+	section(&buf, "goroutines", r.Goroutines)
+
+	return buf.WriteTo(w)
+}
+
+// section добавляет в отчёт именованный раздел, пропуская пустые.
+func section(buf *bytes.Buffer, name string, data []byte) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "\n===== %s =====\n", name)
+	buf.Write(bytes.TrimRight(data, "\n"))
+	buf.WriteByte('\n')
+}
+
+// SaveCrashReport сохраняет отчёт в каталог dir и возвращает путь до файла.
+// Если каталог недоступен для записи, отчёт сохраняется во временный каталог.
+func SaveCrashReport(dir string, r CrashReport) (string, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	name := crashFilePrefix + r.Time.Format(crashTimeFormat) + crashFileSuffix
+
+	var lastErr error
+	for _, candidate := range []string{dir, os.TempDir()} {
+		if candidate == "" {
+			continue
+		}
+		path := filepath.Join(candidate, name)
+		if err := writeCrashFile(path, r); err != nil {
+			lastErr = err
+			continue
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf(i18n.T("diag.error.crash_write"), lastErr)
+}
+
+func writeCrashFile(path string, r CrashReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CrashReports возвращает пути отчётов о сбоях в каталоге dir, от старых к новым.
+func CrashReports(dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, crashFilePrefix+"*"+crashFileSuffix))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// Goroutines возвращает стеки всех горутин процесса.
+func Goroutines() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		if len(buf) >= 8*1024*1024 {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
@@ -0,0 +1,113 @@
+package diag
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactYAMLMasksSecrets(t *testing.T) {
+	input := []byte("logFile: /tmp/terem.log\nadguard:\n  user: admin\n  password: hunter2\nproxy:\n  users:\n    - name: bob\n      apiToken: abc\n")
+
+	out, err := RedactYAML(input)
+	if err != nil {
+		t.Fatalf("redact: %v", err)
+	}
+	text := string(out)
+
+	for _, secret := range []string{"hunter2", "abc"} {
+		if strings.Contains(text, secret) {
+			t.Fatalf("secret %q leaked into %s", secret, text)
+		}
+	}
+	for _, kept := range []string{"/tmp/terem.log", "admin", "bob"} {
+		if !strings.Contains(text, kept) {
+			t.Fatalf("expected %q to be preserved in %s", kept, text)
+		}
+	}
+}
+
+func TestWriteBundleRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []Entry{
+		{Name: "config.yaml", Data: []byte("language: ru\n")},
+		{Name: "logs/terem.log", Data: []byte("line\n")},
+	}
+	if err := WriteBundle(&buf, entries); err != nil {
+		t.Fatalf("write bundle: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	got := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		got[header.Name] = string(data)
+	}
+
+	for _, entry := range entries {
+		if got[entry.Name] != string(entry.Data) {
+			t.Fatalf("entry %s: expected %q, got %q", entry.Name, entry.Data, got[entry.Name])
+		}
+	}
+}
+
+func TestSaveCrashReport(t *testing.T) {
+	dir := t.TempDir()
+	path, err := SaveCrashReport(dir, CrashReport{
+		Version:  "1.0.0",
+		Build:    "1.0.0 (abc1234, linux-mipsle, go1.25.0)",
+		Reason:   "boom",
+		Stack:    []byte("main.main()"),
+		LogLines: []string{"[INFO] started"},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for _, want := range []string{"version: 1.0.0", "build:   1.0.0 (abc1234", "reason:  boom", "===== stack =====", "[INFO] started"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %q in report:\n%s", want, data)
+		}
+	}
+
+	if reports := CrashReports(dir); len(reports) != 1 || reports[0] != path {
+		t.Fatalf("unexpected crash reports list: %v", reports)
+	}
+}
+
+func TestLogFilesIncludesBackups(t *testing.T) {
+	dir := t.TempDir()
+	current := filepath.Join(dir, "terem.log")
+	backup := filepath.Join(dir, "terem-2026-01-02T03-04-05.000.log.gz")
+	for _, name := range []string{current, backup, filepath.Join(dir, "other.log")} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := LogFiles(current)
+	if len(files) != 2 || files[0] != current || files[1] != backup {
+		t.Fatalf("unexpected log files: %v", files)
+	}
+}
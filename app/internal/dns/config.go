@@ -0,0 +1,243 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Локальные порты шифрующих посредников, на которые dnsmasq пересылает запросы
+const (
+	StubbyPort     = 5453
+	DNSCryptPort   = 5354
+	upstreamMarker = "# upstream: "
+)
+
+// HostOverride локальная подмена имени
+type HostOverride struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+// Forward пересылка запросов домена на отдельный сервер
+type Forward struct {
+	Domain string `json:"domain"`
+	Server string `json:"server"` // IP или IP#порт
+}
+
+// Settings параметры DNS, которыми управляет терем
+type Settings struct {
+	Upstreams []Upstream     `json:"upstreams"`
+	Hosts     []HostOverride `json:"hosts,omitempty"`
+	Forwards  []Forward      `json:"forwards,omitempty"`
+}
+
+var domainPattern = regexp.MustCompile(`^([A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9])?\.)*[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9])?$`)
+
+// Validate проверяет параметры DNS
+func (s Settings) Validate() error {
+	if len(s.Upstreams) == 0 {
+		return errors.New(i18n.T("dns.error.no_upstreams"))
+	}
+	for _, h := range s.Hosts {
+		if !domainPattern.MatchString(h.Name) || net.ParseIP(h.IP) == nil {
+			return fmt.Errorf(i18n.T("dns.error.host"), h.Name+"="+h.IP)
+		}
+	}
+	for _, f := range s.Forwards {
+		ip, port, _ := strings.Cut(f.Server, "#")
+		if !domainPattern.MatchString(f.Domain) || net.ParseIP(ip) == nil {
+			return fmt.Errorf(i18n.T("dns.error.forward"), f.Domain+"="+f.Server)
+		}
+		if port != "" {
+			if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+				return fmt.Errorf(i18n.T("dns.error.forward"), f.Domain+"="+f.Server)
+			}
+		}
+	}
+	return nil
+}
+
+// Uses сообщает, есть ли среди вышестоящих серверов серверы с протоколом proto
+func (s Settings) Uses(proto string) bool {
+	for _, u := range s.Upstreams {
+		if u.Proto == proto {
+			return true
+		}
+	}
+	return false
+}
+
+// byProto возвращает вышестоящие серверы с протоколом proto
+func (s Settings) byProto(proto string) []Upstream {
+	var result []Upstream
+	for _, u := range s.Upstreams {
+		if u.Proto == proto {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// RenderDnsmasq формирует дополнительный файл конфигурации dnsmasq.
+// Зашифрованные серверы подключаются через stubby и dnscrypt-proxy на локальных портах.
+func RenderDnsmasq(s Settings) string {
+	var b strings.Builder
+	b.WriteString("# Создано terem: ручные изменения будут перезаписаны\n")
+	for _, u := range s.Upstreams {
+		b.WriteString(upstreamMarker + u.String() + "\n")
+	}
+	b.WriteString("no-resolv\n")
+
+	for _, u := range s.byProto(ProtoPlain) {
+		port := u.Port
+		if port == 0 {
+			port = 53
+		}
+		fmt.Fprintf(&b, "server=%s#%d\n", u.Address, port)
+	}
+	if s.Uses(ProtoDoT) {
+		fmt.Fprintf(&b, "server=127.0.0.1#%d\n", StubbyPort)
+	}
+	if s.Uses(ProtoDoH) {
+		fmt.Fprintf(&b, "server=127.0.0.1#%d\n", DNSCryptPort)
+	}
+
+	for _, f := range s.Forwards {
+		fmt.Fprintf(&b, "server=/%s/%s\n", f.Domain, f.Server)
+	}
+	for _, h := range s.Hosts {
+		fmt.Fprintf(&b, "address=/%s/%s\n", h.Name, h.IP)
+	}
+	return b.String()
+}
+
+// ParseDnsmasq восстанавливает параметры из файла, созданного RenderDnsmasq
+func ParseDnsmasq(content string) Settings {
+	var s Settings
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, upstreamMarker):
+			if u, err := ParseUpstream(strings.TrimPrefix(line, upstreamMarker)); err == nil {
+				s.Upstreams = append(s.Upstreams, u)
+			}
+		case strings.HasPrefix(line, "server=/"):
+			parts := strings.Split(strings.TrimPrefix(line, "server=/"), "/")
+			if len(parts) == 2 {
+				s.Forwards = append(s.Forwards, Forward{Domain: parts[0], Server: parts[1]})
+			}
+		case strings.HasPrefix(line, "address=/"):
+			parts := strings.Split(strings.TrimPrefix(line, "address=/"), "/")
+			if len(parts) == 2 {
+				s.Hosts = append(s.Hosts, HostOverride{Name: parts[0], IP: parts[1]})
+			}
+		}
+	}
+	return s
+}
+
+// RenderStubby формирует конфигурацию stubby для серверов DNS-over-TLS
+func RenderStubby(s Settings) string {
+	var b strings.Builder
+	b.WriteString("# Создано terem: ручные изменения будут перезаписаны\n")
+	b.WriteString("resolution_type: GETDNS_RESOLUTION_STUB\n")
+	b.WriteString("dns_transport_list:\n  - GETDNS_TRANSPORT_TLS\n")
+	b.WriteString("tls_authentication: GETDNS_AUTHENTICATION_REQUIRED\n")
+	b.WriteString("tls_query_padding_blocksize: 128\n")
+	b.WriteString("edns_client_subnet_private: 1\n")
+	b.WriteString("idle_timeout: 10000\n")
+	b.WriteString("round_robin_upstreams: 1\n")
+	fmt.Fprintf(&b, "listen_addresses:\n  - 127.0.0.1@%d\n", StubbyPort)
+	b.WriteString("upstream_recursive_servers:\n")
+	for _, u := range s.byProto(ProtoDoT) {
+		fmt.Fprintf(&b, "  - address_data: %s\n", u.Address)
+		if u.Port != 0 && u.Port != 853 {
+			fmt.Fprintf(&b, "    tls_port: %d\n", u.Port)
+		}
+		if u.TLSName != "" {
+			fmt.Fprintf(&b, "    tls_auth_name: \"%s\"\n", u.TLSName)
+		}
+	}
+	return b.String()
+}
+
+// RenderDNSCrypt формирует конфигурацию dnscrypt-proxy для серверов DNS-over-HTTPS
+func RenderDNSCrypt(s Settings) (string, error) {
+	var names []string
+	var static strings.Builder
+	for i, u := range s.byProto(ProtoDoH) {
+		stamp, err := u.Stamp()
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("terem-%d", i)
+		names = append(names, "'"+name+"'")
+		fmt.Fprintf(&static, "  [static.'%s']\n  stamp = '%s'\n", name, stamp)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Создано terem: ручные изменения будут перезаписаны\n")
+	fmt.Fprintf(&b, "listen_addresses = ['127.0.0.1:%d']\n", DNSCryptPort)
+	fmt.Fprintf(&b, "server_names = [%s]\n", strings.Join(names, ", "))
+	b.WriteString("max_clients = 250\n")
+	b.WriteString("ipv4_servers = true\nipv6_servers = false\n")
+	b.WriteString("dnscrypt_servers = false\ndoh_servers = true\n")
+	b.WriteString("require_dnssec = false\nrequire_nolog = false\nrequire_nofilter = false\n")
+	b.WriteString("timeout = 5000\nkeepalive = 30\n")
+	b.WriteString("bootstrap_resolvers = ['9.9.9.9:53', '1.1.1.1:53']\n")
+	b.WriteString("ignore_system_dns = true\n")
+	b.WriteString("cache = true\ncache_size = 4096\n")
+	b.WriteString("\n[static]\n")
+	b.WriteString(static.String())
+	return b.String(), nil
+}
+
+// ParseUpstreams разбирает список вышестоящих серверов в формате ParseUpstream
+func ParseUpstreams(items []string) ([]Upstream, error) {
+	var upstreams []Upstream
+	for _, item := range items {
+		u, err := ParseUpstream(item)
+		if err != nil {
+			return nil, err
+		}
+		upstreams = append(upstreams, u)
+	}
+	return upstreams, nil
+}
+
+// ParseHosts разбирает список подмен вида "имя=IP, имя2=IP2"
+func ParseHosts(items []string) ([]HostOverride, error) {
+	var hosts []HostOverride
+	for _, item := range items {
+		name, ip, ok := strings.Cut(item, "=")
+		h := HostOverride{Name: strings.TrimSpace(name), IP: strings.TrimSpace(ip)}
+		if !ok || !domainPattern.MatchString(h.Name) || net.ParseIP(h.IP) == nil {
+			return nil, fmt.Errorf(i18n.T("dns.error.host"), item)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// ParseForwards разбирает список правил вида "домен=IP[#порт]"
+func ParseForwards(items []string) ([]Forward, error) {
+	var forwards []Forward
+	for _, item := range items {
+		domain, server, ok := strings.Cut(item, "=")
+		f := Forward{Domain: strings.TrimSpace(domain), Server: strings.TrimSpace(server)}
+		if !ok {
+			return nil, fmt.Errorf(i18n.T("dns.error.forward"), item)
+		}
+		forwards = append(forwards, f)
+	}
+	if err := (Settings{Upstreams: []Upstream{{}}, Forwards: forwards}).Validate(); err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
@@ -0,0 +1,284 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+// fakeAnswer формирует ответ на запрос с одной записью A 10.0.0.1
+func fakeAnswer(query []byte) []byte {
+	msg := append([]byte(nil), query[:2]...)
+	msg = append(msg, 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0)
+	msg = append(msg, query[12:]...)
+	msg = append(msg, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 10, 0, 0, 1)
+	return msg
+}
+
+// startUDPServer запускает локальный DNS-сервер-заглушку
+func startUDPServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(fakeAnswer(buf[:n]), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// startTLSServer запускает DNS-over-TLS-заглушку с сертификатом httptest
+func startTLSServer(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				answer := fakeAnswer(query)
+				_, _ = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(answer))), answer...))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func mustUpstream(t *testing.T, s string) Upstream {
+	t.Helper()
+	u, err := ParseUpstream(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestParseUpstream(t *testing.T) {
+	cases := map[string]Upstream{
+		"1.1.1.1":                          {Proto: ProtoPlain, Address: "1.1.1.1", Port: 53},
+		"9.9.9.9:5353":                     {Proto: ProtoPlain, Address: "9.9.9.9", Port: 5353},
+		"tls://1.1.1.1@cloudflare-dns.com": {Proto: ProtoDoT, Address: "1.1.1.1", Port: 853, TLSName: "cloudflare-dns.com"},
+		"https://dns.google/dns-query":     {Proto: ProtoDoH, URL: "https://dns.google/dns-query"},
+	}
+	for in, want := range cases {
+		got := mustUpstream(t, in)
+		if got != want {
+			t.Errorf("%s: got %+v, want %+v", in, got, want)
+		}
+		if got.String() != in {
+			t.Errorf("%s: String() = %q", in, got.String())
+		}
+	}
+	for _, bad := range []string{"", "dns.google", "tls://dns.google", "1.1.1.1:99999", "https://"} {
+		if _, err := ParseUpstream(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestStamp(t *testing.T) {
+	stamp, err := mustUpstream(t, "https://dns.google/dns-query").Stamp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(stamp, "sdns://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 10}, "dns.google"...)
+	want = append(append(want, 10), "/dns-query"...)
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("stamp = %v, want %v", raw, want)
+	}
+}
+
+func TestDnsmasqRoundTrip(t *testing.T) {
+	s := Settings{
+		Upstreams: []Upstream{
+			mustUpstream(t, "1.1.1.1"),
+			mustUpstream(t, "tls://9.9.9.9@dns.quad9.net"),
+			mustUpstream(t, "https://dns.google/dns-query"),
+		},
+		Hosts:    []HostOverride{{Name: "nas.lan", IP: "192.168.1.10"}},
+		Forwards: []Forward{{Domain: "corp.example", Server: "10.8.0.1#5353"}},
+	}
+	content := RenderDnsmasq(s)
+	for _, line := range []string{"no-resolv", "server=1.1.1.1#53", "server=127.0.0.1#5453", "server=127.0.0.1#5354",
+		"server=/corp.example/10.8.0.1#5353", "address=/nas.lan/192.168.1.10"} {
+		if !strings.Contains(content, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, content)
+		}
+	}
+	if got := ParseDnsmasq(content); !reflect.DeepEqual(got, s) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, s)
+	}
+	if got := ServerLines(content); !reflect.DeepEqual(got, []string{"1.1.1.1#53", "127.0.0.1#5453", "127.0.0.1#5354"}) {
+		t.Errorf("ServerLines = %v", got)
+	}
+
+	stubby := RenderStubby(s)
+	if !strings.Contains(stubby, "127.0.0.1@5453") || !strings.Contains(stubby, `tls_auth_name: "dns.quad9.net"`) {
+		t.Errorf("unexpected stubby config:\n%s", stubby)
+	}
+	dnscrypt, err := RenderDNSCrypt(s)
+	if err != nil || !strings.Contains(dnscrypt, "server_names = ['terem-0']") || !strings.Contains(dnscrypt, "stamp = 'sdns://") {
+		t.Errorf("unexpected dnscrypt config (%v):\n%s", err, dnscrypt)
+	}
+}
+
+func TestValidateAndParseLists(t *testing.T) {
+	if err := (Settings{}).Validate(); err == nil {
+		t.Error("expected error without upstreams")
+	}
+	if _, err := ParseHosts([]string{"nas.lan=192.168.1.300"}); err == nil {
+		t.Error("expected error for bad host IP")
+	}
+	if _, err := ParseForwards([]string{"corp.example=10.8.0.1#0"}); err == nil {
+		t.Error("expected error for bad forward port")
+	}
+	if _, err := ParseUpstreams([]string{"1.1.1.1", "dns.google"}); err == nil {
+		t.Error("expected error for upstream without address")
+	}
+	upstreams, err := ParseUpstreams([]string{"1.1.1.1", "tls://9.9.9.9@dns.quad9.net"})
+	if err != nil || len(upstreams) != 2 || upstreams[1].Proto != ProtoDoT {
+		t.Errorf("ParseUpstreams = %v, %v", upstreams, err)
+	}
+	hosts, err := ParseHosts([]string{"nas.lan = 192.168.1.10"})
+	if err != nil || !reflect.DeepEqual(hosts, []HostOverride{{Name: "nas.lan", IP: "192.168.1.10"}}) {
+		t.Errorf("ParseHosts = %v, %v", hosts, err)
+	}
+}
+
+func TestTesterTransports(t *testing.T) {
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(fakeAnswer(query))
+	}))
+	defer doh.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(doh.Certificate())
+	dot := startTLSServer(t, doh.TLS.Certificates[0])
+
+	tester := Tester{
+		Timeout:    2 * time.Second,
+		TLSConfig:  &tls.Config{RootCAs: pool, ServerName: "example.com"},
+		HTTPClient: doh.Client(),
+	}
+	upstreams := []Upstream{
+		mustUpstream(t, startUDPServer(t)),
+		mustUpstream(t, "tls://"+dot+"@example.com"),
+		mustUpstream(t, doh.URL+"/dns-query"),
+	}
+	for _, res := range tester.TestAll(context.Background(), upstreams, "example.org") {
+		if res.Error != "" || !reflect.DeepEqual(res.Addrs, []string{"10.0.0.1"}) {
+			t.Errorf("%s: addrs %v, error %q", res.Upstream, res.Addrs, res.Error)
+		}
+	}
+
+	// Недоступный сервер возвращает ошибку, а не зависает
+	closed, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	addr := closed.LocalAddr().String()
+	closed.Close()
+	res := Tester{Timeout: 200 * time.Millisecond}.Test(context.Background(), mustUpstream(t, addr), "example.org")
+	if res.Error == "" {
+		t.Error("expected error for unreachable server")
+	}
+}
+
+func TestParseAnswerErrors(t *testing.T) {
+	query, id, err := BuildQuery("example.org", TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer := fakeAnswer(query)
+	if _, err := ParseAnswer(answer, id+1); err == nil {
+		t.Error("expected id mismatch")
+	}
+	answer[3] = 0x83 // NXDOMAIN
+	if _, err := ParseAnswer(answer, id); err == nil || !strings.Contains(err.Error(), "NXDOMAIN") {
+		t.Errorf("expected NXDOMAIN, got %v", err)
+	}
+	if _, _, err := BuildQuery("bad..name", TypeA); err == nil {
+		t.Error("expected error for empty label")
+	}
+}
+
+// newRunner имитирует роутер с установленными пакетами; команды с подстрокой fail завершаются ошибкой
+func newRunner(fail string) *testutil.Runner {
+	return &testutil.Runner{
+		Fail:    fail,
+		Outputs: map[string]string{"ls /opt/etc/init.d/": "/opt/etc/init.d/S56svc"},
+		Handlers: map[string]func(string) (string, error){
+			"opkg list-installed": func(command string) (string, error) {
+				return testutil.Arg(command, 2) + " - 1.0", nil
+			},
+		},
+	}
+}
+
+func TestManagerApply(t *testing.T) {
+	s := Settings{Upstreams: []Upstream{mustUpstream(t, "tls://1.1.1.1@cloudflare-dns.com")}}
+
+	r := newRunner("")
+	if err := (Manager{Runner: r}).Apply(s); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{StubbyConf, DropInFile, "conf-dir=" + DnsmasqDir, "dnsmasq --test", "S56svc' restart"} {
+		if !r.Ran(want) {
+			t.Errorf("command with %q not run: %v", want, r.Commands)
+		}
+	}
+	if r.Ran(DNSCryptConf) {
+		t.Error("dnscrypt-proxy configured without DoH upstreams")
+	}
+
+	r = newRunner("dnsmasq --test")
+	if err := (Manager{Runner: r}).Apply(s); err == nil {
+		t.Fatal("expected check error")
+	}
+	if !r.Ran("mv '"+DropInFile+".bak'") || strings.HasSuffix(r.Commands[len(r.Commands)-1], "restart") {
+		t.Errorf("expected rollback without restart: %v", r.Commands)
+	}
+}
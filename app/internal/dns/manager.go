@@ -0,0 +1,173 @@
+package dns
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Пути конфигурации в Entware
+const (
+	DnsmasqConf  = "/opt/etc/dnsmasq.conf"
+	DnsmasqDir   = "/opt/etc/dnsmasq.d"
+	DropInFile   = DnsmasqDir + "/terem-dns.conf"
+	StubbyConf   = "/opt/etc/stubby/stubby.yml"
+	DNSCryptConf = "/opt/etc/dnscrypt-proxy.toml"
+)
+
+// Службы DNS
+var (
+	Dnsmasq  = service.Service{Name: "dnsmasq", Package: "dnsmasq-full"}
+	Stubby   = service.Service{Name: "stubby"}
+	DNSCrypt = service.Service{Name: "dnscrypt-proxy2", Process: "dnscrypt-proxy"}
+)
+
+// State обнаруженное состояние DNS на роутере
+type State struct {
+	Resolver  string     `json:"resolver"`            // Активный резолвер (dnsmasq или пусто)
+	Backends  []string   `json:"backends,omitempty"`  // Запущенные шифрующие посредники
+	Servers   []string   `json:"servers,omitempty"`   // Серверы из строк server= dnsmasq
+	Managed   bool       `json:"managed"`             // Конфигурацией управляет терем
+	Settings  Settings   `json:"settings"`            // Параметры терема
+	Upstreams []Upstream `json:"upstreams,omitempty"` // Фактические вышестоящие серверы
+}
+
+// Manager управляет DNS на роутере
+type Manager struct {
+	Runner utils.Runner
+}
+
+func (m Manager) svc(s service.Service) service.Service {
+	s.Runner = m.Runner
+	return s
+}
+
+// Load возвращает параметры из файла терема (пустые, если файла нет)
+func (m Manager) Load() (Settings, bool) {
+	content, err := service.ReadFile(m.Runner, DropInFile)
+	if err != nil {
+		return Settings{}, false
+	}
+	return ParseDnsmasq(content), true
+}
+
+// Detect определяет активный резолвер и вышестоящие серверы
+func (m Manager) Detect() State {
+	var st State
+	if m.svc(Dnsmasq).Running() {
+		st.Resolver = Dnsmasq.Name
+	}
+	if m.svc(Stubby).Running() {
+		st.Backends = append(st.Backends, Stubby.Name)
+	}
+	if m.svc(DNSCrypt).Running() {
+		st.Backends = append(st.Backends, DNSCrypt.Process)
+	}
+	st.Settings, st.Managed = m.Load()
+
+	content, _ := utils.OrLocal(m.Runner).RunCommand(fmt.Sprintf("cat %s %s/*.conf 2>/dev/null", DnsmasqConf, DnsmasqDir))
+	st.Servers = ServerLines(content)
+
+	if st.Managed {
+		st.Upstreams = st.Settings.Upstreams
+	} else {
+		for _, server := range st.Servers {
+			addr, port, _ := strings.Cut(server, "#")
+			p, _ := strconv.Atoi(port)
+			st.Upstreams = append(st.Upstreams, Upstream{Proto: ProtoPlain, Address: addr, Port: p})
+		}
+	}
+	return st
+}
+
+// ServerLines возвращает серверы из строк server= без привязки к домену
+func ServerLines(content string) []string {
+	var servers []string
+	for _, line := range strings.Split(content, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "server=")
+		if ok && !strings.HasPrefix(value, "/") && value != "" {
+			servers = append(servers, value)
+		}
+	}
+	return servers
+}
+
+// Apply устанавливает недостающие пакеты, записывает конфигурацию и перезапускает службы
+func (m Manager) Apply(s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if s.Uses(ProtoDoT) {
+		if err := m.ensure(m.svc(Stubby)); err != nil {
+			return err
+		}
+		if err := service.WriteFile(m.Runner, StubbyConf, RenderStubby(s)); err != nil {
+			return err
+		}
+		if err := m.svc(Stubby).Restart(); err != nil {
+			return err
+		}
+	}
+	if s.Uses(ProtoDoH) {
+		content, err := RenderDNSCrypt(s)
+		if err != nil {
+			return err
+		}
+		if err := m.ensure(m.svc(DNSCrypt)); err != nil {
+			return err
+		}
+		if err := service.WriteFile(m.Runner, DNSCryptConf, content); err != nil {
+			return err
+		}
+		if err := m.svc(DNSCrypt).Restart(); err != nil {
+			return err
+		}
+	}
+
+	if err := m.ensure(m.svc(Dnsmasq)); err != nil {
+		return err
+	}
+	if err := EnsureConfDir(utils.OrLocal(m.Runner), DnsmasqConf, DnsmasqDir); err != nil {
+		return err
+	}
+	if err := service.WriteFile(m.Runner, DropInFile, RenderDnsmasq(s)); err != nil {
+		return err
+	}
+	if output, err := utils.OrLocal(m.Runner).RunCommand("dnsmasq --test -C " + DnsmasqConf + " 2>&1"); err != nil {
+		m.rollback()
+		return fmt.Errorf(i18n.T("dns.error.check"), strings.TrimSpace(output+" "+err.Error()))
+	}
+	return m.svc(Dnsmasq).Restart()
+}
+
+// ensure устанавливает пакет службы, если он отсутствует
+func (m Manager) ensure(svc service.Service) error {
+	if svc.Installed() {
+		return nil
+	}
+	return svc.Install()
+}
+
+// EnsureConfDir подключает каталог dir в конфигурации dnsmasq conf, если он ещё не подключён.
+// Без строки conf-dir dnsmasq не читает файлы терема в dnsmasq.d
+func EnsureConfDir(runner utils.Runner, conf, dir string) error {
+	line := "conf-dir=" + dir + ",*.conf"
+	command := fmt.Sprintf("grep -q %s %s 2>/dev/null || echo %s >> %s",
+		utils.ShellQuote("^conf-dir="+dir), utils.ShellQuote(conf), utils.ShellQuote(line), utils.ShellQuote(conf))
+	if _, err := runner.RunCommand(command); err != nil {
+		return fmt.Errorf(i18n.T("service.error.write"), conf, err)
+	}
+	return nil
+}
+
+// rollback восстанавливает предыдущий файл терема, отклонённый dnsmasq
+func (m Manager) rollback() {
+	backup := utils.ShellQuote(DropInFile + ".bak")
+	_, _ = utils.OrLocal(m.Runner).RunCommand(fmt.Sprintf("if [ -f %s ]; then mv %s %s; else rm -f %s; fi",
+		backup, backup, utils.ShellQuote(DropInFile), utils.ShellQuote(DropInFile)))
+}
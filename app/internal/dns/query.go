@@ -0,0 +1,264 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Типы записей DNS
+const (
+	TypeA    uint16 = 1
+	TypeAAAA uint16 = 28
+)
+
+// Result результат проверочного запроса к вышестоящему серверу
+type Result struct {
+	Upstream string        `json:"upstream"`
+	Addrs    []string      `json:"addrs,omitempty"`
+	RTT      time.Duration `json:"rtt"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Tester выполняет проверочные запросы напрямую к вышестоящим серверам
+type Tester struct {
+	Timeout    time.Duration
+	TLSConfig  *tls.Config  // Для DNS-over-TLS (по умолчанию проверка сертификата по TLSName)
+	HTTPClient *http.Client // Для DNS-over-HTTPS
+}
+
+// TestAll запрашивает имя name через каждый сервер и возвращает результаты с временем ответа
+func (t Tester) TestAll(ctx context.Context, upstreams []Upstream, name string) []Result {
+	results := make([]Result, 0, len(upstreams))
+	for _, u := range upstreams {
+		results = append(results, t.Test(ctx, u, name))
+	}
+	return results
+}
+
+// Test запрашивает A-запись name через сервер u
+func (t Tester) Test(ctx context.Context, u Upstream, name string) Result {
+	res := Result{Upstream: u.String()}
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	query, id, err := BuildQuery(name, TypeA)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	start := time.Now()
+	var answer []byte
+	switch u.Proto {
+	case ProtoDoT:
+		answer, err = t.exchangeTLS(ctx, u, query)
+	case ProtoDoH:
+		answer, err = t.exchangeHTTPS(ctx, u, query)
+	default:
+		answer, err = exchangeUDP(ctx, u.Dial(), query)
+	}
+	res.RTT = time.Since(start)
+	if err == nil {
+		res.Addrs, err = ParseAnswer(answer, id)
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// BuildQuery формирует запрос DNS с рекурсией и случайным идентификатором
+func BuildQuery(name string, qtype uint16) ([]byte, uint16, error) {
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = append(msg, 0x01, 0x00) // RD
+	msg = append(msg, 0, 1, 0, 0, 0, 0, 0, 0)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, 0, fmt.Errorf(i18n.T("dns.error.name"), name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+	return msg, id, nil
+}
+
+// ParseAnswer проверяет ответ и возвращает адреса из записей A и AAAA
+func ParseAnswer(msg []byte, id uint16) ([]string, error) {
+	if len(msg) < 12 {
+		return nil, errors.New(i18n.T("dns.error.short"))
+	}
+	if binary.BigEndian.Uint16(msg) != id {
+		return nil, errors.New(i18n.T("dns.error.id"))
+	}
+	if rcode := msg[3] & 0x0f; rcode != 0 {
+		return nil, fmt.Errorf(i18n.T("dns.error.rcode"), rcodeName(rcode))
+	}
+
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	an := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	var err error
+	for i := 0; i < qd; i++ {
+		if off, err = skipName(msg, off); err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+
+	var addrs []string
+	for i := 0; i < an; i++ {
+		if off, err = skipName(msg, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(msg) {
+			return nil, errors.New(i18n.T("dns.error.short"))
+		}
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return nil, errors.New(i18n.T("dns.error.short"))
+		}
+		if (rtype == TypeA && rdlen == 4) || (rtype == TypeAAAA && rdlen == 16) {
+			addrs = append(addrs, net.IP(msg[off:off+rdlen]).String())
+		}
+		off += rdlen
+	}
+	return addrs, nil
+}
+
+// skipName пропускает имя в сообщении DNS, учитывая сжатие
+func skipName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errors.New(i18n.T("dns.error.short"))
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			return off + 1, nil
+		case length&0xc0 == 0xc0:
+			return off + 2, nil
+		}
+		off += length + 1
+	}
+}
+
+func rcodeName(rcode byte) string {
+	switch rcode {
+	case 2:
+		return "SERVFAIL"
+	case 3:
+		return "NXDOMAIN"
+	case 5:
+		return "REFUSED"
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// exchangeUDP отправляет запрос по UDP
+func exchangeUDP(ctx context.Context, addr string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// exchangeTLS отправляет запрос по DNS-over-TLS (длина сообщения в двух байтах перед ним)
+func (t Tester) exchangeTLS(ctx context.Context, u Upstream, query []byte) ([]byte, error) {
+	config := t.TLSConfig
+	if config == nil {
+		config = &tls.Config{ServerName: u.TLSName}
+		if u.TLSName == "" {
+			config.ServerName = u.Address
+		}
+	}
+	d := tls.Dialer{Config: config}
+	conn, err := d.DialContext(ctx, "tcp", u.Dial())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(query)))); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	answer := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// exchangeHTTPS отправляет запрос по DNS-over-HTTPS (RFC 8484, POST)
+func (t Tester) exchangeHTTPS(ctx context.Context, u Upstream, query []byte) ([]byte, error) {
+	client := t.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.URL, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u.URL, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
@@ -0,0 +1,146 @@
+// Package dns управляет DNS на роутере: dnsmasq как локальный резолвер, stubby (DNS-over-TLS)
+// и dnscrypt-proxy (DNS-over-HTTPS) как шифрующие посредники, подмена имён, пересылка по доменам
+// и проверка вышестоящих серверов.
+package dns
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Протоколы вышестоящих серверов
+const (
+	ProtoPlain = "plain" // Обычный DNS (UDP/TCP 53)
+	ProtoDoT   = "dot"   // DNS-over-TLS (TCP 853)
+	ProtoDoH   = "doh"   // DNS-over-HTTPS
+)
+
+// Upstream вышестоящий DNS-сервер
+type Upstream struct {
+	Proto   string `json:"proto"`
+	Address string `json:"address,omitempty"` // IP-адрес (plain, dot)
+	Port    int    `json:"port,omitempty"`
+	TLSName string `json:"tlsName,omitempty"` // Имя сервера для проверки сертификата (dot)
+	URL     string `json:"url,omitempty"`     // Адрес запроса (doh)
+}
+
+// ParseUpstream разбирает запись вышестоящего сервера:
+//
+//	1.1.1.1, 1.1.1.1:5353          — обычный DNS
+//	tls://1.1.1.1@cloudflare-dns.com — DNS-over-TLS с именем для проверки сертификата
+//	https://dns.google/dns-query   — DNS-over-HTTPS
+func ParseUpstream(s string) (Upstream, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "https://"):
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return Upstream{}, fmt.Errorf(i18n.T("dns.error.upstream"), s)
+		}
+		return Upstream{Proto: ProtoDoH, URL: s}, nil
+
+	case strings.HasPrefix(s, "tls://"):
+		rest := strings.TrimPrefix(s, "tls://")
+		addr, name, _ := strings.Cut(rest, "@")
+		host, port, err := splitHostPort(addr, 853)
+		if err != nil {
+			return Upstream{}, fmt.Errorf(i18n.T("dns.error.upstream"), s)
+		}
+		if net.ParseIP(host) == nil {
+			// tls://dns.google — адрес неизвестен, имя используется и для проверки сертификата
+			return Upstream{}, fmt.Errorf(i18n.T("dns.error.upstream_ip"), s)
+		}
+		return Upstream{Proto: ProtoDoT, Address: host, Port: port, TLSName: name}, nil
+	}
+
+	host, port, err := splitHostPort(s, 53)
+	if err != nil || net.ParseIP(host) == nil {
+		return Upstream{}, fmt.Errorf(i18n.T("dns.error.upstream"), s)
+	}
+	return Upstream{Proto: ProtoPlain, Address: host, Port: port}, nil
+}
+
+// String возвращает запись сервера в формате ParseUpstream
+func (u Upstream) String() string {
+	switch u.Proto {
+	case ProtoDoH:
+		return u.URL
+	case ProtoDoT:
+		s := "tls://" + hostPort(u.Address, u.Port, 853)
+		if u.TLSName != "" {
+			s += "@" + u.TLSName
+		}
+		return s
+	}
+	return hostPort(u.Address, u.Port, 53)
+}
+
+// Dial возвращает адрес для подключения host:port
+func (u Upstream) Dial() string {
+	port := u.Port
+	if port == 0 {
+		port = 53
+		if u.Proto == ProtoDoT {
+			port = 853
+		}
+	}
+	return net.JoinHostPort(u.Address, strconv.Itoa(port))
+}
+
+// Stamp возвращает штамп DNS Stamps (sdns://) для DoH-сервера, нужный dnscrypt-proxy
+func (u Upstream) Stamp() (string, error) {
+	parsed, err := url.Parse(u.URL)
+	if err != nil || u.Proto != ProtoDoH {
+		return "", fmt.Errorf(i18n.T("dns.error.upstream"), u.URL)
+	}
+
+	// Протокол 0x02 (DoH), свойства: DNSSEC не гарантируется, журнал ведётся, фильтров нет
+	buf := []byte{0x02}
+	buf = binary.LittleEndian.AppendUint64(buf, 0)
+	addr := ""
+	if host := parsed.Hostname(); net.ParseIP(host) != nil {
+		addr = host
+	}
+	buf = appendLP(buf, addr)
+	buf = append(buf, 0) // Пустой список хешей сертификатов
+	buf = appendLP(buf, parsed.Host)
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/dns-query"
+	}
+	buf = appendLP(buf, path)
+	return "sdns://" + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// appendLP добавляет строку с префиксом длины
+func appendLP(buf []byte, s string) []byte {
+	return append(append(buf, byte(len(s))), s...)
+}
+
+func splitHostPort(s string, defaultPort int) (string, int, error) {
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return "", 0, fmt.Errorf("invalid port %q", port)
+		}
+		return host, p, nil
+	}
+	return strings.Trim(s, "[]"), defaultPort, nil
+}
+
+func hostPort(host string, port, defaultPort int) string {
+	if port == 0 || port == defaultPort {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
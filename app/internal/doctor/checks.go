@@ -0,0 +1,292 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/buildinfo"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+const (
+	opkgBinary = "/opt/bin/opkg"
+	opkgConfig = "/opt/etc/opkg.conf"
+	// minSaneYear год, раньше которого системные часы считаются не настроенными
+	minSaneYear = 2024
+	// maxClockSkew допустимое расхождение часов роутера и машины, с которой выполняется проверка
+	maxClockSkew = 5 * time.Minute
+)
+
+// result создаёт результат проверки с локализованным заголовком
+func result(id string, status Status, detail string) Result {
+	r := Result{
+		ID:     id,
+		Title:  i18n.T("doctor.check." + id),
+		Status: status,
+		Detail: detail,
+	}
+	if status != StatusPass {
+		r.Fix = i18n.T("doctor.fix." + id)
+	}
+	return r
+}
+
+// checkEntware проверяет наличие Entware (opkg в /opt)
+func checkEntware(_ context.Context, opts Options) Result {
+	if _, err := opts.Runner.RunCommand("test -x " + opkgBinary); err != nil {
+		return result("entware", StatusFail, i18n.T("doctor.detail.entware_missing", opkgBinary))
+	}
+	return result("entware", StatusPass, opkgBinary)
+}
+
+// checkOptMount проверяет, что /opt смонтирован как отдельный раздел
+func checkOptMount(_ context.Context, opts Options) Result {
+	output, err := opts.Runner.RunCommand("cat /proc/mounts")
+	if err != nil {
+		return result("opt_mount", StatusWarn, err.Error())
+	}
+	if device, fsType, ok := FindMount(output, "/opt"); ok {
+		return result("opt_mount", StatusPass, fmt.Sprintf("%s (%s)", device, fsType))
+	}
+	return result("opt_mount", StatusWarn, i18n.T("doctor.detail.opt_not_mounted"))
+}
+
+// FindMount ищет точку монтирования target в содержимом /proc/mounts
+func FindMount(mounts, target string) (device, fsType string, ok bool) {
+	for _, line := range strings.Split(mounts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[1] == target {
+			device, fsType, ok = fields[0], fields[2], true
+		}
+	}
+	return device, fsType, ok
+}
+
+// checkFreeSpace проверяет свободное место в /opt
+func checkFreeSpace(_ context.Context, opts Options) Result {
+	output, err := opts.Runner.RunCommand("df -k /opt 2>/dev/null || df -k /")
+	if err != nil {
+		return result("free_space", StatusWarn, err.Error())
+	}
+	freeKB, err := ParseDFAvailable(output)
+	if err != nil {
+		return result("free_space", StatusWarn, err.Error())
+	}
+
+	freeMB := int(freeKB / 1024)
+	detail := i18n.T("doctor.detail.free_space", freeMB)
+	switch {
+	case freeMB < opts.FailFreeMB:
+		return result("free_space", StatusFail, detail)
+	case freeMB < opts.WarnFreeMB:
+		return result("free_space", StatusWarn, detail)
+	}
+	return result("free_space", StatusPass, detail)
+}
+
+// ParseDFAvailable возвращает свободное место (в KB) из вывода df -k.
+// Учитывает перенос строки busybox при длинном имени устройства.
+func ParseDFAvailable(output string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf(i18n.T("doctor.error.df_format"), output)
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, fmt.Errorf(i18n.T("doctor.error.df_format"), output)
+	}
+	value, err := strconv.ParseInt(fields[len(fields)-3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf(i18n.T("doctor.error.df_format"), output)
+	}
+	return value, nil
+}
+
+// checkFeeds проверяет доступность репозиториев opkg
+func checkFeeds(ctx context.Context, opts Options) Result {
+	feeds := opts.Feeds
+	if len(feeds) == 0 {
+		if content, err := opts.Runner.RunCommand("cat " + opkgConfig); err == nil {
+			feeds = ParseFeeds(content)
+		}
+	}
+	if len(feeds) == 0 {
+		return result("feeds", StatusWarn, i18n.T("doctor.detail.feeds_none", opkgConfig))
+	}
+
+	var unreachable []string
+	for _, feed := range feeds {
+		if err := probeFeed(ctx, opts, feed); err != nil {
+			unreachable = append(unreachable, feed)
+		}
+	}
+
+	switch {
+	case len(unreachable) == 0:
+		return result("feeds", StatusPass, i18n.T("doctor.detail.feeds_ok", len(feeds)))
+	case len(unreachable) == len(feeds):
+		return result("feeds", StatusFail, i18n.T("doctor.detail.feeds_unreachable", strings.Join(unreachable, ", ")))
+	}
+	return result("feeds", StatusWarn, i18n.T("doctor.detail.feeds_unreachable", strings.Join(unreachable, ", ")))
+}
+
+// ParseFeeds извлекает адреса репозиториев из opkg.conf (строки src и src/gz)
+func ParseFeeds(content string) []string {
+	var feeds []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && (fields[0] == "src" || fields[0] == "src/gz") {
+			feeds = append(feeds, strings.TrimRight(fields[2], "/"))
+		}
+	}
+	return feeds
+}
+
+// probeFeed проверяет наличие индекса пакетов в репозитории
+func probeFeed(ctx context.Context, opts Options, feed string) error {
+	target := strings.TrimRight(feed, "/") + "/Packages.gz"
+
+	if !utils.IsLocal(opts.Runner) {
+		_, err := opts.Runner.RunCommand(fmt.Sprintf("wget -q -T %d --spider %s",
+			int(opts.Timeout.Seconds()), utils.ShellQuote(target)))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s: %s", target, resp.Status)
+	}
+	return nil
+}
+
+// checkUtilities проверяет наличие обязательных утилит
+func checkUtilities(_ context.Context, opts Options) Result {
+	var missing, packages []string
+	for _, util := range utils.RequiredUtilities {
+		if _, err := opts.Runner.RunCommand("which " + util.Name); err != nil {
+			missing = append(missing, util.Name)
+			packages = append(packages, util.Package)
+		}
+	}
+	if len(missing) == 0 {
+		return result("utilities", StatusPass, i18n.T("doctor.detail.utilities_ok", len(utils.RequiredUtilities)))
+	}
+	r := result("utilities", StatusWarn, i18n.T("doctor.detail.utilities_missing", strings.Join(missing, ", ")))
+	r.Fix = i18n.T("doctor.fix.utilities", strings.Join(packages, " "))
+	return r
+}
+
+// checkConfigPath проверяет, что конфигурация хранится в постоянном и доступном для записи каталоге
+func checkConfigPath(_ context.Context, opts Options) Result {
+	if opts.ConfigPath == "" {
+		return result("config_path", StatusWarn, i18n.T("doctor.detail.path_unknown"))
+	}
+	dir := filepath.Dir(opts.ConfigPath)
+	if _, err := opts.Runner.RunCommand("test -w " + utils.ShellQuote(dir)); err != nil {
+		return result("config_path", StatusFail, i18n.T("doctor.detail.path_not_writable", dir))
+	}
+	if isTemporary(opts.ConfigPath) {
+		return result("config_path", StatusWarn, i18n.T("doctor.detail.path_temporary", opts.ConfigPath))
+	}
+	return result("config_path", StatusPass, opts.ConfigPath)
+}
+
+// checkLogPath проверяет, что каталог лога доступен для записи
+func checkLogPath(_ context.Context, opts Options) Result {
+	if opts.LogPath == "" {
+		return result("log_path", StatusWarn, i18n.T("doctor.detail.path_unknown"))
+	}
+	dir := filepath.Dir(opts.LogPath)
+	if _, err := opts.Runner.RunCommand("test -w " + utils.ShellQuote(dir)); err != nil {
+		return result("log_path", StatusFail, i18n.T("doctor.detail.path_not_writable", dir))
+	}
+	return result("log_path", StatusPass, opts.LogPath)
+}
+
+// isTemporary сообщает, находится ли путь во временном каталоге, очищаемом при перезагрузке
+func isTemporary(path string) bool {
+	for _, dir := range []string{os.TempDir(), "/tmp"} {
+		if strings.HasPrefix(filepath.Clean(path), filepath.Clean(dir)+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClock проверяет, что системные часы установлены
+func checkClock(_ context.Context, opts Options) Result {
+	output, err := opts.Runner.RunCommand("date +%s")
+	if err != nil {
+		return result("clock", StatusWarn, err.Error())
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return result("clock", StatusWarn, err.Error())
+	}
+
+	remote := time.Unix(seconds, 0)
+	detail := remote.Format(time.RFC3339)
+	if remote.Year() < minSaneYear {
+		return result("clock", StatusFail, detail)
+	}
+	if !utils.IsLocal(opts.Runner) {
+		skew := opts.Now().Sub(remote)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > maxClockSkew {
+			return result("clock", StatusWarn, i18n.T("doctor.detail.clock_skew", detail, skew.Round(time.Second)))
+		}
+	}
+	return result("clock", StatusPass, detail)
+}
+
+// checkDNS проверяет разрешение имён
+func checkDNS(ctx context.Context, opts Options) Result {
+	if !utils.IsLocal(opts.Runner) {
+		if _, err := opts.Runner.RunCommand("nslookup " + utils.ShellQuote(opts.DNSHost)); err != nil {
+			return result("dns", StatusFail, i18n.T("doctor.detail.dns_failed", opts.DNSHost))
+		}
+		return result("dns", StatusPass, opts.DNSHost)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	addrs, err := opts.Lookup(ctx, opts.DNSHost)
+	if err != nil || len(addrs) == 0 {
+		return result("dns", StatusFail, i18n.T("doctor.detail.dns_failed", opts.DNSHost))
+	}
+	return result("dns", StatusPass, fmt.Sprintf("%s → %s", opts.DNSHost, strings.Join(addrs, ", ")))
+}
+
+// checkPackage сравнивает версию запущенного terem с установленным пакетом opkg
+func checkPackage(_ context.Context, opts Options) Result {
+	if opts.Version == "" {
+		return result("package", StatusPass, i18n.T("doctor.detail.package_skipped"))
+	}
+	pkg, ok := buildinfo.PackageVersion(opts.Runner, buildinfo.AppName)
+	if !ok {
+		return result("package", StatusPass, i18n.T("doctor.detail.package_none", opts.Version))
+	}
+	if !buildinfo.Matches(pkg, opts.Version) {
+		return result("package", StatusWarn, i18n.T("doctor.detail.package_mismatch", pkg, opts.Version))
+	}
+	return result("package", StatusPass, pkg)
+}
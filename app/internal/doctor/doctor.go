@@ -0,0 +1,125 @@
+// Package doctor проверяет окружение роутера (Entware, /opt, репозитории, утилиты, пути, часы, DNS)
+// и выдаёт результат pass/warn/fail с рекомендациями по исправлению.
+package doctor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Status результат отдельной проверки
+type Status string
+
+const (
+	StatusPass Status = "pass" // проверка пройдена
+	StatusWarn Status = "warn" // работа возможна, но есть замечания
+	StatusFail Status = "fail" // проверка не пройдена
+)
+
+// Result описывает результат одной проверки
+type Result struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// Options параметры проверок. Нулевые значения заменяются значениями по умолчанию.
+type Options struct {
+	Runner     utils.Runner  // Где выполнять команды (локально или на роутере по SSH)
+	Feeds      []string      // Адреса репозиториев opkg; пусто — читаются из opkg.conf
+	ConfigPath string        // Путь до файла конфигурации terem
+	LogPath    string        // Путь до файла лога terem
+	Version    string        // Версия запущенного terem; пусто — сравнение с пакетом opkg пропускается
+	WarnFreeMB int           // Порог свободного места для предупреждения
+	FailFreeMB int           // Порог свободного места для ошибки
+	DNSHost    string        // Имя для проверки DNS
+	Timeout    time.Duration // Тайм-аут сетевых проверок
+	HTTPClient *http.Client  // HTTP-клиент для локальной проверки репозиториев
+	// Lookup выполняет DNS-запрос при локальной проверке
+	Lookup func(ctx context.Context, host string) ([]string, error)
+	// Now возвращает текущее время (для проверки часов)
+	Now func() time.Time
+}
+
+// Check описывает отдельную проверку
+type Check struct {
+	ID  string
+	Run func(ctx context.Context, opts Options) Result
+}
+
+// Checks возвращает список проверок в порядке выполнения
+func Checks() []Check {
+	return []Check{
+		{ID: "entware", Run: checkEntware},
+		{ID: "opt_mount", Run: checkOptMount},
+		{ID: "free_space", Run: checkFreeSpace},
+		{ID: "feeds", Run: checkFeeds},
+		{ID: "utilities", Run: checkUtilities},
+		{ID: "config_path", Run: checkConfigPath},
+		{ID: "log_path", Run: checkLogPath},
+		{ID: "clock", Run: checkClock},
+		{ID: "dns", Run: checkDNS},
+		{ID: "package", Run: checkPackage},
+	}
+}
+
+// WithDefaults возвращает копию параметров с заполненными значениями по умолчанию
+func (o Options) WithDefaults() Options {
+	if o.Runner == nil {
+		o.Runner = utils.Local{}
+	}
+	if o.WarnFreeMB == 0 {
+		o.WarnFreeMB = 50
+	}
+	if o.FailFreeMB == 0 {
+		o.FailFreeMB = 10
+	}
+	if o.DNSHost == "" {
+		o.DNSHost = "bin.entware.net"
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{Timeout: o.Timeout}
+	}
+	if o.Lookup == nil {
+		o.Lookup = net.DefaultResolver.LookupHost
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// Run выполняет все проверки последовательно
+func Run(ctx context.Context, opts Options) []Result {
+	opts = opts.WithDefaults()
+	checks := Checks()
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, check.Run(ctx, opts))
+	}
+	return results
+}
+
+// Count возвращает количество результатов каждого статуса
+func Count(results []Result) (pass, warn, fail int) {
+	for _, r := range results {
+		switch r.Status {
+		case StatusPass:
+			pass++
+		case StatusWarn:
+			warn++
+		case StatusFail:
+			fail++
+		}
+	}
+	return pass, warn, fail
+}
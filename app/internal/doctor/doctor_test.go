@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+// newRunner возвращает runner, отвечающий заданным выводом по началу команды;
+// остальные команды завершаются ошибкой
+func newRunner(outputs map[string]string) *testutil.Runner {
+	return &testutil.Runner{Outputs: outputs, Strict: true}
+}
+
+func TestParseDFAvailable(t *testing.T) {
+	wrapped := "Filesystem           1K-blocks      Used Available Use% Mounted on\n" +
+		"/dev/sda1-very-long-device-name\n" +
+		"                       7736432    512340   6814172   7% /opt"
+	got, err := ParseDFAvailable(wrapped)
+	if err != nil || got != 6814172 {
+		t.Fatalf("expected 6814172, got %d (%v)", got, err)
+	}
+
+	if _, err := ParseDFAvailable("garbage"); err == nil {
+		t.Fatal("expected error for malformed output")
+	}
+}
+
+func TestParseFeeds(t *testing.T) {
+	conf := "src/gz entware http://bin.entware.net/mipselsf-k3.4/\n# src/gz old http://old\nsrc keendev http://example.org/keenetic\ndest root /\n"
+	feeds := ParseFeeds(conf)
+	if len(feeds) != 2 || feeds[0] != "http://bin.entware.net/mipselsf-k3.4" || feeds[1] != "http://example.org/keenetic" {
+		t.Fatalf("unexpected feeds: %v", feeds)
+	}
+}
+
+func TestRunWithLocalMirror(t *testing.T) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/good/Packages.gz" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(mirror.Close)
+
+	runner := newRunner(map[string]string{
+		"test -x /opt/bin/opkg": "",
+		"cat /proc/mounts":      "rootfs / rootfs rw 0 0\n/dev/sda1 /opt ext4 rw 0 0\n",
+		"df -k":                 "Filesystem 1K-blocks Used Available Use% Mounted on\n/dev/sda1 100000 90000 20480 90% /opt",
+		"which curl":            "/opt/bin/curl",
+		"which wget":            "/opt/bin/wget",
+		"which nc":              "/opt/bin/nc",
+		"which ipset":           "/opt/sbin/ipset",
+		"test -w":               "",
+		"date +%s":              "1760000000",
+	})
+
+	// Локальный runner нужен для HTTP-проверки зеркала, поэтому подменяем только команды
+	opts := Options{
+		Runner:     localFake{runner},
+		Feeds:      []string{mirror.URL + "/good", mirror.URL + "/bad"},
+		ConfigPath: "/opt/etc/terem/config.yaml",
+		LogPath:    "/tmp/terem.log",
+		Timeout:    time.Second,
+		Lookup: func(ctx context.Context, host string) ([]string, error) {
+			return []string{"192.0.2.1"}, nil
+		},
+	}
+
+	results := map[string]Result{}
+	for _, r := range Run(context.Background(), opts) {
+		results[r.ID] = r
+	}
+
+	expect := map[string]Status{
+		"entware":     StatusPass,
+		"opt_mount":   StatusPass,
+		"free_space":  StatusWarn,
+		"feeds":       StatusWarn,
+		"utilities":   StatusWarn,
+		"config_path": StatusPass,
+		"log_path":    StatusPass,
+		"clock":       StatusPass,
+		"dns":         StatusPass,
+	}
+	for id, status := range expect {
+		if results[id].Status != status {
+			t.Errorf("%s: expected %s, got %s (%s)", id, status, results[id].Status, results[id].Detail)
+		}
+	}
+
+	if !strings.Contains(results["utilities"].Fix, "iptables") {
+		t.Errorf("expected fix to suggest installing iptables, got %q", results["utilities"].Fix)
+	}
+	if !strings.Contains(results["feeds"].Detail, "/bad") {
+		t.Errorf("expected unreachable feed in detail, got %q", results["feeds"].Detail)
+	}
+}
+
+func TestClockNotSet(t *testing.T) {
+	r := checkClock(context.Background(), Options{Runner: newRunner(map[string]string{"date": "86400"})}.WithDefaults())
+	if r.Status != StatusFail || r.Fix == "" {
+		t.Fatalf("expected failing clock check with fix, got %+v", r)
+	}
+}
+
+// localFake выдаёт себя за локальный runner, чтобы сетевые проверки шли через Go-клиент
+type localFake struct{ *testutil.Runner }
+
+func (localFake) Local() bool { return true }
+
+func TestPackageVersion(t *testing.T) {
+	opts := Options{Runner: newRunner(map[string]string{"opkg list-installed": "terem - 1.1.0-1\n"}), Version: "1.2.0"}.WithDefaults()
+	if r := checkPackage(context.Background(), opts); r.Status != StatusWarn || !strings.Contains(r.Detail, "1.1.0-1") {
+		t.Fatalf("expected mismatch warning, got %+v", r)
+	}
+	opts.Version = "1.1.0"
+	if r := checkPackage(context.Background(), opts); r.Status != StatusPass {
+		t.Fatalf("expected matching package, got %+v", r)
+	}
+}
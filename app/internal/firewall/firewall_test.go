@@ -0,0 +1,234 @@
+package firewall
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+const sampleSave = `# Generated by iptables-save v1.8.7 on Sun Oct 19 12:00:00 2026
+*nat
+:PREROUTING ACCEPT [120:7200]
+:POSTROUTING ACCEPT [0:0]
+[3:180] -A PREROUTING -i eth3 -p tcp -m tcp --dport 8080 -m comment --comment "terem: tcp 8080 → 192.168.1.10:80" -j DNAT --to-destination 192.168.1.10:80
+[0:0] -A POSTROUTING -o eth3 -j MASQUERADE
+COMMIT
+*filter
+:INPUT DROP [1000:64000]
+:FORWARD ACCEPT [0:0]
+:_NDM_SL_PROTECT - [0:0]
+[900:60000] -A INPUT -m state --state RELATED,ESTABLISHED -j ACCEPT
+[10:600] -A INPUT -j _NDM_SL_PROTECT
+[2:120] -A FORWARD -d 192.168.1.10/32 -p tcp -m tcp --dport 80 -m comment --comment "terem: tcp 8080 → 192.168.1.10:80" -j ACCEPT
+COMMIT
+`
+
+func TestParseSave(t *testing.T) {
+	rs, err := ParseSave(sampleSave, FamilyIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Tables) != 2 || rs.Tables[0].Name != "nat" || rs.Tables[1].Name != "filter" {
+		t.Fatalf("tables = %+v", rs.Tables)
+	}
+	filter := rs.Tables[1]
+	if len(filter.Chains) != 3 {
+		t.Fatalf("chains = %+v", filter.Chains)
+	}
+	input := filter.Chains[0]
+	if input.Policy != "DROP" || input.Packets != 1000 || input.Bytes != 64000 || len(input.Rules) != 2 {
+		t.Errorf("INPUT = %+v", input)
+	}
+	if custom := filter.Chains[2]; custom.Policy != "" || custom.Name != "_NDM_SL_PROTECT" {
+		t.Errorf("custom chain = %+v", custom)
+	}
+
+	dnat := rs.Tables[0].Chains[0].Rules[0]
+	if dnat.Comment != "terem: tcp 8080 → 192.168.1.10:80" || dnat.Target != "DNAT" || dnat.Packets != 3 || !dnat.Owned() {
+		t.Errorf("DNAT rule = %+v", dnat)
+	}
+	if !strings.HasPrefix(dnat.Text, "-i eth3 -p tcp") {
+		t.Errorf("rule text = %q", dnat.Text)
+	}
+
+	owned := Owned([]Ruleset{rs})
+	if len(owned) != 2 {
+		t.Errorf("owned = %+v", owned)
+	}
+
+	if _, err := ParseSave("-A INPUT -j ACCEPT\n", FamilyIPv4); err == nil {
+		t.Error("expected error for rule outside table")
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	got := SplitArgs(`-m comment --comment "say \"hi\" there" -j ACCEPT`)
+	want := []string{"-m", "comment", "--comment", `say "hi" there`, "-j", "ACCEPT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitArgs = %q", got)
+	}
+}
+
+const sampleNFT = `{"nftables": [
+ {"metainfo": {"version": "1.0.6", "json_schema_version": 1}},
+ {"table": {"family": "inet", "name": "fw4", "handle": 1}},
+ {"chain": {"family": "inet", "table": "fw4", "name": "input", "handle": 1, "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}},
+ {"rule": {"family": "inet", "table": "fw4", "chain": "input", "handle": 5, "expr": [
+   {"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "lo"}},
+   {"counter": {"packets": 42, "bytes": 4200}},
+   {"accept": null}]}},
+ {"rule": {"family": "inet", "table": "fw4", "chain": "input", "handle": 9, "comment": "terem: allow tcp 22", "expr": [
+   {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": {"set": [22, 2222]}}},
+   {"counter": {"packets": 1, "bytes": 60}},
+   {"jump": {"target": "accept_ssh"}}]}}
+]}`
+
+func TestParseNFT(t *testing.T) {
+	rs, err := ParseNFT([]byte(sampleNFT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Tables) != 1 || rs.Tables[0].Family != "inet" || len(rs.Tables[0].Chains) != 1 {
+		t.Fatalf("ruleset = %+v", rs)
+	}
+	chain := rs.Tables[0].Chains[0]
+	if chain.Policy != "drop" || len(chain.Rules) != 2 {
+		t.Fatalf("chain = %+v", chain)
+	}
+	if r := chain.Rules[0]; r.Text != "iifname lo counter accept" || r.Packets != 42 || r.Target != "accept" || r.Owned() {
+		t.Errorf("rule 0 = %+v", r)
+	}
+	r := chain.Rules[1]
+	if r.Text != `tcp dport { 22, 2222 } counter jump accept_ssh comment "terem: allow tcp 22"` || r.Target != "accept_ssh" || !r.Owned() {
+		t.Errorf("rule 1 = %+v", r)
+	}
+
+	if _, err := ParseNFT([]byte("not json")); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestRuleBuilders(t *testing.T) {
+	fwd := Forward{Proto: ProtoBoth, Port: 8080, To: "192.168.1.10", ToPort: 80}
+	if err := fwd.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	rules := fwd.Rules()
+	if len(rules) != 4 {
+		t.Fatalf("forward rules = %+v", rules)
+	}
+	if r := rules[0]; r.Table != "nat" || r.Chain != "PREROUTING" || r.Target != "DNAT" || !r.Owned() ||
+		!strings.Contains(r.Text, `--comment "terem: tcp+udp 8080 → 192.168.1.10:80"`) {
+		t.Errorf("DNAT rule = %+v", r)
+	}
+	if r := rules[1]; r.Table != "filter" || r.Chain != ChainForward || r.Target != "ACCEPT" {
+		t.Errorf("forward accept = %+v", r)
+	}
+	for _, bad := range []Forward{
+		{Proto: "icmp", Port: 80, To: "192.168.1.10"},
+		{Proto: ProtoTCP, Port: 0, To: "192.168.1.10"},
+		{Proto: ProtoTCP, Port: 80, To: "fd00::1"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+
+	deny := Filter{Chain: ChainInput, Proto: ProtoTCP, Port: 22}
+	if families := []string{deny.Rules()[0].Family, deny.Rules()[1].Family}; !reflect.DeepEqual(families, []string{FamilyIPv4, FamilyIPv6}) {
+		t.Errorf("filter without source families = %v", families)
+	}
+	allow := Filter{Allow: true, Chain: ChainInput, Proto: ProtoUDP, Port: 51820, Source: "2001:db8::1"}
+	if rules := allow.Rules(); len(rules) != 1 || rules[0].Family != FamilyIPv6 || rules[0].Args[1] != "2001:db8::1/128" {
+		t.Errorf("allow rules = %+v", rules)
+	}
+	if err := (Filter{Chain: "OUTPUT", Proto: ProtoTCP, Port: 22}).Validate(); err == nil {
+		t.Error("expected chain error")
+	}
+	if err := (Filter{Chain: ChainInput, Proto: ProtoTCP, Port: 22, Source: "lan"}).Validate(); err == nil {
+		t.Error("expected source error")
+	}
+
+	mark := Mark{Mark: 1001, Set: "vpn", Note: "vpn wg0"}
+	if err := mark.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if r := mark.Rules()[0]; r.Table != "mangle" || r.Target != "MARK" || r.Comment != "terem: vpn wg0" ||
+		!strings.HasSuffix(r.Text, "--set-mark 1001") {
+		t.Errorf("mark rule = %+v", r)
+	}
+	for _, bad := range []Mark{{Mark: 1001}, {Mark: 1001, MAC: "aa:bb:cc:dd:ee:ff", Set: "vpn"}, {MAC: "aa:bb:cc:dd:ee:ff"}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+	if rules := (Masquerade{Iface: "wg0"}).Rules(); len(rules) != 2 || rules[0].Target != "MASQUERADE" || rules[1].Chain != ChainForward {
+		t.Errorf("masquerade rules = %+v", rules)
+	}
+}
+
+// newRunner имитирует iptables: выводит правила save, проверка -C успешна при exists,
+// добавление правил с подстрокой failAdd завершается ошибкой
+func newRunner(save string, exists bool, failAdd string) *testutil.Runner {
+	iptables := func(command string) (string, error) {
+		switch {
+		case strings.Contains(command, " -C ") && !exists:
+			return "", errors.New("exit status 1")
+		case failAdd != "" && strings.Contains(command, " -I ") && strings.Contains(command, failAdd):
+			return "iptables: No chain/target/match by that name.", errors.New("exit status 1")
+		}
+		return "", nil
+	}
+	return &testutil.Runner{
+		Outputs:  map[string]string{"iptables-save": save},
+		Handlers: map[string]func(string) (string, error){"iptables ": iptables, "ip6tables ": iptables},
+	}
+}
+
+func TestManager(t *testing.T) {
+	r := newRunner(sampleSave, false, "")
+	m := Manager{Runner: r}
+
+	sets, err := m.Load()
+	if err != nil || len(sets) != 1 {
+		t.Fatalf("Load = %+v, %v", sets, err)
+	}
+
+	removed, err := m.RemoveOwned()
+	if err != nil || removed != 2 {
+		t.Fatalf("RemoveOwned = %d, %v", removed, err)
+	}
+	want := `iptables -t nat -D 'PREROUTING' '-i' 'eth3' '-p' 'tcp' '-m' 'tcp' '--dport' '8080' '-m' 'comment' '--comment' 'terem: tcp 8080 → 192.168.1.10:80' '-j' 'DNAT' '--to-destination' '192.168.1.10:80'`
+	if len(r.Find(want)) != 1 || len(r.Find(" -D ")) != 2 {
+		t.Errorf("delete commands: %v", r.Commands)
+	}
+
+	// По пояснению удаляются только правила с совпадающим комментарием
+	if removed, err := m.RemoveNote("vpn wg0"); err != nil || removed != 0 {
+		t.Errorf("RemoveNote = %d, %v", removed, err)
+	}
+
+	// Чужие правила не удаляются
+	foreign := sets[0].Tables[0].Chains[1].Rules[0]
+	if err := m.Remove(foreign); err == nil {
+		t.Error("expected refusal to remove foreign rule")
+	}
+
+	r = newRunner("", false, "ACCEPT")
+	m = Manager{Runner: r}
+	if err := m.Add(Forward{Proto: ProtoTCP, Port: 8080, To: "192.168.1.10"}.Rules()); err == nil {
+		t.Fatal("expected add error")
+	}
+	if len(r.Find(" -I ")) != 2 || len(r.Find("iptables -t nat -D")) != 1 {
+		t.Errorf("expected rollback of DNAT rule: %v", r.Commands)
+	}
+
+	r = newRunner("", true, "")
+	m = Manager{Runner: r}
+	if err := m.Add(Filter{Chain: ChainInput, Proto: ProtoTCP, Port: 22}.Rules()); err != nil || len(r.Find(" -I ")) != 0 {
+		t.Errorf("existing rules must be skipped: %v, %v", err, r.Commands)
+	}
+}
@@ -0,0 +1,146 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Manager читает и изменяет правила межсетевого экрана
+type Manager struct {
+	Runner utils.Runner
+}
+
+// tool возвращает утилиту для семейства правил
+func tool(family string) string {
+	if family == FamilyIPv6 {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+// Load читает правила iptables, а также ip6tables и nftables, если они есть на роутере
+func (m Manager) Load() ([]Ruleset, error) {
+	output, err := utils.OrLocal(m.Runner).RunCommand("iptables-save -c 2>/dev/null")
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("firewall.error.save"), err)
+	}
+	v4, err := ParseSave(output, FamilyIPv4)
+	if err != nil {
+		return nil, err
+	}
+	sets := []Ruleset{v4}
+
+	if output, err := utils.OrLocal(m.Runner).RunCommand("command -v ip6tables-save >/dev/null 2>&1 && ip6tables-save -c 2>/dev/null || true"); err == nil && strings.TrimSpace(output) != "" {
+		v6, err := ParseSave(output, FamilyIPv6)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, v6)
+	}
+
+	if output, err := utils.OrLocal(m.Runner).RunCommand("command -v nft >/dev/null 2>&1 && nft -j list ruleset 2>/dev/null || true"); err == nil && strings.TrimSpace(output) != "" {
+		nft, err := ParseNFT([]byte(output))
+		if err != nil {
+			return nil, err
+		}
+		if len(nft.Tables) > 0 {
+			sets = append(sets, nft)
+		}
+	}
+	return sets, nil
+}
+
+// command возвращает команду iptables с действием action (-C, -I, -D) для правила
+func command(r Rule, action string) string {
+	parts := []string{tool(r.Family), "-t", r.Table, action, utils.ShellQuote(r.Chain)}
+	for _, a := range r.Args {
+		parts = append(parts, utils.ShellQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Exists проверяет наличие правила командой iptables -C
+func (m Manager) Exists(r Rule) bool {
+	_, err := utils.OrLocal(m.Runner).RunCommand(command(r, "-C") + " 2>/dev/null")
+	return err == nil
+}
+
+// Add добавляет правила в начало цепочек; уже существующие правила пропускаются.
+// При ошибке добавленные правила удаляются.
+func (m Manager) Add(rules []Rule) error {
+	var added []Rule
+	for _, r := range rules {
+		if !r.Owned() {
+			return fmt.Errorf(i18n.T("firewall.error.not_owned"), r.Text)
+		}
+		if m.Exists(r) {
+			continue
+		}
+		if output, err := utils.OrLocal(m.Runner).RunCommand(command(r, "-I") + " 2>&1"); err != nil {
+			for _, a := range added {
+				_ = m.Remove(a)
+			}
+			return fmt.Errorf(i18n.T("firewall.error.add"), r.Text, strings.TrimSpace(output+" "+err.Error()))
+		}
+		added = append(added, r)
+	}
+	return nil
+}
+
+// Remove удаляет правило; удалять можно только правила терема
+func (m Manager) Remove(r Rule) error {
+	if !r.Owned() {
+		return fmt.Errorf(i18n.T("firewall.error.not_owned"), r.Text)
+	}
+	cmd := command(r, "-D")
+	if r.Family == FamilyNFT {
+		family, table, _ := strings.Cut(r.Table, " ")
+		cmd = fmt.Sprintf("nft delete rule %s %s %s handle %s",
+			utils.ShellQuote(family), utils.ShellQuote(table), utils.ShellQuote(r.Chain), strconv.Itoa(r.Handle))
+	}
+	if output, err := utils.OrLocal(m.Runner).RunCommand(cmd + " 2>&1"); err != nil {
+		return fmt.Errorf(i18n.T("firewall.error.remove"), r.Text, strings.TrimSpace(output+" "+err.Error()))
+	}
+	return nil
+}
+
+// RemoveOwned удаляет все правила терема и возвращает число удалённых правил
+func (m Manager) RemoveOwned() (int, error) {
+	return m.removeWhere(func(Rule) bool { return true })
+}
+
+// RemoveNote удаляет правила терема с пояснением note в комментарии
+// и возвращает число удалённых правил
+func (m Manager) RemoveNote(note string) (int, error) {
+	return m.removeWhere(func(r Rule) bool { return r.Comment == comment(note) })
+}
+
+// removeWhere удаляет правила терема, отобранные match.
+// Правила iptables удаляются первыми: при iptables-nft они видны и в nft,
+// поэтому правила nftables удаляются по заново прочитанному набору.
+func (m Manager) removeWhere(match func(Rule) bool) (int, error) {
+	removed := 0
+	var errs []error
+	for _, nft := range []bool{false, true} {
+		sets, err := m.Load()
+		if err != nil {
+			return removed, err
+		}
+		for _, r := range Owned(sets) {
+			if (r.Family == FamilyNFT) != nft || !match(r) {
+				continue
+			}
+			if err := m.Remove(r); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, errors.Join(errs...)
+}
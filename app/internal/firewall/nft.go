@@ -0,0 +1,172 @@
+package firewall
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// nftObject элемент массива nftables из вывода nft -j list ruleset
+type nftObject struct {
+	Table *struct {
+		Family string `json:"family"`
+		Name   string `json:"name"`
+	} `json:"table"`
+	Chain *struct {
+		Family string `json:"family"`
+		Table  string `json:"table"`
+		Name   string `json:"name"`
+		Policy string `json:"policy"`
+	} `json:"chain"`
+	Rule *struct {
+		Family  string           `json:"family"`
+		Table   string           `json:"table"`
+		Chain   string           `json:"chain"`
+		Handle  int              `json:"handle"`
+		Comment string           `json:"comment"`
+		Expr    []map[string]any `json:"expr"`
+	} `json:"rule"`
+}
+
+// ParseNFT разбирает вывод nft -j list ruleset
+func ParseNFT(data []byte) (Ruleset, error) {
+	var doc struct {
+		Nftables []nftObject `json:"nftables"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Ruleset{}, fmt.Errorf(i18n.T("firewall.error.nft"), err)
+	}
+
+	rs := Ruleset{Family: FamilyNFT}
+	table := func(family, name string) *Table {
+		for i := range rs.Tables {
+			if rs.Tables[i].Family == family && rs.Tables[i].Name == name {
+				return &rs.Tables[i]
+			}
+		}
+		rs.Tables = append(rs.Tables, Table{Name: name, Family: family})
+		return &rs.Tables[len(rs.Tables)-1]
+	}
+
+	for _, obj := range doc.Nftables {
+		switch {
+		case obj.Table != nil:
+			table(obj.Table.Family, obj.Table.Name)
+		case obj.Chain != nil:
+			c := table(obj.Chain.Family, obj.Chain.Table).chain(obj.Chain.Name)
+			c.Policy = obj.Chain.Policy
+		case obj.Rule != nil:
+			r := Rule{
+				Family:  FamilyNFT,
+				Table:   obj.Rule.Family + " " + obj.Rule.Table,
+				Chain:   obj.Rule.Chain,
+				Handle:  obj.Rule.Handle,
+				Comment: obj.Rule.Comment,
+			}
+			parts := make([]string, 0, len(obj.Rule.Expr))
+			for _, expr := range obj.Rule.Expr {
+				parts = append(parts, nftStatement(expr, &r))
+			}
+			r.Text = strings.Join(parts, " ")
+			if r.Comment != "" {
+				r.Text += fmt.Sprintf(" comment %q", r.Comment)
+			}
+			c := table(obj.Rule.Family, obj.Rule.Table).chain(r.Chain)
+			c.Rules = append(c.Rules, r)
+		}
+	}
+	return rs, nil
+}
+
+// nftStatement возвращает текст выражения правила и заполняет счётчики и действие
+func nftStatement(expr map[string]any, r *Rule) string {
+	for key, value := range expr {
+		switch key {
+		case "match":
+			m, _ := value.(map[string]any)
+			left, right := nftOperand(m["left"]), nftOperand(m["right"])
+			if op, _ := m["op"].(string); op != "" && op != "==" && op != "in" {
+				return left + " " + op + " " + right
+			}
+			return left + " " + right
+		case "counter":
+			m, _ := value.(map[string]any)
+			packets, _ := m["packets"].(float64)
+			bytes, _ := m["bytes"].(float64)
+			r.Counters = Counters{Packets: uint64(packets), Bytes: uint64(bytes)}
+			return "counter"
+		case "accept", "drop", "return", "continue", "reject", "masquerade":
+			r.Target = key
+			return key
+		case "jump", "goto":
+			m, _ := value.(map[string]any)
+			target, _ := m["target"].(string)
+			r.Target = target
+			return key + " " + target
+		case "dnat", "snat":
+			m, _ := value.(map[string]any)
+			r.Target = key
+			to := nftOperand(m["addr"])
+			if port, ok := m["port"]; ok {
+				to += ":" + nftOperand(port)
+			}
+			return key + " to " + to
+		default:
+			return key
+		}
+	}
+	return ""
+}
+
+// nftOperand возвращает текст операнда выражения nftables
+func nftOperand(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, nftOperand(item))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	case map[string]any:
+		if p, ok := v["payload"].(map[string]any); ok {
+			return nftOperand(p["protocol"]) + " " + nftOperand(p["field"])
+		}
+		if m, ok := v["meta"].(map[string]any); ok {
+			return nftOperand(m["key"])
+		}
+		if ct, ok := v["ct"].(map[string]any); ok {
+			return "ct " + nftOperand(ct["key"])
+		}
+		if p, ok := v["prefix"].(map[string]any); ok {
+			return nftOperand(p["addr"]) + "/" + nftOperand(p["len"])
+		}
+		if rng, ok := v["range"].([]any); ok && len(rng) == 2 {
+			return nftOperand(rng[0]) + "-" + nftOperand(rng[1])
+		}
+		if set, ok := v["set"]; ok {
+			if s, ok := set.(string); ok {
+				return s // Именованный набор: @name
+			}
+			return nftOperand(set)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return strings.Join(keys, " ")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,274 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Протоколы правил терема
+const (
+	ProtoTCP  = "tcp"
+	ProtoUDP  = "udp"
+	ProtoBoth = "tcp+udp"
+)
+
+// Цепочки, в которые терем добавляет правила разрешения и запрета
+const (
+	ChainInput   = "INPUT"   // Доступ к самому роутеру
+	ChainForward = "FORWARD" // Транзитный трафик
+)
+
+// comment возвращает комментарий с меткой терема
+func comment(note string) string {
+	if note = strings.TrimSpace(note); note == "" {
+		return Tag
+	}
+	return Tag + ": " + note
+}
+
+// protocols раскрывает ProtoBoth в tcp и udp
+func protocols(proto string) []string {
+	if proto == ProtoBoth {
+		return []string{ProtoTCP, ProtoUDP}
+	}
+	return []string{proto}
+}
+
+func validProto(proto string) bool {
+	return proto == ProtoTCP || proto == ProtoUDP || proto == ProtoBoth
+}
+
+func validPort(port int) bool {
+	return port >= 1 && port <= 65535
+}
+
+// Forward проброс порта роутера на адрес в локальной сети (DNAT)
+type Forward struct {
+	Proto  string `json:"proto"`           // ProtoTCP, ProtoUDP или ProtoBoth
+	Port   int    `json:"port"`            // Внешний порт
+	To     string `json:"to"`              // IPv4-адрес в локальной сети
+	ToPort int    `json:"toPort"`          // Порт назначения; 0 — равен внешнему
+	Iface  string `json:"iface,omitempty"` // Входящий интерфейс; пусто — любой
+	Note   string `json:"note,omitempty"`  // Пояснение в комментарии правила
+}
+
+// Validate проверяет параметры проброса
+func (f Forward) Validate() error {
+	if !validProto(f.Proto) {
+		return fmt.Errorf(i18n.T("firewall.error.proto"), f.Proto)
+	}
+	if !validPort(f.Port) {
+		return fmt.Errorf(i18n.T("firewall.error.port"), f.Port)
+	}
+	if f.ToPort != 0 && !validPort(f.ToPort) {
+		return fmt.Errorf(i18n.T("firewall.error.port"), f.ToPort)
+	}
+	if addr, err := netip.ParseAddr(f.To); err != nil || !addr.Is4() {
+		return fmt.Errorf(i18n.T("firewall.error.forward_addr"), f.To)
+	}
+	return nil
+}
+
+// Rules возвращает правила проброса: DNAT в nat/PREROUTING и разрешение в filter/FORWARD
+func (f Forward) Rules() []Rule {
+	toPort := f.ToPort
+	if toPort == 0 {
+		toPort = f.Port
+	}
+	note := f.Note
+	if note == "" {
+		note = fmt.Sprintf("%s %d → %s:%d", f.Proto, f.Port, f.To, toPort)
+	}
+
+	var rules []Rule
+	for _, proto := range protocols(f.Proto) {
+		var match []string
+		if f.Iface != "" {
+			match = append(match, "-i", f.Iface)
+		}
+		dnat := append(match, "-p", proto, "--dport", strconv.Itoa(f.Port),
+			"-m", "comment", "--comment", comment(note),
+			"-j", "DNAT", "--to-destination", fmt.Sprintf("%s:%d", f.To, toPort))
+		accept := []string{"-d", f.To, "-p", proto, "--dport", strconv.Itoa(toPort),
+			"-m", "comment", "--comment", comment(note), "-j", "ACCEPT"}
+		rules = append(rules,
+			newRule(FamilyIPv4, "nat", "PREROUTING", dnat),
+			newRule(FamilyIPv4, "filter", ChainForward, accept))
+	}
+	return rules
+}
+
+// Filter правило разрешения или запрета доступа
+type Filter struct {
+	Allow  bool   `json:"allow"`
+	Chain  string `json:"chain"`            // ChainInput или ChainForward
+	Proto  string `json:"proto"`            // ProtoTCP, ProtoUDP или ProtoBoth
+	Port   int    `json:"port"`             // Порт назначения
+	Source string `json:"source,omitempty"` // Адрес или подсеть источника; пусто — любой
+	Note   string `json:"note,omitempty"`
+}
+
+// Validate проверяет параметры правила
+func (f Filter) Validate() error {
+	if f.Chain != ChainInput && f.Chain != ChainForward {
+		return fmt.Errorf(i18n.T("firewall.error.chain"), f.Chain)
+	}
+	if !validProto(f.Proto) {
+		return fmt.Errorf(i18n.T("firewall.error.proto"), f.Proto)
+	}
+	if !validPort(f.Port) {
+		return fmt.Errorf(i18n.T("firewall.error.port"), f.Port)
+	}
+	if _, err := f.source(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// source разбирает адрес источника; пустой адрес означает любой
+func (f Filter) source() (netip.Prefix, error) {
+	if f.Source == "" {
+		return netip.Prefix{}, nil
+	}
+	if p, err := netip.ParsePrefix(f.Source); err == nil {
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(f.Source)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf(i18n.T("firewall.error.source"), f.Source)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Rules возвращает правила для iptables и ip6tables; источник определяет семейство,
+// без источника правило добавляется в оба
+func (f Filter) Rules() []Rule {
+	target, verb := "DROP", "deny"
+	if f.Allow {
+		target, verb = "ACCEPT", "allow"
+	}
+	note := f.Note
+	if note == "" {
+		note = fmt.Sprintf("%s %s %d", verb, f.Proto, f.Port)
+		if f.Source != "" {
+			note += " from " + f.Source
+		}
+	}
+
+	families := []string{FamilyIPv4, FamilyIPv6}
+	src, _ := f.source()
+	if src.IsValid() {
+		families = []string{FamilyIPv4}
+		if src.Addr().Is6() {
+			families = []string{FamilyIPv6}
+		}
+	}
+
+	var rules []Rule
+	for _, family := range families {
+		for _, proto := range protocols(f.Proto) {
+			var args []string
+			if src.IsValid() {
+				args = append(args, "-s", src.String())
+			}
+			args = append(args, "-p", proto, "--dport", strconv.Itoa(f.Port),
+				"-m", "comment", "--comment", comment(note), "-j", target)
+			rules = append(rules, newRule(family, "filter", f.Chain, args))
+		}
+	}
+	return rules
+}
+
+// Mark маркировка транзитных пакетов в mangle/PREROUTING для маршрутизации по отдельной таблице
+type Mark struct {
+	Mark  int    `json:"mark"`            // Значение метки (fwmark)
+	MAC   string `json:"mac,omitempty"`   // MAC-адрес источника
+	Set   string `json:"set,omitempty"`   // Список ipset адресов назначения
+	Iface string `json:"iface,omitempty"` // Входящий интерфейс; пусто — любой
+	Note  string `json:"note,omitempty"`
+}
+
+// Validate проверяет параметры маркировки: нужен ровно один признак — MAC-адрес или список
+func (m Mark) Validate() error {
+	if m.Mark <= 0 {
+		return fmt.Errorf(i18n.T("firewall.error.mark"), m.Mark)
+	}
+	if (m.MAC == "") == (m.Set == "") {
+		return errors.New(i18n.T("firewall.error.mark_match"))
+	}
+	if m.MAC != "" {
+		if _, err := net.ParseMAC(m.MAC); err != nil {
+			return fmt.Errorf(i18n.T("firewall.error.mac"), m.MAC)
+		}
+	}
+	return nil
+}
+
+// Rules возвращает правило маркировки для iptables
+func (m Mark) Rules() []Rule {
+	note := m.Note
+	if note == "" {
+		note = fmt.Sprintf("mark %d", m.Mark)
+	}
+	var args []string
+	if m.Iface != "" {
+		args = append(args, "-i", m.Iface)
+	}
+	if m.MAC != "" {
+		args = append(args, "-m", "mac", "--mac-source", strings.ToUpper(m.MAC))
+	} else {
+		args = append(args, "-m", "set", "--match-set", m.Set, "dst")
+	}
+	args = append(args, "-m", "comment", "--comment", comment(note),
+		"-j", "MARK", "--set-mark", strconv.Itoa(m.Mark))
+	return []Rule{newRule(FamilyIPv4, "mangle", "PREROUTING", args)}
+}
+
+// Masquerade выход локальной сети через интерфейс: подмена адреса источника
+// в nat/POSTROUTING и разрешение транзита в filter/FORWARD
+type Masquerade struct {
+	Iface string `json:"iface"`
+	Note  string `json:"note,omitempty"`
+}
+
+// Rules возвращает правила выхода через интерфейс
+func (m Masquerade) Rules() []Rule {
+	note := m.Note
+	if note == "" {
+		note = "masquerade " + m.Iface
+	}
+	return []Rule{
+		newRule(FamilyIPv4, "nat", "POSTROUTING",
+			[]string{"-o", m.Iface, "-m", "comment", "--comment", comment(note), "-j", "MASQUERADE"}),
+		newRule(FamilyIPv4, "filter", ChainForward,
+			[]string{"-o", m.Iface, "-m", "comment", "--comment", comment(note), "-j", "ACCEPT"}),
+	}
+}
+
+// newRule создаёт правило iptables с текстом в формате iptables-save
+func newRule(family, table, chain string, args []string) Rule {
+	r := Rule{Family: family, Table: table, Chain: chain, Args: args}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = a
+		if strings.ContainsAny(a, " \t\"") {
+			quoted[i] = strconv.Quote(a)
+		}
+		if i > 0 {
+			switch args[i-1] {
+			case "-j":
+				r.Target = a
+			case "--comment":
+				r.Comment = a
+			}
+		}
+	}
+	r.Text = strings.Join(quoted, " ")
+	return r
+}
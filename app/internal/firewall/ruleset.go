@@ -0,0 +1,216 @@
+// Package firewall читает правила iptables, ip6tables и nftables в виде дерева
+// «таблица → цепочка → правило» со счётчиками, добавляет проброс портов и правила
+// разрешения/запрета с меткой терема и удаляет только собственные правила терема.
+package firewall
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Семейства наборов правил
+const (
+	FamilyIPv4 = "ipv4" // iptables
+	FamilyIPv6 = "ipv6" // ip6tables
+	FamilyNFT  = "nft"  // nftables
+)
+
+// Tag метка в комментарии правил, добавленных теремом
+const Tag = "terem"
+
+// Counters счётчики пакетов и байт
+type Counters struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// Rule правило цепочки
+type Rule struct {
+	Family  string   `json:"family"`
+	Table   string   `json:"table"`
+	Chain   string   `json:"chain"`
+	Args    []string `json:"args,omitempty"`   // Условия и действие iptables после имени цепочки
+	Handle  int      `json:"handle,omitempty"` // Номер правила nftables
+	Text    string   `json:"text"`             // Правило в виде, близком к выводу утилиты
+	Target  string   `json:"target,omitempty"`
+	Comment string   `json:"comment,omitempty"`
+	Counters
+}
+
+// Owned сообщает, добавлено ли правило теремом
+func (r Rule) Owned() bool {
+	return r.Comment == Tag || strings.HasPrefix(r.Comment, Tag+":")
+}
+
+// Chain цепочка правил
+type Chain struct {
+	Name   string `json:"name"`
+	Policy string `json:"policy,omitempty"` // Политика встроенной цепочки; пусто для пользовательских
+	Counters
+	Rules []Rule `json:"rules"`
+}
+
+// Table таблица правил
+type Table struct {
+	Name   string  `json:"name"`
+	Family string  `json:"family,omitempty"` // Семейство таблицы nftables (ip, ip6, inet…)
+	Chains []Chain `json:"chains"`
+}
+
+// Ruleset набор правил одного семейства
+type Ruleset struct {
+	Family string  `json:"family"`
+	Tables []Table `json:"tables"`
+}
+
+// Rules возвращает все правила набора
+func (rs Ruleset) Rules() []Rule {
+	var rules []Rule
+	for _, t := range rs.Tables {
+		for _, c := range t.Chains {
+			rules = append(rules, c.Rules...)
+		}
+	}
+	return rules
+}
+
+// Owned возвращает правила терема из всех наборов
+func Owned(sets []Ruleset) []Rule {
+	var owned []Rule
+	for _, rs := range sets {
+		for _, r := range rs.Rules() {
+			if r.Owned() {
+				owned = append(owned, r)
+			}
+		}
+	}
+	return owned
+}
+
+// chain возвращает цепочку таблицы, создавая её при необходимости
+func (t *Table) chain(name string) *Chain {
+	for i := range t.Chains {
+		if t.Chains[i].Name == name {
+			return &t.Chains[i]
+		}
+	}
+	t.Chains = append(t.Chains, Chain{Name: name})
+	return &t.Chains[len(t.Chains)-1]
+}
+
+// ParseSave разбирает вывод iptables-save или ip6tables-save (со счётчиками -c или без них)
+func ParseSave(content, family string) (Ruleset, error) {
+	rs := Ruleset{Family: family}
+	var table *Table
+	for n, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fail := func() (Ruleset, error) {
+			return Ruleset{}, fmt.Errorf(i18n.T("firewall.error.parse"), n+1, line)
+		}
+
+		switch {
+		case strings.HasPrefix(line, "*"):
+			rs.Tables = append(rs.Tables, Table{Name: line[1:]})
+			table = &rs.Tables[len(rs.Tables)-1]
+		case line == "COMMIT":
+			table = nil
+		case table == nil:
+			return fail()
+		case strings.HasPrefix(line, ":"):
+			// :INPUT ACCEPT [12:3456]
+			fields := strings.Fields(line[1:])
+			if len(fields) < 2 {
+				return fail()
+			}
+			c := table.chain(fields[0])
+			if fields[1] != "-" {
+				c.Policy = fields[1]
+			}
+			if len(fields) > 2 {
+				c.Counters, _ = parseCounters(fields[2])
+			}
+		default:
+			// [5:300] -A INPUT -p tcp -j ACCEPT
+			var counters Counters
+			if strings.HasPrefix(line, "[") {
+				end := strings.IndexByte(line, ']')
+				if end < 0 {
+					return fail()
+				}
+				counters, _ = parseCounters(line[:end+1])
+				line = strings.TrimSpace(line[end+1:])
+			}
+			args := SplitArgs(line)
+			if len(args) < 2 || args[0] != "-A" {
+				return fail()
+			}
+			r := Rule{
+				Family:   family,
+				Table:    table.Name,
+				Chain:    args[1],
+				Args:     args[2:],
+				Text:     strings.TrimSpace(strings.TrimPrefix(line, "-A "+args[1])),
+				Counters: counters,
+			}
+			for i := 0; i+1 < len(r.Args); i++ {
+				switch r.Args[i] {
+				case "-j", "-g", "--jump", "--goto":
+					r.Target = r.Args[i+1]
+				case "--comment":
+					r.Comment = r.Args[i+1]
+				}
+			}
+			c := table.chain(r.Chain)
+			c.Rules = append(c.Rules, r)
+		}
+	}
+	return rs, nil
+}
+
+// parseCounters разбирает счётчики вида [пакеты:байты]
+func parseCounters(s string) (Counters, bool) {
+	packets, bytes, ok := strings.Cut(strings.Trim(s, "[]"), ":")
+	if !ok {
+		return Counters{}, false
+	}
+	p, err1 := strconv.ParseUint(packets, 10, 64)
+	b, err2 := strconv.ParseUint(bytes, 10, 64)
+	return Counters{Packets: p, Bytes: b}, err1 == nil && err2 == nil
+}
+
+// SplitArgs разбивает строку правила на аргументы с учётом кавычек, как это делает iptables-restore
+func SplitArgs(line string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuotes, started := false, false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '\\' && inQuotes && i+1 < len(line):
+			i++
+			cur.WriteByte(line[i])
+		case ch == '"':
+			inQuotes = !inQuotes
+			started = true
+		case (ch == ' ' || ch == '\t') && !inQuotes:
+			if started {
+				args = append(args, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteByte(ch)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, cur.String())
+	}
+	return args
+}
@@ -0,0 +1,253 @@
+package ipset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// maxSourceSize ограничение размера загружаемого списка
+const maxSourceSize = 32 << 20
+
+// resolveWorkers число одновременных DNS-запросов при разрешении доменов
+const resolveWorkers = 8
+
+// Parsed записи, найденные в источнике
+type Parsed struct {
+	Entries []string // Адреса и подсети
+	Domains []string // Домены, которые нужно разрешить в адреса
+	Skipped int      // Строки, которые не удалось разобрать
+}
+
+// sinkholes адреса-заглушки из hosts-файлов блокировщиков
+var sinkholes = map[string]bool{"0.0.0.0": true, "127.0.0.1": true, "::": true, "::1": true}
+
+// ParseEntries разбирает список: по одной записи в строке (адрес, подсеть или домен),
+// поддерживаются комментарии #, ; и //, а также строки hosts-файлов «0.0.0.0 domain»
+func ParseEntries(content, family string) Parsed {
+	var p Parsed
+	seen := map[string]bool{}
+	addEntry := func(e string) {
+		if !seen[e] {
+			seen[e] = true
+			p.Entries = append(p.Entries, e)
+		}
+	}
+	addDomain := func(d string) {
+		d = strings.ToLower(strings.TrimSuffix(d, "."))
+		if !seen[d] {
+			seen[d] = true
+			p.Domains = append(p.Domains, d)
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		for _, mark := range []string{"#", ";", "//"} {
+			if i := strings.Index(line, mark); i >= 0 {
+				line = line[:i]
+			}
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// hosts-файл: «0.0.0.0 example.com» — берём домен
+		if len(fields) >= 2 && sinkholes[fields[0]] {
+			for _, d := range fields[1:] {
+				if isDomain(d) {
+					addDomain(d)
+				}
+			}
+			continue
+		}
+
+		value := fields[0]
+		if entry, err := NormalizeEntry(value, family); err == nil {
+			addEntry(entry)
+		} else if isAddress(value) {
+			continue // Адрес другого семейства
+		} else if isDomain(value) {
+			addDomain(value)
+		} else {
+			p.Skipped++
+		}
+	}
+	return p
+}
+
+// isAddress сообщает, является ли строка адресом или подсетью любого семейства
+func isAddress(s string) bool {
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// isDomain проверяет, похожа ли строка на доменное имя
+func isDomain(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if len(s) == 0 || len(s) > 253 || !strings.Contains(s, ".") {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// LookupFunc разрешает домен в адреса; network — ip4 или ip6
+type LookupFunc func(ctx context.Context, network, host string) ([]netip.Addr, error)
+
+// Importer загружает источники и собирает из них записи списка
+type Importer struct {
+	Runner     utils.Runner // Для чтения локальных файлов на роутере
+	HTTPClient *http.Client
+	Lookup     LookupFunc // По умолчанию net.DefaultResolver.LookupNetIP
+	Timeout    time.Duration
+}
+
+func (im Importer) timeout() time.Duration {
+	if im.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return im.Timeout
+}
+
+// Fetch возвращает содержимое источника: адрес http(s):// или путь к файлу на роутере
+func (im Importer) Fetch(ctx context.Context, source string) (string, error) {
+	source = strings.TrimSpace(source)
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return service.ReadFile(im.Runner, strings.TrimPrefix(source, "file://"))
+	}
+
+	client := im.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: im.timeout()}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", fmt.Errorf(i18n.T("ipset.error.fetch"), source, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf(i18n.T("ipset.error.fetch"), source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(i18n.T("ipset.error.fetch"), source, resp.Status)
+	}
+	return readLimited(resp.Body, source, maxSourceSize)
+}
+
+// readLimited читает список не больше limit байт; список сверх предела не обрезается,
+// а отклоняется, чтобы не загрузить его частично
+func readLimited(r io.Reader, source string, limit int64) (string, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return "", fmt.Errorf(i18n.T("ipset.error.fetch"), source, err)
+	}
+	if int64(len(body)) > limit {
+		return "", fmt.Errorf(i18n.T("ipset.error.size"), source, limit>>20)
+	}
+	return string(body), nil
+}
+
+// Resolve разрешает домены в адреса семейства family; возвращает адреса и число неразрешённых доменов
+func (im Importer) Resolve(ctx context.Context, domains []string, family string) ([]string, int) {
+	lookup := im.Lookup
+	if lookup == nil {
+		lookup = defaultLookup
+	}
+	network := "ip4"
+	if family == FamilyIPv6 {
+		network = "ip6"
+	}
+
+	var mu sync.Mutex
+	var entries []string
+	failed := 0
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range min(resolveWorkers, len(domains)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range jobs {
+				lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				addrs, err := lookup(lctx, network, domain)
+				cancel()
+				mu.Lock()
+				if err != nil || len(addrs) == 0 {
+					failed++
+				}
+				for _, a := range addrs {
+					entries = append(entries, a.Unmap().String())
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, d := range domains {
+		jobs <- d
+	}
+	close(jobs)
+	wg.Wait()
+
+	slices.Sort(entries)
+	return slices.Compact(entries), failed
+}
+
+// Result итог сборки записей из источников
+type Result struct {
+	Entries    []string `json:"-"`
+	Count      int      `json:"count"`      // Записей в списке
+	Domains    int      `json:"domains"`    // Разрешено доменов
+	Unresolved int      `json:"unresolved"` // Домены без адресов
+	Skipped    int      `json:"skipped"`    // Нераспознанные строки
+}
+
+// Collect загружает все источники, разрешает домены и возвращает записи без повторов
+func (im Importer) Collect(ctx context.Context, sources []string, family string) (Result, error) {
+	var res Result
+	var domains []string
+	for _, source := range sources {
+		content, err := im.Fetch(ctx, source)
+		if err != nil {
+			return Result{}, err
+		}
+		p := ParseEntries(content, family)
+		res.Entries = append(res.Entries, p.Entries...)
+		domains = append(domains, p.Domains...)
+		res.Skipped += p.Skipped
+	}
+	slices.Sort(domains)
+	domains = slices.Compact(domains)
+
+	resolved, failed := im.Resolve(ctx, domains, family)
+	res.Entries = append(res.Entries, resolved...)
+	res.Domains = len(domains) - failed
+	res.Unresolved = failed
+
+	slices.Sort(res.Entries)
+	res.Entries = slices.Compact(res.Entries)
+	res.Count = len(res.Entries)
+	return res, nil
+}
@@ -0,0 +1,253 @@
+// Package ipset управляет именованными списками ipset: создание и удаление, добавление
+// и удаление записей, импорт адресов из файлов и по HTTP (подсети, адреса и домены),
+// периодическое обновление и восстановление содержимого после перезагрузки.
+package ipset
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Семейства адресов списков
+const (
+	FamilyIPv4 = "inet"
+	FamilyIPv6 = "inet6"
+)
+
+// SetType тип списков терема: подсети и отдельные адреса
+const SetType = "hash:net"
+
+// defaultMaxElem ёмкость списка по умолчанию (как у ipset)
+const defaultMaxElem = 65536
+
+// namePattern допустимые имена списков (ipset ограничивает длину 31 символом).
+// Точка не допускается: run-parts пропускает сценарии cron с точкой в имени
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,31}$`)
+
+// ValidateName проверяет имя списка
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf(i18n.T("ipset.error.name"), name)
+	}
+	return nil
+}
+
+// Info сведения о списке из ipset list -t
+type Info struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Family     string `json:"family,omitempty"`
+	Entries    int    `json:"entries"`
+	References int    `json:"references"` // Сколько правил iptables ссылаются на список
+}
+
+// ParseList разбирает вывод ipset list -t
+func ParseList(output string) []Info {
+	var sets []Info
+	var cur *Info
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			sets = append(sets, Info{Name: value})
+			cur = &sets[len(sets)-1]
+		case "Type":
+			if cur != nil {
+				cur.Type = value
+			}
+		case "Header":
+			if cur != nil {
+				fields := strings.Fields(value)
+				for i := 0; i+1 < len(fields); i++ {
+					if fields[i] == "family" {
+						cur.Family = fields[i+1]
+					}
+				}
+			}
+		case "References":
+			if cur != nil {
+				cur.References, _ = strconv.Atoi(value)
+			}
+		case "Number of entries":
+			if cur != nil {
+				cur.Entries, _ = strconv.Atoi(value)
+			}
+		}
+	}
+	return sets
+}
+
+// ParseSave возвращает записи списка из вывода ipset save
+func ParseSave(output, name string) []string {
+	var entries []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "add" && fields[1] == name {
+			entries = append(entries, fields[2])
+		}
+	}
+	return entries
+}
+
+// NormalizeEntry проверяет адрес или подсеть и приводит запись к виду, который хранит ipset
+func NormalizeEntry(entry, family string) (string, error) {
+	entry = strings.TrimSpace(entry)
+	var prefix netip.Prefix
+	if p, err := netip.ParsePrefix(entry); err == nil {
+		prefix = p.Masked()
+	} else if addr, err := netip.ParseAddr(entry); err == nil {
+		prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+	} else {
+		return "", fmt.Errorf(i18n.T("ipset.error.entry"), entry)
+	}
+	if prefix.Addr().Is6() != (family == FamilyIPv6) {
+		return "", fmt.Errorf(i18n.T("ipset.error.family"), entry, family)
+	}
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String(), nil
+	}
+	return prefix.String(), nil
+}
+
+// Manager выполняет команды ipset
+type Manager struct {
+	Runner utils.Runner
+	Dir    string // Каталог файлов сохранения; по умолчанию SaveDir
+}
+
+func (m Manager) run(command string) (string, error) {
+	return utils.RunChecked(m.Runner, "ipset.error.command", command)
+}
+
+// List возвращает все списки ipset на роутере
+func (m Manager) List() ([]Info, error) {
+	output, err := m.run("ipset list -t")
+	if err != nil {
+		return nil, err
+	}
+	return ParseList(output), nil
+}
+
+// Create создаёт список, если его ещё нет
+func (m Manager) Create(name, family string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if family != FamilyIPv4 && family != FamilyIPv6 {
+		return fmt.Errorf(i18n.T("ipset.error.family_name"), family)
+	}
+	_, err := m.run(fmt.Sprintf("ipset create %s %s family %s -exist", utils.ShellQuote(name), SetType, family))
+	return err
+}
+
+// Destroy удаляет список и его файл сохранения
+func (m Manager) Destroy(name string) error {
+	if _, err := m.run("ipset destroy " + utils.ShellQuote(name)); err != nil {
+		return err
+	}
+	_, err := m.run("rm -f " + utils.ShellQuote(m.saveFile(name)))
+	return err
+}
+
+// Entries возвращает записи списка
+func (m Manager) Entries(name string) ([]string, error) {
+	output, err := m.run("ipset save " + utils.ShellQuote(name))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSave(output, name), nil
+}
+
+// Add добавляет запись в список
+func (m Manager) Add(name, family, entry string) (string, error) {
+	entry, err := NormalizeEntry(entry, family)
+	if err != nil {
+		return "", err
+	}
+	_, err = m.run(fmt.Sprintf("ipset add -exist %s %s", utils.ShellQuote(name), utils.ShellQuote(entry)))
+	return entry, err
+}
+
+// Remove удаляет запись из списка
+func (m Manager) Remove(name, family, entry string) error {
+	entry, err := NormalizeEntry(entry, family)
+	if err != nil {
+		return err
+	}
+	_, err = m.run(fmt.Sprintf("ipset del -exist %s %s", utils.ShellQuote(name), utils.ShellQuote(entry)))
+	return err
+}
+
+// tempName возвращает имя временного списка для атомарной замены содержимого
+func tempName(name string) string {
+	if len(name) > 27 {
+		name = name[:27]
+	}
+	return name + "_new"
+}
+
+// Replace атомарно заменяет содержимое списка: записи загружаются во временный список,
+// который затем меняется местами с рабочим (ipset swap)
+func (m Manager) Replace(name, family string, entries []string) error {
+	if err := m.Create(name, family); err != nil {
+		return err
+	}
+	tmp := tempName(name)
+	maxElem := max(defaultMaxElem, len(entries)+len(entries)/4)
+
+	var script strings.Builder
+	fmt.Fprintf(&script, "create %s %s family %s maxelem %d -exist\nflush %s\n", tmp, SetType, family, maxElem, tmp)
+	for _, e := range entries {
+		fmt.Fprintf(&script, "add %s %s -exist\n", tmp, e)
+	}
+
+	file := "/tmp/terem-ipset-" + name + ".restore"
+	defer func() { _, _ = utils.OrLocal(m.Runner).RunCommand("rm -f " + utils.ShellQuote(file)) }()
+	if err := writeChunks(utils.OrLocal(m.Runner), file, script.String()); err != nil {
+		return err
+	}
+	if _, err := m.run("ipset restore -exist < " + utils.ShellQuote(file)); err != nil {
+		_, _ = utils.OrLocal(m.Runner).RunCommand("ipset destroy " + utils.ShellQuote(tmp))
+		return err
+	}
+	if _, err := m.run(fmt.Sprintf("ipset swap %s %s", utils.ShellQuote(tmp), utils.ShellQuote(name))); err != nil {
+		_, _ = utils.OrLocal(m.Runner).RunCommand("ipset destroy " + utils.ShellQuote(tmp))
+		return err
+	}
+	_, err := m.run("ipset destroy " + utils.ShellQuote(tmp))
+	return err
+}
+
+// chunkSize размер части файла в одной команде: один аргумент sh ограничен 128 КБ
+const chunkSize = 32 * 1024
+
+// writeChunks записывает файл через runner частями, не упираясь в ограничение длины аргумента
+func writeChunks(runner utils.Runner, file, content string) error {
+	redirect := ">"
+	for len(content) > 0 || redirect == ">" {
+		part := content
+		if len(part) > chunkSize {
+			part = part[:strings.LastIndexByte(part[:chunkSize], '\n')+1]
+			if part == "" {
+				part = content[:chunkSize]
+			}
+		}
+		content = content[len(part):]
+		command := fmt.Sprintf("printf '%%s' %s %s %s", utils.ShellQuote(part), redirect, utils.ShellQuote(file))
+		if _, err := runner.RunCommand(command); err != nil {
+			return fmt.Errorf(i18n.T("service.error.write"), file, err)
+		}
+		redirect = ">>"
+	}
+	return nil
+}
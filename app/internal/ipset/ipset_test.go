@@ -0,0 +1,197 @@
+package ipset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+const sampleList = `Name: vpn
+Type: hash:net
+Revision: 7
+Header: family inet hashsize 1024 maxelem 65536 bucketsize 12 initval 0x5f1c2d3e
+Size in memory: 1240
+References: 2
+Number of entries: 3
+Members:
+
+Name: vpn6
+Type: hash:net
+Revision: 7
+Header: family inet6 hashsize 1024 maxelem 65536
+Size in memory: 1240
+References: 0
+Number of entries: 0
+Members:
+`
+
+func TestParseListAndSave(t *testing.T) {
+	sets := ParseList(sampleList)
+	want := []Info{
+		{Name: "vpn", Type: "hash:net", Family: FamilyIPv4, Entries: 3, References: 2},
+		{Name: "vpn6", Type: "hash:net", Family: FamilyIPv6},
+	}
+	if !reflect.DeepEqual(sets, want) {
+		t.Errorf("ParseList = %+v", sets)
+	}
+
+	save := "create vpn hash:net family inet hashsize 1024 maxelem 65536\nadd vpn 10.0.0.0/8\nadd vpn 1.1.1.1\nadd other 2.2.2.2\n"
+	if got := ParseSave(save, "vpn"); !reflect.DeepEqual(got, []string{"10.0.0.0/8", "1.1.1.1"}) {
+		t.Errorf("ParseSave = %v", got)
+	}
+}
+
+func TestNormalizeEntry(t *testing.T) {
+	for in, want := range map[string]string{
+		"10.1.2.3/8":     "10.0.0.0/8",
+		"192.168.1.5/32": "192.168.1.5",
+		" 1.1.1.1 ":      "1.1.1.1",
+	} {
+		if got, err := NormalizeEntry(in, FamilyIPv4); err != nil || got != want {
+			t.Errorf("NormalizeEntry(%q) = %q, %v", in, got, err)
+		}
+	}
+	if got, err := NormalizeEntry("2001:db8::1/48", FamilyIPv6); err != nil || got != "2001:db8::/48" {
+		t.Errorf("IPv6 = %q, %v", got, err)
+	}
+	for _, bad := range []string{"example.com", "2001:db8::1", "300.1.1.1"} {
+		if _, err := NormalizeEntry(bad, FamilyIPv4); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+	for _, bad := range []string{"with space", "with.dot"} {
+		if err := ValidateName(bad); err == nil {
+			t.Errorf("%q: expected name error", bad)
+		}
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	content := `# blocklist
+10.0.0.0/8
+1.1.1.1 ; cloudflare
+2001:db8::/32
+0.0.0.0 ads.example.com tracker.example.com
+example.org // comment
+EXAMPLE.org.
+not_a_domain
+10.0.0.0/8
+`
+	p := ParseEntries(content, FamilyIPv4)
+	if !reflect.DeepEqual(p.Entries, []string{"10.0.0.0/8", "1.1.1.1"}) {
+		t.Errorf("entries = %v", p.Entries)
+	}
+	if !reflect.DeepEqual(p.Domains, []string{"ads.example.com", "tracker.example.com", "example.org"}) {
+		t.Errorf("domains = %v", p.Domains)
+	}
+	if p.Skipped != 1 {
+		t.Errorf("skipped = %d", p.Skipped)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/list.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "203.0.113.0/24\nservice.example\nmissing.example\n")
+	}))
+	defer srv.Close()
+
+	im := Importer{
+		Runner: &testutil.Runner{Files: map[string]string{"/opt/etc/lists/local.txt": "198.51.100.7\n203.0.113.9/24\n"}},
+		Lookup: func(_ context.Context, network, host string) ([]netip.Addr, error) {
+			if network != "ip4" {
+				t.Errorf("network = %s", network)
+			}
+			if host == "service.example" {
+				return []netip.Addr{netip.MustParseAddr("192.0.2.10"), netip.MustParseAddr("192.0.2.11")}, nil
+			}
+			return nil, errors.New("no such host")
+		},
+	}
+	res, err := im.Collect(context.Background(), []string{srv.URL + "/list.txt", "file:///opt/etc/lists/local.txt"}, FamilyIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"192.0.2.10", "192.0.2.11", "198.51.100.7", "203.0.113.0/24"}
+	if !reflect.DeepEqual(res.Entries, want) || res.Count != 4 || res.Domains != 1 || res.Unresolved != 1 {
+		t.Errorf("Collect = %+v", res)
+	}
+
+	if _, err := im.Collect(context.Background(), []string{srv.URL + "/missing"}, FamilyIPv4); err == nil {
+		t.Error("expected fetch error")
+	}
+}
+
+func TestReadLimitedRejectsOversizedList(t *testing.T) {
+	if got, err := readLimited(strings.NewReader("10.0.0.1\n"), "list", 9); err != nil || got != "10.0.0.1\n" {
+		t.Errorf("readLimited = %q, %v", got, err)
+	}
+	if _, err := readLimited(strings.NewReader("10.0.0.1\n10.0.0.2\n"), "list", 9); err == nil {
+		t.Error("expected oversized list to be rejected instead of truncated")
+	}
+}
+
+func TestReplaceAndSchedule(t *testing.T) {
+	r := &testutil.Runner{}
+	m := Manager{Runner: r}
+
+	entries := make([]string, 5000)
+	for i := range entries {
+		entries[i] = fmt.Sprintf("10.%d.%d.0/24", i/256, i%256)
+	}
+	if err := m.Replace("vpn", FamilyIPv4, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// Сценарий восстановления записан частями, каждая часть заканчивается целой строкой
+	writes := r.Find("printf '%s' ")
+	if len(writes) < 3 || !strings.Contains(writes[0], "' > '/tmp/terem-ipset-vpn.restore'") ||
+		!strings.Contains(writes[1], "' >> '/tmp/terem-ipset-vpn.restore'") {
+		t.Fatalf("chunked writes: %d", len(writes))
+	}
+	for _, w := range writes {
+		if len(w) > 2*chunkSize || !strings.Contains(w, "\n' >") {
+			t.Fatalf("bad chunk of %d bytes", len(w))
+		}
+	}
+	if !strings.Contains(writes[0], "create vpn_new hash:net family inet maxelem 65536 -exist") {
+		t.Errorf("restore header: %.120s", writes[0])
+	}
+	for _, want := range []string{"ipset restore -exist < '/tmp/terem-ipset-vpn.restore'", "ipset swap 'vpn_new' 'vpn'", "ipset destroy 'vpn_new'"} {
+		if len(r.Find(want)) != 1 {
+			t.Errorf("missing %q in %v", want, r.Find("ipset"))
+		}
+	}
+
+	r = &testutil.Runner{Files: map[string]string{}}
+	m = Manager{Runner: r}
+	if err := m.Schedule("vpn", RefreshDaily); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Find("rm -f '/opt/etc/cron.hourly/terem-ipset-vpn' '/opt/etc/cron.daily/terem-ipset-vpn'")) != 1 ||
+		len(r.Find("chmod +x '/opt/etc/cron.daily/terem-ipset-vpn'")) != 1 {
+		t.Errorf("schedule commands: %v", r.Commands)
+	}
+	if err := m.Schedule("vpn", "monthly"); err == nil {
+		t.Error("expected period error")
+	}
+
+	if err := m.Save("vpn"); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Find("mkdir -p '/opt/etc/terem/ipset' && ipset save 'vpn' > '/opt/etc/terem/ipset/vpn.ipset'.tmp")) != 1 ||
+		len(r.Find("chmod +x '"+InitScript+"'")) != 1 {
+		t.Errorf("save commands: %v", r.Commands)
+	}
+}
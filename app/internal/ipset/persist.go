@@ -0,0 +1,144 @@
+package ipset
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Пути сохранения и автозапуска
+const (
+	SaveDir    = "/opt/etc/terem/ipset"           // Файлы ipset save для восстановления после перезагрузки
+	InitScript = "/opt/etc/init.d/S20terem-ipset" // Восстанавливает списки при загрузке
+	CronDir    = "/opt/etc"                       // Каталоги cron.hourly, cron.daily и cron.weekly
+	cronPrefix = "terem-ipset-"                   // Префикс сценариев обновления в каталогах cron
+	saveSuffix = ".ipset"                         // Расширение файлов сохранения
+)
+
+// Периоды обновления списков
+const (
+	RefreshNever  = ""
+	RefreshHourly = "hourly"
+	RefreshDaily  = "daily"
+	RefreshWeekly = "weekly"
+)
+
+// RefreshPeriods периоды обновления в порядке показа в меню
+var RefreshPeriods = []string{RefreshNever, RefreshHourly, RefreshDaily, RefreshWeekly}
+
+// initScript восстанавливает сохранённые списки при запуске Entware
+const initScript = `#!/bin/sh
+# Создан теремом: восстанавливает списки ipset после перезагрузки
+case "$1" in
+	start|restart)
+		for f in ` + SaveDir + `/*` + saveSuffix + `; do
+			[ -f "$f" ] && ipset restore -exist < "$f"
+		done
+		;;
+esac
+`
+
+var defaultLookup LookupFunc = net.DefaultResolver.LookupNetIP
+
+func (m Manager) dir() string {
+	if m.Dir == "" {
+		return SaveDir
+	}
+	return m.Dir
+}
+
+func (m Manager) saveFile(name string) string {
+	return path.Join(m.dir(), name+saveSuffix)
+}
+
+// Save сохраняет содержимое списка в файл и устанавливает init-скрипт восстановления
+func (m Manager) Save(name string) error {
+	file := utils.ShellQuote(m.saveFile(name))
+	command := fmt.Sprintf("mkdir -p %s && ipset save %s > %s.tmp && mv %s.tmp %s",
+		utils.ShellQuote(m.dir()), utils.ShellQuote(name), file, file, file)
+	if _, err := m.run(command); err != nil {
+		return err
+	}
+	return m.EnsureInitScript()
+}
+
+// EnsureInitScript создаёт init-скрипт восстановления списков, если его нет
+func (m Manager) EnsureInitScript() error {
+	return service.EnsureScript(m.Runner, InitScript, initScript)
+}
+
+// Restore восстанавливает списки из всех файлов сохранения; возвращает число восстановленных списков
+func (m Manager) Restore() (int, error) {
+	output, err := utils.OrLocal(m.Runner).RunCommand("ls " + utils.ShellQuote(m.dir()) + "/*" + saveSuffix + " 2>/dev/null || true")
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, file := range strings.Fields(output) {
+		if _, err := m.run("ipset restore -exist < " + utils.ShellQuote(file)); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// Refresh собирает записи из источников, атомарно заменяет содержимое списка и сохраняет его
+func (m Manager) Refresh(ctx context.Context, im Importer, name, family string, sources []string) (Result, error) {
+	if len(sources) == 0 {
+		return Result{}, fmt.Errorf(i18n.T("ipset.error.no_sources"), name)
+	}
+	if im.Runner == nil {
+		im.Runner = m.Runner
+	}
+	res, err := im.Collect(ctx, sources, family)
+	if err != nil {
+		return res, err
+	}
+	if err := m.Replace(name, family, res.Entries); err != nil {
+		return res, err
+	}
+	return res, m.Save(name)
+}
+
+// cronScript возвращает путь к сценарию обновления списка для периода
+func cronScript(period, name string) string {
+	return path.Join(CronDir, "cron."+period, cronPrefix+name)
+}
+
+// Schedule задаёт период обновления списка: сценарий в каталоге cron.<period> Entware
+// вызывает terem ipset refresh; RefreshNever удаляет расписание
+func (m Manager) Schedule(name, period string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	var scripts []string
+	for _, p := range RefreshPeriods[1:] {
+		scripts = append(scripts, utils.ShellQuote(cronScript(p, name)))
+	}
+	if _, err := m.run("rm -f " + strings.Join(scripts, " ")); err != nil {
+		return err
+	}
+
+	switch period {
+	case RefreshNever:
+		return nil
+	case RefreshHourly, RefreshDaily, RefreshWeekly:
+	default:
+		return fmt.Errorf(i18n.T("ipset.error.period"), period)
+	}
+	file := cronScript(period, name)
+	content := fmt.Sprintf("#!/bin/sh\n# Создан теремом: обновление списка ipset %s\nexec %s ipset refresh %s >/dev/null 2>&1\n",
+		name, utils.ShellQuote(utils.Binary()), utils.ShellQuote(name))
+	if err := service.WriteFile(m.Runner, file, content); err != nil {
+		return err
+	}
+	_, err := m.run("chmod +x " + utils.ShellQuote(file) + " && rm -f " + utils.ShellQuote(file+".bak"))
+	return err
+}
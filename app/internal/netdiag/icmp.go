@@ -0,0 +1,86 @@
+// Package netdiag реализует сетевую диагностику без внешних утилит: ping по ICMP
+// (raw-сокет или непривилегированный ICMP-сокет), трассировку маршрута пакетами UDP,
+// непрерывную трассировку в духе mtr, запросы к DNS и замер скорости между роутером
+// и другим экземпляром терема, запущенным командой «terem net serve».
+package netdiag
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Типы сообщений ICMP
+const (
+	icmpEchoReply    = 0
+	icmpUnreachable  = 3
+	icmpEcho         = 8
+	icmpTimeExceeded = 11
+)
+
+// echo эхо-запрос или эхо-ответ ICMP
+type echo struct {
+	Type int
+	ID   int
+	Seq  int
+	Data []byte
+}
+
+// checksum контрольная сумма Интернета (RFC 1071)
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// marshal кодирует сообщение с контрольной суммой
+func (e echo) marshal() []byte {
+	b := make([]byte, 8+len(e.Data))
+	b[0] = byte(e.Type)
+	binary.BigEndian.PutUint16(b[4:], uint16(e.ID))
+	binary.BigEndian.PutUint16(b[6:], uint16(e.Seq))
+	copy(b[8:], e.Data)
+	binary.BigEndian.PutUint16(b[2:], checksum(b))
+	return b
+}
+
+// parseEcho разбирает эхо-сообщение ICMP без заголовка IP
+func parseEcho(b []byte) (echo, error) {
+	if len(b) < 8 {
+		return echo{}, errors.New(i18n.T("netdiag.error.short"))
+	}
+	return echo{
+		Type: int(b[0]),
+		ID:   int(binary.BigEndian.Uint16(b[4:])),
+		Seq:  int(binary.BigEndian.Uint16(b[6:])),
+		Data: b[8:],
+	}, nil
+}
+
+// resolve4 возвращает адрес IPv4 узла: сам адрес или первый из ответа DNS
+func resolve4(ctx context.Context, host string) (netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !addr.Unmap().Is4() {
+			return netip.Addr{}, fmt.Errorf(i18n.T("netdiag.error.ipv4"), host)
+		}
+		return addr.Unmap(), nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+	if err != nil || len(addrs) == 0 {
+		return netip.Addr{}, fmt.Errorf(i18n.T("netdiag.error.resolve"), host, err)
+	}
+	return addrs[0].Unmap(), nil
+}
@@ -0,0 +1,165 @@
+package netdiag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// LookupTypes типы записей, которые умеет запрашивать Resolver
+var LookupTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT", "PTR"}
+
+// defaultLookupTypes типы записей по умолчанию для имени
+var defaultLookupTypes = []string{"A", "AAAA", "CNAME", "MX"}
+
+// Record запись DNS
+type Record struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Lookup итог запросов к DNS
+type Lookup struct {
+	Name    string        `json:"name"`
+	Server  string        `json:"server"`
+	RTT     time.Duration `json:"rtt"`
+	Records []Record      `json:"records"`
+}
+
+// Resolver выполняет запросы к системному или указанному серверу DNS
+type Resolver struct {
+	Server  string        // Адрес сервера; пусто — системный
+	Timeout time.Duration // Ожидание каждого запроса; по умолчанию 5 с
+}
+
+// server возвращает адрес сервера с портом
+func (r Resolver) server() string {
+	if r.Server == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(r.Server); err == nil {
+		return r.Server
+	}
+	return net.JoinHostPort(r.Server, "53")
+}
+
+// resolver возвращает резолвер Go, направленный на сервер
+func (r Resolver) resolver() *net.Resolver {
+	server := r.server()
+	if server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// notFound сообщает, что записи такого типа у имени нет
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// Lookup запрашивает записи указанных типов; для адреса IP — только PTR.
+// Без типов запрашиваются A, AAAA, CNAME и MX
+func (r Resolver) Lookup(ctx context.Context, name string, types []string) (Lookup, error) {
+	result := Lookup{Name: name, Server: valueOrSystem(r.server())}
+	if _, err := netip.ParseAddr(name); err == nil {
+		types = []string{"PTR"}
+	} else if len(types) == 0 {
+		types = defaultLookupTypes
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	res := r.resolver()
+
+	var errs []error
+	start := time.Now()
+	for _, t := range types {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if !slices.Contains(LookupTypes, t) {
+			return result, fmt.Errorf(i18n.T("netdiag.error.type"), t, strings.Join(LookupTypes, ", "))
+		}
+		qctx, cancel := context.WithTimeout(ctx, timeout)
+		records, err := query(qctx, res, name, t)
+		cancel()
+		if err != nil && !notFound(err) {
+			errs = append(errs, err)
+		}
+		result.Records = append(result.Records, records...)
+	}
+	result.RTT = time.Since(start)
+	if len(result.Records) == 0 && len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// valueOrSystem возвращает адрес сервера или отметку системного резолвера
+func valueOrSystem(server string) string {
+	if server == "" {
+		return "system"
+	}
+	return server
+}
+
+// query запрашивает записи одного типа
+func query(ctx context.Context, res *net.Resolver, name, t string) ([]Record, error) {
+	var records []Record
+	add := func(value string) { records = append(records, Record{Type: t, Value: value}) }
+	switch t {
+	case "A", "AAAA":
+		network := "ip4"
+		if t == "AAAA" {
+			network = "ip6"
+		}
+		addrs, err := res.LookupNetIP(ctx, network, name)
+		for _, a := range addrs {
+			add(a.Unmap().String())
+		}
+		return records, err
+	case "CNAME":
+		cname, err := res.LookupCNAME(ctx, name)
+		if err == nil && !strings.EqualFold(strings.TrimSuffix(cname, "."), strings.TrimSuffix(name, ".")) {
+			add(cname)
+		}
+		return records, err
+	case "MX":
+		mxs, err := res.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			add(fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+		return records, err
+	case "NS":
+		nss, err := res.LookupNS(ctx, name)
+		for _, ns := range nss {
+			add(ns.Host)
+		}
+		return records, err
+	case "TXT":
+		txts, err := res.LookupTXT(ctx, name)
+		for _, txt := range txts {
+			add(txt)
+		}
+		return records, err
+	default:
+		names, err := res.LookupAddr(ctx, name)
+		for _, n := range names {
+			add(n)
+		}
+		return records, err
+	}
+}
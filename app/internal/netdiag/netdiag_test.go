@@ -0,0 +1,176 @@
+package netdiag
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestEchoRoundTrip(t *testing.T) {
+	msg := echo{Type: icmpEcho, ID: 0x1234, Seq: 7, Data: []byte("terem")}
+	b := msg.marshal()
+	if checksum(b) != 0 {
+		t.Errorf("checksum of marshalled message = %#x", checksum(b))
+	}
+	e, err := parseEcho(b)
+	if err != nil || e.Type != icmpEcho || e.ID != 0x1234 || e.Seq != 7 || string(e.Data) != "terem" {
+		t.Errorf("parseEcho = %+v, %v", e, err)
+	}
+	if _, err := parseEcho(b[:5]); err == nil {
+		t.Error("short message accepted")
+	}
+}
+
+func TestPingStats(t *testing.T) {
+	var s PingStats
+	for _, r := range []Reply{{RTT: 10 * time.Millisecond}, {Lost: true}, {RTT: 30 * time.Millisecond}} {
+		s.add(r)
+	}
+	s.finish()
+	if s.Sent != 3 || s.Received != 2 || int(s.Loss) != 33 {
+		t.Errorf("counters = %+v", s)
+	}
+	if s.Min != 10*time.Millisecond || s.Max != 30*time.Millisecond || s.Avg != 20*time.Millisecond || s.Jitter != 10*time.Millisecond {
+		t.Errorf("rtt = %v/%v/%v ± %v", s.Min, s.Avg, s.Max, s.Jitter)
+	}
+}
+
+// recvErr собирает служебные данные IP_RECVERR, как их отдаёт ядро
+func recvErr(origin, typ, code byte, from [4]byte) []byte {
+	data := make([]byte, 32)
+	data[4], data[5], data[6] = origin, typ, code
+	binary.LittleEndian.PutUint16(data[16:], syscall.AF_INET)
+	copy(data[20:], from[:])
+	oob := make([]byte, syscall.CmsgSpace(len(data)))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	h.Level, h.Type = syscall.IPPROTO_IP, syscall.IP_RECVERR
+	h.SetLen(syscall.CmsgLen(len(data)))
+	copy(oob[syscall.CmsgLen(0):], data)
+	return oob
+}
+
+func TestParseRecvErr(t *testing.T) {
+	hop := [4]byte{10, 0, 0, 1}
+	p, ok := parseRecvErr(recvErr(soEEOriginICMP, icmpTimeExceeded, 0, hop))
+	if !ok || p.kind != probeHop || p.from != netip.AddrFrom4(hop) {
+		t.Errorf("time exceeded = %+v, %v", p, ok)
+	}
+	if p, _ = parseRecvErr(recvErr(soEEOriginICMP, icmpUnreachable, 3, hop)); p.kind != probeReached {
+		t.Errorf("port unreachable = %+v", p)
+	}
+	if p, _ = parseRecvErr(recvErr(soEEOriginICMP, icmpUnreachable, 1, hop)); p.kind != probeUnreachable || unreachableMark(p.code) != "!H" {
+		t.Errorf("host unreachable = %+v", p)
+	}
+	if _, ok = parseRecvErr(recvErr(1, icmpTimeExceeded, 0, hop)); ok {
+		t.Error("local error accepted")
+	}
+}
+
+func TestTraceLoopback(t *testing.T) {
+	var hops []Hop
+	trace, err := Tracer{MaxHops: 3, Probes: 2, Timeout: time.Second, NoNames: true, OnHop: func(h Hop) { hops = append(hops, h) }}.Run(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Skipf("UDP probes unavailable: %v", err)
+	}
+	if !trace.Reached || len(trace.Hops) != 1 || len(hops) != 1 || hops[0].Addr != "127.0.0.1" || len(hops[0].RTTs) != 2 {
+		t.Errorf("trace = %+v", trace)
+	}
+}
+
+func TestMTRLoopback(t *testing.T) {
+	rounds := 0
+	hops, err := MTR{MaxHops: 5, Rounds: 2, Interval: 10 * time.Millisecond, OnRound: func(int, []HopStats) { rounds++ }}.Run(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Skipf("UDP probes unavailable: %v", err)
+	}
+	if rounds != 2 || len(hops) != 1 || hops[0].Sent != 2 || hops[0].Lost != 0 || hops[0].Loss() != 0 {
+		t.Errorf("hops = %+v", hops)
+	}
+}
+
+func TestPingLoopback(t *testing.T) {
+	conn, err := listenICMP()
+	if err != nil {
+		t.Skipf("ICMP unavailable: %v", err)
+	}
+	conn.Close()
+	stats, err := Pinger{Count: 2, Interval: 10 * time.Millisecond, Timeout: time.Second}.Run(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Sent != 2 || stats.Received != 2 || stats.Loss != 0 || stats.Mode != conn.mode {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestLookupAddress(t *testing.T) {
+	if _, err := (Resolver{}).Lookup(context.Background(), "example.org", []string{"SRV"}); err == nil {
+		t.Error("unsupported type accepted")
+	}
+	r := Resolver{Server: "127.0.0.1:1", Timeout: 200 * time.Millisecond}
+	if r.server() != "127.0.0.1:1" || (Resolver{Server: "1.1.1.1"}).server() != "1.1.1.1:53" {
+		t.Errorf("server = %q", r.server())
+	}
+	result, _ := r.Lookup(context.Background(), "127.0.0.1", []string{"A"})
+	if result.Server != "127.0.0.1:1" || result.Name != "127.0.0.1" {
+		t.Errorf("lookup = %+v", result)
+	}
+}
+
+func TestSpeedLoopback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sessions := make(chan SpeedSession, 2)
+	done := make(chan error)
+	go func() {
+		done <- SpeedServer{OnSession: func(s SpeedSession) { sessions <- s }}.Serve(ctx, ln)
+	}()
+
+	test := SpeedTest{Duration: 300 * time.Millisecond}
+	for _, mode := range []string{SpeedDownload, SpeedUpload} {
+		result, err := test.Run(context.Background(), ln.Addr().String(), mode)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if result.Mode != mode || result.Bytes == 0 || result.Duration <= 0 || result.Bits <= 0 {
+			t.Errorf("%s = %+v", mode, result)
+		}
+	}
+	if _, err := test.Run(context.Background(), ln.Addr().String(), "sideways"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	modes := map[string]bool{}
+	for range 2 {
+		s := <-sessions
+		modes[s.Mode] = s.Err == nil
+	}
+	if !modes[SpeedDownload] || !modes[SpeedUpload] {
+		t.Errorf("sessions = %v", modes)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+}
+
+func TestParseHello(t *testing.T) {
+	if mode, d, err := parseHello("TEREM-SPEED 1 upload 1500\n"); err != nil || mode != SpeedUpload || d != 1500*time.Millisecond {
+		t.Errorf("parseHello = %q, %v, %v", mode, d, err)
+	}
+	for _, line := range []string{"GET / HTTP/1.1", "TEREM-SPEED 1 upload 0", "TEREM-SPEED 1 upload 600000", "TEREM-SPEED 2 upload 10"} {
+		if _, _, err := parseHello(line); err == nil {
+			t.Errorf("%q accepted", line)
+		}
+	}
+	if FormatRate(94.2e6) != "94.20 Mbit/s" || FormatRate(512) != "512 bit/s" {
+		t.Errorf("FormatRate = %q", FormatRate(94.2e6))
+	}
+}
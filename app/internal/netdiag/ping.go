@@ -0,0 +1,199 @@
+package netdiag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Способы отправки ICMP
+const (
+	ModeRaw   = "raw"   // raw-сокет, нужны права root или CAP_NET_RAW
+	ModeDgram = "dgram" // непривилегированный ICMP-сокет (net.ipv4.ping_group_range)
+)
+
+// Reply результат одного эхо-запроса
+type Reply struct {
+	Seq  int           `json:"seq"`
+	From string        `json:"from,omitempty"`
+	Size int           `json:"size,omitempty"`
+	RTT  time.Duration `json:"rtt,omitempty"`
+	Lost bool          `json:"lost,omitempty"`
+}
+
+// PingStats итог серии эхо-запросов
+type PingStats struct {
+	Target   string        `json:"target"`
+	Addr     string        `json:"addr"`
+	Mode     string        `json:"mode"`
+	Sent     int           `json:"sent"`
+	Received int           `json:"received"`
+	Loss     float64       `json:"loss"` // Потери в процентах
+	Min      time.Duration `json:"min,omitempty"`
+	Avg      time.Duration `json:"avg,omitempty"`
+	Max      time.Duration `json:"max,omitempty"`
+	Jitter   time.Duration `json:"jitter,omitempty"` // Стандартное отклонение времени ответа
+	Replies  []Reply       `json:"replies"`
+}
+
+// add учитывает ответ в статистике
+func (s *PingStats) add(r Reply) {
+	s.Sent++
+	s.Replies = append(s.Replies, r)
+	if !r.Lost {
+		s.Received++
+	}
+}
+
+// finish вычисляет потери и время ответа
+func (s *PingStats) finish() {
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+	}
+	if s.Received == 0 {
+		return
+	}
+	var sum, squares float64
+	for _, r := range s.Replies {
+		if r.Lost {
+			continue
+		}
+		if s.Min == 0 || r.RTT < s.Min {
+			s.Min = r.RTT
+		}
+		s.Max = max(s.Max, r.RTT)
+		sum += float64(r.RTT)
+		squares += float64(r.RTT) * float64(r.RTT)
+	}
+	avg := sum / float64(s.Received)
+	s.Avg = time.Duration(avg)
+	s.Jitter = time.Duration(math.Sqrt(max(0, squares/float64(s.Received)-avg*avg)))
+}
+
+// Pinger отправляет эхо-запросы ICMP
+type Pinger struct {
+	Count    int           // Количество запросов; по умолчанию 4
+	Interval time.Duration // Пауза между запросами; по умолчанию 1 с
+	Timeout  time.Duration // Ожидание ответа; по умолчанию 2 с
+	Size     int           // Размер данных; по умолчанию 56 байт
+	OnReply  func(Reply)   // Вызывается после каждого запроса
+}
+
+// icmpConn соединение для эхо-запросов и способ его открытия
+type icmpConn struct {
+	net.PacketConn
+	mode string
+}
+
+// dest возвращает адрес назначения в виде, который понимает соединение
+func (c icmpConn) dest(addr netip.Addr) net.Addr {
+	if c.mode == ModeDgram {
+		return &net.UDPAddr{IP: addr.AsSlice()}
+	}
+	return &net.IPAddr{IP: addr.AsSlice()}
+}
+
+// listenICMP открывает raw-сокет ICMP, а без прав — непривилегированный ICMP-сокет
+func listenICMP() (icmpConn, error) {
+	conn, rawErr := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if rawErr == nil {
+		return icmpConn{conn, ModeRaw}, nil
+	}
+	conn, err := listenICMPDgram()
+	if err == nil {
+		return icmpConn{conn, ModeDgram}, nil
+	}
+	return icmpConn{}, fmt.Errorf(i18n.T("netdiag.error.icmp"), errors.Join(rawErr, err))
+}
+
+// Run отправляет серию эхо-запросов узлу host и возвращает статистику;
+// отмена контекста завершает серию досрочно
+func (p Pinger) Run(ctx context.Context, host string) (PingStats, error) {
+	count, interval, timeout, size := p.Count, p.Interval, p.Timeout, p.Size
+	if count <= 0 {
+		count = 4
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	if size <= 0 {
+		size = 56
+	}
+
+	stats := PingStats{Target: host}
+	addr, err := resolve4(ctx, host)
+	if err != nil {
+		return stats, err
+	}
+	stats.Addr = addr.String()
+	conn, err := listenICMP()
+	if err != nil {
+		return stats, err
+	}
+	defer conn.Close()
+	stats.Mode = conn.mode
+
+	id := os.Getpid() & 0xffff
+	buf := make([]byte, 1500)
+	for seq := 1; seq <= count && ctx.Err() == nil; seq++ {
+		start := time.Now()
+		msg := echo{Type: icmpEcho, ID: id, Seq: seq, Data: make([]byte, size)}
+		if _, err := conn.WriteTo(msg.marshal(), conn.dest(addr)); err != nil {
+			return stats, fmt.Errorf(i18n.T("netdiag.error.send"), addr, err)
+		}
+		reply := p.wait(ctx, conn, buf, id, seq, start, timeout)
+		stats.add(reply)
+		if p.OnReply != nil {
+			p.OnReply(reply)
+		}
+		if seq < count {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Until(start.Add(interval))):
+			}
+		}
+	}
+	stats.finish()
+	return stats, nil
+}
+
+// wait ждёт эхо-ответ с номером seq; ответы на чужие запросы пропускаются
+func (p Pinger) wait(ctx context.Context, conn icmpConn, buf []byte, id, seq int, start time.Time, timeout time.Duration) Reply {
+	deadline := start.Add(timeout)
+	for ctx.Err() == nil {
+		// Короткие интервалы ожидания позволяют быстро реагировать на отмену
+		step := time.Now().Add(200 * time.Millisecond)
+		if step.After(deadline) {
+			step = deadline
+		}
+		_ = conn.SetReadDeadline(step)
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if time.Now().Before(deadline) {
+				continue
+			}
+			break
+		}
+		e, err := parseEcho(buf[:n])
+		// Непривилегированный сокет сам подменяет идентификатор и отбирает ответы
+		if err != nil || e.Type != icmpEchoReply || e.Seq != seq || (conn.mode == ModeRaw && e.ID != id) {
+			continue
+		}
+		host, _, _ := net.SplitHostPort(from.String())
+		if host == "" {
+			host = from.String()
+		}
+		return Reply{Seq: seq, From: host, Size: n, RTT: time.Since(start)}
+	}
+	return Reply{Seq: seq, Lost: true}
+}
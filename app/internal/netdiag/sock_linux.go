@@ -0,0 +1,121 @@
+package netdiag
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Источник ошибки в очереди ошибок сокета (linux/errqueue.h)
+const soEEOriginICMP = 2
+
+// listenICMPDgram открывает непривилегированный ICMP-сокет: ядро само назначает
+// идентификатор эхо-запросов и передаёт сокету только ответы на них
+func listenICMPDgram() (net.PacketConn, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{}); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
+
+// waitReadable ждёт, пока на сокете появятся данные или ошибка
+func waitReadable(fd int, timeout time.Duration) (bool, error) {
+	var set syscall.FdSet
+	n := int(unsafe.Sizeof(set.Bits[0])) * 8
+	set.Bits[fd/n] |= 1 << (uint(fd) % uint(n))
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	for {
+		ready, err := syscall.Select(fd+1, &set, nil, nil, &tv)
+		if err == syscall.EINTR {
+			continue
+		}
+		return ready > 0, err
+	}
+}
+
+// probeUDP отправляет пакет UDP с заданным TTL и ждёт ответа ICMP из очереди ошибок
+// сокета (IP_RECVERR), поэтому трассировка не требует прав root
+func probeUDP(dst netip.Addr, ttl, port int, timeout time.Duration) probe {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_UDP)
+	if err != nil {
+		return probe{err: os.NewSyscallError("socket", err)}
+	}
+	defer syscall.Close(fd)
+	for _, opt := range [][2]int{{syscall.IP_TTL, ttl}, {syscall.IP_RECVERR, 1}} {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, opt[0], opt[1]); err != nil {
+			return probe{err: os.NewSyscallError("setsockopt", err)}
+		}
+	}
+	start := time.Now()
+	if err := syscall.Sendto(fd, []byte("terem"), 0, &syscall.SockaddrInet4{Port: port, Addr: dst.As4()}); err != nil {
+		return probe{err: os.NewSyscallError("sendto", err)}
+	}
+
+	buf, oob := make([]byte, 512), make([]byte, 512)
+	for {
+		left := timeout - time.Since(start)
+		if left <= 0 {
+			return probe{}
+		}
+		ready, err := waitReadable(fd, left)
+		if err != nil {
+			return probe{err: os.NewSyscallError("select", err)}
+		}
+		if !ready {
+			return probe{}
+		}
+		_, oobn, _, _, err := syscall.Recvmsg(fd, buf, oob, syscall.MSG_ERRQUEUE)
+		if err != nil {
+			// Ошибок нет — пришёл ответ UDP: на узле слушают этот порт
+			if _, _, err := syscall.Recvfrom(fd, buf, syscall.MSG_DONTWAIT); err == nil {
+				return probe{from: dst, rtt: time.Since(start), kind: probeReached}
+			}
+			continue
+		}
+		if p, ok := parseRecvErr(oob[:oobn]); ok {
+			p.rtt = time.Since(start)
+			return p
+		}
+	}
+}
+
+// parseRecvErr разбирает sock_extended_err и адрес отправителя ICMP из служебных данных
+func parseRecvErr(oob []byte) (probe, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return probe{}, false
+	}
+	for _, m := range msgs {
+		// struct sock_extended_err (16 байт), за ним sockaddr_in отправителя
+		if m.Header.Level != syscall.IPPROTO_IP || m.Header.Type != syscall.IP_RECVERR || len(m.Data) < 24 {
+			continue
+		}
+		if m.Data[4] != soEEOriginICMP {
+			continue
+		}
+		p := probe{from: netip.AddrFrom4([4]byte(m.Data[20:24])), code: int(m.Data[6])}
+		switch int(m.Data[5]) {
+		case icmpTimeExceeded:
+			p.kind = probeHop
+		case icmpUnreachable:
+			p.kind = probeReached
+			if p.code != 3 { // Кроме «порт недоступен»: узел или сеть недоступны, запрещено
+				p.kind = probeUnreachable
+			}
+		default:
+			continue
+		}
+		return p, true
+	}
+	return probe{}, false
+}
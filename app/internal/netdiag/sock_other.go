@@ -0,0 +1,22 @@
+//go:build !linux
+
+package netdiag
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// listenICMPDgram непривилегированные ICMP-сокеты поддерживаются только в Linux
+func listenICMPDgram() (net.PacketConn, error) {
+	return nil, errors.New(i18n.T("netdiag.error.unsupported"))
+}
+
+// probeUDP трассировка через очередь ошибок сокета поддерживается только в Linux
+func probeUDP(netip.Addr, int, int, time.Duration) probe {
+	return probe{err: errors.New(i18n.T("netdiag.error.unsupported"))}
+}
@@ -0,0 +1,287 @@
+package netdiag
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// SpeedPort порт сервера замера скорости по умолчанию
+const SpeedPort = 5210
+
+// Направления замера
+const (
+	SpeedDownload = "download" // Сервер передаёт, клиент принимает
+	SpeedUpload   = "upload"   // Клиент передаёт, сервер принимает
+)
+
+// speedHello первая строка запроса клиента: «TEREM-SPEED 1 <направление> <миллисекунды>»;
+// сервер отвечает строкой «OK» или «ERR <причина>»
+const speedHello = "TEREM-SPEED 1"
+
+// Ограничения сервера
+const (
+	maxSpeedDuration = time.Minute // Наибольшая длительность одного замера
+	speedChunk       = 64 << 10    // Размер блока передачи
+)
+
+// SpeedResult итог замера скорости в одном направлении
+type SpeedResult struct {
+	Mode     string        `json:"mode"`
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+	Bits     float64       `json:"bitsPerSecond"`
+}
+
+// rate вычисляет скорость в битах в секунду
+func rate(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / d.Seconds()
+}
+
+// FormatRate возвращает скорость в удобных единицах
+func FormatRate(bits float64) string {
+	switch {
+	case bits >= 1e9:
+		return fmt.Sprintf("%.2f Gbit/s", bits/1e9)
+	case bits >= 1e6:
+		return fmt.Sprintf("%.2f Mbit/s", bits/1e6)
+	case bits >= 1e3:
+		return fmt.Sprintf("%.1f kbit/s", bits/1e3)
+	}
+	return fmt.Sprintf("%.0f bit/s", bits)
+}
+
+// SpeedSession сведения о замере, проведённом сервером
+type SpeedSession struct {
+	Remote string
+	SpeedResult
+	Err error
+}
+
+// SpeedServer принимает замеры скорости от «terem net speed»
+type SpeedServer struct {
+	OnSession func(SpeedSession) // Вызывается по завершении каждого замера
+}
+
+// Serve обслуживает соединения до отмены контекста
+func (s SpeedServer) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			session := s.handle(conn)
+			if s.OnSession != nil {
+				s.OnSession(session)
+			}
+		}()
+	}
+}
+
+// handle проводит один замер
+func (s SpeedServer) handle(conn net.Conn) SpeedSession {
+	defer conn.Close()
+	session := SpeedSession{Remote: conn.RemoteAddr().String()}
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		session.Err = err
+		return session
+	}
+	mode, duration, err := parseHello(line)
+	if err != nil {
+		fmt.Fprintf(conn, "ERR %s\n", err)
+		session.Err = err
+		return session
+	}
+	session.Mode = mode
+	if _, err := fmt.Fprintf(conn, "OK\n"); err != nil {
+		session.Err = err
+		return session
+	}
+
+	start := time.Now()
+	switch mode {
+	case SpeedDownload:
+		_ = conn.SetWriteDeadline(start.Add(duration + 10*time.Second))
+		session.Bytes, session.Err = sendFor(conn, duration, nil)
+	case SpeedUpload:
+		_ = conn.SetReadDeadline(start.Add(duration + 10*time.Second))
+		session.Bytes, session.Err = io.Copy(io.Discard, r)
+		fmt.Fprintf(conn, "%d %d\n", session.Bytes, time.Since(start).Microseconds())
+	}
+	session.Duration = time.Since(start)
+	session.Bits = rate(session.Bytes, session.Duration)
+	return session
+}
+
+// parseHello разбирает первую строку запроса клиента
+func parseHello(line string) (string, time.Duration, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 || strings.Join(fields[:2], " ") != speedHello {
+		return "", 0, errors.New(i18n.T("netdiag.error.protocol"))
+	}
+	ms, err := strconv.Atoi(fields[3])
+	if err != nil || ms <= 0 || time.Duration(ms)*time.Millisecond > maxSpeedDuration {
+		return "", 0, errors.New(i18n.T("netdiag.error.protocol"))
+	}
+	if fields[2] != SpeedDownload && fields[2] != SpeedUpload {
+		return "", 0, errors.New(i18n.T("netdiag.error.protocol"))
+	}
+	return fields[2], time.Duration(ms) * time.Millisecond, nil
+}
+
+// sendFor передаёт данные в течение duration и учитывает их в counter
+func sendFor(w io.Writer, duration time.Duration, counter *atomic.Int64) (int64, error) {
+	block := make([]byte, speedChunk)
+	deadline := time.Now().Add(duration)
+	var total int64
+	for time.Now().Before(deadline) {
+		n, err := w.Write(block)
+		total += int64(n)
+		if counter != nil {
+			counter.Add(int64(n))
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// countingReader учитывает прочитанные байты
+type countingReader struct {
+	r       io.Reader
+	counter *atomic.Int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.counter.Add(int64(n))
+	return n, err
+}
+
+// SpeedTest замер скорости до сервера «terem net serve»
+type SpeedTest struct {
+	Duration   time.Duration                                          // Длительность каждого направления; по умолчанию 5 с
+	OnProgress func(mode string, elapsed time.Duration, bits float64) // Текущая скорость раз в секунду
+}
+
+// speedAddr добавляет к адресу порт по умолчанию
+func speedAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(SpeedPort))
+}
+
+// Run замеряет скорость в направлении mode
+func (t SpeedTest) Run(ctx context.Context, host, mode string) (SpeedResult, error) {
+	duration := t.Duration
+	if duration <= 0 {
+		duration = 5 * time.Second
+	}
+	duration = min(duration, maxSpeedDuration)
+	result := SpeedResult{Mode: mode}
+	if mode != SpeedDownload && mode != SpeedUpload {
+		return result, errors.New(i18n.T("netdiag.error.protocol"))
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", speedAddr(host))
+	if err != nil {
+		return result, fmt.Errorf(i18n.T("netdiag.error.connect"), speedAddr(host), err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if _, err := fmt.Fprintf(conn, "%s %s %d\n", speedHello, mode, duration.Milliseconds()); err != nil {
+		return result, fmt.Errorf(i18n.T("netdiag.error.speed"), err)
+	}
+	r := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	status, err := r.ReadString('\n')
+	if err != nil {
+		return result, fmt.Errorf(i18n.T("netdiag.error.speed"), err)
+	}
+	if status = strings.TrimSpace(status); status != "OK" {
+		return result, fmt.Errorf("%s: %s", i18n.T("netdiag.error.protocol"), strings.TrimPrefix(status, "ERR "))
+	}
+
+	// Текущая скорость по счётчику переданных байтов
+	var counter atomic.Int64
+	start := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	if t.OnProgress != nil {
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			last := int64(0)
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					current := counter.Load()
+					t.OnProgress(mode, time.Since(start).Round(time.Second), rate(current-last, time.Second))
+					last = current
+				}
+			}
+		}()
+	}
+
+	switch mode {
+	case SpeedDownload:
+		_ = conn.SetReadDeadline(start.Add(duration + 10*time.Second))
+		result.Bytes, err = io.Copy(io.Discard, countingReader{r, &counter})
+		result.Duration = time.Since(start)
+	case SpeedUpload:
+		_ = conn.SetWriteDeadline(start.Add(duration + 10*time.Second))
+		if _, err = sendFor(conn, duration, &counter); err != nil {
+			break
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			_ = tcp.CloseWrite()
+		}
+		// Сервер сообщает, сколько байтов принял и за какое время
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		var line string
+		if line, err = r.ReadString('\n'); err == nil {
+			var micros int64
+			if _, err = fmt.Sscanf(line, "%d %d", &result.Bytes, &micros); err != nil {
+				err = fmt.Errorf("%s: %s", i18n.T("netdiag.error.protocol"), strings.TrimSpace(line))
+			}
+			result.Duration = time.Duration(micros) * time.Microsecond
+		}
+	}
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if err != nil {
+		return result, fmt.Errorf(i18n.T("netdiag.error.speed"), err)
+	}
+	result.Bits = rate(result.Bytes, result.Duration)
+	return result, nil
+}
@@ -0,0 +1,258 @@
+package netdiag
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Порты назначения проб, как у traceroute
+const (
+	tracePort = 33434 // Первый порт
+	maxPorts  = 30000 // Сколько портов перебирать по кругу
+)
+
+// Исход пробы
+const (
+	probeLost        = iota // Ответа нет
+	probeHop                // Промежуточный узел: время жизни истекло
+	probeReached            // Узел назначения: порт недоступен или ответ UDP
+	probeUnreachable        // Узел или сеть недоступны, доступ запрещён
+)
+
+// probe результат отправки одного пакета
+type probe struct {
+	from netip.Addr
+	rtt  time.Duration
+	kind int
+	code int
+	err  error
+}
+
+// unreachableMark обозначение кода «недоступно» в стиле traceroute
+func unreachableMark(code int) string {
+	switch code {
+	case 0:
+		return "!N"
+	case 1:
+		return "!H"
+	case 2:
+		return "!P"
+	case 9, 10, 13:
+		return "!X"
+	}
+	return fmt.Sprintf("!<%d>", code)
+}
+
+// Hop узел маршрута
+type Hop struct {
+	TTL     int             `json:"ttl"`
+	Addr    string          `json:"addr,omitempty"`
+	Name    string          `json:"name,omitempty"`
+	RTTs    []time.Duration `json:"rtts,omitempty"` // Время ответа на каждую пробу; потерянные не входят
+	Lost    int             `json:"lost,omitempty"`
+	Reached bool            `json:"reached,omitempty"`
+	Note    string          `json:"note,omitempty"` // Отметка недоступности: !N, !H, !X
+}
+
+// Trace итог трассировки
+type Trace struct {
+	Target  string `json:"target"`
+	Addr    string `json:"addr"`
+	Hops    []Hop  `json:"hops"`
+	Reached bool   `json:"reached"`
+}
+
+// Tracer трассирует маршрут пакетами UDP с растущим временем жизни
+type Tracer struct {
+	MaxHops int           // Наибольшее число узлов; по умолчанию 30
+	Probes  int           // Проб на узел; по умолчанию 3
+	Timeout time.Duration // Ожидание ответа на пробу; по умолчанию 2 с
+	Port    int           // Первый порт назначения; по умолчанию 33434
+	NoNames bool          // Не определять имена узлов
+	OnHop   func(Hop)     // Вызывается после каждого узла
+}
+
+// reverseName возвращает имя узла по адресу или пустую строку
+func reverseName(ctx context.Context, addr string) string {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// Run трассирует маршрут до host; отмена контекста завершает трассировку досрочно
+func (t Tracer) Run(ctx context.Context, host string) (Trace, error) {
+	maxHops, probes, timeout, port := t.MaxHops, t.Probes, t.Timeout, t.Port
+	if maxHops <= 0 {
+		maxHops = 30
+	}
+	if probes <= 0 {
+		probes = 3
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	if port <= 0 {
+		port = tracePort
+	}
+
+	trace := Trace{Target: host}
+	addr, err := resolve4(ctx, host)
+	if err != nil {
+		return trace, err
+	}
+	trace.Addr = addr.String()
+
+	for ttl := 1; ttl <= maxHops && ctx.Err() == nil; ttl++ {
+		hop := Hop{TTL: ttl}
+		for i := 0; i < probes && ctx.Err() == nil; i++ {
+			p := probeUDP(addr, ttl, port+(ttl-1)*probes+i, timeout)
+			if p.err != nil {
+				return trace, p.err
+			}
+			if p.kind == probeLost {
+				hop.Lost++
+				continue
+			}
+			if hop.Addr == "" && p.from.IsValid() {
+				hop.Addr = p.from.String()
+			}
+			hop.RTTs = append(hop.RTTs, p.rtt)
+			switch p.kind {
+			case probeReached:
+				hop.Reached = true
+			case probeUnreachable:
+				hop.Reached, hop.Note = true, unreachableMark(p.code)
+			}
+		}
+		if hop.Addr != "" && !t.NoNames {
+			hop.Name = reverseName(ctx, hop.Addr)
+		}
+		trace.Hops = append(trace.Hops, hop)
+		trace.Reached = hop.Reached && hop.Note == ""
+		if t.OnHop != nil {
+			t.OnHop(hop)
+		}
+		if hop.Reached {
+			break
+		}
+	}
+	return trace, nil
+}
+
+// HopStats накопленная статистика узла при непрерывной трассировке
+type HopStats struct {
+	TTL   int           `json:"ttl"`
+	Addr  string        `json:"addr,omitempty"`
+	Sent  int           `json:"sent"`
+	Lost  int           `json:"lost"`
+	Last  time.Duration `json:"last,omitempty"`
+	Best  time.Duration `json:"best,omitempty"`
+	Worst time.Duration `json:"worst,omitempty"`
+	Avg   time.Duration `json:"avg,omitempty"`
+	total time.Duration
+}
+
+// Loss потери на узле в процентах
+func (h HopStats) Loss() float64 {
+	if h.Sent == 0 {
+		return 0
+	}
+	return float64(h.Lost) * 100 / float64(h.Sent)
+}
+
+// add учитывает пробу
+func (h *HopStats) add(p probe) {
+	h.Sent++
+	if p.kind == probeLost {
+		h.Lost++
+		return
+	}
+	if h.Addr == "" && p.from.IsValid() {
+		h.Addr = p.from.String()
+	}
+	h.Last, h.total = p.rtt, h.total+p.rtt
+	if h.Best == 0 || p.rtt < h.Best {
+		h.Best = p.rtt
+	}
+	h.Worst = max(h.Worst, p.rtt)
+	h.Avg = h.total / time.Duration(h.Sent-h.Lost)
+}
+
+// MTR непрерывная трассировка: раунды проб ко всем узлам маршрута с накоплением
+// потерь и времени ответа
+type MTR struct {
+	MaxHops  int                              // Наибольшее число узлов; по умолчанию 30
+	Rounds   int                              // Количество раундов; по умолчанию 10
+	Interval time.Duration                    // Пауза между раундами; по умолчанию 1 с
+	Timeout  time.Duration                    // Ожидание ответа; по умолчанию 1 с
+	OnRound  func(round int, hops []HopStats) // Вызывается после каждого раунда
+}
+
+// Run выполняет раунды до host и возвращает статистику узлов;
+// отмена контекста завершает работу после текущего раунда
+func (m MTR) Run(ctx context.Context, host string) ([]HopStats, error) {
+	maxHops, rounds, interval, timeout := m.MaxHops, m.Rounds, m.Interval, m.Timeout
+	if maxHops <= 0 {
+		maxHops = 30
+	}
+	if rounds <= 0 {
+		rounds = 10
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	addr, err := resolve4(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	hops := make([]HopStats, maxHops)
+	for i := range hops {
+		hops[i].TTL = i + 1
+	}
+	limit := maxHops // Узел назначения, как только он найден, ограничивает маршрут
+	for round := 1; round <= rounds && ctx.Err() == nil; round++ {
+		start := time.Now()
+		results := make([]probe, limit)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = probeUDP(addr, i+1, tracePort+(round*maxHops+i)%maxPorts, timeout)
+			}()
+		}
+		wg.Wait()
+		for i, p := range results {
+			if p.err != nil {
+				return hops[:limit], p.err
+			}
+			hops[i].add(p)
+			if p.kind == probeReached || p.kind == probeUnreachable {
+				limit = min(limit, i+1)
+			}
+		}
+		if m.OnRound != nil {
+			m.OnRound(round, hops[:limit])
+		}
+		if round < rounds {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Until(start.Add(interval))):
+			}
+		}
+	}
+	return hops[:limit], nil
+}
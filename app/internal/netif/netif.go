@@ -0,0 +1,317 @@
+// Package netif собирает сведения о сетевых интерфейсах из /sys/class/net и netlink:
+// состояние, MTU, адреса, счётчики трафика, скорость, участие в мостах и беспроводные интерфейсы.
+package netif
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// DefaultSysRoot каталог сетевых интерфейсов в sysfs
+const DefaultSysRoot = "/sys/class/net"
+
+// Виды интерфейсов
+const (
+	KindEthernet = "ethernet"
+	KindBridge   = "bridge"
+	KindWireless = "wireless"
+	KindLoopback = "loopback"
+	KindVLAN     = "vlan"
+	KindPPP      = "ppp"
+	KindTunnel   = "tunnel"
+	KindOther    = "other"
+)
+
+// Типы ARPHRD из /sys/class/net/*/type
+const (
+	arphrdEther    = 1
+	arphrdPPP      = 512
+	arphrdTunnel   = 768
+	arphrdTunnel6  = 769
+	arphrdLoopback = 772
+	arphrdSit      = 776
+	arphrdIPGRE    = 778
+	arphrdNone     = 65534
+)
+
+// Counters счётчики трафика интерфейса из /sys/class/net/*/statistics
+type Counters struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+	RxErrors  uint64 `json:"rxErrors"`
+	TxErrors  uint64 `json:"txErrors"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+// Interface сведения о сетевом интерфейсе
+type Interface struct {
+	Name     string   `json:"name"`
+	Index    int      `json:"index"`
+	Kind     string   `json:"kind"`
+	State    string   `json:"state"`
+	MTU      int      `json:"mtu"`
+	MAC      string   `json:"mac,omitempty"`
+	Master   string   `json:"master,omitempty"`  // Мост, в который входит интерфейс
+	Members  []string `json:"members,omitempty"` // Участники моста
+	Wireless bool     `json:"wireless"`
+	IPv4     []string `json:"ipv4,omitempty"`
+	IPv6     []string `json:"ipv6,omitempty"`
+	Stats    Counters `json:"stats"`
+	RxRate   float64  `json:"rxRate"` // Скорость приёма, байт/с
+	TxRate   float64  `json:"txRate"` // Скорость передачи, байт/с
+}
+
+// Reader читает сведения об интерфейсах
+type Reader struct {
+	// SysRoot каталог интерфейсов (по умолчанию DefaultSysRoot)
+	SysRoot string
+	// Addrs возвращает адреса интерфейса (по умолчанию — через netlink)
+	Addrs func(name string) ([]net.Addr, error)
+}
+
+// NewReader создаёт Reader для текущей системы
+func NewReader() Reader {
+	return Reader{SysRoot: DefaultSysRoot, Addrs: interfaceAddrs}
+}
+
+// interfaceAddrs возвращает адреса интерфейса через netlink
+func interfaceAddrs(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+// List возвращает все интерфейсы, отсортированные по индексу
+func (r Reader) List() ([]Interface, error) {
+	root := r.root()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("netif.error.read"), root, err)
+	}
+
+	result := make([]Interface, 0, len(entries))
+	for _, entry := range entries {
+		iface, err := r.Read(entry.Name())
+		if err != nil {
+			continue
+		}
+		result = append(result, iface)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Index != result[j].Index {
+			return result[i].Index < result[j].Index
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// Read возвращает сведения об одном интерфейсе
+func (r Reader) Read(name string) (Interface, error) {
+	dir := filepath.Join(r.root(), name)
+	if _, err := os.Stat(dir); err != nil {
+		return Interface{}, fmt.Errorf(i18n.T("netif.error.read"), dir, err)
+	}
+
+	iface := Interface{
+		Name:  name,
+		Index: readInt(filepath.Join(dir, "ifindex")),
+		State: readString(filepath.Join(dir, "operstate")),
+		MTU:   readInt(filepath.Join(dir, "mtu")),
+		MAC:   readString(filepath.Join(dir, "address")),
+		Stats: readCounters(filepath.Join(dir, "statistics")),
+	}
+	if iface.State == "" {
+		iface.State = "unknown"
+	}
+
+	iface.Wireless = exists(filepath.Join(dir, "wireless")) || exists(filepath.Join(dir, "phy80211"))
+	iface.Kind = detectKind(dir, iface.Wireless)
+	if iface.Kind == KindLoopback || iface.MAC == "00:00:00:00:00:00" {
+		iface.MAC = ""
+	}
+
+	if link, err := os.Readlink(filepath.Join(dir, "master")); err == nil {
+		iface.Master = filepath.Base(link)
+	}
+	if iface.Kind == KindBridge {
+		if members, err := os.ReadDir(filepath.Join(dir, "brif")); err == nil {
+			for _, m := range members {
+				iface.Members = append(iface.Members, m.Name())
+			}
+		}
+	}
+
+	if r.Addrs != nil {
+		if addrs, err := r.Addrs(name); err == nil {
+			iface.IPv4, iface.IPv6 = splitAddrs(addrs)
+		}
+	}
+
+	return iface, nil
+}
+
+// Collect возвращает интерфейсы со скоростью трафика, измеренной за interval
+func (r Reader) Collect(interval time.Duration) ([]Interface, error) {
+	first, err := r.Counters()
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(interval)
+
+	ifaces, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	ApplyRates(ifaces, first, interval)
+	return ifaces, nil
+}
+
+// Counters возвращает счётчики трафика всех интерфейсов
+func (r Reader) Counters() (map[string]Counters, error) {
+	root := r.root()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("netif.error.read"), root, err)
+	}
+	result := make(map[string]Counters, len(entries))
+	for _, entry := range entries {
+		result[entry.Name()] = readCounters(filepath.Join(root, entry.Name(), "statistics"))
+	}
+	return result, nil
+}
+
+// ApplyRates вычисляет скорость приёма/передачи по разнице счётчиков
+func ApplyRates(ifaces []Interface, previous map[string]Counters, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return
+	}
+	for i := range ifaces {
+		prev, ok := previous[ifaces[i].Name]
+		if !ok {
+			continue
+		}
+		ifaces[i].RxRate = rate(prev.RxBytes, ifaces[i].Stats.RxBytes, seconds)
+		ifaces[i].TxRate = rate(prev.TxBytes, ifaces[i].Stats.TxBytes, seconds)
+	}
+}
+
+// rate возвращает скорость изменения счётчика (0 при сбросе счётчика)
+func rate(before, after uint64, seconds float64) float64 {
+	if after < before {
+		return 0
+	}
+	return float64(after-before) / seconds
+}
+
+func (r Reader) root() string {
+	if r.SysRoot == "" {
+		return DefaultSysRoot
+	}
+	return r.SysRoot
+}
+
+// detectKind определяет вид интерфейса по uevent и типу ARPHRD
+func detectKind(dir string, wireless bool) string {
+	if wireless {
+		return KindWireless
+	}
+	if exists(filepath.Join(dir, "bridge")) {
+		return KindBridge
+	}
+
+	for _, line := range strings.Split(readString(filepath.Join(dir, "uevent")), "\n") {
+		if value, ok := strings.CutPrefix(line, "DEVTYPE="); ok {
+			switch value {
+			case "bridge":
+				return KindBridge
+			case "wlan":
+				return KindWireless
+			case "vlan":
+				return KindVLAN
+			case "ppp":
+				return KindPPP
+			}
+		}
+	}
+
+	switch readInt(filepath.Join(dir, "type")) {
+	case arphrdEther:
+		return KindEthernet
+	case arphrdLoopback:
+		return KindLoopback
+	case arphrdPPP:
+		return KindPPP
+	case arphrdTunnel, arphrdTunnel6, arphrdSit, arphrdIPGRE, arphrdNone:
+		return KindTunnel
+	}
+	return KindOther
+}
+
+// splitAddrs разделяет адреса на IPv4 и IPv6 в формате CIDR
+func splitAddrs(addrs []net.Addr) (v4, v6 []string) {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			v4 = append(v4, ipNet.String())
+		} else {
+			v6 = append(v6, ipNet.String())
+		}
+	}
+	return v4, v6
+}
+
+// readCounters читает счётчики из каталога statistics
+func readCounters(dir string) Counters {
+	return Counters{
+		RxBytes:   readUint(filepath.Join(dir, "rx_bytes")),
+		TxBytes:   readUint(filepath.Join(dir, "tx_bytes")),
+		RxPackets: readUint(filepath.Join(dir, "rx_packets")),
+		TxPackets: readUint(filepath.Join(dir, "tx_packets")),
+		RxErrors:  readUint(filepath.Join(dir, "rx_errors")),
+		TxErrors:  readUint(filepath.Join(dir, "tx_errors")),
+		RxDropped: readUint(filepath.Join(dir, "rx_dropped")),
+		TxDropped: readUint(filepath.Join(dir, "tx_dropped")),
+	}
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readInt(path string) int {
+	value, _ := strconv.Atoi(readString(path))
+	return value
+}
+
+func readUint(path string) uint64 {
+	value, _ := strconv.ParseUint(readString(path), 10, 64)
+	return value
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
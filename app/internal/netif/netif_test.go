@@ -0,0 +1,128 @@
+package netif
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSys создаёт файл в тестовом дереве sysfs
+func writeSys(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fakeSys(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	writeSys(t, root, "lo/ifindex", "1")
+	writeSys(t, root, "lo/type", "772")
+	writeSys(t, root, "lo/operstate", "unknown")
+	writeSys(t, root, "lo/mtu", "65536")
+	writeSys(t, root, "lo/address", "00:00:00:00:00:00")
+
+	writeSys(t, root, "br-lan/ifindex", "4")
+	writeSys(t, root, "br-lan/type", "1")
+	writeSys(t, root, "br-lan/operstate", "up")
+	writeSys(t, root, "br-lan/mtu", "1500")
+	writeSys(t, root, "br-lan/address", "aa:bb:cc:00:00:01")
+	writeSys(t, root, "br-lan/bridge/stp_state", "0")
+	writeSys(t, root, "br-lan/brif/eth0/port_no", "1")
+	writeSys(t, root, "br-lan/brif/wlan0/port_no", "2")
+	writeSys(t, root, "br-lan/statistics/rx_bytes", "5000")
+	writeSys(t, root, "br-lan/statistics/tx_bytes", "9000")
+
+	writeSys(t, root, "eth0/ifindex", "2")
+	writeSys(t, root, "eth0/type", "1")
+	writeSys(t, root, "eth0/operstate", "up")
+	writeSys(t, root, "eth0/mtu", "1500")
+	if err := os.Symlink("../br-lan", filepath.Join(root, "eth0", "master")); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSys(t, root, "wlan0/ifindex", "3")
+	writeSys(t, root, "wlan0/type", "1")
+	writeSys(t, root, "wlan0/operstate", "dormant")
+	writeSys(t, root, "wlan0/phy80211/name", "phy0")
+
+	writeSys(t, root, "wg0/ifindex", "5")
+	writeSys(t, root, "wg0/type", "65534")
+	writeSys(t, root, "wg0/operstate", "unknown")
+
+	return root
+}
+
+func TestListParsesSysfs(t *testing.T) {
+	root := fakeSys(t)
+	reader := Reader{
+		SysRoot: root,
+		Addrs: func(name string) ([]net.Addr, error) {
+			if name != "br-lan" {
+				return nil, nil
+			}
+			return []net.Addr{
+				&net.IPNet{IP: net.ParseIP("192.168.1.1").To4(), Mask: net.CIDRMask(24, 32)},
+				&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+			}, nil
+		},
+	}
+
+	ifaces, err := reader.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(ifaces) != 5 {
+		t.Fatalf("expected 5 interfaces, got %d", len(ifaces))
+	}
+
+	byName := map[string]Interface{}
+	for i, iface := range ifaces {
+		if i > 0 && ifaces[i-1].Index > iface.Index {
+			t.Fatalf("interfaces are not sorted by index: %v", ifaces)
+		}
+		byName[iface.Name] = iface
+	}
+
+	if lo := byName["lo"]; lo.Kind != KindLoopback || lo.MAC != "" || lo.MTU != 65536 {
+		t.Errorf("unexpected loopback: %+v", lo)
+	}
+	br := byName["br-lan"]
+	if br.Kind != KindBridge || len(br.Members) != 2 || br.Stats.RxBytes != 5000 {
+		t.Errorf("unexpected bridge: %+v", br)
+	}
+	if len(br.IPv4) != 1 || br.IPv4[0] != "192.168.1.1/24" || len(br.IPv6) != 1 {
+		t.Errorf("unexpected bridge addresses: %v %v", br.IPv4, br.IPv6)
+	}
+	if eth := byName["eth0"]; eth.Master != "br-lan" || eth.Kind != KindEthernet {
+		t.Errorf("unexpected eth0: %+v", eth)
+	}
+	if wlan := byName["wlan0"]; !wlan.Wireless || wlan.Kind != KindWireless || wlan.State != "dormant" {
+		t.Errorf("unexpected wlan0: %+v", wlan)
+	}
+	if wg := byName["wg0"]; wg.Kind != KindTunnel {
+		t.Errorf("unexpected wg0: %+v", wg)
+	}
+}
+
+func TestApplyRates(t *testing.T) {
+	ifaces := []Interface{{Name: "eth0", Stats: Counters{RxBytes: 3000, TxBytes: 100}}}
+	previous := map[string]Counters{"eth0": {RxBytes: 1000, TxBytes: 200}}
+
+	ApplyRates(ifaces, previous, 2*time.Second)
+
+	if ifaces[0].RxRate != 1000 {
+		t.Errorf("expected rx rate 1000, got %v", ifaces[0].RxRate)
+	}
+	if ifaces[0].TxRate != 0 {
+		t.Errorf("expected zero tx rate after counter reset, got %v", ifaces[0].TxRate)
+	}
+}
@@ -0,0 +1,89 @@
+package ports
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Назначение портов приложений
+const (
+	RoleDNS   = "dns"
+	RoleWeb   = "web"
+	RoleProxy = "proxy"
+	RoleSSH   = "ssh"
+)
+
+// Need порт, который приложение собирается прослушивать
+type Need struct {
+	Proto string     `json:"proto"`           // tcp или udp
+	Port  int        `json:"port"`            // Номер порта
+	Addr  netip.Addr `json:"addr,omitzero"`   // Адрес привязки; пусто или 0.0.0.0 — все адреса
+	Role  string     `json:"role,omitempty"`  // Назначение порта: RoleDNS, RoleWeb и т. д.
+	Fixed bool       `json:"fixed,omitempty"` // Порт нельзя сменить на этом шаге
+}
+
+// String возвращает порт в виде tcp/53
+func (n Need) String() string {
+	return fmt.Sprintf("%s/%d", n.Proto, n.Port)
+}
+
+// overlaps сообщает, пересекается ли адрес привязки с адресом прослушивающего сокета
+func (n Need) overlaps(local netip.Addr) bool {
+	local = local.Unmap()
+	if !n.Addr.IsValid() || n.Addr.IsUnspecified() || local.IsUnspecified() {
+		return true
+	}
+	return n.Addr.Unmap() == local
+}
+
+// Conflict порт, который уже занят другим процессом
+type Conflict struct {
+	Need   Need   `json:"need"`
+	Socket Socket `json:"socket"`
+}
+
+// Conflicts возвращает прослушивающие сокеты, которые занимают нужные порты.
+// Сокеты процессов из own (самого приложения) конфликтом не считаются;
+// сокет IPv4 и IPv6 одного процесса на том же порту учитывается один раз
+func Conflicts(needs []Need, sockets []Socket, own ...string) []Conflict {
+	var result []Conflict
+	for _, n := range needs {
+		for _, s := range sockets {
+			if !s.Listening() || !strings.HasPrefix(s.Proto, n.Proto) || int(s.Local.Port()) != n.Port || !n.overlaps(s.Local.Addr()) {
+				continue
+			}
+			if s.Process != "" && slices.ContainsFunc(own, func(name string) bool { return SameProcess(s.Process, name) }) {
+				continue
+			}
+			if slices.ContainsFunc(result, func(c Conflict) bool {
+				return c.Need == n && c.Socket.PID == s.PID && c.Socket.Process == s.Process
+			}) {
+				continue
+			}
+			result = append(result, Conflict{Need: n, Socket: s})
+		}
+	}
+	return result
+}
+
+// commLen длина имени процесса в /proc/<pid>/comm: ядро обрезает его до 15 символов
+const commLen = 15
+
+// SameProcess сравнивает имя процесса из procfs с именем исполняемого файла с учётом обрезки comm
+func SameProcess(comm, name string) bool {
+	if len(name) > commLen {
+		name = name[:commLen]
+	}
+	return comm == name
+}
+
+// Conflicts читает сокеты и возвращает занятые нужные порты
+func (r Reader) Conflicts(needs []Need, own ...string) ([]Conflict, error) {
+	sockets, err := r.Sockets()
+	if err != nil {
+		return nil, err
+	}
+	return Conflicts(needs, sockets, own...), nil
+}
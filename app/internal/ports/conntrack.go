@@ -0,0 +1,99 @@
+package ports
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Conn запись таблицы отслеживания соединений (conntrack)
+type Conn struct {
+	Family    string         `json:"family"`
+	Proto     string         `json:"proto"`
+	State     string         `json:"state,omitempty"`
+	Timeout   int            `json:"timeout"` // Секунд до удаления записи
+	Src       netip.AddrPort `json:"src"`
+	Dst       netip.AddrPort `json:"dst"`
+	ReplySrc  netip.AddrPort `json:"replySrc"`
+	ReplyDst  netip.AddrPort `json:"replyDst"`
+	Assured   bool           `json:"assured,omitempty"`
+	Unreplied bool           `json:"unreplied,omitempty"`
+	Mark      int            `json:"mark,omitempty"`
+}
+
+// NAT сообщает, что адрес ответа не совпадает с исходным: соединение транслируется
+func (c Conn) NAT() bool {
+	return c.ReplyDst.Addr().IsValid() && (c.ReplyDst != c.Src || c.ReplySrc != c.Dst)
+}
+
+// endpoint собирает адрес и порт одного направления
+type endpoint struct {
+	src, dst     netip.Addr
+	sport, dport uint16
+	hasSrc       bool
+}
+
+// ParseConntrack разбирает /proc/net/nf_conntrack или устаревший /proc/net/ip_conntrack,
+// в котором нет первых двух полей с семейством адресов
+func ParseConntrack(content string) []Conn {
+	var conns []Conn
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		c := Conn{Family: "ipv4"}
+		if len(fields) > 2 && (fields[0] == "ipv4" || fields[0] == "ipv6") {
+			c.Family, fields = fields[0], fields[2:]
+		}
+		if len(fields) < 4 {
+			continue
+		}
+		c.Proto = fields[0]
+		c.Timeout, _ = strconv.Atoi(fields[2])
+
+		// Первые src/dst/sport/dport — исходное направление, вторые — ответ
+		var dirs [2]endpoint
+		dir := 0
+		for _, f := range fields[3:] {
+			key, value, ok := strings.Cut(f, "=")
+			if !ok {
+				switch f {
+				case "[ASSURED]":
+					c.Assured = true
+				case "[UNREPLIED]":
+					c.Unreplied = true
+				default:
+					if c.State == "" && !strings.HasPrefix(f, "[") {
+						c.State = f
+					}
+				}
+				continue
+			}
+			switch key {
+			case "src":
+				if dirs[dir].hasSrc {
+					dir = min(dir+1, 1)
+				}
+				dirs[dir].src, _ = netip.ParseAddr(value)
+				dirs[dir].hasSrc = true
+			case "dst":
+				dirs[dir].dst, _ = netip.ParseAddr(value)
+			case "sport":
+				port, _ := strconv.ParseUint(value, 10, 16)
+				dirs[dir].sport = uint16(port)
+			case "dport":
+				port, _ := strconv.ParseUint(value, 10, 16)
+				dirs[dir].dport = uint16(port)
+			case "mark":
+				c.Mark, _ = strconv.Atoi(value)
+			}
+		}
+		if !dirs[0].src.IsValid() {
+			continue
+		}
+		c.Src = netip.AddrPortFrom(dirs[0].src, dirs[0].sport)
+		c.Dst = netip.AddrPortFrom(dirs[0].dst, dirs[0].dport)
+		c.ReplySrc = netip.AddrPortFrom(dirs[1].src, dirs[1].sport)
+		c.ReplyDst = netip.AddrPortFrom(dirs[1].dst, dirs[1].dport)
+		conns = append(conns, c)
+	}
+	return conns
+}
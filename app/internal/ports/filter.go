@@ -0,0 +1,140 @@
+package ports
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Filter отбор сокетов и соединений; пустые поля не ограничивают выбор
+type Filter struct {
+	Port    int          `json:"port,omitempty"`
+	Prefix  netip.Prefix `json:"prefix,omitzero"`
+	Proto   string       `json:"proto,omitempty"`   // tcp или udp, включая IPv6
+	State   string       `json:"state,omitempty"`   // Состояние в обозначениях ss
+	Process string       `json:"process,omitempty"` // Часть имени процесса
+}
+
+// stateAliases привычные названия состояний
+var stateAliases = map[string]string{
+	"LISTENING":   StateListen,
+	"ESTABLISHED": StateEstablished,
+}
+
+// ParseFilter собирает фильтр из слов: число — порт, адрес или подсеть — IP,
+// tcp или udp — протокол, название состояния — состояние, остальное — имя процесса
+func ParseFilter(words []string) (Filter, error) {
+	var f Filter
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		upper := strings.ToUpper(word)
+		if alias, ok := stateAliases[upper]; ok {
+			upper = alias
+		}
+		switch {
+		case word == "":
+		case isDigits(word):
+			port, err := strconv.Atoi(word)
+			if err != nil || port < 1 || port > 65535 {
+				return f, fmt.Errorf(i18n.T("ports.error.port"), word)
+			}
+			f.Port = port
+		case word == "tcp" || word == "udp":
+			f.Proto = word
+		case slices.Contains(States, upper):
+			f.State = upper
+		default:
+			if prefix, err := netip.ParsePrefix(word); err == nil {
+				f.Prefix = prefix.Masked()
+			} else if addr, err := netip.ParseAddr(word); err == nil {
+				f.Prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+			} else {
+				f.Process = word
+			}
+		}
+	}
+	return f, nil
+}
+
+// isDigits проверяет, что строка состоит только из цифр
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// Empty сообщает, что фильтр ничего не ограничивает
+func (f Filter) Empty() bool {
+	return f == Filter{}
+}
+
+// String возвращает фильтр в виде слов, которые понимает ParseFilter
+func (f Filter) String() string {
+	var words []string
+	if f.Port != 0 {
+		words = append(words, strconv.Itoa(f.Port))
+	}
+	if f.Prefix.IsValid() {
+		if f.Prefix.IsSingleIP() {
+			words = append(words, f.Prefix.Addr().String())
+		} else {
+			words = append(words, f.Prefix.String())
+		}
+	}
+	for _, w := range []string{f.Proto, f.State, f.Process} {
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// matchAddrs проверяет порт и адрес по любому из концов соединения
+func (f Filter) matchAddrs(addrs ...netip.AddrPort) bool {
+	if f.Port != 0 && !slices.ContainsFunc(addrs, func(a netip.AddrPort) bool { return int(a.Port()) == f.Port }) {
+		return false
+	}
+	if f.Prefix.IsValid() && !slices.ContainsFunc(addrs, func(a netip.AddrPort) bool { return f.Prefix.Contains(a.Addr()) }) {
+		return false
+	}
+	return true
+}
+
+// Match проверяет сокет
+func (f Filter) Match(s Socket) bool {
+	return f.matchAddrs(s.Local, s.Remote) &&
+		(f.Proto == "" || strings.HasPrefix(s.Proto, f.Proto)) &&
+		(f.State == "" || s.State == f.State) &&
+		(f.Process == "" || strings.Contains(strings.ToLower(s.Process), strings.ToLower(f.Process)))
+}
+
+// MatchConn проверяет запись conntrack; имя процесса у таких записей неизвестно
+func (f Filter) MatchConn(c Conn) bool {
+	return f.Process == "" && f.matchAddrs(c.Src, c.Dst, c.ReplySrc, c.ReplyDst) &&
+		(f.Proto == "" || c.Proto == f.Proto) &&
+		(f.State == "" || c.State == f.State || (f.State == StateEstablished && c.State == "ESTABLISHED"))
+}
+
+// Sockets возвращает сокеты, подходящие под фильтр
+func (f Filter) Sockets(sockets []Socket) []Socket {
+	var result []Socket
+	for _, s := range sockets {
+		if f.Match(s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Conns возвращает записи conntrack, подходящие под фильтр
+func (f Filter) Conns(conns []Conn) []Conn {
+	var result []Conn
+	for _, c := range conns {
+		if f.MatchConn(c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
@@ -0,0 +1,158 @@
+// Package ports показывает прослушиваемые порты и соединения роутера: сокеты из
+// /proc/net/tcp*, /proc/net/udp* с процессами-владельцами и таблицу conntrack.
+package ports
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Протоколы сокетов в порядке файлов /proc/net
+var Protocols = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// Состояния сокетов в обозначениях ss
+const (
+	StateListen      = "LISTEN"
+	StateEstablished = "ESTAB"
+	StateUnconnected = "UNCONN"
+)
+
+// tcpStates состояния TCP из include/net/tcp_states.h
+var tcpStates = map[string]string{
+	"01": StateEstablished,
+	"02": "SYN-SENT",
+	"03": "SYN-RECV",
+	"04": "FIN-WAIT-1",
+	"05": "FIN-WAIT-2",
+	"06": "TIME-WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE-WAIT",
+	"09": "LAST-ACK",
+	"0A": StateListen,
+	"0B": "CLOSING",
+	"0C": "NEW-SYN-RECV",
+}
+
+// States допустимые состояния для фильтра
+var States = []string{
+	StateListen, StateEstablished, StateUnconnected,
+	"SYN-SENT", "SYN-RECV", "FIN-WAIT-1", "FIN-WAIT-2", "TIME-WAIT", "CLOSE", "CLOSE-WAIT", "LAST-ACK", "CLOSING",
+}
+
+// Socket сокет из /proc/net и процесс, который им владеет
+type Socket struct {
+	Proto   string         `json:"proto"`
+	Local   netip.AddrPort `json:"local"`
+	Remote  netip.AddrPort `json:"remote"`
+	State   string         `json:"state"`
+	UID     int            `json:"uid"`
+	Inode   uint64         `json:"inode,omitempty"`
+	PID     int            `json:"pid,omitempty"`
+	Process string         `json:"process,omitempty"`
+}
+
+// TCP сообщает, что сокет работает по TCP
+func (s Socket) TCP() bool {
+	return strings.HasPrefix(s.Proto, "tcp")
+}
+
+// Listening сообщает, что сокет принимает подключения или датаграммы
+func (s Socket) Listening() bool {
+	if s.TCP() {
+		return s.State == StateListen
+	}
+	return s.State == StateUnconnected
+}
+
+// Owner возвращает процесс-владелец в виде «имя[pid]» или пустую строку
+func (s Socket) Owner() string {
+	if s.PID == 0 {
+		return ""
+	}
+	return s.Process + "[" + strconv.Itoa(s.PID) + "]"
+}
+
+// parseAddr разбирает адрес вида «0100007F:0035». Ядро печатает адрес словами
+// по 32 бита в порядке байтов процессора, порт — числом
+func parseAddr(s string) (netip.AddrPort, bool) {
+	hexAddr, hexPort, ok := strings.Cut(s, ":")
+	if !ok || (len(hexAddr) != 8 && len(hexAddr) != 32) {
+		return netip.AddrPort{}, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, false
+	}
+	raw := make([]byte, len(hexAddr)/2)
+	for i := 0; i < len(hexAddr); i += 8 {
+		word, err := strconv.ParseUint(hexAddr[i:i+8], 16, 32)
+		if err != nil {
+			return netip.AddrPort{}, false
+		}
+		binary.NativeEndian.PutUint32(raw[i/2:], uint32(word))
+	}
+	addr, _ := netip.AddrFromSlice(raw)
+	return netip.AddrPortFrom(addr.Unmap(), uint16(port)), true
+}
+
+// ParseSockets разбирает содержимое /proc/net/<proto>
+func ParseSockets(proto, content string) []Socket {
+	var sockets []Socket
+	for _, line := range strings.Split(content, "\n") {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(line)
+		if len(fields) < 10 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		local, ok1 := parseAddr(fields[1])
+		remote, ok2 := parseAddr(fields[2])
+		if !ok1 || !ok2 {
+			continue
+		}
+		s := Socket{Proto: proto, Local: local, Remote: remote, State: tcpStates[fields[3]]}
+		if !strings.HasPrefix(proto, "tcp") {
+			// Для UDP ядро использует те же коды: 07 — сокет без соединения
+			switch fields[3] {
+			case "07":
+				s.State = StateUnconnected
+			case "01":
+				s.State = StateEstablished
+			}
+		}
+		if s.State == "" {
+			s.State = fields[3]
+		}
+		s.UID, _ = strconv.Atoi(fields[7])
+		s.Inode, _ = strconv.ParseUint(fields[9], 10, 64)
+		sockets = append(sockets, s)
+	}
+	return sockets
+}
+
+// Sort упорядочивает сокеты: сначала прослушиваемые, затем по протоколу и порту
+func Sort(sockets []Socket) {
+	sort.SliceStable(sockets, func(i, j int) bool {
+		a, b := sockets[i], sockets[j]
+		if a.Listening() != b.Listening() {
+			return a.Listening()
+		}
+		if a.Local.Port() != b.Local.Port() {
+			return a.Local.Port() < b.Local.Port()
+		}
+		return a.Proto < b.Proto
+	})
+}
+
+// Listeners возвращает прослушиваемые сокеты
+func Listeners(sockets []Socket) []Socket {
+	var result []Socket
+	for _, s := range sockets {
+		if s.Listening() {
+			result = append(result, s)
+		}
+	}
+	return result
+}
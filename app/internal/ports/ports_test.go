@@ -0,0 +1,227 @@
+package ports
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// procAddr кодирует адрес так, как его печатает ядро в /proc/net
+func procAddr(s string) string {
+	ap := netip.MustParseAddrPort(s)
+	raw := ap.Addr().AsSlice()
+	var b strings.Builder
+	for i := 0; i < len(raw); i += 4 {
+		fmt.Fprintf(&b, "%08X", binary.NativeEndian.Uint32(raw[i:]))
+	}
+	return fmt.Sprintf("%s:%04X", b.String(), ap.Port())
+}
+
+// procLine строка /proc/net/tcp или udp
+func procLine(n int, local, remote, state string, inode int) string {
+	return fmt.Sprintf("%4d: %s %s %s 00000000:00000000 00:00000000 00000000     0        0 %d 1 0000000000000000 100 0 0 10 0",
+		n, procAddr(local), procAddr(remote), state, inode)
+}
+
+const procHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+func TestParseSockets(t *testing.T) {
+	content := procHeader +
+		procLine(0, "0.0.0.0:53", "0.0.0.0:0", "0A", 111) + "\n" +
+		procLine(1, "192.168.1.1:80", "192.168.1.50:51000", "01", 222) + "\n"
+	sockets := ParseSockets("tcp", content)
+	if len(sockets) != 2 {
+		t.Fatalf("parsed %d sockets", len(sockets))
+	}
+	if s := sockets[0]; s.Local.String() != "0.0.0.0:53" || s.State != StateListen || !s.Listening() || s.Inode != 111 {
+		t.Errorf("listener = %+v", s)
+	}
+	if s := sockets[1]; s.Remote.String() != "192.168.1.50:51000" || s.State != StateEstablished || s.Listening() {
+		t.Errorf("connection = %+v", s)
+	}
+
+	udp := ParseSockets("udp6", procHeader+procLine(0, "[::ffff:127.0.0.1]:53", "[::]:0", "07", 333))
+	if len(udp) != 1 || udp[0].State != StateUnconnected || !udp[0].Listening() || udp[0].Local.String() != "127.0.0.1:53" {
+		t.Errorf("udp6 = %+v", udp)
+	}
+}
+
+const sampleConntrack = `ipv4     2 tcp      6 431999 ESTABLISHED src=192.168.1.50 dst=93.184.216.34 sport=51000 dport=443 src=93.184.216.34 dst=203.0.113.7 sport=443 dport=51000 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 udp      17 29 src=192.168.1.50 dst=192.168.1.1 sport=40000 dport=53 [UNREPLIED] src=192.168.1.1 dst=192.168.1.50 sport=53 dport=40000 mark=5 zone=0 use=2
+ipv4     2 icmp     1 29 src=192.168.1.50 dst=1.1.1.1 type=8 code=0 id=7 src=1.1.1.1 dst=203.0.113.7 type=0 code=0 id=7 mark=0 use=2
+`
+
+func TestParseConntrack(t *testing.T) {
+	conns := ParseConntrack(sampleConntrack)
+	if len(conns) != 3 {
+		t.Fatalf("parsed %d entries", len(conns))
+	}
+	if c := conns[0]; c.Proto != "tcp" || c.State != "ESTABLISHED" || c.Timeout != 431999 || !c.Assured || c.Dst.String() != "93.184.216.34:443" || !c.NAT() {
+		t.Errorf("tcp = %+v", c)
+	}
+	if c := conns[1]; c.Proto != "udp" || c.State != "" || !c.Unreplied || c.Mark != 5 || c.NAT() || c.ReplySrc.String() != "192.168.1.1:53" {
+		t.Errorf("udp = %+v", c)
+	}
+	if c := conns[2]; c.Proto != "icmp" || c.Src.Addr().String() != "192.168.1.50" {
+		t.Errorf("icmp = %+v", c)
+	}
+	old := ParseConntrack("tcp      6 117 TIME_WAIT src=10.0.0.2 dst=10.0.0.1 sport=1 dport=22 src=10.0.0.1 dst=10.0.0.2 sport=22 dport=1 [ASSURED] use=1")
+	if len(old) != 1 || old[0].Family != "ipv4" || old[0].State != "TIME_WAIT" || old[0].Dst.Port() != 22 {
+		t.Errorf("ip_conntrack = %+v", old)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f, err := ParseFilter([]string{"53", "udp", "listen", "192.168.1.0/24", "dnsmasq"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Port != 53 || f.Proto != "udp" || f.State != StateListen || f.Prefix.String() != "192.168.1.0/24" || f.Process != "dnsmasq" {
+		t.Errorf("filter = %+v", f)
+	}
+	if f.String() != "53 192.168.1.0/24 udp LISTEN dnsmasq" {
+		t.Errorf("String = %q", f)
+	}
+	if _, err := ParseFilter([]string{"70000"}); err == nil {
+		t.Error("port 70000 accepted")
+	}
+
+	s := Socket{Proto: "tcp6", Local: netip.MustParseAddrPort("192.168.1.1:80"), State: StateListen, Process: "lighttpd"}
+	for words, want := range map[string]bool{
+		"80":             true,
+		"tcp LISTEN":     true,
+		"udp":            false,
+		"192.168.1.1":    true,
+		"10.0.0.0/8":     false,
+		"LIGHT":          true,
+		"established 80": false,
+	} {
+		f, _ := ParseFilter(strings.Fields(words))
+		if f.Match(s) != want {
+			t.Errorf("%q matched %v", words, !want)
+		}
+	}
+
+	conns := ParseConntrack(sampleConntrack)
+	f, _ = ParseFilter([]string{"established", "443"})
+	if got := f.Conns(conns); len(got) != 1 || got[0].Proto != "tcp" {
+		t.Errorf("conns = %+v", got)
+	}
+	f, _ = ParseFilter([]string{"203.0.113.7"})
+	if got := f.Conns(conns); len(got) != 2 {
+		t.Errorf("by reply address = %d", len(got))
+	}
+}
+
+func TestReader(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("net/tcp", procHeader+
+		procLine(0, "192.168.1.1:80", "192.168.1.50:51000", "01", 222)+"\n"+
+		procLine(1, "0.0.0.0:53", "0.0.0.0:0", "0A", 111)+"\n")
+	write("net/udp", procHeader+procLine(0, "0.0.0.0:53", "0.0.0.0:0", "07", 112)+"\n")
+	write("412/comm", "dnsmasq\n")
+	if err := os.MkdirAll(filepath.Join(root, "412", "fd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for fd, target := range map[string]string{"3": "socket:[111]", "4": "socket:[112]", "5": "/dev/null"} {
+		if err := os.Symlink(target, filepath.Join(root, "412", "fd", fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := Reader{ProcRoot: root}
+	sockets, err := r.Sockets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sockets) != 3 || !sockets[0].Listening() || !sockets[1].Listening() || sockets[2].Listening() {
+		t.Fatalf("sockets = %+v", sockets)
+	}
+	for _, s := range Listeners(sockets) {
+		if s.Owner() != "dnsmasq[412]" {
+			t.Errorf("%s owner = %q", s.Proto, s.Owner())
+		}
+	}
+	if sockets[2].Owner() != "" {
+		t.Errorf("unowned socket = %+v", sockets[2])
+	}
+
+	if _, err := r.Conntrack(); err == nil {
+		t.Error("missing conntrack accepted")
+	}
+	write("net/ip_conntrack", sampleConntrack)
+	if conns, err := r.Conntrack(); err != nil || len(conns) != 3 {
+		t.Errorf("Conntrack = %d, %v", len(conns), err)
+	}
+	if _, err := (Reader{ProcRoot: t.TempDir()}).Sockets(); err == nil {
+		t.Error("empty procfs accepted")
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	listen := func(proto, local, process string, pid int) Socket {
+		state := StateListen
+		if !strings.HasPrefix(proto, "tcp") {
+			state = StateUnconnected
+		}
+		return Socket{Proto: proto, Local: netip.MustParseAddrPort(local), State: state, PID: pid, Process: process}
+	}
+	sockets := []Socket{
+		listen("tcp", "0.0.0.0:53", "dnsmasq", 412),
+		listen("tcp6", "[::]:53", "dnsmasq", 412),
+		listen("udp", "0.0.0.0:53", "dnsmasq", 412),
+		listen("tcp", "127.0.0.1:3000", "AdGuardHome", 500),
+		listen("tcp", "192.168.1.1:8888", "ndm", 1),
+		{Proto: "tcp", Local: netip.MustParseAddrPort("192.168.1.1:3128"), Remote: netip.MustParseAddrPort("192.168.1.50:50000"), State: StateEstablished},
+	}
+	needs := []Need{
+		{Proto: "tcp", Port: 3000, Role: RoleWeb},
+		{Proto: "udp", Port: 53, Role: RoleDNS},
+		{Proto: "tcp", Port: 53, Role: RoleDNS},
+	}
+
+	got := Conflicts(needs, sockets, "AdGuardHome")
+	if len(got) != 2 || got[0].Need.Proto != "udp" || got[1].Need.Proto != "tcp" || got[1].Socket.Owner() != "dnsmasq[412]" {
+		t.Errorf("conflicts = %+v", got)
+	}
+	if got := Conflicts(needs, sockets); len(got) != 3 || got[0].Need.Port != 3000 {
+		t.Errorf("without own = %+v", got)
+	}
+
+	proxy := func(addr string, port int) []Need {
+		return []Need{{Proto: "tcp", Port: port, Addr: netip.MustParseAddr(addr), Role: RoleProxy}}
+	}
+	for name, tc := range map[string]struct {
+		needs []Need
+		want  int
+	}{
+		"same address":      {proxy("192.168.1.1", 8888), 1},
+		"other address":     {proxy("10.0.0.1", 8888), 0},
+		"all addresses":     {proxy("0.0.0.0", 8888), 1},
+		"only a connection": {proxy("0.0.0.0", 3128), 0},
+	} {
+		if got := Conflicts(tc.needs, sockets); len(got) != tc.want {
+			t.Errorf("%s: %d conflicts", name, len(got))
+		}
+	}
+
+	if !SameProcess("dnscrypt-proxy", "dnscrypt-proxy") || !SameProcess("adguardhome-lon", "adguardhome-long-name") || SameProcess("sshd", "ssh") {
+		t.Error("SameProcess")
+	}
+	if (Need{Proto: "udp", Port: 53}).String() != "udp/53" {
+		t.Error("Need.String")
+	}
+}
@@ -0,0 +1,122 @@
+package ports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// DefaultProcRoot каталог procfs
+const DefaultProcRoot = "/proc"
+
+// conntrackFiles таблицы conntrack: современная и для старых ядер
+var conntrackFiles = []string{"net/nf_conntrack", "net/ip_conntrack"}
+
+// Reader читает сокеты и conntrack из procfs
+type Reader struct {
+	// ProcRoot каталог procfs (по умолчанию DefaultProcRoot)
+	ProcRoot string
+}
+
+// root возвращает каталог procfs
+func (r Reader) root() string {
+	if r.ProcRoot == "" {
+		return DefaultProcRoot
+	}
+	return r.ProcRoot
+}
+
+// Sockets возвращает сокеты TCP и UDP с процессами-владельцами, упорядоченные функцией Sort.
+// Без прав root владельцы известны только для сокетов своих процессов
+func (r Reader) Sockets() ([]Socket, error) {
+	var sockets []Socket
+	found := false
+	for _, proto := range Protocols {
+		content, err := os.ReadFile(filepath.Join(r.root(), "net", proto))
+		if err != nil {
+			continue
+		}
+		found = true
+		sockets = append(sockets, ParseSockets(proto, string(content))...)
+	}
+	if !found {
+		return nil, errors.New(i18n.T("ports.error.proc"))
+	}
+
+	owners := r.owners()
+	for i, s := range sockets {
+		if o, ok := owners[s.Inode]; ok {
+			sockets[i].PID, sockets[i].Process = o.pid, o.name
+		}
+	}
+	Sort(sockets)
+	return sockets, nil
+}
+
+// owner процесс, владеющий сокетом
+type owner struct {
+	pid  int
+	name string
+}
+
+// owners сопоставляет inode сокетов процессам по ссылкам /proc/<pid>/fd/*
+func (r Reader) owners() map[uint64]owner {
+	result := make(map[uint64]owner)
+	entries, err := os.ReadDir(r.root())
+	if err != nil {
+		return result
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(r.root(), e.Name())
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+		var name string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil {
+				continue
+			}
+			value, ok := strings.CutPrefix(link, "socket:[")
+			if !ok {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(value, "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, seen := result[inode]; seen {
+				continue
+			}
+			if name == "" {
+				comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+				name = strings.TrimSpace(string(comm))
+			}
+			result[inode] = owner{pid: pid, name: name}
+		}
+	}
+	return result
+}
+
+// Conntrack возвращает таблицу отслеживания соединений
+func (r Reader) Conntrack() ([]Conn, error) {
+	for _, name := range conntrackFiles {
+		content, err := os.ReadFile(filepath.Join(r.root(), name))
+		if err == nil {
+			return ParseConntrack(string(content)), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, errors.New(i18n.T("ports.error.conntrack"))
+}
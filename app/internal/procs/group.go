@@ -0,0 +1,87 @@
+package procs
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Group процессы одного приложения и их суммарные ресурсы. Общие страницы памяти
+// учитываются в каждом процессе, поэтому сумма RSS — оценка сверху
+type Group struct {
+	Name  string    `json:"name"`
+	Procs []Process `json:"processes"`
+	RSS   uint64    `json:"rss"`
+	CPU   float64   `json:"cpu"`
+}
+
+// add добавляет процесс в группу
+func (g *Group) add(p Process) {
+	g.Procs = append(g.Procs, p)
+	g.RSS += p.RSS
+	g.CPU += p.CPU
+}
+
+// GroupByName объединяет процессы с одинаковым именем; потоки ядра пропускаются.
+// Группы упорядочены по занятой памяти
+func GroupByName(list []Process) []Group {
+	var groups []Group
+	index := make(map[string]int)
+	for _, p := range list {
+		if p.Kernel() {
+			continue
+		}
+		i, ok := index[p.Name]
+		if !ok {
+			i = len(groups)
+			index[p.Name] = i
+			groups = append(groups, Group{Name: p.Name})
+		}
+		groups[i].add(p)
+	}
+	slices.SortStableFunc(groups, func(a, b Group) int {
+		return cmp.Or(cmp.Compare(b.RSS, a.RSS), cmp.Compare(a.Name, b.Name))
+	})
+	return groups
+}
+
+// App возвращает процессы приложения: процессы с именами names и все их потомки.
+// Имена сравниваются с учётом обрезки comm до 15 символов
+func App(list []Process, names ...string) Group {
+	g := Group{}
+	if len(names) > 0 {
+		g.Name = names[0]
+	}
+	member := make(map[int]bool)
+	for _, p := range list {
+		if slices.ContainsFunc(names, func(name string) bool { return sameName(p.Name, name) }) {
+			member[p.PID] = true
+		}
+	}
+	// Потомки могут стоять в списке раньше родителя, поэтому проходим до стабилизации
+	for changed := true; changed; {
+		changed = false
+		for _, p := range list {
+			if !member[p.PID] && member[p.PPID] {
+				member[p.PID] = true
+				changed = true
+			}
+		}
+	}
+	for _, p := range list {
+		if member[p.PID] {
+			g.add(p)
+		}
+	}
+	return g
+}
+
+// commLen длина имени процесса в /proc/<pid>/comm
+const commLen = 15
+
+// sameName сравнивает имя из comm с именем исполняемого файла
+func sameName(comm, name string) bool {
+	if len(name) > commLen {
+		name = name[:commLen]
+	}
+	return comm == name
+}
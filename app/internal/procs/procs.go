@@ -0,0 +1,135 @@
+// Package procs показывает процессы роутера по данным /proc: загрузку процессора,
+// занятую память, сортировку и отбор по имени, группировку по приложениям,
+// а также отправку сигналов и смену приоритета.
+package procs
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Process процесс из /proc/<pid>/stat
+type Process struct {
+	PID     int     `json:"pid"`
+	PPID    int     `json:"ppid"`
+	Name    string  `json:"name"`    // Имя из comm (не длиннее 15 символов)
+	State   string  `json:"state"`   // Состояние: R, S, D, Z, T и т. д.
+	Nice    int     `json:"nice"`    // Приоритет от -20 до 19
+	Threads int     `json:"threads"` // Число потоков
+	RSS     uint64  `json:"rss"`     // Занятая физическая память, байт
+	CPU     float64 `json:"cpu"`     // Доля процессорного времени с прошлого снимка, %
+	Ticks   uint64  `json:"-"`       // Процессорное время utime+stime в тиках
+}
+
+// Kernel сообщает, что это поток ядра: такие процессы не занимают пользовательскую память
+func (p Process) Kernel() bool {
+	return p.PID == 2 || p.PPID == 2
+}
+
+// Поля /proc/<pid>/stat после имени процесса (нумерация с состояния)
+const (
+	statState   = 0
+	statPPID    = 1
+	statUTime   = 11
+	statSTime   = 12
+	statNice    = 16
+	statThreads = 17
+	statRSS     = 21
+)
+
+// ParseStat разбирает /proc/<pid>/stat; имя процесса может содержать пробелы и скобки,
+// поэтому поля отсчитываются от последней закрывающей скобки. RSS переводится из страниц в байты
+func ParseStat(content string) (Process, error) {
+	var p Process
+	open := strings.IndexByte(content, '(')
+	end := strings.LastIndexByte(content, ')')
+	if open < 0 || end < open {
+		return p, errors.New(i18n.T("procs.error.stat"))
+	}
+	fields := strings.Fields(content[end+1:])
+	if len(fields) <= statRSS {
+		return p, errors.New(i18n.T("procs.error.stat"))
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(content[:open]))
+	if err != nil {
+		return p, errors.New(i18n.T("procs.error.stat"))
+	}
+	number := func(i int) int64 {
+		v, _ := strconv.ParseInt(fields[i], 10, 64)
+		return v
+	}
+	p = Process{
+		PID:     pid,
+		PPID:    int(number(statPPID)),
+		Name:    content[open+1 : end],
+		State:   fields[statState],
+		Nice:    int(number(statNice)),
+		Threads: int(number(statThreads)),
+		RSS:     uint64(max(number(statRSS), 0)) * uint64(os.Getpagesize()),
+		Ticks:   uint64(max(number(statUTime), 0) + max(number(statSTime), 0)),
+	}
+	return p, nil
+}
+
+// Ключи сортировки
+const (
+	SortCPU  = "cpu"
+	SortMem  = "mem"
+	SortPID  = "pid"
+	SortName = "name"
+)
+
+// SortKeys допустимые ключи сортировки в порядке показа в меню
+var SortKeys = []string{SortCPU, SortMem, SortPID, SortName}
+
+// Sort упорядочивает процессы: по загрузке процессора и памяти — по убыванию,
+// по PID и имени — по возрастанию; при равенстве — по PID
+func Sort(list []Process, key string) error {
+	var compare func(a, b Process) int
+	switch key {
+	case SortCPU:
+		compare = func(a, b Process) int { return cmp.Or(cmp.Compare(b.CPU, a.CPU), cmp.Compare(b.RSS, a.RSS)) }
+	case SortMem:
+		compare = func(a, b Process) int { return cmp.Compare(b.RSS, a.RSS) }
+	case SortPID:
+		compare = func(a, b Process) int { return 0 }
+	case SortName:
+		compare = func(a, b Process) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+	default:
+		return fmt.Errorf(i18n.T("procs.error.sort"), key, strings.Join(SortKeys, ", "))
+	}
+	slices.SortStableFunc(list, func(a, b Process) int { return cmp.Or(compare(a, b), cmp.Compare(a.PID, b.PID)) })
+	return nil
+}
+
+// Filter возвращает процессы, в имени которых есть подстрока name (без учёта регистра);
+// пустая строка пропускает все процессы
+func Filter(list []Process, name string) []Process {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return list
+	}
+	var result []Process
+	for _, p := range list {
+		if strings.Contains(strings.ToLower(p.Name), name) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Find возвращает процесс по PID
+func Find(list []Process, pid int) (Process, bool) {
+	i := slices.IndexFunc(list, func(p Process) bool { return p.PID == pid })
+	if i < 0 {
+		return Process{}, false
+	}
+	return list[i], true
+}
@@ -0,0 +1,156 @@
+package procs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// statLine строка /proc/<pid>/stat с нужными полями
+func statLine(pid, ppid int, name, state string, ticks, nice, rssPages int) string {
+	return fmt.Sprintf("%d (%s) %s %d 1 1 0 -1 4194560 100 0 0 0 %d 0 0 0 20 %d 1 0 500 1000000 %d 18446744073709551615",
+		pid, name, state, ppid, ticks, nice, rssPages)
+}
+
+func TestParseStat(t *testing.T) {
+	p, err := ParseStat(statLine(412, 1, "my (odd) name", "S", 30, -5, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := uint64(os.Getpagesize())
+	if p.PID != 412 || p.PPID != 1 || p.Name != "my (odd) name" || p.State != "S" || p.Ticks != 30 || p.Nice != -5 || p.RSS != 10*page {
+		t.Errorf("process = %+v", p)
+	}
+	if _, err := ParseStat("12 (short) S 1 2"); err == nil {
+		t.Error("truncated stat accepted")
+	}
+	if kthread, _ := ParseStat(statLine(7, 2, "kworker/0:1", "I", 0, 0, 0)); !kthread.Kernel() {
+		t.Error("kernel thread not detected")
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	m := ParseMemInfo("MemTotal:       125000 kB\nMemFree:         10000 kB\nMemAvailable:    50000 kB\nSwapTotal:       65536 kB\nSwapFree:        61440 kB\n")
+	if m.Total != 125000*1024 || m.Available != 50000*1024 || m.SwapUsed() != 4096*1024 || m.Used() != 75000*1024 {
+		t.Errorf("memory = %+v", m)
+	}
+	if p := m.Percent(m.Available); p < 39.9 || p > 40.1 {
+		t.Errorf("percent = %.2f", p)
+	}
+	old := ParseMemInfo("MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 50 kB\nCached: 150 kB\n")
+	if old.Available != 300*1024 {
+		t.Errorf("old kernel available = %d", old.Available)
+	}
+}
+
+func TestSortFilterGroups(t *testing.T) {
+	list := []Process{
+		{PID: 1, Name: "init", RSS: 100},
+		{PID: 2, Name: "kthreadd"},
+		{PID: 10, PPID: 2, Name: "kworker/0:1"},
+		{PID: 412, PPID: 1, Name: "dnsmasq", RSS: 900, CPU: 1},
+		{PID: 500, PPID: 1, Name: "AdGuardHome", RSS: 5000, CPU: 3},
+		{PID: 501, PPID: 500, Name: "helper", RSS: 200},
+		{PID: 502, PPID: 501, Name: "worker", RSS: 100},
+		{PID: 413, PPID: 1, Name: "dnsmasq", RSS: 300},
+	}
+	sorted := append([]Process(nil), list...)
+	if err := Sort(sorted, SortMem); err != nil || sorted[0].PID != 500 || sorted[1].PID != 412 {
+		t.Errorf("by memory = %+v, %v", sorted[:2], err)
+	}
+	if err := Sort(sorted, SortName); err != nil || sorted[0].Name != "AdGuardHome" || sorted[1].PID != 412 || sorted[2].PID != 413 {
+		t.Errorf("by name = %+v", sorted[:3])
+	}
+	if err := Sort(sorted, "size"); err == nil {
+		t.Error("unknown sort key accepted")
+	}
+	if got := Filter(list, "DNS"); len(got) != 2 {
+		t.Errorf("filter = %+v", got)
+	}
+
+	groups := GroupByName(list)
+	if len(groups) != 5 || groups[0].Name != "AdGuardHome" || groups[1].Name != "dnsmasq" || len(groups[1].Procs) != 2 || groups[1].RSS != 1200 {
+		t.Errorf("groups = %+v", groups)
+	}
+	app := App(list, "AdGuardHome")
+	if len(app.Procs) != 3 || app.RSS != 5300 || app.CPU != 3 {
+		t.Errorf("app = %+v", app)
+	}
+	if long := App([]Process{{PID: 9, Name: "dnscrypt-proxy2"[:15]}}, "dnscrypt-proxy2"); len(long.Procs) != 1 {
+		t.Errorf("truncated comm = %+v", long)
+	}
+	if p, ok := Find(list, 501); !ok || p.Name != "helper" {
+		t.Errorf("Find = %+v", p)
+	}
+}
+
+func TestReader(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("stat", "cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\n")
+	write("meminfo", "MemTotal: 131072 kB\nMemAvailable: 16384 kB\n")
+	write("1/stat", statLine(1, 0, "init", "S", 10, 0, 100))
+	write("412/stat", statLine(412, 1, "dnsmasq", "S", 20, 0, 200))
+	write("self/stat", "ignored")
+	r := Reader{ProcRoot: root}
+
+	prev, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev.Procs) != 2 || prev.Procs[0].PID != 1 || prev.Memory.Total != 131072*1024 {
+		t.Fatalf("snapshot = %+v", prev)
+	}
+
+	write("stat", "cpu  150 0 150 750 150 0 0 0 0 0\n")
+	write("412/stat", statLine(412, 1, "dnsmasq", "S", 70, 0, 200))
+	write("600/stat", statLine(600, 1, "new", "R", 20, 0, 10))
+	cur, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Usage(prev, &cur)
+	// 200 тиков прошло, из них 100 — простой
+	if cur.Load != 50 {
+		t.Errorf("load = %.1f", cur.Load)
+	}
+	for pid, want := range map[int]float64{1: 0, 412: 25, 600: 10} {
+		if p, _ := Find(cur.Procs, pid); p.CPU != want {
+			t.Errorf("pid %d cpu = %.1f, want %.1f", pid, p.CPU, want)
+		}
+	}
+
+	if _, err := (Reader{ProcRoot: t.TempDir()}).Snapshot(); err == nil {
+		t.Error("empty procfs accepted")
+	}
+}
+
+func TestSignals(t *testing.T) {
+	for name, want := range map[string]syscall.Signal{"TERM": syscall.SIGTERM, "sigkill": syscall.SIGKILL, " hup ": syscall.SIGHUP} {
+		if sig, err := ParseSignal(name); err != nil || sig != want {
+			t.Errorf("%q = %v, %v", name, sig, err)
+		}
+	}
+	if _, err := ParseSignal("STOP"); err == nil {
+		t.Error("unsupported signal accepted")
+	}
+	if err := Kill(1, "TERM"); err == nil {
+		t.Error("init is not protected")
+	}
+	if err := Kill(os.Getpid(), "KILL"); err == nil {
+		t.Error("own process is not protected")
+	}
+	if err := Renice(os.Getpid(), 20); err == nil {
+		t.Error("nice 20 accepted")
+	}
+}
@@ -0,0 +1,181 @@
+package procs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// DefaultProcRoot каталог procfs
+const DefaultProcRoot = "/proc"
+
+// Memory сведения из /proc/meminfo, байт
+type Memory struct {
+	Total     uint64 `json:"total"`
+	Available uint64 `json:"available"` // MemAvailable или, на старых ядрах, MemFree+Buffers+Cached
+	SwapTotal uint64 `json:"swapTotal"`
+	SwapFree  uint64 `json:"swapFree"`
+}
+
+// Used возвращает занятую память
+func (m Memory) Used() uint64 {
+	return m.Total - min(m.Available, m.Total)
+}
+
+// SwapUsed возвращает занятую часть подкачки
+func (m Memory) SwapUsed() uint64 {
+	return m.SwapTotal - min(m.SwapFree, m.SwapTotal)
+}
+
+// Percent возвращает долю объёма от всей памяти, %
+func (m Memory) Percent(size uint64) float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(size) * 100 / float64(m.Total)
+}
+
+// ParseMemInfo разбирает /proc/meminfo
+func ParseMemInfo(content string) Memory {
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(content, "\n") {
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[key] = v * 1024
+		}
+	}
+	m := Memory{
+		Total:     values["MemTotal"],
+		Available: values["MemAvailable"],
+		SwapTotal: values["SwapTotal"],
+		SwapFree:  values["SwapFree"],
+	}
+	if _, ok := values["MemAvailable"]; !ok {
+		m.Available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return m
+}
+
+// parseCPUTicks возвращает из /proc/stat суммарное и простойное время всех процессоров в тиках
+func parseCPUTicks(content string) (total, idle uint64, ok bool) {
+	line, _, _ := strings.Cut(content, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, false
+	}
+	// user nice system idle iowait irq softirq steal; guest уже учтено в user
+	for i, f := range fields[1:min(len(fields), 9)] {
+		v, _ := strconv.ParseUint(f, 10, 64)
+		total += v
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+	return total, idle, true
+}
+
+// Snapshot состояние процессов и памяти в момент чтения
+type Snapshot struct {
+	Procs  []Process `json:"processes"`
+	Memory Memory    `json:"memory"`
+	Load   float64   `json:"load"` // Загрузка процессора с прошлого снимка, %
+	ticks  uint64
+	idle   uint64
+}
+
+// Reader читает процессы из procfs
+type Reader struct {
+	// ProcRoot каталог procfs (по умолчанию DefaultProcRoot)
+	ProcRoot string
+}
+
+// root возвращает каталог procfs
+func (r Reader) root() string {
+	if r.ProcRoot == "" {
+		return DefaultProcRoot
+	}
+	return r.ProcRoot
+}
+
+// Snapshot читает процессы, счётчики процессора и память. Процессы, завершившиеся
+// во время чтения, пропускаются. Загрузка процессора рассчитывается функцией Usage
+func (r Reader) Snapshot() (Snapshot, error) {
+	var s Snapshot
+	stat, err := os.ReadFile(filepath.Join(r.root(), "stat"))
+	if err != nil {
+		return s, errors.New(i18n.T("procs.error.proc"))
+	}
+	s.ticks, s.idle, _ = parseCPUTicks(string(stat))
+	if meminfo, err := os.ReadFile(filepath.Join(r.root(), "meminfo")); err == nil {
+		s.Memory = ParseMemInfo(string(meminfo))
+	}
+
+	entries, err := os.ReadDir(r.root())
+	if err != nil {
+		return s, errors.New(i18n.T("procs.error.proc"))
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(r.root(), e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		if p, err := ParseStat(string(content)); err == nil {
+			s.Procs = append(s.Procs, p)
+		}
+	}
+	_ = Sort(s.Procs, SortPID)
+	return s, nil
+}
+
+// Sample делает два снимка с интервалом interval и возвращает второй с рассчитанной загрузкой
+func (r Reader) Sample(interval time.Duration) (Snapshot, error) {
+	prev, err := r.Snapshot()
+	if err != nil {
+		return prev, err
+	}
+	time.Sleep(interval)
+	cur, err := r.Snapshot()
+	if err != nil {
+		return cur, err
+	}
+	Usage(prev, &cur)
+	return cur, nil
+}
+
+// Usage рассчитывает загрузку процессора за время между снимками: общую и долю каждого
+// процесса от всех ядер. Процессы, появившиеся после prev, учитываются целиком
+func Usage(prev Snapshot, cur *Snapshot) {
+	if cur.ticks <= prev.ticks {
+		return
+	}
+	elapsed := float64(cur.ticks - prev.ticks)
+	if cur.idle >= prev.idle {
+		cur.Load = max(0, 100*(1-float64(cur.idle-prev.idle)/elapsed))
+	}
+	before := make(map[int]uint64, len(prev.Procs))
+	for _, p := range prev.Procs {
+		before[p.PID] = p.Ticks
+	}
+	for i, p := range cur.Procs {
+		used := p.Ticks
+		if last, ok := before[p.PID]; ok && last <= p.Ticks {
+			used -= last
+		}
+		cur.Procs[i].CPU = min(100, float64(used)*100/elapsed)
+	}
+}
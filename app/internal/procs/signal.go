@@ -0,0 +1,73 @@
+package procs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Signals сигналы, которые можно отправить процессу, в порядке показа в меню
+var Signals = []string{"TERM", "HUP", "INT", "KILL"}
+
+// signalNumbers номера сигналов по именам
+var signalNumbers = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+}
+
+// ParseSignal возвращает сигнал по имени: TERM, SIGTERM или term
+func ParseSignal(name string) (syscall.Signal, error) {
+	key := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	if sig, ok := signalNumbers[key]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf(i18n.T("procs.error.signal_name"), name, strings.Join(Signals, ", "))
+}
+
+// Диапазон приоритетов nice
+const (
+	MinNice = -20
+	MaxNice = 19
+)
+
+// checkTarget не даёт отправить сигнал init и самому терему
+func checkTarget(pid int) error {
+	if pid <= 1 || pid == os.Getpid() {
+		return fmt.Errorf(i18n.T("procs.error.protected"), pid)
+	}
+	return nil
+}
+
+// Kill отправляет процессу сигнал по имени
+func Kill(pid int, signal string) error {
+	sig, err := ParseSignal(signal)
+	if err != nil {
+		return err
+	}
+	if err := checkTarget(pid); err != nil {
+		return err
+	}
+	if err := kill(pid, sig); err != nil {
+		return fmt.Errorf(i18n.T("procs.error.kill"), signal, pid, err)
+	}
+	return nil
+}
+
+// Renice меняет приоритет процесса
+func Renice(pid, nice int) error {
+	if nice < MinNice || nice > MaxNice {
+		return fmt.Errorf(i18n.T("procs.error.nice"), nice, MinNice, MaxNice)
+	}
+	if pid <= 0 {
+		return fmt.Errorf(i18n.T("procs.error.protected"), pid)
+	}
+	if err := setNice(pid, nice); err != nil {
+		return fmt.Errorf(i18n.T("procs.error.renice"), pid, err)
+	}
+	return nil
+}
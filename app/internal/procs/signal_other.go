@@ -0,0 +1,20 @@
+//go:build !unix
+
+package procs
+
+import (
+	"errors"
+	"syscall"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// kill не поддерживается вне Unix
+func kill(int, syscall.Signal) error {
+	return errors.New(i18n.T("procs.error.unsupported"))
+}
+
+// setNice не поддерживается вне Unix
+func setNice(int, int) error {
+	return errors.New(i18n.T("procs.error.unsupported"))
+}
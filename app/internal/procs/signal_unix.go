@@ -0,0 +1,15 @@
+//go:build unix
+
+package procs
+
+import "syscall"
+
+// kill отправляет сигнал процессу
+func kill(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// setNice задаёт приоритет процесса
+func setNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
@@ -0,0 +1,229 @@
+// Package proxy настраивает прокси-серверы Entware (tinyproxy, 3proxy, privoxy):
+// генерирует конфигурацию по шаблонам, проверяет её, перезапускает службу и сообщает о состоянии.
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ports"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Kind описание поддерживаемого прокси-сервера
+type Kind struct {
+	ID          string // Имя службы и пакета opkg
+	Title       string // Название для меню
+	ConfigPath  string // Путь к файлу конфигурации
+	DefaultPort int    // Порт по умолчанию
+	Auth        bool   // Поддерживается ли вход по имени и паролю
+	Check       string // Команда проверки конфигурации (%s — путь к файлу), если сервер её поддерживает
+	template    *template.Template
+}
+
+// Kinds поддерживаемые прокси-серверы
+var Kinds = []Kind{
+	{
+		ID:          "tinyproxy",
+		Title:       "Tinyproxy",
+		ConfigPath:  "/opt/etc/tinyproxy/tinyproxy.conf",
+		DefaultPort: 8888,
+		Auth:        true,
+		template:    template.Must(template.New("tinyproxy").Parse(tinyproxyTemplate)),
+	},
+	{
+		ID:          "3proxy",
+		Title:       "3proxy",
+		ConfigPath:  "/opt/etc/3proxy.cfg",
+		DefaultPort: 3128,
+		Auth:        true,
+		template:    template.Must(template.New("3proxy").Funcs(template.FuncMap{"join": strings.Join}).Parse(threeProxyTemplate)),
+	},
+	{
+		ID:          "privoxy",
+		Title:       "Privoxy",
+		ConfigPath:  "/opt/etc/privoxy/config",
+		DefaultPort: 8118,
+		Check:       "privoxy --config-test %s",
+		template:    template.Must(template.New("privoxy").Funcs(template.FuncMap{"hostport": hostPort}).Parse(privoxyTemplate)),
+	},
+}
+
+// startWait время, за которое служба должна запуститься после перезапуска
+var startWait = 3 * time.Second
+
+// hostPort формирует адрес с портом; адрес IPv6 заключается в квадратные скобки
+func hostPort(addr string, port int) string {
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
+// Find возвращает описание прокси-сервера по идентификатору
+func Find(id string) (Kind, bool) {
+	for _, k := range Kinds {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Kind{}, false
+}
+
+// User учётная запись для входа на прокси
+type User struct {
+	Name     string
+	Password string
+}
+
+// Settings параметры прокси-сервера
+type Settings struct {
+	ListenAddr     string   // Адрес, на котором принимаются подключения
+	Port           int      // Порт
+	Users          []User   // Пользователи (пусто — без авторизации)
+	AllowedSubnets []string // Подсети, из которых разрешены подключения
+}
+
+// UserNames возвращает имена пользователей
+func (s Settings) UserNames() []string {
+	names := make([]string, len(s.Users))
+	for i, u := range s.Users {
+		names[i] = u.Name
+	}
+	return names
+}
+
+// Ports возвращает порт, который займёт прокси-сервер
+func (s Settings) Ports() []ports.Need {
+	addr, _ := netip.ParseAddr(s.ListenAddr)
+	return []ports.Need{{Proto: "tcp", Port: s.Port, Addr: addr, Role: ports.RoleProxy}}
+}
+
+var userPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Validate проверяет параметры прокси для сервера kind
+func (s Settings) Validate(kind Kind) error {
+	if net.ParseIP(s.ListenAddr) == nil {
+		return fmt.Errorf(i18n.T("proxy.error.listen"), s.ListenAddr)
+	}
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf(i18n.T("proxy.error.port"), s.Port)
+	}
+	if len(s.AllowedSubnets) == 0 {
+		return errors.New(i18n.T("proxy.error.no_subnets"))
+	}
+	for _, subnet := range s.AllowedSubnets {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return fmt.Errorf(i18n.T("proxy.error.subnet"), subnet)
+		}
+	}
+	if len(s.Users) > 0 && !kind.Auth {
+		return fmt.Errorf(i18n.T("proxy.error.auth_unsupported"), kind.Title)
+	}
+	for _, u := range s.Users {
+		if !userPattern.MatchString(u.Name) || u.Password == "" || strings.ContainsAny(u.Password, " \t\n:\"") {
+			return fmt.Errorf(i18n.T("proxy.error.user"), u.Name)
+		}
+	}
+	return nil
+}
+
+// Render формирует файл конфигурации по шаблону сервера
+func (k Kind) Render(s Settings) (string, error) {
+	if err := s.Validate(k); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := k.template.Execute(&buf, s); err != nil {
+		return "", fmt.Errorf(i18n.T("proxy.error.template"), k.ID, err)
+	}
+	return buf.String(), nil
+}
+
+// Manager управляет прокси-сервером на роутере
+type Manager struct {
+	Kind   Kind
+	Runner utils.Runner
+}
+
+// Service возвращает службу прокси-сервера
+func (m Manager) Service() service.Service {
+	return service.Service{Name: m.Kind.ID, Runner: m.Runner}
+}
+
+// Apply записывает конфигурацию, проверяет её и перезапускает службу.
+// Если проверка не прошла или служба не запустилась, восстанавливается предыдущая
+// конфигурация, а созданный заново файл удаляется. Файл с паролями доступен только владельцу.
+func (m Manager) Apply(s Settings) error {
+	content, err := m.Kind.Render(s)
+	if err != nil {
+		return err
+	}
+	_, err = utils.OrLocal(m.Runner).RunCommand("[ -f " + utils.ShellQuote(m.Kind.ConfigPath) + " ]")
+	existed := err == nil
+
+	write := service.WriteFile
+	if len(s.Users) > 0 {
+		write = service.WritePrivateFile
+	}
+	if err := write(m.Runner, m.Kind.ConfigPath, content); err != nil {
+		return err
+	}
+	if err := m.Check(); err != nil {
+		m.rollback(existed)
+		return err
+	}
+
+	svc := m.Service()
+	if err := svc.Restart(); err == nil && m.started() {
+		return nil
+	}
+	m.rollback(existed)
+	if existed {
+		_ = svc.Restart()
+	}
+	return fmt.Errorf(i18n.T("proxy.error.not_running"), m.Kind.Title)
+}
+
+// started ждёт запуска службы после перезапуска
+func (m Manager) started() bool {
+	deadline := time.Now().Add(startWait)
+	for !m.Service().Running() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return true
+}
+
+// Check проверяет конфигурацию средствами сервера, если он это поддерживает
+func (m Manager) Check() error {
+	if m.Kind.Check == "" {
+		return nil
+	}
+	output, err := utils.OrLocal(m.Runner).RunCommand(fmt.Sprintf(m.Kind.Check, utils.ShellQuote(m.Kind.ConfigPath)) + " 2>&1")
+	if err != nil {
+		return fmt.Errorf(i18n.T("proxy.error.check"), m.Kind.Title, strings.TrimSpace(output+" "+err.Error()))
+	}
+	return nil
+}
+
+// rollback восстанавливает предыдущую конфигурацию из резервной копии, а если файла
+// до записи не было, удаляет отклонённый (старая копия .bak к нему не относится)
+func (m Manager) rollback(existed bool) {
+	config := utils.ShellQuote(m.Kind.ConfigPath)
+	if !existed {
+		_, _ = utils.OrLocal(m.Runner).RunCommand("rm -f " + config)
+		return
+	}
+	backup := utils.ShellQuote(m.Kind.ConfigPath + ".bak")
+	_, _ = utils.OrLocal(m.Runner).RunCommand(fmt.Sprintf("[ -f %s ] && mv %s %s", backup, backup, config))
+}
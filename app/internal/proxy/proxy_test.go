@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+func settings() Settings {
+	return Settings{
+		ListenAddr:     "192.168.1.1",
+		Port:           3128,
+		Users:          []User{{Name: "alice", Password: "secret"}, {Name: "bob", Password: "hunter2"}},
+		AllowedSubnets: []string{"192.168.1.0/24", "10.8.0.0/24"},
+	}
+}
+
+func TestRenderAndParseRoundTrip(t *testing.T) {
+	for _, kind := range Kinds {
+		s := settings()
+		if !kind.Auth {
+			s.Users = nil
+		}
+		content, err := kind.Render(s)
+		if err != nil {
+			t.Fatalf("%s: render: %v", kind.ID, err)
+		}
+		parsed, ok := kind.ParseSettings(content)
+		if !ok {
+			t.Fatalf("%s: generated config not recognized:\n%s", kind.ID, content)
+		}
+		if !reflect.DeepEqual(parsed, s) {
+			t.Errorf("%s: round trip mismatch:\n got %+v\nwant %+v\n%s", kind.ID, parsed, s, content)
+		}
+	}
+}
+
+func TestThreeProxyAuthRules(t *testing.T) {
+	kind, _ := Find("3proxy")
+	content, err := kind.Render(settings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"users alice:CL:secret bob:CL:hunter2", "auth strong", "allow alice,bob 192.168.1.0/24", "deny *", "proxy -p3128 -i192.168.1.1"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in config:\n%s", want, content)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	privoxy, _ := Find("privoxy")
+	tiny, _ := Find("tinyproxy")
+
+	cases := map[string]func(*Settings){
+		"listen":  func(s *Settings) { s.ListenAddr = "router" },
+		"port":    func(s *Settings) { s.Port = 70000 },
+		"subnet":  func(s *Settings) { s.AllowedSubnets = []string{"192.168.1.0/33"} },
+		"subnets": func(s *Settings) { s.AllowedSubnets = nil },
+		"user":    func(s *Settings) { s.Users = []User{{Name: "a b", Password: "x"}} },
+		"pass":    func(s *Settings) { s.Users = []User{{Name: "a", Password: "x:y"}} },
+	}
+	for name, mutate := range cases {
+		s := settings()
+		mutate(&s)
+		if err := s.Validate(tiny); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	if err := settings().Validate(privoxy); err == nil {
+		t.Error("expected privoxy to reject auth users")
+	}
+}
+
+// newRunner имитирует роутер со службой прокси: команды с подстрокой fail завершаются ошибкой,
+// missing — файла конфигурации ещё нет, down — служба не запускается
+func newRunner(fail string, missing, down bool) *testutil.Runner {
+	r := &testutil.Runner{
+		Fail:     fail,
+		Outputs:  map[string]string{"ls /opt/etc/init.d/": "/opt/etc/init.d/S99proxy"},
+		Handlers: map[string]func(string) (string, error){},
+	}
+	if missing {
+		r.Handlers["[ -f "] = func(string) (string, error) { return "", errors.New("exit status 1") }
+	}
+	if !down {
+		r.Outputs["pidof "] = "1234"
+	}
+	return r
+}
+
+func TestApplyRollsBackOnFailedCheck(t *testing.T) {
+	kind, _ := Find("privoxy")
+	runner := newRunner("privoxy --config-test", false, false)
+	s := settings()
+	s.Users = nil
+
+	if err := (Manager{Kind: kind, Runner: runner}).Apply(s); err == nil {
+		t.Fatal("expected check error")
+	}
+	last := runner.Commands[len(runner.Commands)-1]
+	if !strings.Contains(last, "mv '/opt/etc/privoxy/config.bak' '/opt/etc/privoxy/config'") {
+		t.Errorf("expected rollback, got %q", last)
+	}
+	for _, c := range runner.Commands {
+		if strings.HasSuffix(c, " restart") {
+			t.Errorf("service must not be restarted after failed check: %q", c)
+		}
+	}
+
+	runner = newRunner("", false, false)
+	if err := (Manager{Kind: kind, Runner: runner}).Apply(s); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if last := runner.Commands[len(runner.Commands)-2]; last != "'/opt/etc/init.d/S99proxy' restart" {
+		t.Errorf("expected restart, got %q", last)
+	}
+	if last := runner.Commands[len(runner.Commands)-1]; !strings.HasPrefix(last, "pidof ") {
+		t.Errorf("expected running check after restart, got %q", last)
+	}
+}
+
+func TestApplyRollsBackWhenServiceDoesNotStart(t *testing.T) {
+	startWait = 0
+	kind, _ := Find("tinyproxy")
+
+	// Новый файл без предыдущей версии удаляется, устаревшая копия .bak не восстанавливается
+	runner := newRunner("", true, true)
+	if err := (Manager{Kind: kind, Runner: runner}).Apply(settings()); err == nil {
+		t.Fatal("expected error when service does not start")
+	}
+	joined := strings.Join(runner.Commands, "\n")
+	if !strings.Contains(joined, "rm -f '/opt/etc/tinyproxy/tinyproxy.conf'") || strings.Contains(joined, "mv ") {
+		t.Errorf("expected new config to be removed, got:\n%s", joined)
+	}
+
+	// Предыдущая конфигурация восстанавливается, и служба перезапускается с ней
+	runner = newRunner("", false, true)
+	if err := (Manager{Kind: kind, Runner: runner}).Apply(settings()); err == nil {
+		t.Fatal("expected error when service does not start")
+	}
+	n := len(runner.Commands)
+	if !strings.Contains(runner.Commands[n-3], "mv '/opt/etc/tinyproxy/tinyproxy.conf.bak'") || runner.Commands[n-1] != "'/opt/etc/init.d/S99proxy' restart" {
+		t.Errorf("expected rollback and restart, got:\n%s", strings.Join(runner.Commands, "\n"))
+	}
+}
+
+func TestApplyWritesPasswordsPrivately(t *testing.T) {
+	startWait = 0
+	kind, _ := Find("3proxy")
+	runner := newRunner("", false, false)
+	if err := (Manager{Kind: kind, Runner: runner}).Apply(settings()); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if joined := strings.Join(runner.Commands, "\n"); !strings.Contains(joined, "chmod 600 '/opt/etc/3proxy.cfg' &&") {
+		t.Errorf("expected config with passwords to be written 0600, got:\n%s", joined)
+	}
+}
+
+func TestPrivoxyBracketsIPv6(t *testing.T) {
+	kind, _ := Find("privoxy")
+	s := settings()
+	s.Users, s.ListenAddr = nil, "fd00::1"
+	content, err := kind.Render(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "listen-address [fd00::1]:3128") {
+		t.Errorf("expected bracketed IPv6 listen address:\n%s", content)
+	}
+	if parsed, _ := kind.ParseSettings(content); parsed.ListenAddr != "fd00::1" || parsed.Port != 3128 {
+		t.Errorf("unexpected parsed address %q:%d", parsed.ListenAddr, parsed.Port)
+	}
+}
+
+func TestCountConnections(t *testing.T) {
+	proc := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0101A8C0:0C38 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0 10 0
+   1: 0101A8C0:0C38 0A01A8C0:D431 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0 10 0
+   2: 0101A8C0:0C38 0B01A8C0:D432 01 00000000:00000000 00:00000000 00000000     0        0 3 1 0 10 0
+   3: 0101A8C0:0016 0B01A8C0:D433 01 00000000:00000000 00:00000000 00000000     0        0 4 1 0 10 0
+   4: 00000000000000000000000001000000:0C38 00000000000000000000000001000000:9C40 01 00000000:00000000 00:00000000 00000000 0 0 5 1 0 10 0`
+	if got := CountConnections(proc, 3128); got != 3 {
+		t.Fatalf("expected 3 established connections, got %d", got)
+	}
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// tcpEstablished состояние TCP_ESTABLISHED в /proc/net/tcp
+const tcpEstablished = "01"
+
+// Status состояние прокси-сервера
+type Status struct {
+	Installed   bool
+	Running     bool
+	Connections int // Число установленных подключений к порту прокси
+}
+
+// Status возвращает состояние прокси-сервера, слушающего порт port
+func (m Manager) Status(port int) Status {
+	svc := m.Service()
+	st := Status{Installed: svc.Installed(), Running: svc.Running()}
+	if st.Running {
+		output, _ := utils.OrLocal(m.Runner).RunCommand("cat /proc/net/tcp /proc/net/tcp6 2>/dev/null")
+		st.Connections = CountConnections(output, port)
+	}
+	return st
+}
+
+// CountConnections считает установленные входящие TCP-подключения к локальному порту
+// по содержимому /proc/net/tcp и /proc/net/tcp6
+func CountConnections(procNetTCP string, port int) int {
+	count := 0
+	for _, line := range strings.Split(procNetTCP, "\n") {
+		fields := strings.Fields(line)
+		// sl local_address rem_address st ...
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		if local, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(local) == port {
+			count++
+		}
+	}
+	return count
+}
+
+// ParseSettings восстанавливает параметры из конфигурации, созданной теремом.
+// Используется для подстановки текущих значений при повторной настройке.
+func (k Kind) ParseSettings(content string) (Settings, bool) {
+	s := Settings{Port: k.DefaultPort}
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch k.ID {
+		case "tinyproxy":
+			switch fields[0] {
+			case "Port":
+				s.Port, _ = strconv.Atoi(fields[1])
+				found = true
+			case "Listen":
+				s.ListenAddr = fields[1]
+			case "Allow":
+				s.AllowedSubnets = append(s.AllowedSubnets, fields[1])
+			case "BasicAuth":
+				if len(fields) >= 3 {
+					s.Users = append(s.Users, User{Name: fields[1], Password: fields[2]})
+				}
+			}
+		case "3proxy":
+			switch {
+			case fields[0] == "proxy":
+				for _, f := range fields[1:] {
+					if v, ok := strings.CutPrefix(f, "-p"); ok {
+						s.Port, _ = strconv.Atoi(v)
+						found = true
+					}
+					if v, ok := strings.CutPrefix(f, "-i"); ok {
+						s.ListenAddr = v
+					}
+				}
+			case fields[0] == "allow" && len(fields) >= 3:
+				s.AllowedSubnets = append(s.AllowedSubnets, fields[2])
+			case fields[0] == "users":
+				for _, entry := range fields[1:] {
+					parts := strings.SplitN(entry, ":", 3)
+					if len(parts) == 3 {
+						s.Users = append(s.Users, User{Name: parts[0], Password: parts[2]})
+					}
+				}
+			}
+		case "privoxy":
+			switch fields[0] {
+			case "listen-address":
+				if i := strings.LastIndex(fields[1], ":"); i > 0 {
+					s.ListenAddr = strings.Trim(fields[1][:i], "[]")
+					s.Port, _ = strconv.Atoi(fields[1][i+1:])
+					found = true
+				}
+			case "permit-access":
+				s.AllowedSubnets = append(s.AllowedSubnets, fields[1])
+			}
+		}
+	}
+	return s, found
+}
@@ -0,0 +1,48 @@
+package proxy
+
+// Шаблоны конфигурации. Файлы полностью генерируются теремом, поэтому
+// ручные правки будут перезаписаны при следующей настройке (предыдущая версия хранится в .bak).
+
+const tinyproxyTemplate = `# Создано terem: ручные изменения будут перезаписаны
+Port {{.Port}}
+Listen {{.ListenAddr}}
+Timeout 600
+MaxClients 100
+LogLevel Info
+Syslog On
+ViaProxyName "terem"
+{{range .AllowedSubnets}}Allow {{.}}
+{{end}}{{range .Users}}BasicAuth {{.Name}} {{.Password}}
+{{end}}`
+
+const threeProxyTemplate = `# Создано terem: ручные изменения будут перезаписаны
+daemon
+nserver 127.0.0.1
+nscache 65536
+timeouts 1 5 30 60 180 1800 15 60
+log /opt/var/log/3proxy.log D
+rotate 7
+{{if .Users}}users{{range .Users}} {{.Name}}:CL:{{.Password}}{{end}}
+auth strong
+{{range .AllowedSubnets}}allow {{join $.UserNames ","}} {{.}}
+{{end}}{{else}}auth iponly
+{{range .AllowedSubnets}}allow * {{.}}
+{{end}}{{end}}deny *
+proxy -p{{.Port}} -i{{.ListenAddr}}
+`
+
+const privoxyTemplate = `# Создано terem: ручные изменения будут перезаписаны
+confdir /opt/etc/privoxy
+logdir /opt/var/log/privoxy
+actionsfile match-all.action
+actionsfile default.action
+actionsfile user.action
+filterfile default.filter
+filterfile user.filter
+logfile logfile
+listen-address {{hostport .ListenAddr .Port}}
+toggle 1
+enable-remote-toggle 0
+enable-edit-actions 0
+{{range .AllowedSubnets}}permit-access {{.}}
+{{end}}`
@@ -0,0 +1,138 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/firewall"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Manager применяет политику маршрутизации на роутере
+type Manager struct {
+	Runner utils.Runner
+}
+
+func (m Manager) run(command string) (string, error) {
+	return utils.RunChecked(m.Runner, "route.error.command", command)
+}
+
+// Rules возвращает действующие правила ip rule для IPv4
+func (m Manager) Rules() ([]IPRule, error) {
+	output, err := m.run("ip -4 rule show")
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(output), nil
+}
+
+// Routes возвращает маршруты таблицы в виде вывода ip route
+func (m Manager) Routes(table int) ([]string, error) {
+	output, err := m.run(fmt.Sprintf("ip -4 route show table %d", table))
+	if err != nil {
+		return nil, err
+	}
+	var routes []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			routes = append(routes, line)
+		}
+	}
+	return routes, nil
+}
+
+// Marks возвращает правила маркировки пакетов из межсетевого экрана
+func (m Manager) Marks() ([]firewall.Mark, error) {
+	sets, err := firewall.Manager{Runner: m.Runner}.Load()
+	if err != nil {
+		return nil, err
+	}
+	return MarksFrom(sets), nil
+}
+
+// Flush очищает таблицу маршрутизации
+func (m Manager) Flush(table int) error {
+	if table == TableMain || !ValidTable(table) {
+		return fmt.Errorf(i18n.T("route.error.table"), table)
+	}
+	_, err := utils.OrLocal(m.Runner).RunCommand(fmt.Sprintf("ip route flush table %d 2>/dev/null; true", table))
+	return err
+}
+
+// Clear удаляет правила ip rule и маркировку пакетов политики терема; таблицы не трогает
+func (m Manager) Clear() error {
+	var errs []error
+	rules, err := m.Rules()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, r := range rules {
+		if r.Owned() {
+			if _, err := m.run(fmt.Sprintf("ip rule del priority %d", r.Priority)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if _, err := (firewall.Manager{Runner: m.Runner}).RemoveNote(note); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Apply заменяет действующую политику терема на p: заполняет таблицы, добавляет правила
+// ip rule в порядке списка и маркировку пакетов, затем создаёт или удаляет init-скрипт
+func (m Manager) Apply(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := m.Clear(); err != nil {
+		return err
+	}
+	var commands []string
+	for _, t := range p.Tables {
+		commands = append(commands, fmt.Sprintf("ip route flush table %d 2>/dev/null; true", t.ID))
+		commands = append(commands, t.Commands()...)
+	}
+	for i, r := range p.Rules {
+		commands = append(commands, r.command(PriorityBase+i))
+	}
+	for _, c := range commands {
+		if _, err := m.run(c); err != nil {
+			return err
+		}
+	}
+	if err := (firewall.Manager{Runner: m.Runner}).Add(p.Marks()); err != nil {
+		return err
+	}
+	if p.Empty() {
+		return m.RemoveInitScript()
+	}
+	return m.EnsureInitScript()
+}
+
+// initScript применяет политику при запуске Entware и снимает её при выключении
+const initScript = `#!/bin/sh
+# Создан теремом: политика маршрутизации
+case "$1" in
+	start|restart)
+		%[1]s route apply
+		;;
+	stop)
+		%[1]s route clear
+		;;
+esac
+`
+
+// EnsureInitScript создаёт init-скрипт применения политики при загрузке, если его нет
+func (m Manager) EnsureInitScript() error {
+	return service.EnsureScript(m.Runner, InitScript, fmt.Sprintf(initScript, utils.ShellQuote(utils.Binary())))
+}
+
+// RemoveInitScript удаляет init-скрипт, когда политика пуста
+func (m Manager) RemoveInitScript() error {
+	_, err := m.run("rm -f " + utils.ShellQuote(InitScript))
+	return err
+}
@@ -0,0 +1,213 @@
+package route
+
+import (
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/firewall"
+)
+
+// IPRule правило из вывода ip rule show
+type IPRule struct {
+	Priority int    `json:"priority"`
+	From     string `json:"from"`
+	To       string `json:"to,omitempty"`
+	Mark     int    `json:"fwmark,omitempty"`
+	Iif      string `json:"iif,omitempty"`
+	Oif      string `json:"oif,omitempty"`
+	Not      bool   `json:"not,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Action   string `json:"action,omitempty"` // unreachable, blackhole, prohibit, goto
+	Text     string `json:"text"`
+}
+
+// Owned сообщает, что правило относится к политике терема
+func (r IPRule) Owned() bool {
+	return r.Priority >= PriorityBase && r.Priority < PriorityBase+PriorityRange
+}
+
+// parseNumber разбирает десятичное или шестнадцатеричное число, отбрасывая маску после «/»
+func parseNumber(s string) int {
+	s, _, _ = strings.Cut(s, "/")
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// ParseRules разбирает вывод ip rule show; правила упорядочены по приоритету
+func ParseRules(output string) []IPRule {
+	var rules []IPRule
+	for _, line := range strings.Split(output, "\n") {
+		head, rest, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		priority, err := strconv.Atoi(head)
+		if err != nil {
+			continue
+		}
+		r := IPRule{Priority: priority, From: "all", Text: strings.Join(strings.Fields(rest), " ")}
+		fields := strings.Fields(rest)
+		for i := 0; i < len(fields); i++ {
+			value := ""
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+			switch fields[i] {
+			case "not":
+				r.Not = true
+				continue
+			case "unreachable", "blackhole", "prohibit":
+				r.Action = fields[i]
+				continue
+			case "from":
+				r.From = value
+			case "to":
+				r.To = value
+			case "fwmark":
+				r.Mark = parseNumber(value)
+			case "iif":
+				r.Iif = value
+			case "oif":
+				r.Oif = value
+			case "lookup", "table":
+				r.Table = value
+			case "goto":
+				r.Action = "goto " + value
+			default:
+				continue
+			}
+			i++
+		}
+		rules = append(rules, r)
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+	return rules
+}
+
+// MarksFrom извлекает правила маркировки пакетов из наборов правил межсетевого экрана
+// (mangle/PREROUTING, цель MARK) в порядке их проверки
+func MarksFrom(sets []firewall.Ruleset) []firewall.Mark {
+	var marks []firewall.Mark
+	for _, rs := range sets {
+		for _, r := range rs.Rules() {
+			if r.Table != "mangle" || r.Chain != "PREROUTING" || r.Target != "MARK" {
+				continue
+			}
+			var m firewall.Mark
+			for i := 0; i+1 < len(r.Args); i++ {
+				switch r.Args[i] {
+				case "--mac-source":
+					m.MAC = r.Args[i+1]
+				case "--match-set":
+					m.Set = r.Args[i+1]
+				case "--set-mark", "--set-xmark":
+					m.Mark = parseNumber(r.Args[i+1])
+				case "-i":
+					m.Iface = r.Args[i+1]
+				case "--comment":
+					m.Note = r.Args[i+1]
+				}
+			}
+			if m.Mark > 0 && (m.MAC != "" || m.Set != "") {
+				marks = append(marks, m)
+			}
+		}
+	}
+	return marks
+}
+
+// Decision таблица, по которой уходит трафик клиента
+type Decision struct {
+	Table    string `json:"table"`
+	Priority int    `json:"priority,omitempty"` // Приоритет сработавшего правила
+	Rule     string `json:"rule,omitempty"`     // Текст сработавшего правила
+	Mark     int    `json:"fwmark,omitempty"`   // Метка пакетов клиента
+}
+
+// SetDecision особая таблица для адресов назначения из списка ipset
+type SetDecision struct {
+	Set string `json:"set"`
+	Decision
+}
+
+// Effective итог политики для клиента: общий выбор таблицы и отличия для списков ipset
+type Effective struct {
+	IP  string `json:"ip"`
+	MAC string `json:"mac,omitempty"`
+	Decision
+	Sets []SetDecision `json:"sets,omitempty"`
+}
+
+// matches сообщает, подходит ли правило пакету клиента с меткой mark к произвольному адресу.
+// Правила с условиями на назначение, интерфейсы или с отрицанием не учитываются
+func (r IPRule) matches(addr netip.Addr, mark int) bool {
+	if r.Not || r.To != "" && r.To != "all" || r.Iif != "" || r.Oif != "" || r.Table == "local" {
+		return false
+	}
+	if r.Mark != 0 && r.Mark != mark {
+		return false
+	}
+	if r.From == "all" {
+		return true
+	}
+	prefix, err := netip.ParsePrefix(r.From)
+	if err != nil {
+		a, err := netip.ParseAddr(r.From)
+		if err != nil {
+			return false
+		}
+		prefix = netip.PrefixFrom(a, a.BitLen())
+	}
+	return prefix.Contains(addr)
+}
+
+// decide находит первое подходящее правило
+func decide(rules []IPRule, addr netip.Addr, mark int) Decision {
+	for _, r := range rules {
+		if !r.matches(addr, mark) {
+			continue
+		}
+		table := r.Table
+		if r.Action != "" {
+			table = r.Action
+		}
+		return Decision{Table: table, Priority: r.Priority, Rule: r.Text, Mark: mark}
+	}
+	return Decision{Table: "main", Mark: mark}
+}
+
+// Resolve вычисляет политику для клиента по правилам ip rule и маркировке пакетов.
+// Метка по MAC-адресу берётся от последнего подходящего правила маркировки, как в iptables;
+// для списков ipset показываются только те, что меняют таблицу
+func Resolve(ip, mac string, rules []IPRule, marks []firewall.Mark) Effective {
+	e := Effective{IP: ip, MAC: mac}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		e.Table = "main"
+		return e
+	}
+	mark := 0
+	for _, m := range marks {
+		if m.MAC != "" && mac != "" && strings.EqualFold(m.MAC, mac) {
+			mark = m.Mark
+		}
+	}
+	e.Decision = decide(rules, addr, mark)
+	seen := map[string]bool{}
+	for i := len(marks) - 1; i >= 0; i-- {
+		m := marks[i]
+		if m.Set == "" || seen[m.Set] {
+			continue
+		}
+		seen[m.Set] = true
+		if d := decide(rules, addr, m.Mark); d.Table != e.Table {
+			e.Sets = append(e.Sets, SetDecision{Set: m.Set, Decision: d})
+		}
+	}
+	return e
+}
@@ -0,0 +1,227 @@
+// Package route управляет политикой маршрутизации: таблицами с маршрутом по умолчанию
+// и правилами ip rule, которые направляют в них трафик по адресу источника, по MAC-адресу
+// клиента или по спискам ipset (через метки пакетов), а также вычисляет, по какой таблице
+// фактически уходит трафик каждого клиента.
+package route
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+
+	"github.com/qzeleza/terem/internal/firewall"
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ipset"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Приоритеты правил ip rule терема. Правила политики стоят раньше правил туннелей VPN,
+// поэтому исключения из политики срабатывают и для клиентов, выходящих через туннель.
+const (
+	PriorityBase  = 5000 // Приоритет первого правила политики
+	PriorityRange = 1000 // Сколько приоритетов занимает политика
+)
+
+// Особые таблицы ядра
+const (
+	TableDefault = 253
+	TableMain    = 254
+	TableLocal   = 255
+)
+
+// InitScript применяет политику из конфигурации при загрузке роутера
+const InitScript = "/opt/etc/init.d/S51terem-routing"
+
+// note пояснение в комментарии правил маркировки политики
+const note = "routing"
+
+// LocalNets подсети, которые остаются в основной таблице даже у перенаправленных клиентов:
+// обращения внутри локальной сети не уходят во внешний канал
+var LocalNets = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16"}
+
+// devPattern допустимое имя сетевого интерфейса
+var devPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,14}$`)
+
+// Table таблица маршрутизации с маршрутом по умолчанию через интерфейс или шлюз
+type Table struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+	Dev  string `json:"dev,omitempty"`
+	Via  string `json:"via,omitempty"`
+}
+
+// Rule правило выбора таблицы: ровно один признак — источник, MAC-адрес или список ipset
+type Rule struct {
+	Table  int    `json:"table"`
+	Source string `json:"source,omitempty"`
+	MAC    string `json:"mac,omitempty"`
+	IPSet  string `json:"ipset,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Policy политика маршрутизации; правила проверяются в порядке списка
+type Policy struct {
+	Tables []Table `json:"tables,omitempty"`
+	Rules  []Rule  `json:"rules,omitempty"`
+}
+
+// ValidTable сообщает, можно ли направлять трафик в таблицу с этим номером:
+// основная таблица допустима, служебные local и default — нет
+func ValidTable(id int) bool {
+	return id > 0 && id != TableDefault && id != TableLocal
+}
+
+// Validate проверяет таблицу: номер не занят ядром, задан интерфейс или шлюз
+func (t Table) Validate() error {
+	if !ValidTable(t.ID) || t.ID == TableMain {
+		return fmt.Errorf(i18n.T("route.error.table"), t.ID)
+	}
+	if t.Dev == "" && t.Via == "" {
+		return fmt.Errorf(i18n.T("route.error.target"), t.ID)
+	}
+	if t.Dev != "" && !devPattern.MatchString(t.Dev) {
+		return fmt.Errorf(i18n.T("route.error.dev"), t.Dev)
+	}
+	if t.Via != "" {
+		if addr, err := netip.ParseAddr(t.Via); err != nil || !addr.Is4() {
+			return fmt.Errorf(i18n.T("route.error.address"), t.Via)
+		}
+	}
+	return nil
+}
+
+// Title название таблицы для интерфейса
+func (t Table) Title() string {
+	if t.Name != "" {
+		return fmt.Sprintf("%d (%s)", t.ID, t.Name)
+	}
+	return fmt.Sprint(t.ID)
+}
+
+// Commands возвращает команды заполнения таблицы: маршрут по умолчанию и исключения
+// для локальных подсетей
+func (t Table) Commands() []string {
+	target := ""
+	if t.Via != "" {
+		target += " via " + utils.ShellQuote(t.Via)
+	}
+	if t.Dev != "" {
+		target += " dev " + utils.ShellQuote(t.Dev)
+	}
+	commands := []string{fmt.Sprintf("ip route replace default%s table %d", target, t.ID)}
+	for _, n := range LocalNets {
+		commands = append(commands, fmt.Sprintf("ip route replace throw %s table %d", n, t.ID))
+	}
+	return commands
+}
+
+// source возвращает подсеть источника правила
+func (r Rule) source() (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(r.Source); err == nil && prefix.Addr().Is4() {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(r.Source)
+	if err != nil || !addr.Is4() {
+		return netip.Prefix{}, fmt.Errorf(i18n.T("route.error.address"), r.Source)
+	}
+	return netip.PrefixFrom(addr, 32), nil
+}
+
+// Validate проверяет правило: допустимая таблица и ровно один признак
+func (r Rule) Validate() error {
+	if !ValidTable(r.Table) {
+		return fmt.Errorf(i18n.T("route.error.table"), r.Table)
+	}
+	set := 0
+	for _, v := range []string{r.Source, r.MAC, r.IPSet} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New(i18n.T("route.error.match"))
+	}
+	switch {
+	case r.Source != "":
+		_, err := r.source()
+		return err
+	case r.MAC != "":
+		if _, err := net.ParseMAC(r.MAC); err != nil {
+			return fmt.Errorf(i18n.T("firewall.error.mac"), r.MAC)
+		}
+	case r.IPSet != "":
+		return ipset.ValidateName(r.IPSet)
+	}
+	return nil
+}
+
+// Match описание признака правила для интерфейса
+func (r Rule) Match() string {
+	switch {
+	case r.Source != "":
+		return fmt.Sprintf(i18n.T("route.match.source"), r.Source)
+	case r.MAC != "":
+		return fmt.Sprintf(i18n.T("route.match.mac"), r.MAC)
+	default:
+		return fmt.Sprintf(i18n.T("route.match.ipset"), r.IPSet)
+	}
+}
+
+// mark возвращает маркировку пакетов для правила по MAC-адресу или списку;
+// значение метки совпадает с номером таблицы
+func (r Rule) mark() (firewall.Mark, bool) {
+	if r.Source != "" {
+		return firewall.Mark{}, false
+	}
+	return firewall.Mark{Mark: r.Table, MAC: r.MAC, Set: r.IPSet, Note: note}, true
+}
+
+// command возвращает команду добавления правила ip rule с заданным приоритетом
+func (r Rule) command(priority int) string {
+	if r.Source != "" {
+		prefix, _ := r.source()
+		return fmt.Sprintf("ip rule add from %s lookup %d priority %d", prefix, r.Table, priority)
+	}
+	return fmt.Sprintf("ip rule add fwmark %d lookup %d priority %d", r.Table, r.Table, priority)
+}
+
+// Validate проверяет таблицы и правила политики
+func (p Policy) Validate() error {
+	if len(p.Rules) > PriorityRange {
+		return fmt.Errorf(i18n.T("route.error.too_many"), PriorityRange)
+	}
+	seen := map[int]bool{}
+	for _, t := range p.Tables {
+		if seen[t.ID] {
+			return fmt.Errorf(i18n.T("route.error.duplicate"), t.ID)
+		}
+		seen[t.ID] = true
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range p.Rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Empty сообщает, что политика не задаёт ни таблиц, ни правил
+func (p Policy) Empty() bool {
+	return len(p.Tables) == 0 && len(p.Rules) == 0
+}
+
+// Marks возвращает правила межсетевого экрана для маркировки пакетов политики
+func (p Policy) Marks() []firewall.Rule {
+	var rules []firewall.Rule
+	for _, r := range p.Rules {
+		if mark, ok := r.mark(); ok {
+			rules = append(rules, mark.Rules()...)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,140 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/qzeleza/terem/internal/firewall"
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+const sampleRules = `0:	from all lookup local
+5000:	from 192.168.1.50 lookup 100
+5001:	from all fwmark 0x65 lookup 101
+11001:	from all fwmark 0x3e9 lookup 1001
+32766:	from all lookup main
+32767:	from all lookup default
+`
+
+func TestParseRules(t *testing.T) {
+	rules := ParseRules(sampleRules)
+	if len(rules) != 6 {
+		t.Fatalf("parsed %d rules", len(rules))
+	}
+	if r := rules[1]; r.From != "192.168.1.50" || r.Table != "100" || !r.Owned() || r.Text != "from 192.168.1.50 lookup 100" {
+		t.Errorf("rule = %+v", r)
+	}
+	if r := rules[3]; r.Mark != 1001 || r.Owned() {
+		t.Errorf("rule = %+v", r)
+	}
+	if r := ParseRules("100: not from all to 10.0.0.0/8 unreachable"); r[0].Action != "unreachable" || !r[0].Not || r[0].To != "10.0.0.0/8" {
+		t.Errorf("rule = %+v", r[0])
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rules := ParseRules(sampleRules)
+	marks := []firewall.Mark{
+		{Mark: 101, MAC: "AA:BB:CC:DD:EE:FF"},
+		{Mark: 1001, Set: "vpn"},
+	}
+	e := Resolve("192.168.1.50", "", rules, marks)
+	if e.Table != "100" || e.Priority != 5000 || len(e.Sets) != 0 {
+		t.Errorf("by source = %+v", e)
+	}
+	e = Resolve("192.168.1.60", "aa:bb:cc:dd:ee:ff", rules, marks)
+	if e.Table != "101" || e.Mark != 101 || len(e.Sets) != 1 || e.Sets[0].Set != "vpn" || e.Sets[0].Table != "1001" {
+		t.Errorf("by MAC = %+v", e)
+	}
+	if e = Resolve("192.168.1.70", "", rules, nil); e.Table != "main" || e.Priority != 32766 {
+		t.Errorf("default = %+v", e)
+	}
+}
+
+func TestMarksFrom(t *testing.T) {
+	save := `*mangle
+:PREROUTING ACCEPT [0:0]
+-A PREROUTING -m mac --mac-source AA:BB:CC:DD:EE:FF -m comment --comment "terem: routing" -j MARK --set-xmark 0x65/0xffffffff
+-A PREROUTING -m set --match-set vpn dst -j MARK --set-mark 1001
+-A PREROUTING -j ACCEPT
+COMMIT
+`
+	rs, err := firewall.ParseSave(save, firewall.FamilyIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marks := MarksFrom([]firewall.Ruleset{rs})
+	if len(marks) != 2 || marks[0].Mark != 101 || marks[0].Note != "terem: routing" || marks[1].Set != "vpn" || marks[1].Mark != 1001 {
+		t.Errorf("marks = %+v", marks)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, p := range []Policy{
+		{Tables: []Table{{ID: TableMain, Dev: "eth3"}}},
+		{Tables: []Table{{ID: 100}}},
+		{Tables: []Table{{ID: 100, Via: "fe80::1"}}},
+		{Tables: []Table{{ID: 100, Dev: "eth3"}, {ID: 100, Dev: "ppp0"}}},
+		{Rules: []Rule{{Table: TableLocal, Source: "192.168.1.1"}}},
+		{Rules: []Rule{{Table: 100}}},
+		{Rules: []Rule{{Table: 100, Source: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:ff"}}},
+		{Rules: []Rule{{Table: 100, MAC: "bad"}}},
+		{Rules: []Rule{{Table: 100, IPSet: "bad name"}}},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected error for %+v", p)
+		}
+	}
+	ok := Policy{
+		Tables: []Table{{ID: 100, Dev: "eth3", Via: "10.0.0.1"}},
+		Rules:  []Rule{{Table: 100, Source: "192.168.1.0/24"}, {Table: TableMain, MAC: "aa:bb:cc:dd:ee:ff"}},
+	}
+	if err := ok.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	r := &testutil.Runner{Outputs: map[string]string{"ip -4 rule show": sampleRules}, Fail: " -C "}
+	p := Policy{
+		Tables: []Table{{ID: 100, Dev: "eth3", Via: "10.0.0.1"}},
+		Rules: []Rule{
+			{Table: 100, Source: "192.168.1.50"},
+			{Table: 100, MAC: "aa:bb:cc:dd:ee:ff"},
+			{Table: 100, IPSet: "isp2"},
+		},
+	}
+	if err := (Manager{Runner: r}).Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ip rule del priority 5000",
+		"ip rule del priority 5001",
+		"ip route flush table 100",
+		"ip route replace default via '10.0.0.1' dev 'eth3' table 100",
+		"ip route replace throw 10.0.0.0/8 table 100",
+		"ip rule add from 192.168.1.50/32 lookup 100 priority 5000",
+		"ip rule add fwmark 100 lookup 100 priority 5001",
+		"ip rule add fwmark 100 lookup 100 priority 5002",
+		"'--mac-source' 'AA:BB:CC:DD:EE:FF' '-m' 'comment' '--comment' 'terem: routing'",
+		"'--match-set' 'isp2' 'dst'",
+		InitScript,
+	} {
+		if len(r.Find(want)) == 0 {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if len(r.Find("ip rule del priority 11001")) != 0 {
+		t.Error("VPN rules must stay")
+	}
+
+	r = &testutil.Runner{Fail: " -C "}
+	if err := (Manager{Runner: r}).Apply(Policy{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Find("rm -f '"+InitScript+"'")) != 1 {
+		t.Errorf("init script not removed: %v", r.Commands)
+	}
+	if err := (Manager{Runner: r}).Flush(TableMain); err == nil {
+		t.Error("main table must not be flushed")
+	}
+}
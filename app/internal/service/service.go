@@ -0,0 +1,163 @@
+// Package service управляет пакетами и службами Entware: установка через opkg,
+// поиск init-скрипта, перезапуск, проверка состояния и запись файлов конфигурации.
+package service
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// InitDir каталог init-скриптов Entware
+const InitDir = "/opt/etc/init.d"
+
+// Service служба Entware
+type Service struct {
+	Name    string       // Имя службы и процесса (например, tinyproxy)
+	Package string       // Пакет opkg (по умолчанию совпадает с Name)
+	Process string       // Имя процесса, если отличается от Name
+	Runner  utils.Runner // Выполнение команд (по умолчанию — локально)
+}
+
+func (s Service) pkg() string {
+	if s.Package == "" {
+		return s.Name
+	}
+	return s.Package
+}
+
+// ProcessName возвращает имя процесса службы
+func (s Service) ProcessName() string {
+	if s.Process == "" {
+		return s.Name
+	}
+	return s.Process
+}
+
+// Installed сообщает, установлен ли пакет службы
+func (s Service) Installed() bool {
+	output, err := utils.OrLocal(s.Runner).RunCommand("opkg list-installed " + utils.ShellQuote(s.pkg()) + " 2>/dev/null")
+	return err == nil && strings.HasPrefix(strings.TrimSpace(output), s.pkg()+" ")
+}
+
+// Install устанавливает пакет службы через opkg
+func (s Service) Install() error {
+	if _, err := utils.OrLocal(s.Runner).RunCommand("opkg update >/dev/null 2>&1; opkg install " + utils.ShellQuote(s.pkg())); err != nil {
+		return fmt.Errorf(i18n.T("service.error.install"), s.pkg(), err)
+	}
+	return nil
+}
+
+// InitScript возвращает путь к init-скрипту службы (S??<name> в /opt/etc/init.d)
+func (s Service) InitScript() (string, error) {
+	output, err := utils.OrLocal(s.Runner).RunCommand(fmt.Sprintf("ls %s/S*%s 2>/dev/null | head -n 1", InitDir, s.Name))
+	script := strings.TrimSpace(output)
+	if err != nil || script == "" {
+		return "", fmt.Errorf(i18n.T("service.error.no_init"), s.Name, InitDir)
+	}
+	return script, nil
+}
+
+// Restart перезапускает службу через её init-скрипт
+func (s Service) Restart() error {
+	return s.control("restart")
+}
+
+// Stop останавливает службу
+func (s Service) Stop() error {
+	return s.control("stop")
+}
+
+// Start запускает службу
+func (s Service) Start() error {
+	return s.control("start")
+}
+
+// DisableAutostart отключает запуск службы при загрузке роутера: в init-скрипте Entware
+// ENABLED=yes заменяется на ENABLED=no. Возвращает false, если скрипт такой настройки не
+// поддерживает и служба после перезагрузки запустится снова
+func (s Service) DisableAutostart() (bool, error) {
+	script, err := s.InitScript()
+	if err != nil {
+		return false, err
+	}
+	quoted := utils.ShellQuote(script)
+	if _, err := utils.OrLocal(s.Runner).RunCommand("grep -q '^ENABLED=' " + quoted); err != nil {
+		return false, nil
+	}
+	if _, err := utils.OrLocal(s.Runner).RunCommand("sed -i 's/^ENABLED=.*/ENABLED=no/' " + quoted); err != nil {
+		return false, fmt.Errorf(i18n.T("service.error.write"), script, err)
+	}
+	return true, nil
+}
+
+func (s Service) control(action string) error {
+	script, err := s.InitScript()
+	if err != nil {
+		return err
+	}
+	if _, err := utils.OrLocal(s.Runner).RunCommand(utils.ShellQuote(script) + " " + action); err != nil {
+		return fmt.Errorf(i18n.T("service.error.control"), s.Name, action, err)
+	}
+	return nil
+}
+
+// Running сообщает, запущен ли процесс службы
+func (s Service) Running() bool {
+	output, err := utils.OrLocal(s.Runner).RunCommand("pidof " + utils.ShellQuote(s.ProcessName()))
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+// WriteFile записывает файл на роутер, сохраняя предыдущую версию в <path>.bak
+func WriteFile(runner utils.Runner, file, content string) error {
+	return writeFile(runner, file, content, false)
+}
+
+// WritePrivateFile записывает файл с паролями: файл и его резервная копия доступны только владельцу (0600)
+func WritePrivateFile(runner utils.Runner, file, content string) error {
+	return writeFile(runner, file, content, true)
+}
+
+func writeFile(runner utils.Runner, file, content string, private bool) error {
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	quoted := utils.ShellQuote(file)
+	backup := utils.ShellQuote(file + ".bak")
+	command := fmt.Sprintf("mkdir -p %s && { [ ! -f %s ] || cp -p %s %s; }", utils.ShellQuote(path.Dir(file)), quoted, quoted, backup)
+	if private {
+		// Права меняются до записи, чтобы пароли ни на миг не оказались в файле, доступном всем
+		command += fmt.Sprintf(" && (umask 077 && : >> %s) && chmod 600 %s && { [ ! -f %s ] || chmod 600 %s; }",
+			quoted, quoted, backup, backup)
+	}
+	command += fmt.Sprintf(" && printf '%%s' %s > %s", utils.ShellQuote(content), quoted)
+	if _, err := utils.OrLocal(runner).RunCommand(command); err != nil {
+		return fmt.Errorf(i18n.T("service.error.write"), file, err)
+	}
+	return nil
+}
+
+// ReadFile читает файл на роутере; отсутствующий файл возвращает ошибку
+func ReadFile(runner utils.Runner, file string) (string, error) {
+	output, err := utils.OrLocal(runner).RunCommand("cat " + utils.ShellQuote(file))
+	if err != nil {
+		return "", fmt.Errorf(i18n.T("service.error.read"), file, err)
+	}
+	return output, nil
+}
+
+// EnsureScript записывает исполняемый скрипт, если его содержимое отличается от content.
+// Резервная копия не сохраняется: скрипт целиком генерируется теремом.
+func EnsureScript(runner utils.Runner, file, content string) error {
+	if current, err := ReadFile(runner, file); err == nil && strings.TrimSpace(current) == strings.TrimSpace(content) {
+		return nil
+	}
+	if err := WriteFile(runner, file, content); err != nil {
+		return err
+	}
+	_, err := utils.RunChecked(runner, "service.error.command", "chmod +x "+utils.ShellQuote(file)+" && rm -f "+utils.ShellQuote(file+".bak"))
+	return err
+}
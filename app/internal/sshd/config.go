@@ -0,0 +1,195 @@
+// Package sshd управляет сервером OpenSSH из Entware: порт, способы входа, вход под root,
+// список authorized_keys, проверка конфигурации через sshd -t и предупреждения о потере доступа.
+package sshd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/ports"
+)
+
+// Способы входа
+const (
+	AuthPassword = "password" // Только по паролю
+	AuthKey      = "key"      // Только по ключу
+	AuthBoth     = "both"     // По паролю и по ключу
+)
+
+// Политики входа под root (значения PermitRootLogin)
+const (
+	RootYes     = "yes"
+	RootKeyOnly = "prohibit-password"
+	RootNo      = "no"
+
+	rootLoginLegacy = "without-password" // Устаревший синоним prohibit-password
+)
+
+// Значения OpenSSH по умолчанию
+const (
+	DefaultPort      = 22
+	defaultRootLogin = RootKeyOnly
+)
+
+// AuthModes и RootLogins допустимые значения в порядке показа в меню
+var (
+	AuthModes  = []string{AuthKey, AuthBoth, AuthPassword}
+	RootLogins = []string{RootKeyOnly, RootYes, RootNo}
+)
+
+// Settings параметры sshd, которыми управляет терем
+type Settings struct {
+	Port      int    `json:"port"`
+	Auth      string `json:"auth"`      // AuthPassword, AuthKey или AuthBoth
+	RootLogin string `json:"rootLogin"` // RootYes, RootKeyOnly или RootNo
+}
+
+// Ports возвращает порт, который займёт sshd
+func (s Settings) Ports() []ports.Need {
+	return []ports.Need{{Proto: "tcp", Port: s.Port, Role: ports.RoleSSH}}
+}
+
+// Validate проверяет параметры
+func (s Settings) Validate() error {
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf(i18n.T("sshd.error.port"), s.Port)
+	}
+	switch s.Auth {
+	case AuthPassword, AuthKey, AuthBoth:
+	default:
+		return fmt.Errorf(i18n.T("sshd.error.auth"), s.Auth)
+	}
+	switch s.RootLogin {
+	case RootYes, RootKeyOnly, RootNo:
+	default:
+		return fmt.Errorf(i18n.T("sshd.error.root_login"), s.RootLogin)
+	}
+	return nil
+}
+
+// PasswordAllowed сообщает, разрешён ли вход по паролю
+func (s Settings) PasswordAllowed() bool {
+	return s.Auth == AuthPassword || s.Auth == AuthBoth
+}
+
+// KeysAllowed сообщает, разрешён ли вход по ключу
+func (s Settings) KeysAllowed() bool {
+	return s.Auth == AuthKey || s.Auth == AuthBoth
+}
+
+// ParseSettings читает параметры из sshd_config; отсутствующие параметры принимают значения OpenSSH по умолчанию
+func ParseSettings(content string) Settings {
+	s := Settings{Port: DefaultPort, RootLogin: defaultRootLogin}
+	if port, err := strconv.Atoi(Option(content, "Port")); err == nil {
+		s.Port = port
+	}
+	if root := strings.ToLower(Option(content, "PermitRootLogin")); root != "" {
+		if root == rootLoginLegacy {
+			root = RootKeyOnly
+		}
+		s.RootLogin = root
+	}
+
+	password := !strings.EqualFold(Option(content, "PasswordAuthentication"), "no")
+	pubkey := !strings.EqualFold(Option(content, "PubkeyAuthentication"), "no")
+	switch {
+	case password && pubkey:
+		s.Auth = AuthBoth
+	case pubkey:
+		s.Auth = AuthKey
+	default:
+		s.Auth = AuthPassword
+	}
+	return s
+}
+
+// Render записывает параметры в sshd_config, сохраняя остальные строки
+func Render(content string, s Settings) string {
+	yesNo := func(v bool) string {
+		if v {
+			return "yes"
+		}
+		return "no"
+	}
+	content = SetOption(content, "Port", strconv.Itoa(s.Port))
+	content = SetOption(content, "PermitRootLogin", s.RootLogin)
+	content = SetOption(content, "PubkeyAuthentication", yesNo(s.KeysAllowed()))
+	content = SetOption(content, "PasswordAuthentication", yesNo(s.PasswordAllowed()))
+	content = SetOption(content, "KbdInteractiveAuthentication", yesNo(s.PasswordAllowed()))
+	return content
+}
+
+// splitOption разбирает строку конфигурации на ключ и значение; comment — строка закомментирована
+func splitOption(line string) (key, value string, comment bool) {
+	line = strings.TrimSpace(line)
+	if rest, ok := strings.CutPrefix(line, "#"); ok {
+		comment = true
+		line = strings.TrimSpace(rest)
+	}
+	key = line
+	if i := strings.IndexAny(line, " \t="); i >= 0 {
+		key, value = line[:i], line[i:]
+	}
+	return key, strings.Trim(value, "= \t"), comment
+}
+
+// Option возвращает значение параметра до первого блока Match (sshd использует первое вхождение)
+func Option(content, key string) string {
+	for _, line := range strings.Split(content, "\n") {
+		k, v, comment := splitOption(line)
+		if strings.EqualFold(k, "Match") && !comment {
+			break
+		}
+		if !comment && strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// SetOption задаёт значение параметра: заменяет первое действующее вхождение,
+// иначе раскомментирует пример, иначе добавляет строку перед первым блоком Match
+func SetOption(content, key, value string) string {
+	lines := strings.Split(content, "\n")
+	line := key + " " + value
+
+	active, commented, match := -1, -1, -1
+	for i, l := range lines {
+		k, _, comment := splitOption(l)
+		switch {
+		case strings.EqualFold(k, "Match") && !comment:
+			match = i
+		case !strings.EqualFold(k, key):
+			continue
+		case !comment && active < 0:
+			active = i
+		case comment && commented < 0:
+			commented = i
+		}
+		if match >= 0 {
+			break
+		}
+	}
+
+	switch {
+	case active >= 0:
+		lines[active] = line
+	case commented >= 0 && (match < 0 || commented < match):
+		lines[commented] = line
+	case match >= 0:
+		at := match
+		for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		lines = append(lines[:at], append([]string{line}, lines[at:]...)...)
+	default:
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = append(lines[:len(lines)-1], line, "")
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
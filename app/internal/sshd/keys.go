@@ -0,0 +1,108 @@
+package sshd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// keyTypes поддерживаемые типы открытых ключей
+var keyTypes = map[string]bool{
+	"ssh-ed25519":                        true,
+	"ssh-rsa":                            true,
+	"ecdsa-sha2-nistp256":                true,
+	"ecdsa-sha2-nistp384":                true,
+	"ecdsa-sha2-nistp521":                true,
+	"sk-ssh-ed25519@openssh.com":         true,
+	"sk-ecdsa-sha2-nistp256@openssh.com": true,
+}
+
+// Key открытый ключ из authorized_keys
+type Key struct {
+	Options     string `json:"options,omitempty"` // Параметры перед типом ключа (from=, command= и т. п.)
+	Type        string `json:"type"`
+	Blob        string `json:"-"` // Ключ в base64
+	Comment     string `json:"comment,omitempty"`
+	Fingerprint string `json:"fingerprint"` // SHA256:… как в ssh-keygen -l
+}
+
+// String возвращает ключ в формате строки authorized_keys
+func (k Key) String() string {
+	parts := []string{k.Options, k.Type, k.Blob, k.Comment}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// ParseKey разбирает строку authorized_keys (с параметрами или без них)
+func ParseKey(line string) (Key, error) {
+	line = strings.TrimSpace(line)
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if !keyTypes[field] || i+1 >= len(fields) {
+			continue
+		}
+		k := Key{
+			Options: strings.Join(fields[:i], " "),
+			Type:    field,
+			Blob:    fields[i+1],
+			Comment: strings.Join(fields[i+2:], " "),
+		}
+		raw, err := base64.StdEncoding.DecodeString(k.Blob)
+		if err != nil || !blobHasType(raw, k.Type) {
+			return Key{}, fmt.Errorf(i18n.T("sshd.error.key"), shorten(line))
+		}
+		sum := sha256.Sum256(raw)
+		k.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+		return k, nil
+	}
+	return Key{}, fmt.Errorf(i18n.T("sshd.error.key"), shorten(line))
+}
+
+// blobHasType проверяет, что двоичный ключ начинается с имени своего типа
+func blobHasType(raw []byte, keyType string) bool {
+	if len(raw) < 4 {
+		return false
+	}
+	n := binary.BigEndian.Uint32(raw)
+	return int(n) == len(keyType) && len(raw) >= 4+len(keyType) && bytes.Equal(raw[4:4+n], []byte(keyType))
+}
+
+// ParseKeys разбирает файл authorized_keys, пропуская пустые строки, комментарии и некорректные записи
+func ParseKeys(content string) []Key {
+	var keys []Key
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, err := ParseKey(line); err == nil {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// RemoveKey удаляет из файла authorized_keys строки с ключом fingerprint, сохраняя остальные
+func RemoveKey(content, fingerprint string) (string, bool) {
+	var kept []string
+	removed := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if k, err := ParseKey(line); err == nil && k.Fingerprint == fingerprint {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+func shorten(s string) string {
+	if len(s) > 40 {
+		return s[:37] + "..."
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package sshd
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Session текущий сеанс пользователя
+type Session struct {
+	User       string // Имя пользователя
+	ClientIP   string // Адрес клиента (пусто, если сеанс не через SSH)
+	ServerPort int    // Порт SSH-сервера, через который открыт сеанс
+}
+
+// SSH сообщает, открыт ли сеанс через SSH
+func (s Session) SSH() bool {
+	return s.ClientIP != ""
+}
+
+// CurrentSession определяет сеанс по переменным окружения SSH_CONNECTION и USER
+func CurrentSession() Session {
+	s := Session{User: os.Getenv("USER")}
+	if s.User == "" {
+		s.User = os.Getenv("LOGNAME")
+	}
+	if s.User == "" {
+		s.User = "root"
+	}
+	// SSH_CONNECTION: "адрес_клиента порт_клиента адрес_сервера порт_сервера"
+	if fields := strings.Fields(os.Getenv("SSH_CONNECTION")); len(fields) == 4 {
+		s.ClientIP = fields[0]
+		s.ServerPort, _ = strconv.Atoi(fields[3])
+	}
+	return s
+}
+
+// LockoutRisks возвращает предупреждения о возможной потере доступа при переходе от cur к next.
+// keys — число ключей в authorized_keys.
+func LockoutRisks(cur, next Settings, keys int, session Session) []string {
+	var risks []string
+	if next.Auth == AuthKey && keys == 0 {
+		risks = append(risks, i18n.T("sshd.risk.no_keys"))
+	}
+
+	if session.User == "root" {
+		switch {
+		case next.RootLogin == RootNo:
+			risks = append(risks, i18n.T("sshd.risk.root_disabled"))
+		case next.RootLogin == RootKeyOnly && !next.KeysAllowed():
+			risks = append(risks, i18n.T("sshd.risk.root_no_method"))
+		case next.RootLogin == RootKeyOnly && keys == 0 && next.Auth != AuthKey:
+			risks = append(risks, i18n.T("sshd.risk.root_needs_key"))
+		}
+	}
+
+	// Сеанс открыт через настраиваемый sshd: после перезапуска подключаться придётся по-новому
+	if session.SSH() && session.ServerPort == cur.Port && next.Port != cur.Port {
+		risks = append(risks, i18n.T("sshd.risk.port", cur.Port, next.Port))
+	}
+	if session.SSH() && session.ServerPort == cur.Port && cur.PasswordAllowed() && !next.PasswordAllowed() && keys > 0 {
+		risks = append(risks, i18n.T("sshd.risk.password_off"))
+	}
+	return risks
+}
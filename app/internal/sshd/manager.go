@@ -0,0 +1,171 @@
+package sshd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// ConfigPath путь к sshd_config в Entware
+const ConfigPath = "/opt/etc/ssh/sshd_config"
+
+// Service служба OpenSSH в Entware
+var Service = service.Service{Name: "sshd", Package: "openssh-server"}
+
+// Manager управляет сервером OpenSSH
+type Manager struct {
+	Runner     utils.Runner
+	ConfigPath string // По умолчанию ConfigPath
+	KeysFile   string // По умолчанию берётся из AuthorizedKeysFile и домашнего каталога root
+}
+
+func (m Manager) configPath() string {
+	if m.ConfigPath == "" {
+		return ConfigPath
+	}
+	return m.ConfigPath
+}
+
+// Service возвращает службу sshd с исполнителем команд менеджера
+func (m Manager) Service() service.Service {
+	svc := Service
+	svc.Runner = m.Runner
+	return svc
+}
+
+// Load читает sshd_config и возвращает параметры и содержимое файла
+func (m Manager) Load() (Settings, string, error) {
+	content, err := service.ReadFile(m.Runner, m.configPath())
+	if err != nil {
+		return Settings{}, "", err
+	}
+	return ParseSettings(content), content, nil
+}
+
+// Install устанавливает openssh-server и создаёт недостающие ключи хоста
+func (m Manager) Install() error {
+	svc := m.Service()
+	if !svc.Installed() {
+		if err := svc.Install(); err != nil {
+			return err
+		}
+	}
+	if output, err := utils.OrLocal(m.Runner).RunCommand("ssh-keygen -A 2>&1"); err != nil {
+		return fmt.Errorf(i18n.T("sshd.error.host_keys"), strings.TrimSpace(output+" "+err.Error()))
+	}
+	return nil
+}
+
+// Check проверяет конфигурацию командой sshd -t
+func (m Manager) Check() error {
+	output, err := utils.OrLocal(m.Runner).RunCommand("sshd -t -f " + utils.ShellQuote(m.configPath()) + " 2>&1")
+	if err != nil {
+		return fmt.Errorf(i18n.T("sshd.error.check"), strings.TrimSpace(output+" "+err.Error()))
+	}
+	return nil
+}
+
+// Apply записывает параметры, проверяет конфигурацию и перезапускает sshd.
+// Если sshd -t отклоняет конфигурацию, восстанавливается предыдущий файл.
+func (m Manager) Apply(s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	_, content, err := m.Load()
+	if err != nil {
+		return err
+	}
+	file := m.configPath()
+	if err := service.WriteFile(m.Runner, file, Render(content, s)); err != nil {
+		return err
+	}
+	if err := m.Check(); err != nil {
+		_, _ = utils.OrLocal(m.Runner).RunCommand(fmt.Sprintf("mv %s %s", utils.ShellQuote(file+".bak"), utils.ShellQuote(file)))
+		return err
+	}
+	return m.Service().Restart()
+}
+
+// KeysPath возвращает путь к authorized_keys пользователя root
+func (m Manager) KeysPath() string {
+	if m.KeysFile != "" {
+		return m.KeysFile
+	}
+	file := ".ssh/authorized_keys"
+	if content, err := service.ReadFile(m.Runner, m.configPath()); err == nil {
+		if fields := strings.Fields(Option(content, "AuthorizedKeysFile")); len(fields) > 0 {
+			file = fields[0]
+		}
+	}
+	if path.IsAbs(file) {
+		return file
+	}
+
+	home, _ := utils.OrLocal(m.Runner).RunCommand("awk -F: '$1==\"root\"{print $6}' /etc/passwd")
+	home = strings.TrimSpace(home)
+	if home == "" {
+		home = "/root"
+	}
+	return path.Join(home, strings.TrimPrefix(file, "%h/"))
+}
+
+// Keys возвращает ключи из authorized_keys; отсутствующий файл означает пустой список
+func (m Manager) Keys() ([]Key, error) {
+	content, err := utils.OrLocal(m.Runner).RunCommand("cat " + utils.ShellQuote(m.KeysPath()) + " 2>/dev/null || true")
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("service.error.read"), m.KeysPath(), err)
+	}
+	return ParseKeys(content), nil
+}
+
+// AddKey добавляет ключ в authorized_keys, если ключа с таким отпечатком ещё нет
+func (m Manager) AddKey(line string) (Key, error) {
+	key, err := ParseKey(line)
+	if err != nil {
+		return Key{}, err
+	}
+	keys, err := m.Keys()
+	if err != nil {
+		return Key{}, err
+	}
+	for _, k := range keys {
+		if k.Fingerprint == key.Fingerprint {
+			return Key{}, fmt.Errorf(i18n.T("sshd.error.key_exists"), key.Fingerprint)
+		}
+	}
+
+	file := utils.ShellQuote(m.KeysPath())
+	dir := utils.ShellQuote(path.Dir(m.KeysPath()))
+	// Если последняя строка файла не завершена переводом строки, ключ склеился бы с ней
+	command := fmt.Sprintf("mkdir -p %s && chmod 700 %s && { [ -z \"$(tail -c1 %s 2>/dev/null)\" ] || echo >> %s; } && printf '%%s\\n' %s >> %s && chmod 600 %s",
+		dir, dir, file, file, utils.ShellQuote(key.String()), file, file)
+	if _, err := utils.OrLocal(m.Runner).RunCommand(command); err != nil {
+		return Key{}, fmt.Errorf(i18n.T("service.error.write"), m.KeysPath(), err)
+	}
+	return key, nil
+}
+
+// RemoveKey удаляет ключ с отпечатком fingerprint из authorized_keys.
+// Резервная копия, которую оставляет запись файла, тоже удаляется: в ней остался бы отозванный ключ
+func (m Manager) RemoveKey(fingerprint string) error {
+	file := m.KeysPath()
+	content, err := service.ReadFile(m.Runner, file)
+	if err != nil {
+		return err
+	}
+	updated, removed := RemoveKey(content, fingerprint)
+	if !removed {
+		return fmt.Errorf(i18n.T("sshd.error.key_missing"), fingerprint)
+	}
+	if err := service.WriteFile(m.Runner, file, updated); err != nil {
+		return err
+	}
+	if _, err := utils.OrLocal(m.Runner).RunCommand("rm -f " + utils.ShellQuote(file+".bak")); err != nil {
+		return fmt.Errorf(i18n.T("service.error.write"), file+".bak", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package sshd
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+const (
+	aliceKey         = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOHh/oUD6u0fBDtqg+bTavkplO3uJas9N42wesmNBgW3 alice@laptop"
+	aliceFingerprint = "SHA256:qm4pmGqT46cgutuSXE25KmhP+3jruxTQHmTFFvKccjk" // ssh-keygen -lf
+)
+
+const sampleConfig = `# Entware sshd_config
+#Port 22
+#PermitRootLogin prohibit-password
+PasswordAuthentication	yes
+AuthorizedKeysFile	.ssh/authorized_keys
+Subsystem sftp /opt/lib/sftp-server
+
+Match User backup
+	PasswordAuthentication no
+`
+
+func TestParseAndRenderSettings(t *testing.T) {
+	s := ParseSettings(sampleConfig)
+	if want := (Settings{Port: 22, Auth: AuthBoth, RootLogin: RootKeyOnly}); s != want {
+		t.Fatalf("parsed %+v, want %+v", s, want)
+	}
+
+	next := Settings{Port: 2222, Auth: AuthKey, RootLogin: RootNo}
+	content := Render(sampleConfig, next)
+	if got := ParseSettings(content); got != next {
+		t.Errorf("round trip %+v, want %+v\n%s", got, next, content)
+	}
+	// Пример раскомментирован на месте, новые параметры добавлены до блока Match, блок Match не тронут
+	for _, want := range []string{"Port 2222\nPermitRootLogin no\nPasswordAuthentication no\n",
+		"sftp-server\nPubkeyAuthentication yes\nKbdInteractiveAuthentication no\n\nMatch User backup\n\tPasswordAuthentication no\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in:\n%s", want, content)
+		}
+	}
+	if strings.Count(content, "Port ") != 1 {
+		t.Errorf("duplicate Port lines:\n%s", content)
+	}
+
+	if err := (Settings{Port: 0, Auth: AuthKey, RootLogin: RootNo}).Validate(); err == nil {
+		t.Error("expected port error")
+	}
+	if err := (Settings{Port: 22, Auth: "otp", RootLogin: RootNo}).Validate(); err == nil {
+		t.Error("expected auth error")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	k, err := ParseKey(`from="192.168.1.0/24" ` + aliceKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Fingerprint != aliceFingerprint || k.Comment != "alice@laptop" || k.Options != `from="192.168.1.0/24"` {
+		t.Errorf("key = %+v", k)
+	}
+	for _, bad := range []string{"", "ssh-ed25519", "ssh-ed25519 !!!", "ssh-rsa " + strings.Fields(aliceKey)[1]} {
+		if _, err := ParseKey(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+
+	content := "# keys\n" + aliceKey + "\ngarbage\n"
+	if keys := ParseKeys(content); len(keys) != 1 {
+		t.Errorf("ParseKeys = %+v", keys)
+	}
+	updated, removed := RemoveKey(content, aliceFingerprint)
+	if !removed || updated != "# keys\ngarbage" {
+		t.Errorf("RemoveKey = %q, %v", updated, removed)
+	}
+}
+
+func TestLockoutRisks(t *testing.T) {
+	cur := Settings{Port: 22, Auth: AuthBoth, RootLogin: RootYes}
+	root := Session{User: "root", ClientIP: "192.168.1.10", ServerPort: 22}
+
+	if risks := LockoutRisks(cur, cur, 0, root); len(risks) != 0 {
+		t.Errorf("unchanged settings: %v", risks)
+	}
+	if risks := LockoutRisks(cur, Settings{Port: 22, Auth: AuthKey, RootLogin: RootYes}, 0, root); len(risks) != 1 {
+		t.Errorf("key-only without keys: %v", risks)
+	}
+	if risks := LockoutRisks(cur, Settings{Port: 2222, Auth: AuthBoth, RootLogin: RootNo}, 1, root); len(risks) != 2 {
+		t.Errorf("port change and root disabled: %v", risks)
+	}
+	// Сеанс через другой SSH-сервер (например, dropbear) не зависит от порта sshd
+	other := Session{User: "root", ClientIP: "192.168.1.10", ServerPort: 222}
+	if risks := LockoutRisks(cur, Settings{Port: 2222, Auth: AuthBoth, RootLogin: RootYes}, 1, other); len(risks) != 0 {
+		t.Errorf("session via another server: %v", risks)
+	}
+}
+
+// newRunner имитирует роутер с конфигурацией sshd; при failTest проверка конфигурации не проходит
+func newRunner(failTest bool) *testutil.Runner {
+	r := &testutil.Runner{
+		Files:   map[string]string{ConfigPath: sampleConfig},
+		Outputs: map[string]string{"awk ": "/opt/root", "ls /opt/etc/init.d/": "/opt/etc/init.d/S40sshd"},
+	}
+	if failTest {
+		r.Handlers = map[string]func(string) (string, error){
+			"sshd -t": func(string) (string, error) {
+				return "line 3: Bad configuration option", errors.New("exit status 255")
+			},
+		}
+	}
+	return r
+}
+
+func TestManagerApplyAndKeys(t *testing.T) {
+	r := newRunner(false)
+	m := Manager{Runner: r}
+
+	if got := m.KeysPath(); got != "/opt/root/.ssh/authorized_keys" {
+		t.Errorf("KeysPath = %s", got)
+	}
+	if err := m.Apply(Settings{Port: 2222, Auth: AuthKey, RootLogin: RootKeyOnly}); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Ran("sshd -t -f '"+ConfigPath+"'") || !r.Ran("'/opt/etc/init.d/S40sshd' restart") {
+		t.Errorf("expected check and restart: %v", r.Commands)
+	}
+
+	r = newRunner(true)
+	m = Manager{Runner: r}
+	if err := m.Apply(Settings{Port: 2222, Auth: AuthKey, RootLogin: RootKeyOnly}); err == nil || !strings.Contains(err.Error(), "Bad configuration") {
+		t.Fatalf("expected check error, got %v", err)
+	}
+	if !r.Ran("mv '"+ConfigPath+".bak'") || r.Ran("'/opt/etc/init.d/S40sshd' restart") {
+		t.Errorf("expected rollback without restart: %v", r.Commands)
+	}
+
+	key, err := m.AddKey(aliceKey)
+	if err != nil || key.Fingerprint != aliceFingerprint {
+		t.Fatalf("AddKey = %+v, %v", key, err)
+	}
+	if !r.Ran("mkdir -p '/opt/root/.ssh' && chmod 700") {
+		t.Errorf("keys directory not prepared: %v", r.Commands)
+	}
+	if !r.Ran("mkdir -p '/opt/root/.ssh' && chmod 700 '/opt/root/.ssh' && { [ -z \"$(tail -c1 '/opt/root/.ssh/authorized_keys' 2>/dev/null)\" ] || echo >> '/opt/root/.ssh/authorized_keys'; } && printf") {
+		t.Errorf("missing trailing newline is not added before the key: %v", r.Commands)
+	}
+	r.Files["/opt/root/.ssh/authorized_keys"] = aliceKey + "\n"
+	if _, err := m.AddKey(aliceKey); err == nil {
+		t.Error("expected duplicate key error")
+	}
+	keys, err := m.Keys()
+	if err != nil || !reflect.DeepEqual([]string{keys[0].Fingerprint}, []string{aliceFingerprint}) {
+		t.Errorf("Keys = %+v, %v", keys, err)
+	}
+	if err := m.RemoveKey("SHA256:missing"); err == nil {
+		t.Error("expected missing key error")
+	}
+	if err := m.RemoveKey(aliceFingerprint); err != nil {
+		t.Error(err)
+	}
+	if !r.Ran("rm -f '/opt/root/.ssh/authorized_keys.bak'") {
+		t.Errorf("backup with the revoked key is kept: %v", r.Commands)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// DefaultMountRoot каталог, в котором прошивки Keenetic и OpenWrt монтируют накопители
+const DefaultMountRoot = "/tmp/mnt"
+
+// Manager монтирует разделы и управляет файлом подкачки
+type Manager struct {
+	Runner utils.Runner
+	Statfs func(path string) (Usage, error) // Занятость файловой системы (по умолчанию StatFS)
+}
+
+// run выполняет команду и возвращает ошибку с её выводом
+func (m Manager) run(key, command string) error {
+	if output, err := utils.OrLocal(m.Runner).RunCommand(command + " 2>&1"); err != nil {
+		return fmt.Errorf(i18n.T(key), strings.TrimSpace(output+" "+err.Error()))
+	}
+	return nil
+}
+
+// DevicePath возвращает путь к устройству в /dev по имени sda1 или полному пути
+func DevicePath(name string) string {
+	if strings.HasPrefix(name, "/dev/") {
+		return name
+	}
+	return "/dev/" + name
+}
+
+// MountPoint возвращает точку монтирования по умолчанию для раздела
+func MountPoint(name string) string {
+	return path.Join(DefaultMountRoot, path.Base(name))
+}
+
+// Mount создаёт точку монтирования и монтирует раздел; тип файловой системы определяет mount
+func (m Manager) Mount(device, point string) error {
+	point = path.Clean(point)
+	if !path.IsAbs(point) {
+		return fmt.Errorf(i18n.T("storage.error.point"), point)
+	}
+	return m.run("storage.error.mount", fmt.Sprintf("mkdir -p %s && mount %s %s",
+		utils.ShellQuote(point), utils.ShellQuote(DevicePath(device)), utils.ShellQuote(point)))
+}
+
+// Unmount отмонтирует файловую систему. Корень, EntwareRoot и каталоги над ним
+// не отмонтируются: без них перестанут работать терем и пакеты Entware
+func (m Manager) Unmount(point string) error {
+	point = path.Clean(point)
+	if Protected(point) {
+		return fmt.Errorf(i18n.T("storage.error.protected"), point)
+	}
+	return m.run("storage.error.unmount", "umount "+utils.ShellQuote(point))
+}
+
+// Protected сообщает, что точку монтирования нельзя отмонтировать
+func Protected(point string) bool {
+	point = path.Clean(point)
+	return point == "/" || point == EntwareRoot || strings.HasPrefix(EntwareRoot, point+"/")
+}
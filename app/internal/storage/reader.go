@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// Пути по умолчанию
+const (
+	DefaultSysRoot  = "/sys"
+	DefaultProcRoot = "/proc"
+	EntwareRoot     = "/opt" // Каталог Entware, место на котором проверяется всегда
+)
+
+// DefaultFreeThreshold порог свободного места, %, ниже которого выводится предупреждение
+const DefaultFreeThreshold = 10
+
+// sectorSize размер сектора, в котором ядро указывает размеры и счётчики в /sys/block
+const sectorSize = 512
+
+// Reader читает сведения о накопителях из sysfs и procfs
+type Reader struct {
+	SysRoot  string                           // Каталог sysfs (по умолчанию DefaultSysRoot)
+	ProcRoot string                           // Каталог procfs (по умолчанию DefaultProcRoot)
+	Statfs   func(path string) (Usage, error) // Занятость файловой системы (по умолчанию StatFS)
+}
+
+func (r Reader) sysRoot() string {
+	if r.SysRoot == "" {
+		return DefaultSysRoot
+	}
+	return r.SysRoot
+}
+
+func (r Reader) procRoot() string {
+	if r.ProcRoot == "" {
+		return DefaultProcRoot
+	}
+	return r.ProcRoot
+}
+
+func (r Reader) statfs(path string) (Usage, error) {
+	if r.Statfs == nil {
+		return StatFS(path)
+	}
+	return r.Statfs(path)
+}
+
+// Mounts читает /proc/mounts
+func (r Reader) Mounts() ([]Mount, error) {
+	content, err := os.ReadFile(filepath.Join(r.procRoot(), "mounts"))
+	if err != nil {
+		return nil, errors.New(i18n.T("storage.error.mounts"))
+	}
+	return ParseMounts(string(content)), nil
+}
+
+// Devices возвращает накопители с разделами, точками монтирования и занятостью
+func (r Reader) Devices() ([]Device, error) {
+	block := filepath.Join(r.sysRoot(), "block")
+	entries, err := os.ReadDir(block)
+	if err != nil {
+		return nil, errors.New(i18n.T("storage.error.sys"))
+	}
+	mounts, _ := r.Mounts()
+
+	var devices []Device
+	for _, e := range entries {
+		if !physical(e.Name()) {
+			continue
+		}
+		dir := filepath.Join(block, e.Name())
+		d := Device{
+			Volume:     r.volume(dir, e.Name(), mounts),
+			Model:      readString(dir, "device/model"),
+			Vendor:     readString(dir, "device/vendor"),
+			Removable:  readString(dir, "removable") == "1",
+			Rotational: readString(dir, "queue/rotational") == "1",
+			Health:     readHealth(dir),
+		}
+		if d.Size == 0 {
+			continue
+		}
+		if strings.HasPrefix(d.Name, "mmcblk") {
+			d.Transport = "mmc"
+		} else if target, err := filepath.EvalSymlinks(dir); err == nil && strings.Contains(target, "/usb") {
+			d.Transport = "usb"
+		}
+
+		parts, _ := os.ReadDir(dir)
+		for _, p := range parts {
+			if _, err := os.Stat(filepath.Join(dir, p.Name(), "partition")); err == nil {
+				d.Partitions = append(d.Partitions, r.volume(filepath.Join(dir, p.Name()), p.Name(), mounts))
+			}
+		}
+		sort.Slice(d.Partitions, func(i, j int) bool { return d.Partitions[i].Name < d.Partitions[j].Name })
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// volume читает размер раздела и дополняет его сведениями о монтировании
+func (r Reader) volume(dir, name string, mounts []Mount) Volume {
+	sectors, _ := strconv.ParseUint(readString(dir, "size"), 10, 64)
+	v := Volume{
+		Name:     name,
+		Size:     sectors * sectorSize,
+		ReadOnly: readString(dir, "ro") == "1",
+	}
+	for _, m := range mounts {
+		if m.Device == "/dev/"+name {
+			v.FSType = m.FSType
+			v.MountPoint = m.Point
+			if usage, err := r.statfs(m.Point); err == nil {
+				v.Usage = usage
+			}
+			break
+		}
+	}
+	return v
+}
+
+// readHealth собирает признаки состояния: состояние устройства, ошибки и объём чтения и записи
+func readHealth(dir string) Health {
+	h := Health{State: readString(dir, "device/state")}
+	if v, err := strconv.ParseUint(strings.TrimPrefix(readString(dir, "device/ioerr_cnt"), "0x"), 16, 64); err == nil {
+		h.IOErrors = v
+	}
+	// Поля stat: чтения, слияния, секторы прочитано, время, записи, слияния, секторы записано
+	if fields := strings.Fields(readString(dir, "stat")); len(fields) >= 7 {
+		read, _ := strconv.ParseUint(fields[2], 10, 64)
+		written, _ := strconv.ParseUint(fields[6], 10, 64)
+		h.Read, h.Written = read*sectorSize, written*sectorSize
+	}
+	return h
+}
+
+// readString возвращает содержимое файла sysfs без пробелов по краям или пустую строку
+func readString(dir, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// Alert предупреждение о нехватке места на файловой системе
+type Alert struct {
+	Device string `json:"device"`
+	Point  string `json:"point"`
+	Usage  Usage  `json:"usage"`
+}
+
+// Alerts проверяет свободное место в EntwareRoot и на смонтированных накопителях и возвращает
+// файловые системы, где доступно меньше threshold процентов. Каждое устройство проверяется один раз
+func (r Reader) Alerts(threshold int) ([]Alert, error) {
+	mounts, err := r.Mounts()
+	if err != nil {
+		return nil, err
+	}
+	var alerts []Alert
+	seen := make(map[string]bool)
+	for _, m := range mounts {
+		onDisk := strings.HasPrefix(m.Device, "/dev/") && physical(filepath.Base(m.Device))
+		if (!onDisk && m.Point != EntwareRoot) || seen[m.Device] {
+			continue
+		}
+		seen[m.Device] = true
+		usage, err := r.statfs(m.Point)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		if usage.FreePercent() < float64(threshold) {
+			alerts = append(alerts, Alert{Device: m.Device, Point: m.Point, Usage: usage})
+		}
+	}
+	return alerts, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package storage
+
+import (
+	"errors"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// StatFS не поддерживается на этой платформе
+func StatFS(string) (Usage, error) {
+	return Usage{}, errors.New(i18n.T("storage.error.unsupported"))
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// StatFS возвращает занятость файловой системы, на которой находится path
+func StatFS(path string) (Usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Usage{}, fmt.Errorf(i18n.T("storage.error.statfs"), path, err)
+	}
+	bsize := uint64(st.Bsize)
+	return Usage{
+		Total: uint64(st.Blocks) * bsize,
+		Free:  uint64(st.Bfree) * bsize,
+		Avail: uint64(st.Bavail) * bsize,
+	}, nil
+}
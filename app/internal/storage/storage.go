@@ -0,0 +1,137 @@
+// Package storage показывает накопители роутера по данным /sys/block и /proc/mounts:
+// устройства, разделы, файловые системы, точки монтирования, свободное место и
+// признаки неисправности, а также монтирует разделы и следит за порогом свободного места.
+package storage
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Volume раздел или устройство целиком, если на нём нет таблицы разделов
+type Volume struct {
+	Name       string `json:"name"`                 // Имя в /dev: sda1, mmcblk0p1
+	Size       uint64 `json:"size"`                 // Размер, байт
+	ReadOnly   bool   `json:"readOnly,omitempty"`   // Запись запрещена на уровне устройства
+	FSType     string `json:"fsType,omitempty"`     // Файловая система (известна для смонтированных)
+	MountPoint string `json:"mountPoint,omitempty"` // Первая точка монтирования
+	Usage      Usage  `json:"usage,omitzero"`       // Занятость файловой системы
+}
+
+// Mounted сообщает, что раздел смонтирован
+func (v Volume) Mounted() bool {
+	return v.MountPoint != ""
+}
+
+// Health признаки состояния устройства, доступные без smartctl
+type Health struct {
+	State    string `json:"state,omitempty"` // Состояние SCSI-устройства: running, offline и т. д.
+	IOErrors uint64 `json:"ioErrors"`        // Счётчик ошибок ввода-вывода ioerr_cnt
+	Read     uint64 `json:"read"`            // Прочитано с загрузки, байт
+	Written  uint64 `json:"written"`         // Записано с загрузки, байт
+}
+
+// OK сообщает, что устройство работает и ошибок ввода-вывода не было
+func (h Health) OK() bool {
+	return (h.State == "" || h.State == "running") && h.IOErrors == 0
+}
+
+// Device блочное устройство из /sys/block
+type Device struct {
+	Volume
+	Model      string   `json:"model,omitempty"`
+	Vendor     string   `json:"vendor,omitempty"`
+	Transport  string   `json:"transport,omitempty"` // usb, mmc или пусто
+	Removable  bool     `json:"removable,omitempty"`
+	Rotational bool     `json:"rotational,omitempty"`
+	Health     Health   `json:"health"`
+	Partitions []Volume `json:"partitions,omitempty"`
+}
+
+// Volumes возвращает разделы устройства или само устройство, если разделов нет
+func (d Device) Volumes() []Volume {
+	if len(d.Partitions) > 0 {
+		return d.Partitions
+	}
+	return []Volume{d.Volume}
+}
+
+// Usage занятость файловой системы, байт
+type Usage struct {
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`  // Свободно, включая резерв root
+	Avail uint64 `json:"avail"` // Доступно обычным пользователям
+}
+
+// Used возвращает занятый объём
+func (u Usage) Used() uint64 {
+	return u.Total - min(u.Free, u.Total)
+}
+
+// FreePercent возвращает долю доступного места, %
+func (u Usage) FreePercent() float64 {
+	if u.Total == 0 {
+		return 100
+	}
+	return float64(u.Avail) * 100 / float64(u.Total)
+}
+
+// Mount запись /proc/mounts
+type Mount struct {
+	Device  string   `json:"device"`
+	Point   string   `json:"point"`
+	FSType  string   `json:"fsType"`
+	Options []string `json:"options,omitempty"`
+}
+
+// ReadOnly сообщает, что файловая система смонтирована только для чтения
+func (m Mount) ReadOnly() bool {
+	return slices.Contains(m.Options, "ro")
+}
+
+// ParseMounts разбирает /proc/mounts; пробелы и другие символы в путях
+// записаны ядром восьмеричными последовательностями вида \040
+func ParseMounts(content string) []Mount {
+	var mounts []Mount
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mounts = append(mounts, Mount{
+			Device:  unescape(fields[0]),
+			Point:   unescape(fields[1]),
+			FSType:  fields[2],
+			Options: strings.Split(fields[3], ","),
+		})
+	}
+	return mounts
+}
+
+// unescape раскрывает восьмеричные последовательности /proc/mounts
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// skipDevices префиксы виртуальных устройств, которые не показываются
+var skipDevices = []string{"loop", "ram", "zram", "mtdblock", "dm-", "nbd", "sr", "ubiblock"}
+
+// physical сообщает, что устройство в /sys/block — накопитель, а не виртуальное устройство
+func physical(name string) bool {
+	return !slices.ContainsFunc(skipDevices, func(prefix string) bool { return strings.HasPrefix(name, prefix) })
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qzeleza/terem/internal/procs"
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+func TestParseMounts(t *testing.T) {
+	mounts := ParseMounts("rootfs / rootfs rw 0 0\n/dev/sda1 /opt ext4 rw,noatime 0 0\n/dev/sdb1 /tmp/mnt/My\\040Disk vfat ro,relatime 0 0\n\nbad line\n")
+	if len(mounts) != 3 {
+		t.Fatalf("mounts = %+v", mounts)
+	}
+	if m := mounts[2]; m.Point != "/tmp/mnt/My Disk" || m.FSType != "vfat" || !m.ReadOnly() || mounts[1].ReadOnly() {
+		t.Errorf("mount = %+v", m)
+	}
+	if got := unescape(`a\04`); got != `a\04` {
+		t.Errorf("truncated escape = %q", got)
+	}
+}
+
+func TestReader(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usb := "sys/devices/platform/usb1/1-1/host0/block/sda"
+	write(usb+"/size", "62521344\n")
+	write(usb+"/removable", "1\n")
+	write(usb+"/queue/rotational", "0\n")
+	write(usb+"/device/model", "Ultra           \n")
+	write(usb+"/device/vendor", "SanDisk \n")
+	write(usb+"/device/state", "running\n")
+	write(usb+"/device/ioerr_cnt", "0x2\n")
+	write(usb+"/stat", "100 0 2048 10 50 0 4096 20 0 30 30\n")
+	write(usb+"/sda1/partition", "1\n")
+	write(usb+"/sda1/size", "2097152\n")
+	write(usb+"/sda2/partition", "2\n")
+	write(usb+"/sda2/size", "60000000\n")
+	write("sys/block/mmcblk0/size", "1024\n")
+	write("sys/block/loop0/size", "1024\n")
+	write("sys/block/mtdblock0/size", "1024\n")
+	if err := os.Symlink(filepath.Join(root, usb), filepath.Join(root, "sys/block/sda")); err != nil {
+		t.Fatal(err)
+	}
+	write("proc/mounts", "/dev/sda1 /opt ext4 rw 0 0\n/dev/sda1 /tmp/mnt/opt ext4 rw 0 0\n/dev/sda2 /tmp/mnt/data vfat rw 0 0\ntmpfs /tmp tmpfs rw 0 0\n")
+
+	usage := map[string]Usage{
+		"/opt":          {Total: 1000, Free: 80, Avail: 50},
+		"/tmp/mnt/data": {Total: 1000, Free: 600, Avail: 600},
+		"/tmp":          {Total: 1000, Free: 0, Avail: 0},
+	}
+	r := Reader{
+		SysRoot:  filepath.Join(root, "sys"),
+		ProcRoot: filepath.Join(root, "proc"),
+		Statfs: func(path string) (Usage, error) {
+			if u, ok := usage[path]; ok {
+				return u, nil
+			}
+			return Usage{}, errors.New("no such mount")
+		},
+	}
+
+	devices, err := r.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 || devices[0].Name != "mmcblk0" || devices[1].Name != "sda" {
+		t.Fatalf("devices = %+v", devices)
+	}
+	if mmc := devices[0]; mmc.Transport != "mmc" || len(mmc.Volumes()) != 1 || mmc.Volumes()[0].Name != "mmcblk0" {
+		t.Errorf("mmc = %+v", mmc)
+	}
+	sda := devices[1]
+	if sda.Transport != "usb" || !sda.Removable || sda.Rotational || sda.Model != "Ultra" || sda.Vendor != "SanDisk" || sda.Size != 62521344*512 {
+		t.Errorf("sda = %+v", sda)
+	}
+	if h := sda.Health; h.OK() || h.IOErrors != 2 || h.Read != 2048*512 || h.Written != 4096*512 {
+		t.Errorf("health = %+v", h)
+	}
+	if len(sda.Partitions) != 2 {
+		t.Fatalf("partitions = %+v", sda.Partitions)
+	}
+	if p := sda.Partitions[0]; p.MountPoint != "/opt" || p.FSType != "ext4" || p.Usage.Avail != 50 || !p.Mounted() {
+		t.Errorf("sda1 = %+v", p)
+	}
+
+	alerts, err := r.Alerts(DefaultFreeThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Point != "/opt" || alerts[0].Device != "/dev/sda1" {
+		t.Errorf("alerts = %+v", alerts)
+	}
+
+	if _, err := (Reader{SysRoot: t.TempDir()}).Devices(); err == nil {
+		t.Error("empty sysfs accepted")
+	}
+}
+
+func TestManager(t *testing.T) {
+	f := &testutil.Runner{}
+	m := Manager{Runner: f}
+	if err := m.Mount("sda2", "/tmp/mnt/My Disk"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Commands[0]; !strings.Contains(got, "mount '/dev/sda2' '/tmp/mnt/My Disk'") {
+		t.Errorf("mount command = %q", got)
+	}
+	if err := m.Mount("sda2", "data"); err == nil {
+		t.Error("relative mount point accepted")
+	}
+	for _, point := range []string{"/", "/opt", "/opt/"} {
+		if err := m.Unmount(point); err == nil {
+			t.Errorf("%s unmounted", point)
+		}
+	}
+	if err := m.Unmount("/tmp/mnt/data"); err != nil {
+		t.Error(err)
+	}
+
+	if err := m.CreateSwap(DefaultSwapPath, 1<<20); err == nil {
+		t.Error("tiny swap accepted")
+	}
+	f.Commands = nil
+	if err := m.CreateSwap(DefaultSwapPath, 64<<20); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Ran("count=64") || !strings.HasPrefix(f.Commands[len(f.Commands)-1], "swapon '/opt/swap'") {
+		t.Errorf("swap commands = %q", f.Commands)
+	}
+	f.Fail, f.Commands = "mkswap", nil
+	if err := m.CreateSwap(DefaultSwapPath, 64<<20); err == nil {
+		t.Error("mkswap failure ignored")
+	}
+	if last := f.Commands[len(f.Commands)-1]; last != "rm -f '/opt/swap'" {
+		t.Errorf("cleanup = %q", last)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	swaps := ParseSwaps("Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n/opt/swap                               file\t\t131068\t\t1024\t\t-2\n/dev/zram0 partition 65532 0 100\n")
+	if len(swaps) != 2 || swaps[0].Path != "/opt/swap" || swaps[0].Size != 131068*1024 || swaps[0].Used != 1024*1024 || swaps[0].Priority != -2 {
+		t.Fatalf("swaps = %+v", swaps)
+	}
+
+	if SwapAdvised(procs.Memory{Total: 128 << 20, Available: 100 << 20}) {
+		t.Error("swap advised with enough memory")
+	}
+	if !SwapAdvised(procs.Memory{Total: 128 << 20, Available: 30 << 20}) {
+		t.Error("swap not advised on low memory")
+	}
+	if SwapAdvised(procs.Memory{Total: 128 << 20, Available: 30 << 20, SwapTotal: 64 << 20}) {
+		t.Error("swap advised when it is already enabled")
+	}
+	for total, want := range map[uint64]uint64{4 << 20: MinSwapSize, 120<<20 + 12345: 240 << 20, 1 << 30: 512 << 20} {
+		if got := SuggestedSwapSize(total); got != want {
+			t.Errorf("SuggestedSwapSize(%d) = %d, want %d", total, got, want)
+		}
+	}
+
+	f := &testutil.Runner{Outputs: map[string]string{
+		"[ -f '/opt/swap' ]":       "134217728\n",
+		"cat /proc/swaps":          "Filename Type Size Used Priority\n/opt/swap file 131068 2048 -2\n/dev/zram0 partition 65532 0 100\n",
+		"cat '" + SwapScript + "'": swapScript("/opt/swap"),
+	}}
+	m := Manager{Runner: f}
+	s, err := m.SwapStatus("/opt/swap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Exists || s.Size != 128<<20 || !s.Active || s.Used != 2048*1024 || !s.Persistent || len(s.Other) != 1 {
+		t.Errorf("status = %+v", s)
+	}
+	if other, _ := m.SwapStatus("/opt/other"); other.Persistent || other.Active {
+		t.Errorf("other file status = %+v", other)
+	}
+
+	f.Commands = nil
+	if err := m.ResizeSwap("/opt/swap", 256<<20); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Ran("swapoff '/opt/swap'") || !f.Ran("count=256") || !strings.HasPrefix(f.Commands[len(f.Commands)-1], "swapon '/opt/swap'") {
+		t.Errorf("resize commands = %q", f.Commands)
+	}
+
+	f.Commands = nil
+	if err := m.PersistSwap("/opt/swap", true); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Ran(SwapScript) || !f.Ran("chmod +x") {
+		t.Errorf("persist commands = %q", f.Commands)
+	}
+
+	f.Commands = nil
+	if err := m.RemoveSwap("/opt/swap"); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Ran("swapoff") || !f.Ran("rm -f '"+SwapScript+"'") || f.Commands[len(f.Commands)-1] != "rm -f '/opt/swap' 2>&1" {
+		t.Errorf("remove commands = %q", f.Commands)
+	}
+}
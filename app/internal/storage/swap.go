@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/procs"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+	"github.com/qzeleza/terem/internal/zlog"
+)
+
+// Файл подкачки
+const (
+	DefaultSwapPath = EntwareRoot + "/swap"
+	MinSwapSize     = 16 << 20 // Наименьший размер, байт
+	MaxSwapSize     = 2 << 30  // Наибольший размер, байт
+)
+
+// SwapScript init-скрипт Entware, который включает файл подкачки после перезагрузки.
+// S01 — чтобы подкачка появилась раньше служб, которым она нужна
+const SwapScript = "/opt/etc/init.d/S01terem-swap"
+
+// LowMemory доступная память, ниже которой перед установкой тяжёлых приложений советуется подкачка
+const LowMemory = zlog.TinyMemory
+
+// suggestedSwapMax наибольший размер подкачки, который предлагается по умолчанию
+const suggestedSwapMax = 512 << 20
+
+// swapScript возвращает init-скрипт для файла подкачки
+func swapScript(file string) string {
+	return `#!/bin/sh
+# Создан теремом: включает файл подкачки после перезагрузки
+SWAP=` + utils.ShellQuote(file) + `
+case "$1" in
+	start|restart)
+		[ -f "$SWAP" ] && ! grep -q "^$SWAP " /proc/swaps && swapon "$SWAP"
+		;;
+	stop)
+		swapoff "$SWAP"
+		;;
+esac
+`
+}
+
+// Swap запись /proc/swaps
+type Swap struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"` // file или partition
+	Size     uint64 `json:"size"` // Размер, байт
+	Used     uint64 `json:"used"` // Занято, байт
+	Priority int    `json:"priority"`
+}
+
+// ParseSwaps разбирает /proc/swaps; размеры в нём указаны в КБ
+func ParseSwaps(content string) []Swap {
+	var swaps []Swap
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] == "Filename" {
+			continue
+		}
+		size, _ := strconv.ParseUint(fields[2], 10, 64)
+		used, _ := strconv.ParseUint(fields[3], 10, 64)
+		priority, _ := strconv.Atoi(fields[4])
+		swaps = append(swaps, Swap{
+			Path:     unescape(fields[0]),
+			Type:     fields[1],
+			Size:     size * 1024,
+			Used:     used * 1024,
+			Priority: priority,
+		})
+	}
+	return swaps
+}
+
+// Swaps читает /proc/swaps
+func (r Reader) Swaps() ([]Swap, error) {
+	content, err := os.ReadFile(filepath.Join(r.procRoot(), "swaps"))
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("storage.error.swaps"), err)
+	}
+	return ParseSwaps(string(content)), nil
+}
+
+// SwapAdvised сообщает, что перед установкой тяжёлого приложения стоит включить подкачку:
+// её нет, а доступной памяти меньше LowMemory
+func SwapAdvised(mem procs.Memory) bool {
+	return mem.Total > 0 && mem.SwapTotal == 0 && mem.Available < LowMemory
+}
+
+// SuggestedSwapSize возвращает размер подкачки для объёма памяти: вдвое больше памяти,
+// но не меньше MinSwapSize и не больше 512 МБ
+func SuggestedSwapSize(total uint64) uint64 {
+	return max(MinSwapSize, min(suggestedSwapMax, 2*total)) >> 20 << 20
+}
+
+// SwapFile состояние файла подкачки
+type SwapFile struct {
+	Path       string `json:"path"`
+	Exists     bool   `json:"exists"`
+	Size       uint64 `json:"size"`            // Размер файла, байт
+	Active     bool   `json:"active"`          // Подкачка включена
+	Used       uint64 `json:"used"`            // Занято, байт
+	Persistent bool   `json:"persistent"`      // Включается после перезагрузки
+	Other      []Swap `json:"other,omitempty"` // Другие включённые области подкачки
+}
+
+// SwapStatus возвращает состояние файла подкачки и другие включённые области подкачки
+func (m Manager) SwapStatus(file string) (SwapFile, error) {
+	s := SwapFile{Path: file}
+	quoted := utils.ShellQuote(file)
+	if output, err := utils.OrLocal(m.Runner).RunCommand("[ -f " + quoted + " ] && wc -c < " + quoted); err == nil {
+		s.Exists = true
+		s.Size, _ = strconv.ParseUint(strings.TrimSpace(output), 10, 64)
+	}
+	content, err := utils.OrLocal(m.Runner).RunCommand("cat /proc/swaps")
+	if err != nil {
+		return s, fmt.Errorf(i18n.T("storage.error.swaps"), err)
+	}
+	for _, sw := range ParseSwaps(content) {
+		if sw.Path == file {
+			s.Active, s.Used = true, sw.Used
+		} else {
+			s.Other = append(s.Other, sw)
+		}
+	}
+	if script, err := service.ReadFile(m.Runner, SwapScript); err == nil {
+		s.Persistent = strings.Contains(script, "SWAP="+quoted+"\n")
+	}
+	return s, nil
+}
+
+// checkSwapSize проверяет размер файла подкачки
+func checkSwapSize(size uint64) error {
+	if size < MinSwapSize || size > MaxSwapSize {
+		return fmt.Errorf(i18n.T("storage.error.swap_size"), utils.FormatBytes(size),
+			utils.FormatBytes(MinSwapSize), utils.FormatBytes(MaxSwapSize))
+	}
+	return nil
+}
+
+// makeSwap создаёт и форматирует файл подкачки размером size байт. Если места
+// недостаточно или команда не выполнилась, файл удаляется
+func (m Manager) makeSwap(file string, size uint64) error {
+	if err := checkSwapSize(size); err != nil {
+		return err
+	}
+	if utils.IsLocal(m.Runner) {
+		statfs := m.Statfs
+		if statfs == nil {
+			statfs = StatFS
+		}
+		if usage, err := statfs(path.Dir(file)); err == nil && usage.Avail < size {
+			return fmt.Errorf(i18n.T("storage.error.no_space"), path.Dir(file), utils.FormatBytes(usage.Avail), utils.FormatBytes(size))
+		}
+	}
+
+	quoted := utils.ShellQuote(file)
+	err := m.run("storage.error.swap", fmt.Sprintf("dd if=/dev/zero of=%s bs=1M count=%d && chmod 600 %s && mkswap %s",
+		quoted, size>>20, quoted, quoted))
+	if err != nil {
+		_, _ = utils.OrLocal(m.Runner).RunCommand("rm -f " + quoted)
+	}
+	return err
+}
+
+// CreateSwap создаёт файл подкачки размером size байт, форматирует и включает его.
+// При ошибке созданный файл удаляется
+func (m Manager) CreateSwap(file string, size uint64) error {
+	if err := checkSwapSize(size); err != nil {
+		return err
+	}
+	if _, err := utils.OrLocal(m.Runner).RunCommand("[ ! -e " + utils.ShellQuote(file) + " ]"); err != nil {
+		return fmt.Errorf(i18n.T("storage.error.swap_exists"), file)
+	}
+	if err := m.makeSwap(file, size); err != nil {
+		return err
+	}
+	if err := m.EnableSwap(file); err != nil {
+		_, _ = utils.OrLocal(m.Runner).RunCommand("rm -f " + utils.ShellQuote(file))
+		return err
+	}
+	return nil
+}
+
+// EnableSwap включает файл подкачки
+func (m Manager) EnableSwap(file string) error {
+	return m.run("storage.error.swapon", "swapon "+utils.ShellQuote(file))
+}
+
+// DisableSwap выключает файл подкачки; ядро возвращает занятые страницы в память,
+// поэтому при её нехватке команда завершается ошибкой
+func (m Manager) DisableSwap(file string) error {
+	return m.run("storage.error.swapoff", "swapoff "+utils.ShellQuote(file))
+}
+
+// ResizeSwap пересоздаёт файл подкачки с новым размером; включённая подкачка
+// выключается на время пересоздания и включается снова
+func (m Manager) ResizeSwap(file string, size uint64) error {
+	if err := checkSwapSize(size); err != nil {
+		return err
+	}
+	s, err := m.SwapStatus(file)
+	if err != nil {
+		return err
+	}
+	if !s.Exists {
+		return fmt.Errorf(i18n.T("storage.error.swap_missing"), file)
+	}
+	if s.Active {
+		if err := m.DisableSwap(file); err != nil {
+			return err
+		}
+	}
+	if err := m.run("storage.error.swap", "rm -f "+utils.ShellQuote(file)); err != nil {
+		return err
+	}
+	if err := m.makeSwap(file, size); err != nil {
+		return err
+	}
+	if s.Active {
+		return m.EnableSwap(file)
+	}
+	return nil
+}
+
+// PersistSwap устанавливает init-скрипт, включающий файл подкачки после перезагрузки,
+// или удаляет его
+func (m Manager) PersistSwap(file string, on bool) error {
+	if !on {
+		return m.run("storage.error.persist", "rm -f "+utils.ShellQuote(SwapScript))
+	}
+	if err := service.WriteFile(m.Runner, SwapScript, swapScript(file)); err != nil {
+		return err
+	}
+	return m.run("storage.error.persist", "chmod +x "+utils.ShellQuote(SwapScript)+" && rm -f "+utils.ShellQuote(SwapScript+".bak"))
+}
+
+// RemoveSwap выключает и удаляет файл подкачки вместе с init-скриптом, если он для этого файла
+func (m Manager) RemoveSwap(file string) error {
+	s, err := m.SwapStatus(file)
+	if err != nil {
+		return err
+	}
+	if s.Active {
+		if err := m.DisableSwap(file); err != nil {
+			return err
+		}
+	}
+	if s.Persistent {
+		if err := m.PersistSwap(file, false); err != nil {
+			return err
+		}
+	}
+	return m.run("storage.error.swap", "rm -f "+utils.ShellQuote(file))
+}
@@ -0,0 +1,88 @@
+// Package testutil содержит общие заготовки для тестов пакетов, выполняющих команды на роутере
+package testutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// Runner имитирует роутер для тестов: запоминает выполненные команды,
+// отдаёт вывод Outputs по началу команды и содержимое Files на cat
+type Runner struct {
+	Files    map[string]string                               // Содержимое файлов по пути
+	Outputs  map[string]string                               // Вывод команд по их началу
+	Handlers map[string]func(command string) (string, error) // Ответ на команды по их началу
+	Fail     string                                          // Команды с этой подстрокой завершаются ошибкой
+	Strict   bool                                            // Команды без ответа завершаются ошибкой
+	Commands []string                                        // Выполненные команды по порядку
+}
+
+// RunCommand запоминает команду и возвращает ответ: ошибку для Fail, результат обработчика,
+// вывод из Outputs или содержимое файла для cat (отсутствующий файл — ошибка,
+// если команда не гасит её «|| true»). Остальные команды выполняются успешно без вывода,
+// а при Strict завершаются ошибкой
+func (r *Runner) RunCommand(command string) (string, error) {
+	r.Commands = append(r.Commands, command)
+	if r.Fail != "" && strings.Contains(command, r.Fail) {
+		return "failed", errors.New("exit status 1")
+	}
+	for prefix, handle := range r.Handlers {
+		if strings.HasPrefix(command, prefix) {
+			return handle(command)
+		}
+	}
+	for prefix, output := range r.Outputs {
+		if strings.HasPrefix(command, prefix) {
+			return output, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(command, "cat "); ok {
+		content, found := r.Files[catPath(rest)]
+		if !found && !strings.Contains(command, "|| true") {
+			return "", errors.New("no such file")
+		}
+		return content, nil
+	}
+	if r.Strict {
+		return "", errors.New("command failed: " + command)
+	}
+	return "", nil
+}
+
+// catPath извлекает путь из аргументов cat, в том числе заключённый в одинарные кавычки
+func catPath(args string) string {
+	if rest, ok := strings.CutPrefix(args, "'"); ok {
+		if i := strings.Index(rest, "'"); i >= 0 {
+			return rest[:i]
+		}
+	}
+	if fields := strings.Fields(args); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// Find возвращает выполненные команды с подстрокой part
+func (r *Runner) Find(part string) []string {
+	var found []string
+	for _, c := range r.Commands {
+		if strings.Contains(c, part) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// Ran сообщает, выполнялась ли команда с подстрокой part
+func (r *Runner) Ran(part string) bool {
+	return len(r.Find(part)) > 0
+}
+
+// Arg возвращает n-й аргумент команды без одинарных кавычек
+func Arg(command string, n int) string {
+	fields := strings.Fields(command)
+	if n >= len(fields) {
+		return ""
+	}
+	return strings.Trim(fields[n], "'")
+}
@@ -0,0 +1,206 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+)
+
+// binaryName имя исполняемого файла в архиве сборки
+const binaryName = "terem"
+
+// Суффиксы временных файлов рядом с исполняемым
+const (
+	newSuffix    = ".new" // Загруженная сборка до замены
+	backupSuffix = ".old" // Прежняя версия для отката
+)
+
+// checkTimeout сколько ждать пробного запуска новой версии
+const checkTimeout = 15 * time.Second
+
+// SignedMessage возвращает сообщение, которое подписывается для сборки a выпуска version.
+// Кроме контрольной суммы файла подписываются версия и архитектура: подпись одного
+// содержимого позволила бы выдать старую подписанную сборку за новую (откат на уязвимую
+// версию) или за сборку для другой архитектуры. Адрес ленты не подписывается, поэтому
+// подписанный выпуск можно раздавать с зеркала или из локальной ленты file://
+func SignedMessage(version string, a Asset) []byte {
+	return fmt.Appendf(nil, "terem-update\nversion %s\narch %s\nsha256 %s\n",
+		version, a.Arch, strings.ToLower(strings.TrimSpace(a.SHA256)))
+}
+
+// Verify проверяет контрольную сумму содержимого сборки a выпуска version
+// и подпись SignedMessage ключом key в base64
+func Verify(data []byte, version string, a Asset, key string) error {
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), strings.TrimSpace(a.SHA256)) {
+		return fmt.Errorf(i18n.T("update.error.checksum"), a.URL)
+	}
+	if key == "" {
+		return errors.New(i18n.T("update.error.no_key"))
+	}
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New(i18n.T("update.error.key"))
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(a.Signature))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(pub), SignedMessage(version, a), sig) {
+		return fmt.Errorf(i18n.T("update.error.signature"), a.URL)
+	}
+	return nil
+}
+
+// extract возвращает исполняемый файл из архива .tar.gz или сами данные для других сборок
+func extract(data []byte, name string) ([]byte, error) {
+	if !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tgz") {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("update.error.archive"), name, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf(i18n.T("update.error.archive"), name, binaryName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("update.error.archive"), name, err)
+		}
+		if h.Typeflag == tar.TypeReg && filepath.Base(h.Name) == binaryName {
+			return io.ReadAll(io.LimitReader(tr, maxBinarySize))
+		}
+	}
+}
+
+func (u Updater) executable() (string, error) {
+	if u.Executable != "" {
+		return u.Executable, nil
+	}
+	file, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(file)
+}
+
+// check пробно запускает файл
+func (u Updater) check(ctx context.Context, file string) error {
+	if u.Check != nil {
+		return u.Check(ctx, file)
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, file, "--help").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Install загружает и проверяет сборку выпуска r, пробно запускает её и заменяет
+// исполняемый файл; прежний файл остаётся рядом с суффиксом .old. Если новая версия
+// после замены не запускается, прежняя возвращается на место
+func (u Updater) Install(ctx context.Context, r Release) error {
+	a, ok := r.Asset(u.arch())
+	if !ok {
+		return fmt.Errorf(i18n.T("update.error.arch"), r.Version, u.arch())
+	}
+	link := u.resolve(a.URL)
+	data, err := u.fetch(ctx, link, maxBinarySize)
+	if err != nil {
+		return err
+	}
+	if err := Verify(data, r.Version, a, PublicKey); err != nil {
+		return err
+	}
+	if data, err = extract(data, link); err != nil {
+		return err
+	}
+
+	exe, err := u.executable()
+	if err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	// Новый файл пишется в тот же каталог, чтобы замена была переименованием
+	next := exe + newSuffix
+	if err := os.WriteFile(next, data, 0o755); err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	defer os.Remove(next)
+	if err := os.Chmod(next, 0o755); err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	if err := u.check(ctx, next); err != nil {
+		return fmt.Errorf(i18n.T("update.error.check"), err)
+	}
+
+	backup := exe + backupSuffix
+	if err := copyFile(exe, backup); err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	if err := os.Rename(next, exe); err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	if err := u.check(ctx, exe); err != nil {
+		if rerr := os.Rename(backup, exe); rerr != nil {
+			return fmt.Errorf(i18n.T("update.error.rollback"), err, rerr)
+		}
+		return fmt.Errorf(i18n.T("update.error.rolled_back"), err)
+	}
+	return nil
+}
+
+// Rollback возвращает версию, сохранённую при последнем обновлении
+func (u Updater) Rollback() error {
+	exe, err := u.executable()
+	if err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	backup := exe + backupSuffix
+	if _, err := os.Stat(backup); err != nil {
+		return fmt.Errorf(i18n.T("update.error.no_backup"), backup)
+	}
+	if err := os.Rename(backup, exe); err != nil {
+		return fmt.Errorf(i18n.T("update.error.replace"), err)
+	}
+	return nil
+}
+
+// HasBackup сообщает, сохранена ли прежняя версия для отката
+func (u Updater) HasBackup() bool {
+	exe, err := u.executable()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(exe + backupSuffix)
+	return err == nil
+}
+
+// copyFile копирует файл с правами доступа
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
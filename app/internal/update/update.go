@@ -0,0 +1,259 @@
+// Package update обновляет исполняемый файл терема из ленты выпусков.
+//
+// Лента — JSON с последним выпуском: версия, дата, адрес журнала версий
+// в формате docs/*/changelog.mdx и сборки для архитектур. Каждая сборка
+// проверяется по SHA-256 и подписи ed25519 (см. SignedMessage) ключом, встроенным
+// при сборке; файл заменяется переименованием, а прежний сохраняется рядом для отката.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/version"
+)
+
+// DefaultFeed лента выпусков по умолчанию
+const DefaultFeed = "https://github.com/qzeleza/terem/releases/latest/download/feed.json"
+
+// PublicKey открытый ключ ed25519 в base64 для проверки подписей сборок;
+// задаётся только при сборке: -ldflags "-X github.com/qzeleza/terem/internal/update.PublicKey=...".
+// Заменить его из конфигурации нельзя: файл конфигурации может оказаться доступен для записи
+// не только root, и подменённый ключ позволил бы установить чужую сборку
+var PublicKey string
+
+// Ограничения размера загружаемых файлов
+const (
+	maxFeedSize   = 1 << 20  // Лента и журнал версий
+	maxBinarySize = 64 << 20 // Сборка
+)
+
+// Asset сборка для одной архитектуры
+type Asset struct {
+	Arch      string `json:"arch"`      // ОС и архитектура, например linux-mipsle
+	URL       string `json:"url"`       // Адрес файла или архива .tar.gz; относительный — от адреса ленты
+	SHA256    string `json:"sha256"`    // Контрольная сумма файла в hex
+	Signature string `json:"signature"` // Подпись ed25519 сообщения SignedMessage в base64
+}
+
+// Release выпуск из ленты
+type Release struct {
+	Version   string  `json:"version"`
+	Date      string  `json:"date,omitempty"`
+	Changelog string  `json:"changelog,omitempty"` // Адрес журнала версий; {lang} заменяется кодом языка
+	Assets    []Asset `json:"assets"`
+}
+
+// Asset возвращает сборку для архитектуры arch
+func (r Release) Asset(arch string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Arch == arch {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Arch возвращает ОС и архитектуру текущей сборки в виде linux-mipsle
+func Arch() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// Note раздел журнала версий
+type Note struct {
+	Version string   `json:"version"`
+	Date    string   `json:"date,omitempty"`
+	Lines   []string `json:"lines"`
+}
+
+var (
+	// notePattern заголовок выпуска: ## [v1.2.0] - 2024-01-15
+	notePattern = regexp.MustCompile(`^##\s+\[?v?([0-9][^\]\s]*)\]?(?:\s+-\s+(\S+))?`)
+	// linkPattern строка со ссылкой на скачивание сборки
+	linkPattern = regexp.MustCompile(`^[-*]\s+\[[^\]]+\]\([a-z]+://[^)]+\)$`)
+)
+
+// ParseChangelog разбирает журнал версий в формате docs/*/changelog.mdx:
+// заголовок, пустые строки, разделители и ссылки на скачивание пропускаются
+func ParseChangelog(content string) []Note {
+	var notes []Note
+	lines := strings.Split(content, "\n")
+	// Пропускаем шапку mdx между строками ---
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		if m := notePattern.FindStringSubmatch(text); m != nil {
+			notes = append(notes, Note{Version: m[1], Date: m[2]})
+			continue
+		}
+		if len(notes) == 0 || text == "" || text == "---" || linkPattern.MatchString(text) {
+			continue
+		}
+		n := &notes[len(notes)-1]
+		n.Lines = append(n.Lines, text)
+	}
+	for i := range notes {
+		notes[i].Lines = dropEmptySections(notes[i].Lines)
+	}
+	return notes
+}
+
+// dropEmptySections убирает подзаголовки, после которых не осталось строк
+func dropEmptySections(lines []string) []string {
+	var result []string
+	for i, line := range lines {
+		heading := strings.HasPrefix(line, "#")
+		if heading && (i+1 == len(lines) || strings.HasPrefix(lines[i+1], "#")) {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// Between возвращает разделы журнала новее from и не новее to
+func Between(notes []Note, from, to string) []Note {
+	var result []Note
+	for _, n := range notes {
+		if version.Compare(n.Version, from) > 0 && version.Compare(n.Version, to) <= 0 {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// Updater проверяет ленту выпусков и обновляет исполняемый файл
+type Updater struct {
+	Feed       string // Адрес ленты: http(s)://, file:// или путь; пусто — DefaultFeed
+	Executable string // Заменяемый файл; пусто — текущий исполняемый файл
+	Arch       string // Архитектура сборки; пусто — Arch()
+	Lang       string // Язык журнала версий для {lang}
+	HTTPClient *http.Client
+	Timeout    time.Duration
+	// Check проверяет, что файл запускается; по умолчанию запускает его с --help
+	Check func(ctx context.Context, file string) error
+}
+
+func (u Updater) feed() string {
+	if u.Feed == "" {
+		return DefaultFeed
+	}
+	return u.Feed
+}
+
+func (u Updater) arch() string {
+	if u.Arch == "" {
+		return Arch()
+	}
+	return u.Arch
+}
+
+func (u Updater) timeout() time.Duration {
+	if u.Timeout <= 0 {
+		return 2 * time.Minute
+	}
+	return u.Timeout
+}
+
+// Latest загружает ленту и возвращает последний выпуск
+func (u Updater) Latest(ctx context.Context) (Release, error) {
+	data, err := u.fetch(ctx, u.feed(), maxFeedSize)
+	if err != nil {
+		return Release{}, err
+	}
+	var r Release
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Release{}, fmt.Errorf(i18n.T("update.error.format"), u.feed(), err)
+	}
+	if r.Version == "" {
+		return Release{}, fmt.Errorf(i18n.T("update.error.no_version"), u.feed())
+	}
+	return r, nil
+}
+
+// Changes возвращает разделы журнала версий от current до выпуска r;
+// если журнал не указан, результат пуст
+func (u Updater) Changes(ctx context.Context, r Release, current string) ([]Note, error) {
+	if r.Changelog == "" {
+		return nil, nil
+	}
+	link := strings.ReplaceAll(r.Changelog, "{lang}", u.Lang)
+	data, err := u.fetch(ctx, u.resolve(link), maxFeedSize)
+	if err != nil {
+		return nil, err
+	}
+	return Between(ParseChangelog(string(data)), current, r.Version), nil
+}
+
+// resolve возвращает адрес ref относительно адреса ленты
+func (u Updater) resolve(ref string) string {
+	base, err := url.Parse(u.feed())
+	if err != nil || base.Scheme == "" {
+		if path.IsAbs(ref) || strings.Contains(ref, "://") {
+			return ref
+		}
+		return path.Join(path.Dir(u.feed()), ref)
+	}
+	target, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(target).String()
+}
+
+// fetch загружает файл по адресу http(s)://, file:// или по пути не больше limit байт
+func (u Updater) fetch(ctx context.Context, source string, limit int64) ([]byte, error) {
+	var body io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := u.HTTPClient
+		if client == nil {
+			client = &http.Client{Timeout: u.timeout()}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("update.error.fetch"), source, err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("update.error.fetch"), source, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf(i18n.T("update.error.fetch"), source, resp.Status)
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("update.error.fetch"), source, err)
+		}
+		defer f.Close()
+		body = f
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("update.error.fetch"), source, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf(i18n.T("update.error.size"), source, limit>>20)
+	}
+	return data, nil
+}
@@ -0,0 +1,171 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangelog(t *testing.T) {
+	content := "---\ntitle: 'Журнал версий'\n---\n\n# Журнал версий\n\n## [v1.2.0] - 2024-01-15\n\n### Новое\n- Автообновление\n\n### Скачать\n- [terem.tar.gz](https://example.com/terem.tar.gz)\n\n---\n\n## [v1.1.0] - 2023-12-01\n- WireGuard\n\n## [v1.0.0] - 2023-10-15\n- Первый выпуск\n"
+	notes := ParseChangelog(content)
+	if len(notes) != 3 {
+		t.Fatalf("notes = %+v", notes)
+	}
+	if n := notes[0]; n.Version != "1.2.0" || n.Date != "2024-01-15" || len(n.Lines) != 2 || n.Lines[1] != "- Автообновление" {
+		t.Errorf("note = %+v", n)
+	}
+	between := Between(notes, "1.0.0", "1.2.0")
+	if len(between) != 2 || between[0].Version != "1.2.0" || between[1].Version != "1.1.0" {
+		t.Errorf("between = %+v", between)
+	}
+}
+
+// release собирает в dir архив сборки и ленту выпуска 1.3.0 с ним; сборка подписывается
+// как выпуск signed. Возвращает адрес ленты
+func release(t *testing.T, dir string, priv ed25519.PrivateKey, binary []byte, signed string, tamper bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "terem", Mode: 0o755, Size: int64(len(binary)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(binary)
+	tw.Close()
+	gz.Close()
+	archive := buf.Bytes()
+
+	path := filepath.Join(dir, "feed.json")
+	sum := sha256.Sum256(archive)
+	asset := Asset{Arch: "linux-test", URL: "terem-test.tar.gz", SHA256: hex.EncodeToString(sum[:])}
+	asset.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(signed, asset)))
+	if tamper {
+		archive = append(archive, 0)
+		sum = sha256.Sum256(archive)
+		asset.SHA256 = hex.EncodeToString(sum[:])
+	}
+	if err := os.WriteFile(filepath.Join(dir, "terem-test.tar.gz"), archive, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "changelog.mdx"), []byte("## [v1.3.0] - 2026-10-01\n- Самообновление\n\n## [v1.0.0] - 2023-10-15\n- Первый выпуск\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	feed, _ := json.Marshal(Release{
+		Version:   "1.3.0",
+		Date:      "2026-10-01",
+		Changelog: "changelog.mdx",
+		Assets:    []Asset{asset},
+	})
+	if err := os.WriteFile(path, feed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + path
+}
+
+func TestInstall(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "bin", "terem")
+	os.MkdirAll(filepath.Dir(exe), 0o755)
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		data, _ := os.ReadFile(exe)
+		return string(data)
+	}
+
+	key := base64.StdEncoding.EncodeToString(pub)
+	t.Cleanup(func() { PublicKey = "" })
+	PublicKey = key
+	u := Updater{
+		Feed:       release(t, dir, priv, []byte("new"), "1.3.0", false),
+		Executable: exe,
+		Arch:       "linux-test",
+		Check:      func(context.Context, string) error { return nil },
+	}
+	ctx := context.Background()
+	r, err := u.Latest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, err := u.Changes(ctx, r, "1.0.0")
+	if err != nil || len(notes) != 1 || notes[0].Version != "1.3.0" {
+		t.Errorf("changes = %+v, %v", notes, err)
+	}
+	if err := u.Install(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if read() != "new" || !u.HasBackup() {
+		t.Errorf("installed = %q, backup %v", read(), u.HasBackup())
+	}
+	if _, err := os.Stat(exe + newSuffix); err == nil {
+		t.Error("temporary file left")
+	}
+	if err := u.Rollback(); err != nil || read() != "old" {
+		t.Errorf("rollback = %q, %v", read(), err)
+	}
+	if err := u.Rollback(); err == nil {
+		t.Error("rollback without backup")
+	}
+
+	// Новая версия не запускается после замены — возвращается прежняя
+	u.Check = func(_ context.Context, file string) error {
+		if file == exe {
+			return errors.New("exec format error")
+		}
+		return nil
+	}
+	if err := u.Install(ctx, r); err == nil || read() != "old" {
+		t.Errorf("failed check = %q, %v", read(), err)
+	}
+
+	u.Check = nil
+	foreign := base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize))
+	for name, c := range map[string]struct {
+		u   Updater
+		key string
+	}{
+		"foreign key": {Updater{Feed: u.Feed, Executable: exe, Arch: "linux-test"}, foreign},
+		"no key":      {Updater{Feed: u.Feed, Executable: exe, Arch: "linux-test"}, ""},
+		"other arch":  {Updater{Feed: u.Feed, Executable: exe, Arch: "linux-other"}, key},
+		"tampered":    {Updater{Feed: release(t, t.TempDir(), priv, []byte("bad"), "1.3.0", true), Executable: exe, Arch: "linux-test"}, key},
+		// Старая подписанная сборка, выданная лентой за новый выпуск
+		"downgrade": {Updater{Feed: release(t, t.TempDir(), priv, []byte("bad"), "1.0.0", false), Executable: exe, Arch: "linux-test"}, key},
+	} {
+		PublicKey = c.key
+		r, err := c.u.Latest(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.u.Install(ctx, r); err == nil || read() != "old" {
+			t.Errorf("%s: installed %q, %v", name, read(), err)
+		}
+	}
+
+	// Подпись привязана к ленте: та же сборка из другой ленты не принимается
+	PublicKey = key
+	moved := t.TempDir()
+	for _, file := range []string{"feed.json", "terem-test.tar.gz"} {
+		data, _ := os.ReadFile(filepath.Join(dir, file))
+		os.WriteFile(filepath.Join(moved, file), data, 0o644)
+	}
+	other := Updater{Feed: "file://" + filepath.Join(moved, "feed.json"), Executable: exe, Arch: "linux-test"}
+	if r, err := other.Latest(ctx); err != nil || other.Install(ctx, r) == nil || read() != "old" {
+		t.Errorf("feed-bound signature accepted from another feed: %q, %v", read(), err)
+	}
+}
@@ -30,6 +30,11 @@ func ExecuteCommand(cmd string) (string, error) {
 	return strings.TrimSpace(out.String()), nil
 }
 
+// ShellQuote экранирует строку для безопасной подстановки в команду sh
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // readFile читает содержимое файла
 func ReadFile(path string) (string, error) {
 	data, err := os.ReadFile(path)
@@ -59,6 +64,17 @@ func FormatUptime(bootTime time.Time) string {
 	}
 }
 
+// DefaultBinary путь к терему на роутере, если его не удалось определить
+const DefaultBinary = "/opt/bin/terem"
+
+// Binary возвращает путь к исполняемому файлу терема для сценариев cron и init.d
+func Binary() string {
+	if exe, err := os.Executable(); err == nil && strings.HasPrefix(exe, "/opt/") {
+		return exe
+	}
+	return DefaultBinary
+}
+
 // GetEnv получает значение переменной окружения или возвращает значение по умолчанию.
 func GetEnv(key, defaultValue string) string {
 	// Получаем значение переменной окружения
@@ -85,6 +101,20 @@ func GetEnvBool(key string, defaultValue bool) bool {
 	return parsed
 }
 
+// FormatBytes форматирует количество байт в читаемый вид (B, KB, MB, GB, TB)
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit && exp < 3; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
 // PadRight дополняет строку пробелами справа до указанной ширины
 //
 // Параметры:
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Значения AcceptRA
+const (
+	RAUnknown    = -1 // Не удалось прочитать настройку
+	RADisabled   = 0  // Объявления маршрутизаторов игнорируются
+	RAEnabled    = 1  // Принимаются, если не включена пересылка
+	RAForwarding = 2  // Принимаются даже при включённой пересылке
+)
+
+// IPv6Info сведения об IPv6 на роутере
+type IPv6Info struct {
+	Global          []string `json:"global,omitempty"`          // Глобальные адреса (2000::/3)
+	ULA             []string `json:"ula,omitempty"`             // Уникальные локальные адреса (fc00::/7)
+	LinkLocal       []string `json:"linkLocal,omitempty"`       // Локальные адреса канала (fe80::/10)
+	Gateway         string   `json:"gateway,omitempty"`         // Шлюз маршрута по умолчанию
+	GatewayDev      string   `json:"gatewayDev,omitempty"`      // Интерфейс маршрута по умолчанию
+	DelegatedPrefix []string `json:"delegatedPrefix,omitempty"` // Делегированные провайдером префиксы
+	AcceptRA        int      `json:"acceptRa"`                  // Приём RA на интерфейсе маршрута по умолчанию
+	RAServer        string   `json:"raServer,omitempty"`        // Режим раздачи RA в локальную сеть (odhcpd)
+}
+
+// Enabled сообщает, есть ли у роутера хотя бы один IPv6-адрес
+func (i IPv6Info) Enabled() bool {
+	return len(i.Global)+len(i.ULA)+len(i.LinkLocal) > 0
+}
+
+// GetIPv6Info получает сведения об IPv6: адреса, маршрут по умолчанию, делегированный префикс и RA
+func GetIPv6Info() IPv6Info {
+	info := IPv6Info{AcceptRA: RAUnknown}
+
+	if output, err := ExecuteCommand("ip -6 addr show scope global 2>/dev/null; ip -6 addr show scope link 2>/dev/null"); err == nil {
+		info.Global, info.ULA, info.LinkLocal = ClassifyIPv6Addrs(output)
+	}
+
+	if output, err := ExecuteCommand("ip -6 route show default 2>/dev/null"); err == nil {
+		info.Gateway, info.GatewayDev = ParseDefaultRoute(output)
+	}
+
+	// OpenWrt сообщает делегированный префикс через ubus, остальные системы — маршрутом unreachable
+	if output, err := ExecuteCommand("ubus call network.interface.wan6 status 2>/dev/null"); err == nil {
+		info.DelegatedPrefix = ParseUbusPrefixes(output)
+	}
+	if len(info.DelegatedPrefix) == 0 {
+		if output, err := ExecuteCommand("ip -6 route show type unreachable 2>/dev/null"); err == nil {
+			info.DelegatedPrefix = ParseUnreachablePrefixes(output)
+		}
+	}
+
+	if info.GatewayDev != "" {
+		if value, err := ReadFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/accept_ra", info.GatewayDev)); err == nil {
+			if ra, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				info.AcceptRA = ra
+			}
+		}
+	}
+
+	if output, err := ExecuteCommand("uci -q get dhcp.lan.ra"); err == nil {
+		info.RAServer = strings.TrimSpace(output)
+	}
+
+	return info
+}
+
+// ClassifyIPv6Addrs разбирает вывод ip -6 addr и делит адреса на глобальные, ULA и link-local.
+// Адреса возвращаются в формате CIDR без повторов.
+func ClassifyIPv6Addrs(output string) (global, ula, linkLocal []string) {
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "inet6" || seen[fields[1]] {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(fields[1])
+		if err != nil {
+			continue
+		}
+		seen[fields[1]] = true
+
+		switch {
+		case ip.IsLinkLocalUnicast():
+			linkLocal = append(linkLocal, fields[1])
+		case ip.IsPrivate():
+			ula = append(ula, fields[1])
+		case ip.IsGlobalUnicast():
+			global = append(global, fields[1])
+		}
+	}
+	return global, ula, linkLocal
+}
+
+// ParseDefaultRoute извлекает шлюз и интерфейс из строки вида
+// "default via fe80::1 dev eth0 proto ra metric 1024"
+func ParseDefaultRoute(output string) (gateway, dev string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "default" {
+			continue
+		}
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "via":
+				gateway = fields[i+1]
+			case "dev":
+				dev = fields[i+1]
+			}
+		}
+		return gateway, dev
+	}
+	return "", ""
+}
+
+// ParseUbusPrefixes извлекает делегированные префиксы из ubus call network.interface.* status
+func ParseUbusPrefixes(output string) []string {
+	var status struct {
+		Prefixes []struct {
+			Address string `json:"address"`
+			Mask    int    `json:"mask"`
+		} `json:"ipv6-prefix"`
+	}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil
+	}
+
+	var prefixes []string
+	for _, p := range status.Prefixes {
+		if p.Address != "" {
+			prefixes = append(prefixes, fmt.Sprintf("%s/%d", p.Address, p.Mask))
+		}
+	}
+	return prefixes
+}
+
+// ParseUnreachablePrefixes извлекает делегированные префиксы из маршрутов вида
+// "unreachable 2001:db8:1200::/56 dev lo proto static metric 2147483647"
+func ParseUnreachablePrefixes(output string) []string {
+	var prefixes []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "unreachable" {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(fields[1])
+		if err != nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+			continue
+		}
+		prefixes = append(prefixes, fields[1])
+	}
+	return prefixes
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyIPv6Addrs(t *testing.T) {
+	output := `2: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 state UP qlen 1000
+    inet6 2001:db8:1:2::1/64 scope global dynamic noprefixroute
+       valid_lft 86300sec preferred_lft 14300sec
+    inet6 fd12:3456:789a::1/60 scope global noprefixroute
+       valid_lft forever preferred_lft forever
+3: br-lan: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 state UP qlen 1000
+    inet6 fe80::1/64 scope link
+       valid_lft forever preferred_lft forever
+    inet6 2001:db8:1:2::1/64 scope global dynamic noprefixroute`
+
+	global, ula, linkLocal := ClassifyIPv6Addrs(output)
+
+	if !reflect.DeepEqual(global, []string{"2001:db8:1:2::1/64"}) {
+		t.Errorf("unexpected global addresses: %v", global)
+	}
+	if !reflect.DeepEqual(ula, []string{"fd12:3456:789a::1/60"}) {
+		t.Errorf("unexpected ULA addresses: %v", ula)
+	}
+	if !reflect.DeepEqual(linkLocal, []string{"fe80::1/64"}) {
+		t.Errorf("unexpected link-local addresses: %v", linkLocal)
+	}
+}
+
+func TestParseDefaultRoute(t *testing.T) {
+	gateway, dev := ParseDefaultRoute("default via fe80::1 dev eth0 proto ra metric 1024 expires 1790sec hoplimit 64 pref medium")
+	if gateway != "fe80::1" || dev != "eth0" {
+		t.Errorf("unexpected route: %q %q", gateway, dev)
+	}
+
+	if gateway, dev := ParseDefaultRoute(""); gateway != "" || dev != "" {
+		t.Errorf("expected empty route, got %q %q", gateway, dev)
+	}
+}
+
+func TestParseDelegatedPrefixes(t *testing.T) {
+	ubus := `{"up": true, "ipv6-prefix": [{"address": "2001:db8:1200::", "mask": 56, "preferred": 3600}]}`
+	if got := ParseUbusPrefixes(ubus); !reflect.DeepEqual(got, []string{"2001:db8:1200::/56"}) {
+		t.Errorf("unexpected ubus prefixes: %v", got)
+	}
+
+	routes := "unreachable 2001:db8:1200::/56 dev lo proto static metric 2147483647 pref medium\n" +
+		"unreachable fd12:3456:789a::/48 dev lo proto static metric 2147483647 pref medium"
+	if got := ParseUnreachablePrefixes(routes); !reflect.DeepEqual(got, []string{"2001:db8:1200::/56"}) {
+		t.Errorf("unexpected route prefixes: %v", got)
+	}
+}
@@ -6,11 +6,55 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/qzeleza/terem/internal/i18n"
 )
 
+// Runner выполняет команды оболочки на локальной или удалённой системе
+type Runner interface {
+	RunCommand(command string) (string, error)
+}
+
+// Local выполняет команды на текущей системе
+type Local struct{}
+
+// RunCommand выполняет команду локально через sh
+func (Local) RunCommand(command string) (string, error) {
+	return ExecuteCommand(command)
+}
+
+// OrLocal возвращает r, а если он не задан — Local
+func OrLocal(r Runner) Runner {
+	if r == nil {
+		return Local{}
+	}
+	return r
+}
+
+// RunChecked выполняет команду и при ошибке возвращает её вместе с выводом команды.
+// Текст ошибки берётся из перевода key с параметрами «команда» и «вывод».
+func RunChecked(r Runner, key, command string) (string, error) {
+	output, err := OrLocal(r).RunCommand(command + " 2>&1")
+	if err != nil {
+		return output, fmt.Errorf(i18n.T(key), command, strings.TrimSpace(output+" "+err.Error()))
+	}
+	return output, nil
+}
+
+// IsLocal сообщает, выполняет ли runner команды на текущей системе.
+// Runner может явно указать это, реализовав метод Local() bool.
+func IsLocal(r Runner) bool {
+	switch v := r.(type) {
+	case nil, Local, *Local:
+		return true
+	case interface{ Local() bool }:
+		return v.Local()
+	}
+	return false
+}
+
 // Router представляет информацию о роутере
 type Router struct {
 	Name     string
@@ -16,11 +16,14 @@ type networkInfo struct {
 	IP      string
 	Gateway string
 	MAC     string
+	IPv6    IPv6Info
 }
 
 type RAMInfo struct {
-	Total int // Общее количество памяти
-	Free  int // Свободная память
+	Total     int `json:"totalMb"`     // Общее количество памяти
+	Free      int `json:"freeMb"`      // Свободная память
+	SwapTotal int `json:"swapTotalMb"` // Размер подкачки
+	SwapFree  int `json:"swapFreeMb"`  // Свободная часть подкачки
 }
 
 // GetRouterModel получает модель роутера
@@ -52,7 +55,7 @@ func GetRouterModel() (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf(i18n.T("sysinfo.error.model"))
+	return "", fmt.Errorf("%s", i18n.T("sysinfo.error.model"))
 }
 
 // GetSystemArch получает архитектуру процессора
@@ -94,11 +97,22 @@ func GetMemoryInfo() (RAMInfo, error) {
 					}
 				}
 			}
+		} else if strings.HasPrefix(line, "SwapTotal:") || strings.HasPrefix(line, "SwapFree:") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				if val, err := strconv.Atoi(fields[1]); err == nil {
+					if strings.HasPrefix(line, "SwapTotal:") {
+						memInfo.SwapTotal = val / 1024
+					} else {
+						memInfo.SwapFree = val / 1024
+					}
+				}
+			}
 		}
 	}
 
 	if memInfo.Total == 0 {
-		return memInfo, fmt.Errorf(i18n.T("sysinfo.error.mem_missing"))
+		return memInfo, fmt.Errorf("%s", i18n.T("sysinfo.error.mem_missing"))
 	}
 
 	return memInfo, nil
@@ -115,7 +129,7 @@ func GetSystemUptime() (time.Time, error) {
 	// Первое число - время работы в секундах
 	fields := strings.Fields(content)
 	if len(fields) == 0 {
-		return time.Time{}, fmt.Errorf(i18n.T("sysinfo.error.uptime_format"))
+		return time.Time{}, fmt.Errorf("%s", i18n.T("sysinfo.error.uptime_format"))
 	}
 
 	// Парсим секунды с плавающей точкой
@@ -147,7 +161,7 @@ func GetHostname() (string, error) {
 		return hostname, nil
 	}
 
-	return "", fmt.Errorf(i18n.T("sysinfo.error.hostname"))
+	return "", fmt.Errorf("%s", i18n.T("sysinfo.error.hostname"))
 }
 
 // GetNetworkInfo получает сетевую информацию
@@ -233,5 +247,7 @@ func GetNetworkInfo() (*networkInfo, error) {
 		}
 	}
 
+	info.IPv6 = GetIPv6Info()
+
 	return info, nil
 }
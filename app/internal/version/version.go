@@ -0,0 +1,53 @@
+// Package version разбирает и сравнивает номера версий терема и пакетов opkg.
+package version
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionPattern версия вида 1.2.0, v1.10 или 1.2.0-1 (номер сборки пакета opkg)
+var versionPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*(-[0-9]+)?$`)
+
+// Valid сообщает, является ли строка версией; dev, хеш коммита или вывод
+// git describe вида 1.2.0-5-gabc1234 версией не считаются
+func Valid(v string) bool {
+	return versionPattern.MatchString(v)
+}
+
+// Compare сравнивает версии вида 1.2.0, v1.10 или 1.2.0-1 по числовым частям;
+// возвращает -1, 0 или 1. Строка, которая не является версией (см. Valid),
+// считается старше любой версии
+func Compare(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts возвращает числовые части версии; у строки, не являющейся версией, их нет
+func versionParts(v string) []int {
+	if !Valid(v) {
+		return nil
+	}
+	var parts []int
+	for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r < '0' || r > '9' }) {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	return parts
+}
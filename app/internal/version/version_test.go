@@ -0,0 +1,25 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.0", "1.2.0", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"1.2.0", "1.2.0-1", -1},
+		{"0.9", "1.0.0", -1},
+		// Хеш коммита и вывод git describe не являются версиями
+		{"3293afe", "1.0.0", -1},
+		{"1.2.0-5-gabc1234", "1.2.0", -1},
+		{"dev", "0.1", -1},
+	}
+	for _, c := range cases {
+		if got := Compare(c.a, c.b); got != c.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// upTimeout сколько секунд ждать появления интерфейса OpenVPN после запуска
+const upTimeout = 30
+
+// OpenVPN сведения из клиентского профиля OpenVPN
+type OpenVPN struct {
+	Remotes      []string // Серверы: «адрес порт [протокол]»
+	Proto        string
+	Dev          string
+	AuthUserPass bool   // Сервер требует имя и пароль
+	AuthFile     string // Файл с именем и паролем, если указан в профиле
+}
+
+// ParseOpenVPN разбирает профиль OpenVPN, включая встроенные блоки <ca>, <cert> и др.
+func ParseOpenVPN(content string) OpenVPN {
+	var o OpenVPN
+	block := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if block != "" {
+			if line == "</"+block+">" {
+				block = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">") && !strings.HasPrefix(line, "</") {
+			block = strings.Trim(line, "<>")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		switch fields[0] {
+		case "remote":
+			o.Remotes = append(o.Remotes, strings.Join(fields[1:], " "))
+		case "proto":
+			if len(fields) > 1 {
+				o.Proto = fields[1]
+			}
+		case "dev":
+			if len(fields) > 1 {
+				o.Dev = fields[1]
+			}
+		case "auth-user-pass":
+			o.AuthUserPass = true
+			if len(fields) > 1 {
+				o.AuthFile = fields[1]
+			}
+		}
+	}
+	return o
+}
+
+// Validate проверяет, что профиль указывает сервер и использует tun
+func (o OpenVPN) Validate() error {
+	if len(o.Remotes) == 0 {
+		return errors.New(i18n.T("vpn.error.ovpn_remote"))
+	}
+	if o.Dev != "" && !strings.HasPrefix(o.Dev, "tun") {
+		return fmt.Errorf(i18n.T("vpn.error.ovpn_dev"), o.Dev)
+	}
+	return nil
+}
+
+// PrepareOpenVPN готовит профиль к запуску теремом: отключает redirect-gateway, чтобы
+// туннель не забирал маршрут по умолчанию роутера, и подставляет файл с именем и паролем
+func PrepareOpenVPN(content, authFile string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "redirect-gateway":
+			lines[i] = "# " + strings.TrimSpace(line) + " # отключено теремом"
+		case fields[0] == "auth-user-pass" && authFile != "":
+			lines[i] = "auth-user-pass " + authFile
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pidFile путь к PID-файлу процесса OpenVPN туннеля
+func pidFile(name string) string {
+	return path.Join(RunDir, "terem-vpn-"+name+".pid")
+}
+
+// statusFile путь к файлу состояния OpenVPN туннеля
+func statusFile(name string) string {
+	return "/tmp/terem-vpn-" + name + ".status"
+}
+
+// upOpenVPN запускает OpenVPN в фоне и ждёт появления интерфейса туннеля
+func (m Manager) upOpenVPN(name string) error {
+	if m.linkExists(name) {
+		return nil
+	}
+	command := fmt.Sprintf("mkdir -p %s && openvpn --config %s --dev %s --dev-type tun --daemon %s "+
+		"--writepid %s --status %s 10 --pull-filter ignore redirect-gateway",
+		utils.ShellQuote(RunDir), utils.ShellQuote(m.Profile(name, KindOpenVPN)), utils.ShellQuote(name),
+		utils.ShellQuote("terem-vpn-"+name), utils.ShellQuote(pidFile(name)), utils.ShellQuote(statusFile(name)))
+	if _, err := m.run(command); err != nil {
+		return err
+	}
+	wait := fmt.Sprintf("i=0; until ip link show dev %s >/dev/null 2>&1; do i=$((i+1)); [ $i -ge %d ] && exit 1; sleep 1; done",
+		utils.ShellQuote(name), upTimeout)
+	if _, err := utils.OrLocal(m.Runner).RunCommand(wait); err != nil {
+		return fmt.Errorf(i18n.T("vpn.error.ovpn_timeout"), name, upTimeout)
+	}
+	return nil
+}
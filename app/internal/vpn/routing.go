@@ -0,0 +1,82 @@
+package vpn
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qzeleza/terem/internal/firewall"
+	"github.com/qzeleza/terem/internal/route"
+)
+
+// rulePriority базовый приоритет правил ip rule терема; к нему прибавляется номер таблицы
+const rulePriority = 10000
+
+// Priority возвращает приоритет правила ip rule для таблицы туннеля
+func Priority(table int) int {
+	return rulePriority + table
+}
+
+// note пояснение в комментарии правил межсетевого экрана туннеля
+func note(name string) string {
+	return "vpn " + name
+}
+
+// Rules возвращает правила межсетевого экрана туннеля: выход локальной сети через туннель
+// и маркировку пакетов выбранных клиентов и списков ipset
+func (t Tunnel) Rules() ([]firewall.Rule, error) {
+	rules := firewall.Masquerade{Iface: t.Name, Note: note(t.Name)}.Rules()
+	if t.Table <= 0 {
+		return rules, nil
+	}
+	var marks []firewall.Mark
+	for _, mac := range t.Clients {
+		marks = append(marks, firewall.Mark{Mark: t.Table, MAC: mac, Note: note(t.Name)})
+	}
+	for _, set := range t.IPSets {
+		marks = append(marks, firewall.Mark{Mark: t.Table, Set: set, Note: note(t.Name)})
+	}
+	for _, mark := range marks {
+		if err := mark.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, mark.Rules()...)
+	}
+	return rules, nil
+}
+
+// Route включает маршрутизацию туннеля: правила межсетевого экрана, а для выборочной
+// маршрутизации — таблицу с маршрутом по умолчанию через туннель и правило ip rule по метке
+func (m Manager) Route(t Tunnel) error {
+	rules, err := t.Rules()
+	if err != nil {
+		return err
+	}
+	if t.Table > 0 {
+		commands := append(route.Table{ID: t.Table, Dev: t.Name}.Commands(),
+			fmt.Sprintf("while ip rule del priority %d 2>/dev/null; do :; done", Priority(t.Table)),
+			fmt.Sprintf("ip rule add fwmark %d lookup %d priority %d", t.Table, t.Table, Priority(t.Table)))
+		for _, c := range commands {
+			if _, err := m.run(c); err != nil {
+				return err
+			}
+		}
+	}
+	return firewall.Manager{Runner: m.Runner}.Add(rules)
+}
+
+// Unroute отключает маршрутизацию туннеля: удаляет его правила межсетевого экрана,
+// правило ip rule и таблицу маршрутизации
+func (m Manager) Unroute(t Tunnel) error {
+	var errs []error
+	if _, err := (firewall.Manager{Runner: m.Runner}).RemoveNote(note(t.Name)); err != nil {
+		errs = append(errs, err)
+	}
+	if t.Table > 0 {
+		command := fmt.Sprintf("while ip rule del priority %d 2>/dev/null; do :; done; ip route flush table %d 2>/dev/null; true",
+			Priority(t.Table), t.Table)
+		if _, err := m.run(command); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package vpn
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status состояние туннеля
+type Status struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Up        bool      `json:"up"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Handshake time.Time `json:"handshake,omitzero"` // WireGuard — последнее рукопожатие, OpenVPN — обновление состояния
+	RX        uint64    `json:"rx"`
+	TX        uint64    `json:"tx"`
+	PublicKey string    `json:"publicKey,omitempty"`
+}
+
+// merge добавляет к состоянию сведения из вывода wg или файла состояния OpenVPN
+func (s *Status) merge(o Status) {
+	if s.Endpoint == "" {
+		s.Endpoint = o.Endpoint
+	}
+	if o.Handshake.After(s.Handshake) {
+		s.Handshake = o.Handshake
+	}
+	if s.PublicKey == "" {
+		s.PublicKey = o.PublicKey
+	}
+	s.RX += o.RX
+	s.TX += o.TX
+}
+
+// ParseWireGuardDump разбирает вывод wg show <dev> dump: первая строка — интерфейс,
+// далее по строке на удалённую сторону; трафик суммируется, рукопожатие берётся последнее
+func ParseWireGuardDump(output string) Status {
+	var st Status
+	for i, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if i == 0 {
+			if len(fields) >= 2 {
+				st.PublicKey = fields[1]
+			}
+			continue
+		}
+		if len(fields) < 7 {
+			continue
+		}
+		peer := Status{}
+		if fields[2] != "(none)" {
+			peer.Endpoint = fields[2]
+		}
+		if ts, err := strconv.ParseInt(fields[4], 10, 64); err == nil && ts > 0 {
+			peer.Handshake = time.Unix(ts, 0)
+		}
+		peer.RX, _ = strconv.ParseUint(fields[5], 10, 64)
+		peer.TX, _ = strconv.ParseUint(fields[6], 10, 64)
+		st.merge(peer)
+	}
+	return st
+}
+
+// ParseOpenVPNStatus разбирает файл --status клиента OpenVPN
+func ParseOpenVPNStatus(content string) Status {
+	var st Status
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ",")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Updated":
+			for _, layout := range []string{time.DateTime, time.ANSIC} {
+				if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+					st.Handshake = t
+					break
+				}
+			}
+		case "TCP/UDP read bytes":
+			st.RX, _ = strconv.ParseUint(value, 10, 64)
+		case "TCP/UDP write bytes":
+			st.TX, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return st
+}
@@ -0,0 +1,316 @@
+// Package vpn управляет клиентскими VPN-туннелями WireGuard и OpenVPN: импорт профилей
+// .conf и .ovpn, генерация ключей, подъём и остановка туннелей, сведения о рукопожатии
+// и трафике, а также выборочная маршрутизация клиентов и списков ipset через туннель.
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/service"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// Виды туннелей
+const (
+	KindWireGuard = "wireguard"
+	KindOpenVPN   = "openvpn"
+)
+
+// Пути профилей и автозапуска
+const (
+	Dir        = "/opt/etc/terem/vpn"           // Профили туннелей терема
+	InitScript = "/opt/etc/init.d/S52terem-vpn" // Поднимает туннели с автозапуском при загрузке
+	RunDir     = "/opt/var/run"                 // PID-файлы OpenVPN
+)
+
+// Kind описание поддерживаемого вида VPN
+type Kind struct {
+	ID      string // KindWireGuard или KindOpenVPN
+	Title   string // Название для меню
+	Ext     string // Расширение файла профиля
+	Package string // Пакет opkg с утилитами
+	Tool    string // Утилита, наличие которой проверяется перед подъёмом туннеля
+}
+
+// Kinds поддерживаемые виды VPN
+var Kinds = []Kind{
+	{ID: KindWireGuard, Title: "WireGuard", Ext: ".conf", Package: "wireguard-tools", Tool: "wg"},
+	{ID: KindOpenVPN, Title: "OpenVPN", Ext: ".ovpn", Package: "openvpn-openssl", Tool: "openvpn"},
+}
+
+// FindKind возвращает описание вида VPN по идентификатору
+func FindKind(id string) (Kind, bool) {
+	for _, k := range Kinds {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Kind{}, false
+}
+
+// Service возвращает службу с утилитами вида VPN (для проверки и установки пакета)
+func (k Kind) Service(runner utils.Runner) service.Service {
+	return service.Service{Name: k.Tool, Package: k.Package, Runner: runner}
+}
+
+// namePattern допустимые имена туннелей: имя становится именем сетевого интерфейса (до 15 символов)
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,14}$`)
+
+// ValidateName проверяет имя туннеля
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf(i18n.T("vpn.error.name"), name)
+	}
+	return nil
+}
+
+// DetectKind определяет вид профиля по содержимому
+func DetectKind(content string) (string, error) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.EqualFold(line, "[Interface]"):
+			return KindWireGuard, nil
+		case line == "client" || strings.HasPrefix(line, "remote "):
+			return KindOpenVPN, nil
+		}
+	}
+	return "", errors.New(i18n.T("vpn.error.kind"))
+}
+
+// Tunnel туннель терема и его выборочная маршрутизация
+type Tunnel struct {
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Table   int      `json:"table,omitempty"`   // Таблица маршрутизации и метка пакетов; 0 — без выборочной маршрутизации
+	Clients []string `json:"clients,omitempty"` // MAC-адреса клиентов, выходящих в интернет через туннель
+	IPSets  []string `json:"ipsets,omitempty"`  // Списки ipset адресов, доступных через туннель
+}
+
+// TableBase первая таблица маршрутизации, выдаваемая туннелям
+const TableBase = 1001
+
+// NextTable возвращает свободный номер таблицы маршрутизации
+func NextTable(used []int) int {
+	table := TableBase
+	for slices.Contains(used, table) {
+		table++
+	}
+	return table
+}
+
+// Credentials имя и пароль для профилей OpenVPN с auth-user-pass
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// Manager управляет туннелями на роутере
+type Manager struct {
+	Runner utils.Runner
+	Dir    string // Каталог профилей; по умолчанию Dir
+}
+
+func (m Manager) dir() string {
+	if m.Dir == "" {
+		return Dir
+	}
+	return m.Dir
+}
+
+func (m Manager) run(command string) (string, error) {
+	return utils.RunChecked(m.Runner, "vpn.error.command", command)
+}
+
+// Profile путь к файлу профиля туннеля
+func (m Manager) Profile(name, kind string) string {
+	k, _ := FindKind(kind)
+	return path.Join(m.dir(), name+k.Ext)
+}
+
+// authFile путь к файлу с именем и паролем OpenVPN
+func (m Manager) authFile(name string) string {
+	return path.Join(m.dir(), name+".auth")
+}
+
+// Read возвращает содержимое профиля туннеля
+func (m Manager) Read(name, kind string) (string, error) {
+	return service.ReadFile(m.Runner, m.Profile(name, kind))
+}
+
+// writePrivate записывает файл, доступный только владельцу: профили содержат ключи
+func (m Manager) writePrivate(file, content string) error {
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	command := fmt.Sprintf("mkdir -p %s && (umask 077 && printf '%%s' %s > %s)",
+		utils.ShellQuote(path.Dir(file)), utils.ShellQuote(content), utils.ShellQuote(file))
+	if _, err := utils.OrLocal(m.Runner).RunCommand(command); err != nil {
+		return fmt.Errorf(i18n.T("service.error.write"), file, err)
+	}
+	return nil
+}
+
+// Import проверяет профиль, сохраняет его в каталог терема и возвращает вид туннеля.
+// Для профилей OpenVPN с auth-user-pass имя и пароль сохраняются в отдельный файл.
+func (m Manager) Import(name, content string, auth Credentials) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	kind, err := DetectKind(content)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case KindWireGuard:
+		cfg, err := ParseWireGuard(content)
+		if err != nil {
+			return "", err
+		}
+		if err := cfg.Validate(); err != nil {
+			return "", err
+		}
+	case KindOpenVPN:
+		cfg := ParseOpenVPN(content)
+		if err := cfg.Validate(); err != nil {
+			return "", err
+		}
+		authFile := ""
+		if cfg.AuthUserPass && cfg.AuthFile == "" {
+			if auth.User == "" {
+				return "", fmt.Errorf(i18n.T("vpn.error.auth"), name)
+			}
+			authFile = m.authFile(name)
+			if err := m.writePrivate(authFile, auth.User+"\n"+auth.Password+"\n"); err != nil {
+				return "", err
+			}
+		}
+		content = PrepareOpenVPN(content, authFile)
+	}
+	return kind, m.writePrivate(m.Profile(name, kind), content)
+}
+
+// Delete останавливает туннель и удаляет его профиль
+func (m Manager) Delete(t Tunnel) error {
+	if err := m.Down(t); err != nil {
+		return err
+	}
+	_, err := m.run(fmt.Sprintf("rm -f %s %s",
+		utils.ShellQuote(m.Profile(t.Name, t.Kind)), utils.ShellQuote(m.authFile(t.Name))))
+	return err
+}
+
+// Installed сообщает, есть ли на роутере утилита для вида туннеля
+func (m Manager) Installed(kind string) bool {
+	k, ok := FindKind(kind)
+	if !ok {
+		return false
+	}
+	_, err := utils.OrLocal(m.Runner).RunCommand("command -v " + k.Tool + " >/dev/null 2>&1")
+	return err == nil
+}
+
+// Up поднимает туннель и включает его маршрутизацию
+func (m Manager) Up(t Tunnel) error {
+	if err := ValidateName(t.Name); err != nil {
+		return err
+	}
+	k, ok := FindKind(t.Kind)
+	if !ok {
+		return fmt.Errorf(i18n.T("vpn.error.kind_name"), t.Kind)
+	}
+	if !m.Installed(t.Kind) {
+		return fmt.Errorf(i18n.T("vpn.error.not_installed"), k.Title, k.Package)
+	}
+	content, err := m.Read(t.Name, t.Kind)
+	if err != nil {
+		return err
+	}
+
+	if t.Kind == KindWireGuard {
+		err = m.upWireGuard(t.Name, content)
+	} else {
+		err = m.upOpenVPN(t.Name)
+	}
+	if err == nil {
+		err = m.Route(t)
+	}
+	if err != nil {
+		_ = m.Down(t)
+	}
+	return err
+}
+
+// Down отключает маршрутизацию и останавливает туннель; остановленный туннель не считается ошибкой
+func (m Manager) Down(t Tunnel) error {
+	var errs []error
+	if err := m.Unroute(t); err != nil {
+		errs = append(errs, err)
+	}
+	switch t.Kind {
+	case KindWireGuard:
+		if m.linkExists(t.Name) {
+			if _, err := m.run("ip link del dev " + utils.ShellQuote(t.Name)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case KindOpenVPN:
+		pid := utils.ShellQuote(pidFile(t.Name))
+		if _, err := m.run(fmt.Sprintf("[ ! -f %s ] || { kill $(cat %s) 2>/dev/null; rm -f %s; }", pid, pid, pid)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// linkExists сообщает, есть ли сетевой интерфейс туннеля
+func (m Manager) linkExists(name string) bool {
+	_, err := utils.OrLocal(m.Runner).RunCommand("ip link show dev " + utils.ShellQuote(name) + " >/dev/null 2>&1")
+	return err == nil
+}
+
+// Status возвращает состояние туннеля
+func (m Manager) Status(name, kind string) (Status, error) {
+	st := Status{Name: name, Kind: kind, Up: m.linkExists(name)}
+	if !st.Up {
+		return st, nil
+	}
+	switch kind {
+	case KindWireGuard:
+		output, err := m.run("wg show " + utils.ShellQuote(name) + " dump")
+		if err != nil {
+			return st, err
+		}
+		st.merge(ParseWireGuardDump(output))
+	case KindOpenVPN:
+		if content, err := service.ReadFile(m.Runner, statusFile(name)); err == nil {
+			st.merge(ParseOpenVPNStatus(content))
+		}
+	}
+	return st, nil
+}
+
+// initScript поднимает туннели с автозапуском при запуске Entware и останавливает их при выключении
+const initScript = `#!/bin/sh
+# Создан теремом: туннели VPN с автозапуском
+case "$1" in
+	start|restart)
+		%[1]s vpn up --autostart
+		;;
+	stop)
+		%[1]s vpn down --all
+		;;
+esac
+`
+
+// EnsureInitScript создаёт init-скрипт автозапуска туннелей, если его нет
+func (m Manager) EnsureInitScript() error {
+	return service.EnsureScript(m.Runner, InitScript, fmt.Sprintf(initScript, utils.ShellQuote(utils.Binary())))
+}
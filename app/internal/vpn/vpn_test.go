@@ -0,0 +1,222 @@
+package vpn
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qzeleza/terem/internal/testutil"
+)
+
+const sampleWG = `[Interface]
+# Ключ выдан провайдером
+PrivateKey = yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=
+Address = 10.66.0.2/32, fd00:66::2/128
+DNS = 10.66.0.1
+MTU = 1380
+
+[Peer]
+PublicKey = xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+Endpoint = vpn.example.com:51820
+AllowedIPs = 0.0.0.0/0, ::/0, 10.66.0.0/24
+PersistentKeepalive = 25
+`
+
+func TestParseWireGuard(t *testing.T) {
+	w, err := ParseWireGuard(sampleWG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(w.Addresses, []string{"10.66.0.2/32", "fd00:66::2/128"}) || w.MTU != 1380 ||
+		len(w.Peers) != 1 || w.Peers[0].Keepalive != 25 {
+		t.Errorf("parsed %+v", w)
+	}
+	if got := w.Routes(); !reflect.DeepEqual(got, []string{"10.66.0.0/24"}) {
+		t.Errorf("Routes = %v", got)
+	}
+	conf := w.SetConf()
+	if strings.Contains(conf, "Address") || strings.Contains(conf, "DNS") ||
+		!strings.Contains(conf, "AllowedIPs = 0.0.0.0/0, ::/0, 10.66.0.0/24\nPersistentKeepalive = 25") {
+		t.Errorf("SetConf:\n%s", conf)
+	}
+
+	w.Peers[0].PublicKey = "short"
+	if err := w.Validate(); err == nil {
+		t.Error("expected public key error")
+	}
+	if _, err := ParseWireGuard("[Interface]\nMTU = big\n"); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	private, public, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := PublicKey(private); err != nil || got != public {
+		t.Errorf("PublicKey = %q, %v; want %q", got, err, public)
+	}
+	if _, err := PublicKey("bad"); err == nil {
+		t.Error("expected key error")
+	}
+
+	updated := SetPrivateKey(sampleWG, private)
+	w, _ := ParseWireGuard(updated)
+	if w.PrivateKey != private || strings.Count(updated, "PrivateKey") != 1 || !strings.Contains(updated, "# Ключ выдан провайдером") {
+		t.Errorf("SetPrivateKey:\n%s", updated)
+	}
+	if got := SetPrivateKey("[Interface]\nAddress = 10.0.0.2/32\n", private); !strings.HasPrefix(got, "[Interface]\nPrivateKey = "+private+"\nAddress") {
+		t.Errorf("SetPrivateKey without key:\n%s", got)
+	}
+}
+
+const sampleOVPN = `client
+dev tun
+proto udp
+remote vpn.example.com 1194
+auth-user-pass
+redirect-gateway def1
+<ca>
+-----BEGIN CERTIFICATE-----
+remote fake.example 1
+-----END CERTIFICATE-----
+</ca>
+`
+
+func TestOpenVPN(t *testing.T) {
+	if kind, err := DetectKind(sampleOVPN); err != nil || kind != KindOpenVPN {
+		t.Errorf("DetectKind = %q, %v", kind, err)
+	}
+	if kind, err := DetectKind(sampleWG); err != nil || kind != KindWireGuard {
+		t.Errorf("DetectKind = %q, %v", kind, err)
+	}
+	if _, err := DetectKind("hello"); err == nil {
+		t.Error("expected kind error")
+	}
+
+	o := ParseOpenVPN(sampleOVPN)
+	if !reflect.DeepEqual(o.Remotes, []string{"vpn.example.com 1194"}) || !o.AuthUserPass || o.AuthFile != "" || o.Proto != "udp" {
+		t.Errorf("parsed %+v", o)
+	}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := ParseOpenVPN("dev tap\nremote a 1\n").Validate(); err == nil {
+		t.Error("expected tap error")
+	}
+
+	prepared := PrepareOpenVPN(sampleOVPN, "/opt/etc/terem/vpn/office.auth")
+	if !strings.Contains(prepared, "\nauth-user-pass /opt/etc/terem/vpn/office.auth\n") ||
+		!strings.Contains(prepared, "\n# redirect-gateway def1") {
+		t.Errorf("PrepareOpenVPN:\n%s", prepared)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	dump := "privkey\tpubkey=\t0\toff\n" +
+		"peer1=\t(none)\t203.0.113.5:51820\t0.0.0.0/0\t1700000000\t1000\t2000\t25\n" +
+		"peer2=\t(none)\t(none)\t10.0.0.0/8\t0\t10\t20\toff\n"
+	st := ParseWireGuardDump(dump)
+	if st.PublicKey != "pubkey=" || st.Endpoint != "203.0.113.5:51820" || st.RX != 1010 || st.TX != 2020 ||
+		!st.Handshake.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("dump = %+v", st)
+	}
+
+	status := "OpenVPN STATISTICS\nUpdated,2024-03-01 12:30:00\nTUN/TAP read bytes,5\nTCP/UDP read bytes,4096\nTCP/UDP write bytes,1024\nEND\n"
+	st = ParseOpenVPNStatus(status)
+	if st.RX != 4096 || st.TX != 1024 || st.Handshake.Format(time.DateTime) != "2024-03-01 12:30:00" {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+// newRunner возвращает имитацию роутера с файлами files, помнящую созданные интерфейсы
+func newRunner(files map[string]string) *testutil.Runner {
+	links := map[string]bool{}
+	return &testutil.Runner{
+		Files: files,
+		Fail:  " -C ",
+		Handlers: map[string]func(string) (string, error){
+			"ip link show dev ": func(command string) (string, error) {
+				if !links[testutil.Arg(command, 4)] {
+					return "", errors.New("exit status 1")
+				}
+				return "", nil
+			},
+			"ip link add dev ": func(command string) (string, error) {
+				links[testutil.Arg(command, 4)] = true
+				return "", nil
+			},
+		},
+	}
+}
+
+func TestUpWireGuard(t *testing.T) {
+	r := newRunner(map[string]string{Dir + "/wg0.conf": sampleWG})
+	m := Manager{Runner: r}
+	tunnel := Tunnel{Name: "wg0", Kind: KindWireGuard, Table: 1001,
+		Clients: []string{"aa:bb:cc:dd:ee:ff"}, IPSets: []string{"vpn"}}
+	if err := m.Up(tunnel); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ip link add dev 'wg0' type wireguard",
+		"wg setconf 'wg0' '/tmp/terem-wg-wg0.conf'",
+		"ip address add '10.66.0.2/32' dev 'wg0'",
+		"ip link set dev 'wg0' mtu 1380 up",
+		"ip route replace '10.66.0.0/24' dev 'wg0'",
+		"ip route replace default dev 'wg0' table 1001",
+		"ip route replace throw 192.168.0.0/16 table 1001",
+		"ip rule add fwmark 1001 lookup 1001 priority 11001",
+		"iptables -t nat -I 'POSTROUTING' '-o' 'wg0' '-m' 'comment' '--comment' 'terem: vpn wg0' '-j' 'MASQUERADE'",
+		"iptables -t mangle -I 'PREROUTING' '-m' 'mac' '--mac-source' 'AA:BB:CC:DD:EE:FF'",
+		"iptables -t mangle -I 'PREROUTING' '-m' 'set' '--match-set' 'vpn' 'dst'",
+	} {
+		if len(r.Find(want)) != 1 {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if len(r.Find("umask 077")) == 0 || strings.Contains(strings.Join(r.Find("wg setconf"), ""), "PrivateKey") {
+		t.Error("keys must be passed through a private file")
+	}
+
+	if err := m.Down(tunnel); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Find("ip route flush table 1001")) != 1 || len(r.Find("ip link del dev 'wg0'")) != 1 {
+		t.Errorf("down commands: %v", r.Commands)
+	}
+
+	bad := tunnel
+	bad.Clients = []string{"not-a-mac"}
+	if _, err := bad.Rules(); err == nil {
+		t.Error("expected MAC error")
+	}
+}
+
+func TestImport(t *testing.T) {
+	r := newRunner(map[string]string{})
+	m := Manager{Runner: r}
+	if _, err := m.Import("office", sampleOVPN, Credentials{}); err == nil {
+		t.Error("expected credentials error")
+	}
+	kind, err := m.Import("office", sampleOVPN, Credentials{User: "user", Password: "secret"})
+	if err != nil || kind != KindOpenVPN {
+		t.Fatalf("Import = %q, %v", kind, err)
+	}
+	if len(r.Find("'user\nsecret\n' > '/opt/etc/terem/vpn/office.auth'")) != 1 ||
+		len(r.Find("auth-user-pass /opt/etc/terem/vpn/office.auth")) != 1 {
+		t.Errorf("import commands: %v", r.Commands)
+	}
+	if _, err := m.Import("bad name", sampleWG, Credentials{}); err == nil {
+		t.Error("expected name error")
+	}
+	if got := NextTable([]int{1001, 1002, 1004}); got != 1003 {
+		t.Errorf("NextTable = %d", got)
+	}
+}
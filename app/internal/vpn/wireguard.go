@@ -0,0 +1,297 @@
+package vpn
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/utils"
+)
+
+// defaultMTU MTU интерфейса WireGuard, если профиль его не задаёт (как у wg-quick)
+const defaultMTU = 1420
+
+// WireGuard профиль WireGuard в формате wg-quick
+type WireGuard struct {
+	PrivateKey string
+	ListenPort int
+	Addresses  []string // Адреса интерфейса с маской
+	DNS        []string
+	MTU        int
+	Peers      []Peer
+}
+
+// Peer удалённая сторона туннеля WireGuard
+type Peer struct {
+	PublicKey    string
+	PresharedKey string
+	Endpoint     string
+	AllowedIPs   []string
+	Keepalive    int
+}
+
+// splitValues разбирает список значений через запятую
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// ParseWireGuard разбирает профиль wg-quick; ключи секций регистронезависимы
+func ParseWireGuard(content string) (WireGuard, error) {
+	var w WireGuard
+	section := ""
+	for n, line := range strings.Split(content, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			if section == "peer" {
+				w.Peers = append(w.Peers, Peer{})
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return w, fmt.Errorf(i18n.T("vpn.error.wg_line"), n+1, line)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		var err error
+		switch section {
+		case "interface":
+			switch key {
+			case "privatekey":
+				w.PrivateKey = value
+			case "listenport":
+				w.ListenPort, err = strconv.Atoi(value)
+			case "address":
+				w.Addresses = append(w.Addresses, splitValues(value)...)
+			case "dns":
+				w.DNS = append(w.DNS, splitValues(value)...)
+			case "mtu":
+				w.MTU, err = strconv.Atoi(value)
+			}
+		case "peer":
+			p := &w.Peers[len(w.Peers)-1]
+			switch key {
+			case "publickey":
+				p.PublicKey = value
+			case "presharedkey":
+				p.PresharedKey = value
+			case "endpoint":
+				p.Endpoint = value
+			case "allowedips":
+				p.AllowedIPs = append(p.AllowedIPs, splitValues(value)...)
+			case "persistentkeepalive":
+				if value != "off" {
+					p.Keepalive, err = strconv.Atoi(value)
+				}
+			}
+		}
+		if err != nil {
+			return w, fmt.Errorf(i18n.T("vpn.error.wg_line"), n+1, line)
+		}
+	}
+	return w, nil
+}
+
+// validKey проверяет ключ WireGuard: 32 байта в base64
+func validKey(key string) bool {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(raw) == 32
+}
+
+// Validate проверяет профиль: ключи, адреса интерфейса и хотя бы одну удалённую сторону
+func (w WireGuard) Validate() error {
+	if !validKey(w.PrivateKey) {
+		return errors.New(i18n.T("vpn.error.wg_private"))
+	}
+	if len(w.Addresses) == 0 {
+		return errors.New(i18n.T("vpn.error.wg_address"))
+	}
+	for _, a := range w.Addresses {
+		if _, err := netip.ParsePrefix(a); err != nil {
+			return fmt.Errorf(i18n.T("vpn.error.address"), a)
+		}
+	}
+	if len(w.Peers) == 0 {
+		return errors.New(i18n.T("vpn.error.wg_peer"))
+	}
+	for _, p := range w.Peers {
+		if !validKey(p.PublicKey) || (p.PresharedKey != "" && !validKey(p.PresharedKey)) {
+			return fmt.Errorf(i18n.T("vpn.error.wg_public"), p.PublicKey)
+		}
+		for _, a := range p.AllowedIPs {
+			if _, err := netip.ParsePrefix(a); err != nil {
+				return fmt.Errorf(i18n.T("vpn.error.address"), a)
+			}
+		}
+	}
+	return nil
+}
+
+// SetConf возвращает настройки для wg setconf: только ключи, которые понимает wg,
+// без Address, DNS и MTU из формата wg-quick
+func (w WireGuard) SetConf() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Interface]\nPrivateKey = %s\n", w.PrivateKey)
+	if w.ListenPort > 0 {
+		fmt.Fprintf(&b, "ListenPort = %d\n", w.ListenPort)
+	}
+	for _, p := range w.Peers {
+		fmt.Fprintf(&b, "\n[Peer]\nPublicKey = %s\n", p.PublicKey)
+		if p.PresharedKey != "" {
+			fmt.Fprintf(&b, "PresharedKey = %s\n", p.PresharedKey)
+		}
+		if p.Endpoint != "" {
+			fmt.Fprintf(&b, "Endpoint = %s\n", p.Endpoint)
+		}
+		if len(p.AllowedIPs) > 0 {
+			fmt.Fprintf(&b, "AllowedIPs = %s\n", strings.Join(p.AllowedIPs, ", "))
+		}
+		if p.Keepalive > 0 {
+			fmt.Fprintf(&b, "PersistentKeepalive = %d\n", p.Keepalive)
+		}
+	}
+	return b.String()
+}
+
+// Routes возвращает подсети AllowedIPs, кроме маршрутов по умолчанию:
+// маршрут по умолчанию туннеля попадает только в его таблицу выборочной маршрутизации
+func (w WireGuard) Routes() []string {
+	var routes []string
+	for _, p := range w.Peers {
+		for _, a := range p.AllowedIPs {
+			if prefix, err := netip.ParsePrefix(a); err == nil && prefix.Bits() > 0 {
+				routes = append(routes, prefix.Masked().String())
+			}
+		}
+	}
+	return routes
+}
+
+// GenerateKey создаёт закрытый ключ WireGuard и возвращает его вместе с открытым
+func GenerateKey() (private, public string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf(i18n.T("vpn.error.keygen"), err)
+	}
+	return base64.StdEncoding.EncodeToString(key.Bytes()),
+		base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// PublicKey вычисляет открытый ключ по закрытому
+func PublicKey(private string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(private)
+	if err != nil || len(raw) != 32 {
+		return "", errors.New(i18n.T("vpn.error.wg_private"))
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return "", errors.New(i18n.T("vpn.error.wg_private"))
+	}
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// SetPrivateKey заменяет закрытый ключ в секции [Interface], сохраняя остальной профиль
+func SetPrivateKey(content, private string) string {
+	lines := strings.Split(content, "\n")
+	section, iface := "", -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			section = strings.ToLower(strings.Trim(trimmed, "[]"))
+			if section == "interface" {
+				iface = i
+			}
+			continue
+		}
+		key, _, ok := strings.Cut(trimmed, "=")
+		if section == "interface" && ok && strings.EqualFold(strings.TrimSpace(key), "PrivateKey") {
+			lines[i] = "PrivateKey = " + private
+			return strings.Join(lines, "\n")
+		}
+	}
+	if iface < 0 {
+		return "[Interface]\nPrivateKey = " + private + "\n" + content
+	}
+	lines = append(lines[:iface+1], append([]string{"PrivateKey = " + private}, lines[iface+1:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// RegenerateKey создаёт новый закрытый ключ туннеля WireGuard, записывает его в профиль
+// и возвращает открытый ключ, который нужно указать на сервере
+func (m Manager) RegenerateKey(name string) (string, error) {
+	content, err := m.Read(name, KindWireGuard)
+	if err != nil {
+		return "", err
+	}
+	private, public, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	return public, m.writePrivate(m.Profile(name, KindWireGuard), SetPrivateKey(content, private))
+}
+
+// upWireGuard создаёт интерфейс WireGuard, применяет ключи и адреса и добавляет маршруты AllowedIPs
+func (m Manager) upWireGuard(name, content string) error {
+	cfg, err := ParseWireGuard(content)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	dev := utils.ShellQuote(name)
+	if !m.linkExists(name) {
+		if _, err := m.run("ip link add dev " + dev + " type wireguard"); err != nil {
+			return err
+		}
+	}
+	// wg setconf читает ключи только из файла; файл доступен лишь владельцу и сразу удаляется
+	tmp := "/tmp/terem-wg-" + name + ".conf"
+	if err := m.writePrivate(tmp, cfg.SetConf()); err != nil {
+		return err
+	}
+	_, err = m.run(fmt.Sprintf("wg setconf %s %s", dev, utils.ShellQuote(tmp)))
+	_, _ = utils.OrLocal(m.Runner).RunCommand("rm -f " + utils.ShellQuote(tmp))
+	if err != nil {
+		return err
+	}
+
+	mtu := cfg.MTU
+	if mtu <= 0 {
+		mtu = defaultMTU
+	}
+	commands := []string{"ip address flush dev " + dev}
+	for _, a := range cfg.Addresses {
+		commands = append(commands, fmt.Sprintf("ip address add %s dev %s", utils.ShellQuote(a), dev))
+	}
+	commands = append(commands, fmt.Sprintf("ip link set dev %s mtu %d up", dev, mtu))
+	for _, r := range cfg.Routes() {
+		commands = append(commands, fmt.Sprintf("ip route replace %s dev %s", utils.ShellQuote(r), dev))
+	}
+	for _, c := range commands {
+		if _, err := m.run(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
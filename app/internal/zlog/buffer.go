@@ -0,0 +1,246 @@
+package zlog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// BufferOptions описывает параметры буферизованной записи лога.
+// Нулевые значения полей заменяются параметрами профиля, выбранного AutoProfile.
+type BufferOptions struct {
+	Interval   time.Duration // Период принудительного сброса буфера в файл
+	Size       int           // Объём накопленных данных (в байтах), при котором выполняется сброс
+	FlushLevel zerolog.Level // Уровень записи, при котором буфер сбрасывается немедленно
+}
+
+// maxPendingFactor во сколько раз накопленные данные могут превысить порог сброса,
+// пока файл недоступен; сверх этого они отбрасываются, чтобы не исчерпать память
+const maxPendingFactor = 4
+
+// memorySink — промежуточный writer между форматтером и файлом.
+// Хранит последние записи в кольцевом буфере и, в буферизованном режиме,
+// накапливает данные перед записью в файл, чтобы снизить износ flash-памяти.
+type memorySink struct {
+	mu sync.Mutex
+	// out — файловый writer, nil если запись в файл отключена
+	out io.Writer
+
+	ring  []string
+	next  int
+	count int
+
+	buffered bool
+	pending  bytes.Buffer
+	opts     BufferOptions
+	stop     chan struct{}
+}
+
+func newMemorySink(out io.Writer, entries int) *memorySink {
+	return &memorySink{
+		out:  out,
+		ring: make([]string, entries),
+		opts: BufferOptions{
+			Interval:   5 * time.Second,
+			Size:       16 * 1024,
+			FlushLevel: ErrorLevel,
+		},
+	}
+}
+
+// Write сохраняет отформатированную запись в кольцевом буфере и передаёт её в файл
+// напрямую либо через буфер, в зависимости от режима.
+func (s *memorySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remember(strings.TrimRight(string(p), "\n"))
+
+	if s.out == nil {
+		return len(p), nil
+	}
+	if !s.buffered {
+		return s.out.Write(p)
+	}
+
+	// Запись уже принята в буфер: при ошибке сброса она остаётся в нём до следующей попытки
+	s.pending.Write(p)
+	if s.pending.Len() >= s.opts.Size {
+		if err := s.flushLocked(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// remember добавляет строку в кольцевой буфер, вытесняя самую старую запись.
+func (s *memorySink) remember(line string) {
+	if len(s.ring) == 0 {
+		return
+	}
+	s.ring[s.next] = line
+	s.next = (s.next + 1) % len(s.ring)
+	if s.count < len(s.ring) {
+		s.count++
+	}
+}
+
+// Recent возвращает до n последних записей в хронологическом порядке.
+func (s *memorySink) Recent(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n <= 0 || n > s.count {
+		n = s.count
+	}
+	result := make([]string, 0, n)
+	start := (s.next - n + len(s.ring)) % max(len(s.ring), 1)
+	for i := 0; i < n; i++ {
+		result = append(result, s.ring[(start+i)%len(s.ring)])
+	}
+	return result
+}
+
+// Resize меняет ёмкость кольцевого буфера, сохраняя самые свежие записи.
+func (s *memorySink) Resize(entries int) {
+	if entries < 0 {
+		entries = 0
+	}
+	kept := s.Recent(entries)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ring = make([]string, entries)
+	s.next, s.count = 0, 0
+	for _, line := range kept {
+		s.remember(line)
+	}
+}
+
+// SetDefaults задаёт параметры буферизации по умолчанию (используется AutoProfile).
+func (s *memorySink) SetDefaults(interval time.Duration, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts.Interval = interval
+	s.opts.Size = size
+}
+
+// Enable включает буферизованный режим и запускает периодический сброс.
+func (s *memorySink) Enable(opts BufferOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opts.Interval > 0 {
+		s.opts.Interval = opts.Interval
+	}
+	if opts.Size > 0 {
+		s.opts.Size = opts.Size
+	}
+	if opts.FlushLevel != zerolog.NoLevel && opts.FlushLevel > DebugLevel {
+		s.opts.FlushLevel = opts.FlushLevel
+	}
+
+	s.buffered = true
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.stop = make(chan struct{})
+	go s.loop(s.opts.Interval, s.stop)
+}
+
+// Disable сбрасывает накопленные данные и возвращает синхронную запись.
+func (s *memorySink) Disable() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	err := s.flushLocked()
+	s.buffered = false
+	return err
+}
+
+// Buffered сообщает, включён ли буферизованный режим.
+func (s *memorySink) Buffered() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buffered
+}
+
+// Flush записывает накопленные данные в файл.
+func (s *memorySink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// ShouldFlush сообщает, требует ли запись указанного уровня немедленного сброса.
+func (s *memorySink) ShouldFlush(level zerolog.Level) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buffered && level >= s.opts.FlushLevel && level != zerolog.NoLevel
+}
+
+// flushLocked записывает накопленные данные в файл; вызывается под s.mu.
+// Незаписанная часть остаётся в буфере до следующего сброса
+func (s *memorySink) flushLocked() error {
+	if s.out == nil || s.pending.Len() == 0 {
+		return nil
+	}
+	n, err := s.out.Write(s.pending.Bytes())
+	if err == nil {
+		s.pending.Reset()
+		return nil
+	}
+	s.pending.Next(n)
+	if s.pending.Len() >= maxPendingFactor*s.opts.Size {
+		s.pending.Reset()
+	}
+	return err
+}
+
+// loop сбрасывает буфер с периодом interval до закрытия stop
+func (s *memorySink) loop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// levelWriter передаёт события форматтеру и сбрасывает буфер
+// при записи событий уровня Error и выше.
+type levelWriter struct {
+	console zerolog.ConsoleWriter
+	sink    *memorySink
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	return w.console.Write(p)
+}
+
+func (w levelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	n, err := w.console.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if w.sink.ShouldFlush(level) {
+		if err := w.sink.Flush(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
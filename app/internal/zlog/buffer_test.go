@@ -0,0 +1,125 @@
+package zlog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferedWritesAreDeferredUntilFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terem.log")
+	logger := New(path)
+	t.Cleanup(func() { _ = logger.Close() })
+
+	logger.EnableBuffering(BufferOptions{Interval: time.Hour, Size: 1 << 20})
+	logger.Info("first entry")
+
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "first entry") {
+		t.Fatalf("expected entry to stay in buffer, file contains %q", data)
+	}
+
+	if err := logger.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "first entry") {
+		t.Fatalf("expected entry after flush, got %q", data)
+	}
+}
+
+func TestBufferFlushesOnErrorLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terem.log")
+	logger := New(path)
+	t.Cleanup(func() { _ = logger.Close() })
+
+	logger.EnableBuffering(BufferOptions{Interval: time.Hour, Size: 1 << 20})
+	logger.Info("pending entry")
+	logger.Error("broken entry")
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "pending entry") || !strings.Contains(string(data), "broken entry") {
+		t.Fatalf("expected buffer to be flushed on error, got %q", data)
+	}
+}
+
+// flakyWriter отказывает в записи, пока fail установлен
+type flakyWriter struct {
+	fail    bool
+	written strings.Builder
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("no space left on device")
+	}
+	return w.written.Write(p)
+}
+
+func TestFailedFlushKeepsPendingData(t *testing.T) {
+	out := &flakyWriter{fail: true}
+	sink := newMemorySink(out, 10)
+	sink.Enable(BufferOptions{Interval: time.Hour, Size: 8})
+	t.Cleanup(func() { _ = sink.Disable() })
+
+	if n, err := sink.Write([]byte("first entry\n")); n != len("first entry\n") || err == nil {
+		t.Fatalf("expected accepted write with flush error, got %d, %v", n, err)
+	}
+	out.fail = false
+	if _, err := sink.Write([]byte("second entry\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := out.written.String(); got != "first entry\nsecond entry\n" {
+		t.Fatalf("expected both entries after recovery, got %q", got)
+	}
+}
+
+func TestRecentWithoutFileLogging(t *testing.T) {
+	logger := New("")
+	t.Cleanup(func() { _ = logger.Close() })
+
+	logger.SetRecentLimit(2)
+	logger.Info("one")
+	logger.Info("two")
+	logger.Warn("three")
+
+	recent := logger.Recent(0)
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %v", len(recent), recent)
+	}
+	if !strings.HasSuffix(recent[0], "two") || !strings.Contains(recent[1], "[WARN]") || !strings.HasSuffix(recent[1], "three") {
+		t.Fatalf("unexpected entries order: %v", recent)
+	}
+}
+
+func TestAdoptRecentKeepsEntries(t *testing.T) {
+	prev := New("")
+	prev.Info("before switch")
+	_ = prev.Close()
+
+	logger := New("")
+	t.Cleanup(func() { _ = logger.Close() })
+	logger.AdoptRecent(prev)
+	logger.Info("after switch")
+
+	recent := logger.Recent(0)
+	if len(recent) != 2 || !strings.HasSuffix(recent[0], "before switch") || !strings.HasSuffix(recent[1], "after switch") {
+		t.Fatalf("unexpected entries: %v", recent)
+	}
+}
+
+func TestCloseFlushesBuffer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terem.log")
+	logger := New(path)
+
+	logger.EnableBuffering(BufferOptions{Interval: time.Hour, Size: 1 << 20})
+	logger.Info("last words")
+
+	if err := logger.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "last words") {
+		t.Fatalf("expected entry after close, got %q", data)
+	}
+}
@@ -12,24 +12,34 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/qzeleza/terem/internal/i18n"
 	"github.com/rs/zerolog"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-const consoleTimeFormat = "02-01-2006 15:04:05"
+const (
+	consoleTimeFormat = "02-01-2006 15:04:05"
+	// defaultRecentEntries количество последних записей, хранимых в памяти по умолчанию
+	defaultRecentEntries = 500
+)
 
 // Logger — структура логгера с настройками ротации.
-// Поле zlog — внутренний zerolog.Logger; rot — ротационный writer.
+// Поле zlog — внутренний zerolog.Logger; rot — ротационный writer (nil, если запись в файл отключена);
+// sink — буфер последних записей и отложенной записи в файл.
 type Logger struct {
 	zlog zerolog.Logger
 	rot  *lumberjack.Logger
+	sink *memorySink
 }
 
 var (
 	registry   = newLoggerRegistry()
 	sighupOnce sync.Once
+	// fatalHook вызывается перед завершением приложения по Fatal
+	fatalHook func(msg string)
+	hookMu    sync.RWMutex
 )
 
 // Уровни логирования (константы пакета, равны zerolog.Level для удобства использования как log.InfoLevel).
@@ -45,44 +55,68 @@ const (
 )
 
 // New создаёт новый экземпляр логгера и регистрирует его в глобальном реестре.
-// Параметры ротации подстраиваются автоматически через AutoProfile.
+// Если filename пуст, запись в файл отключается и записи хранятся только в памяти (см. Recent).
+// Параметры ротации и буферизации подстраиваются автоматически через AutoProfile.
 func New(filename string) *Logger {
-	rotator := newRotator(filename)
-	writer := newConsoleWriter(rotator)
+	var out io.Writer
+	var rotator *lumberjack.Logger
+	if filename != "" {
+		rotator = newRotator(filename)
+		out = rotator
+	}
+	sink := newMemorySink(out, defaultRecentEntries)
+	writer := levelWriter{console: newConsoleWriter(sink), sink: sink}
 	z := newZerolog(writer)
 
-	logger := &Logger{zlog: z, rot: rotator}
+	logger := &Logger{zlog: z, rot: rotator, sink: sink}
 	registry.Add(logger)
 	logger.AutoProfile()
 
 	return logger
 }
 
-// AutoProfile устанавливает параметры ротации на основе объёма памяти устройства.
+// Пороги объёма памяти, по которым AutoProfile выбирает профиль.
+const (
+	TinyMemory  = 65 * 1024 * 1024  // Роутеры с 64 МБ
+	SmallMemory = 128 * 1024 * 1024 // Роутеры со 128 МБ
+)
+
+// AutoProfile устанавливает параметры ротации и буферизации на основе объёма памяти устройства.
 func (l *Logger) AutoProfile() {
-	if l == nil || l.rot == nil {
+	if l == nil {
 		return
 	}
 
 	mem := getTotalMemory()
 
+	var maxSize, maxBackups, maxAge, recent, bufSize int
+	var compress bool
+	var interval time.Duration
+
 	switch {
-	case mem < 65*1024*1024:
-		l.rot.MaxSize = 5
-		l.rot.MaxBackups = 1
-		l.rot.MaxAge = 3
-		l.rot.Compress = false
-	case mem < 128*1024*1024:
-		l.rot.MaxSize = 10
-		l.rot.MaxBackups = 2
-		l.rot.MaxAge = 7
-		l.rot.Compress = false
+	case mem < TinyMemory:
+		maxSize, maxBackups, maxAge, compress = 5, 1, 3, false
+		recent, bufSize, interval = 100, 4*1024, 30*time.Second
+	case mem < SmallMemory:
+		maxSize, maxBackups, maxAge, compress = 10, 2, 7, false
+		recent, bufSize, interval = 200, 16*1024, 15*time.Second
 	default:
-		l.rot.MaxSize = 50
-		l.rot.MaxBackups = 3
-		l.rot.MaxAge = 14
-		l.rot.Compress = true
+		maxSize, maxBackups, maxAge, compress = 50, 3, 14, true
+		recent, bufSize, interval = defaultRecentEntries, 64*1024, 5*time.Second
+	}
+
+	if l.sink != nil {
+		l.sink.Resize(recent)
+		l.sink.SetDefaults(interval, bufSize)
+	}
+
+	if l.rot == nil {
+		return
 	}
+	l.rot.MaxSize = maxSize
+	l.rot.MaxBackups = maxBackups
+	l.rot.MaxAge = maxAge
+	l.rot.Compress = compress
 }
 
 // SetLevel устанавливает глобальный уровень логирования (использует константы пакета).
@@ -125,24 +159,97 @@ func (l *Logger) SetCompress(compress bool) {
 	l.rot.Compress = compress
 }
 
+// EnableBuffering включает буферизованную запись в файл: записи накапливаются в памяти
+// и сбрасываются по таймеру, при переполнении буфера, при записи уровня Error и выше,
+// при Close, Rotate и SIGHUP.
+func (l *Logger) EnableBuffering(opts BufferOptions) {
+	if l == nil || l.sink == nil {
+		return
+	}
+	l.sink.Enable(opts)
+}
+
+// DisableBuffering сбрасывает накопленные записи и возвращает синхронную запись в файл.
+func (l *Logger) DisableBuffering() error {
+	if l == nil || l.sink == nil {
+		return nil
+	}
+	return l.sink.Disable()
+}
+
+// Buffered сообщает, включена ли буферизованная запись.
+func (l *Logger) Buffered() bool {
+	if l == nil || l.sink == nil {
+		return false
+	}
+	return l.sink.Buffered()
+}
+
+// Flush записывает в файл все накопленные в буфере записи.
+func (l *Logger) Flush() error {
+	if l == nil || l.sink == nil {
+		return nil
+	}
+	return l.sink.Flush()
+}
+
+// Recent возвращает до n последних записей лога (n <= 0 — все сохранённые).
+// Записи доступны и при отключённой записи в файл.
+func (l *Logger) Recent(n int) []string {
+	if l == nil || l.sink == nil {
+		return nil
+	}
+	return l.sink.Recent(n)
+}
+
+// AdoptRecent переносит в память последние записи логгера prev, например когда логгер
+// пересоздаётся при смене режима записи и просмотр последних записей не должен опустеть.
+func (l *Logger) AdoptRecent(prev *Logger) {
+	if l == nil || l.sink == nil {
+		return
+	}
+	entries := prev.Recent(0)
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	for _, line := range entries {
+		l.sink.remember(line)
+	}
+}
+
+// SetRecentLimit задаёт количество последних записей, хранимых в памяти.
+func (l *Logger) SetRecentLimit(entries int) {
+	if l == nil || l.sink == nil {
+		return
+	}
+	l.sink.Resize(entries)
+}
+
 // Rotate принудительно выполняет ротацию текущего файла лога.
+// Перед ротацией буфер сбрасывается в текущий файл.
 func (l *Logger) Rotate() error {
 	if l == nil || l.rot == nil {
 		return nil
 	}
+	if err := l.Flush(); err != nil {
+		return err
+	}
 	return l.rot.Rotate()
 }
 
-// Close закрывает writer и исключает логгер из глобального реестра.
+// Close сбрасывает буфер, закрывает writer и исключает логгер из глобального реестра.
 func (l *Logger) Close() error {
 	if l == nil {
 		return nil
 	}
 	registry.Remove(l)
+	var flushErr error
+	if l.sink != nil {
+		flushErr = l.sink.Disable()
+	}
 	if l.rot == nil {
-		return nil
+		return flushErr
 	}
-	return l.rot.Close()
+	return errors.Join(flushErr, l.rot.Close())
 }
 
 // EnableSIGHUP включает обработчик SIGHUP для всех зарегистрированных логгеров.
@@ -162,6 +269,11 @@ func EnableSIGHUP() {
 	})
 }
 
+// FlushAll сбрасывает буферы всех зарегистрированных логгеров (например, перед аварийным завершением).
+func FlushAll() error {
+	return registry.FlushAll()
+}
+
 // Debug логирует на уровне Debug. Возвращает nil для совместимости с прежним API.
 func (l *Logger) Debug(args ...interface{}) error {
 	if l == nil {
@@ -198,12 +310,29 @@ func (l *Logger) Error(args ...interface{}) error {
 	return nil
 }
 
+// SetFatalHook задаёт функцию, вызываемую с текстом сообщения перед завершением по Fatal
+// (например, для сохранения отчёта о сбое). nil отключает хук.
+func SetFatalHook(hook func(msg string)) {
+	hookMu.Lock()
+	fatalHook = hook
+	hookMu.Unlock()
+}
+
 // Fatal логирует на уровне Fatal и завершает приложение.
 func (l *Logger) Fatal(args ...interface{}) error {
 	if l == nil {
 		return nil
 	}
-	l.zlog.Fatal().Msg(formatArgs(args...))
+	msg := formatArgs(args...)
+
+	hookMu.RLock()
+	hook := fatalHook
+	hookMu.RUnlock()
+	if hook != nil {
+		hook(msg)
+	}
+
+	l.zlog.Fatal().Msg(msg)
 	return nil
 }
 
@@ -245,7 +374,7 @@ func newConsoleWriter(out io.Writer) zerolog.ConsoleWriter {
 	return writer
 }
 
-func newZerolog(writer zerolog.ConsoleWriter) zerolog.Logger {
+func newZerolog(writer zerolog.LevelWriter) zerolog.Logger {
 	return zerolog.New(writer).With().Timestamp().Logger().Level(DebugLevel)
 }
 
@@ -331,3 +460,13 @@ func (r *loggerRegistry) RotateAll() error {
 	}
 	return errors.Join(errs...)
 }
+
+func (r *loggerRegistry) FlushAll() error {
+	var errs []error
+	for _, logger := range r.Snapshot() {
+		if err := logger.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
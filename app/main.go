@@ -10,68 +10,78 @@ import (
 
 	"github.com/qzeleza/terem/cmd/args"
 	"github.com/qzeleza/terem/cmd/tui"
+	"github.com/qzeleza/terem/internal/buildinfo"
 	"github.com/qzeleza/terem/internal/i18n"
+	"github.com/qzeleza/terem/internal/zlog"
 )
 
 func main() {
 
 	LANGUAGE := "ru"
 	DEBUG := false
-	VERSION := "1.0.0"
-	APPNAME := "terem"
+	VERSION := buildinfo.Get().Version // Задаётся при сборке, см. Makefile
+	APPNAME := buildinfo.AppName
 	LOGFILE := fmt.Sprintf("/tmp/%s.log", APPNAME)
 	CONF := fmt.Sprintf("/opt/etc/%s/config.yaml", APPNAME)
 
-	// 1. Инициализируем конфигурацию приложения
+	// 1. Инициализируем конфигурацию приложения. NewSetup сам выбирает пути к логу
+	// и конфигурации с учётом запасных вариантов, поэтому дальше используются ac.LogFile и ac.ConfFile
 	ac, err := tui.NewSetup(LANGUAGE, APPNAME, VERSION, DEBUG, LOGFILE, CONF)
 	if err != nil {
 		fmt.Printf(i18n.T("main.error.setup")+"\n", err)
 		os.Exit(1)
 	}
 
-	// 2. Устанавливаем файлы логов и конфигурации
-	ac.LogFile = LOGFILE
-	ac.ConfFile = CONF
-
-	// 3. Создаем корневой контекст для graceful shutdown
+	// 2. Создаем корневой контекст для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	ac.RootCtx = ctx
 	ac.CancelFunc = cancel
 	defer cancel()
 
-	// 4. Настраиваем обработку сигналов для graceful shutdown
+	// 3. Настраиваем обработку сигналов для graceful shutdown
 	setupSignalHandler(cancel, ac)
 
-	// 5. Восстановление паники в случае ошибки
+	// 4. Восстановление паники в случае ошибки
 	defer func() {
 		if r := recover(); r != nil {
-			msg := fmt.Sprintf("PANIC: %v\n%s", r, debug.Stack())
+			stack := debug.Stack()
+			msg := fmt.Sprintf("PANIC: %v\n%s", r, stack)
 			fmt.Fprintf(os.Stderr, "%s\n", msg)
 
-			// Если логгер уже инициализирован, логируем ошибку
+			// Если логгер уже инициализирован, логируем ошибку и сохраняем отчёт о сбое
 			if ac != nil {
 				ac.Log.Error("critical error: ", msg)
+				if path, err := ac.WriteCrashReport(r, stack); err == nil {
+					fmt.Fprintf(os.Stderr, i18n.T("diag.crash.saved")+"\n", path)
+				}
 			}
+			// Сбрасываем буферы логов, т.к. os.Exit не выполняет отложенные вызовы
+			_ = zlog.FlushAll()
 
 			os.Exit(1)
 		}
 	}()
 
-	// 6. Закрываем логгер при завершении программы
-	defer ac.Log.Close()
+	// 5. Запускаем приложение c обработкой аргументов командной строки
+	code := args.Execute(ac)
 
-	// 7. Запускаем приложение c обработкой аргументов командной строки
-	args.Execute(ac)
+	// 6. Закрываем логгер до выхода: os.Exit не выполняет отложенные вызовы
+	ac.Log.Close()
+	if code != 0 {
+		os.Exit(code)
+	}
 }
 
 // setupSignalHandler настраивает обработку сигналов для graceful shutdown
 func setupSignalHandler(cancel context.CancelFunc, ac *tui.AppConfig) {
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
 		sig := <-signalChan
 		ac.Log.Info(fmt.Sprintf(i18n.T("shutdown.signal"), sig))
+		// Сразу сбрасываем буфер лога: при обрыве сессии (SIGHUP) процесс может не дойти до Close
+		_ = ac.Log.Flush()
 		cancel()
 	}()
 }